@@ -0,0 +1,79 @@
+// Package access implements middleware that allows or denies
+// requests based on the IP address of the client.
+package access
+
+import (
+	"log"
+	"net"
+	"net/http"
+
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+// Access is middleware that restricts which clients can make
+// requests under certain paths.
+type Access struct {
+	Next  httpserver.Handler
+	Rules []Rule
+
+	// TrustedProxies are the proxies whose X-Forwarded-For
+	// headers are used to find the client; see httpserver.ClientIP.
+	TrustedProxies []*net.IPNet
+}
+
+// Rule restricts access to the requests under Path. Only the
+// first rule with a matching path applies to a request.
+//
+// By default, a client that matches any deny entry is denied,
+// whatever the allow entries say. If FirstMatch is true, the
+// entries are checked in order instead, and the first one that
+// matches the client decides. Either way, a client that matches
+// no entry is denied if the rule has allow entries, and allowed
+// if it only denies.
+type Rule struct {
+	Path       string
+	Entries    []Entry
+	FirstMatch bool
+	LogDenied  bool
+}
+
+// Entry allows or denies the clients in an IP range.
+type Entry struct {
+	Allow bool
+	IPNet *net.IPNet
+}
+
+// ServeHTTP implements the httpserver.Handler interface.
+func (a Access) ServeHTTP(w http.ResponseWriter, r *http.Request) (int, error) {
+	for _, rule := range a.Rules {
+		if !httpserver.Path(r.URL.Path).Matches(rule.Path) {
+			continue
+		}
+		ip := httpserver.ClientIP(r, a.TrustedProxies)
+		if !rule.Allowed(ip) {
+			if rule.LogDenied {
+				log.Printf("[INFO] access: denied %s to %s %s", ip, r.Method, r.URL.Path)
+			}
+			return http.StatusForbidden, nil
+		}
+		break
+	}
+	return a.Next.ServeHTTP(w, r)
+}
+
+// Allowed reports whether the client at ip may make requests
+// under the rule's path. A nil ip matches no entries.
+func (rule Rule) Allowed(ip net.IP) bool {
+	var hasAllow, allowed bool
+	for _, entry := range rule.Entries {
+		hasAllow = hasAllow || entry.Allow
+		if ip == nil || !entry.IPNet.Contains(ip) {
+			continue
+		}
+		if !entry.Allow || rule.FirstMatch {
+			return entry.Allow
+		}
+		allowed = true
+	}
+	return allowed || !hasAllow
+}
@@ -0,0 +1,116 @@
+package access
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func TestRuleAllowed(t *testing.T) {
+	entry := func(allow bool, s string) Entry {
+		ipnet, err := httpserver.ParseCIDR(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return Entry{Allow: allow, IPNet: ipnet}
+	}
+	allow := func(s string) Entry { return entry(true, s) }
+	deny := func(s string) Entry { return entry(false, s) }
+
+	for i, test := range []struct {
+		rule     Rule
+		ip       string
+		expected bool
+	}{
+		// single IPs
+		{Rule{Entries: []Entry{allow("1.2.3.4")}}, "1.2.3.4", true},
+		{Rule{Entries: []Entry{allow("1.2.3.4")}}, "1.2.3.5", false},
+		{Rule{Entries: []Entry{deny("1.2.3.4")}}, "1.2.3.4", false},
+		{Rule{Entries: []Entry{deny("1.2.3.4")}}, "1.2.3.5", true},
+
+		// CIDR ranges
+		{Rule{Entries: []Entry{allow("10.0.0.0/8")}}, "10.200.3.4", true},
+		{Rule{Entries: []Entry{allow("10.0.0.0/8")}}, "11.0.0.1", false},
+		{Rule{Entries: []Entry{deny("192.168.0.0/16")}}, "192.168.77.1", false},
+
+		// IPv6
+		{Rule{Entries: []Entry{allow("2001:db8::/32")}}, "2001:db8:1::1", true},
+		{Rule{Entries: []Entry{allow("2001:db8::/32")}}, "2001:db9::1", false},
+		{Rule{Entries: []Entry{deny("::1")}}, "::1", false},
+		{Rule{Entries: []Entry{allow("10.0.0.0/8")}}, "::ffff:10.0.0.1", true},
+		{Rule{Entries: []Entry{allow("::/0")}}, "10.0.0.1", false},
+
+		// deny wins, in any order
+		{Rule{Entries: []Entry{allow("10.0.0.0/8"), deny("10.0.0.5")}}, "10.0.0.5", false},
+		{Rule{Entries: []Entry{deny("10.0.0.5"), allow("10.0.0.0/8")}}, "10.0.0.5", false},
+		{Rule{Entries: []Entry{allow("10.0.0.0/8"), deny("10.0.0.5")}}, "10.0.0.6", true},
+
+		// first match wins
+		{Rule{FirstMatch: true, Entries: []Entry{allow("10.0.0.5"), deny("10.0.0.0/8")}}, "10.0.0.5", true},
+		{Rule{FirstMatch: true, Entries: []Entry{allow("10.0.0.5"), deny("10.0.0.0/8")}}, "10.0.0.6", false},
+		{Rule{FirstMatch: true, Entries: []Entry{deny("10.0.0.0/8"), allow("10.0.0.5")}}, "10.0.0.5", false},
+		{Rule{FirstMatch: true, Entries: []Entry{deny("10.0.0.0/8"), allow("10.0.0.5")}}, "8.8.8.8", false},
+		{Rule{FirstMatch: true, Entries: []Entry{deny("10.0.0.0/8")}}, "8.8.8.8", true},
+
+		// unknown client
+		{Rule{Entries: []Entry{allow("10.0.0.0/8")}}, "", false},
+		{Rule{Entries: []Entry{deny("10.0.0.0/8")}}, "", true},
+	} {
+		if got := test.rule.Allowed(net.ParseIP(test.ip)); got != test.expected {
+			t.Errorf("Test %d: Expected Allowed(%s) to be %v, got %v", i, test.ip, test.expected, got)
+		}
+	}
+}
+
+func TestAccess(t *testing.T) {
+	a := Access{
+		Next: httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+			return http.StatusOK, nil
+		}),
+		Rules: []Rule{{
+			Path: "/admin",
+			Entries: []Entry{
+				{Allow: true, IPNet: &net.IPNet{IP: net.IP{192, 168, 0, 0}, Mask: net.CIDRMask(16, 32)}},
+			},
+		}, {
+			Path: "/",
+			Entries: []Entry{
+				{Allow: false, IPNet: &net.IPNet{IP: net.IP{6, 6, 6, 0}, Mask: net.CIDRMask(24, 32)}},
+			},
+		}},
+		TrustedProxies: []*net.IPNet{{IP: net.IP{10, 0, 0, 1}, Mask: net.CIDRMask(32, 32)}},
+	}
+
+	for i, test := range []struct {
+		path, remoteAddr, xff string
+		expected              int
+	}{
+		{"/admin", "192.168.1.1:1234", "", http.StatusOK},
+		{"/admin", "8.8.8.8:1234", "", http.StatusForbidden},
+		{"/admin", "6.6.6.6:1234", "", http.StatusForbidden},
+		{"/admin", "10.0.0.1:1234", "192.168.1.1", http.StatusOK},
+		{"/admin", "8.8.8.8:1234", "192.168.1.1", http.StatusForbidden},
+		{"/", "8.8.8.8:1234", "", http.StatusOK},
+		{"/", "6.6.6.6:1234", "", http.StatusForbidden},
+		{"/", "10.0.0.1:1234", "6.6.6.6", http.StatusForbidden},
+	} {
+		r, err := http.NewRequest("GET", test.path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.RemoteAddr = test.remoteAddr
+		if test.xff != "" {
+			r.Header.Set("X-Forwarded-For", test.xff)
+		}
+		status, err := a.ServeHTTP(httptest.NewRecorder(), r)
+		if err != nil {
+			t.Errorf("Test %d: Expected no error, got %v", i, err)
+		}
+		if status != test.expected {
+			t.Errorf("Test %d: Expected status %d, got %d", i, test.expected, status)
+		}
+	}
+}
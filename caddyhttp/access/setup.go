@@ -0,0 +1,119 @@
+package access
+
+import (
+	"net"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func init() {
+	caddy.RegisterPlugin("access", caddy.Plugin{
+		ServerType: "http",
+		Action:     setup,
+	})
+}
+
+// setup configures a new Access middleware instance.
+func setup(c *caddy.Controller) error {
+	rules, err := accessParse(c)
+	if err != nil {
+		return err
+	}
+
+	cfg := httpserver.GetConfig(c)
+	cfg.AddMiddleware(func(next httpserver.Handler) httpserver.Handler {
+		return Access{Next: next, Rules: rules, TrustedProxies: cfg.TrustedProxies}
+	})
+
+	return nil
+}
+
+// accessParse parses the directive:
+//
+//	access [path] {
+//		allow  <ip|cidr|all>...
+//		deny   <ip|cidr|all>...
+//		policy deny_wins|first_match
+//		log_denied
+//	}
+func accessParse(c *caddy.Controller) ([]Rule, error) {
+	var rules []Rule
+
+	for c.Next() {
+		rule := Rule{Path: "/"}
+
+		args := c.RemainingArgs()
+		switch len(args) {
+		case 0:
+		case 1:
+			rule.Path = args[0]
+		default:
+			return rules, c.ArgErr()
+		}
+
+		for c.NextBlock() {
+			switch what := c.Val(); what {
+			case "allow", "deny":
+				ranges := c.RemainingArgs()
+				if len(ranges) == 0 {
+					return rules, c.ArgErr()
+				}
+				for _, s := range ranges {
+					ipnets, err := parseRange(s)
+					if err != nil {
+						return rules, c.Errf("invalid %s address '%s': %v", what, s, err)
+					}
+					for _, ipnet := range ipnets {
+						rule.Entries = append(rule.Entries, Entry{Allow: what == "allow", IPNet: ipnet})
+					}
+				}
+			case "policy":
+				if !c.NextArg() {
+					return rules, c.ArgErr()
+				}
+				switch c.Val() {
+				case "deny_wins":
+					rule.FirstMatch = false
+				case "first_match":
+					rule.FirstMatch = true
+				default:
+					return rules, c.Errf("unknown access policy '%s': must be deny_wins or first_match", c.Val())
+				}
+				if c.NextArg() {
+					return rules, c.ArgErr()
+				}
+			case "log_denied":
+				if c.NextArg() {
+					return rules, c.ArgErr()
+				}
+				rule.LogDenied = true
+			default:
+				return rules, c.Errf("unknown access property '%s'", what)
+			}
+		}
+
+		if len(rule.Entries) == 0 {
+			return rules, c.Err("access: at least one allow or deny is required")
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+// parseRange parses an IP address, a CIDR range, or "all"
+// for every IPv4 and IPv6 address.
+func parseRange(s string) ([]*net.IPNet, error) {
+	if s == "all" {
+		return []*net.IPNet{
+			{IP: net.IPv4zero.To4(), Mask: net.CIDRMask(0, 32)},
+			{IP: net.IPv6zero, Mask: net.CIDRMask(0, 128)},
+		}, nil
+	}
+	ipnet, err := httpserver.ParseCIDR(s)
+	if err != nil {
+		return nil, err
+	}
+	return []*net.IPNet{ipnet}, nil
+}
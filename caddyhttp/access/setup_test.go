@@ -0,0 +1,117 @@
+package access
+
+import (
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func TestSetup(t *testing.T) {
+	c := caddy.NewTestController("http", `access /admin {
+		allow 10.0.0.0/8
+	}`)
+	err := setup(c)
+	if err != nil {
+		t.Errorf("Expected no errors, got: %v", err)
+	}
+	mids := httpserver.GetConfig(c).Middleware()
+	if len(mids) == 0 {
+		t.Fatal("Expected middleware, got 0 instead")
+	}
+
+	handler := mids[0](httpserver.EmptyNext)
+	myHandler, ok := handler.(Access)
+	if !ok {
+		t.Fatalf("Expected handler to be type Access, got: %#v", handler)
+	}
+	if len(myHandler.Rules) != 1 || myHandler.Rules[0].Path != "/admin" {
+		t.Errorf("Expected one rule for /admin, got %+v", myHandler.Rules)
+	}
+	if !httpserver.SameNext(myHandler.Next, httpserver.EmptyNext) {
+		t.Error("'Next' field of handler was not set properly")
+	}
+}
+
+func TestAccessParse(t *testing.T) {
+	for i, test := range []struct {
+		input     string
+		shouldErr bool
+		expected  []Rule
+	}{
+		{`access {
+			allow 10.0.0.0/8 192.168.1.1
+			deny 10.0.0.5
+		}`, false, []Rule{{
+			Path: "/",
+			Entries: []Entry{
+				{Allow: true, IPNet: &net.IPNet{IP: net.IP{10, 0, 0, 0}, Mask: net.CIDRMask(8, 32)}},
+				{Allow: true, IPNet: &net.IPNet{IP: net.IP{192, 168, 1, 1}, Mask: net.CIDRMask(32, 32)}},
+				{Allow: false, IPNet: &net.IPNet{IP: net.IP{10, 0, 0, 5}, Mask: net.CIDRMask(32, 32)}},
+			},
+		}}},
+		{`access /admin {
+			allow ::1
+			deny all
+			policy first_match
+			log_denied
+		}
+		access /api {
+			deny 2001:db8::/32
+		}`, false, []Rule{{
+			Path: "/admin",
+			Entries: []Entry{
+				{Allow: true, IPNet: &net.IPNet{IP: net.IPv6loopback, Mask: net.CIDRMask(128, 128)}},
+				{Allow: false, IPNet: &net.IPNet{IP: net.IP{0, 0, 0, 0}, Mask: net.CIDRMask(0, 32)}},
+				{Allow: false, IPNet: &net.IPNet{IP: net.IPv6zero, Mask: net.CIDRMask(0, 128)}},
+			},
+			FirstMatch: true,
+			LogDenied:  true,
+		}, {
+			Path: "/api",
+			Entries: []Entry{
+				{Allow: false, IPNet: &net.IPNet{IP: net.ParseIP("2001:db8::"), Mask: net.CIDRMask(32, 128)}},
+			},
+		}}},
+		{`access`, true, nil},
+		{`access /a /b {
+			deny all
+		}`, true, nil},
+		{`access {
+			allow
+		}`, true, nil},
+		{`access {
+			allow 10.0.0.0/33
+		}`, true, nil},
+		{`access {
+			deny all
+			policy
+		}`, true, nil},
+		{`access {
+			deny all
+			policy allow_wins
+		}`, true, nil},
+		{`access {
+			deny all
+			log_denied yes
+		}`, true, nil},
+		{`access {
+			permit all
+		}`, true, nil},
+	} {
+		actual, err := accessParse(caddy.NewTestController("http", test.input))
+		if err == nil && test.shouldErr {
+			t.Errorf("Test %d didn't error, but it should have", i)
+		} else if err != nil && !test.shouldErr {
+			t.Errorf("Test %d errored, but it shouldn't have; got '%v'", i, err)
+		}
+		if test.shouldErr {
+			continue
+		}
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("Test %d: Expected rules %+v, got %+v", i, test.expected, actual)
+		}
+	}
+}
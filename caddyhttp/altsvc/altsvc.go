@@ -0,0 +1,100 @@
+// Package altsvc is middleware that adds the Alt-Svc header to
+// HTTPS responses, to advertise alternative services such as an
+// HTTP/3 listener to clients.
+package altsvc
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+// AltSvc is middleware that advertises alternative services for
+// the site. Like HSTS, the header is only sent over HTTPS, as
+// clients may not trust alternatives advertised over plaintext.
+// Alternatives that the response advertises already, from a proxy
+// backend for example, are kept.
+type AltSvc struct {
+	Next         httpserver.Handler
+	Alternatives []Alternative
+}
+
+// Alternative is an alternative service, such as h3 on :443.
+type Alternative struct {
+	Protocol  string        // the ALPN protocol ID, such as h3
+	Authority string        // [host]:port
+	MaxAge    time.Duration // how long clients may remember it; 0 for their default of 24 hours
+}
+
+// String returns the alternative as it is in the header,
+// for example h3=":443"; ma=86400.
+func (a Alternative) String() string {
+	s := a.key()
+	if a.MaxAge > 0 {
+		s += "; ma=" + strconv.FormatInt(int64(a.MaxAge/time.Second), 10)
+	}
+	return s
+}
+
+// key returns the protocol and authority of a, which
+// identify it in the header.
+func (a Alternative) key() string {
+	return a.Protocol + "=" + strconv.Quote(a.Authority)
+}
+
+// ServeHTTP implements the httpserver.Handler interface.
+func (a AltSvc) ServeHTTP(w http.ResponseWriter, r *http.Request) (int, error) {
+	if r.TLS == nil {
+		return a.Next.ServeHTTP(w, r)
+	}
+
+	// the header is set when the response header is written, so
+	// that headers set by later handlers are known by then, merged
+	// with the one of the response if it has one
+	setHeader := func(int) {
+		w.Header().Set("Alt-Svc", merge(w.Header()["Alt-Svc"], a.Alternatives))
+	}
+	aw := httpserver.NewHeaderWriter(w, setHeader)
+	status, err := a.Next.ServeHTTP(aw, r)
+	if !aw.WroteHeader() {
+		// the response is written further up the
+		// chain, if at all (error pages for example)
+		setHeader(status)
+	}
+	return status, err
+}
+
+// merge returns the Alt-Svc header value that advertises the
+// alternatives of the header values existing and alternatives.
+// An alternative in both is advertised as it is in existing,
+// and if existing clears the alternatives, it is kept as is.
+func merge(existing []string, alternatives []Alternative) string {
+	var values []string
+	advertised := make(map[string]bool)
+	for _, value := range existing {
+		for _, alt := range strings.Split(value, ",") {
+			alt = strings.TrimSpace(alt)
+			if alt == "" {
+				continue
+			}
+			if alt == "clear" {
+				return alt
+			}
+			values = append(values, alt)
+			key := alt
+			if i := strings.Index(alt, ";"); i >= 0 {
+				key = strings.TrimSpace(alt[:i])
+			}
+			advertised[key] = true
+		}
+	}
+	for _, alt := range alternatives {
+		if !advertised[alt.key()] {
+			values = append(values, alt.String())
+		}
+	}
+	return strings.Join(values, ", ")
+}
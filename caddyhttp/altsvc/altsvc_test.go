@@ -0,0 +1,78 @@
+package altsvc
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func TestAltSvc(t *testing.T) {
+	h3 := []Alternative{{Protocol: "h3", Authority: ":443", MaxAge: 24 * time.Hour}}
+
+	for i, test := range []struct {
+		alternatives []Alternative
+		tls          bool
+		header       []string // the response's own
+		status       int      // returned by the next handler instead of writing
+		expected     []string
+	}{
+		{h3, true, nil, 0, []string{`h3=":443"; ma=86400`}},
+		{[]Alternative{{Protocol: "h3", Authority: ":443"}, {Protocol: "h3-29", Authority: "alt.example.com:8443"}}, true, nil, 0,
+			[]string{`h3=":443", h3-29="alt.example.com:8443"`}},
+
+		// never over plaintext
+		{h3, false, nil, 0, nil},
+
+		// merged with the response's own header
+		{h3, true, []string{`h2="alt.example.com:443"`}, 0,
+			[]string{`h2="alt.example.com:443", h3=":443"; ma=86400`}},
+		{h3, true, []string{`h2=":8443"; ma=60, quic=":443"`, `h3-29=":443"`}, 0,
+			[]string{`h2=":8443"; ma=60, quic=":443", h3-29=":443", h3=":443"; ma=86400`}},
+		// without duplicating alternatives or clearing them
+		{h3, true, []string{`h3=":443"; ma=60`}, 0, []string{`h3=":443"; ma=60`}},
+		{h3, true, []string{"clear"}, 0, []string{"clear"}},
+
+		// responses written further up the chain get it too
+		{h3, true, nil, http.StatusNotFound, []string{`h3=":443"; ma=86400`}},
+	} {
+		a := AltSvc{
+			Next: httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+				if test.status != 0 {
+					return test.status, nil
+				}
+				if test.header != nil {
+					w.Header()["Alt-Svc"] = test.header
+				}
+				w.Write([]byte("body"))
+				return 0, nil
+			}),
+			Alternatives: test.alternatives,
+		}
+		req, err := http.NewRequest("GET", "/", nil)
+		if err != nil {
+			t.Fatalf("Test %d: Could not create HTTP request: %v", i, err)
+		}
+		if test.tls {
+			req.TLS = new(tls.ConnectionState)
+		}
+		rec := httptest.NewRecorder()
+
+		if _, err := a.ServeHTTP(rec, req); err != nil {
+			t.Errorf("Test %d: Expected no error, got %v", i, err)
+		}
+
+		got := rec.HeaderMap["Alt-Svc"]
+		if len(got) != len(test.expected) {
+			t.Fatalf("Test %d: Expected %q, got %q", i, test.expected, got)
+		}
+		for j := range got {
+			if got[j] != test.expected[j] {
+				t.Errorf("Test %d: Expected %q, got %q", i, test.expected, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package altsvc
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func init() {
+	caddy.RegisterPlugin("alt_svc", caddy.Plugin{
+		ServerType: "http",
+		Action:     setup,
+	})
+}
+
+// setup configures a new AltSvc middleware instance.
+func setup(c *caddy.Controller) error {
+	alts, err := altSvcParse(c)
+	if err != nil {
+		return err
+	}
+
+	httpserver.GetConfig(c).AddMiddleware(func(next httpserver.Handler) httpserver.Handler {
+		return AltSvc{Next: next, Alternatives: alts}
+	})
+
+	return nil
+}
+
+// altSvcParse parses the directive, once per alternative:
+//
+//	alt_svc protocol [host]:port [max_age]
+//
+// For example, alt_svc h3 :443 24h advertises HTTP/3 on
+// port 443 of the same host for a day.
+func altSvcParse(c *caddy.Controller) ([]Alternative, error) {
+	var alts []Alternative
+
+	for c.Next() {
+		args := c.RemainingArgs()
+		if len(args) < 2 || len(args) > 3 {
+			return nil, c.ArgErr()
+		}
+
+		alt := Alternative{Protocol: args[0], Authority: args[1]}
+		if !validProtocol(alt.Protocol) {
+			return nil, c.Errf("invalid protocol ID '%s'", alt.Protocol)
+		}
+		_, port, err := net.SplitHostPort(alt.Authority)
+		if err != nil || strings.ContainsAny(alt.Authority, `"\`) {
+			return nil, c.Errf("alternative must be [host]:port, got '%s'", alt.Authority)
+		}
+		if p, err := strconv.Atoi(port); err != nil || p < 1 || p > 65535 {
+			return nil, c.Errf("invalid port '%s'", port)
+		}
+		if len(args) == 3 {
+			maxAge, err := httpserver.ParseMaxAge(args[2])
+			if err != nil {
+				return nil, c.Err(err.Error())
+			}
+			if maxAge < time.Second {
+				return nil, c.Errf("max_age must be at least a second, got '%s'", args[2])
+			}
+			alt.MaxAge = maxAge
+		}
+
+		alts = append(alts, alt)
+	}
+
+	return alts, nil
+}
+
+// validProtocol reports whether id can be used as a protocol
+// ID in the header as it is: a token without the % that would
+// start a percent-encoded character.
+func validProtocol(id string) bool {
+	if id == "" {
+		return false
+	}
+	for _, c := range id {
+		if c <= ' ' || c >= 0x7f || strings.ContainsRune(`"(),/:;<=>?@[\]{}%`, c) {
+			return false
+		}
+	}
+	return true
+}
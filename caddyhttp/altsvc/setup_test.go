@@ -0,0 +1,67 @@
+package altsvc
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func TestSetup(t *testing.T) {
+	c := caddy.NewTestController("http", `alt_svc h3 :443`)
+	err := setup(c)
+	if err != nil {
+		t.Errorf("Expected no errors, got: %v", err)
+	}
+	mids := httpserver.GetConfig(c).Middleware()
+	if len(mids) == 0 {
+		t.Fatal("Expected middleware, got 0 instead")
+	}
+
+	handler := mids[0](httpserver.EmptyNext)
+	myHandler, ok := handler.(AltSvc)
+	if !ok {
+		t.Fatalf("Expected handler to be type AltSvc, got: %#v", handler)
+	}
+	if !httpserver.SameNext(myHandler.Next, httpserver.EmptyNext) {
+		t.Error("'Next' field of handler was not set properly")
+	}
+}
+
+func TestAltSvcParse(t *testing.T) {
+	for i, test := range []struct {
+		input     string
+		shouldErr bool
+		expected  []Alternative
+	}{
+		{`alt_svc h3 :443`, false, []Alternative{{Protocol: "h3", Authority: ":443"}}},
+		{`alt_svc h3 :443 86400`, false, []Alternative{{Protocol: "h3", Authority: ":443", MaxAge: 24 * time.Hour}}},
+		{"alt_svc h3 :443 24h\nalt_svc h3-29 [::1]:8443 90.5s", false, []Alternative{
+			{Protocol: "h3", Authority: ":443", MaxAge: 24 * time.Hour},
+			{Protocol: "h3-29", Authority: "[::1]:8443", MaxAge: 90 * time.Second},
+		}},
+		{`alt_svc`, true, nil},
+		{`alt_svc h3`, true, nil},
+		{`alt_svc h3 :443 24h extra`, true, nil},
+		{`alt_svc h3 443`, true, nil},
+		{`alt_svc h3 :0`, true, nil},
+		{`alt_svc h3 :https`, true, nil},
+		{`alt_svc h3%20 :443`, true, nil},
+		{`alt_svc h3=x :443`, true, nil},
+		{`alt_svc h3 :443 0`, true, nil},
+		{`alt_svc h3 :443 500ms`, true, nil},
+		{`alt_svc h3 :443 forever`, true, nil},
+	} {
+		actual, err := altSvcParse(caddy.NewTestController("http", test.input))
+		if err == nil && test.shouldErr {
+			t.Errorf("Test %d didn't error, but it should have", i)
+		} else if err != nil && !test.shouldErr {
+			t.Errorf("Test %d errored, but it shouldn't have; got '%v'", i, err)
+		}
+		if !test.shouldErr && !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("Test %d: Expected %+v, got %+v", i, test.expected, actual)
+		}
+	}
+}
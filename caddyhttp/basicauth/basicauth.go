@@ -13,11 +13,11 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 	"sync"
 
-	"github.com/jimstudt/http-authentication/basic"
 	"github.com/mholt/caddy/caddyhttp/httpserver"
 )
 
@@ -37,30 +37,33 @@ func (a BasicAuth) ServeHTTP(w http.ResponseWriter, r *http.Request) (int, error
 	var protected, isAuthenticated bool
 
 	for _, rule := range a.Rules {
-		for _, res := range rule.Resources {
-			if !httpserver.Path(r.URL.Path).Matches(res) {
-				continue
-			}
+		if !rule.Protects(r.URL.Path) {
+			continue
+		}
 
-			// path matches; this endpoint is protected
-			protected = true
+		// path matches; this endpoint is protected
+		protected = true
 
-			// parse auth header
-			username, password, ok := r.BasicAuth()
+		// parse auth header
+		username, password, ok := r.BasicAuth()
 
-			// check credentials
-			if !ok ||
-				username != rule.Username ||
-				!rule.Password(password) {
+		// check credentials
+		if !ok {
+			continue
+		}
+		if rule.Users != nil {
+			if !rule.Users.Authenticate(username, password) {
 				continue
 			}
+		} else if username != rule.Username || !rule.Password(password) {
+			continue
+		}
 
-			// by this point, authentication was successful
-			isAuthenticated = true
+		// by this point, authentication was successful
+		isAuthenticated = true
 
-			// remove credentials from request to avoid leaking upstream
-			r.Header.Del("Authorization")
-		}
+		// remove credentials from request to avoid leaking upstream
+		r.Header.Del("Authorization")
 	}
 
 	if protected && !isAuthenticated {
@@ -77,11 +80,48 @@ func (a BasicAuth) ServeHTTP(w http.ResponseWriter, r *http.Request) (int, error
 
 // Rule represents a BasicAuth rule. A username and password
 // combination protect the associated resources, which are
-// file or directory paths.
+// file or directory paths. If Users is set, any user in it
+// may access the resources instead. Paths under a resource
+// that match one of the Exceptions are left unprotected.
 type Rule struct {
-	Username  string
-	Password  func(string) bool
-	Resources []string
+	Username   string
+	Password   func(string) bool
+	Users      *HtpasswdFile
+	Resources  []string
+	Exceptions []string
+}
+
+// Protects reports whether the rule protects the request path
+// p. The longest resource or exception that p starts with
+// decides, so a resource may be nested inside an exception.
+// Exceptions only match whole path segments, so that except
+// /public leaves /publicity protected. The cleaned path is
+// checked as well, so that an exception can't be reached
+// through a path like /public/../private.
+func (r Rule) Protects(p string) bool {
+	return r.protects(p) || r.protects(path.Clean("/"+p))
+}
+
+func (r Rule) protects(p string) bool {
+	resource := longestMatch(p, r.Resources, false)
+	return resource >= 0 && resource > longestMatch(p, r.Exceptions, true)
+}
+
+// longestMatch returns the length of the longest of prefixes
+// that p matches, or -1 if it matches none of them. With
+// segments, a prefix must end where a path segment of p ends.
+func longestMatch(p string, prefixes []string, segments bool) int {
+	longest := -1
+	for _, prefix := range prefixes {
+		if len(prefix) <= longest || !httpserver.Path(p).Matches(prefix) {
+			continue
+		}
+		if segments && len(p) > len(prefix) && !strings.HasSuffix(prefix, "/") && p[len(prefix)] != '/' {
+			continue
+		}
+		longest = len(prefix)
+	}
+	return longest
 }
 
 // PasswordMatcher determines whether a password matches a rule.
@@ -131,15 +171,12 @@ func parseHtpasswd(pm map[string]PasswordMatcher, r io.Reader) error {
 			return fmt.Errorf("malformed line, no color: %q", line)
 		}
 		user, encoded := line[:i], line[i+1:]
-		for _, p := range basic.DefaultSystems {
-			matcher, err := p(encoded)
-			if err != nil {
-				return err
-			}
-			if matcher != nil {
-				pm[user] = matcher.MatchesPassword
-				break
-			}
+		matcher, err := hashMatcher(encoded)
+		if err != nil {
+			return err
+		}
+		if matcher != nil {
+			pm[user] = matcher
 		}
 	}
 	return scanner.Err()
@@ -150,3 +150,60 @@ md5:$apr1$l42y8rex$pOA2VJ0x/0TwaFeAF9nX61`
 		}
 	}
 }
+
+func TestBasicAuthExceptions(t *testing.T) {
+	rw := BasicAuth{
+		Next: httpserver.HandlerFunc(contentHandler),
+		Rules: []Rule{
+			{
+				Username:   "okuser",
+				Password:   PlainMatcher("okpass"),
+				Resources:  []string{"/admin", "/admin/public/private"},
+				Exceptions: []string{"/admin/public"},
+			},
+		},
+	}
+
+	for i, test := range []struct {
+		path   string
+		auth   bool
+		result int
+	}{
+		{"/admin", false, http.StatusUnauthorized},
+		{"/admin/settings", false, http.StatusUnauthorized},
+		{"/admin/settings", true, http.StatusOK},
+		{"/admin/public", false, http.StatusOK},
+		{"/admin/public/style.css", false, http.StatusOK},
+		{"/admin/publicity", false, http.StatusUnauthorized},
+		{"/admin/public.html", false, http.StatusUnauthorized},
+		{"/admin/public/private", false, http.StatusUnauthorized},
+		{"/admin/public/private/key", false, http.StatusUnauthorized},
+		{"/admin/public/private/key", true, http.StatusOK},
+		{"/admin/public/../settings", false, http.StatusUnauthorized},
+		{"/other", false, http.StatusOK},
+	} {
+		req, err := http.NewRequest("GET", "/", nil)
+		if err != nil {
+			t.Fatalf("Test %d: Could not create HTTP request: %v", i, err)
+		}
+		req.URL.Path = test.path
+		if test.auth {
+			req.SetBasicAuth("okuser", "okpass")
+		}
+
+		rec := httptest.NewRecorder()
+		result, err := rw.ServeHTTP(rec, req)
+		if err != nil {
+			t.Fatalf("Test %d: Could not ServeHTTP: %v", i, err)
+		}
+		if result != test.result {
+			t.Errorf("Test %d: Expected status code %d for %s but was %d",
+				i, test.result, test.path, result)
+		}
+		challenged := rec.Header().Get("Www-Authenticate") != ""
+		if challenged != (test.result == http.StatusUnauthorized) {
+			t.Errorf("Test %d: Expected Www-Authenticate header only for a 401, got %q for %s",
+				i, rec.Header().Get("Www-Authenticate"), test.path)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package basicauth
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jimstudt/http-authentication/basic"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// htpasswdReloadInterval is how often, at most, an HtpasswdFile
+// checks whether the file on disk has changed.
+var htpasswdReloadInterval = time.Second
+
+// HtpasswdFile is a set of users loaded from an htpasswd file.
+// The file is reloaded when it changes on disk; requests that
+// are being authenticated during a reload keep using the users
+// that were loaded before it.
+type HtpasswdFile struct {
+	Path string
+
+	mu      sync.RWMutex
+	users   map[string]PasswordMatcher
+	dummy   PasswordMatcher
+	modTime time.Time
+	size    int64
+	checked time.Time
+}
+
+// NewHtpasswdFile loads the htpasswd file at path.
+func NewHtpasswdFile(path string) (*HtpasswdFile, error) {
+	f := &HtpasswdFile{Path: path}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.load(info); err != nil {
+		return nil, err
+	}
+	f.checked = time.Now()
+	return f, nil
+}
+
+// Authenticate reports whether password is the password of
+// username. Passwords of unknown users are still checked, against
+// a known user's hash, so that both take about the same time.
+func (f *HtpasswdFile) Authenticate(username, password string) bool {
+	f.reloadIfChanged()
+
+	f.mu.RLock()
+	match, ok := f.users[username]
+	dummy := f.dummy
+	f.mu.RUnlock()
+
+	if !ok {
+		if dummy != nil {
+			dummy(password)
+		}
+		return false
+	}
+	return match(password)
+}
+
+// reloadIfChanged reloads the file if it was modified since
+// it was loaded. It checks at most once per reload interval.
+func (f *HtpasswdFile) reloadIfChanged() {
+	now := time.Now()
+	f.mu.Lock()
+	due := now.Sub(f.checked) >= htpasswdReloadInterval
+	if due {
+		f.checked = now
+	}
+	modTime, size := f.modTime, f.size
+	f.mu.Unlock()
+	if !due {
+		return
+	}
+
+	info, err := os.Stat(f.Path)
+	if err != nil {
+		log.Printf("[ERROR] basicauth: %v; keeping previously loaded users", err)
+		return
+	}
+	if info.ModTime().Equal(modTime) && info.Size() == size {
+		return
+	}
+	if err := f.load(info); err != nil {
+		log.Printf("[ERROR] basicauth: reloading %s: %v; keeping previously loaded users", f.Path, err)
+	}
+}
+
+// load reads the file and replaces the users with the ones it
+// contains. info is the state of the file it is loaded from.
+func (f *HtpasswdFile) load(info os.FileInfo) error {
+	fh, err := os.Open(f.Path)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+
+	users, first, err := readHtpasswd(fh, f.Path)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	f.users = users
+	f.dummy = users[first]
+	f.modTime = info.ModTime()
+	f.size = info.Size()
+	f.mu.Unlock()
+	return nil
+}
+
+// readHtpasswd reads the users from an htpasswd file named name.
+// Malformed lines and unsupported hashes are skipped with a warning.
+// It also returns the first user in the file, if any.
+func readHtpasswd(r io.Reader, name string) (map[string]PasswordMatcher, string, error) {
+	users := make(map[string]PasswordMatcher)
+	var first string
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.IndexByte(line, '#') == 0 {
+			continue
+		}
+		i := strings.IndexByte(line, ':')
+		if i <= 0 {
+			log.Printf("[WARNING] basicauth: %s:%d: malformed line, no colon; skipping", name, lineNum)
+			continue
+		}
+		user, encoded := line[:i], line[i+1:]
+		matcher, err := hashMatcher(encoded)
+		if err != nil {
+			log.Printf("[WARNING] basicauth: %s:%d: %v; skipping", name, lineNum, err)
+			continue
+		}
+		if matcher == nil {
+			log.Printf("[WARNING] basicauth: %s:%d: unsupported password hash; skipping", name, lineNum)
+			continue
+		}
+		if len(users) == 0 {
+			first = user
+		}
+		users[user] = matcher
+	}
+	return users, first, scanner.Err()
+}
+
+// hashMatcher returns a PasswordMatcher for the encoded password
+// of an htpasswd line, or nil if its hash is not supported.
+func hashMatcher(encoded string) (PasswordMatcher, error) {
+	if isBcrypt(encoded) {
+		return bcryptMatcher(encoded)
+	}
+	for _, p := range basic.DefaultSystems {
+		matcher, err := p(encoded)
+		if err != nil {
+			return nil, err
+		}
+		if matcher != nil {
+			return matcher.MatchesPassword, nil
+		}
+	}
+	return nil, nil
+}
+
+// isBcrypt reports whether encoded looks like a bcrypt hash.
+func isBcrypt(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") ||
+		strings.HasPrefix(encoded, "$2b$") ||
+		strings.HasPrefix(encoded, "$2y$")
+}
+
+// bcryptMatcher returns a PasswordMatcher that checks passwords
+// against the bcrypt hash encoded.
+func bcryptMatcher(encoded string) (PasswordMatcher, error) {
+	hash := []byte(encoded)
+	if _, err := bcrypt.Cost(hash); err != nil {
+		return nil, fmt.Errorf("bcrypt hash: %v", err)
+	}
+	return func(pw string) bool {
+		return bcrypt.CompareHashAndPassword(hash, []byte(pw)) == nil
+	}, nil
+}
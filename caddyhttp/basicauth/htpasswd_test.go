@@ -0,0 +1,178 @@
+package basicauth
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// writeHtpasswd writes a temporary htpasswd file with the given
+// lines, which may contain {user:password} to be replaced with
+// user's bcrypt hash of password.
+func writeHtpasswd(t *testing.T, name string, lines ...string) {
+	for i, line := range lines {
+		if strings.HasPrefix(line, "{") && strings.HasSuffix(line, "}") {
+			parts := strings.SplitN(line[1:len(line)-1], ":", 2)
+			hash, err := bcrypt.GenerateFromPassword([]byte(parts[1]), bcrypt.MinCost)
+			if err != nil {
+				t.Fatal(err)
+			}
+			lines[i] = parts[0] + ":" + string(hash)
+		}
+	}
+	if err := ioutil.WriteFile(name, []byte(strings.Join(lines, "\n")), 0600); err != nil {
+		t.Fatalf("write htpasswd file %q: %v", name, err)
+	}
+}
+
+func tempHtpasswd(t *testing.T, lines ...string) string {
+	fh, err := ioutil.TempFile("", "basicauth-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fh.Close()
+	writeHtpasswd(t, fh.Name(), lines...)
+	return fh.Name()
+}
+
+func TestHtpasswdFile(t *testing.T) {
+	name := tempHtpasswd(t,
+		"# users",
+		"{alice:wonderland}",
+		"malformed line",
+		":nouser",
+		"carol:$2a$04$notavalidhash",
+		"{bob:builder}",
+	)
+	defer os.Remove(name)
+
+	f, err := NewHtpasswdFile(name)
+	if err != nil {
+		t.Fatalf("NewHtpasswdFile: %v", err)
+	}
+
+	for i, test := range []struct {
+		user, password string
+		expect         bool
+	}{
+		{"alice", "wonderland", true},
+		{"bob", "builder", true},
+		{"alice", "builder", false},
+		{"alice", "", false},
+		{"bob", "wonderland", false},
+		{"Alice", "wonderland", false},
+		{"carol", "", false},
+		{"mallory", "wonderland", false},
+		{"", "", false},
+	} {
+		if got := f.Authenticate(test.user, test.password); got != test.expect {
+			t.Errorf("Test %d: Expected %s:%s to authenticate %v, got %v",
+				i, test.user, test.password, test.expect, got)
+		}
+	}
+	if len(f.users) != 2 {
+		t.Errorf("Expected malformed lines to be skipped and 2 users loaded, got %d", len(f.users))
+	}
+}
+
+func TestHtpasswdFileBcryptVariant(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// htpasswd -B writes $2y$ hashes
+	name := tempHtpasswd(t, "user:$2y$"+string(hash[4:]))
+	defer os.Remove(name)
+
+	f, err := NewHtpasswdFile(name)
+	if err != nil {
+		t.Fatalf("NewHtpasswdFile: %v", err)
+	}
+	if !f.Authenticate("user", "secret") {
+		t.Error("Expected $2y$ hash to match its password")
+	}
+}
+
+func TestHtpasswdFileReload(t *testing.T) {
+	defer func(d time.Duration) { htpasswdReloadInterval = d }(htpasswdReloadInterval)
+	htpasswdReloadInterval = 0
+
+	name := tempHtpasswd(t, "{alice:wonderland}")
+	defer os.Remove(name)
+
+	f, err := NewHtpasswdFile(name)
+	if err != nil {
+		t.Fatalf("NewHtpasswdFile: %v", err)
+	}
+	if !f.Authenticate("alice", "wonderland") {
+		t.Fatal("Expected alice to authenticate before reload")
+	}
+
+	writeHtpasswd(t, name, "{alice:looking-glass}", "{bob:builder}")
+	if f.Authenticate("alice", "wonderland") {
+		t.Error("Expected alice's old password to be rejected after reload")
+	}
+	if !f.Authenticate("alice", "looking-glass") || !f.Authenticate("bob", "builder") {
+		t.Error("Expected users of the changed file to authenticate after reload")
+	}
+
+	// a file that can no longer be read keeps the loaded users
+	os.Remove(name)
+	if !f.Authenticate("bob", "builder") {
+		t.Error("Expected users to be kept when the file disappears")
+	}
+}
+
+func TestBasicAuthHtpasswdFile(t *testing.T) {
+	name := tempHtpasswd(t, "{alice:wonderland}", "{bob:builder}")
+	defer os.Remove(name)
+
+	users, err := NewHtpasswdFile(name)
+	if err != nil {
+		t.Fatalf("NewHtpasswdFile: %v", err)
+	}
+	rw := BasicAuth{
+		Next: httpserver.HandlerFunc(contentHandler),
+		Rules: []Rule{
+			{Users: users, Resources: []string{"/testing"}},
+		},
+	}
+
+	for i, test := range []struct {
+		from     string
+		result   int
+		user     string
+		password string
+	}{
+		{"/testing", http.StatusOK, "alice", "wonderland"},
+		{"/testing", http.StatusOK, "bob", "builder"},
+		{"/testing", http.StatusUnauthorized, "alice", "builder"},
+		{"/testing", http.StatusUnauthorized, "mallory", "wonderland"},
+		{"/testing", http.StatusUnauthorized, "", ""},
+		{"/other", http.StatusOK, "", ""},
+	} {
+		req, err := http.NewRequest("GET", test.from, nil)
+		if err != nil {
+			t.Fatalf("Test %d: Could not create HTTP request: %v", i, err)
+		}
+		if test.user != "" {
+			req.SetBasicAuth(test.user, test.password)
+		}
+
+		rec := httptest.NewRecorder()
+		result, err := rw.ServeHTTP(rec, req)
+		if err != nil {
+			t.Fatalf("Test %d: Could not ServeHTTP: %v", i, err)
+		}
+		if result != test.result {
+			t.Errorf("Test %d: Expected status code %d but was %d", i, test.result, result)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package basicauth
 
 import (
+	"path/filepath"
 	"strings"
 
 	"github.com/mholt/caddy"
@@ -35,6 +36,22 @@ func setup(c *caddy.Controller) error {
 	return nil
 }
 
+// basicAuthParse parses the basicauth directive:
+//
+//	basicauth user password {
+//		resources...
+//		except paths...
+//	}
+//	basicauth resource user password
+//	basicauth resource {
+//		htpasswd file
+//		except   paths...
+//	}
+//
+// The password may be htpasswd=file to look up the user in an
+// htpasswd file. In the last form, any user in the file may
+// access the resource. Paths under a resource that are listed
+// after except remain public.
 func basicAuthParse(c *caddy.Controller) ([]Rule, error) {
 	var rules []Rule
 	cfg := httpserver.GetConfig(c)
@@ -46,6 +63,38 @@ func basicAuthParse(c *caddy.Controller) ([]Rule, error) {
 		args := c.RemainingArgs()
 
 		switch len(args) {
+		case 1:
+			rule.Resources = append(rule.Resources, args[0])
+			for c.NextBlock() {
+				switch c.Val() {
+				case "htpasswd":
+					if !c.NextArg() {
+						return rules, c.ArgErr()
+					}
+					if rule.Users != nil {
+						return rules, c.Err("htpasswd file already specified")
+					}
+					filename := c.Val()
+					if !filepath.IsAbs(filename) {
+						filename = filepath.Join(cfg.Root, filename)
+					}
+					if rule.Users, err = NewHtpasswdFile(filename); err != nil {
+						return rules, c.Errf("Loading htpasswd file: %v", err)
+					}
+					if c.NextArg() {
+						return rules, c.ArgErr()
+					}
+				case "except":
+					if err := parseExceptions(c, &rule); err != nil {
+						return rules, err
+					}
+				default:
+					return rules, c.Errf("Unknown basicauth property '%s'", c.Val())
+				}
+			}
+			if rule.Users == nil {
+				return rules, c.Err("Expecting an htpasswd file for the resource")
+			}
 		case 2:
 			rule.Username = args[0]
 			if rule.Password, err = passwordMatcher(rule.Username, args[1], cfg.Root); err != nil {
@@ -53,6 +102,12 @@ func basicAuthParse(c *caddy.Controller) ([]Rule, error) {
 			}
 
 			for c.NextBlock() {
+				if c.Val() == "except" {
+					if err := parseExceptions(c, &rule); err != nil {
+						return rules, err
+					}
+					continue
+				}
 				rule.Resources = append(rule.Resources, c.Val())
 				if c.NextArg() {
 					return rules, c.Errf("Expecting only one resource per line (extra '%s')", c.Val())
@@ -74,6 +129,17 @@ func basicAuthParse(c *caddy.Controller) ([]Rule, error) {
 	return rules, nil
 }
 
+// parseExceptions adds the paths on the current line of c
+// to the exceptions of rule.
+func parseExceptions(c *caddy.Controller, rule *Rule) error {
+	paths := c.RemainingArgs()
+	if len(paths) == 0 {
+		return c.ArgErr()
+	}
+	rule.Exceptions = append(rule.Exceptions, paths...)
+	return nil
+}
+
 func passwordMatcher(username, passw, siteRoot string) (PasswordMatcher, error) {
 	if !strings.HasPrefix(passw, "htpasswd=") {
 		return PlainMatcher(passw), nil
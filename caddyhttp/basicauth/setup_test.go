@@ -78,6 +78,16 @@ md5:$apr1$l42y8rex$pOA2VJ0x/0TwaFeAF9nX61`
 			{Username: "user1", Resources: []string{"/res1"}},
 			{Username: "user2", Resources: []string{"/res2"}},
 		}},
+		{`basicauth user pwd {
+			/admin
+			except /admin/public /admin/assets
+		}`, false, "pwd", []Rule{
+			{Username: "user", Resources: []string{"/admin"}, Exceptions: []string{"/admin/public", "/admin/assets"}},
+		}},
+		{`basicauth user pwd {
+			/admin
+			except
+		}`, true, "", []Rule{}},
 		{`basicauth user`, true, "", []Rule{}},
 		{`basicauth`, true, "", []Rule{}},
 		{`basicauth /resource user pwd asdf`, true, "", []Rule{}},
@@ -127,6 +137,51 @@ md5:$apr1$l42y8rex$pOA2VJ0x/0TwaFeAF9nX61`
 				t.Errorf("Test %d, rule %d: Expected resource list %s, but got %s",
 					i, j, expectedRes, actualRes)
 			}
+
+			expectedExc := fmt.Sprintf("%v", expectedRule.Exceptions)
+			actualExc := fmt.Sprintf("%v", actualRule.Exceptions)
+			if actualExc != expectedExc {
+				t.Errorf("Test %d, rule %d: Expected exceptions %s, but got %s",
+					i, j, expectedExc, actualExc)
+			}
+		}
+	}
+}
+
+func TestBasicAuthParseHtpasswdFile(t *testing.T) {
+	name := tempHtpasswd(t, "{alice:wonderland}")
+	defer os.Remove(name)
+
+	for i, test := range []struct {
+		input     string
+		shouldErr bool
+	}{
+		{"basicauth /admin {\n\thtpasswd " + name + "\n}", false},
+		{"basicauth /admin {\n\thtpasswd " + name + "\n\thtpasswd " + name + "\n}", true},
+		{"basicauth /admin {\n\thtpasswd\n}", true},
+		{"basicauth /admin {\n\thtpasswd " + name + " extra\n}", true},
+		{"basicauth /admin {\n\thtpasswd " + name + ".missing\n}", true},
+		{"basicauth /admin {\n\thtpasswd " + name + "\n\texcept /admin/public\n}", false},
+		{"basicauth /admin {\n\tusers " + name + "\n}", true},
+		{"basicauth /admin", true},
+	} {
+		rules, err := basicAuthParse(caddy.NewTestController("http", test.input))
+		if err == nil && test.shouldErr {
+			t.Errorf("Test %d didn't error, but it should have", i)
+		} else if err != nil && !test.shouldErr {
+			t.Errorf("Test %d errored, but it shouldn't have; got '%v'", i, err)
+		}
+		if test.shouldErr {
+			continue
+		}
+		if len(rules) != 1 || rules[0].Users == nil {
+			t.Fatalf("Test %d: Expected one rule with an htpasswd file, got %#v", i, rules)
+		}
+		if got := fmt.Sprintf("%v", rules[0].Resources); got != "[/admin]" {
+			t.Errorf("Test %d: Expected resources [/admin], got %s", i, got)
+		}
+		if !rules[0].Users.Authenticate("alice", "wonderland") {
+			t.Errorf("Test %d: Expected alice to authenticate", i)
 		}
 	}
 }
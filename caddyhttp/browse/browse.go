@@ -105,14 +105,17 @@ func (l Listing) BreadcrumbMap() map[string]string {
 	return result
 }
 
-// FileInfo is the info about a particular file or directory
+// FileInfo is the info about a particular file or directory.
+// A symbolic link is described by the file it points to, which
+// is what is served for it, unless the link is broken.
 type FileInfo struct {
-	Name    string
-	Size    int64
-	URL     string
-	ModTime time.Time
-	Mode    os.FileMode
-	IsDir   bool
+	Name      string
+	Size      int64
+	URL       string
+	ModTime   time.Time
+	Mode      os.FileMode
+	IsDir     bool
+	IsSymlink bool
 }
 
 // HumanSize returns the size of the file as a human-readable string
@@ -202,33 +205,42 @@ func directoryListing(files []os.FileInfo, canGoUp bool, urlPath string, config
 	for _, f := range files {
 		name := f.Name()
 
-		for _, indexName := range staticfiles.IndexPages {
+		isSymlink := f.Mode()&os.ModeSymlink != 0
+		if isSymlink {
+			if target, err := statFile(config.Fs.Root, path.Join(urlPath, name)); err == nil {
+				f = target
+			}
+		}
+
+		if config.Fs.IsHidden(f) || config.Fs.IsHiddenPath(path.Join(urlPath, name)) {
+			continue
+		}
+
+		for _, indexName := range config.Fs.Indexes() {
 			if name == indexName {
 				hasIndexFile = true
 				break
 			}
 		}
 
+		href := name
 		if f.IsDir() {
-			name += "/"
+			href += "/"
 			dirCount++
 		} else {
 			fileCount++
 		}
 
-		url := url.URL{Path: "./" + name} // prepend with "./" to fix paths with ':' in the name
-
-		if config.Fs.IsHidden(f) {
-			continue
-		}
+		url := url.URL{Path: "./" + href} // prepend with "./" to fix paths with ':' in the name
 
 		fileinfos = append(fileinfos, FileInfo{
-			IsDir:   f.IsDir(),
-			Name:    f.Name(),
-			Size:    f.Size(),
-			URL:     url.String(),
-			ModTime: f.ModTime().UTC(),
-			Mode:    f.Mode(),
+			IsDir:     f.IsDir(),
+			Name:      name,
+			Size:      f.Size(),
+			URL:       url.String(),
+			ModTime:   f.ModTime().UTC(),
+			Mode:      f.Mode(),
+			IsSymlink: isSymlink,
 		})
 	}
 
@@ -242,6 +254,17 @@ func directoryListing(files []os.FileInfo, canGoUp bool, urlPath string, config
 	}, hasIndexFile
 }
 
+// statFile returns the info of the file name in fs, following
+// symbolic links.
+func statFile(fs http.FileSystem, name string) (os.FileInfo, error) {
+	f, err := fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return f.Stat()
+}
+
 // ServeHTTP determines if the request is for this plugin, and if all prerequisites are met.
 // If so, control is handed over to ServeListing.
 func (b Browse) ServeHTTP(w http.ResponseWriter, r *http.Request) (int, error) {
@@ -256,6 +279,9 @@ func (b Browse) ServeHTTP(w http.ResponseWriter, r *http.Request) (int, error) {
 	if bc == nil {
 		return b.Next.ServeHTTP(w, r)
 	}
+	if bc.Fs.IsHiddenPath(r.URL.Path) {
+		return http.StatusNotFound, nil
+	}
 
 	// Browse works on existing directories; delegate everything else
 	requestedFilepath, err := bc.Fs.Root.Open(r.URL.Path)
@@ -2,6 +2,7 @@ package browse
 
 import (
 	"encoding/json"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -362,3 +363,124 @@ func isReversed(data sort.Interface) bool {
 	}
 	return true
 }
+
+func TestBrowseListingEntries(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "caddy_browse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	dir := filepath.Join(tmp, "files")
+	for _, name := range []string{"sub", ".git"} {
+		if err := os.MkdirAll(filepath.Join(dir, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, content := range map[string]string{
+		"plain.txt":  "plain",
+		"a b#c?.txt": "special",
+		"100%.txt":   "percent",
+		"secret.txt": "hidden",
+		".env":       "hidden by default",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("sub", filepath.Join(dir, "link")); err != nil {
+		t.Skipf("Symlinks not supported: %v", err)
+	}
+	if err := os.Symlink("missing", filepath.Join(dir, "broken")); err != nil {
+		t.Fatal(err)
+	}
+
+	newBrowse := func(tmpl *template.Template) Browse {
+		return Browse{
+			Next: httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+				t.Fatalf("Next shouldn't be called")
+				return 0, nil
+			}),
+			Configs: []Config{{
+				PathScope: "/files",
+				Fs: staticfiles.FileServer{
+					Root: http.Dir(tmp),
+					Hide: []string{"files/secret.txt"},
+				},
+				Template: tmpl,
+			}},
+		}
+	}
+
+	// JSON
+	req, err := http.NewRequest("GET", "/files/?sort=name&order=asc", nil)
+	if err != nil {
+		t.Fatalf("Could not create HTTP request: %v", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+	if code, err := newBrowse(nil).ServeHTTP(rec, req); code != http.StatusOK {
+		t.Fatalf("Wrong status, expected %d, got %d (%v)", http.StatusOK, code, err)
+	}
+
+	var items []FileInfo
+	if err := json.Unmarshal(rec.Body.Bytes(), &items); err != nil {
+		t.Fatalf("Expected a JSON list of entries, got error: %v", err)
+	}
+	expected := []struct {
+		name      string
+		url       string
+		size      int64
+		isDir     bool
+		isSymlink bool
+	}{
+		{"100%.txt", "./100%25.txt", 7, false, false},
+		{"a b#c?.txt", "./a%20b%23c%3F.txt", 7, false, false},
+		{"broken", "./broken", int64(len("missing")), false, true},
+		{"link", "./link/", -1, true, true},
+		{"plain.txt", "./plain.txt", 5, false, false},
+		{"sub", "./sub/", -1, true, false},
+	}
+	if len(items) != len(expected) {
+		t.Fatalf("Expected %d entries, got %d: %+v", len(expected), len(items), items)
+	}
+	for i, e := range expected {
+		item := items[i]
+		if item.Name != e.name || item.URL != e.url || item.IsDir != e.isDir || item.IsSymlink != e.isSymlink {
+			t.Errorf("Entry %d: Expected %+v, got %+v", i, e, item)
+		}
+		if e.size >= 0 && item.Size != e.size {
+			t.Errorf("Entry %d: Expected size %d, got %d", i, e.size, item.Size)
+		}
+		if item.ModTime.IsZero() {
+			t.Errorf("Entry %d: Expected a modification time", i)
+		}
+	}
+
+	// HTML from a custom template
+	tmpl := template.Must(template.New("listing").Parse(
+		`{{.NumDirs}}/{{.NumFiles}}:{{range .Items}} {{.URL}}{{if .IsSymlink}}@{{end}}{{end}}`))
+	req, err = http.NewRequest("GET", "/files/?sort=name&order=asc", nil)
+	if err != nil {
+		t.Fatalf("Could not create HTTP request: %v", err)
+	}
+	rec = httptest.NewRecorder()
+	if code, err := newBrowse(tmpl).ServeHTTP(rec, req); code != http.StatusOK {
+		t.Fatalf("Wrong status, expected %d, got %d (%v)", http.StatusOK, code, err)
+	}
+	if got, want := rec.Body.String(), "2/4: ./100%25.txt ./a%20b%23c%3F.txt ./broken@ ./link/@ ./plain.txt ./sub/"; got != want {
+		t.Errorf("Expected listing %q, got %q", want, got)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "text/html; charset=utf-8" {
+		t.Errorf("Expected HTML content type, got %q", got)
+	}
+
+	// hidden directories can't be browsed
+	req, err = http.NewRequest("GET", "/files/.git/", nil)
+	if err != nil {
+		t.Fatalf("Could not create HTTP request: %v", err)
+	}
+	if code, _ := newBrowse(tmpl).ServeHTTP(httptest.NewRecorder(), req); code != http.StatusNotFound {
+		t.Errorf("Expected status %d for a hidden directory, got %d", http.StatusNotFound, code)
+	}
+}
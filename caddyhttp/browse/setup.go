@@ -3,7 +3,6 @@ package browse
 import (
 	"fmt"
 	"io/ioutil"
-	"net/http"
 	"text/template"
 
 	"github.com/mholt/caddy"
@@ -20,6 +19,10 @@ func init() {
 
 // setup configures a new Browse middleware instance.
 func setup(c *caddy.Controller) error {
+	if err := httpserver.CheckStaticRoot(c, "browse"); err != nil {
+		return err
+	}
+
 	configs, err := browseParse(c)
 	if err != nil {
 		return err
@@ -64,8 +67,15 @@ func browseParse(c *caddy.Controller) ([]Config, error) {
 		}
 
 		bc.Fs = staticfiles.FileServer{
-			Root: http.Dir(cfg.Root),
-			Hide: httpserver.GetConfig(c).HiddenFiles,
+			Root:          cfg.RootFileSys(),
+			Hide:          httpserver.GetConfig(c).HiddenFiles,
+			Precompressed: httpserver.GetConfig(c).Precompressed,
+			IndexPages:    httpserver.GetConfig(c).IndexPages,
+
+			HiddenPatterns:   cfg.HiddenPatterns,
+			HiddenExceptions: cfg.HiddenExceptions,
+			TrailingSlash:    cfg.TrailingSlash,
+			MimeTypes:        cfg.MimeTypes,
 		}
 
 		// Second argument would be the template file to use
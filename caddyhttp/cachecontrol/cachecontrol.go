@@ -0,0 +1,109 @@
+// Package cachecontrol is middleware that sets the Cache-Control
+// header of responses by their content type.
+package cachecontrol
+
+import (
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+// CacheControl is middleware that tells clients how long they may
+// cache responses under certain paths, by the media type of each
+// response, which is only known once the response header is written.
+// Responses that have a Cache-Control or Expires header already, set
+// by a proxy backend or another handler for example, are kept as they
+// are, and so are error responses, so that clients don't keep errors.
+type CacheControl struct {
+	Next  httpserver.Handler
+	Paths []string
+	Rules []Rule
+}
+
+// Rule is the Cache-Control header of responses of a media type.
+type Rule struct {
+	// Type is a media type, like text/html, all the media types
+	// of a type, like image/*, or * for any response, including
+	// those without a Content-Type.
+	Type string
+
+	// Value is the value of the header, like max-age=3600.
+	Value string
+}
+
+// matches reports whether the rule is for responses of mediaType,
+// which is "" if the response has no Content-Type.
+func (rule Rule) matches(mediaType string) bool {
+	switch {
+	case rule.Type == "*":
+		return true
+	case strings.HasSuffix(rule.Type, "/*"):
+		return mediaType != "" && strings.HasPrefix(mediaType, rule.Type[:len(rule.Type)-1])
+	default:
+		return mediaType == rule.Type
+	}
+}
+
+// ServeHTTP implements the httpserver.Handler interface.
+func (c CacheControl) ServeHTTP(w http.ResponseWriter, r *http.Request) (int, error) {
+	if !c.matches(r.URL.Path) {
+		return c.Next.ServeHTTP(w, r)
+	}
+
+	// the header is set when the response header is written,
+	// once handlers further down have set the Content-Type
+	cw := httpserver.NewHeaderWriter(w, func(status int) {
+		setHeader(w.Header(), c.Rules, r.URL.Path, status)
+	})
+	status, err := c.Next.ServeHTTP(cw, r)
+	if !cw.WroteHeader() && status < 400 {
+		// the response is written further up
+		// the chain, if at all
+		setHeader(w.Header(), c.Rules, r.URL.Path, http.StatusOK)
+	}
+	return status, err
+}
+
+// matches reports whether the responses to requests for path
+// get the header.
+func (c CacheControl) matches(path string) bool {
+	for _, p := range c.Paths {
+		if httpserver.Path(path).Matches(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// value returns the Cache-Control header value of the first of rules
+// for responses of mediaType, or "" if there is none.
+func value(rules []Rule, mediaType string) string {
+	for _, rule := range rules {
+		if rule.matches(mediaType) {
+			return rule.Value
+		}
+	}
+	return ""
+}
+
+// setHeader sets the header of a response with status, unless it is
+// an error or has caching headers. The media type of a response with
+// no Content-Type, like a 304 Not Modified, is that of the extension
+// of the requested path, so that it gets the header it would get in
+// full.
+func setHeader(header http.Header, rules []Rule, reqPath string, status int) {
+	if status >= 400 || header.Get("Cache-Control") != "" || header.Get("Expires") != "" {
+		return
+	}
+	contentType := header.Get("Content-Type")
+	if contentType == "" {
+		contentType = mime.TypeByExtension(path.Ext(reqPath))
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if value := value(rules, mediaType); value != "" {
+		header.Set("Cache-Control", value)
+	}
+}
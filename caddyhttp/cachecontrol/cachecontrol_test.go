@@ -0,0 +1,87 @@
+package cachecontrol
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func TestCacheControl(t *testing.T) {
+	page := func(status int, header http.Header) httpserver.Handler {
+		return httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+			for name, values := range header {
+				w.Header()[name] = values
+			}
+			w.WriteHeader(status)
+			return 0, nil
+		})
+	}
+	rules := []Rule{
+		{Type: "image/*", Value: "max-age=31536000, immutable"},
+		{Type: "text/css", Value: "max-age=31536000"},
+		{Type: "text/html", Value: "no-cache"},
+	}
+
+	for i, test := range []struct {
+		path     string
+		next     httpserver.Handler
+		rules    []Rule
+		expected string
+	}{
+		{"/style.css", page(http.StatusOK, http.Header{"Content-Type": {"text/css; charset=utf-8"}}), rules, "max-age=31536000"},
+		{"/", page(http.StatusOK, http.Header{"Content-Type": {"text/html; charset=utf-8"}}), rules, "no-cache"},
+		{"/a.png", page(http.StatusOK, http.Header{"Content-Type": {"image/png"}}), rules, "max-age=31536000, immutable"},
+		{"/a.json", page(http.StatusOK, http.Header{"Content-Type": {"application/json"}}), rules, ""},
+
+		// upstream caching headers are respected
+		{"/style.css", page(http.StatusOK, http.Header{"Content-Type": {"text/css"}, "Cache-Control": {"private"}}), rules, "private"},
+		{"/style.css", page(http.StatusOK, http.Header{"Content-Type": {"text/css"}, "Expires": {"0"}}), rules, ""},
+		// errors are not cached
+		{"/style.css", page(http.StatusNotFound, http.Header{"Content-Type": {"text/css"}}), rules, ""},
+		{"/style.css", httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+			return http.StatusNotFound, nil
+		}), rules, ""},
+
+		// without a Content-Type, the extension tells the type
+		{"/style.css", page(http.StatusNotModified, nil), rules, "max-age=31536000"},
+		{"/unknown", page(http.StatusOK, nil), rules, ""},
+		{"/unknown", page(http.StatusOK, nil), append(rules, Rule{Type: "*", Value: "no-store"}), "no-store"},
+	} {
+		c := CacheControl{Next: test.next, Paths: []string{"/"}, Rules: test.rules}
+		r, err := http.NewRequest("GET", test.path, nil)
+		if err != nil {
+			t.Fatalf("Test %d: Could not create HTTP request: %v", i, err)
+		}
+		rec := httptest.NewRecorder()
+
+		if _, err := c.ServeHTTP(rec, r); err != nil {
+			t.Errorf("Test %d: Expected no error, got: %v", i, err)
+		}
+		if got := rec.Header().Get("Cache-Control"); got != test.expected {
+			t.Errorf("Test %d: Expected Cache-Control %q, got %q", i, test.expected, got)
+		}
+	}
+}
+
+func TestCacheControlOtherPaths(t *testing.T) {
+	c := CacheControl{
+		Next: httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+			w.Header().Set("Content-Type", "text/html")
+			return 0, nil
+		}),
+		Paths: []string{"/static"},
+		Rules: []Rule{{Type: "*", Value: "no-cache"}},
+	}
+	r, err := http.NewRequest("GET", "/api", nil)
+	if err != nil {
+		t.Fatalf("Could not create HTTP request: %v", err)
+	}
+	rec := httptest.NewRecorder()
+
+	c.ServeHTTP(rec, r)
+	if got := rec.Header().Get("Cache-Control"); got != "" {
+		t.Errorf("Expected no Cache-Control for other paths, got %q", got)
+	}
+}
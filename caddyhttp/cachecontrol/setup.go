@@ -0,0 +1,118 @@
+package cachecontrol
+
+import (
+	"mime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func init() {
+	caddy.RegisterPlugin("cache_control", caddy.Plugin{
+		ServerType: "http",
+		Action:     setup,
+	})
+}
+
+// setup configures a new CacheControl middleware instance.
+func setup(c *caddy.Controller) error {
+	cc, err := cacheControlParse(c)
+	if err != nil {
+		return err
+	}
+
+	httpserver.GetConfig(c).AddMiddleware(func(next httpserver.Handler) httpserver.Handler {
+		cc.Next = next
+		return cc
+	})
+
+	return nil
+}
+
+// cacheControlParse parses the directive:
+//
+//	cache_control [paths...] {
+//		<media type> <directives...>
+//	}
+//
+// where the directives are max_age <duration>, immutable, no_cache,
+// no_store, must_revalidate, public and private. For example:
+//
+//	cache_control {
+//		image/*   max_age 1y immutable
+//		text/css  max_age 30d
+//		text/html no_cache
+//	}
+//
+// A response gets the header of the first media type that it is of.
+func cacheControlParse(c *caddy.Controller) (CacheControl, error) {
+	var cc CacheControl
+	types := make(map[string]bool)
+
+	for c.Next() {
+		paths := c.RemainingArgs()
+		if len(paths) == 0 {
+			paths = []string{"/"}
+		}
+		cc.Paths = append(cc.Paths, paths...)
+
+		for c.NextBlock() {
+			rule := Rule{Type: strings.ToLower(c.Val())}
+			if rule.Type != "*" && !strings.HasSuffix(rule.Type, "/*") {
+				mediaType, _, err := mime.ParseMediaType(rule.Type)
+				if err != nil || !strings.Contains(mediaType, "/") {
+					return cc, c.Errf("invalid media type '%s'", c.Val())
+				}
+				rule.Type = mediaType
+			}
+			if types[rule.Type] {
+				return cc, c.Errf("duplicate media type '%s'", rule.Type)
+			}
+			types[rule.Type] = true
+
+			directives, err := parseDirectives(c, c.RemainingArgs())
+			if err != nil {
+				return cc, err
+			}
+			rule.Value = strings.Join(directives, ", ")
+			cc.Rules = append(cc.Rules, rule)
+		}
+	}
+
+	if len(cc.Rules) == 0 {
+		return cc, c.Err("cache_control needs at least one media type")
+	}
+
+	return cc, nil
+}
+
+// parseDirectives parses args into Cache-Control directives,
+// like max-age=3600.
+func parseDirectives(c *caddy.Controller, args []string) ([]string, error) {
+	if len(args) == 0 {
+		return nil, c.ArgErr()
+	}
+	var directives []string
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "max_age":
+			if i+1 == len(args) {
+				return nil, c.ArgErr()
+			}
+			i++
+			maxAge, err := httpserver.ParseMaxAge(args[i])
+			if err != nil {
+				return nil, c.Err(err.Error())
+			}
+			directives = append(directives, "max-age="+strconv.FormatInt(int64(maxAge/time.Second), 10))
+		case "immutable", "no_cache", "no_store", "must_revalidate", "public", "private":
+			directives = append(directives, strings.Replace(args[i], "_", "-", -1))
+		default:
+			return nil, c.Errf("unknown cache_control directive '%s'", args[i])
+		}
+	}
+	return directives, nil
+}
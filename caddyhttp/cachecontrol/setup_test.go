@@ -0,0 +1,90 @@
+package cachecontrol
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func TestSetup(t *testing.T) {
+	c := caddy.NewTestController("http", `cache_control {
+		text/html no_cache
+	}`)
+	err := setup(c)
+	if err != nil {
+		t.Errorf("Expected no errors, got: %v", err)
+	}
+	mids := httpserver.GetConfig(c).Middleware()
+	if len(mids) == 0 {
+		t.Fatal("Expected middleware, got 0 instead")
+	}
+
+	handler := mids[0](httpserver.EmptyNext)
+	myHandler, ok := handler.(CacheControl)
+	if !ok {
+		t.Fatalf("Expected handler to be type CacheControl, got: %#v", handler)
+	}
+	if !httpserver.SameNext(myHandler.Next, httpserver.EmptyNext) {
+		t.Error("'Next' field of handler was not set properly")
+	}
+}
+
+func TestCacheControlParse(t *testing.T) {
+	for i, test := range []struct {
+		input     string
+		shouldErr bool
+		expected  CacheControl
+	}{
+		{`cache_control {
+			image/*   max_age 1y immutable
+			Text/CSS  max_age 30d
+			text/html no_cache
+			*         max_age 1h30m must_revalidate
+		  }`, false, CacheControl{Paths: []string{"/"}, Rules: []Rule{
+			{Type: "image/*", Value: "max-age=31536000, immutable"},
+			{Type: "text/css", Value: "max-age=2592000"},
+			{Type: "text/html", Value: "no-cache"},
+			{Type: "*", Value: "max-age=5400, must-revalidate"},
+		}}},
+		{`cache_control /static /assets {
+			application/javascript;charset=utf-8 public max_age 3600
+		  }`, false, CacheControl{Paths: []string{"/static", "/assets"}, Rules: []Rule{
+			{Type: "application/javascript", Value: "public, max-age=3600"},
+		}}},
+		{`cache_control`, true, CacheControl{}},
+		{`cache_control {
+			text/html
+		  }`, true, CacheControl{}},
+		{`cache_control {
+			html no_cache
+		  }`, true, CacheControl{}},
+		{`cache_control {
+			text/html no_cache
+			text/html no_store
+		  }`, true, CacheControl{}},
+		{`cache_control {
+			text/html max_age
+		  }`, true, CacheControl{}},
+		{`cache_control {
+			text/html max_age -1
+		  }`, true, CacheControl{}},
+		{`cache_control {
+			text/html max_age forever
+		  }`, true, CacheControl{}},
+		{`cache_control {
+			text/html cache_forever
+		  }`, true, CacheControl{}},
+	} {
+		actual, err := cacheControlParse(caddy.NewTestController("http", test.input))
+		if err == nil && test.shouldErr {
+			t.Errorf("Test %d didn't error, but it should have", i)
+		} else if err != nil && !test.shouldErr {
+			t.Errorf("Test %d errored, but it shouldn't have; got '%v'", i, err)
+		}
+		if !test.shouldErr && !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("Test %d: Expected %+v, got %+v", i, test.expected, actual)
+		}
+	}
+}
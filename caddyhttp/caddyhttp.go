@@ -5,28 +5,66 @@ import (
 	_ "github.com/mholt/caddy/caddyhttp/httpserver"
 
 	// plug in the standard directives
+	_ "github.com/mholt/caddy/caddyhttp/access"
+	_ "github.com/mholt/caddy/caddyhttp/altsvc"
 	_ "github.com/mholt/caddy/caddyhttp/basicauth"
 	_ "github.com/mholt/caddy/caddyhttp/bind"
 	_ "github.com/mholt/caddy/caddyhttp/browse"
+	_ "github.com/mholt/caddy/caddyhttp/cachecontrol"
+	_ "github.com/mholt/caddy/caddyhttp/canonicalhost"
+	_ "github.com/mholt/caddy/caddyhttp/concurrency"
+	_ "github.com/mholt/caddy/caddyhttp/connlimit"
+	_ "github.com/mholt/caddy/caddyhttp/cors"
+	_ "github.com/mholt/caddy/caddyhttp/csp"
+	_ "github.com/mholt/caddy/caddyhttp/digestauth"
+	_ "github.com/mholt/caddy/caddyhttp/enforcehttps"
 	_ "github.com/mholt/caddy/caddyhttp/errors"
+	_ "github.com/mholt/caddy/caddyhttp/etag"
 	_ "github.com/mholt/caddy/caddyhttp/expvar"
 	_ "github.com/mholt/caddy/caddyhttp/extensions"
 	_ "github.com/mholt/caddy/caddyhttp/fastcgi"
+	_ "github.com/mholt/caddy/caddyhttp/fault"
 	_ "github.com/mholt/caddy/caddyhttp/gzip"
 	_ "github.com/mholt/caddy/caddyhttp/header"
+	_ "github.com/mholt/caddy/caddyhttp/hidden"
+	_ "github.com/mholt/caddy/caddyhttp/hsts"
+	_ "github.com/mholt/caddy/caddyhttp/index"
 	_ "github.com/mholt/caddy/caddyhttp/internalsrv"
+	_ "github.com/mholt/caddy/caddyhttp/jwt"
+	_ "github.com/mholt/caddy/caddyhttp/lang"
 	_ "github.com/mholt/caddy/caddyhttp/log"
+	_ "github.com/mholt/caddy/caddyhttp/maintenance"
 	_ "github.com/mholt/caddy/caddyhttp/markdown"
+	_ "github.com/mholt/caddy/caddyhttp/matcher"
 	_ "github.com/mholt/caddy/caddyhttp/maxrequestbody"
+	_ "github.com/mholt/caddy/caddyhttp/maxrequestheader"
+	_ "github.com/mholt/caddy/caddyhttp/methodoverride"
 	_ "github.com/mholt/caddy/caddyhttp/mime"
+	_ "github.com/mholt/caddy/caddyhttp/minify"
+	_ "github.com/mholt/caddy/caddyhttp/mirror"
 	_ "github.com/mholt/caddy/caddyhttp/pprof"
+	_ "github.com/mholt/caddy/caddyhttp/precompressed"
 	_ "github.com/mholt/caddy/caddyhttp/proxy"
+	_ "github.com/mholt/caddy/caddyhttp/proxyprotocol"
+	_ "github.com/mholt/caddy/caddyhttp/push"
+	_ "github.com/mholt/caddy/caddyhttp/recovery"
 	_ "github.com/mholt/caddy/caddyhttp/redirect"
+	_ "github.com/mholt/caddy/caddyhttp/requesttimeout"
+	_ "github.com/mholt/caddy/caddyhttp/requirecontenttype"
+	_ "github.com/mholt/caddy/caddyhttp/requireheader"
 	_ "github.com/mholt/caddy/caddyhttp/rewrite"
 	_ "github.com/mholt/caddy/caddyhttp/root"
+	_ "github.com/mholt/caddy/caddyhttp/servertiming"
+	_ "github.com/mholt/caddy/caddyhttp/slowrequests"
 	_ "github.com/mholt/caddy/caddyhttp/status"
+	_ "github.com/mholt/caddy/caddyhttp/stripprefix"
+	_ "github.com/mholt/caddy/caddyhttp/sub"
+	_ "github.com/mholt/caddy/caddyhttp/tcp"
 	_ "github.com/mholt/caddy/caddyhttp/templates"
+	_ "github.com/mholt/caddy/caddyhttp/throttle"
 	_ "github.com/mholt/caddy/caddyhttp/timeouts"
+	_ "github.com/mholt/caddy/caddyhttp/trailingslash"
+	_ "github.com/mholt/caddy/caddyhttp/trustedproxies"
 	_ "github.com/mholt/caddy/caddyhttp/websocket"
 	_ "github.com/mholt/caddy/startupshutdown"
 )
@@ -0,0 +1,74 @@
+// Package canonicalhost is middleware that redirects requests
+// for other host names of a site to its canonical host.
+package canonicalhost
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+// CanonicalHost is middleware that permanently redirects requests
+// whose Host is not the canonical host, keeping the scheme, path
+// and query. Requests for IP addresses are not redirected.
+type CanonicalHost struct {
+	Next httpserver.Handler
+
+	// Host is the canonical host name, in lower case
+	Host string
+
+	// Port, if not empty, is the canonical port; otherwise
+	// the port of the request is kept
+	Port string
+}
+
+// ServeHTTP implements the httpserver.Handler interface.
+func (ch CanonicalHost) ServeHTTP(w http.ResponseWriter, r *http.Request) (int, error) {
+	host, port := splitHost(r.Host)
+	if host == "" || net.ParseIP(host) != nil {
+		return ch.Next.ServeHTTP(w, r)
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if defaultPort(scheme, port) {
+		port = ""
+	}
+	wantPort := port
+	if ch.Port != "" {
+		wantPort = ch.Port
+		if defaultPort(scheme, wantPort) {
+			wantPort = ""
+		}
+	}
+
+	if host == ch.Host && port == wantPort {
+		return ch.Next.ServeHTTP(w, r)
+	}
+
+	to := ch.Host
+	if wantPort != "" {
+		to = net.JoinHostPort(ch.Host, wantPort)
+	}
+	http.Redirect(w, r, scheme+"://"+to+r.URL.RequestURI(), http.StatusMovedPermanently)
+	return 0, nil
+}
+
+// splitHost splits hostport into its host, normalized to
+// lower case without a trailing dot, and port, if any.
+func splitHost(hostport string) (host, port string) {
+	host = hostport
+	if h, p, err := net.SplitHostPort(hostport); err == nil {
+		host, port = h, p
+	}
+	return strings.TrimSuffix(strings.ToLower(host), "."), port
+}
+
+// defaultPort reports whether port is the default one for scheme.
+func defaultPort(scheme, port string) bool {
+	return (scheme == "http" && port == "80") || (scheme == "https" && port == "443")
+}
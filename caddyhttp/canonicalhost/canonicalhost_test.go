@@ -0,0 +1,91 @@
+package canonicalhost
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func TestCanonicalHost(t *testing.T) {
+	for i, test := range []struct {
+		canonical, port string
+		url             string
+		tls             bool
+		expectLocation  string
+	}{
+		// www to apex
+		{"example.com", "", "http://www.example.com/a/b?c=d", false, "http://example.com/a/b?c=d"},
+		{"example.com", "", "https://www.example.com/", true, "https://example.com/"},
+		// apex to www
+		{"www.example.com", "", "http://example.com/a%20b?c=d", false, "http://www.example.com/a%20b?c=d"},
+		// already canonical
+		{"example.com", "", "http://example.com/a", false, ""},
+		{"example.com", "", "http://EXAMPLE.com./a", false, ""},
+		{"www.example.com", "", "https://www.example.com/", true, ""},
+		// the request port is kept, or replaced by the canonical one
+		{"example.com", "", "http://www.example.com:8080/a", false, "http://example.com:8080/a"},
+		{"example.com", "", "http://example.com:8080/a", false, ""},
+		{"example.com", "", "http://www.example.com:80/a", false, "http://example.com/a"},
+		{"example.com", "8443", "https://example.com:9443/a", true, "https://example.com:8443/a"},
+		{"example.com", "8443", "https://example.com:8443/a", true, ""},
+		{"example.com", "443", "https://www.example.com:8443/a", true, "https://example.com/a"},
+		{"example.com", "443", "https://example.com/a", true, ""},
+		// IP literals are skipped
+		{"example.com", "", "http://127.0.0.1/a", false, ""},
+		{"example.com", "", "http://127.0.0.1:8080/a", false, ""},
+		{"example.com", "", "http://[::1]:8080/a", false, ""},
+	} {
+		var nextCalled bool
+		ch := CanonicalHost{
+			Next: httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+				nextCalled = true
+				return 0, nil
+			}),
+			Host: test.canonical,
+			Port: test.port,
+		}
+
+		req, err := http.NewRequest("GET", test.url, nil)
+		if err != nil {
+			t.Fatalf("Test %d: Could not create HTTP request: %v", i, err)
+		}
+		if test.tls {
+			req.TLS = new(tls.ConnectionState)
+		}
+		rec := httptest.NewRecorder()
+		ch.ServeHTTP(rec, req)
+
+		if test.expectLocation == "" {
+			if !nextCalled {
+				t.Errorf("Test %d: Expected the request to pass through, got redirect to %q", i, rec.Header().Get("Location"))
+			}
+			continue
+		}
+		if nextCalled {
+			t.Errorf("Test %d: Next handler was unexpectedly called", i)
+		}
+		if rec.Code != http.StatusMovedPermanently {
+			t.Errorf("Test %d: Expected status %d, got %d", i, http.StatusMovedPermanently, rec.Code)
+		}
+		if got := rec.Header().Get("Location"); got != test.expectLocation {
+			t.Errorf("Test %d: Expected Location %q, got %q", i, test.expectLocation, got)
+		}
+
+		// following the redirect must not redirect again
+		req, err = http.NewRequest("GET", test.expectLocation, nil)
+		if err != nil {
+			t.Fatalf("Test %d: Could not create HTTP request: %v", i, err)
+		}
+		if test.tls {
+			req.TLS = new(tls.ConnectionState)
+		}
+		nextCalled = false
+		ch.ServeHTTP(httptest.NewRecorder(), req)
+		if !nextCalled {
+			t.Errorf("Test %d: Expected the redirect target %q to be canonical", i, test.expectLocation)
+		}
+	}
+}
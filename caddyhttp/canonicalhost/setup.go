@@ -0,0 +1,67 @@
+package canonicalhost
+
+import (
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func init() {
+	caddy.RegisterPlugin("canonical_host", caddy.Plugin{
+		ServerType: "http",
+		Action:     setup,
+	})
+}
+
+// setup configures a new CanonicalHost middleware instance.
+func setup(c *caddy.Controller) error {
+	ch, err := canonicalHostParse(c)
+	if err != nil {
+		return err
+	}
+
+	httpserver.GetConfig(c).AddMiddleware(func(next httpserver.Handler) httpserver.Handler {
+		ch.Next = next
+		return ch
+	})
+
+	return nil
+}
+
+// canonicalHostParse parses the directive:
+//
+//	canonical_host <host>[:port]
+func canonicalHostParse(c *caddy.Controller) (CanonicalHost, error) {
+	var ch CanonicalHost
+
+	for c.Next() {
+		if ch.Host != "" {
+			return ch, c.Err("canonical host already specified")
+		}
+		if !c.NextArg() {
+			return ch, c.ArgErr()
+		}
+		arg := c.Val()
+		if c.NextArg() {
+			return ch, c.ArgErr()
+		}
+		if strings.ContainsAny(arg, "/?#@") {
+			return ch, c.Errf("canonical host must be a host name, optionally with a port, not '%s'", arg)
+		}
+		host, port := splitHost(arg)
+		if port != "" {
+			if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+				return ch, c.Errf("invalid port in canonical host '%s'", arg)
+			}
+		}
+		if host == "" || (strings.ContainsAny(host, ":[]") && net.ParseIP(host) == nil) {
+			return ch, c.Errf("invalid canonical host '%s'", arg)
+		}
+		ch.Host, ch.Port = host, port
+	}
+
+	return ch, nil
+}
@@ -0,0 +1,61 @@
+package canonicalhost
+
+import (
+	"testing"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func TestSetup(t *testing.T) {
+	c := caddy.NewTestController("http", `canonical_host example.com`)
+	err := setup(c)
+	if err != nil {
+		t.Errorf("Expected no errors, got: %v", err)
+	}
+	mids := httpserver.GetConfig(c).Middleware()
+	if len(mids) == 0 {
+		t.Fatal("Expected middleware, got 0 instead")
+	}
+
+	handler := mids[0](httpserver.EmptyNext)
+	myHandler, ok := handler.(CanonicalHost)
+	if !ok {
+		t.Fatalf("Expected handler to be type CanonicalHost, got: %#v", handler)
+	}
+	if myHandler.Host != "example.com" {
+		t.Errorf("Expected host example.com, got %s", myHandler.Host)
+	}
+	if !httpserver.SameNext(myHandler.Next, httpserver.EmptyNext) {
+		t.Error("'Next' field of handler was not set properly")
+	}
+}
+
+func TestCanonicalHostParse(t *testing.T) {
+	for i, test := range []struct {
+		input     string
+		shouldErr bool
+		expected  CanonicalHost
+	}{
+		{`canonical_host example.com`, false, CanonicalHost{Host: "example.com"}},
+		{`canonical_host WWW.Example.com.`, false, CanonicalHost{Host: "www.example.com"}},
+		{`canonical_host example.com:8443`, false, CanonicalHost{Host: "example.com", Port: "8443"}},
+		{`canonical_host`, true, CanonicalHost{}},
+		{`canonical_host example.com www.example.com`, true, CanonicalHost{}},
+		{"canonical_host example.com\ncanonical_host www.example.com", true, CanonicalHost{}},
+		{`canonical_host https://example.com`, true, CanonicalHost{}},
+		{`canonical_host example.com/path`, true, CanonicalHost{}},
+		{`canonical_host example.com:http`, true, CanonicalHost{}},
+		{`canonical_host example.com:70000`, true, CanonicalHost{}},
+	} {
+		actual, err := canonicalHostParse(caddy.NewTestController("http", test.input))
+		if err == nil && test.shouldErr {
+			t.Errorf("Test %d didn't error, but it should have", i)
+		} else if err != nil && !test.shouldErr {
+			t.Errorf("Test %d errored, but it shouldn't have; got '%v'", i, err)
+		}
+		if !test.shouldErr && actual != test.expected {
+			t.Errorf("Test %d: Expected %+v, got %+v", i, test.expected, actual)
+		}
+	}
+}
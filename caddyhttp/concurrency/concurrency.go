@@ -0,0 +1,71 @@
+// Package concurrency is middleware that limits the number of
+// requests a site handles at the same time.
+package concurrency
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+// Concurrency is middleware that handles at most as many requests
+// at once as its semaphore has room for. Excess requests wait up to
+// Wait for a slot, then are answered with 503 Service Unavailable.
+type Concurrency struct {
+	Next httpserver.Handler
+	Wait time.Duration
+
+	// slots is the semaphore; its capacity is the limit.
+	slots chan struct{}
+}
+
+// New returns Concurrency middleware that handles up to limit
+// requests at once, with excess requests waiting up to wait.
+func New(next httpserver.Handler, limit int, wait time.Duration) Concurrency {
+	return Concurrency{Next: next, Wait: wait, slots: make(chan struct{}, limit)}
+}
+
+// Limit returns the most requests that are handled at once.
+func (c Concurrency) Limit() int {
+	return cap(c.slots)
+}
+
+// ServeHTTP implements the httpserver.Handler interface.
+func (c Concurrency) ServeHTTP(w http.ResponseWriter, r *http.Request) (int, error) {
+	if !c.acquire() {
+		seconds := (c.Wait + time.Second - 1) / time.Second
+		if seconds < 1 {
+			seconds = 1
+		}
+		w.Header().Set("Retry-After", strconv.FormatInt(int64(seconds), 10))
+		return http.StatusServiceUnavailable, nil
+	}
+	// released even if a handler panics
+	defer func() { <-c.slots }()
+
+	return c.Next.ServeHTTP(w, r)
+}
+
+// acquire takes a slot, waiting for one up to c.Wait.
+// It reports whether it got one.
+func (c Concurrency) acquire() bool {
+	select {
+	case c.slots <- struct{}{}:
+		return true
+	default:
+	}
+	if c.Wait <= 0 {
+		return false
+	}
+
+	timer := time.NewTimer(c.Wait)
+	defer timer.Stop()
+	select {
+	case c.slots <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
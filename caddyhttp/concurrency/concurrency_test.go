@@ -0,0 +1,158 @@
+package concurrency
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+// blockingHandler counts the requests in flight and
+// blocks them until release is closed.
+type blockingHandler struct {
+	inFlight, maxInFlight, started int32
+	release                        chan struct{}
+}
+
+func (h *blockingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) (int, error) {
+	n := atomic.AddInt32(&h.inFlight, 1)
+	for {
+		max := atomic.LoadInt32(&h.maxInFlight)
+		if n <= max || atomic.CompareAndSwapInt32(&h.maxInFlight, max, n) {
+			break
+		}
+	}
+	atomic.AddInt32(&h.started, 1)
+	<-h.release
+	atomic.AddInt32(&h.inFlight, -1)
+	return http.StatusOK, nil
+}
+
+// serveConcurrently makes n requests to c at once and returns
+// their status codes and response headers. It counts the requests
+// that are done in finished.
+func serveConcurrently(t *testing.T, c Concurrency, n int, finished *int32, afterStart func()) ([]int, []http.Header) {
+	statuses := make([]int, n)
+	headers := make([]http.Header, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			req, err := http.NewRequest("GET", "/", nil)
+			if err != nil {
+				t.Errorf("Could not create HTTP request: %v", err)
+				return
+			}
+			rec := httptest.NewRecorder()
+			statuses[i], _ = c.ServeHTTP(rec, req)
+			headers[i] = rec.Header()
+			atomic.AddInt32(finished, 1)
+		}(i)
+	}
+	afterStart()
+	wg.Wait()
+	return statuses, headers
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	for deadline := time.Now().Add(5 * time.Second); !cond(); time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out")
+		}
+	}
+}
+
+func TestConcurrencyRejects(t *testing.T) {
+	h := &blockingHandler{release: make(chan struct{})}
+	c := New(h, 3, 0)
+
+	var rejected int
+	var finished int32
+	statuses, headers := serveConcurrently(t, c, 10, &finished, func() {
+		// the limit is reached and the rest is rejected
+		waitFor(t, func() bool { return atomic.LoadInt32(&finished) == 7 })
+		close(h.release)
+	})
+	for i, status := range statuses {
+		if status == http.StatusServiceUnavailable {
+			rejected++
+			if got := headers[i].Get("Retry-After"); got != "1" {
+				t.Errorf("Expected Retry-After 1, got '%s'", got)
+			}
+		} else if status != http.StatusOK {
+			t.Errorf("Expected status %d or %d, got %d", http.StatusOK, http.StatusServiceUnavailable, status)
+		}
+	}
+	if rejected != 7 {
+		t.Errorf("Expected 7 requests to be rejected, got %d", rejected)
+	}
+	if max := atomic.LoadInt32(&h.maxInFlight); max > 3 {
+		t.Errorf("Expected at most 3 requests in flight, got %d", max)
+	}
+}
+
+func TestConcurrencyQueues(t *testing.T) {
+	h := &blockingHandler{release: make(chan struct{})}
+	c := New(h, 2, 5*time.Second)
+
+	var finished int32
+	statuses, _ := serveConcurrently(t, c, 8, &finished, func() {
+		waitFor(t, func() bool { return atomic.LoadInt32(&h.started) == 2 })
+		time.Sleep(50 * time.Millisecond)
+		close(h.release)
+	})
+	for i, status := range statuses {
+		if status != http.StatusOK {
+			t.Errorf("Request %d: Expected queued request to succeed, got %d", i, status)
+		}
+	}
+	if max := atomic.LoadInt32(&h.maxInFlight); max != 2 {
+		t.Errorf("Expected 2 requests in flight at most, got %d", max)
+	}
+}
+
+func TestConcurrencyWaitTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	h := &blockingHandler{release: release}
+	c := New(h, 1, 1500*time.Millisecond)
+
+	go c.ServeHTTP(httptest.NewRecorder(), &http.Request{})
+	waitFor(t, func() bool { return atomic.LoadInt32(&h.started) == 1 })
+
+	rec := httptest.NewRecorder()
+	start := time.Now()
+	status, _ := c.ServeHTTP(rec, &http.Request{})
+	if status != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %d, got %d", http.StatusServiceUnavailable, status)
+	}
+	if waited := time.Since(start); waited < 1500*time.Millisecond {
+		t.Errorf("Expected to wait 1.5s before rejecting, waited %s", waited)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("Expected Retry-After 2, got '%s'", got)
+	}
+}
+
+func TestConcurrencyReleasesOnPanic(t *testing.T) {
+	c := New(httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+		panic("oops")
+	}), 1, 0)
+
+	func() {
+		defer func() { recover() }()
+		c.ServeHTTP(httptest.NewRecorder(), &http.Request{})
+	}()
+
+	c.Next = httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+		return http.StatusOK, nil
+	})
+	if status, _ := c.ServeHTTP(httptest.NewRecorder(), &http.Request{}); status != http.StatusOK {
+		t.Errorf("Expected the slot to be released after a panic, got status %d", status)
+	}
+}
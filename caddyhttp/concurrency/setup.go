@@ -0,0 +1,79 @@
+package concurrency
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func init() {
+	caddy.RegisterPlugin("concurrency", caddy.Plugin{
+		ServerType: "http",
+		Action:     setup,
+	})
+}
+
+// setup configures a new Concurrency middleware instance.
+func setup(c *caddy.Controller) error {
+	limit, wait, err := concurrencyParse(c)
+	if err != nil {
+		return err
+	}
+
+	// one semaphore for the site, shared by the
+	// handler of every middleware chain built
+	con := New(nil, limit, wait)
+	httpserver.GetConfig(c).AddMiddleware(func(next httpserver.Handler) httpserver.Handler {
+		con.Next = next
+		return con
+	})
+
+	return nil
+}
+
+// concurrencyParse parses the directive:
+//
+//	concurrency <limit> {
+//		wait <duration>
+//	}
+//
+// Without wait, excess requests are rejected right away.
+func concurrencyParse(c *caddy.Controller) (limit int, wait time.Duration, err error) {
+	for c.Next() {
+		if limit != 0 {
+			return 0, 0, c.Err("concurrency can only be specified once per site")
+		}
+		if !c.NextArg() {
+			return 0, 0, c.ArgErr()
+		}
+		limit, err = strconv.Atoi(c.Val())
+		if err != nil || limit < 1 {
+			return 0, 0, c.Errf("limit must be a positive integer, got '%s'", c.Val())
+		}
+		if len(c.RemainingArgs()) != 0 {
+			return 0, 0, c.ArgErr()
+		}
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "wait":
+				if !c.NextArg() {
+					return 0, 0, c.ArgErr()
+				}
+				wait, err = time.ParseDuration(c.Val())
+				if err != nil || wait < 0 {
+					return 0, 0, c.Errf("wait must be a non-negative duration, got '%s'", c.Val())
+				}
+				if c.NextArg() {
+					return 0, 0, c.ArgErr()
+				}
+			default:
+				return 0, 0, c.Errf("unknown subdirective '%s'", c.Val())
+			}
+		}
+	}
+
+	return limit, wait, nil
+}
@@ -0,0 +1,63 @@
+package concurrency
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func TestSetup(t *testing.T) {
+	c := caddy.NewTestController("http", `concurrency 10`)
+	err := setup(c)
+	if err != nil {
+		t.Errorf("Expected no errors, got: %v", err)
+	}
+	mids := httpserver.GetConfig(c).Middleware()
+	if len(mids) == 0 {
+		t.Fatal("Expected middleware, got 0 instead")
+	}
+
+	handler := mids[0](httpserver.EmptyNext)
+	myHandler, ok := handler.(Concurrency)
+	if !ok {
+		t.Fatalf("Expected handler to be type Concurrency, got: %#v", handler)
+	}
+	if !httpserver.SameNext(myHandler.Next, httpserver.EmptyNext) {
+		t.Error("'Next' field of handler was not set properly")
+	}
+	if myHandler.Limit() != 10 {
+		t.Errorf("Expected limit 10, got %d", myHandler.Limit())
+	}
+}
+
+func TestConcurrencyParse(t *testing.T) {
+	for i, test := range []struct {
+		input     string
+		shouldErr bool
+		limit     int
+		wait      time.Duration
+	}{
+		{`concurrency 5`, false, 5, 0},
+		{"concurrency 5 {\n\twait 2s\n}", false, 5, 2 * time.Second},
+		{`concurrency`, true, 0, 0},
+		{`concurrency 0`, true, 0, 0},
+		{`concurrency many`, true, 0, 0},
+		{`concurrency 5 2s`, true, 0, 0},
+		{"concurrency 5 {\n\twait\n}", true, 0, 0},
+		{"concurrency 5 {\n\twait -1s\n}", true, 0, 0},
+		{"concurrency 5 {\n\tqueue 10\n}", true, 0, 0},
+		{"concurrency 5\nconcurrency 6", true, 0, 0},
+	} {
+		limit, wait, err := concurrencyParse(caddy.NewTestController("http", test.input))
+		if err == nil && test.shouldErr {
+			t.Errorf("Test %d didn't error, but it should have", i)
+		} else if err != nil && !test.shouldErr {
+			t.Errorf("Test %d errored, but it shouldn't have; got '%v'", i, err)
+		}
+		if !test.shouldErr && (limit != test.limit || wait != test.wait) {
+			t.Errorf("Test %d: Expected limit %d and wait %s, got %d and %s", i, test.limit, test.wait, limit, wait)
+		}
+	}
+}
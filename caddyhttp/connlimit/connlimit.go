@@ -0,0 +1,129 @@
+// Package connlimit limits how fast each client may open
+// connections to the listener of a site, and how many may be
+// open at once, which protects the TLS handshake and the
+// reading of requests from floods.
+package connlimit
+
+import (
+	"strconv"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+	"github.com/mholt/caddy/caddyhttp/throttle"
+)
+
+func init() {
+	caddy.RegisterPlugin("conn_limit", caddy.Plugin{
+		ServerType: "http",
+		Action:     setupConnLimit,
+	})
+	caddy.RegisterPlugin("max_conns", caddy.Plugin{
+		ServerType: "http",
+		Action:     setupMaxConns,
+	})
+}
+
+// setupConnLimit parses the conn_limit directive:
+//
+//	conn_limit <n>/<s|m|h|duration> [burst] {
+//		except      <ranges...>
+//		max_sources <n>
+//	}
+//
+// Connections from an IP address beyond the rate are closed
+// right after they are accepted. The sites on a listener must
+// have the same limit, if any.
+func setupConnLimit(c *caddy.Controller) error {
+	config := httpserver.GetConfig(c)
+	for c.Next() {
+		if config.ConnLimit != nil {
+			return c.Err("conn_limit can only be specified once per site")
+		}
+		limit := new(httpserver.ConnLimit)
+
+		args := c.RemainingArgs()
+		if len(args) == 0 || len(args) > 2 {
+			return c.ArgErr()
+		}
+		rate, err := throttle.ParseRate(args[0])
+		if err != nil {
+			return c.Errf("invalid rate '%s': %v", args[0], err)
+		}
+		limit.Rate = rate
+		if len(args) == 2 {
+			burst, err := strconv.Atoi(args[1])
+			if err != nil || burst < 1 {
+				return c.Errf("burst must be a positive integer, got '%s'", args[1])
+			}
+			limit.Burst = burst
+		} else {
+			// allow at least one connection, and a second's worth at once
+			limit.Burst = int(rate)
+			if limit.Burst < 1 {
+				limit.Burst = 1
+			}
+		}
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "except":
+				ranges := c.RemainingArgs()
+				if len(ranges) == 0 {
+					return c.ArgErr()
+				}
+				for _, r := range ranges {
+					ipnet, err := httpserver.ParseCIDR(r)
+					if err != nil {
+						return c.Errf("invalid source range '%s': %v", r, err)
+					}
+					limit.Except = append(limit.Except, ipnet)
+				}
+			case "max_sources":
+				if !c.NextArg() {
+					return c.ArgErr()
+				}
+				n, err := strconv.Atoi(c.Val())
+				if err != nil || n < 1 {
+					return c.Errf("max_sources must be a positive integer, got '%s'", c.Val())
+				}
+				limit.MaxSources = n
+				if c.NextArg() {
+					return c.ArgErr()
+				}
+			default:
+				return c.Errf("unknown subdirective '%s'", c.Val())
+			}
+		}
+
+		config.ConnLimit = limit
+	}
+	return nil
+}
+
+// setupMaxConns parses the max_conns directive:
+//
+//	max_conns <n>
+//
+// Once the listener of the site has n connections open, it
+// accepts no more until one is closed. The lowest maximum of
+// the sites on a listener applies.
+func setupMaxConns(c *caddy.Controller) error {
+	config := httpserver.GetConfig(c)
+	for c.Next() {
+		if config.MaxConns != 0 {
+			return c.Err("max_conns can only be specified once per site")
+		}
+		if !c.NextArg() {
+			return c.ArgErr()
+		}
+		n, err := strconv.Atoi(c.Val())
+		if err != nil || n < 1 {
+			return c.Errf("max_conns must be a positive integer, got '%s'", c.Val())
+		}
+		if c.NextArg() {
+			return c.ArgErr()
+		}
+		config.MaxConns = n
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package connlimit
+
+import (
+	"testing"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func TestSetupConnLimit(t *testing.T) {
+	for i, test := range []struct {
+		input              string
+		shouldErr          bool
+		expectedRate       float64
+		expectedBurst      int
+		expectedExcept     []string
+		expectedMaxSources int
+	}{
+		{"conn_limit 10/s", false, 10, 10, nil, 0},
+		{"conn_limit 60/m 5", false, 1, 5, nil, 0},
+		{"conn_limit 1/10s", false, 0.1, 1, nil, 0},
+		{"conn_limit 5/s {\n\texcept 10.0.0.0/8 ::1\n\tmax_sources 100\n}", false, 5, 5, []string{"10.0.0.0/8", "::1/128"}, 100},
+		{"conn_limit", true, 0, 0, nil, 0},
+		{"conn_limit 10", true, 0, 0, nil, 0},
+		{"conn_limit 10/s 0", true, 0, 0, nil, 0},
+		{"conn_limit 10/s 5 5", true, 0, 0, nil, 0},
+		{"conn_limit 10/s {\n\texcept\n}", true, 0, 0, nil, 0},
+		{"conn_limit 10/s {\n\texcept 10.0.0.0/33\n}", true, 0, 0, nil, 0},
+		{"conn_limit 10/s {\n\tmax_sources -1\n}", true, 0, 0, nil, 0},
+		{"conn_limit 10/s {\n\tallow 10.0.0.0/8\n}", true, 0, 0, nil, 0},
+		{"conn_limit 10/s\nconn_limit 5/s", true, 0, 0, nil, 0},
+	} {
+		c := caddy.NewTestController("http", test.input)
+		err := setupConnLimit(c)
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: Expected an error, but did not have one", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: Did not expect error, but got: %v", i, err)
+			continue
+		}
+
+		limit := httpserver.GetConfig(c).ConnLimit
+		if limit == nil {
+			t.Fatalf("Test %d: Expected a connection limit, got none", i)
+		}
+		if limit.Rate != test.expectedRate || limit.Burst != test.expectedBurst {
+			t.Errorf("Test %d: Expected rate %v and burst %d, got %v and %d",
+				i, test.expectedRate, test.expectedBurst, limit.Rate, limit.Burst)
+		}
+		if limit.MaxSources != test.expectedMaxSources {
+			t.Errorf("Test %d: Expected max sources %d, got %d", i, test.expectedMaxSources, limit.MaxSources)
+		}
+		if len(limit.Except) != len(test.expectedExcept) {
+			t.Fatalf("Test %d: Expected %d excepted ranges, got %d", i, len(test.expectedExcept), len(limit.Except))
+		}
+		for j, ipnet := range limit.Except {
+			if ipnet.String() != test.expectedExcept[j] {
+				t.Errorf("Test %d: Expected excepted range %d to be %s, got %s", i, j, test.expectedExcept[j], ipnet)
+			}
+		}
+	}
+}
+
+func TestSetupMaxConns(t *testing.T) {
+	for i, test := range []struct {
+		input     string
+		shouldErr bool
+		expected  int
+	}{
+		{"max_conns 1000", false, 1000},
+		{"max_conns", true, 0},
+		{"max_conns 0", true, 0},
+		{"max_conns many", true, 0},
+		{"max_conns 10 20", true, 0},
+		{"max_conns 10\nmax_conns 20", true, 0},
+	} {
+		c := caddy.NewTestController("http", test.input)
+		err := setupMaxConns(c)
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: Expected an error, but did not have one", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: Did not expect error, but got: %v", i, err)
+			continue
+		}
+		if got := httpserver.GetConfig(c).MaxConns; got != test.expected {
+			t.Errorf("Test %d: Expected max_conns %d, got %d", i, test.expected, got)
+		}
+	}
+}
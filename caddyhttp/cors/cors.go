@@ -0,0 +1,147 @@
+// Package cors is middleware that handles Cross-Origin Resource
+// Sharing, answering preflight requests and adding the CORS
+// headers to the responses of actual requests.
+package cors
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+// CORS is middleware that allows cross-origin requests to its rules'
+// paths from the configured origins.
+type CORS struct {
+	Next  httpserver.Handler
+	Rules []Rule
+}
+
+// Rule is the CORS policy of the requests for a path.
+type Rule struct {
+	Path string
+
+	// Origins are the allowed origins; "*" allows any origin.
+	Origins []string
+
+	// Methods are the methods allowed in actual requests.
+	Methods []string
+
+	// Headers are the request headers allowed in actual requests.
+	// If empty, the headers a preflight request asks for are allowed.
+	Headers []string
+
+	// ExposedHeaders are the response headers that scripts may read.
+	ExposedHeaders []string
+
+	// Credentials allows requests with cookies and authentication.
+	// The allowed origin is then always the request's origin, since
+	// browsers refuse credentials for a wildcard origin.
+	Credentials bool
+
+	// MaxAge is how long the result of a preflight request may be
+	// cached; if 0, no Access-Control-Max-Age header is sent.
+	MaxAge time.Duration
+}
+
+// ServeHTTP implements the httpserver.Handler interface.
+func (c CORS) ServeHTTP(w http.ResponseWriter, r *http.Request) (int, error) {
+	rule, ok := c.match(r.URL.Path)
+	if !ok {
+		return c.Next.ServeHTTP(w, r)
+	}
+
+	if !rule.anyOrigin() || rule.Credentials {
+		// the allowed origin depends on the request's origin
+		w.Header().Add("Vary", "Origin")
+	}
+
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return c.Next.ServeHTTP(w, r)
+	}
+	allowed := rule.allowsOrigin(origin)
+
+	requestMethod := r.Header.Get("Access-Control-Request-Method")
+	if r.Method == http.MethodOptions && requestMethod != "" {
+		// preflight request
+		if !allowed || !rule.allowsMethod(requestMethod) {
+			return http.StatusForbidden, nil
+		}
+		rule.setOriginHeaders(w, origin)
+		w.Header().Set("Access-Control-Allow-Methods", strings.Join(rule.Methods, ", "))
+		if len(rule.Headers) > 0 {
+			w.Header().Set("Access-Control-Allow-Headers", strings.Join(rule.Headers, ", "))
+		} else if requestHeaders := r.Header.Get("Access-Control-Request-Headers"); requestHeaders != "" {
+			w.Header().Set("Access-Control-Allow-Headers", requestHeaders)
+			w.Header().Add("Vary", "Access-Control-Request-Headers")
+		}
+		if rule.MaxAge > 0 {
+			w.Header().Set("Access-Control-Max-Age", strconv.FormatInt(int64(rule.MaxAge/time.Second), 10))
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return 0, nil
+	}
+
+	if allowed {
+		rule.setOriginHeaders(w, origin)
+		if len(rule.ExposedHeaders) > 0 {
+			w.Header().Set("Access-Control-Expose-Headers", strings.Join(rule.ExposedHeaders, ", "))
+		}
+	}
+	return c.Next.ServeHTTP(w, r)
+}
+
+// match returns the rule with the longest path that p matches.
+func (c CORS) match(p string) (Rule, bool) {
+	var match Rule
+	var ok bool
+	for _, rule := range c.Rules {
+		if httpserver.Path(p).Matches(rule.Path) && (!ok || len(rule.Path) > len(match.Path)) {
+			match, ok = rule, true
+		}
+	}
+	return match, ok
+}
+
+// setOriginHeaders sets the headers that allow origin, which
+// is an allowed origin, to access the response.
+func (r Rule) setOriginHeaders(w http.ResponseWriter, origin string) {
+	if r.anyOrigin() && !r.Credentials {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		return
+	}
+	w.Header().Set("Access-Control-Allow-Origin", origin)
+	if r.Credentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+func (r Rule) anyOrigin() bool {
+	for _, o := range r.Origins {
+		if o == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+func (r Rule) allowsOrigin(origin string) bool {
+	for _, o := range r.Origins {
+		if o == "*" || strings.EqualFold(o, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r Rule) allowsMethod(method string) bool {
+	for _, m := range r.Methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
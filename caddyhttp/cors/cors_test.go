@@ -0,0 +1,152 @@
+package cors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func nextHandler(w http.ResponseWriter, r *http.Request) (int, error) {
+	return http.StatusOK, nil
+}
+
+func TestCORS(t *testing.T) {
+	c := CORS{
+		Next: httpserver.HandlerFunc(nextHandler),
+		Rules: []Rule{
+			{
+				Path:           "/api",
+				Origins:        []string{"https://example.com"},
+				Methods:        []string{"GET", "PUT"},
+				Headers:        []string{"Content-Type", "X-Token"},
+				ExposedHeaders: []string{"X-Total"},
+				Credentials:    true,
+				MaxAge:         10 * time.Minute,
+			},
+			{
+				Path:    "/public",
+				Origins: []string{"*"},
+				Methods: defaultMethods,
+			},
+		},
+	}
+
+	for i, test := range []struct {
+		method, path, origin, requestMethod string
+		status                              int
+		expected                            map[string]string
+	}{
+		// preflight request
+		{"OPTIONS", "/api/items", "https://example.com", "PUT", 0, map[string]string{
+			"Access-Control-Allow-Origin":      "https://example.com",
+			"Access-Control-Allow-Credentials": "true",
+			"Access-Control-Allow-Methods":     "GET, PUT",
+			"Access-Control-Allow-Headers":     "Content-Type, X-Token",
+			"Access-Control-Max-Age":           "600",
+			"Vary":                             "Origin",
+		}},
+		// preflight for a method that isn't allowed
+		{"OPTIONS", "/api/items", "https://example.com", "DELETE", http.StatusForbidden, map[string]string{
+			"Access-Control-Allow-Origin": "",
+		}},
+		// preflight from an origin that isn't allowed
+		{"OPTIONS", "/api/items", "https://evil.com", "PUT", http.StatusForbidden, map[string]string{
+			"Access-Control-Allow-Origin": "",
+		}},
+		// allowed actual request
+		{"GET", "/api/items", "https://example.com", "", http.StatusOK, map[string]string{
+			"Access-Control-Allow-Origin":      "https://example.com",
+			"Access-Control-Allow-Credentials": "true",
+			"Access-Control-Expose-Headers":    "X-Total",
+			"Access-Control-Allow-Methods":     "",
+			"Vary":                             "Origin",
+		}},
+		// actual request from an origin that isn't allowed
+		{"GET", "/api/items", "https://evil.com", "", http.StatusOK, map[string]string{
+			"Access-Control-Allow-Origin":      "",
+			"Access-Control-Allow-Credentials": "",
+			"Vary":                             "Origin",
+		}},
+		// same-origin request without Origin header
+		{"GET", "/api/items", "", "", http.StatusOK, map[string]string{
+			"Access-Control-Allow-Origin": "",
+		}},
+		// plain OPTIONS request is passed on
+		{"OPTIONS", "/api/items", "https://example.com", "", http.StatusOK, map[string]string{
+			"Access-Control-Allow-Origin":  "https://example.com",
+			"Access-Control-Allow-Methods": "",
+		}},
+		// wildcard origin
+		{"GET", "/public/file", "https://any.org", "", http.StatusOK, map[string]string{
+			"Access-Control-Allow-Origin": "*",
+			"Vary":                        "",
+		}},
+		// path without cors
+		{"GET", "/other", "https://example.com", "", http.StatusOK, map[string]string{
+			"Access-Control-Allow-Origin": "",
+			"Vary":                        "",
+		}},
+	} {
+		req, err := http.NewRequest(test.method, test.path, nil)
+		if err != nil {
+			t.Fatalf("Test %d: Could not create HTTP request: %v", i, err)
+		}
+		if test.origin != "" {
+			req.Header.Set("Origin", test.origin)
+		}
+		if test.requestMethod != "" {
+			req.Header.Set("Access-Control-Request-Method", test.requestMethod)
+		}
+
+		rec := httptest.NewRecorder()
+		status, err := c.ServeHTTP(rec, req)
+		if err != nil {
+			t.Fatalf("Test %d: Could not ServeHTTP: %v", i, err)
+		}
+		if status != test.status {
+			t.Errorf("Test %d: Expected status %d, got %d", i, test.status, status)
+		}
+		if test.status == 0 && rec.Code != http.StatusNoContent {
+			t.Errorf("Test %d: Expected preflight response %d, got %d", i, http.StatusNoContent, rec.Code)
+		}
+		for name, value := range test.expected {
+			if got := rec.Header().Get(name); got != value {
+				t.Errorf("Test %d: Expected %s header '%s', got '%s'", i, name, value, got)
+			}
+		}
+	}
+}
+
+func TestCORSCredentialsWithWildcard(t *testing.T) {
+	c := CORS{
+		Next:  httpserver.HandlerFunc(nextHandler),
+		Rules: []Rule{{Path: "/", Origins: []string{"*"}, Methods: defaultMethods, Credentials: true}},
+	}
+	req, err := http.NewRequest("OPTIONS", "/", nil)
+	if err != nil {
+		t.Fatalf("Could not create HTTP request: %v", err)
+	}
+	req.Header.Set("Origin", "https://example.com")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	req.Header.Set("Access-Control-Request-Headers", "X-Custom")
+
+	rec := httptest.NewRecorder()
+	if _, err := c.ServeHTTP(rec, req); err != nil {
+		t.Fatalf("Could not ServeHTTP: %v", err)
+	}
+	for name, value := range map[string]string{
+		"Access-Control-Allow-Origin":      "https://example.com",
+		"Access-Control-Allow-Credentials": "true",
+		"Access-Control-Allow-Headers":     "X-Custom",
+	} {
+		if got := rec.Header().Get(name); got != value {
+			t.Errorf("Expected %s header '%s', got '%s'", name, value, got)
+		}
+	}
+	if vary := rec.Header()["Vary"]; len(vary) != 2 || vary[0] != "Origin" || vary[1] != "Access-Control-Request-Headers" {
+		t.Errorf("Expected Vary headers Origin and Access-Control-Request-Headers, got %v", vary)
+	}
+}
@@ -0,0 +1,122 @@
+package cors
+
+import (
+	"strings"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func init() {
+	caddy.RegisterPlugin("cors_policy", caddy.Plugin{
+		ServerType: "http",
+		Action:     setup,
+	})
+}
+
+// defaultMethods are the methods allowed if none are configured.
+var defaultMethods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE"}
+
+// setup configures a new CORS middleware instance.
+func setup(c *caddy.Controller) error {
+	rules, err := corsParse(c)
+	if err != nil {
+		return err
+	}
+
+	httpserver.GetConfig(c).AddMiddleware(func(next httpserver.Handler) httpserver.Handler {
+		return CORS{Next: next, Rules: rules}
+	})
+
+	return nil
+}
+
+// corsParse parses the cors_policy directive, which is not called
+// cors so as not to clash with the plugin of that name:
+//
+//	cors_policy [path] {
+//		origins     origins...
+//		methods     methods...
+//		headers     headers...
+//		expose      headers...
+//		credentials
+//		max_age     <duration|seconds>
+//	}
+//
+// Any origin is allowed if no origins are given.
+func corsParse(c *caddy.Controller) ([]Rule, error) {
+	var rules []Rule
+	paths := make(map[string]bool)
+
+	for c.Next() {
+		rule := Rule{Path: "/"}
+
+		args := c.RemainingArgs()
+		switch len(args) {
+		case 0:
+		case 1:
+			rule.Path = args[0]
+		default:
+			return rules, c.ArgErr()
+		}
+		if paths[rule.Path] {
+			return rules, c.Errf("cors_policy for path '%s' already specified", rule.Path)
+		}
+		paths[rule.Path] = true
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "credentials":
+				rule.Credentials = true
+				if c.NextArg() {
+					return rules, c.ArgErr()
+				}
+				continue
+			case "max_age":
+				if !c.NextArg() {
+					return rules, c.ArgErr()
+				}
+				maxAge, err := httpserver.ParseMaxAge(c.Val())
+				if err != nil {
+					return rules, c.Err(err.Error())
+				}
+				rule.MaxAge = maxAge
+				if c.NextArg() {
+					return rules, c.ArgErr()
+				}
+				continue
+			case "origins", "methods", "headers", "expose":
+			default:
+				return rules, c.Errf("unknown subdirective '%s'", c.Val())
+			}
+
+			subdirective := c.Val()
+			values := c.RemainingArgs()
+			if len(values) == 0 {
+				return rules, c.ArgErr()
+			}
+			switch subdirective {
+			case "origins":
+				rule.Origins = append(rule.Origins, values...)
+			case "methods":
+				for _, method := range values {
+					rule.Methods = append(rule.Methods, strings.ToUpper(method))
+				}
+			case "headers":
+				rule.Headers = append(rule.Headers, values...)
+			case "expose":
+				rule.ExposedHeaders = append(rule.ExposedHeaders, values...)
+			}
+		}
+
+		if len(rule.Origins) == 0 {
+			rule.Origins = []string{"*"}
+		}
+		if len(rule.Methods) == 0 {
+			rule.Methods = defaultMethods
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
@@ -0,0 +1,85 @@
+package cors
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func TestSetup(t *testing.T) {
+	c := caddy.NewTestController("http", `cors_policy`)
+	err := setup(c)
+	if err != nil {
+		t.Errorf("Expected no errors, got: %v", err)
+	}
+	mids := httpserver.GetConfig(c).Middleware()
+	if len(mids) == 0 {
+		t.Fatal("Expected middleware, got 0 instead")
+	}
+
+	handler := mids[0](httpserver.EmptyNext)
+	myHandler, ok := handler.(CORS)
+	if !ok {
+		t.Fatalf("Expected handler to be type CORS, got: %#v", handler)
+	}
+	if !httpserver.SameNext(myHandler.Next, httpserver.EmptyNext) {
+		t.Error("'Next' field of handler was not set properly")
+	}
+}
+
+func TestCORSParse(t *testing.T) {
+	for i, test := range []struct {
+		input     string
+		shouldErr bool
+		expected  []Rule
+	}{
+		{`cors_policy`, false, []Rule{
+			{Path: "/", Origins: []string{"*"}, Methods: defaultMethods},
+		}},
+		{`cors_policy /api {
+			origins https://a.com https://b.com
+			methods get post
+			headers Content-Type
+			expose X-Total
+			credentials
+			max_age 1h
+		}`, false, []Rule{
+			{
+				Path:           "/api",
+				Origins:        []string{"https://a.com", "https://b.com"},
+				Methods:        []string{"GET", "POST"},
+				Headers:        []string{"Content-Type"},
+				ExposedHeaders: []string{"X-Total"},
+				Credentials:    true,
+				MaxAge:         time.Hour,
+			},
+		}},
+		{"cors_policy /a\ncors_policy /b {\n\tmax_age 60\n}", false, []Rule{
+			{Path: "/a", Origins: []string{"*"}, Methods: defaultMethods},
+			{Path: "/b", Origins: []string{"*"}, Methods: defaultMethods, MaxAge: time.Minute},
+		}},
+		{"cors_policy /a\ncors_policy /a", true, nil},
+		{`cors_policy /a /b`, true, nil},
+		{"cors_policy {\n\torigins\n}", true, nil},
+		{"cors_policy {\n\tcredentials yes\n}", true, nil},
+		{"cors_policy {\n\tmax_age forever\n}", true, nil},
+		{"cors_policy {\n\tmax_age\n}", true, nil},
+		{"cors_policy {\n\tallow_all\n}", true, nil},
+	} {
+		actual, err := corsParse(caddy.NewTestController("http", test.input))
+		if err == nil && test.shouldErr {
+			t.Errorf("Test %d didn't error, but it should have", i)
+		} else if err != nil && !test.shouldErr {
+			t.Errorf("Test %d errored, but it shouldn't have; got '%v'", i, err)
+		}
+		if test.shouldErr {
+			continue
+		}
+		if got, want := fmt.Sprintf("%+v", actual), fmt.Sprintf("%+v", test.expected); got != want {
+			t.Errorf("Test %d: Expected rules %s, got %s", i, want, got)
+		}
+	}
+}
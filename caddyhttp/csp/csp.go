@@ -0,0 +1,156 @@
+// Package csp is middleware that builds and sends a
+// Content-Security-Policy header.
+package csp
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"strings"
+
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+// NoncePlaceholder is the placeholder, without braces, for the nonce
+// that is generated for each request whose policy refers to it. It can
+// be used in the policy, as in 'nonce-{csp_nonce}', and in templates,
+// as in {{.Placeholder "csp_nonce"}}, to allow specific inline scripts.
+const NoncePlaceholder = "csp_nonce"
+
+// CSP is middleware that adds a Content-Security-Policy header, as
+// configured by the most specific rule that matches the request path.
+type CSP struct {
+	Next  httpserver.Handler
+	Rules []httpserver.HandlerConfig
+}
+
+// ServeHTTP implements the httpserver.Handler interface.
+func (c CSP) ServeHTTP(w http.ResponseWriter, r *http.Request) (int, error) {
+	if rule, ok := httpserver.ConfigSelector(c.Rules).Select(r).(*Rule); ok {
+		var nonce string
+		if rule.usesNonce() {
+			var err error
+			if nonce, err = newNonce(); err != nil {
+				return http.StatusInternalServerError, err
+			}
+			httpserver.SetPlaceholder(r, NoncePlaceholder, nonce)
+		}
+		w.Header().Set(rule.HeaderName(), rule.Policy(nonce))
+	}
+	return c.Next.ServeHTTP(w, r)
+}
+
+// Rule is a policy for the requests under a path.
+type Rule struct {
+	Path       string
+	Directives []Directive
+
+	// ReportOnly sends the policy in the report-only header,
+	// so that violations are reported but not enforced
+	ReportOnly bool
+}
+
+// Directive is a policy directive and its values, often a source list.
+type Directive struct {
+	Name   string
+	Values []string
+}
+
+// BasePath satisfies httpserver.HandlerConfig.
+func (r *Rule) BasePath() string { return r.Path }
+
+// Match satisfies httpserver.HandlerConfig.
+func (r *Rule) Match(req *http.Request) bool {
+	return httpserver.Path(req.URL.Path).Matches(r.Path)
+}
+
+// HeaderName returns the name of the header that carries the policy.
+func (r *Rule) HeaderName() string {
+	if r.ReportOnly {
+		return "Content-Security-Policy-Report-Only"
+	}
+	return "Content-Security-Policy"
+}
+
+// Policy returns the policy as a header value, with nonce in place
+// of the nonce placeholder. A fetch directive with an empty source
+// list allows nothing, so 'none' is written out for clarity.
+func (r *Rule) Policy(nonce string) string {
+	parts := make([]string, 0, len(r.Directives))
+	for _, d := range r.Directives {
+		values := d.Values
+		if len(values) == 0 && directives[d.Name] == sourceList {
+			values = []string{"'none'"}
+		}
+		part := d.Name
+		if len(values) > 0 {
+			part += " " + strings.Join(values, " ")
+		}
+		parts = append(parts, strings.Replace(part, "{"+NoncePlaceholder+"}", nonce, -1))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// usesNonce reports whether the policy refers to the nonce.
+func (r *Rule) usesNonce() bool {
+	for _, d := range r.Directives {
+		for _, v := range d.Values {
+			if strings.Contains(v, "{"+NoncePlaceholder+"}") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// newNonce returns a random, base64-encoded 128-bit nonce.
+func newNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// directiveKind is the kind of values a directive takes.
+type directiveKind int
+
+const (
+	sourceList directiveKind = iota
+	otherValues
+	noValues
+)
+
+// directives are the known policy directives and their kinds.
+var directives = map[string]directiveKind{
+	"default-src":               sourceList,
+	"script-src":                sourceList,
+	"script-src-elem":           sourceList,
+	"script-src-attr":           sourceList,
+	"style-src":                 sourceList,
+	"style-src-elem":            sourceList,
+	"style-src-attr":            sourceList,
+	"img-src":                   sourceList,
+	"connect-src":               sourceList,
+	"font-src":                  sourceList,
+	"object-src":                sourceList,
+	"media-src":                 sourceList,
+	"frame-src":                 sourceList,
+	"child-src":                 sourceList,
+	"worker-src":                sourceList,
+	"manifest-src":              sourceList,
+	"prefetch-src":              sourceList,
+	"base-uri":                  sourceList,
+	"form-action":               sourceList,
+	"frame-ancestors":           sourceList,
+	"navigate-to":               sourceList,
+	"plugin-types":              otherValues,
+	"sandbox":                   otherValues,
+	"report-uri":                otherValues,
+	"report-to":                 otherValues,
+	"require-sri-for":           otherValues,
+	"require-trusted-types-for": otherValues,
+	"trusted-types":             otherValues,
+	"upgrade-insecure-requests": noValues,
+	"block-all-mixed-content":   noValues,
+}
@@ -0,0 +1,106 @@
+package csp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func TestPolicy(t *testing.T) {
+	for i, test := range []struct {
+		directives []Directive
+		nonce      string
+		expected   string
+	}{
+		{[]Directive{{"default-src", []string{"'self'"}}}, "", "default-src 'self'"},
+		{[]Directive{
+			{"default-src", []string{"'self'"}},
+			{"script-src", []string{"'self'", "https://cdn.example.com"}},
+			{"upgrade-insecure-requests", nil},
+		}, "", "default-src 'self'; script-src 'self' https://cdn.example.com; upgrade-insecure-requests"},
+		// empty source lists allow nothing
+		{[]Directive{{"object-src", nil}, {"frame-ancestors", nil}, {"sandbox", nil}}, "", "object-src 'none'; frame-ancestors 'none'; sandbox"},
+		{[]Directive{{"script-src", []string{"'nonce-{csp_nonce}'", "'strict-dynamic'"}}}, "abc=", "script-src 'nonce-abc=' 'strict-dynamic'"},
+	} {
+		rule := &Rule{Path: "/", Directives: test.directives}
+		if got := rule.Policy(test.nonce); got != test.expected {
+			t.Errorf("Test %d: Expected policy %q, got %q", i, test.expected, got)
+		}
+	}
+}
+
+func TestCSP(t *testing.T) {
+	c := CSP{
+		Next: httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+			return 0, nil
+		}),
+		Rules: []httpserver.HandlerConfig{
+			&Rule{Path: "/", Directives: []Directive{{"default-src", []string{"'self'"}}}},
+			&Rule{Path: "/beta", Directives: []Directive{{"default-src", []string{"'none'"}}}, ReportOnly: true},
+		},
+	}
+
+	for i, test := range []struct {
+		path, header, expected string
+	}{
+		{"/", "Content-Security-Policy", "default-src 'self'"},
+		{"/beta/page", "Content-Security-Policy-Report-Only", "default-src 'none'"},
+	} {
+		req, err := http.NewRequest("GET", test.path, nil)
+		if err != nil {
+			t.Fatalf("Test %d: Could not create HTTP request: %v", i, err)
+		}
+		rec := httptest.NewRecorder()
+		c.ServeHTTP(rec, req)
+
+		if got := rec.Header().Get(test.header); got != test.expected {
+			t.Errorf("Test %d: Expected %s %q, got %q", i, test.header, test.expected, got)
+		}
+		if len(rec.HeaderMap) != 1 {
+			t.Errorf("Test %d: Expected only the %s header, got %v", i, test.header, rec.HeaderMap)
+		}
+	}
+}
+
+func TestCSPNonce(t *testing.T) {
+	var bodyNonce string
+	c := CSP{
+		Next: httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+			// like a template would, with {{.Placeholder "csp_nonce"}}
+			ctx := httpserver.Context{Req: r, URL: r.URL}
+			bodyNonce = ctx.Placeholder(NoncePlaceholder)
+			return 0, nil
+		}),
+		Rules: []httpserver.HandlerConfig{
+			&Rule{Path: "/", Directives: []Directive{{"script-src", []string{"'nonce-{csp_nonce}'"}}}},
+		},
+	}
+
+	seen := make(map[string]bool)
+	for i := 0; i < 5; i++ {
+		req, err := http.NewRequest("GET", "/", nil)
+		if err != nil {
+			t.Fatalf("Could not create HTTP request: %v", err)
+		}
+		req = req.WithContext(context.WithValue(req.Context(), httpserver.PlaceholdersCtxKey, make(map[string]string)))
+		rec := httptest.NewRecorder()
+		c.ServeHTTP(rec, req)
+
+		policy := rec.Header().Get("Content-Security-Policy")
+		nonce := strings.TrimSuffix(strings.TrimPrefix(policy, "script-src 'nonce-"), "'")
+		if len(nonce) != 24 || policy != "script-src 'nonce-"+nonce+"'" {
+			t.Fatalf("Request %d: Expected a policy with a nonce, got %q", i, policy)
+		}
+		if bodyNonce != nonce {
+			t.Errorf("Request %d: Expected the same nonce in the header and body, got %q and %q", i, nonce, bodyNonce)
+		}
+		if seen[nonce] {
+			t.Errorf("Request %d: Nonce %q was used before", i, nonce)
+		}
+		seen[nonce] = true
+	}
+}
@@ -0,0 +1,103 @@
+package csp
+
+import (
+	"strings"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func init() {
+	caddy.RegisterPlugin("csp", caddy.Plugin{
+		ServerType: "http",
+		Action:     setup,
+	})
+}
+
+// setup configures a new CSP middleware instance.
+func setup(c *caddy.Controller) error {
+	rules, err := cspParse(c)
+	if err != nil {
+		return err
+	}
+
+	httpserver.GetConfig(c).AddMiddleware(func(next httpserver.Handler) httpserver.Handler {
+		return CSP{Next: next, Rules: rules}
+	})
+
+	return nil
+}
+
+// cspParse parses the directive:
+//
+//	csp [path] {
+//		<directive> [values...]
+//		report_only
+//	}
+//
+// Directives given more than once have their values merged.
+func cspParse(c *caddy.Controller) ([]httpserver.HandlerConfig, error) {
+	var rules []httpserver.HandlerConfig
+
+	for c.Next() {
+		rule := &Rule{Path: "/"}
+		args := c.RemainingArgs()
+		switch len(args) {
+		case 0:
+		case 1:
+			rule.Path = args[0]
+		default:
+			return nil, c.ArgErr()
+		}
+		for _, other := range rules {
+			if other.BasePath() == rule.Path {
+				return nil, c.Errf("duplicate csp path '%s'", rule.Path)
+			}
+		}
+
+		for c.NextBlock() {
+			name := c.Val()
+			values := c.RemainingArgs()
+			if name == "report_only" {
+				if len(values) > 0 {
+					return nil, c.ArgErr()
+				}
+				rule.ReportOnly = true
+				continue
+			}
+
+			name = strings.ToLower(name)
+			kind, ok := directives[name]
+			if !ok {
+				return nil, c.Errf("unknown Content-Security-Policy directive '%s'", c.Val())
+			}
+			if kind == noValues && len(values) > 0 {
+				return nil, c.Errf("csp directive '%s' takes no values", name)
+			}
+			for _, v := range values {
+				if strings.ContainsAny(v, ";,") {
+					return nil, c.Errf("invalid value '%s' for csp directive '%s'", v, name)
+				}
+			}
+			rule.add(name, values)
+		}
+
+		if len(rule.Directives) == 0 {
+			return nil, c.Err("csp requires at least one policy directive")
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+// add adds the values to the directive called name.
+func (r *Rule) add(name string, values []string) {
+	for i := range r.Directives {
+		if r.Directives[i].Name == name {
+			r.Directives[i].Values = append(r.Directives[i].Values, values...)
+			return
+		}
+	}
+	r.Directives = append(r.Directives, Directive{Name: name, Values: values})
+}
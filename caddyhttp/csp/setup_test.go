@@ -0,0 +1,102 @@
+package csp
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func TestSetup(t *testing.T) {
+	c := caddy.NewTestController("http", `csp {
+		default-src 'self'
+	}`)
+	err := setup(c)
+	if err != nil {
+		t.Errorf("Expected no errors, got: %v", err)
+	}
+	mids := httpserver.GetConfig(c).Middleware()
+	if len(mids) == 0 {
+		t.Fatal("Expected middleware, got 0 instead")
+	}
+
+	handler := mids[0](httpserver.EmptyNext)
+	myHandler, ok := handler.(CSP)
+	if !ok {
+		t.Fatalf("Expected handler to be type CSP, got: %#v", handler)
+	}
+	if !httpserver.SameNext(myHandler.Next, httpserver.EmptyNext) {
+		t.Error("'Next' field of handler was not set properly")
+	}
+}
+
+func TestCSPParse(t *testing.T) {
+	for i, test := range []struct {
+		input     string
+		shouldErr bool
+		expected  []httpserver.HandlerConfig
+	}{
+		{`csp {
+			default-src 'self'
+			Script-Src 'self' https://cdn.example.com
+			script-src 'nonce-{csp_nonce}'
+			object-src
+			upgrade-insecure-requests
+		}`, false, []httpserver.HandlerConfig{&Rule{Path: "/", Directives: []Directive{
+			{"default-src", []string{"'self'"}},
+			{"script-src", []string{"'self'", "https://cdn.example.com", "'nonce-{csp_nonce}'"}},
+			{"object-src", nil},
+			{"upgrade-insecure-requests", nil},
+		}}}},
+		{`csp /admin {
+			default-src 'none'
+			report-uri /csp-report
+			report_only
+		}
+		csp {
+			default-src 'self'
+		}`, false, []httpserver.HandlerConfig{
+			&Rule{Path: "/admin", ReportOnly: true, Directives: []Directive{
+				{"default-src", []string{"'none'"}},
+				{"report-uri", []string{"/csp-report"}},
+			}},
+			&Rule{Path: "/", Directives: []Directive{{"default-src", []string{"'self'"}}}},
+		}},
+		{`csp`, true, nil},
+		{`csp {
+			report_only
+		}`, true, nil},
+		{`csp / /admin {
+			default-src 'self'
+		}`, true, nil},
+		{`csp {
+			default_src 'self'
+		}`, true, nil},
+		{`csp {
+			upgrade-insecure-requests yes
+		}`, true, nil},
+		{`csp {
+			default-src 'self'; script-src *
+		}`, true, nil},
+		{`csp {
+			report_only true
+		}`, true, nil},
+		{`csp {
+			default-src 'self'
+		}
+		csp {
+			img-src *
+		}`, true, nil},
+	} {
+		actual, err := cspParse(caddy.NewTestController("http", test.input))
+		if err == nil && test.shouldErr {
+			t.Errorf("Test %d didn't error, but it should have", i)
+		} else if err != nil && !test.shouldErr {
+			t.Errorf("Test %d errored, but it shouldn't have; got '%v'", i, err)
+		}
+		if !test.shouldErr && !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("Test %d: Expected %+v, got %+v", i, test.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+// Package digestauth implements HTTP Digest Access Authentication
+// (RFC 7616) for Caddy.
+//
+// Credentials are configured like those of basicauth, a username and
+// password that protect a set of resources, and its rules decide which
+// requests are protected. Unlike basicauth, the password has to be
+// known in plaintext, since the server computes the same digest as the
+// client; hashed htpasswd files can't be used.
+package digestauth
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"hash"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/mholt/caddy/caddyhttp/basicauth"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+// DigestAuth is middleware to protect resources with a username and
+// password without sending the password over the wire. Only the "auth"
+// quality of protection is supported; the request body is not protected,
+// so HTTPS should still be used for anything important.
+type DigestAuth struct {
+	Next   httpserver.Handler
+	Realm  string
+	Rules  []Rule
+	Nonces *NonceStore
+}
+
+// Rule represents a DigestAuth rule: a basicauth rule, which
+// decides what it protects, with the password in plaintext.
+// The password matcher of the basicauth rule is not used.
+type Rule struct {
+	basicauth.Rule
+	Password string
+}
+
+// algorithms are the supported digest algorithms in order of
+// preference, which is the order the challenges are sent in.
+var algorithms = []struct {
+	name string
+	hash func() hash.Hash
+}{
+	{"SHA-256", sha256.New},
+	{"MD5", md5.New},
+}
+
+// ServeHTTP implements the httpserver.Handler interface.
+func (d DigestAuth) ServeHTTP(w http.ResponseWriter, r *http.Request) (int, error) {
+	var rules []Rule
+	for _, rule := range d.Rules {
+		if rule.Protects(r.URL.Path) {
+			rules = append(rules, rule)
+		}
+	}
+	if len(rules) == 0 {
+		// Pass-through when no paths match
+		return d.Next.ServeHTTP(w, r)
+	}
+
+	var stale bool
+	if params, ok := parseAuthorization(r.Header.Get("Authorization")); ok {
+		var authenticated bool
+		authenticated, stale = d.authenticate(r, rules, params)
+		if authenticated {
+			// remove credentials from request to avoid leaking upstream
+			r.Header.Del("Authorization")
+			return d.Next.ServeHTTP(w, r)
+		}
+	}
+
+	nonce, err := d.Nonces.Issue()
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	for _, alg := range algorithms {
+		challenge := `Digest realm="` + d.Realm + `", qop="auth", algorithm=` + alg.name + `, nonce="` + nonce + `"`
+		if stale {
+			challenge += ", stale=true"
+		}
+		w.Header().Add("WWW-Authenticate", challenge)
+	}
+	return http.StatusUnauthorized, nil
+}
+
+// authenticate checks the digest credentials params of r against
+// rules. If the response is correct but the nonce has expired, stale
+// is true so that the client can retry with a new nonce without
+// asking the user for their password again.
+func (d DigestAuth) authenticate(r *http.Request, rules []Rule, params map[string]string) (ok, stale bool) {
+	if params["realm"] != d.Realm || params["qop"] != "auth" || params["cnonce"] == "" {
+		return false, false
+	}
+	uri := r.RequestURI
+	if uri == "" {
+		uri = r.URL.RequestURI()
+	}
+	if params["uri"] != uri {
+		return false, false
+	}
+	nc, err := strconv.ParseUint(params["nc"], 16, 64)
+	if err != nil || nc == 0 {
+		return false, false
+	}
+	alg := params["algorithm"]
+	if alg == "" {
+		alg = "MD5"
+	}
+	var newHash func() hash.Hash
+	for _, a := range algorithms {
+		if strings.EqualFold(a.name, alg) {
+			newHash = a.hash
+		}
+	}
+	if newHash == nil {
+		return false, false
+	}
+
+	h := func(s string) string {
+		sum := newHash()
+		sum.Write([]byte(s))
+		return hex.EncodeToString(sum.Sum(nil))
+	}
+
+	// compute a digest even for unknown users, so that they
+	// can't be told apart from known ones by response time
+	username := params["username"]
+	password, known := "", false
+	for _, rule := range rules {
+		if rule.Username == username {
+			password, known = rule.Password, true
+			break
+		}
+	}
+	ha1 := h(username + ":" + d.Realm + ":" + password)
+	ha2 := h(r.Method + ":" + params["uri"])
+	expected := h(ha1 + ":" + params["nonce"] + ":" + params["nc"] + ":" + params["cnonce"] + ":auth:" + ha2)
+	if subtle.ConstantTimeCompare([]byte(expected), []byte(params["response"])) != 1 || !known {
+		return false, false
+	}
+
+	return d.Nonces.Use(params["nonce"], nc)
+}
+
+// parseAuthorization parses the parameters of a Digest Authorization
+// header. Parameter names are lowercased.
+func parseAuthorization(header string) (map[string]string, bool) {
+	const prefix = "Digest "
+	if len(header) < len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return nil, false
+	}
+	s := header[len(prefix):]
+	params := make(map[string]string)
+	for {
+		s = strings.TrimLeft(s, " \t,")
+		if s == "" {
+			return params, true
+		}
+		i := strings.IndexByte(s, '=')
+		if i <= 0 {
+			return nil, false
+		}
+		name := strings.ToLower(strings.TrimSpace(s[:i]))
+		s = strings.TrimLeft(s[i+1:], " \t")
+
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			var b []byte
+			i = 1
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				b = append(b, s[i])
+			}
+			if i == len(s) {
+				return nil, false
+			}
+			value, s = string(b), s[i+1:]
+		} else {
+			i = strings.IndexAny(s, ", \t")
+			if i < 0 {
+				i = len(s)
+			}
+			value, s = s[:i], s[i:]
+		}
+		params[name] = value
+	}
+}
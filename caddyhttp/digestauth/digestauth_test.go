@@ -0,0 +1,249 @@
+package digestauth
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mholt/caddy/caddyhttp/basicauth"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func newTestDigestAuth() DigestAuth {
+	return DigestAuth{
+		Next:   httpserver.HandlerFunc(contentHandler),
+		Realm:  "Restricted",
+		Rules:  []Rule{{Rule: basicauth.Rule{Username: "okuser", Resources: []string{"/testing"}}, Password: "okpass"}},
+		Nonces: NewNonceStore(time.Minute),
+	}
+}
+
+func contentHandler(w http.ResponseWriter, r *http.Request) (int, error) {
+	fmt.Fprint(w, r.URL.String())
+	return http.StatusOK, nil
+}
+
+// challenge requests uri without credentials and returns the
+// parameters of the challenge for algorithm alg.
+func challenge(t *testing.T, d DigestAuth, uri, alg string) map[string]string {
+	req, err := http.NewRequest("GET", uri, nil)
+	if err != nil {
+		t.Fatalf("Could not create HTTP request: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	status, err := d.ServeHTTP(rec, req)
+	if err != nil {
+		t.Fatalf("Could not ServeHTTP: %v", err)
+	}
+	if status != http.StatusUnauthorized {
+		t.Fatalf("Expected status %d without credentials, got %d", http.StatusUnauthorized, status)
+	}
+	for _, header := range rec.Header()["Www-Authenticate"] {
+		params, ok := parseAuthorization(header)
+		if ok && params["algorithm"] == alg {
+			return params
+		}
+	}
+	t.Fatalf("No %s challenge in %v", alg, rec.Header()["Www-Authenticate"])
+	return nil
+}
+
+// authorization computes the Authorization header a client sends
+// in response to the challenge params.
+func authorization(params map[string]string, method, uri, user, pass, nc string) string {
+	var newHash func() hash.Hash = md5.New
+	if params["algorithm"] == "SHA-256" {
+		newHash = sha256.New
+	}
+	h := func(s string) string {
+		sum := newHash()
+		sum.Write([]byte(s))
+		return hex.EncodeToString(sum.Sum(nil))
+	}
+	const cnonce = "0a4f113b"
+	ha1 := h(user + ":" + params["realm"] + ":" + pass)
+	ha2 := h(method + ":" + uri)
+	response := h(ha1 + ":" + params["nonce"] + ":" + nc + ":" + cnonce + ":auth:" + ha2)
+	return fmt.Sprintf(`Digest username="%s", realm="%s", nonce="%s", uri="%s", algorithm=%s, qop=auth, nc=%s, cnonce="%s", response="%s"`,
+		user, params["realm"], params["nonce"], uri, params["algorithm"], nc, cnonce, response)
+}
+
+func serveWithAuth(t *testing.T, d DigestAuth, uri, auth string) (int, *httptest.ResponseRecorder, *http.Request) {
+	req, err := http.NewRequest("GET", uri, nil)
+	if err != nil {
+		t.Fatalf("Could not create HTTP request: %v", err)
+	}
+	req.Header.Set("Authorization", auth)
+	rec := httptest.NewRecorder()
+	status, err := d.ServeHTTP(rec, req)
+	if err != nil {
+		t.Fatalf("Could not ServeHTTP: %v", err)
+	}
+	return status, rec, req
+}
+
+func TestDigestAuthHandshake(t *testing.T) {
+	for _, alg := range []string{"MD5", "SHA-256"} {
+		d := newTestDigestAuth()
+		params := challenge(t, d, "/testing?a=b", alg)
+		if params["realm"] != "Restricted" || params["qop"] != "auth" || params["nonce"] == "" {
+			t.Errorf("%s: Unexpected challenge %v", alg, params)
+		}
+
+		for i, test := range []struct {
+			user, pass, nc string
+			expected       int
+		}{
+			{"okuser", "okpass", "00000001", http.StatusOK},
+			{"okuser", "okpass", "00000002", http.StatusOK},
+			{"okuser", "badpass", "00000003", http.StatusUnauthorized},
+			{"baduser", "okpass", "00000004", http.StatusUnauthorized},
+			{"okuser", "okpass", "00000005", http.StatusOK},
+		} {
+			auth := authorization(params, "GET", "/testing?a=b", test.user, test.pass, test.nc)
+			status, _, req := serveWithAuth(t, d, "/testing?a=b", auth)
+			if status != test.expected {
+				t.Errorf("%s: Test %d: Expected status %d, got %d", alg, i, test.expected, status)
+			}
+			if status == http.StatusOK && req.Header.Get("Authorization") != "" {
+				t.Errorf("%s: Test %d: Expected Authorization header to be stripped", alg, i)
+			}
+		}
+	}
+}
+
+func TestDigestAuthReplay(t *testing.T) {
+	d := newTestDigestAuth()
+	params := challenge(t, d, "/testing", "SHA-256")
+	auth := authorization(params, "GET", "/testing", "okuser", "okpass", "00000001")
+
+	if status, _, _ := serveWithAuth(t, d, "/testing", auth); status != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, status)
+	}
+	status, rec, _ := serveWithAuth(t, d, "/testing", auth)
+	if status != http.StatusUnauthorized {
+		t.Errorf("Expected replayed request to be rejected, got status %d", status)
+	}
+	if h := rec.Header().Get("Www-Authenticate"); strings.Contains(h, "stale=true") {
+		t.Errorf("Expected replayed request not to be told the nonce is stale, got %s", h)
+	}
+}
+
+func TestDigestAuthStaleNonce(t *testing.T) {
+	d := newTestDigestAuth()
+	params := challenge(t, d, "/testing", "MD5")
+	d.Nonces.nonces[params["nonce"]].issued = time.Now().Add(-2 * time.Minute)
+
+	auth := authorization(params, "GET", "/testing", "okuser", "okpass", "00000001")
+	status, rec, _ := serveWithAuth(t, d, "/testing", auth)
+	if status != http.StatusUnauthorized {
+		t.Fatalf("Expected status %d with expired nonce, got %d", http.StatusUnauthorized, status)
+	}
+	if h := rec.Header().Get("Www-Authenticate"); !strings.Contains(h, "stale=true") {
+		t.Errorf("Expected stale=true in challenge, got %s", h)
+	}
+
+	// a wrong password with an expired nonce is not stale
+	auth = authorization(params, "GET", "/testing", "okuser", "badpass", "00000002")
+	if _, rec, _ = serveWithAuth(t, d, "/testing", auth); strings.Contains(rec.Header().Get("Www-Authenticate"), "stale=true") {
+		t.Errorf("Expected no stale=true for a wrong password, got %s", rec.Header().Get("Www-Authenticate"))
+	}
+}
+
+func TestDigestAuthWrongURI(t *testing.T) {
+	d := newTestDigestAuth()
+	params := challenge(t, d, "/testing", "MD5")
+	auth := authorization(params, "GET", "/testing/other", "okuser", "okpass", "00000001")
+	if status, _, _ := serveWithAuth(t, d, "/testing", auth); status != http.StatusUnauthorized {
+		t.Errorf("Expected status %d for mismatched uri, got %d", http.StatusUnauthorized, status)
+	}
+}
+
+func TestDigestAuthUnprotected(t *testing.T) {
+	d := newTestDigestAuth()
+	req, err := http.NewRequest("GET", "/public", nil)
+	if err != nil {
+		t.Fatalf("Could not create HTTP request: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	status, err := d.ServeHTTP(rec, req)
+	if err != nil {
+		t.Fatalf("Could not ServeHTTP: %v", err)
+	}
+	if status != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, status)
+	}
+	if h := rec.Header().Get("Www-Authenticate"); h != "" {
+		t.Errorf("Expected no challenge, got %s", h)
+	}
+}
+
+func TestParseAuthorization(t *testing.T) {
+	for i, test := range []struct {
+		header   string
+		ok       bool
+		expected map[string]string
+	}{
+		{`Digest username="a b", nc=00000001, qop=auth`, true,
+			map[string]string{"username": "a b", "nc": "00000001", "qop": "auth"}},
+		{`digest Realm="with \"quotes\", and comma"`, true,
+			map[string]string{"realm": `with "quotes", and comma`}},
+		{`Basic dXNlcjpwYXNz`, false, nil},
+		{`Digest username="unterminated`, false, nil},
+		{`Digest novalue`, false, nil},
+	} {
+		params, ok := parseAuthorization(test.header)
+		if ok != test.ok {
+			t.Errorf("Test %d: Expected ok=%t, got %t", i, test.ok, ok)
+			continue
+		}
+		if fmt.Sprint(params) != fmt.Sprint(test.expected) {
+			t.Errorf("Test %d: Expected %v, got %v", i, test.expected, params)
+		}
+	}
+}
+
+func TestNonceStoreForgetsOldest(t *testing.T) {
+	s := NewNonceStore(time.Minute)
+
+	// expired nonces are forgotten as new ones are issued
+	expired, err := s.Issue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.nonces[expired].issued = time.Now().Add(-2 * time.Minute)
+	if _, err := s.Issue(); err != nil {
+		t.Fatal(err)
+	}
+	if _, known := s.nonces[expired]; known || len(s.issued) != 1 {
+		t.Errorf("Expected the expired nonce to be forgotten, got %d nonces", len(s.issued))
+	}
+
+	// when full, the oldest nonce makes room for a new one
+	oldest := s.issued[0].nonce
+	for len(s.nonces) < maxNonces {
+		if _, err := s.Issue(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	second := s.issued[1].nonce
+	if _, err := s.Issue(); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.nonces) != maxNonces {
+		t.Errorf("Expected %d nonces, got %d", maxNonces, len(s.nonces))
+	}
+	if ok, stale := s.Use(oldest, 1); ok || !stale {
+		t.Error("Expected the oldest nonce to be forgotten")
+	}
+	if ok, _ := s.Use(second, 1); !ok {
+		t.Error("Expected the second oldest nonce to be kept")
+	}
+}
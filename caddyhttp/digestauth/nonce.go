@@ -0,0 +1,98 @@
+package digestauth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// maxNonces is the most nonces a NonceStore keeps track of. Every
+// challenge issues one, so without a limit unauthenticated clients
+// could make the store grow until the nonces expire.
+const maxNonces = 100000
+
+// NonceStore issues nonces and keeps track of their use, so that
+// requests can't be replayed. It is safe for concurrent use.
+type NonceStore struct {
+	// Lifetime is how long a nonce is valid after it is issued.
+	Lifetime time.Duration
+
+	mu     sync.Mutex
+	nonces map[string]*nonceState
+
+	// issued holds the nonces in the order they were issued, which
+	// is the order they expire in, so that the ones that are gone
+	// can be forgotten from the front without going through them all
+	issued []*nonceState
+}
+
+type nonceState struct {
+	nonce  string
+	issued time.Time
+	count  uint64
+}
+
+// NewNonceStore returns a NonceStore whose nonces are valid for lifetime.
+func NewNonceStore(lifetime time.Duration) *NonceStore {
+	return &NonceStore{
+		Lifetime: lifetime,
+		nonces:   make(map[string]*nonceState),
+	}
+}
+
+// Issue returns a new nonce. If the store is full, the nonce that
+// is closest to expiring is forgotten to make room for it.
+func (s *NonceStore) Issue() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	state := &nonceState{nonce: hex.EncodeToString(b), issued: time.Now()}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for len(s.issued) > 0 {
+		oldest := s.issued[0]
+		if state.issued.Sub(oldest.issued) <= s.Lifetime && len(s.nonces) < maxNonces {
+			break
+		}
+		s.forgetOldest()
+	}
+	s.nonces[state.nonce] = state
+	s.issued = append(s.issued, state)
+	return state.nonce, nil
+}
+
+// forgetOldest forgets the nonce that was issued first.
+// s.mu must be locked.
+func (s *NonceStore) forgetOldest() {
+	oldest := s.issued[0]
+	s.issued[0] = nil
+	s.issued = s.issued[1:]
+	if s.nonces[oldest.nonce] == oldest {
+		delete(s.nonces, oldest.nonce)
+	}
+}
+
+// Use records the use of nonce with the nonce count nc. It reports
+// whether the use is valid, which it is only if nc is higher than any
+// count the nonce was used with before. If the nonce has expired or is
+// unknown, for example because the server was restarted, stale is true.
+func (s *NonceStore) Use(nonce string, nc uint64) (ok, stale bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, known := s.nonces[nonce]
+	if !known {
+		return false, true
+	}
+	if time.Since(state.issued) > s.Lifetime {
+		delete(s.nonces, nonce)
+		return false, true
+	}
+	if nc <= state.count {
+		return false, false
+	}
+	state.count = nc
+	return true, false
+}
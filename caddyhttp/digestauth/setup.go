@@ -0,0 +1,91 @@
+package digestauth
+
+import (
+	"time"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func init() {
+	caddy.RegisterPlugin("digestauth", caddy.Plugin{
+		ServerType: "http",
+		Action:     setup,
+	})
+}
+
+// defaultRealm is the realm of the protection space.
+const defaultRealm = "Restricted"
+
+// nonceLifetime is how long a nonce is valid; after that, clients
+// are asked to authenticate again with a new one.
+const nonceLifetime = 5 * time.Minute
+
+// setup configures a new DigestAuth middleware instance.
+func setup(c *caddy.Controller) error {
+	rules, err := digestAuthParse(c)
+	if err != nil {
+		return err
+	}
+
+	digest := DigestAuth{
+		Realm:  defaultRealm,
+		Rules:  rules,
+		Nonces: NewNonceStore(nonceLifetime),
+	}
+
+	httpserver.GetConfig(c).AddMiddleware(func(next httpserver.Handler) httpserver.Handler {
+		digest.Next = next
+		return digest
+	})
+
+	return nil
+}
+
+// digestAuthParse parses the digestauth directive, which takes
+// the same forms as basicauth with plaintext passwords:
+//
+//	digestauth user password {
+//		resources...
+//		except paths...
+//	}
+//	digestauth resource user password
+func digestAuthParse(c *caddy.Controller) ([]Rule, error) {
+	var rules []Rule
+
+	for c.Next() {
+		var rule Rule
+
+		args := c.RemainingArgs()
+
+		switch len(args) {
+		case 2:
+			rule.Username = args[0]
+			rule.Password = args[1]
+			for c.NextBlock() {
+				if c.Val() == "except" {
+					paths := c.RemainingArgs()
+					if len(paths) == 0 {
+						return rules, c.ArgErr()
+					}
+					rule.Exceptions = append(rule.Exceptions, paths...)
+					continue
+				}
+				rule.Resources = append(rule.Resources, c.Val())
+				if c.NextArg() {
+					return rules, c.Errf("Expecting only one resource per line (extra '%s')", c.Val())
+				}
+			}
+		case 3:
+			rule.Resources = append(rule.Resources, args[0])
+			rule.Username = args[1]
+			rule.Password = args[2]
+		default:
+			return rules, c.ArgErr()
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
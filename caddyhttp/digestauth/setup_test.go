@@ -0,0 +1,89 @@
+package digestauth
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/basicauth"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func TestSetup(t *testing.T) {
+	c := caddy.NewTestController("http", `digestauth /admin user pwd`)
+	err := setup(c)
+	if err != nil {
+		t.Errorf("Expected no errors, but got: %v", err)
+	}
+	mids := httpserver.GetConfig(c).Middleware()
+	if len(mids) == 0 {
+		t.Fatal("Expected middleware, got 0 instead")
+	}
+
+	handler := mids[0](httpserver.EmptyNext)
+	myHandler, ok := handler.(DigestAuth)
+	if !ok {
+		t.Fatalf("Expected handler to be type DigestAuth, got: %#v", handler)
+	}
+	if !httpserver.SameNext(myHandler.Next, httpserver.EmptyNext) {
+		t.Error("'Next' field of handler was not set properly")
+	}
+	if myHandler.Nonces == nil {
+		t.Error("Expected a nonce store")
+	}
+}
+
+func TestDigestAuthParse(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+		expected  []Rule
+	}{
+		{`digestauth /admin user pwd`, false, []Rule{
+			{Rule: basicauth.Rule{Username: "user", Resources: []string{"/admin"}}, Password: "pwd"},
+		}},
+		{`digestauth user pwd {
+			/resource1
+			/resource2
+		}`, false, []Rule{
+			{Rule: basicauth.Rule{Username: "user", Resources: []string{"/resource1", "/resource2"}}, Password: "pwd"},
+		}},
+		{`digestauth /res1 user1 pwd1
+		  digestauth /res2 user2 pwd2`, false, []Rule{
+			{Rule: basicauth.Rule{Username: "user1", Resources: []string{"/res1"}}, Password: "pwd1"},
+			{Rule: basicauth.Rule{Username: "user2", Resources: []string{"/res2"}}, Password: "pwd2"},
+		}},
+		{`digestauth user pwd {
+			/resource1
+			except /resource1/public
+		}`, false, []Rule{
+			{Rule: basicauth.Rule{Username: "user", Resources: []string{"/resource1"}, Exceptions: []string{"/resource1/public"}}, Password: "pwd"},
+		}},
+		{`digestauth user pwd {
+			/resource1 /resource2
+		}`, true, nil},
+		{`digestauth user pwd {
+			except
+		}`, true, nil},
+		{`digestauth user`, true, nil},
+		{`digestauth`, true, nil},
+		{`digestauth /resource user pwd asdf`, true, nil},
+	}
+
+	for i, test := range tests {
+		actual, err := digestAuthParse(caddy.NewTestController("http", test.input))
+
+		if err == nil && test.shouldErr {
+			t.Errorf("Test %d didn't error, but it should have", i)
+		} else if err != nil && !test.shouldErr {
+			t.Errorf("Test %d errored, but it shouldn't have; got '%v'", i, err)
+		}
+		if test.shouldErr {
+			continue
+		}
+
+		if got, want := fmt.Sprintf("%+v", actual), fmt.Sprintf("%+v", test.expected); got != want {
+			t.Errorf("Test %d: Expected rules %s, got %s", i, want, got)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+// Package enforcehttps is middleware that redirects plaintext
+// requests to HTTPS, except for some paths.
+package enforcehttps
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+// EnforceHTTPS is middleware that redirects requests that clients made
+// over plaintext HTTP to the same URL with HTTPS, except for the paths
+// that must stay reachable over HTTP, like health checks. Behind a
+// proxy that terminates TLS, the scheme is the one that the trusted
+// proxies report (see httpserver.ResolveClient), so requests that came
+// over HTTPS are not redirected again and again.
+type EnforceHTTPS struct {
+	Next           httpserver.Handler
+	Except         []string
+	Port           string // of HTTPS, or "" for the default of 443
+	TrustedProxies []*net.IPNet
+}
+
+// ServeHTTP implements the httpserver.Handler interface.
+func (e EnforceHTTPS) ServeHTTP(w http.ResponseWriter, r *http.Request) (int, error) {
+	client := httpserver.ResolveClient(r, e.TrustedProxies)
+	if client.Scheme == "https" || e.exempt(r.URL.Path) {
+		return e.Next.ServeHTTP(w, r)
+	}
+
+	host := client.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if host == "" {
+		return http.StatusBadRequest, nil
+	}
+	if e.Port != "" {
+		host = net.JoinHostPort(host, e.Port)
+	} else if ip := net.ParseIP(host); ip != nil && ip.To4() == nil {
+		host = "[" + host + "]"
+	}
+
+	// only GET and HEAD requests are safe to turn into
+	// one another, others must be repeated as they are
+	code := http.StatusMovedPermanently
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		code = http.StatusPermanentRedirect
+	}
+	http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), code)
+	return 0, nil
+}
+
+// exempt reports whether requests for path are served over HTTP.
+func (e EnforceHTTPS) exempt(path string) bool {
+	for _, p := range e.Except {
+		if httpserver.Path(path).Matches(p) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package enforcehttps
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func TestEnforceHTTPS(t *testing.T) {
+	trusted, err := httpserver.ParseCIDR("10.0.0.0/8")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, test := range []struct {
+		method           string
+		url              string
+		https            bool
+		remoteAddr       string
+		xfp              string
+		port             string
+		expectedCode     int
+		expectedLocation string
+	}{
+		{"GET", "http://example.com/a?b=c", false, "1.2.3.4:1234", "", "", http.StatusMovedPermanently, "https://example.com/a?b=c"},
+		{"HEAD", "http://example.com:8080/", false, "1.2.3.4:1234", "", "", http.StatusMovedPermanently, "https://example.com/"},
+		{"GET", "http://example.com/", false, "1.2.3.4:1234", "", "8443", http.StatusMovedPermanently, "https://example.com:8443/"},
+		{"GET", "http://[::1]/", false, "1.2.3.4:1234", "", "", http.StatusMovedPermanently, "https://[::1]/"},
+		// other methods are repeated as they are
+		{"POST", "http://example.com/form", false, "1.2.3.4:1234", "", "", http.StatusPermanentRedirect, "https://example.com/form"},
+
+		// exempt paths are served over HTTP
+		{"GET", "http://example.com/health", false, "1.2.3.4:1234", "", "", http.StatusOK, ""},
+		{"GET", "http://example.com/health/db", false, "1.2.3.4:1234", "", "", http.StatusOK, ""},
+		{"GET", "http://example.com/.well-known/acme-challenge/token", false, "1.2.3.4:1234", "", "", http.StatusOK, ""},
+
+		// requests over HTTPS are served
+		{"GET", "https://example.com/", true, "1.2.3.4:1234", "", "", http.StatusOK, ""},
+		// as are those that trusted proxies got over HTTPS
+		{"GET", "http://example.com/", false, "10.0.0.1:1234", "https", "", http.StatusOK, ""},
+		{"GET", "http://example.com/", false, "10.0.0.1:1234", "http", "", http.StatusMovedPermanently, "https://example.com/"},
+		// but other clients can't claim to be on HTTPS
+		{"GET", "http://example.com/", false, "1.2.3.4:1234", "https", "", http.StatusMovedPermanently, "https://example.com/"},
+	} {
+		e := EnforceHTTPS{
+			Next: httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+				return http.StatusOK, nil
+			}),
+			Except:         []string{"/health", acmeChallengePath},
+			Port:           test.port,
+			TrustedProxies: []*net.IPNet{trusted},
+		}
+
+		r, err := http.NewRequest(test.method, test.url, nil)
+		if err != nil {
+			t.Fatalf("Test %d: Could not create HTTP request: %v", i, err)
+		}
+		r.RemoteAddr = test.remoteAddr
+		if test.https {
+			r.TLS = new(tls.ConnectionState)
+		}
+		if test.xfp != "" {
+			r.Header.Set("X-Forwarded-Proto", test.xfp)
+		}
+		rec := httptest.NewRecorder()
+
+		code, err := e.ServeHTTP(rec, r)
+		if err != nil {
+			t.Errorf("Test %d: Expected no error, got: %v", i, err)
+		}
+		if code == 0 {
+			code = rec.Code
+		}
+		if code != test.expectedCode {
+			t.Errorf("Test %d: Expected status %d, got %d", i, test.expectedCode, code)
+		}
+		if got := rec.Header().Get("Location"); got != test.expectedLocation {
+			t.Errorf("Test %d: Expected Location %q, got %q", i, test.expectedLocation, got)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package enforcehttps
+
+import (
+	"strconv"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func init() {
+	caddy.RegisterPlugin("enforce_https", caddy.Plugin{
+		ServerType: "http",
+		Action:     setup,
+	})
+}
+
+// acmeChallengePath is where ACME CAs look for HTTP challenges,
+// which must be answered over HTTP.
+const acmeChallengePath = "/.well-known/acme-challenge/"
+
+// setup configures a new EnforceHTTPS middleware instance.
+func setup(c *caddy.Controller) error {
+	e, err := enforceHTTPSParse(c)
+	if err != nil {
+		return err
+	}
+
+	cfg := httpserver.GetConfig(c)
+	cfg.AddMiddleware(func(next httpserver.Handler) httpserver.Handler {
+		e.Next = next
+		e.TrustedProxies = cfg.TrustedProxies
+		return e
+	})
+
+	return nil
+}
+
+// enforceHTTPSParse parses the directive:
+//
+//	enforce_https {
+//		except <paths...>
+//		port   <port>
+//	}
+//
+// ACME HTTP challenges are always exempt.
+func enforceHTTPSParse(c *caddy.Controller) (EnforceHTTPS, error) {
+	e := EnforceHTTPS{Except: []string{acmeChallengePath}}
+	var parsed bool
+
+	for c.Next() {
+		if parsed {
+			return e, c.Err("enforce_https can only be specified once per site")
+		}
+		parsed = true
+
+		if len(c.RemainingArgs()) > 0 {
+			return e, c.ArgErr()
+		}
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "except":
+				paths := c.RemainingArgs()
+				if len(paths) == 0 {
+					return e, c.ArgErr()
+				}
+				e.Except = append(e.Except, paths...)
+			case "port":
+				if !c.NextArg() {
+					return e, c.ArgErr()
+				}
+				port, err := strconv.Atoi(c.Val())
+				if err != nil || port < 1 || port > 65535 {
+					return e, c.Errf("invalid port '%s'", c.Val())
+				}
+				if port != 443 {
+					e.Port = c.Val()
+				}
+				if c.NextArg() {
+					return e, c.ArgErr()
+				}
+			default:
+				return e, c.Errf("unknown enforce_https property '%s'", c.Val())
+			}
+		}
+	}
+
+	return e, nil
+}
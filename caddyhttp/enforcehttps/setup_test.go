@@ -0,0 +1,73 @@
+package enforcehttps
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func TestSetup(t *testing.T) {
+	c := caddy.NewTestController("http", `enforce_https`)
+	err := setup(c)
+	if err != nil {
+		t.Errorf("Expected no errors, got: %v", err)
+	}
+	mids := httpserver.GetConfig(c).Middleware()
+	if len(mids) == 0 {
+		t.Fatal("Expected middleware, got 0 instead")
+	}
+
+	handler := mids[0](httpserver.EmptyNext)
+	myHandler, ok := handler.(EnforceHTTPS)
+	if !ok {
+		t.Fatalf("Expected handler to be type EnforceHTTPS, got: %#v", handler)
+	}
+	if !httpserver.SameNext(myHandler.Next, httpserver.EmptyNext) {
+		t.Error("'Next' field of handler was not set properly")
+	}
+}
+
+func TestEnforceHTTPSParse(t *testing.T) {
+	for i, test := range []struct {
+		input     string
+		shouldErr bool
+		expected  EnforceHTTPS
+	}{
+		{`enforce_https`, false, EnforceHTTPS{Except: []string{acmeChallengePath}}},
+		{`enforce_https {
+			except /health /metrics
+			except /status
+			port   8443
+		  }`, false, EnforceHTTPS{Except: []string{acmeChallengePath, "/health", "/metrics", "/status"}, Port: "8443"}},
+		{`enforce_https {
+			port 443
+		  }`, false, EnforceHTTPS{Except: []string{acmeChallengePath}}},
+		{`enforce_https /health`, true, EnforceHTTPS{}},
+		{`enforce_https {
+			except
+		  }`, true, EnforceHTTPS{}},
+		{`enforce_https {
+			port 0
+		  }`, true, EnforceHTTPS{}},
+		{`enforce_https {
+			port https
+		  }`, true, EnforceHTTPS{}},
+		{`enforce_https {
+			hsts
+		  }`, true, EnforceHTTPS{}},
+		{`enforce_https
+		  enforce_https`, true, EnforceHTTPS{}},
+	} {
+		actual, err := enforceHTTPSParse(caddy.NewTestController("http", test.input))
+		if err == nil && test.shouldErr {
+			t.Errorf("Test %d didn't error, but it should have", i)
+		} else if err != nil && !test.shouldErr {
+			t.Errorf("Test %d errored, but it shouldn't have; got '%v'", i, err)
+		}
+		if !test.shouldErr && !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("Test %d: Expected %+v, got %+v", i, test.expected, actual)
+		}
+	}
+}
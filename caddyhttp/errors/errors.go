@@ -4,6 +4,7 @@ package errors
 import (
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"os"
 	"runtime"
@@ -28,15 +29,26 @@ type ErrorHandler struct {
 	ErrorPages       map[int]string // map of status code to filename
 	Log              *httpserver.Logger
 	Debug            bool // if true, errors are written out to client rather than to a log
+	PassPanics       bool // if true, panics are left to the recover middleware before this one
 }
 
 func (h ErrorHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) (int, error) {
-	defer h.recovery(w, r)
+	if !h.PassPanics {
+		defer h.recovery(w, r)
+	}
 
 	status, err := h.Next.ServeHTTP(w, r)
 
+	// handlers that the request timeout of the site cut off tell
+	// of it in their own way, like a proxy with a bad gateway
+	if status >= 400 && httpserver.RequestTimedOut(r) {
+		status = http.StatusGatewayTimeout
+	}
+
 	if err != nil {
-		errMsg := fmt.Sprintf("%s [ERROR %d %s] %v", time.Now().Format(timeFormat), status, r.URL.Path, err)
+		// clients only get to see the ID of the error, with which
+		// it can be found in the log
+		errMsg := fmt.Sprintf("%s [ERROR %d %s] %v (error ID %s)", time.Now().Format(timeFormat), status, r.URL.Path, err, httpserver.MakeErrorID(r))
 		if h.Debug {
 			// Write error to response instead of to log
 			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
@@ -57,7 +69,9 @@ func (h ErrorHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) (int, er
 
 // errorPage serves a static error page to w according to the status
 // code. If there is an error serving the error page, a plaintext error
-// message is written instead, and the extra error is logged.
+// message is written instead, and the extra error is logged. If the
+// request ran into an error, {error_id} in the page is replaced by its
+// ID.
 func (h ErrorHandler) errorPage(w http.ResponseWriter, r *http.Request, code int) {
 	// See if an error page for this status code was specified
 	if pagePath, ok := h.findErrorPage(code); ok {
@@ -65,22 +79,34 @@ func (h ErrorHandler) errorPage(w http.ResponseWriter, r *http.Request, code int
 		errorPage, err := os.Open(pagePath)
 		if err != nil {
 			// An additional error handling an error... <insert grumpy cat here>
-			h.Log.Printf("%s [NOTICE %d %s] could not load error page: %v",
-				time.Now().Format(timeFormat), code, r.URL.String(), err)
+			h.Log.Printf("%s [NOTICE %d %s] could not load error page: %v%s",
+				time.Now().Format(timeFormat), code, r.URL.String(), err, errorIDSuffix(r))
 			httpserver.DefaultErrorFunc(w, r, code)
 			return
 		}
 		defer errorPage.Close()
 
+		var page io.Reader = errorPage
+		if id := httpserver.ErrorID(r); id != "" {
+			body, err := ioutil.ReadAll(errorPage)
+			if err != nil {
+				h.Log.Printf("%s [NOTICE %d %s] could not load error page: %v%s",
+					time.Now().Format(timeFormat), code, r.URL.String(), err, errorIDSuffix(r))
+				httpserver.DefaultErrorFunc(w, r, code)
+				return
+			}
+			page = strings.NewReader(strings.Replace(string(body), "{"+httpserver.ErrorIDPlaceholder+"}", id, -1))
+		}
+
 		// Copy the page body into the response
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
 		w.WriteHeader(code)
-		_, err = io.Copy(w, errorPage)
+		_, err = io.Copy(w, page)
 
 		if err != nil {
 			// Epic fail... sigh.
-			h.Log.Printf("%s [NOTICE %d %s] could not respond with %s: %v",
-				time.Now().Format(timeFormat), code, r.URL.String(), pagePath, err)
+			h.Log.Printf("%s [NOTICE %d %s] could not respond with %s: %v%s",
+				time.Now().Format(timeFormat), code, r.URL.String(), pagePath, err, errorIDSuffix(r))
 			httpserver.DefaultErrorFunc(w, r, code)
 		}
 
@@ -108,6 +134,9 @@ func (h ErrorHandler) recovery(w http.ResponseWriter, r *http.Request) {
 	if rec == nil {
 		return
 	}
+	if _, ok := rec.(httpserver.Repanic); ok {
+		panic(rec)
+	}
 
 	// Obtain source of panic
 	// From: https://gist.github.com/swdunlop/9629168
@@ -134,7 +163,7 @@ func (h ErrorHandler) recovery(w http.ResponseWriter, r *http.Request) {
 		file = file[pkgPathPos+len(delim):]
 	}
 
-	panicMsg := fmt.Sprintf("%s [PANIC %s] %s:%d - %v", time.Now().Format(timeFormat), r.URL.String(), file, line, rec)
+	panicMsg := fmt.Sprintf("%s [PANIC %s] %s:%d - %v (error ID %s)", time.Now().Format(timeFormat), r.URL.String(), file, line, rec, httpserver.MakeErrorID(r))
 	if h.Debug {
 		// Write error and stack trace to the response rather than to a log
 		var stackBuf [4096]byte
@@ -147,4 +176,14 @@ func (h ErrorHandler) recovery(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// errorIDSuffix returns the ID of the error that handling r ran
+// into in parentheses, to append to log messages, or "" if it ran
+// into none.
+func errorIDSuffix(r *http.Request) string {
+	if id := httpserver.ErrorID(r); id != "" {
+		return " (error ID " + id + ")"
+	}
+	return ""
+}
+
 const timeFormat = "02/Jan/2006:15:04:05 -0700"
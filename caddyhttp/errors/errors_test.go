@@ -2,17 +2,21 @@ package errors
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/mholt/caddy/caddyhttp/httpserver"
+	"github.com/mholt/caddy/caddytls"
 )
 
 func TestErrors(t *testing.T) {
@@ -54,7 +58,7 @@ func TestErrors(t *testing.T) {
 			next:         genErrorHandler(http.StatusMovedPermanently, testErr, ""),
 			expectedCode: http.StatusMovedPermanently,
 			expectedBody: "",
-			expectedLog:  fmt.Sprintf("[ERROR %d %s] %v\n", http.StatusMovedPermanently, "/", testErr),
+			expectedLog:  fmt.Sprintf("[ERROR %d %s] %v (error ID ", http.StatusMovedPermanently, "/", testErr),
 			expectedErr:  testErr,
 		},
 		{
@@ -150,6 +154,30 @@ func TestVisibleErrorWithPanic(t *testing.T) {
 	}
 }
 
+func TestErrorsPassPanics(t *testing.T) {
+	const panicMsg = "I'm a panic"
+	eh := ErrorHandler{
+		ErrorPages: make(map[int]string),
+		PassPanics: true,
+		Next: httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+			panic(panicMsg)
+		}),
+	}
+
+	req, err := http.NewRequest("GET", "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+
+	defer func() {
+		if rec := recover(); rec != panicMsg {
+			t.Errorf("Expected the panic to be passed on, got %v", rec)
+		}
+	}()
+	eh.ServeHTTP(rec, req)
+}
+
 func TestGenericErrorPage(t *testing.T) {
 	// create temporary generic error page
 	const genericErrorContent = "This is a generic error page"
@@ -231,6 +259,88 @@ func TestGenericErrorPage(t *testing.T) {
 	}
 }
 
+func TestErrorID(t *testing.T) {
+	const content = "Something went wrong; please report error {error_id}."
+	path, err := createErrorPageFile("error_id_test.html", content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(path)
+
+	buf := bytes.Buffer{}
+	em := ErrorHandler{
+		ErrorPages: map[int]string{
+			http.StatusInternalServerError: path,
+			http.StatusServiceUnavailable:  "not_exist_file",
+		},
+		Log: httpserver.NewTestLogger(&buf),
+	}
+	testErr := errors.New("secret detail")
+	idPattern := regexp.MustCompile(`\(error ID ([0-9a-f]{16})\)`)
+
+	for i, test := range []struct {
+		next         httpserver.Handler
+		expectedBody string // with <id> in place of the error ID
+		expectedLogs int    // lines
+	}{
+		{
+			next:         genErrorHandler(http.StatusInternalServerError, testErr, ""),
+			expectedBody: "Something went wrong; please report error <id>.",
+			expectedLogs: 1,
+		},
+		{
+			next:         genErrorHandler(http.StatusBadGateway, testErr, ""),
+			expectedBody: "502 Bad Gateway\nError ID: <id>\n",
+			expectedLogs: 1,
+		},
+		// an error serving the error page of an error
+		{
+			next:         genErrorHandler(http.StatusServiceUnavailable, testErr, ""),
+			expectedBody: "503 Service Unavailable\nError ID: <id>\n",
+			expectedLogs: 2,
+		},
+		{
+			next: httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+				panic("secret detail")
+			}),
+			expectedBody: "Something went wrong; please report error <id>.",
+			expectedLogs: 1,
+		},
+	} {
+		em.Next = test.next
+		buf.Reset()
+		req, err := http.NewRequest("GET", "/", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		placeholders := make(map[string]string)
+		req = req.WithContext(context.WithValue(req.Context(), httpserver.PlaceholdersCtxKey, placeholders))
+		rec := httptest.NewRecorder()
+		em.ServeHTTP(rec, req)
+
+		id := placeholders[httpserver.ErrorIDPlaceholder]
+		if id == "" {
+			t.Fatalf("Test %d: Expected {error_id} to be set", i)
+		}
+		logs := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if len(logs) != test.expectedLogs {
+			t.Fatalf("Test %d: Expected %d log lines, got %q", i, test.expectedLogs, logs)
+		}
+		for _, log := range logs {
+			if m := idPattern.FindStringSubmatch(log); m == nil || m[1] != id {
+				t.Errorf("Test %d: Expected log line with error ID %s, got %q", i, id, log)
+			}
+		}
+		body := rec.Body.String()
+		if expected := strings.Replace(test.expectedBody, "<id>", id, -1); body != expected {
+			t.Errorf("Test %d: Expected body %q, got %q", i, expected, body)
+		}
+		if strings.Contains(body, "secret") {
+			t.Errorf("Test %d: Expected body not to have the error, got %q", i, body)
+		}
+	}
+}
+
 func genErrorHandler(status int, err error, body string) httpserver.Handler {
 	return httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
 		if len(body) > 0 {
@@ -256,3 +366,38 @@ func createErrorPageFile(name string, content string) (string, error) {
 
 	return errorPageFilePath, nil
 }
+
+func TestErrorsRequestTimeout(t *testing.T) {
+	site := &httpserver.SiteConfig{
+		Addr:           httpserver.Address{Original: "localhost", Host: "localhost"},
+		TLS:            new(caddytls.Config),
+		RequestTimeout: 10 * time.Millisecond,
+	}
+	site.AddMiddleware(func(next httpserver.Handler) httpserver.Handler {
+		return ErrorHandler{
+			Next: httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+				// like a proxy whose upstream did not answer in time
+				<-r.Context().Done()
+				return http.StatusBadGateway, r.Context().Err()
+			}),
+			Log: httpserver.NewTestLogger(new(bytes.Buffer)),
+		}
+	})
+	s, err := httpserver.NewServer("127.0.0.1:0", []*httpserver.SiteConfig{site})
+	if err != nil {
+		t.Fatalf("Expected no error making server, got: %v", err)
+	}
+
+	req, err := http.NewRequest("GET", "http://localhost/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Errorf("Expected status %d, got %d", http.StatusGatewayTimeout, rec.Code)
+	}
+	if !strings.HasPrefix(rec.Body.String(), "504 Gateway Timeout\n") {
+		t.Errorf("Expected the error page of a 504, got %q", rec.Body.String())
+	}
+}
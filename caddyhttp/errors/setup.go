@@ -20,7 +20,16 @@ func setup(c *caddy.Controller) error {
 
 	handler.Log.Attach(c)
 
-	httpserver.GetConfig(c).AddMiddleware(func(next httpserver.Handler) httpserver.Handler {
+	// the recover directive comes before this one, and its
+	// options of how to handle panics should be the ones used
+	cfg := httpserver.GetConfig(c)
+	for _, dir := range cfg.MiddlewareDirectives() {
+		if dir == "recover" {
+			handler.PassPanics = true
+		}
+	}
+
+	cfg.AddMiddleware(func(next httpserver.Handler) httpserver.Handler {
 		handler.Next = next
 		return handler
 	})
@@ -0,0 +1,99 @@
+// Package etag implements middleware that adds ETags to responses
+// that are generated on the fly, and answers conditional requests
+// for those responses with 304 Not Modified.
+package etag
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strings"
+
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+// ETag is middleware that computes weak ETags from the
+// bodies of responses under certain paths.
+type ETag struct {
+	Next  httpserver.Handler
+	Paths []string
+
+	// MaxSize is the largest body that is held back to be
+	// hashed; larger responses are sent without an ETag.
+	MaxSize int
+}
+
+// ServeHTTP implements the httpserver.Handler interface.
+func (e ETag) ServeHTTP(w http.ResponseWriter, r *http.Request) (int, error) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return e.Next.ServeHTTP(w, r)
+	}
+	if !e.matches(r.URL.Path) {
+		return e.Next.ServeHTTP(w, r)
+	}
+
+	rr := httpserver.NewBufferedResponseRecorder(w)
+	rr.SetBufferLimit(e.MaxSize)
+	status, err := e.Next.ServeHTTP(rr, r)
+	if !rr.Buffered() {
+		// streamed, or too big to hash
+		return status, err
+	}
+
+	header := rr.Header()
+	if rr.Status() == http.StatusOK && status < 400 && rr.Buffer().Len() > 0 &&
+		header.Get("ETag") == "" && !noStore(header) {
+		h := fnv.New64a()
+		h.Write(rr.Buffer().Bytes())
+		etag := fmt.Sprintf(`W/"%x"`, h.Sum64())
+		header.Set("ETag", etag)
+
+		if noneMatch := r.Header.Get("If-None-Match"); noneMatch != "" && etagMatches(noneMatch, etag) {
+			// the client has it already
+			httpserver.WriteNotModified(w)
+			return 0, err
+		}
+	}
+
+	if relErr := rr.Release(); relErr != nil && err == nil {
+		err = relErr
+	}
+	return status, err
+}
+
+// matches reports whether requests for path get ETags.
+func (e ETag) matches(path string) bool {
+	for _, p := range e.Paths {
+		if httpserver.Path(path).Matches(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// noStore reports whether header forbids storing the response,
+// in which case there is no point in validating it later.
+func noStore(header http.Header) bool {
+	for _, v := range header["Cache-Control"] {
+		for _, directive := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(directive), "no-store") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// etagMatches reports whether the If-None-Match header value
+// matches etag, using the weak comparison of RFC 7232.
+func etagMatches(noneMatch, etag string) bool {
+	if strings.TrimSpace(noneMatch) == "*" {
+		return true
+	}
+	for _, candidate := range strings.Split(noneMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+package etag
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func TestETag(t *testing.T) {
+	page := func(body string, header http.Header) httpserver.Handler {
+		return httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+			for name, values := range header {
+				w.Header()[name] = values
+			}
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte(body))
+			return 0, nil
+		})
+	}
+
+	// learn the ETag of the page first
+	rec := httptest.NewRecorder()
+	e := ETag{Next: page("<h1>Hello</h1>", nil), Paths: []string{"/"}, MaxSize: 1024}
+	r, _ := http.NewRequest("GET", "/hello", nil)
+	e.ServeHTTP(rec, r)
+	etag := rec.Header().Get("ETag")
+	if !strings.HasPrefix(etag, `W/"`) {
+		t.Fatalf("Expected a weak ETag, got %q", etag)
+	}
+
+	for i, test := range []struct {
+		method      string
+		path        string
+		noneMatch   string
+		next        httpserver.Handler
+		maxSize     int
+		status      int
+		expectETag  string // "" for none, "*" for whatever was computed
+		expectEmpty bool
+	}{
+		{"GET", "/hello", "", page("<h1>Hello</h1>", nil), 1024, http.StatusOK, etag, false},
+		{"GET", "/hello", etag, page("<h1>Hello</h1>", nil), 1024, http.StatusNotModified, etag, true},
+		{"HEAD", "/hello", etag, page("<h1>Hello</h1>", nil), 1024, http.StatusNotModified, etag, true},
+		{"GET", "/hello", strings.TrimPrefix(etag, "W/"), page("<h1>Hello</h1>", nil), 1024, http.StatusNotModified, etag, true},
+		{"GET", "/hello", `"abc", ` + etag, page("<h1>Hello</h1>", nil), 1024, http.StatusNotModified, etag, true},
+		{"GET", "/hello", "*", page("<h1>Hello</h1>", nil), 1024, http.StatusNotModified, etag, true},
+
+		// changed content
+		{"GET", "/hello", etag, page("<h1>Hello, world</h1>", nil), 1024, http.StatusOK, "*", false},
+
+		// not for writes or other paths
+		{"POST", "/hello", etag, page("<h1>Hello</h1>", nil), 1024, http.StatusOK, "", false},
+		{"GET", "/other", etag, page("<h1>Hello</h1>", nil), 1024, http.StatusOK, "", false},
+
+		// not when told not to store, or too big
+		{"GET", "/hello", etag, page("<h1>Hello</h1>", http.Header{"Cache-Control": {"private, no-store"}}), 1024, http.StatusOK, "", false},
+		{"GET", "/hello", etag, page("<h1>Hello</h1>", nil), 5, http.StatusOK, "", false},
+
+		// existing ETags are kept
+		{"GET", "/hello", etag, page("<h1>Hello</h1>", http.Header{"Etag": {`"v1"`}}), 1024, http.StatusOK, `"v1"`, false},
+	} {
+		e := ETag{Next: test.next, Paths: []string{"/hello"}, MaxSize: test.maxSize}
+		r, err := http.NewRequest(test.method, test.path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if test.noneMatch != "" {
+			r.Header.Set("If-None-Match", test.noneMatch)
+		}
+		rec := httptest.NewRecorder()
+
+		if _, err := e.ServeHTTP(rec, r); err != nil {
+			t.Errorf("Test %d: Expected no error, got %v", i, err)
+		}
+		if rec.Code != test.status {
+			t.Errorf("Test %d: Expected status %d, got %d", i, test.status, rec.Code)
+		}
+		got := rec.Header().Get("ETag")
+		switch test.expectETag {
+		case "*":
+			if got == "" || got == etag {
+				t.Errorf("Test %d: Expected a new ETag, got %q", i, got)
+			}
+		default:
+			if got != test.expectETag {
+				t.Errorf("Test %d: Expected ETag %q, got %q", i, test.expectETag, got)
+			}
+		}
+		if test.expectEmpty && rec.Body.Len() != 0 {
+			t.Errorf("Test %d: Expected empty body, got %q", i, rec.Body.String())
+		} else if !test.expectEmpty && rec.Body.Len() == 0 {
+			t.Errorf("Test %d: Expected a body", i)
+		}
+	}
+}
+
+func TestETagErrorPassthrough(t *testing.T) {
+	e := ETag{
+		Next: httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+			return http.StatusNotFound, nil
+		}),
+		Paths:   []string{"/"},
+		MaxSize: 1024,
+	}
+	r, _ := http.NewRequest("GET", "/missing", nil)
+	rec := httptest.NewRecorder()
+
+	status, _ := e.ServeHTTP(rec, r)
+	if status != http.StatusNotFound {
+		t.Errorf("Expected status %d to be returned, got %d", http.StatusNotFound, status)
+	}
+	if rec.Header().Get("ETag") != "" || rec.Body.Len() != 0 {
+		t.Errorf("Expected nothing written for an unhandled error, got ETag %q and body %q",
+			rec.Header().Get("ETag"), rec.Body.String())
+	}
+}
@@ -0,0 +1,71 @@
+package etag
+
+import (
+	"strconv"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func init() {
+	caddy.RegisterPlugin("etag", caddy.Plugin{
+		ServerType: "http",
+		Action:     setup,
+	})
+}
+
+// setup configures a new ETag middleware instance.
+func setup(c *caddy.Controller) error {
+	e, err := etagParse(c)
+	if err != nil {
+		return err
+	}
+
+	httpserver.GetConfig(c).AddMiddleware(func(next httpserver.Handler) httpserver.Handler {
+		e.Next = next
+		return e
+	})
+
+	return nil
+}
+
+// etagParse parses the directive:
+//
+//	etag [paths...] {
+//		max_size <bytes>
+//	}
+func etagParse(c *caddy.Controller) (ETag, error) {
+	e := ETag{MaxSize: defaultMaxSize}
+
+	for c.Next() {
+		paths := c.RemainingArgs()
+		if len(paths) == 0 {
+			paths = []string{"/"}
+		}
+		e.Paths = append(e.Paths, paths...)
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "max_size":
+				if !c.NextArg() {
+					return e, c.ArgErr()
+				}
+				size, err := strconv.Atoi(c.Val())
+				if err != nil || size < 1 {
+					return e, c.Errf("max_size must be a positive number of bytes, got '%s'", c.Val())
+				}
+				e.MaxSize = size
+				if c.NextArg() {
+					return e, c.ArgErr()
+				}
+			default:
+				return e, c.Errf("unknown etag property '%s'", c.Val())
+			}
+		}
+	}
+
+	return e, nil
+}
+
+// defaultMaxSize is the largest body that gets an ETag by default.
+const defaultMaxSize = 1 << 20
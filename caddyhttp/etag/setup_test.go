@@ -0,0 +1,67 @@
+package etag
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func TestSetup(t *testing.T) {
+	c := caddy.NewTestController("http", `etag`)
+	err := setup(c)
+	if err != nil {
+		t.Errorf("Expected no errors, got: %v", err)
+	}
+	mids := httpserver.GetConfig(c).Middleware()
+	if len(mids) == 0 {
+		t.Fatal("Expected middleware, got 0 instead")
+	}
+
+	handler := mids[0](httpserver.EmptyNext)
+	myHandler, ok := handler.(ETag)
+	if !ok {
+		t.Fatalf("Expected handler to be type ETag, got: %#v", handler)
+	}
+	if !httpserver.SameNext(myHandler.Next, httpserver.EmptyNext) {
+		t.Error("'Next' field of handler was not set properly")
+	}
+}
+
+func TestETagParse(t *testing.T) {
+	for i, test := range []struct {
+		input     string
+		shouldErr bool
+		expected  ETag
+	}{
+		{`etag`, false, ETag{Paths: []string{"/"}, MaxSize: defaultMaxSize}},
+		{`etag /blog /docs`, false, ETag{Paths: []string{"/blog", "/docs"}, MaxSize: defaultMaxSize}},
+		{`etag /blog
+		  etag /docs {
+			max_size 4096
+		  }`, false, ETag{Paths: []string{"/blog", "/docs"}, MaxSize: 4096}},
+		{`etag {
+			max_size
+		  }`, true, ETag{}},
+		{`etag {
+			max_size 0
+		  }`, true, ETag{}},
+		{`etag {
+			max_size 1 2
+		  }`, true, ETag{}},
+		{`etag {
+			strong
+		  }`, true, ETag{}},
+	} {
+		actual, err := etagParse(caddy.NewTestController("http", test.input))
+		if err == nil && test.shouldErr {
+			t.Errorf("Test %d didn't error, but it should have", i)
+		} else if err != nil && !test.shouldErr {
+			t.Errorf("Test %d errored, but it shouldn't have; got '%v'", i, err)
+		}
+		if !test.shouldErr && !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("Test %d: Expected %+v, got %+v", i, test.expected, actual)
+		}
+	}
+}
@@ -14,6 +14,9 @@ func init() {
 
 // setup configures a new instance of 'extensions' middleware for clean URLs.
 func setup(c *caddy.Controller) error {
+	if err := httpserver.CheckStaticRoot(c, "ext"); err != nil {
+		return err
+	}
 	cfg := httpserver.GetConfig(c)
 	root := cfg.Root
 
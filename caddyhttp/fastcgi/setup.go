@@ -21,6 +21,9 @@ func init() {
 
 // setup configures a new FastCGI middleware instance.
 func setup(c *caddy.Controller) error {
+	if err := httpserver.CheckStaticRoot(c, "fastcgi"); err != nil {
+		return err
+	}
 	cfg := httpserver.GetConfig(c)
 
 	rules, err := fastcgiParse(c)
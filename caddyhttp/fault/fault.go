@@ -0,0 +1,95 @@
+// Package fault implements middleware that injects latency and
+// errors into a portion of requests, to test how clients cope
+// with slow or failing servers.
+package fault
+
+import (
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+// Fault is middleware that delays some of the requests under
+// Paths, fails them with Status, or both.
+type Fault struct {
+	Next  httpserver.Handler
+	Paths []string
+
+	// Percent is the chance, from 0 to 100, that a request
+	// is faulted. If it is 0, no request is.
+	Percent float64
+
+	// Delay is how long faulted requests are delayed, or if
+	// MaxDelay is longer, the shortest delay; delays are then
+	// spread evenly up to MaxDelay.
+	Delay    time.Duration
+	MaxDelay time.Duration
+
+	// Status is the status faulted requests fail with after
+	// their delay; if it is 0, they are handled as usual.
+	Status int
+}
+
+// ServeHTTP implements the httpserver.Handler interface.
+func (f Fault) ServeHTTP(w http.ResponseWriter, r *http.Request) (int, error) {
+	if f.Percent <= 0 || !f.matches(r.URL.Path) || random.float64()*100 >= f.Percent {
+		return f.Next.ServeHTTP(w, r)
+	}
+
+	if d := f.delay(); d > 0 {
+		timer := time.NewTimer(d)
+		select {
+		case <-timer.C:
+		case <-r.Context().Done():
+			// no one is waiting anymore
+			timer.Stop()
+		}
+	}
+	if f.Status != 0 {
+		return f.Status, nil
+	}
+	return f.Next.ServeHTTP(w, r)
+}
+
+// matches reports whether requests for path may be faulted.
+func (f Fault) matches(path string) bool {
+	for _, p := range f.Paths {
+		if httpserver.Path(path).Matches(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// delay returns how long to delay a faulted request.
+func (f Fault) delay() time.Duration {
+	if f.MaxDelay <= f.Delay {
+		return f.Delay
+	}
+	return f.Delay + time.Duration(random.int63n(int64(f.MaxDelay-f.Delay)+1))
+}
+
+// lockedRand is a *rand.Rand that is safe for concurrent use.
+type lockedRand struct {
+	sync.Mutex
+	r *rand.Rand
+}
+
+func (l *lockedRand) float64() float64 {
+	l.Lock()
+	defer l.Unlock()
+	return l.r.Float64()
+}
+
+func (l *lockedRand) int63n(n int64) int64 {
+	l.Lock()
+	defer l.Unlock()
+	return l.r.Int63n(n)
+}
+
+// random decides which requests are faulted. It is seeded so
+// that the faulted requests differ from one run to the next.
+var random = &lockedRand{r: rand.New(rand.NewSource(time.Now().UnixNano()))}
@@ -0,0 +1,95 @@
+package fault
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func TestFaultRate(t *testing.T) {
+	const requests = 10000
+
+	for i, test := range []struct {
+		percent  float64
+		path     string
+		expected float64 // fraction of faulted requests
+	}{
+		{30, "/api/items", 0.3},
+		{100, "/api/items", 1},
+		{0, "/api/items", 0},
+		{100, "/other", 0},
+	} {
+		var passed int
+		f := Fault{
+			Next: httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+				passed++
+				return http.StatusOK, nil
+			}),
+			Paths:   []string{"/api"},
+			Percent: test.percent,
+			Status:  http.StatusServiceUnavailable,
+		}
+
+		var faulted int
+		for j := 0; j < requests; j++ {
+			r, err := http.NewRequest("GET", test.path, nil)
+			if err != nil {
+				t.Fatalf("Test %d: Could not create HTTP request: %v", i, err)
+			}
+			status, err := f.ServeHTTP(httptest.NewRecorder(), r)
+			if err != nil {
+				t.Fatalf("Test %d: Expected no error, got: %v", i, err)
+			}
+			if status == http.StatusServiceUnavailable {
+				faulted++
+			}
+		}
+
+		if faulted+passed != requests {
+			t.Errorf("Test %d: Expected every request to be faulted or passed on, got %d and %d", i, faulted, passed)
+		}
+		// within 5 standard deviations for 30%, exact otherwise
+		if rate := float64(faulted) / requests; rate < test.expected-0.025 || rate > test.expected+0.025 {
+			t.Errorf("Test %d: Expected about %.0f%% of requests to be faulted, got %.1f%%", i, test.expected*100, rate*100)
+		} else if (test.expected == 0 || test.expected == 1) && rate != test.expected {
+			t.Errorf("Test %d: Expected %.0f%% of requests to be faulted, got %.1f%%", i, test.expected*100, rate*100)
+		}
+	}
+}
+
+func TestFaultDelay(t *testing.T) {
+	var handled bool
+	f := Fault{
+		Next: httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+			handled = true
+			return http.StatusOK, nil
+		}),
+		Paths:   []string{"/"},
+		Percent: 100,
+		Delay:   20 * time.Millisecond,
+	}
+	r, err := http.NewRequest("GET", "/", nil)
+	if err != nil {
+		t.Fatalf("Could not create HTTP request: %v", err)
+	}
+
+	start := time.Now()
+	status, err := f.ServeHTTP(httptest.NewRecorder(), r)
+	if elapsed := time.Since(start); elapsed < f.Delay {
+		t.Errorf("Expected a delay of %s, took %s", f.Delay, elapsed)
+	}
+	if !handled || status != http.StatusOK || err != nil {
+		t.Errorf("Expected the request to be handled after the delay, got %d and %v", status, err)
+	}
+
+	// delays within a range
+	f.Delay, f.MaxDelay = time.Second, 2*time.Second
+	for i := 0; i < 1000; i++ {
+		if d := f.delay(); d < f.Delay || d > f.MaxDelay {
+			t.Fatalf("Expected a delay from %s to %s, got %s", f.Delay, f.MaxDelay, d)
+		}
+	}
+}
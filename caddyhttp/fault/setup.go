@@ -0,0 +1,127 @@
+package fault
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func init() {
+	caddy.RegisterPlugin("fault", caddy.Plugin{
+		ServerType: "http",
+		Action:     setup,
+	})
+}
+
+// setup configures a new Fault middleware instance.
+func setup(c *caddy.Controller) error {
+	faults, err := faultParse(c)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range faults {
+		f := f
+		httpserver.GetConfig(c).AddMiddleware(func(next httpserver.Handler) httpserver.Handler {
+			f.Next = next
+			return f
+		})
+	}
+
+	return nil
+}
+
+// faultParse parses the directive:
+//
+//	fault [paths...] {
+//		probability <fraction|percentage>
+//		delay       <duration> [max_duration]
+//		status      <code>
+//	}
+//
+// The probability is a fraction like 0.1 or a percentage like 10%.
+// A fault needs a delay, a status, or both.
+func faultParse(c *caddy.Controller) ([]Fault, error) {
+	var faults []Fault
+
+	for c.Next() {
+		f := Fault{Paths: c.RemainingArgs()}
+		if len(f.Paths) == 0 {
+			f.Paths = []string{"/"}
+		}
+		var probability bool
+
+		for c.NextBlock() {
+			prop := c.Val()
+			args := c.RemainingArgs()
+			switch prop {
+			case "probability":
+				if len(args) != 1 {
+					return nil, c.ArgErr()
+				}
+				percent, ok := parsePercent(args[0])
+				if !ok {
+					return nil, c.Errf("probability must be a fraction from 0 to 1 or a percentage, got '%s'", args[0])
+				}
+				f.Percent, probability = percent, true
+			case "delay":
+				if len(args) < 1 || len(args) > 2 {
+					return nil, c.ArgErr()
+				}
+				var delays []time.Duration
+				for _, arg := range args {
+					d, err := time.ParseDuration(arg)
+					if err != nil || d < 0 {
+						return nil, c.Errf("delay must be a non-negative duration, got '%s'", arg)
+					}
+					delays = append(delays, d)
+				}
+				f.Delay = delays[0]
+				if len(delays) == 2 {
+					if delays[1] < delays[0] {
+						return nil, c.Errf("delay range must be from the shortest to the longest delay, got '%s %s'", args[0], args[1])
+					}
+					f.MaxDelay = delays[1]
+				}
+			case "status":
+				if len(args) != 1 {
+					return nil, c.ArgErr()
+				}
+				status, err := strconv.Atoi(args[0])
+				if err != nil || status < 400 || status > 599 {
+					return nil, c.Errf("status must be an error status from 400 to 599, got '%s'", args[0])
+				}
+				f.Status = status
+			default:
+				return nil, c.Errf("unknown fault property '%s'", prop)
+			}
+		}
+
+		if !probability {
+			return nil, c.Err("fault needs a probability")
+		}
+		if f.Delay == 0 && f.MaxDelay == 0 && f.Status == 0 {
+			return nil, c.Err("fault needs a delay or a status")
+		}
+		faults = append(faults, f)
+	}
+
+	return faults, nil
+}
+
+// parsePercent parses s as a fraction from 0 to 1 or a
+// percentage from 0% to 100%, and returns the percentage.
+func parsePercent(s string) (float64, bool) {
+	factor := 100.0
+	if strings.HasSuffix(s, "%") {
+		s, factor = strings.TrimSuffix(s, "%"), 1
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || !(n >= 0 && n*factor <= 100) {
+		return 0, false
+	}
+	return n * factor, true
+}
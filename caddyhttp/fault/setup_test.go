@@ -0,0 +1,109 @@
+package fault
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func TestSetup(t *testing.T) {
+	c := caddy.NewTestController("http", `fault {
+		probability 10%
+		status 503
+	}`)
+	err := setup(c)
+	if err != nil {
+		t.Errorf("Expected no errors, got: %v", err)
+	}
+	mids := httpserver.GetConfig(c).Middleware()
+	if len(mids) == 0 {
+		t.Fatal("Expected middleware, got 0 instead")
+	}
+
+	handler := mids[0](httpserver.EmptyNext)
+	myHandler, ok := handler.(Fault)
+	if !ok {
+		t.Fatalf("Expected handler to be type Fault, got: %#v", handler)
+	}
+	if !httpserver.SameNext(myHandler.Next, httpserver.EmptyNext) {
+		t.Error("'Next' field of handler was not set properly")
+	}
+}
+
+func TestFaultParse(t *testing.T) {
+	for i, test := range []struct {
+		input     string
+		shouldErr bool
+		expected  []Fault
+	}{
+		{`fault {
+			probability 0.25
+			status 503
+		  }`, false, []Fault{{Paths: []string{"/"}, Percent: 25, Status: 503}}},
+		{`fault /api /login {
+			probability 5%
+			delay 100ms 2s
+		  }
+		  fault /slow {
+			probability 1
+			delay 1s
+			status 504
+		  }`, false, []Fault{
+			{Paths: []string{"/api", "/login"}, Percent: 5, Delay: 100 * time.Millisecond, MaxDelay: 2 * time.Second},
+			{Paths: []string{"/slow"}, Percent: 100, Delay: time.Second, Status: 504},
+		}},
+		{`fault {
+			probability 0%
+			status 500
+		  }`, false, []Fault{{Paths: []string{"/"}, Status: 500}}},
+		{`fault`, true, nil},
+		{`fault {
+			status 503
+		  }`, true, nil},
+		{`fault {
+			probability 10%
+		  }`, true, nil},
+		{`fault {
+			probability 1.5
+			status 503
+		  }`, true, nil},
+		{`fault {
+			probability 101%
+			status 503
+		  }`, true, nil},
+		{`fault {
+			probability -0.1
+			status 503
+		  }`, true, nil},
+		{`fault {
+			probability 10%
+			delay 2s 1s
+		  }`, true, nil},
+		{`fault {
+			probability 10%
+			delay soon
+		  }`, true, nil},
+		{`fault {
+			probability 10%
+			status 200
+		  }`, true, nil},
+		{`fault {
+			probability 10%
+			status 503
+			drop
+		  }`, true, nil},
+	} {
+		actual, err := faultParse(caddy.NewTestController("http", test.input))
+		if err == nil && test.shouldErr {
+			t.Errorf("Test %d didn't error, but it should have", i)
+		} else if err != nil && !test.shouldErr {
+			t.Errorf("Test %d errored, but it shouldn't have; got '%v'", i, err)
+		}
+		if !test.shouldErr && !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("Test %d: Expected %+v, got %+v", i, test.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+// +build go1.12
+
+package gzip
+
+import (
+	"io"
+
+	"github.com/andybalholm/brotli"
+)
+
+// brotliSupported is whether br encoding can be offered.
+const brotliSupported = true
+
+// newBrotliWriter creates a new brotli writer based on the
+// compression level. If the level is valid (i.e. between 1
+// and 11), it uses the level. Otherwise, it uses default
+// compression level.
+func newBrotliWriter(c Config, w io.Writer) (encoder, error) {
+	if c.BrotliLevel >= 1 && c.BrotliLevel <= brotli.BestCompression {
+		return brotli.NewWriterLevel(w, c.BrotliLevel), nil
+	}
+	return brotli.NewWriter(w), nil
+}
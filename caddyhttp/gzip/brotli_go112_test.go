@@ -0,0 +1,87 @@
+// +build go1.12
+
+package gzip
+
+import (
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func TestBrotliHandler(t *testing.T) {
+	expected, err := ioutil.ReadFile("testdata/test.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, test := range []struct {
+		acceptEncoding string
+		brotli         bool
+		encoding       string
+	}{
+		{"gzip, deflate, br", true, "br"},
+		{"br;q=1.0, gzip;q=0.9", true, "br"},
+		{"br;q=0.5, gzip", true, "gzip"},
+		{"gzip, br", false, "gzip"},
+		{"gzip", true, "gzip"},
+		{"deflate", true, ""},
+	} {
+		gz := Gzip{
+			Configs: []Config{{
+				RequestFilters:  []RequestFilter{DefaultExtFilter()},
+				ResponseFilters: []ResponseFilter{SkipCompressedFilter{}},
+				Brotli:          test.brotli,
+				BrotliLevel:     5,
+			}},
+			Next: httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+				w.Header().Set("Content-Type", "text/plain")
+				w.Header().Set("Content-Length", "100")
+				w.Write(expected)
+				return 0, nil
+			}),
+		}
+
+		r, err := http.NewRequest("GET", "/file.txt", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Header.Set("Accept-Encoding", test.acceptEncoding)
+		w := httptest.NewRecorder()
+		if _, err := gz.ServeHTTP(w, r); err != nil {
+			t.Fatalf("Test %d: %v", i, err)
+		}
+
+		if got := w.Header().Get("Content-Encoding"); got != test.encoding {
+			t.Errorf("Test %d: Expected Content-Encoding %q, got %q", i, test.encoding, got)
+		}
+		if test.encoding == "" {
+			continue
+		}
+		if got := w.Header().Get("Vary"); got != "Accept-Encoding" {
+			t.Errorf("Test %d: Expected Vary: Accept-Encoding, got %q", i, got)
+		}
+		if got := w.Header().Get("Content-Length"); got != "" {
+			t.Errorf("Test %d: Expected Content-Length to be removed, got %q", i, got)
+		}
+
+		var body io.Reader
+		if test.encoding == "br" {
+			body = brotli.NewReader(w.Body)
+		} else if body, err = gzip.NewReader(w.Body); err != nil {
+			t.Fatalf("Test %d: %v", i, err)
+		}
+		got, err := ioutil.ReadAll(body)
+		if err != nil {
+			t.Fatalf("Test %d: Could not decode %s body: %v", i, test.encoding, err)
+		}
+		if string(got) != string(expected) {
+			t.Errorf("Test %d: Decoded body does not match the original", i)
+		}
+	}
+}
@@ -0,0 +1,16 @@
+// +build !go1.12
+
+package gzip
+
+import (
+	"errors"
+	"io"
+)
+
+// brotliSupported is whether br encoding can be offered;
+// the brotli encoder needs Go 1.12 or newer.
+const brotliSupported = false
+
+func newBrotliWriter(c Config, w io.Writer) (encoder, error) {
+	return nil, errors.New("brotli is not supported by this build")
+}
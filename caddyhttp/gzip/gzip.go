@@ -1,5 +1,5 @@
 // Package gzip provides a middleware layer that performs
-// gzip compression on the response.
+// gzip (or, if enabled, brotli) compression on the response.
 package gzip
 
 import (
@@ -13,6 +13,7 @@ import (
 
 	"github.com/mholt/caddy"
 	"github.com/mholt/caddy/caddyhttp/httpserver"
+	"github.com/mholt/caddy/caddyhttp/staticfiles"
 )
 
 func init() {
@@ -36,11 +37,16 @@ type Config struct {
 	RequestFilters  []RequestFilter
 	ResponseFilters []ResponseFilter
 	Level           int // Compression level
+
+	// Brotli offers br encoding as well, which is used when
+	// the client accepts it at least as much as gzip.
+	Brotli      bool
+	BrotliLevel int // Brotli compression level
 }
 
-// ServeHTTP serves a gzipped response if the client supports it.
+// ServeHTTP serves a compressed response if the client supports it.
 func (g Gzip) ServeHTTP(w http.ResponseWriter, r *http.Request) (int, error) {
-	if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+	if r.Header.Get("Accept-Encoding") == "" {
 		return g.Next.ServeHTTP(w, r)
 	}
 outer:
@@ -53,16 +59,22 @@ outer:
 			}
 		}
 
+		encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"), c.Brotli)
+		if encoding == "" {
+			break
+		}
+
 		// gzipWriter modifies underlying writer at init,
 		// use a discard writer instead to leave ResponseWriter in
 		// original form.
-		gzipWriter, err := newWriter(c, ioutil.Discard)
+		gzipWriter, err := newEncoder(c, encoding, ioutil.Discard)
 		if err != nil {
 			// should not happen
 			return http.StatusInternalServerError, err
 		}
 		defer gzipWriter.Close()
-		gz := &gzipResponseWriter{Writer: gzipWriter, ResponseWriter: w}
+		gz := &gzipResponseWriter{Writer: gzipWriter, ResponseWriter: w, encoding: encoding}
+		gz.recorder, _ = r.Context().Value(httpserver.ResponseRecorderCtxKey).(*httpserver.ResponseRecorder)
 
 		var rw http.ResponseWriter
 		// if no response filter is used
@@ -92,6 +104,22 @@ outer:
 	return g.Next.ServeHTTP(w, r)
 }
 
+// encoder is a compressing writer like *gzip.Writer.
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(io.Writer)
+}
+
+// newEncoder creates a writer for the content encoding,
+// which is either gzip or br, as configured by c.
+func newEncoder(c Config, encoding string, w io.Writer) (encoder, error) {
+	if encoding == "br" {
+		return newBrotliWriter(c, w)
+	}
+	return newWriter(c, w)
+}
+
 // newWriter create a new Gzip Writer based on the compression level.
 // If the level is valid (i.e. between 1 and 9), it uses the level.
 // Otherwise, it uses default compression level.
@@ -102,12 +130,55 @@ func newWriter(c Config, w io.Writer) (*gzip.Writer, error) {
 	return gzip.NewWriter(w), nil
 }
 
+// negotiateEncoding picks the content encoding for a response from
+// the client's Accept-Encoding header: gzip, br if brotli is enabled,
+// or "" if the client accepts neither. The encoding with the highest
+// quality wins; br is preferred when they are equal.
+func negotiateEncoding(acceptEncoding string, brotli bool) string {
+	var gzipQ, brQ, anyQ float64 = -1, -1, -1
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		// an invalid quality makes the coding unacceptable
+		name, q, _ := staticfiles.ParseQValue(part)
+		switch name {
+		case "gzip", "x-gzip":
+			gzipQ = q
+		case "br":
+			brQ = q
+		case "*":
+			anyQ = q
+		}
+	}
+	// an encoding that isn't listed gets the quality of *
+	if gzipQ < 0 {
+		gzipQ = anyQ
+	}
+	if brQ < 0 {
+		brQ = anyQ
+	}
+	if !brotli {
+		brQ = 0
+	}
+
+	if brQ > 0 && brQ >= gzipQ {
+		return "br"
+	}
+	if gzipQ > 0 {
+		return "gzip"
+	}
+	return ""
+}
+
 // gzipResponeWriter wraps the underlying Write method
 // with a gzip.Writer to compress the output.
 type gzipResponseWriter struct {
 	io.Writer
 	http.ResponseWriter
 	statusCodeWritten bool
+	encoding          string // Content-Encoding; gzip if empty
+
+	// recorder is the server's recorder, which counts the
+	// size of the body before compression; it may be nil
+	recorder *httpserver.ResponseRecorder
 }
 
 // WriteHeader wraps the underlying WriteHeader method to prevent
@@ -115,11 +186,19 @@ type gzipResponseWriter struct {
 // example, a backend system that calculates Content-Length would
 // be wrong because it doesn't know it's being gzipped.
 func (w *gzipResponseWriter) WriteHeader(code int) {
+	encoding := w.encoding
+	if encoding == "" {
+		encoding = "gzip"
+	}
 	w.Header().Del("Content-Length")
-	w.Header().Set("Content-Encoding", "gzip")
+	w.Header().Set("Content-Encoding", encoding)
 	w.Header().Add("Vary", "Accept-Encoding")
 	w.ResponseWriter.WriteHeader(code)
 	w.statusCodeWritten = true
+	if w.recorder != nil {
+		// the response is compressed, even if its body is empty
+		w.recorder.CountUncompressed(0)
+	}
 }
 
 // Write wraps the underlying Write method to do compression.
@@ -131,6 +210,9 @@ func (w *gzipResponseWriter) Write(b []byte) (int, error) {
 		w.WriteHeader(http.StatusOK)
 	}
 	n, err := w.Writer.Write(b)
+	if w.recorder != nil {
+		w.recorder.CountUncompressed(n)
+	}
 	return n, err
 }
 
@@ -143,9 +225,16 @@ func (w *gzipResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 	return nil, nil, httpserver.NonHijackerError{Underlying: w.ResponseWriter}
 }
 
-// Flush implements http.Flusher. It simply wraps the underlying
-// ResponseWriter's Flush method if there is one, or panics.
+// Flush implements http.Flusher. It flushes the compressed data
+// written so far, then the underlying ResponseWriter if it is an
+// http.Flusher, or panics.
 func (w *gzipResponseWriter) Flush() {
+	if !w.statusCodeWritten {
+		w.WriteHeader(http.StatusOK)
+	}
+	if enc, ok := w.Writer.(encoder); ok {
+		enc.Flush()
+	}
 	if f, ok := w.ResponseWriter.(http.Flusher); ok {
 		f.Flush()
 	} else {
@@ -0,0 +1,18 @@
+// +build go1.8
+
+package gzip
+
+import (
+	"net/http"
+
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+// Push implements http.Pusher. It simply wraps the underlying
+// ResponseWriter's Push method if there is one, or returns an error.
+func (w *gzipResponseWriter) Push(target string, opts *http.PushOptions) error {
+	if p, ok := w.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return httpserver.NonPusherError{Underlying: w.ResponseWriter}
+}
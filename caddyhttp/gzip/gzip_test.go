@@ -1,13 +1,18 @@
 package gzip
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
 
+	"github.com/mholt/caddy"
 	"github.com/mholt/caddy/caddyhttp/httpserver"
 )
 
@@ -117,3 +122,200 @@ func nextFunc(shouldGzip bool) httpserver.Handler {
 		return 0, nil
 	})
 }
+
+func TestNegotiateEncoding(t *testing.T) {
+	for i, test := range []struct {
+		acceptEncoding string
+		brotli         bool
+		expected       string
+	}{
+		{"gzip", true, "gzip"},
+		{"gzip", false, "gzip"},
+		{"br", true, "br"},
+		{"br", false, ""},
+		{"gzip, deflate, br", true, "br"},
+		{"gzip, deflate, br", false, "gzip"},
+		{"br;q=0.5, gzip;q=0.8", true, "gzip"},
+		{"br;q=0.8, gzip;q=0.5", true, "br"},
+		{"gzip;q=0.8, br;q=0.8", true, "br"},
+		{"GZIP", true, "gzip"},
+		{"x-gzip", true, "gzip"},
+		{"gzip;q=0", true, ""},
+		{"br;q=0, gzip", true, "gzip"},
+		{"*", true, "br"},
+		{"*", false, "gzip"},
+		{"*;q=0.5, gzip", true, "gzip"},
+		{"*, br;q=0", true, "gzip"},
+		{"deflate, identity", true, ""},
+		{"", true, ""},
+	} {
+		if got := negotiateEncoding(test.acceptEncoding, test.brotli); got != test.expected {
+			t.Errorf("Test %d: Expected %q for Accept-Encoding %q (brotli %v), got %q",
+				i, test.expected, test.acceptEncoding, test.brotli, got)
+		}
+	}
+}
+
+func TestGzipUnsupportedClient(t *testing.T) {
+	gz := Gzip{
+		Configs: []Config{{RequestFilters: []RequestFilter{DefaultExtFilter()}, Brotli: brotliSupported}},
+		Next:    nextFunc(false),
+	}
+	for _, acceptEncoding := range []string{"deflate", "identity", "gzip;q=0, br;q=0"} {
+		r, err := http.NewRequest("GET", "/file.txt", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Header.Set("Accept-Encoding", acceptEncoding)
+		w := httptest.NewRecorder()
+		if _, err := gz.ServeHTTP(w, r); err != nil {
+			t.Errorf("Accept-Encoding %q: %v", acceptEncoding, err)
+		}
+		if got := w.Header().Get("Content-Encoding"); got != "" {
+			t.Errorf("Accept-Encoding %q: Expected no Content-Encoding, got %q", acceptEncoding, got)
+		}
+	}
+}
+
+func TestGzipLevel(t *testing.T) {
+	configs, err := gzipParse(caddy.NewTestController("http", `gzip { level 1 }
+		gzip { level 9 }`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if configs[0].Level != 1 || configs[1].Level != 9 {
+		t.Fatalf("Expected levels 1 and 9, got %d and %d", configs[0].Level, configs[1].Level)
+	}
+
+	b, err := ioutil.ReadFile("testdata/test.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	size := func(c Config) int {
+		var buf bytes.Buffer
+		w, err := newWriter(c, &buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(b)
+		w.Close()
+		return buf.Len()
+	}
+	if fast, best := size(configs[0]), size(configs[1]); fast <= best {
+		t.Errorf("Expected level 1 output (%d bytes) to be larger than level 9 output (%d bytes)", fast, best)
+	}
+}
+
+func TestGzipFlush(t *testing.T) {
+	for i, c := range []Config{
+		{},
+		{ResponseFilters: []ResponseFilter{SkipCompressedFilter{}}},
+	} {
+		c.RequestFilters = []RequestFilter{DefaultExtFilter()}
+		rec := httptest.NewRecorder()
+
+		// what the client has got at the first flush
+		var partial []byte
+		var encoding string
+
+		gz := Gzip{
+			Configs: []Config{c},
+			Next: httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+				w.Header().Set("Content-Type", "text/event-stream")
+				w.Write([]byte("data: first\n\n"))
+				w.(http.Flusher).Flush()
+
+				partial = append(partial, rec.Body.Bytes()...)
+				encoding = rec.Header().Get("Content-Encoding")
+
+				w.Write([]byte("data: second\n\n"))
+				return 0, nil
+			}),
+		}
+
+		r, err := http.NewRequest("GET", "/events", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Header.Set("Accept-Encoding", "gzip")
+		if _, err := gz.ServeHTTP(rec, r); err != nil {
+			t.Fatalf("Test %d: %v", i, err)
+		}
+
+		if encoding != "gzip" {
+			t.Fatalf("Test %d: Expected Content-Encoding gzip at the first flush, got %q", i, encoding)
+		}
+		if !rec.Flushed {
+			t.Errorf("Test %d: Expected underlying writer to be flushed", i)
+		}
+		gr, err := gzip.NewReader(bytes.NewReader(partial))
+		if err != nil {
+			t.Fatalf("Test %d: Expected gzip header to be flushed: %v", i, err)
+		}
+		first := make([]byte, len("data: first\n\n"))
+		if _, err := io.ReadFull(gr, first); err != nil {
+			t.Fatalf("Test %d: Expected first event to be flushed: %v", i, err)
+		}
+		if string(first) != "data: first\n\n" {
+			t.Errorf("Test %d: Expected first event, got %q", i, first)
+		}
+	}
+}
+
+func TestGzipSizes(t *testing.T) {
+	body := strings.Repeat("compressible ", 100)
+
+	for i, test := range []struct {
+		acceptEncoding string
+		contentType    string
+		compressed     bool
+	}{
+		{"gzip", "text/plain", true},
+		{"", "text/plain", false},
+		{"gzip", "image/png", false}, // skipped by the response filter
+	} {
+		rec := httptest.NewRecorder()
+		rr := httpserver.NewResponseRecorder(rec)
+		r, err := http.NewRequest("GET", "/file", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = r.WithContext(context.WithValue(r.Context(), httpserver.ResponseRecorderCtxKey, rr))
+		if test.acceptEncoding != "" {
+			r.Header.Set("Accept-Encoding", test.acceptEncoding)
+		}
+
+		gz := Gzip{
+			Configs: []Config{{ResponseFilters: []ResponseFilter{SkipCompressedFilter{}}}},
+			Next: httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+				w.Header().Set("Content-Type", test.contentType)
+				for j := 0; j < 100; j++ {
+					w.Write([]byte("compressible "))
+				}
+				return 0, nil
+			}),
+		}
+		if _, err := gz.ServeHTTP(rr, r); err != nil {
+			t.Fatalf("Test %d: %v", i, err)
+		}
+
+		size, uncompressed := rr.Size(), rr.UncompressedSize()
+		if size != rec.Body.Len() {
+			t.Errorf("Test %d: Expected size %d to be the bytes sent, %d", i, size, rec.Body.Len())
+		}
+		if uncompressed != len(body) {
+			t.Errorf("Test %d: Expected uncompressed size %d, got %d", i, len(body), uncompressed)
+		}
+		if test.compressed && size >= uncompressed {
+			t.Errorf("Test %d: Expected size %d to be less than uncompressed size %d", i, size, uncompressed)
+		}
+		if !test.compressed && size != uncompressed {
+			t.Errorf("Test %d: Expected size %d to equal uncompressed size %d", i, size, uncompressed)
+		}
+
+		repl := httpserver.NewReplacer(r, nil, "-")
+		if got, want := repl.Replace("{size} {size_uncompressed}"), fmt.Sprintf("%d %d", size, uncompressed); got != want {
+			t.Errorf("Test %d: Expected placeholders %q, got %q", i, want, got)
+		}
+	}
+}
@@ -1,9 +1,11 @@
 package gzip
 
 import (
-	"compress/gzip"
 	"net/http"
 	"strconv"
+	"strings"
+
+	"github.com/mholt/caddy/caddyhttp/httpserver"
 )
 
 // ResponseFilter determines if the response should be gzipped.
@@ -30,13 +32,49 @@ type SkipCompressedFilter struct{}
 
 // ShouldCompress returns true if served file is not already compressed
 // encodings via https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/Content-Encoding
+// and is not of a content type that is compressed by itself.
 func (n SkipCompressedFilter) ShouldCompress(w http.ResponseWriter) bool {
 	switch w.Header().Get("Content-Encoding") {
 	case "gzip", "compress", "deflate", "br":
 		return false
-	default:
+	}
+	return !compressedContentType(w.Header().Get("Content-Type"))
+}
+
+// compressedContentTypes are content types, other than images,
+// audio and video, that are compressed already.
+var compressedContentTypes = Set{
+	"application/gzip":             struct{}{},
+	"application/x-gzip":           struct{}{},
+	"application/x-brotli":         struct{}{},
+	"application/x-bzip2":          struct{}{},
+	"application/x-xz":             struct{}{},
+	"application/x-7z-compressed":  struct{}{},
+	"application/x-rar-compressed": struct{}{},
+	"application/zip":              struct{}{},
+	"application/font-woff":        struct{}{},
+	"font/woff":                    struct{}{},
+	"font/woff2":                   struct{}{},
+}
+
+// compressedContentType reports whether a response of contentType
+// wouldn't get any smaller by compressing it.
+func compressedContentType(contentType string) bool {
+	mediaType := contentType
+	if i := strings.Index(mediaType, ";"); i >= 0 {
+		mediaType = mediaType[:i]
+	}
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+
+	switch {
+	case mediaType == "image/svg+xml", mediaType == "image/x-icon", mediaType == "image/bmp":
+		return false
+	case strings.HasPrefix(mediaType, "image/"),
+		strings.HasPrefix(mediaType, "audio/"),
+		strings.HasPrefix(mediaType, "video/"):
 		return true
 	}
+	return compressedContentTypes.Contains(mediaType)
 }
 
 // ResponseFilterWriter validates ResponseFilters. It writes
@@ -68,7 +106,7 @@ func (r *ResponseFilterWriter) WriteHeader(code int) {
 
 	if r.shouldCompress {
 		// replace discard writer with ResponseWriter
-		if gzWriter, ok := r.gzipResponseWriter.Writer.(*gzip.Writer); ok {
+		if gzWriter, ok := r.gzipResponseWriter.Writer.(encoder); ok {
 			gzWriter.Reset(r.ResponseWriter)
 		}
 		// use gzip WriteHeader to include and delete
@@ -91,3 +129,20 @@ func (r *ResponseFilterWriter) Write(b []byte) (int, error) {
 	}
 	return r.ResponseWriter.Write(b)
 }
+
+// Flush implements http.Flusher. It writes the header, so that
+// the filters decide on compression, and then flushes.
+func (r *ResponseFilterWriter) Flush() {
+	if !r.statusCodeWritten {
+		r.WriteHeader(http.StatusOK)
+	}
+	if r.shouldCompress {
+		r.gzipResponseWriter.Flush()
+		return
+	}
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	} else {
+		panic(httpserver.NonFlusherError{Underlying: r.ResponseWriter}) // should be recovered at the beginning of middleware stack
+	}
+}
@@ -1,10 +1,12 @@
 package gzip
 
 import (
+	"bytes"
 	"compress/gzip"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
 
 	"github.com/mholt/caddy/caddyhttp/httpserver"
@@ -33,7 +35,7 @@ func TestLengthFilter(t *testing.T) {
 		for j, filter := range filters {
 			r := httptest.NewRecorder()
 			r.Header().Set("Content-Length", fmt.Sprint(ts.length))
-			wWriter := NewResponseFilterWriter([]ResponseFilter{filter}, &gzipResponseWriter{gzip.NewWriter(r), r, false})
+			wWriter := NewResponseFilterWriter([]ResponseFilter{filter}, &gzipResponseWriter{Writer: gzip.NewWriter(r), ResponseWriter: r})
 			if filter.ShouldCompress(wWriter) != ts.shouldCompress[j] {
 				t.Errorf("Test %v: Expected %v found %v", i, ts.shouldCompress[j], filter.ShouldCompress(r))
 			}
@@ -110,3 +112,73 @@ func TestResponseGzippedOutput(t *testing.T) {
 		t.Errorf("Expected output not to be gzipped")
 	}
 }
+
+func TestSkipCompressedFilter(t *testing.T) {
+	for i, test := range []struct {
+		contentEncoding, contentType string
+		expected                     bool
+	}{
+		{"", "text/html; charset=utf-8", true},
+		{"", "application/json", true},
+		{"", "image/svg+xml", true},
+		{"", "", true},
+		{"gzip", "text/html", false},
+		{"br", "text/html", false},
+		{"", "image/png", false},
+		{"", "IMAGE/JPEG", false},
+		{"", "video/mp4", false},
+		{"", "audio/ogg", false},
+		{"", "application/zip", false},
+		{"", "application/gzip", false},
+		{"", "font/woff2", false},
+	} {
+		w := httptest.NewRecorder()
+		if test.contentEncoding != "" {
+			w.Header().Set("Content-Encoding", test.contentEncoding)
+		}
+		w.Header().Set("Content-Type", test.contentType)
+		if got := (SkipCompressedFilter{}).ShouldCompress(w); got != test.expected {
+			t.Errorf("Test %d: Expected ShouldCompress %v for %q %q, got %v",
+				i, test.expected, test.contentEncoding, test.contentType, got)
+		}
+	}
+}
+
+func TestResponseMinLength(t *testing.T) {
+	for i, test := range []struct {
+		length   int
+		encoding string
+	}{
+		{10, ""},
+		{99, ""},
+		{100, "gzip"},
+		{1000, "gzip"},
+	} {
+		server := Gzip{Configs: []Config{
+			{ResponseFilters: []ResponseFilter{SkipCompressedFilter{}, LengthFilter(100)}},
+		}}
+		server.Next = httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+			w.Header().Set("Content-Length", strconv.Itoa(test.length))
+			w.Write(bytes.Repeat([]byte("a"), test.length))
+			return 200, nil
+		})
+
+		r := urlRequest("/")
+		r.Header.Set("Accept-Encoding", "gzip")
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, r)
+
+		if got := w.Header().Get("Content-Encoding"); got != test.encoding {
+			t.Errorf("Test %d: Expected Content-Encoding %q, got %q", i, test.encoding, got)
+		}
+		contentLength := w.Header().Get("Content-Length")
+		if test.encoding == "" && contentLength != strconv.Itoa(test.length) {
+			t.Errorf("Test %d: Expected Content-Length %d to be kept, got %q", i, test.length, contentLength)
+		} else if test.encoding != "" && contentLength != "" {
+			t.Errorf("Test %d: Expected Content-Length to be removed, got %q", i, contentLength)
+		}
+		if test.encoding == "" && w.Body.Len() != test.length {
+			t.Errorf("Test %d: Expected uncompressed body of %d bytes, got %d", i, test.length, w.Body.Len())
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package gzip
 
 import (
+	"compress/gzip"
 	"fmt"
 	"strconv"
 	"strings"
@@ -72,8 +73,27 @@ func gzipParse(c *caddy.Controller) ([]Config, error) {
 				if !c.NextArg() {
 					return configs, c.ArgErr()
 				}
-				level, _ := strconv.Atoi(c.Val())
+				level, err := strconv.Atoi(c.Val())
+				if err != nil || level < gzip.BestSpeed || level > gzip.BestCompression {
+					return configs, fmt.Errorf("gzip: level must be between %d and %d, got '%s'",
+						gzip.BestSpeed, gzip.BestCompression, c.Val())
+				}
 				config.Level = level
+			case "brotli":
+				if !brotliSupported {
+					return configs, fmt.Errorf("gzip: brotli is not supported by this build (requires Go 1.12 or newer)")
+				}
+				config.Brotli = true
+				if c.NextArg() {
+					level, err := strconv.Atoi(c.Val())
+					if err != nil || level < 1 || level > 11 {
+						return configs, fmt.Errorf("gzip: brotli level must be between 1 and 11, got '%s'", c.Val())
+					}
+					config.BrotliLevel = level
+				}
+				if c.NextArg() {
+					return configs, c.ArgErr()
+				}
 			case "min_length":
 				if !c.NextArg() {
 					return configs, c.ArgErr()
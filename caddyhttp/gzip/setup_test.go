@@ -89,6 +89,25 @@ func TestSetup(t *testing.T) {
 		 min_length 1000
 		}
 		`, false},
+		{`gzip { level 0 } `, true},
+		{`gzip { level 10 } `, true},
+		{`gzip { level best } `, true},
+		{`gzip {
+		 brotli
+		}`, !brotliSupported},
+		{`gzip {
+		 brotli 11
+		 level 6
+		}`, !brotliSupported},
+		{`gzip {
+		 brotli 12
+		}`, true},
+		{`gzip {
+		 brotli fast
+		}`, true},
+		{`gzip {
+		 brotli 5 6
+		}`, true},
 	}
 	for i, test := range tests {
 		_, err := gzipParse(caddy.NewTestController("http", test.input))
@@ -7,6 +7,7 @@ import (
 	"bufio"
 	"net"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/mholt/caddy/caddyhttp/httpserver"
@@ -21,11 +22,19 @@ type Headers struct {
 
 // ServeHTTP implements the httpserver.Handler interface and serves requests,
 // setting headers on the response according to the configured rules.
+// Headers are set before the request is passed on, so handlers further
+// down the chain may still change them, except for values that use a
+// placeholder of the response (like {status}): those are expanded and
+// set when the response header is written, and so take precedence. A
+// brace may be escaped with a backslash to keep it literal. Matching
+// rules are applied in order, so a later rule that sets a header
+// overrides an earlier one, while a header removed by any matching rule
+// stays removed, even if another rule or handler sets it again.
 func (h Headers) ServeHTTP(w http.ResponseWriter, r *http.Request) (int, error) {
-	replacer := httpserver.NewReplacer(r, nil, "")
-	rww := &responseWriterWrapper{w: w}
+	rww := &responseWriterWrapper{w: w, replacer: httpserver.NewReplacer(r, nil, "")}
 	for _, rule := range h.Rules {
-		if httpserver.Path(r.URL.Path).Matches(rule.Path) {
+		if httpserver.Path(r.URL.Path).Matches(rule.Path) &&
+			(rule.RequestMatcher == nil || rule.Match(r)) {
 			for name := range rule.Headers {
 
 				// One can either delete a header, add multiple values to a header, or simply
@@ -35,24 +44,67 @@ func (h Headers) ServeHTTP(w http.ResponseWriter, r *http.Request) (int, error)
 					rww.delHeader(strings.TrimLeft(name, "-"))
 				} else if strings.HasPrefix(name, "+") {
 					for _, value := range rule.Headers[name] {
-						rww.Header().Add(strings.TrimLeft(name, "+"), replacer.Replace(value))
+						rww.addHeader(strings.TrimLeft(name, "+"), value)
 					}
 				} else {
 					for _, value := range rule.Headers[name] {
-						rww.Header().Set(name, replacer.Replace(value))
+						rww.setHeader(name, value)
 					}
 				}
 			}
 		}
 	}
-	return h.Next.ServeHTTP(rww, r)
+	status, err := h.Next.ServeHTTP(rww, r)
+	if !rww.wroteHeader {
+		// the response is written further up the chain, if at
+		// all (error pages for example); it gets the headers too
+		rww.applyOps()
+	}
+	return status, err
+}
+
+// responsePlaceholders are the placeholders that are only known
+// once the response header is written.
+var responsePlaceholders = []string{
+	"{status}", "{size}", "{size_uncompressed}", "{content_type}", "{latency}", "{latency_ms}",
+}
+
+// isResponseValue reports whether value uses a placeholder of the
+// response, so that it must not be expanded before then.
+func isResponseValue(value string) bool {
+	for _, placeholder := range responsePlaceholders {
+		if strings.Contains(value, placeholder) {
+			return true
+		}
+	}
+	return false
+}
+
+// expand replaces the placeholders in value. Braces escaped
+// with a backslash are kept literally, without the backslash.
+func expand(replacer httpserver.Replacer, value string) string {
+	var expanded string
+	for {
+		i := strings.Index(value, `\{`)
+		if j := strings.Index(value, `\}`); j >= 0 && (i < 0 || j < i) {
+			i = j
+		}
+		if i < 0 {
+			return expanded + replacer.Replace(value)
+		}
+		expanded += replacer.Replace(value[:i]) + value[i+1:i+2]
+		value = value[i+2:]
+	}
 }
 
 type (
 	// Rule groups a slice of HTTP headers by a URL pattern.
+	// If RequestMatcher is not nil, the headers are only
+	// applied to requests that it matches as well.
 	Rule struct {
 		Path    string
 		Headers http.Header
+		httpserver.RequestMatcher
 	}
 )
 
@@ -63,7 +115,11 @@ type headerOperation func(http.Header)
 // It defers header operations until writeHeader
 type responseWriterWrapper struct {
 	w           http.ResponseWriter
+	replacer    httpserver.Replacer
 	ops         []headerOperation
+	dels        []headerOperation
+	deferred    map[string]bool // keys with deferred operations
+	applied     bool
 	wroteHeader bool
 }
 
@@ -83,6 +139,23 @@ func (rww *responseWriterWrapper) WriteHeader(status int) {
 		return
 	}
 	rww.wroteHeader = true
+
+	// the status is known now, though the recorder for
+	// the {status} placeholder doesn't have it yet
+	rww.replacer.Set("status", strconv.Itoa(status))
+	rww.applyOps()
+
+	rww.w.WriteHeader(status)
+}
+
+// applyOps performs the deferred header operations once,
+// deletions last so that they win.
+func (rww *responseWriterWrapper) applyOps() {
+	if rww.applied {
+		return
+	}
+	rww.applied = true
+
 	// capture the original headers
 	h := rww.Header()
 
@@ -90,8 +163,42 @@ func (rww *responseWriterWrapper) WriteHeader(status int) {
 	for _, op := range rww.ops {
 		op(h)
 	}
+	for _, op := range rww.dels {
+		op(h)
+	}
+}
 
-	rww.w.WriteHeader(status)
+// setHeader sets the header key to value, with its placeholders
+// expanded; see headerOp.
+func (rww *responseWriterWrapper) setHeader(key, value string) {
+	rww.headerOp(key, value, func(h http.Header) {
+		h.Set(key, expand(rww.replacer, value))
+	})
+}
+
+// addHeader adds value, with its placeholders expanded, to the
+// header key; see headerOp.
+func (rww *responseWriterWrapper) addHeader(key, value string) {
+	rww.headerOp(key, value, func(h http.Header) {
+		h.Add(key, expand(rww.replacer, value))
+	})
+}
+
+// headerOp performs op on the header key right away, unless value
+// uses a placeholder of the response. Then op is deferred until the
+// response header is written, as are the operations on key that
+// follow it, so that they are still performed in order.
+func (rww *responseWriterWrapper) headerOp(key, value string, op headerOperation) {
+	key = http.CanonicalHeaderKey(key)
+	if !rww.deferred[key] && !isResponseValue(value) {
+		op(rww.Header())
+		return
+	}
+	if rww.deferred == nil {
+		rww.deferred = make(map[string]bool)
+	}
+	rww.deferred[key] = true
+	rww.ops = append(rww.ops, op)
 }
 
 // delHeader deletes the existing header according to the key
@@ -101,7 +208,7 @@ func (rww *responseWriterWrapper) delHeader(key string) {
 	rww.Header().Del(key)
 
 	// register a future deletion
-	rww.ops = append(rww.ops, func(h http.Header) {
+	rww.dels = append(rww.dels, func(h http.Header) {
 		h.Del(key)
 	})
 }
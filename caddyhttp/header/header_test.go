@@ -9,6 +9,7 @@ import (
 	"sort"
 	"testing"
 
+	"github.com/mholt/caddy"
 	"github.com/mholt/caddy/caddyhttp/httpserver"
 )
 
@@ -93,3 +94,200 @@ func TestMultipleHeaders(t *testing.T) {
 		t.Errorf("Expected header to contain: %v but got: %v", desiredHeaders, actualHeaders)
 	}
 }
+
+func TestConditionalHeaders(t *testing.T) {
+	rules, err := headersParse(caddy.NewTestController("http", `
+		header / X-Frame-Options DENY
+		header /static {
+			if {method} is GET
+			if {path} ends_with .js
+			Cache-Control "public, max-age=86400"
+			X-Frame-Options SAMEORIGIN
+		}
+		header /static/vendor {
+			if_op or
+			if {path} ends_with .js
+			if {path} ends_with .css
+			Cache-Control "public, max-age=31536000"
+			-Server
+		}
+		header /static/vendor +Vary Origin`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, test := range []struct {
+		method, path string
+		expected     http.Header
+	}{
+		{"GET", "/index.html", http.Header{
+			"X-Frame-Options": {"DENY"},
+			"Server":          {"Caddy"},
+		}},
+		{"GET", "/static/app.js", http.Header{
+			"X-Frame-Options": {"SAMEORIGIN"},
+			"Cache-Control":   {"public, max-age=86400"},
+			"Server":          {"Caddy"},
+		}},
+		{"POST", "/static/app.js", http.Header{
+			"X-Frame-Options": {"DENY"},
+			"Server":          {"Caddy"},
+		}},
+		{"GET", "/static/site.css", http.Header{
+			"X-Frame-Options": {"DENY"},
+			"Server":          {"Caddy"},
+		}},
+		{"GET", "/static/vendor/lib.js", http.Header{
+			"X-Frame-Options": {"SAMEORIGIN"},
+			"Cache-Control":   {"public, max-age=31536000"},
+			"Vary":            {"Origin"},
+		}},
+		{"POST", "/static/vendor/lib.css", http.Header{
+			"X-Frame-Options": {"DENY"},
+			"Cache-Control":   {"public, max-age=31536000"},
+			"Vary":            {"Origin"},
+		}},
+		{"GET", "/static/vendor/logo.png", http.Header{
+			"X-Frame-Options": {"DENY"},
+			"Vary":            {"Origin"},
+			"Server":          {"Caddy"},
+		}},
+	} {
+		he := Headers{
+			Next: httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+				w.Header().Set("Server", "Caddy")
+				w.WriteHeader(http.StatusOK)
+				return 0, nil
+			}),
+			Rules: rules,
+		}
+
+		req, err := http.NewRequest(test.method, test.path, nil)
+		if err != nil {
+			t.Fatalf("Test %d: Could not create HTTP request: %v", i, err)
+		}
+		rec := httptest.NewRecorder()
+		he.ServeHTTP(rec, req)
+
+		if !reflect.DeepEqual(rec.HeaderMap, test.expected) {
+			t.Errorf("Test %d: %s %s: Expected headers %v, but got %v",
+				i, test.method, test.path, test.expected, rec.HeaderMap)
+		}
+	}
+}
+
+func TestHeaderPlaceholders(t *testing.T) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		t.Fatalf("Could not determine hostname: %v", err)
+	}
+	he := Headers{
+		Next: httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+			w.WriteHeader(http.StatusCreated)
+			return 0, nil
+		}),
+		Rules: []Rule{
+			{Path: "/", Headers: http.Header{
+				"X-Served-By": []string{"{hostname} ({method} {path})"},
+				"X-Status":    []string{"{status}"},
+				"X-Unknown":   []string{"[{no_such_placeholder}]"},
+				"X-Escaped":   []string{`\{path\} is {path}`},
+				"+X-Trace":    []string{"{>X-Trace}", "{query}"},
+			}},
+		},
+	}
+
+	req, err := http.NewRequest("PUT", "/api/items?id=42", nil)
+	if err != nil {
+		t.Fatalf("Could not create HTTP request: %v", err)
+	}
+	req.Header.Set("X-Trace", "abc")
+	rec := httptest.NewRecorder()
+	he.ServeHTTP(rec, req)
+
+	for name, expected := range map[string][]string{
+		"X-Served-By": {hostname + " (PUT /api/items)"},
+		"X-Status":    {"201"},
+		"X-Unknown":   {"[]"},
+		"X-Escaped":   {"{path} is /api/items"},
+		"X-Trace":     {"abc", "id=42"},
+	} {
+		if got := rec.HeaderMap[name]; !reflect.DeepEqual(got, expected) {
+			t.Errorf("Expected %s header to be %q, but was %q", name, expected, got)
+		}
+	}
+}
+
+func TestHeadersOnUnwrittenResponse(t *testing.T) {
+	he := Headers{
+		Next: httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+			return http.StatusNotFound, nil
+		}),
+		Rules: []Rule{
+			{Path: "/", Headers: http.Header{
+				"X-Content-Type-Options": []string{"nosniff"},
+				"X-Path":                 []string{"{path}"},
+			}},
+		},
+	}
+
+	req, err := http.NewRequest("GET", "/missing", nil)
+	if err != nil {
+		t.Fatalf("Could not create HTTP request: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	if status, _ := he.ServeHTTP(rec, req); status != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, status)
+	}
+
+	// an error page written after returning must still get the headers
+	if got := rec.Header().Get("X-Content-Type-Options"); got != "nosniff" {
+		t.Errorf("Expected X-Content-Type-Options header to be set, got %q", got)
+	}
+	if got := rec.Header().Get("X-Path"); got != "/missing" {
+		t.Errorf("Expected X-Path header to be /missing, got %q", got)
+	}
+}
+
+func TestHeaderPrecedence(t *testing.T) {
+	he := Headers{
+		Next: httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+			// handlers further down may change the headers
+			// that are known before the response is
+			w.Header().Set("Cache-Control", "no-store")
+			w.Header().Set("X-Status", "handler")
+			w.Header().Set("X-Both", "handler")
+			w.WriteHeader(http.StatusAccepted)
+			return 0, nil
+		}),
+		Rules: []Rule{
+			{Path: "/", Headers: http.Header{
+				"Cache-Control": []string{"max-age=3600"},
+				"X-Status":      []string{"{status}"},
+				"X-Both":        []string{"{status}"},
+			}},
+			{Path: "/", Headers: http.Header{
+				// after a value of the response, a literal one is
+				// deferred too, so that it still wins
+				"+X-Both": []string{"literal"},
+			}},
+		},
+	}
+
+	req, err := http.NewRequest("GET", "/", nil)
+	if err != nil {
+		t.Fatalf("Could not create HTTP request: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	he.ServeHTTP(rec, req)
+
+	for name, expected := range map[string][]string{
+		"Cache-Control": {"no-store"},
+		"X-Status":      {"202"},
+		"X-Both":        {"202", "literal"},
+	} {
+		if got := rec.HeaderMap[name]; !reflect.DeepEqual(got, expected) {
+			t.Errorf("Expected %s header to be %q, but was %q", name, expected, got)
+		}
+	}
+}
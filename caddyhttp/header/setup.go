@@ -41,11 +41,20 @@ func headersParse(c *caddy.Controller) ([]Rule, error) {
 		}
 		pattern := c.Val()
 
-		// See if we already have a definition for this Path pattern...
-		for _, h := range rules {
-			if h.Path == pattern {
-				head = h
-				break
+		matcher, err := httpserver.SetupIfMatcher(c)
+		if err != nil {
+			return rules, err
+		}
+		conditional := httpserver.HasIfMatcher(c)
+
+		// See if we already have a definition for this Path pattern;
+		// rules with conditions are never merged with others...
+		if !conditional {
+			for _, h := range rules {
+				if h.Path == pattern && h.RequestMatcher == nil {
+					head = h
+					break
+				}
 			}
 		}
 
@@ -53,9 +62,16 @@ func headersParse(c *caddy.Controller) ([]Rule, error) {
 		if head.Path == "" {
 			head.Path = pattern
 			isNewPattern = true
+			if conditional {
+				head.RequestMatcher = matcher
+			}
 		}
 
 		for c.NextBlock() {
+			if httpserver.IfMatcherKeyword(c) {
+				continue
+			}
+
 			// A block of headers was opened...
 			name := c.Val()
 			value := ""
@@ -87,7 +103,7 @@ func headersParse(c *caddy.Controller) ([]Rule, error) {
 			rules = append(rules, head)
 		} else {
 			for i := 0; i < len(rules); i++ {
-				if rules[i].Path == pattern {
+				if rules[i].Path == pattern && rules[i].RequestMatcher == nil {
 					rules[i] = head
 					break
 				}
@@ -57,6 +57,41 @@ func TestHeadersParse(t *testing.T) {
 					"Foobar": []string{""},
 				}},
 			}},
+		{`header /static {
+				if {path} ends_with .js
+				Cache-Control "max-age=3600"
+			}
+			header /static X-Static yes`,
+			false, []Rule{
+				{Path: "/static", Headers: http.Header{
+					"Cache-Control": []string{"max-age=3600"},
+				}, RequestMatcher: httpserver.IfMatcher{}},
+				{Path: "/static", Headers: http.Header{
+					"X-Static": []string{"yes"},
+				}},
+			}},
+		{`header /static X-Static yes
+			header /static {
+				if_op or
+				if {path} ends_with .js
+				if {path} ends_with .css
+				Cache-Control "max-age=3600"
+			}
+			header /static -Server`,
+			false, []Rule{
+				{Path: "/static", Headers: http.Header{
+					"X-Static": []string{"yes"},
+					"-Server":  []string{"-Server"},
+				}},
+				{Path: "/static", Headers: http.Header{
+					"Cache-Control": []string{"max-age=3600"},
+				}, RequestMatcher: httpserver.IfMatcher{}},
+			}},
+		{`header /foo {
+				if {path} is
+				Foo Bar
+			}`, true,
+			[]Rule{}},
 		{`header /foo {
 				Foo Bar Baz
 			}`, true,
@@ -88,6 +123,11 @@ func TestHeadersParse(t *testing.T) {
 					i, j, expectedRule.Path, actualRule.Path)
 			}
 
+			if (actualRule.RequestMatcher == nil) != (expectedRule.RequestMatcher == nil) {
+				t.Errorf("Test %d, rule %d: Expected request matcher %v, but got %v",
+					i, j, expectedRule.RequestMatcher, actualRule.RequestMatcher)
+			}
+
 			expectedHeaders := fmt.Sprintf("%v", expectedRule.Headers)
 			actualHeaders := fmt.Sprintf("%v", actualRule.Headers)
 
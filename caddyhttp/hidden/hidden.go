@@ -0,0 +1,74 @@
+// Package hidden configures which paths the static file server
+// and directory listings of a site treat as if they did not exist.
+package hidden
+
+import (
+	"path"
+	"strings"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func init() {
+	caddy.RegisterPlugin("hidden", caddy.Plugin{
+		ServerType: "http",
+		Action:     setupHidden,
+	})
+}
+
+// setupHidden parses the hidden directive:
+//
+//	hidden [off | patterns...] {
+//		except paths...
+//	}
+//
+// The patterns, which replace the default ones, are matched against
+// every component of a request path, so .git hides /.git/config. The
+// paths of except, and the paths below them, are never hidden.
+func setupHidden(c *caddy.Controller) error {
+	config := httpserver.GetConfig(c)
+	for c.Next() {
+		args := c.RemainingArgs()
+		if len(args) > 0 && args[0] == "off" {
+			if len(args) > 1 || len(config.HiddenPatterns) > 0 {
+				return c.Err("hidden off cannot be combined with patterns")
+			}
+			config.HiddenPatterns = []string{}
+		} else if len(args) > 0 {
+			if config.HiddenPatterns != nil && len(config.HiddenPatterns) == 0 {
+				return c.Err("hidden off cannot be combined with patterns")
+			}
+			for _, pattern := range args {
+				if _, err := path.Match(pattern, ""); err != nil || strings.Contains(pattern, "/") {
+					return c.Errf("invalid pattern '%s': patterns match a single path component", pattern)
+				}
+				config.HiddenPatterns = append(config.HiddenPatterns, pattern)
+			}
+		}
+
+		var block bool
+		for c.NextBlock() {
+			block = true
+			switch c.Val() {
+			case "except":
+				paths := c.RemainingArgs()
+				if len(paths) == 0 {
+					return c.ArgErr()
+				}
+				for _, p := range paths {
+					if !strings.HasPrefix(p, "/") {
+						return c.Errf("exception '%s' must be a path starting with /", p)
+					}
+					config.HiddenExceptions = append(config.HiddenExceptions, p)
+				}
+			default:
+				return c.Errf("unknown subdirective '%s'", c.Val())
+			}
+		}
+		if len(args) == 0 && !block {
+			return c.ArgErr()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package hidden
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func TestSetupHidden(t *testing.T) {
+	for i, test := range []struct {
+		input              string
+		shouldErr          bool
+		expectedPatterns   []string
+		expectedExceptions []string
+	}{
+		{"hidden .git .env*", false, []string{".git", ".env*"}, nil},
+		{"hidden .git\nhidden *.bak", false, []string{".git", "*.bak"}, nil},
+		{"hidden off", false, []string{}, nil},
+		{"hidden {\n\texcept /.git/public /docs/.htaccess\n}", false, nil, []string{"/.git/public", "/docs/.htaccess"}},
+		{"hidden .git {\n\texcept /.git/public\n}", false, []string{".git"}, []string{"/.git/public"}},
+		{"hidden", true, nil, nil},
+		{"hidden off .git", true, nil, nil},
+		{"hidden off\nhidden .git", true, nil, nil},
+		{"hidden .git\nhidden off", true, nil, nil},
+		{"hidden [", true, nil, nil},
+		{"hidden .git/config", true, nil, nil},
+		{"hidden {\n\texcept\n}", true, nil, nil},
+		{"hidden {\n\texcept .git\n}", true, nil, nil},
+		{"hidden {\n\tallow /.git\n}", true, nil, nil},
+	} {
+		c := caddy.NewTestController("http", test.input)
+		err := setupHidden(c)
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: Expected an error, but did not have one", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: Did not expect error, but got: %v", i, err)
+			continue
+		}
+
+		config := httpserver.GetConfig(c)
+		if !reflect.DeepEqual(config.HiddenPatterns, test.expectedPatterns) {
+			t.Errorf("Test %d: Expected patterns %#v, got %#v", i, test.expectedPatterns, config.HiddenPatterns)
+		}
+		if !reflect.DeepEqual(config.HiddenExceptions, test.expectedExceptions) {
+			t.Errorf("Test %d: Expected exceptions %#v, got %#v", i, test.expectedExceptions, config.HiddenExceptions)
+		}
+	}
+}
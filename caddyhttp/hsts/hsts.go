@@ -0,0 +1,59 @@
+// Package hsts is middleware that adds the Strict-Transport-Security
+// header to HTTPS responses.
+package hsts
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+// HSTS is middleware that tells clients to only use HTTPS for the
+// site. The header is only sent over HTTPS, as clients ignore it over
+// plaintext connections where it could be forged, and it is not sent
+// if the response has one already, from a proxy backend for example.
+type HSTS struct {
+	Next              httpserver.Handler
+	MaxAge            time.Duration
+	IncludeSubdomains bool
+	Preload           bool
+}
+
+// ServeHTTP implements the httpserver.Handler interface.
+func (h HSTS) ServeHTTP(w http.ResponseWriter, r *http.Request) (int, error) {
+	if r.TLS == nil {
+		return h.Next.ServeHTTP(w, r)
+	}
+
+	// the header is set when the response header is written, so
+	// that headers set by later handlers are known by then; it is
+	// not set if the response has one
+	value := h.Value()
+	setHeader := func(int) {
+		if w.Header().Get("Strict-Transport-Security") == "" {
+			w.Header().Set("Strict-Transport-Security", value)
+		}
+	}
+	hw := httpserver.NewHeaderWriter(w, setHeader)
+	status, err := h.Next.ServeHTTP(hw, r)
+	if !hw.WroteHeader() {
+		// the response is written further up the
+		// chain, if at all (error pages for example)
+		setHeader(status)
+	}
+	return status, err
+}
+
+// Value returns the value of the Strict-Transport-Security header.
+func (h HSTS) Value() string {
+	value := "max-age=" + strconv.FormatInt(int64(h.MaxAge/time.Second), 10)
+	if h.IncludeSubdomains {
+		value += "; includeSubDomains"
+	}
+	if h.Preload {
+		value += "; preload"
+	}
+	return value
+}
@@ -0,0 +1,69 @@
+package hsts
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func TestHSTS(t *testing.T) {
+	for i, test := range []struct {
+		hsts     HSTS
+		tls      bool
+		header   string // the response's own
+		status   int    // returned by the next handler instead of writing
+		expected []string
+	}{
+		{HSTS{MaxAge: 365 * 24 * time.Hour}, true, "", 0, []string{"max-age=31536000"}},
+		{HSTS{MaxAge: time.Hour, IncludeSubdomains: true}, true, "", 0, []string{"max-age=3600; includeSubDomains"}},
+		{HSTS{MaxAge: 2 * 365 * 24 * time.Hour, IncludeSubdomains: true, Preload: true}, true, "", 0,
+			[]string{"max-age=63072000; includeSubDomains; preload"}},
+		{HSTS{}, true, "", 0, []string{"max-age=0"}},
+
+		// never over plaintext
+		{HSTS{MaxAge: time.Hour}, false, "", 0, nil},
+
+		// the response's own header is kept, not duplicated
+		{HSTS{MaxAge: time.Hour}, true, "max-age=60", 0, []string{"max-age=60"}},
+
+		// responses written further up the chain get it too
+		{HSTS{MaxAge: time.Hour}, true, "", http.StatusNotFound, []string{"max-age=3600"}},
+	} {
+		test.hsts.Next = httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+			if test.status != 0 {
+				return test.status, nil
+			}
+			if test.header != "" {
+				w.Header().Set("Strict-Transport-Security", test.header)
+			}
+			w.Write([]byte("body"))
+			return 0, nil
+		})
+		req, err := http.NewRequest("GET", "/", nil)
+		if err != nil {
+			t.Fatalf("Test %d: Could not create HTTP request: %v", i, err)
+		}
+		if test.tls {
+			req.TLS = new(tls.ConnectionState)
+		}
+		rec := httptest.NewRecorder()
+
+		if _, err := test.hsts.ServeHTTP(rec, req); err != nil {
+			t.Errorf("Test %d: Expected no error, got %v", i, err)
+		}
+
+		got := rec.HeaderMap["Strict-Transport-Security"]
+		if len(got) != len(test.expected) {
+			t.Fatalf("Test %d: Expected %q, got %q", i, test.expected, got)
+		}
+		for j := range got {
+			if got[j] != test.expected[j] {
+				t.Errorf("Test %d: Expected %q, got %q", i, test.expected, got)
+			}
+		}
+	}
+}
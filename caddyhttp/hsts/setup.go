@@ -0,0 +1,98 @@
+package hsts
+
+import (
+	"time"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func init() {
+	caddy.RegisterPlugin("hsts", caddy.Plugin{
+		ServerType: "http",
+		Action:     setup,
+	})
+}
+
+// defaultMaxAge is how long clients remember to use HTTPS by default.
+const defaultMaxAge = 365 * 24 * time.Hour
+
+// preloadMinMaxAge is the shortest max-age accepted by the
+// HSTS preload lists of browsers.
+const preloadMinMaxAge = 365 * 24 * time.Hour
+
+// setup configures a new HSTS middleware instance.
+func setup(c *caddy.Controller) error {
+	h, err := hstsParse(c)
+	if err != nil {
+		return err
+	}
+
+	httpserver.GetConfig(c).AddMiddleware(func(next httpserver.Handler) httpserver.Handler {
+		h.Next = next
+		return h
+	})
+
+	return nil
+}
+
+// hstsParse parses the directive:
+//
+//	hsts [max_age] {
+//		max_age <duration|seconds>
+//		include_subdomains
+//		preload
+//	}
+func hstsParse(c *caddy.Controller) (HSTS, error) {
+	h := HSTS{MaxAge: defaultMaxAge}
+	var parsed bool
+
+	for c.Next() {
+		if parsed {
+			return h, c.Err("hsts can only be specified once per site")
+		}
+		parsed = true
+
+		args := c.RemainingArgs()
+		switch len(args) {
+		case 0:
+		case 1:
+			maxAge, err := httpserver.ParseMaxAge(args[0])
+			if err != nil {
+				return h, c.Err(err.Error())
+			}
+			h.MaxAge = maxAge
+		default:
+			return h, c.ArgErr()
+		}
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "max_age":
+				if !c.NextArg() {
+					return h, c.ArgErr()
+				}
+				maxAge, err := httpserver.ParseMaxAge(c.Val())
+				if err != nil {
+					return h, c.Err(err.Error())
+				}
+				h.MaxAge = maxAge
+			case "include_subdomains":
+				h.IncludeSubdomains = true
+			case "preload":
+				h.Preload = true
+			default:
+				return h, c.Errf("unknown subdirective '%s'", c.Val())
+			}
+			if c.NextArg() {
+				return h, c.ArgErr()
+			}
+		}
+	}
+
+	if h.Preload && (!h.IncludeSubdomains || h.MaxAge < preloadMinMaxAge) {
+		return h, c.Errf("hsts preload requires include_subdomains and a max_age of at least %s", preloadMinMaxAge)
+	}
+
+	return h, nil
+}
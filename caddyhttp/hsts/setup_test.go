@@ -0,0 +1,81 @@
+package hsts
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func TestSetup(t *testing.T) {
+	c := caddy.NewTestController("http", `hsts`)
+	err := setup(c)
+	if err != nil {
+		t.Errorf("Expected no errors, got: %v", err)
+	}
+	mids := httpserver.GetConfig(c).Middleware()
+	if len(mids) == 0 {
+		t.Fatal("Expected middleware, got 0 instead")
+	}
+
+	handler := mids[0](httpserver.EmptyNext)
+	myHandler, ok := handler.(HSTS)
+	if !ok {
+		t.Fatalf("Expected handler to be type HSTS, got: %#v", handler)
+	}
+	if !httpserver.SameNext(myHandler.Next, httpserver.EmptyNext) {
+		t.Error("'Next' field of handler was not set properly")
+	}
+}
+
+func TestHSTSParse(t *testing.T) {
+	year := 365 * 24 * time.Hour
+	for i, test := range []struct {
+		input     string
+		shouldErr bool
+		expected  HSTS
+	}{
+		{`hsts`, false, HSTS{MaxAge: year}},
+		{`hsts 600`, false, HSTS{MaxAge: 10 * time.Minute}},
+		{`hsts 0`, false, HSTS{}},
+		{`hsts 1h30.5s`, false, HSTS{MaxAge: time.Hour + 30*time.Second}},
+		{`hsts {
+			max_age 48h
+			include_subdomains
+		}`, false, HSTS{MaxAge: 48 * time.Hour, IncludeSubdomains: true}},
+		{`hsts 63072000 {
+			include_subdomains
+			preload
+		}`, false, HSTS{MaxAge: 2 * year, IncludeSubdomains: true, Preload: true}},
+		{`hsts {
+			preload
+		}`, true, HSTS{}},
+		{`hsts 600 {
+			include_subdomains
+			preload
+		}`, true, HSTS{}},
+		{`hsts -1`, true, HSTS{}},
+		{`hsts 1 2`, true, HSTS{}},
+		{`hsts {
+			max_age
+		}`, true, HSTS{}},
+		{`hsts {
+			include_subdomains yes
+		}`, true, HSTS{}},
+		{`hsts {
+			subdomains
+		}`, true, HSTS{}},
+		{"hsts\nhsts 600", true, HSTS{}},
+	} {
+		actual, err := hstsParse(caddy.NewTestController("http", test.input))
+		if err == nil && test.shouldErr {
+			t.Errorf("Test %d didn't error, but it should have", i)
+		} else if err != nil && !test.shouldErr {
+			t.Errorf("Test %d errored, but it shouldn't have; got '%v'", i, err)
+		}
+		if !test.shouldErr && actual != test.expected {
+			t.Errorf("Test %d: Expected %+v, got %+v", i, test.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package httpserver
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ParseCIDR parses s as an IP range in CIDR notation. A
+// single IP address is accepted too, as a range of one.
+func ParseCIDR(s string) (*net.IPNet, error) {
+	if !strings.Contains(s, "/") {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return nil, &net.ParseError{Type: "IP address", Text: s}
+		}
+		if ip4 := ip.To4(); ip4 != nil {
+			return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}, nil
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+	}
+	_, ipnet, err := net.ParseCIDR(s)
+	return ipnet, err
+}
+
+// trustedProxiesCtxKey is the key of the context value that
+// holds the trusted proxies of the site that serves a request.
+const trustedProxiesCtxKey CtxKey = "trusted_proxies"
+
+// ClientIP returns the IP address of the client that made r.
+// That is the remote address of the connection, unless it is
+// one of the trusted proxies: then the Forwarded chain, or the
+// X-Forwarded-For chain if there is no Forwarded header, is
+// followed from right to left, past the trusted proxies, and
+// the first address that is not trusted is returned. If every
+// hop is trusted, the leftmost one is the client. The result
+// is nil if the remote address is not an IP address.
+func ClientIP(r *http.Request, trusted []*net.IPNet) net.IP {
+	return ResolveClient(r, trusted).IP
+}
+
+// Client describes the client that made a request: its IP
+// address, and the scheme and host of the request it made.
+type Client struct {
+	IP     net.IP
+	Scheme string
+	Host   string
+}
+
+// ResolveClient returns the client that made r, as far as the
+// trusted proxies tell; see ClientIP. If the client is found
+// in a Forwarded header, the proto and host parameters of its
+// element are the scheme and host, if they are present.
+// Without a Forwarded header, the scheme is the last value of
+// the X-Forwarded-Proto header, which the trusted proxy that
+// connected set, if it is http or https. Otherwise, they are
+// those of r.
+func ResolveClient(r *http.Request, trusted []*net.IPNet) Client {
+	client := Client{Scheme: "http", Host: r.Host}
+	if r.TLS != nil {
+		client.Scheme = "https"
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	client.IP = net.ParseIP(host)
+	if client.IP == nil || !ipInNets(client.IP, trusted) {
+		return client
+	}
+
+	if headers := r.Header["Forwarded"]; len(headers) > 0 {
+		elements := ParseForwarded(headers)
+		for i := len(elements) - 1; i >= 0; i-- {
+			hop := parseForwardedFor(elements[i].For)
+			if hop == nil {
+				// can't look past unknown or obfuscated
+				// clients; the last proxy that we trust
+				// is as far as we can go
+				break
+			}
+			client.IP = hop
+			if elements[i].Proto != "" {
+				client.Scheme = strings.ToLower(elements[i].Proto)
+			}
+			if elements[i].Host != "" {
+				client.Host = elements[i].Host
+			}
+			if !ipInNets(hop, trusted) {
+				break
+			}
+		}
+		return client
+	}
+
+	if protos := r.Header["X-Forwarded-Proto"]; len(protos) > 0 {
+		values := strings.Split(protos[len(protos)-1], ",")
+		if proto := strings.ToLower(strings.TrimSpace(values[len(values)-1])); proto == "http" || proto == "https" {
+			client.Scheme = proto
+		}
+	}
+
+	var hops []string
+	for _, header := range r.Header["X-Forwarded-For"] {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(hops[i]))
+		if hop == nil {
+			// can't look past garbage; the last proxy
+			// that we trust is as far as we can go
+			break
+		}
+		client.IP = hop
+		if !ipInNets(hop, trusted) {
+			break
+		}
+	}
+	return client
+}
+
+// ForwardedElement is an element of a Forwarded header (RFC 7239):
+// what one proxy tells about the request it forwarded.
+type ForwardedElement struct {
+	For   string
+	By    string
+	Host  string
+	Proto string
+}
+
+// ParseForwarded parses the elements of the Forwarded headers, in
+// order. Quoted values are unquoted; unknown and malformed parameters
+// are ignored.
+func ParseForwarded(headers []string) []ForwardedElement {
+	var elements []ForwardedElement
+	for _, header := range headers {
+		var element ForwardedElement
+		var nonEmpty bool
+		s := header
+		for {
+			s = strings.TrimLeft(s, " \t")
+			if s != "" && s[0] != ';' && s[0] != ',' {
+				nonEmpty = true
+				i := strings.IndexAny(s, "=;,")
+				if i >= 0 && s[i] == '=' {
+					name := strings.ToLower(strings.TrimSpace(s[:i]))
+					var value string
+					value, s = forwardedValue(strings.TrimLeft(s[i+1:], " \t"))
+					switch name {
+					case "for":
+						element.For = value
+					case "by":
+						element.By = value
+					case "host":
+						element.Host = value
+					case "proto":
+						element.Proto = value
+					}
+				} else if i >= 0 {
+					s = s[i:]
+				} else {
+					s = ""
+				}
+				continue
+			}
+			if s == "" || s[0] == ',' {
+				if nonEmpty {
+					elements = append(elements, element)
+				}
+				element, nonEmpty = ForwardedElement{}, false
+			}
+			if s == "" {
+				break
+			}
+			s = s[1:]
+		}
+	}
+	return elements
+}
+
+// forwardedValue returns the token or quoted string at the
+// start of s, and what follows it.
+func forwardedValue(s string) (value, rest string) {
+	if !strings.HasPrefix(s, `"`) {
+		i := strings.IndexAny(s, ";,")
+		if i < 0 {
+			i = len(s)
+		}
+		return strings.TrimSpace(s[:i]), s[i:]
+	}
+	var b []byte
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) {
+				i++
+			}
+		case '"':
+			return string(b), s[i+1:]
+		}
+		b = append(b, s[i])
+	}
+	return string(b), ""
+}
+
+// parseForwardedFor returns the IP address of the node in the
+// for parameter v, which may have a port and brackets around
+// an IPv6 address. It returns nil for unknown and obfuscated
+// nodes.
+func parseForwardedFor(v string) net.IP {
+	if strings.HasPrefix(v, "[") {
+		end := strings.IndexByte(v, ']')
+		if end < 0 {
+			return nil
+		}
+		return net.ParseIP(v[1:end])
+	}
+	if host, _, err := net.SplitHostPort(v); err == nil {
+		v = host
+	}
+	return net.ParseIP(v)
+}
+
+// ipInNets reports whether ip is in any of nets.
+func ipInNets(ip net.IP, nets []*net.IPNet) bool {
+	for _, ipnet := range nets {
+		if ipnet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
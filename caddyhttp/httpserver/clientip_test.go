@@ -0,0 +1,229 @@
+package httpserver
+
+import (
+	"net"
+	"net/http"
+	"testing"
+)
+
+func TestParseCIDR(t *testing.T) {
+	for i, test := range []struct {
+		input     string
+		expected  string
+		shouldErr bool
+	}{
+		{"192.168.0.1", "192.168.0.1/32", false},
+		{"192.168.0.0/16", "192.168.0.0/16", false},
+		{"192.168.3.4/16", "192.168.0.0/16", false},
+		{"::1", "::1/128", false},
+		{"2001:db8::/32", "2001:db8::/32", false},
+		{"::ffff:10.0.0.1", "10.0.0.1/32", false},
+		{"10.0.0.0/40", "", true},
+		{"example.com", "", true},
+		{"", "", true},
+	} {
+		ipnet, err := ParseCIDR(test.input)
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: Expected error for %q, got %v", i, test.input, ipnet)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: Expected no error for %q, got %v", i, test.input, err)
+			continue
+		}
+		if ipnet.String() != test.expected {
+			t.Errorf("Test %d: Expected %s, got %s", i, test.expected, ipnet)
+		}
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	var trusted []*net.IPNet
+	for _, s := range []string{"10.0.0.0/8", "fd00::/8"} {
+		ipnet, err := ParseCIDR(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		trusted = append(trusted, ipnet)
+	}
+
+	for i, test := range []struct {
+		remoteAddr string
+		xff        []string
+		trusted    []*net.IPNet
+		expected   string
+	}{
+		// untrusted peers can't forge the header
+		{"1.2.3.4:1234", nil, trusted, "1.2.3.4"},
+		{"1.2.3.4:1234", []string{"5.6.7.8"}, trusted, "1.2.3.4"},
+		{"10.0.0.1:1234", []string{"5.6.7.8"}, nil, "10.0.0.1"},
+
+		// trusted peers
+		{"10.0.0.1:1234", nil, trusted, "10.0.0.1"},
+		{"10.0.0.1:1234", []string{"5.6.7.8"}, trusted, "5.6.7.8"},
+		{"10.0.0.1:1234", []string{"9.9.9.9, 5.6.7.8, 10.1.1.1"}, trusted, "5.6.7.8"},
+		{"10.0.0.1:1234", []string{"9.9.9.9", "5.6.7.8, 10.1.1.1"}, trusted, "5.6.7.8"},
+		{"10.0.0.1:1234", []string{"10.2.2.2, 10.1.1.1"}, trusted, "10.2.2.2"},
+		{"10.0.0.1:1234", []string{"5.6.7.8, garbage"}, trusted, "10.0.0.1"},
+		{"10.0.0.1:1234", []string{"garbage, 5.6.7.8"}, trusted, "5.6.7.8"},
+		{"[fd00::1]:443", []string{"2001:db8::1"}, trusted, "2001:db8::1"},
+		{"[2001:db8::2]:443", []string{"2001:db8::1"}, trusted, "2001:db8::2"},
+
+		// oddities
+		{"1.2.3.4", nil, trusted, "1.2.3.4"},
+		{"@", nil, trusted, "<nil>"},
+	} {
+		r, err := http.NewRequest("GET", "/", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.RemoteAddr = test.remoteAddr
+		for _, v := range test.xff {
+			r.Header.Add("X-Forwarded-For", v)
+		}
+		if got := ClientIP(r, test.trusted).String(); got != test.expected {
+			t.Errorf("Test %d: Expected client IP %s, got %s", i, test.expected, got)
+		}
+	}
+}
+
+func TestParseForwarded(t *testing.T) {
+	for i, test := range []struct {
+		headers  []string
+		expected []ForwardedElement
+	}{
+		{[]string{`for=192.0.2.60;proto=http;by=203.0.113.43`}, []ForwardedElement{
+			{For: "192.0.2.60", Proto: "http", By: "203.0.113.43"},
+		}},
+		{[]string{`for=192.0.2.43, for=198.51.100.17`}, []ForwardedElement{
+			{For: "192.0.2.43"}, {For: "198.51.100.17"},
+		}},
+		{[]string{`For="[2001:db8:cafe::17]:4711"`, `for=unknown;HOST=example.com`}, []ForwardedElement{
+			{For: "[2001:db8:cafe::17]:4711"}, {For: "unknown", Host: "example.com"},
+		}},
+		{[]string{` for = "quoted, with \"comma\"" ; proto=https ,`}, []ForwardedElement{
+			{For: `quoted, with "comma"`, Proto: "https"},
+		}},
+		{[]string{`garbage;for=1.2.3.4,,for=5.6.7.8;ext=x`}, []ForwardedElement{
+			{For: "1.2.3.4"}, {For: "5.6.7.8"},
+		}},
+		{[]string{`for="unterminated`}, []ForwardedElement{
+			{For: "unterminated"},
+		}},
+		{[]string{``, ` , `}, nil},
+	} {
+		got := ParseForwarded(test.headers)
+		if len(got) != len(test.expected) {
+			t.Errorf("Test %d: Expected %d elements %+v, got %+v", i, len(test.expected), test.expected, got)
+			continue
+		}
+		for j := range got {
+			if got[j] != test.expected[j] {
+				t.Errorf("Test %d: Expected element %d to be %+v, got %+v", i, j, test.expected[j], got[j])
+			}
+		}
+	}
+}
+
+func TestResolveClientForwarded(t *testing.T) {
+	var trusted []*net.IPNet
+	for _, s := range []string{"10.0.0.0/8", "fd00::/8"} {
+		ipnet, err := ParseCIDR(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		trusted = append(trusted, ipnet)
+	}
+
+	for i, test := range []struct {
+		remoteAddr string
+		forwarded  []string
+		xff        string
+		expected   Client
+	}{
+		// untrusted peers can't forge the header
+		{"1.2.3.4:1234", []string{`for=5.6.7.8;proto=https;host=evil.com`}, "",
+			Client{IP: net.ParseIP("1.2.3.4"), Scheme: "http", Host: "example.com"}},
+
+		// multiple elements, walked back to the first untrusted hop
+		{"10.0.0.1:1234", []string{`for=9.9.9.9;proto=http, for=5.6.7.8;proto=https;host=www.example.com, for=10.1.1.1;proto=http;host=internal`}, "",
+			Client{IP: net.ParseIP("5.6.7.8"), Scheme: "https", Host: "www.example.com"}},
+		{"10.0.0.1:1234", []string{`for=5.6.7.8;proto=HTTPS`, `for="10.1.1.1:8080"`}, "",
+			Client{IP: net.ParseIP("5.6.7.8"), Scheme: "https", Host: "example.com"}},
+
+		// IPv6 in brackets, with and without port
+		{"[fd00::1]:443", []string{`for="[2001:db8:cafe::17]:4711";proto=https`}, "",
+			Client{IP: net.ParseIP("2001:db8:cafe::17"), Scheme: "https", Host: "example.com"}},
+		{"10.0.0.1:1234", []string{`for="[2001:db8::1]"`}, "",
+			Client{IP: net.ParseIP("2001:db8::1"), Scheme: "http", Host: "example.com"}},
+
+		// unknown clients stop the walk
+		{"10.0.0.1:1234", []string{`for=5.6.7.8, for=unknown;proto=https`}, "",
+			Client{IP: net.ParseIP("10.0.0.1"), Scheme: "http", Host: "example.com"}},
+
+		// Forwarded takes precedence over X-Forwarded-For
+		{"10.0.0.1:1234", []string{`for=5.6.7.8`}, "9.9.9.9",
+			Client{IP: net.ParseIP("5.6.7.8"), Scheme: "http", Host: "example.com"}},
+		{"10.0.0.1:1234", nil, "9.9.9.9",
+			Client{IP: net.ParseIP("9.9.9.9"), Scheme: "http", Host: "example.com"}},
+	} {
+		r, err := http.NewRequest("GET", "http://example.com/", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.RemoteAddr = test.remoteAddr
+		for _, v := range test.forwarded {
+			r.Header.Add("Forwarded", v)
+		}
+		if test.xff != "" {
+			r.Header.Set("X-Forwarded-For", test.xff)
+		}
+		got := ResolveClient(r, trusted)
+		if !got.IP.Equal(test.expected.IP) || got.Scheme != test.expected.Scheme || got.Host != test.expected.Host {
+			t.Errorf("Test %d: Expected client %+v, got %+v", i, test.expected, got)
+		}
+	}
+}
+
+func TestResolveClientForwardedProto(t *testing.T) {
+	trusted, err := ParseCIDR("10.0.0.0/8")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, test := range []struct {
+		remoteAddr     string
+		forwarded      string
+		xfp            []string
+		expectedScheme string
+	}{
+		{"10.0.0.1:1234", "", []string{"https"}, "https"},
+		{"10.0.0.1:1234", "", []string{"HTTPS"}, "https"},
+		{"10.0.0.1:1234", "", nil, "http"},
+		// the value of the proxy that connected counts
+		{"10.0.0.1:1234", "", []string{"https, http"}, "http"},
+		{"10.0.0.1:1234", "", []string{"http", "https"}, "https"},
+		{"10.0.0.1:1234", "", []string{"wss"}, "http"},
+		// untrusted peers can't forge it
+		{"1.2.3.4:1234", "", []string{"https"}, "http"},
+		// and Forwarded takes precedence
+		{"10.0.0.1:1234", "for=5.6.7.8;proto=http", []string{"https"}, "http"},
+	} {
+		r, err := http.NewRequest("GET", "http://example.com/", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.RemoteAddr = test.remoteAddr
+		if test.forwarded != "" {
+			r.Header.Set("Forwarded", test.forwarded)
+		}
+		for _, v := range test.xfp {
+			r.Header.Add("X-Forwarded-Proto", v)
+		}
+		if got := ResolveClient(r, []*net.IPNet{trusted}).Scheme; got != test.expectedScheme {
+			t.Errorf("Test %d: Expected scheme %s, got %s", i, test.expectedScheme, got)
+		}
+	}
+}
@@ -9,13 +9,28 @@ import (
 	"github.com/mholt/caddy"
 )
 
-// SetupIfMatcher parses `if` or `if_op` in the current dispenser block.
-// It returns a RequestMatcher and an error if any.
+// SetupIfMatcher parses `if`, `if_op` or `match` in the current dispenser block.
+// It returns a RequestMatcher and an error if any. The named matchers that
+// `match` refers to must all match, whatever `if_op` is, and referring to
+// one that was not defined with the matcher directive is an error.
 func SetupIfMatcher(controller *caddy.Controller) (RequestMatcher, error) {
 	var c = controller.Dispenser // copy the dispenser
 	var matcher IfMatcher
+	var named []RequestMatcher
 	for c.NextBlock() {
 		switch c.Val() {
+		case "match":
+			if !c.NextArg() {
+				return matcher, c.ArgErr()
+			}
+			m, ok := GetConfig(controller).Matchers[c.Val()]
+			if !ok {
+				return matcher, c.Errf("undefined matcher '%s'", c.Val())
+			}
+			named = append(named, m)
+			if c.NextArg() {
+				return matcher, c.ArgErr()
+			}
 		case "if":
 			args1 := c.RemainingArgs()
 			if len(args1) != 3 {
@@ -40,6 +55,9 @@ func SetupIfMatcher(controller *caddy.Controller) (RequestMatcher, error) {
 			}
 		}
 	}
+	if len(named) > 0 {
+		return MergeRequestMatchers(append(named, matcher)...), nil
+	}
 	return matcher, nil
 }
 
@@ -166,7 +184,10 @@ type IfMatcher struct {
 }
 
 // Match satisfies RequestMatcher interface.
-// It returns true if the conditions in m are true.
+// It returns true if the conditions in m are true: all of them, or
+// any of them if if_op is or. Conditions are evaluated in order, only
+// until the result is known, so without conditions, and matches every
+// request while or matches none.
 func (m IfMatcher) Match(r *http.Request) bool {
 	if m.isOr {
 		return m.Or(r)
@@ -194,10 +215,24 @@ func (m IfMatcher) Or(r *http.Request) bool {
 	return false
 }
 
+// HasIfMatcher reports whether the block that follows the
+// current token has any if, if_op or match lines. The
+// dispenser is not advanced.
+func HasIfMatcher(controller *caddy.Controller) bool {
+	c := controller.Dispenser // copy the dispenser
+	for c.NextBlock() {
+		if c.Val() == "if" || c.Val() == "if_op" || c.Val() == "match" {
+			return true
+		}
+		c.RemainingArgs()
+	}
+	return false
+}
+
 // IfMatcherKeyword checks if the next value in the dispenser is a keyword for 'if' config block.
 // If true, remaining arguments in the dispinser are cleard to keep the dispenser valid for use.
 func IfMatcherKeyword(c *caddy.Controller) bool {
-	if c.Val() == "if" || c.Val() == "if_op" {
+	if c.Val() == "if" || c.Val() == "if_op" || c.Val() == "match" {
 		// clear remaining args
 		c.RemainingArgs()
 		return true
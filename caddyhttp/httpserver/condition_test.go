@@ -179,6 +179,61 @@ func TestIfMatcher(t *testing.T) {
 	}
 }
 
+func TestIfMatcherOperators(t *testing.T) {
+	conds := map[bool]ifCond{
+		true:  {a: "a", op: isOp, b: "a"},
+		false: {a: "a", op: isOp, b: "b"},
+	}
+
+	for i, test := range []struct {
+		a, b  bool
+		isOr  bool
+		match bool
+	}{
+		{true, true, false, true},
+		{true, false, false, false},
+		{false, true, false, false},
+		{false, false, false, false},
+		{true, true, true, true},
+		{true, false, true, true},
+		{false, true, true, true},
+		{false, false, true, false},
+	} {
+		matcher := IfMatcher{ifs: []ifCond{conds[test.a], conds[test.b]}, isOr: test.isOr}
+		if got := matcher.Match(nil); got != test.match {
+			t.Errorf("Test %d: Expected %v for %v, %v with isOr %v, got %v", i, test.match, test.a, test.b, test.isOr, got)
+		}
+	}
+}
+
+func TestIfMatcherShortCircuit(t *testing.T) {
+	var evaluated []string
+	ifConditions["test_is"] = func(a, b string) bool {
+		evaluated = append(evaluated, a)
+		return a == b
+	}
+	defer delete(ifConditions, "test_is")
+
+	for i, test := range []struct {
+		isOr     bool
+		ifs      []ifCond
+		expected []string
+	}{
+		// and stops at the first false condition...
+		{false, []ifCond{{"a", "test_is", "b"}, {"b", "test_is", "b"}}, []string{"a"}},
+		{false, []ifCond{{"a", "test_is", "a"}, {"b", "test_is", "b"}}, []string{"a", "b"}},
+		// ...and or at the first true one
+		{true, []ifCond{{"a", "test_is", "a"}, {"b", "test_is", "b"}}, []string{"a"}},
+		{true, []ifCond{{"a", "test_is", "b"}, {"b", "test_is", "b"}}, []string{"a", "b"}},
+	} {
+		evaluated = nil
+		IfMatcher{ifs: test.ifs, isOr: test.isOr}.Match(nil)
+		if fmt.Sprint(evaluated) != fmt.Sprint(test.expected) {
+			t.Errorf("Test %d: Expected conditions %v to be evaluated, got %v", i, test.expected, evaluated)
+		}
+	}
+}
+
 func TestSetupIfMatcher(t *testing.T) {
 	tests := []struct {
 		input     string
@@ -264,6 +319,71 @@ func TestSetupIfMatcher(t *testing.T) {
 	}
 }
 
+func TestSetupIfMatcherNamed(t *testing.T) {
+	api := Matcher{Paths: []string{"/api"}}
+
+	for i, test := range []struct {
+		input     string
+		shouldErr bool
+		url       string
+		method    string
+		expected  bool
+	}{
+		{`test {
+			match api
+		 }`, false, "http://localhost/api/users", "GET", true},
+		{`test {
+			match api
+		 }`, false, "http://localhost/users", "GET", false},
+		// if conditions must be true as well
+		{`test {
+			match api
+			if {method} is POST
+		 }`, false, "http://localhost/api/users", "GET", false},
+		{`test {
+			match api
+			if {method} is POST
+		 }`, false, "http://localhost/api/users", "POST", true},
+		// even if any of them is enough
+		{`test {
+			match api
+			if {method} is POST
+			if {method} is PUT
+			if_op or
+		 }`, false, "http://localhost/users", "PUT", false},
+		{`test {
+			match web
+		 }`, true, "", "", false},
+		{`test {
+			match
+		 }`, true, "", "", false},
+		{`test {
+			match api web
+		 }`, true, "", "", false},
+	} {
+		c := caddy.NewTestController("http", test.input)
+		GetConfig(c).Matchers = map[string]RequestMatcher{"api": api}
+		c.Next()
+		matcher, err := SetupIfMatcher(c)
+		if err == nil && test.shouldErr {
+			t.Errorf("Test %d didn't error, but it should have", i)
+		} else if err != nil && !test.shouldErr {
+			t.Errorf("Test %d errored, but it shouldn't have; got '%v'", i, err)
+		}
+		if err != nil {
+			continue
+		}
+
+		r, err := http.NewRequest(test.method, test.url, nil)
+		if err != nil {
+			t.Fatalf("Test %d: Could not create HTTP request: %v", i, err)
+		}
+		if got := matcher.Match(r); got != test.expected {
+			t.Errorf("Test %d: Expected match %v, got %v", i, test.expected, got)
+		}
+	}
+}
+
 func TestIfMatcherKeyword(t *testing.T) {
 	tests := []struct {
 		keyword  string
@@ -276,6 +396,7 @@ func TestIfMatcherKeyword(t *testing.T) {
 		{"if_op", true},
 		{"if_type", false},
 		{"if_cond", false},
+		{"match", true},
 	}
 
 	for i, test := range tests {
@@ -287,3 +408,39 @@ func TestIfMatcherKeyword(t *testing.T) {
 		}
 	}
 }
+
+func TestHasIfMatcher(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{`test`, false},
+		{`test {
+			a b
+		}`, false},
+		{`test {
+			a b
+			if {path} is /
+		}`, true},
+		{`test {
+			if_op or
+		}`, true},
+		{`test {
+			a if
+		}`, false},
+		{`test {
+			match api
+		}`, true},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("http", test.input)
+		c.Next()
+		if got := HasIfMatcher(c); got != test.expected {
+			t.Errorf("Test %d: expected %v found %v", i, test.expected, got)
+		}
+		if c.Val() != "test" {
+			t.Errorf("Test %d: dispenser was advanced to %q", i, c.Val())
+		}
+	}
+}
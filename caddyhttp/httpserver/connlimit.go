@@ -0,0 +1,210 @@
+package httpserver
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// ConnLimit limits the rate at which each client IP address
+// may open connections to a listener. Connections over the
+// limit are closed as soon as they are accepted, before any
+// TLS handshake or HTTP request is read from them. Connections
+// from trusted proxies are limited by the client in their PROXY
+// protocol header, once it is read.
+type ConnLimit struct {
+	// Rate is the number of connections allowed per second,
+	// on average; Burst is how many can be opened at once.
+	Rate  float64
+	Burst int
+
+	// Except are the source addresses that are not limited.
+	Except []*net.IPNet
+
+	// MaxSources bounds the number of addresses that are
+	// tracked; 0 means defaultMaxConnSources.
+	MaxSources int
+}
+
+// connLimitListener is a net.Listener that enforces a ConnLimit.
+// It keeps a token bucket for each source address; a bucket that
+// has refilled completely is idle and may be dropped, which is
+// done when the number of buckets reaches the maximum.
+type connLimitListener struct {
+	net.Listener
+	limit ConnLimit
+
+	mu      sync.Mutex
+	buckets map[string]*connBucket
+	now     func() time.Time
+}
+
+type connBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newConnLimitListener(ln net.Listener, limit ConnLimit) *connLimitListener {
+	if limit.MaxSources == 0 {
+		limit.MaxSources = defaultMaxConnSources
+	}
+	return &connLimitListener{
+		Listener: ln,
+		limit:    limit,
+		buckets:  make(map[string]*connBucket),
+		now:      time.Now,
+	}
+}
+
+// Accept accepts the next connection whose source is within
+// the limit, closing the ones that are not. The source of a
+// connection with a PROXY protocol header is the client in the
+// header; since the header is read in the goroutine that serves
+// the connection, the connection is limited there.
+func (ln *connLimitListener) Accept() (net.Conn, error) {
+	for {
+		c, err := ln.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		if pc, ok := c.(*proxyProtocolConn); ok {
+			pc.allow = ln.allow
+			return c, nil
+		}
+		if ln.allow(c.RemoteAddr()) {
+			return c, nil
+		}
+		c.Close()
+	}
+}
+
+// allow takes a token from the bucket of the IP address of addr
+// and reports whether there was one. It is safe for concurrent use.
+func (ln *connLimitListener) allow(addr net.Addr) bool {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return true
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return true
+	}
+	for _, ipnet := range ln.limit.Except {
+		if ipnet.Contains(ip) {
+			return true
+		}
+	}
+	key := ip.String()
+
+	ln.mu.Lock()
+	defer ln.mu.Unlock()
+
+	now := ln.now()
+	b, ok := ln.buckets[key]
+	if ok {
+		ln.refill(b, now)
+	} else {
+		if len(ln.buckets) >= ln.limit.MaxSources && !ln.evictIdle(now) {
+			// every source we know of is still limited; rather
+			// than turn away new ones, let them in untracked
+			return true
+		}
+		b = &connBucket{tokens: float64(ln.limit.Burst), last: now}
+		ln.buckets[key] = b
+	}
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// refill adds the tokens earned since b was last used.
+func (ln *connLimitListener) refill(b *connBucket, now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens += elapsed.Seconds() * ln.limit.Rate
+		if burst := float64(ln.limit.Burst); b.tokens > burst {
+			b.tokens = burst
+		}
+	}
+	b.last = now
+}
+
+// evictIdle drops the buckets that have refilled completely,
+// since starting them over makes no difference. It reports
+// whether any bucket was dropped.
+func (ln *connLimitListener) evictIdle(now time.Time) bool {
+	var evicted bool
+	for key, b := range ln.buckets {
+		ln.refill(b, now)
+		if b.tokens >= float64(ln.limit.Burst) {
+			delete(ln.buckets, key)
+			evicted = true
+		}
+	}
+	return evicted
+}
+
+// defaultMaxConnSources is the number of source addresses
+// tracked by a connection limit by default.
+const defaultMaxConnSources = 10000
+
+// maxConnsListener is a net.Listener that has at most a number
+// of the connections it accepted open at once. When there are
+// that many, Accept waits for one of them to be closed; kept-alive
+// connections count for as long as they are open.
+type maxConnsListener struct {
+	net.Listener
+	sem       chan struct{} // holds a token for each open connection
+	done      chan struct{} // closed when the listener is
+	closeOnce sync.Once
+}
+
+func newMaxConnsListener(ln net.Listener, n int) *maxConnsListener {
+	return &maxConnsListener{
+		Listener: ln,
+		sem:      make(chan struct{}, n),
+		done:     make(chan struct{}),
+	}
+}
+
+// Accept waits until fewer than the maximum number of
+// connections are open, then accepts the next one.
+func (ln *maxConnsListener) Accept() (net.Conn, error) {
+	select {
+	case ln.sem <- struct{}{}:
+	case <-ln.done:
+		return nil, errListenerClosed
+	}
+	c, err := ln.Listener.Accept()
+	if err != nil {
+		<-ln.sem
+		return nil, err
+	}
+	return &maxConnsConn{Conn: c, release: func() { <-ln.sem }}, nil
+}
+
+// Close closes the listener, and stops Accept from waiting.
+func (ln *maxConnsListener) Close() error {
+	ln.closeOnce.Do(func() { close(ln.done) })
+	return ln.Listener.Close()
+}
+
+// maxConnsConn is a connection accepted by a maxConnsListener,
+// which makes room for another one once it is closed.
+type maxConnsConn struct {
+	net.Conn
+	releaseOnce sync.Once
+	release     func()
+}
+
+// Close closes c and releases its token.
+func (c *maxConnsConn) Close() error {
+	err := c.Conn.Close()
+	c.releaseOnce.Do(c.release)
+	return err
+}
+
+var errListenerClosed = errors.New("listener closed")
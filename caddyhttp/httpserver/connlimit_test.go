@@ -0,0 +1,335 @@
+package httpserver
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mholt/caddy/caddytls"
+)
+
+// fakeListener accepts the connections sent on conns until
+// the channel is closed.
+type fakeListener struct {
+	net.Listener
+	conns chan net.Conn
+}
+
+func (l fakeListener) Accept() (net.Conn, error) {
+	c, ok := <-l.conns
+	if !ok {
+		return nil, errors.New("listener closed")
+	}
+	return c, nil
+}
+
+// fakeConn is a connection from remote that only notes
+// whether it was closed.
+type fakeConn struct {
+	net.Conn
+	remote net.Addr
+	closed bool
+}
+
+func (c *fakeConn) RemoteAddr() net.Addr { return c.remote }
+func (c *fakeConn) Close() error         { c.closed = true; return nil }
+
+// acceptFrom makes connections from the given addresses to
+// ln in order and returns the number that were accepted.
+func acceptFrom(t *testing.T, ln *connLimitListener, addrs ...string) int {
+	conns := make(chan net.Conn, len(addrs))
+	var made []*fakeConn
+	for _, addr := range addrs {
+		tcpAddr, err := net.ResolveTCPAddr("tcp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := &fakeConn{remote: tcpAddr}
+		made = append(made, c)
+		conns <- c
+	}
+	close(conns)
+	ln.Listener = fakeListener{conns: conns}
+
+	var accepted int
+	for {
+		c, err := ln.Accept()
+		if err != nil {
+			break
+		}
+		if c.(*fakeConn).closed {
+			t.Errorf("Accepted connection from %s was closed", c.RemoteAddr())
+		}
+		accepted++
+	}
+	var closed int
+	for _, c := range made {
+		if c.closed {
+			closed++
+		}
+	}
+	if accepted+closed != len(addrs) {
+		t.Errorf("Expected every connection to be accepted or closed, got %d accepted and %d closed of %d",
+			accepted, closed, len(addrs))
+	}
+	return accepted
+}
+
+func repeat(addr string, n int) []string {
+	addrs := make([]string, n)
+	for i := range addrs {
+		addrs[i] = addr
+	}
+	return addrs
+}
+
+func TestConnLimitListener(t *testing.T) {
+	_, except, err := net.ParseCIDR("10.0.0.0/8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1500000000, 0)
+	ln := newConnLimitListener(nil, ConnLimit{Rate: 2, Burst: 5, Except: []*net.IPNet{except}})
+	ln.now = func() time.Time { return now }
+
+	// a burst is allowed, the rest is closed
+	if got := acceptFrom(t, ln, repeat("1.2.3.4:1234", 20)...); got != 5 {
+		t.Errorf("Expected 5 connections to be accepted, got %d", got)
+	}
+	// other sources have their own allowance
+	if got := acceptFrom(t, ln, repeat("[2001:db8::1]:1234", 6)...); got != 5 {
+		t.Errorf("Expected 5 connections from another source to be accepted, got %d", got)
+	}
+	// tokens are earned back at the rate
+	now = now.Add(time.Second)
+	if got := acceptFrom(t, ln, repeat("1.2.3.4:4321", 5)...); got != 2 {
+		t.Errorf("Expected 2 connections to be accepted after a second, got %d", got)
+	}
+	// excepted sources are not limited
+	if got := acceptFrom(t, ln, repeat("10.1.2.3:1234", 50)...); got != 50 {
+		t.Errorf("Expected all connections from an excepted source to be accepted, got %d", got)
+	}
+}
+
+func TestConnLimitListenerEviction(t *testing.T) {
+	now := time.Unix(1500000000, 0)
+	ln := newConnLimitListener(nil, ConnLimit{Rate: 1, Burst: 2, MaxSources: 2})
+	ln.now = func() time.Time { return now }
+
+	acceptFrom(t, ln, "1.1.1.1:1", "1.1.1.1:2", "2.2.2.2:1")
+	if len(ln.buckets) != 2 {
+		t.Fatalf("Expected 2 sources to be tracked, got %d", len(ln.buckets))
+	}
+
+	// while all tracked sources are limited, new ones are let in untracked
+	if got := acceptFrom(t, ln, repeat("3.3.3.3:1", 3)...); got != 3 {
+		t.Errorf("Expected untracked source to be let in, got %d accepted", got)
+	}
+	if len(ln.buckets) != 2 {
+		t.Errorf("Expected no more than 2 sources to be tracked, got %d", len(ln.buckets))
+	}
+
+	// once a source is idle, it makes room for a new one
+	now = now.Add(2 * time.Second)
+	if got := acceptFrom(t, ln, repeat("3.3.3.3:1", 3)...); got != 2 {
+		t.Errorf("Expected new source to be limited, got %d accepted", got)
+	}
+	if _, ok := ln.buckets["3.3.3.3"]; !ok || len(ln.buckets) > 2 {
+		t.Errorf("Expected the new source to replace an idle one, got %d sources", len(ln.buckets))
+	}
+}
+
+func TestNewServerConnLimit(t *testing.T) {
+	limit := func() *ConnLimit { return &ConnLimit{Rate: 10, Burst: 10} }
+	site := func(host string, limit *ConnLimit) *SiteConfig {
+		return &SiteConfig{
+			Addr:      Address{Original: host, Host: host},
+			TLS:       new(caddytls.Config),
+			ConnLimit: limit,
+		}
+	}
+
+	s, err := NewServer("127.0.0.1:0", []*SiteConfig{site("a.com", limit()), site("b.com", nil), site("c.com", limit())})
+	if err != nil {
+		t.Fatalf("Expected no error for sites that agree on the connection limit, got: %v", err)
+	}
+	if s.connLimit == nil || s.connLimit.Rate != 10 {
+		t.Errorf("Expected the connection limit of the sites, got %+v", s.connLimit)
+	}
+
+	_, err = NewServer("127.0.0.1:0", []*SiteConfig{site("a.com", limit()), site("b.com", &ConnLimit{Rate: 1, Burst: 1})})
+	if err == nil {
+		t.Error("Expected an error for sites with different connection limits")
+	}
+
+	// the lowest maximum number of connections applies
+	sites := []*SiteConfig{site("a.com", nil), site("b.com", nil), site("c.com", nil)}
+	sites[0].MaxConns, sites[2].MaxConns = 100, 50
+	s, err = NewServer("127.0.0.1:0", sites)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if s.maxConns != 50 {
+		t.Errorf("Expected a maximum of 50 connections, got %d", s.maxConns)
+	}
+}
+
+func TestMaxConnsListener(t *testing.T) {
+	tcpLn, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const max, dialers = 3, 20
+	ln := newMaxConnsListener(tcpLn, max)
+	defer ln.Close()
+
+	var open, most int32
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			n := atomic.AddInt32(&open, 1)
+			for {
+				m := atomic.LoadInt32(&most)
+				if n <= m || atomic.CompareAndSwapInt32(&most, m, n) {
+					break
+				}
+			}
+			go func() {
+				// hold the connection like a kept-alive one
+				buf := make([]byte, 1)
+				c.Read(buf)
+				atomic.AddInt32(&open, -1)
+				c.Close()
+			}()
+		}
+	}()
+
+	var wg sync.WaitGroup
+	var served int32
+	for i := 0; i < dialers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c, err := net.Dial("tcp", tcpLn.Addr().String())
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer c.Close()
+			time.Sleep(10 * time.Millisecond)
+			c.Write([]byte("x"))
+			c.SetReadDeadline(time.Now().Add(5 * time.Second))
+			// the server closes the connection once it read from it
+			if _, err := c.Read(make([]byte, 1)); err == io.EOF {
+				atomic.AddInt32(&served, 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if most := atomic.LoadInt32(&most); most > max {
+		t.Errorf("Expected at most %d connections open at once, got %d", max, most)
+	}
+	if served := atomic.LoadInt32(&served); served != dialers {
+		t.Errorf("Expected all %d connections to be served eventually, got %d", dialers, served)
+	}
+}
+
+func TestMaxConnsListenerClose(t *testing.T) {
+	tcpLn, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln := newMaxConnsListener(tcpLn, 1)
+
+	c, err := net.Dial("tcp", tcpLn.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	accepted, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// at the maximum, Accept waits until the listener is closed
+	errc := make(chan error)
+	go func() {
+		_, err := ln.Accept()
+		errc <- err
+	}()
+	select {
+	case err := <-errc:
+		t.Fatalf("Expected Accept to wait at the maximum, got: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	ln.Close()
+	select {
+	case err := <-errc:
+		if err == nil {
+			t.Error("Expected an error from Accept on a closed listener")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected Accept to return once the listener is closed")
+	}
+
+	// closing a connection twice releases its token once
+	accepted.Close()
+	accepted.Close()
+	if len(ln.sem) != 0 {
+		t.Errorf("Expected no tokens to be held, got %d", len(ln.sem))
+	}
+}
+
+func TestConnLimitListenerProxyProtocol(t *testing.T) {
+	tcpLn, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tcpLn.Close()
+	ln := newConnLimitListener(proxyProtocolListener{
+		Listener: tcpLn,
+		trusted:  []*net.IPNet{{IP: net.IP{127, 0, 0, 0}, Mask: net.CIDRMask(8, 32)}},
+	}, ConnLimit{Rate: 0.001, Burst: 1})
+
+	// all from the same proxy, but the limit is per client
+	for i, test := range []struct {
+		client  string
+		allowed bool
+	}{
+		{"192.0.2.1", true},
+		{"192.0.2.1", false},
+		{"192.0.2.2", true},
+	} {
+		client, err := net.Dial("tcp", tcpLn.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		header := "PROXY TCP4 " + test.client + " 198.51.100.1 56324 443\r\n"
+		if _, err := client.Write([]byte(header + "GET / HTTP/1.1\r\n")); err != nil {
+			t.Fatal(err)
+		}
+		client.Close()
+
+		conn, err := ln.Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+		rest, err := ioutil.ReadAll(conn)
+		conn.Close()
+		if test.allowed && (err != nil || string(rest) != "GET / HTTP/1.1\r\n") {
+			t.Errorf("Test %d: Expected the connection of %s to be allowed, got %q, %v", i, test.client, rest, err)
+		}
+		if !test.allowed && err == nil {
+			t.Errorf("Test %d: Expected the connection of %s to be limited, got %q", i, test.client, rest)
+		}
+	}
+}
@@ -75,7 +75,14 @@ func (c Context) Env() map[string]string {
 }
 
 // IP gets the (remote) IP address of the client making the request.
+// If the site trusts the proxy that made the request, it is the
+// client that the proxies report; see ResolveClient.
 func (c Context) IP() string {
+	if trusted, ok := GetContextValue(c.Req, trustedProxiesCtxKey).([]*net.IPNet); ok {
+		if ip := ResolveClient(c.Req, trusted).IP; ip != nil {
+			return ip.String()
+		}
+	}
 	ip, _, err := net.SplitHostPort(c.Req.RemoteAddr)
 	if err != nil {
 		return c.Req.RemoteAddr
@@ -196,6 +203,12 @@ func (c Context) StripExt(path string) string {
 	return path
 }
 
+// Placeholder returns the value of the placeholder {name} for the
+// request, such as one set by a handler with SetPlaceholder.
+func (c Context) Placeholder(name string) string {
+	return NewReplacer(c.Req, nil, "").Replace("{" + name + "}")
+}
+
 // Replace replaces instances of find in input with replacement.
 func (c Context) Replace(input, find, replacement string) string {
 	return strings.Replace(input, find, replacement, -1)
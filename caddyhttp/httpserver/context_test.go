@@ -2,8 +2,10 @@ package httpserver
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
@@ -302,6 +304,25 @@ func TestIP(t *testing.T) {
 	}
 }
 
+func TestIPResolvedClient(t *testing.T) {
+	c := getContextOrFail(t)
+	trusted, err := ParseCIDR("10.0.0.0/8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Req.RemoteAddr = "10.0.0.1:1234"
+	c.Req.Header.Set("X-Forwarded-For", "5.6.7.8")
+	// a placeholder named remote, like a rewrite capture, is not the client
+	ctx := contextWithPlaceholders(c.Req, map[string]string{"remote": "6.6.6.6"})
+	ctx = context.WithValue(ctx, ValuesCtxKey, &contextValues{values: make(map[interface{}]interface{})})
+	c.Req = c.Req.WithContext(ctx)
+	SetContextValue(c.Req, trustedProxiesCtxKey, []*net.IPNet{trusted})
+
+	if got, want := c.IP(), "5.6.7.8"; got != want {
+		t.Errorf("Expected IP of the resolved client %s, found %s", want, got)
+	}
+}
+
 func TestURL(t *testing.T) {
 	context := getContextOrFail(t)
 
@@ -696,6 +717,21 @@ func TestTemplates(t *testing.T) {
 	}
 }
 
+func TestPlaceholder(t *testing.T) {
+	ctx := getContextOrFail(t)
+	ctx.Req = ctx.Req.WithContext(context.WithValue(ctx.Req.Context(), PlaceholdersCtxKey, make(map[string]string)))
+	SetPlaceholder(ctx.Req, "csp_nonce", "abc123")
+
+	tmpl := template.Must(template.New("").Parse(`<script nonce="{{.Placeholder "csp_nonce"}}"></script>{{.Placeholder "unset"}}`))
+	buf := &bytes.Buffer{}
+	if err := tmpl.Execute(buf, ctx); err != nil {
+		t.Fatal(err)
+	}
+	if expected := `<script nonce="abc123"></script>`; buf.String() != expected {
+		t.Errorf("Expected '%s', got '%s'", expected, buf.String())
+	}
+}
+
 func TestFiles(t *testing.T) {
 	tests := []struct {
 		fileNames []string
@@ -794,3 +830,7 @@ func TestFiles(t *testing.T) {
 		}
 	}
 }
+
+func contextWithPlaceholders(r *http.Request, placeholders map[string]string) context.Context {
+	return context.WithValue(r.Context(), PlaceholdersCtxKey, placeholders)
+}
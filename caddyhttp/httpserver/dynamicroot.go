@@ -0,0 +1,80 @@
+package httpserver
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/staticfiles"
+)
+
+// IsDynamicRoot reports whether the site root root contains
+// placeholders, which means it is resolved for every request.
+func IsDynamicRoot(root string) bool {
+	i := strings.Index(root, "{")
+	return i >= 0 && strings.Contains(root[i:], "}")
+}
+
+// DynamicRootBase returns the directory that the dynamic root
+// root is in, up to its first placeholder: /srv for /srv/{label1}
+// and also for /srv/site-{label1}.
+func DynamicRootBase(root string) string {
+	i := strings.Index(root, "{")
+	if i < 0 {
+		return root
+	}
+	return filepath.Dir(root[:i] + "x")
+}
+
+// CheckStaticRoot returns an error if the site root of c has
+// placeholders. Only the static file server resolves such a root
+// for every request; directives that read files from the root as
+// it is when they are set up, like browse and templates, call this
+// so that they are not silently served from the wrong directory.
+func CheckStaticRoot(c *caddy.Controller, directive string) error {
+	if IsDynamicRoot(GetConfig(c).Root) {
+		return c.Errf("%s cannot be used with a root that has placeholders", directive)
+	}
+	return nil
+}
+
+// dynamicRootServer serves static files from a root that is
+// resolved for every request.
+type dynamicRootServer struct {
+	root  string // with placeholders
+	base  string // absolute and clean
+	files staticfiles.FileServer
+}
+
+// ServeHTTP serves the file of r from the root that r resolves to.
+// If that is not a directory below base, nothing is served: roots
+// that escape base are forbidden and roots that don't exist are not
+// found.
+func (d dynamicRootServer) ServeHTTP(w http.ResponseWriter, r *http.Request) (int, error) {
+	root, err := filepath.Abs(NewReplacer(r, nil, "").Replace(d.root))
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	if !isBelow(root, d.base) {
+		return http.StatusForbidden, nil
+	}
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		return http.StatusNotFound, nil
+	}
+
+	files := d.files
+	files.Root = http.Dir(root)
+	return files.ServeHTTP(w, r)
+}
+
+// isBelow reports whether the clean, absolute path p is in the
+// directory base or one of its subdirectories, but not base itself.
+func isBelow(p, base string) bool {
+	rel, err := filepath.Rel(base, p)
+	if err != nil {
+		return false
+	}
+	return rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,146 @@
+package httpserver
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddytls"
+)
+
+// makeSites makes a directory with the directories of the
+// sites a and b, and a file next to it that must not be served.
+func makeSites(t *testing.T) string {
+	tmp, err := ioutil.TempDir("", "caddy_dynamicroot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, site := range []string{"a", "b"} {
+		dir := filepath.Join(tmp, "sites", site)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, "file.txt"), []byte("site "+site), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(tmp, "secret.txt"), []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return tmp
+}
+
+func TestDynamicRootSubdomains(t *testing.T) {
+	tmp := makeSites(t)
+	defer os.RemoveAll(tmp)
+
+	site := &SiteConfig{
+		Addr: Address{Original: "*.example.com", Host: "*.example.com"},
+		TLS:  new(caddytls.Config),
+		Root: filepath.Join(tmp, "sites", "{label1}"),
+	}
+	s, err := NewServer("127.0.0.1:0", []*SiteConfig{site})
+	if err != nil {
+		t.Fatalf("Expected no error making server, got: %v", err)
+	}
+
+	for i, test := range []struct {
+		host         string
+		expectedCode int
+		expectedBody string
+	}{
+		{"a.example.com", http.StatusOK, "site a"},
+		{"b.example.com", http.StatusOK, "site b"},
+		{"c.example.com", http.StatusNotFound, ""},
+	} {
+		req, err := http.NewRequest("GET", "http://"+test.host+"/file.txt", nil)
+		if err != nil {
+			t.Fatalf("Test %d: Could not create HTTP request: %v", i, err)
+		}
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		if rec.Code != test.expectedCode {
+			t.Errorf("Test %d: Expected status %d, got %d", i, test.expectedCode, rec.Code)
+		}
+		if test.expectedBody != "" && rec.Body.String() != test.expectedBody {
+			t.Errorf("Test %d: Expected body %q, got %q", i, test.expectedBody, rec.Body.String())
+		}
+	}
+}
+
+func TestDynamicRootTraversal(t *testing.T) {
+	tmp := makeSites(t)
+	defer os.RemoveAll(tmp)
+
+	d := dynamicRootServer{
+		root: filepath.Join(tmp, "sites", "{>X-Site}"),
+		base: filepath.Join(tmp, "sites"),
+	}
+
+	for i, test := range []struct {
+		site         string
+		path         string
+		expectedCode int
+	}{
+		{"a", "/file.txt", http.StatusOK},
+		{"..", "/secret.txt", http.StatusForbidden},
+		{"../..", "/tmp", http.StatusForbidden},
+		{"a/../..", "/secret.txt", http.StatusForbidden},
+		{"", "/a/file.txt", http.StatusForbidden}, // the base itself
+		{"a/file.txt", "/", http.StatusNotFound},  // not a directory
+		{"missing", "/file.txt", http.StatusNotFound},
+	} {
+		req, err := http.NewRequest("GET", "http://localhost"+test.path, nil)
+		if err != nil {
+			t.Fatalf("Test %d: Could not create HTTP request: %v", i, err)
+		}
+		req.Header.Set("X-Site", test.site)
+		code, _ := d.ServeHTTP(httptest.NewRecorder(), req)
+		if code == 0 {
+			code = http.StatusOK
+		}
+		if code != test.expectedCode {
+			t.Errorf("Test %d: Expected status %d for site %q, got %d", i, test.expectedCode, test.site, code)
+		}
+	}
+}
+
+func TestDynamicRootBase(t *testing.T) {
+	for i, test := range []struct {
+		root     string
+		expected string
+	}{
+		{"/srv/{label1}", "/srv"},
+		{"/srv/site-{label1}", "/srv"},
+		{"/srv/{label2}/{label1}", "/srv"},
+		{"/srv/www", "/srv/www"},
+	} {
+		if got := DynamicRootBase(test.root); got != filepath.FromSlash(test.expected) {
+			t.Errorf("Test %d: Expected base %s of %s, got %s", i, test.expected, test.root, got)
+		}
+	}
+}
+
+func TestCheckStaticRoot(t *testing.T) {
+	for i, test := range []struct {
+		root      string
+		shouldErr bool
+	}{
+		{"", false},
+		{"/srv/www", false},
+		{"/srv/{label1}", true},
+	} {
+		c := caddy.NewTestController("http", "browse")
+		GetConfig(c).Root = test.root
+		err := CheckStaticRoot(c, "browse")
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %d: Expected an error for root %s, got none", i, test.root)
+		} else if !test.shouldErr && err != nil {
+			t.Errorf("Test %d: Expected no error for root %s, got: %v", i, test.root, err)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package httpserver
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Enricher supplies metadata about a request, such as the
+// autonomous system or organization of the client, without the
+// server having to know where the metadata comes from. This
+// lets plugins backed by GeoIP or ASN databases add to the
+// placeholders of every request.
+type Enricher interface {
+	// Enrich returns the values it knows about r, keyed by
+	// name; each value becomes the placeholder {enrich.name}.
+	// Enrich should return once ctx is done, since its values
+	// are discarded after that anyway. Neither ctx nor the
+	// context of r carries the values the server keeps for the
+	// request, such as its placeholders, since Enrich may still
+	// be running while the handlers use them.
+	Enrich(ctx context.Context, r *http.Request) (map[string]string, error)
+}
+
+// EnricherFunc is a convenience type like HandlerFunc,
+// but for Enricher.
+type EnricherFunc func(ctx context.Context, r *http.Request) (map[string]string, error)
+
+// Enrich implements the Enricher interface.
+func (f EnricherFunc) Enrich(ctx context.Context, r *http.Request) (map[string]string, error) {
+	return f(ctx, r)
+}
+
+// EnrichPlaceholderPrefix is the prefix of the placeholders
+// of the values supplied by enrichers.
+const EnrichPlaceholderPrefix = "enrich."
+
+// EnrichTimeout is how long all enrichers together may take
+// for a single request. Enrichers that have not returned by
+// then are skipped, so that a slow lookup cannot hold up the
+// request.
+var EnrichTimeout = 100 * time.Millisecond
+
+type namedEnricher struct {
+	name     string
+	enricher Enricher
+}
+
+var (
+	enrichers   []namedEnricher
+	enrichersMu sync.RWMutex
+)
+
+// RegisterEnricher registers e under name, which must be unique.
+// Enrichers run in the order in which they are registered, and
+// a value supplied by a later enricher replaces the value of the
+// same name supplied by an earlier one. This is typically called
+// from a plugin's init function.
+func RegisterEnricher(name string, e Enricher) {
+	if name == "" {
+		panic("enricher must have a name")
+	}
+	enrichersMu.Lock()
+	defer enrichersMu.Unlock()
+	for _, ne := range enrichers {
+		if ne.name == name {
+			panic("enricher named " + name + " already registered")
+		}
+	}
+	enrichers = append(enrichers, namedEnricher{name: name, enricher: e})
+}
+
+// enrich runs the registered enrichers for r, in order, and sets
+// the placeholders of the values they supply. It returns when
+// all of them are done or EnrichTimeout has passed.
+func enrich(r *http.Request) {
+	enrichersMu.RLock()
+	list := enrichers
+	enrichersMu.RUnlock()
+	if len(list) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(valuelessContext{r.Context()}, EnrichTimeout)
+	defer cancel()
+
+	// enrichers that are still running after the deadline must
+	// not see the changes the handlers make to the request, nor
+	// get at the placeholders and other values they share
+	req := r.WithContext(ctx)
+	u := *r.URL
+	req.URL = &u
+	req.Header = make(http.Header, len(r.Header))
+	for k, v := range r.Header {
+		req.Header[k] = append([]string(nil), v...)
+	}
+
+	type result struct {
+		values map[string]string
+		err    error
+	}
+
+	for _, ne := range list {
+		// buffered, so that an enricher which returns after
+		// the deadline does not leak its goroutine
+		done := make(chan result, 1)
+		go func(e Enricher) {
+			values, err := e.Enrich(ctx, req)
+			done <- result{values, err}
+		}(ne.enricher)
+
+		select {
+		case res := <-done:
+			if res.err != nil {
+				log.Printf("[ERROR] Enricher %s: %v", ne.name, res.err)
+				continue
+			}
+			for name, value := range res.values {
+				SetPlaceholder(r, EnrichPlaceholderPrefix+name, value)
+			}
+		case <-ctx.Done():
+			log.Printf("[WARNING] Enricher %s did not finish within %s; skipping remaining enrichers",
+				ne.name, EnrichTimeout)
+			return
+		}
+	}
+}
+
+// valuelessContext is a context that is canceled along with the
+// one it wraps, but that hides all of its values.
+type valuelessContext struct {
+	context.Context
+}
+
+// Value implements the context.Context interface.
+func (valuelessContext) Value(key interface{}) interface{} { return nil }
@@ -0,0 +1,137 @@
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mholt/caddy/caddytls"
+)
+
+// withEnrichers replaces the registered enrichers for the
+// duration of a test.
+func withEnrichers(f func()) {
+	enrichersMu.Lock()
+	saved := enrichers
+	enrichers = nil
+	enrichersMu.Unlock()
+	defer func() {
+		enrichersMu.Lock()
+		enrichers = saved
+		enrichersMu.Unlock()
+	}()
+	f()
+}
+
+// serveEnriched serves a request for / and returns the
+// expansion of s in the handler.
+func serveEnriched(t *testing.T, s string) string {
+	var expanded string
+	site := &SiteConfig{
+		Addr: Address{Original: "localhost", Host: "localhost"},
+		TLS:  new(caddytls.Config),
+	}
+	site.AddMiddleware(func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+			expanded = NewReplacer(r, nil, "-").Replace(s)
+			return http.StatusOK, nil
+		})
+	})
+	srv, err := NewServer("127.0.0.1:0", []*SiteConfig{site})
+	if err != nil {
+		t.Fatalf("Expected no error making server, got: %v", err)
+	}
+	req, err := http.NewRequest("GET", "http://localhost/", nil)
+	if err != nil {
+		t.Fatalf("Could not create HTTP request: %v", err)
+	}
+	req.RemoteAddr = "1.2.3.4:1234"
+	srv.ServeHTTP(httptest.NewRecorder(), req)
+	return expanded
+}
+
+func TestEnricherPlaceholders(t *testing.T) {
+	withEnrichers(func() {
+		RegisterEnricher("asn", EnricherFunc(func(ctx context.Context, r *http.Request) (map[string]string, error) {
+			if r.RemoteAddr != "1.2.3.4:1234" {
+				return nil, errors.New("unexpected remote address " + r.RemoteAddr)
+			}
+			return map[string]string{"asn": "64496", "org": "Example"}, nil
+		}))
+		RegisterEnricher("override", EnricherFunc(func(ctx context.Context, r *http.Request) (map[string]string, error) {
+			return map[string]string{"org": "Example Org"}, nil
+		}))
+		RegisterEnricher("failing", EnricherFunc(func(ctx context.Context, r *http.Request) (map[string]string, error) {
+			return map[string]string{"asn": "0"}, errors.New("lookup failed")
+		}))
+
+		if got, want := serveEnriched(t, "{enrich.asn} {enrich.org} {enrich.none}"), "64496 Example Org -"; got != want {
+			t.Errorf("Expected '%s', got '%s'", want, got)
+		}
+	})
+}
+
+func TestEnricherTimeout(t *testing.T) {
+	defer func(timeout time.Duration) { EnrichTimeout = timeout }(EnrichTimeout)
+	EnrichTimeout = 20 * time.Millisecond
+
+	withEnrichers(func() {
+		RegisterEnricher("fast", EnricherFunc(func(ctx context.Context, r *http.Request) (map[string]string, error) {
+			return map[string]string{"asn": "64496"}, nil
+		}))
+		RegisterEnricher("slow", EnricherFunc(func(ctx context.Context, r *http.Request) (map[string]string, error) {
+			<-ctx.Done()
+			return map[string]string{"org": "Late"}, nil
+		}))
+		RegisterEnricher("skipped", EnricherFunc(func(ctx context.Context, r *http.Request) (map[string]string, error) {
+			return map[string]string{"country": "NL"}, nil
+		}))
+
+		start := time.Now()
+		got := serveEnriched(t, "{enrich.asn} {enrich.org} {enrich.country}")
+		if want := "64496 - -"; got != want {
+			t.Errorf("Expected '%s', got '%s'", want, got)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("Expected the slow enricher to be cut off, but request took %s", elapsed)
+		}
+	})
+}
+
+func TestEnricherContextValues(t *testing.T) {
+	withEnrichers(func() {
+		RegisterEnricher("snoop", EnricherFunc(func(ctx context.Context, r *http.Request) (map[string]string, error) {
+			// an enricher must not get at the placeholders, which
+			// the handlers may be writing while it runs
+			for _, v := range []interface{}{ctx.Value(PlaceholdersCtxKey), r.Context().Value(PlaceholdersCtxKey)} {
+				if v != nil {
+					return nil, errors.New("placeholders are reachable from the enricher")
+				}
+			}
+			SetPlaceholder(r, "snooped", "yes")
+			return map[string]string{"asn": "64496"}, nil
+		}))
+
+		if got, want := serveEnriched(t, "{enrich.asn} {snooped}"), "64496 -"; got != want {
+			t.Errorf("Expected '%s', got '%s'", want, got)
+		}
+	})
+}
+
+func TestRegisterEnricherDuplicate(t *testing.T) {
+	withEnrichers(func() {
+		e := EnricherFunc(func(ctx context.Context, r *http.Request) (map[string]string, error) {
+			return nil, nil
+		})
+		RegisterEnricher("dup", e)
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected registering a duplicate enricher name to panic")
+			}
+		}()
+		RegisterEnricher("dup", e)
+	})
+}
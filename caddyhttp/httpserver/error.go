@@ -8,6 +8,7 @@ var (
 	_ error = NonHijackerError{}
 	_ error = NonFlusherError{}
 	_ error = NonCloseNotifierError{}
+	_ error = NonPusherError{}
 )
 
 // NonHijackerError is more descriptive error caused by a non hijacker
@@ -42,3 +43,24 @@ type NonCloseNotifierError struct {
 func (c NonCloseNotifierError) Error() string {
 	return fmt.Sprintf("%T is not a closeNotifier", c.Underlying)
 }
+
+// NonPusherError is more descriptive error caused by a non pusher
+type NonPusherError struct {
+	// underlying type which doesn't implement Push
+	Underlying interface{}
+}
+
+// Implement Error
+func (p NonPusherError) Error() string {
+	return fmt.Sprintf("%T is not a pusher", p.Underlying)
+}
+
+// Repanic is what handlers panic with when they have handled a panic,
+// but want net/http to handle it as well, which aborts the response:
+// it closes the connection, so that clients can tell that a response
+// that had been started is incomplete. The server does not recover
+// from it.
+type Repanic struct {
+	// value of the panic that was handled
+	Value interface{}
+}
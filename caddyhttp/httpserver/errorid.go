@@ -0,0 +1,48 @@
+package httpserver
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ErrorIDPlaceholder is the placeholder, without braces, for the ID
+// of the error that handling a request ran into, as in {error_id}.
+const ErrorIDPlaceholder = "error_id"
+
+// MakeErrorID returns the ID of the error that handling r ran into,
+// making one if there is none yet. The error is logged with the ID
+// and clients are shown only the ID, so that what they report can be
+// traced back to the log. All errors of a request, like one serving
+// the error page of another, share the ID, which is also available as
+// the {error_id} placeholder.
+func MakeErrorID(r *http.Request) string {
+	placeholders, _ := r.Context().Value(PlaceholdersCtxKey).(map[string]string)
+	if id := placeholders[ErrorIDPlaceholder]; id != "" {
+		return id
+	}
+	id := newErrorID()
+	if placeholders != nil {
+		placeholders[ErrorIDPlaceholder] = id
+	}
+	return id
+}
+
+// ErrorID returns the ID of the error that handling r ran into,
+// or "" if it ran into none; see MakeErrorID.
+func ErrorID(r *http.Request) string {
+	placeholders, _ := r.Context().Value(PlaceholdersCtxKey).(map[string]string)
+	return placeholders[ErrorIDPlaceholder]
+}
+
+// newErrorID returns a random, hex-encoded 64-bit ID.
+func newErrorID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		// unique enough to find in the log
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,55 @@
+package httpserver
+
+import (
+	"net/http"
+	"strconv"
+)
+
+const (
+	// HandlerCountPlaceholder is the placeholder, without braces, for
+	// the number of handlers in the chain of the site that a request
+	// matched, as in {handler_count}. The chain is the middleware of
+	// the site and the handler that serves its files.
+	HandlerCountPlaceholder = "handler_count"
+
+	// HandlerIndexPlaceholder is the placeholder, without braces, for
+	// the position in that chain of the handler that is handling a
+	// request, counting from 1, as in {handler_index}. The handler
+	// that serves files is the last one, at {handler_count}. Since
+	// keeping track of it takes a wrapper around every handler, it is
+	// only set for sites whose configuration uses it.
+	HandlerIndexPlaceholder = "handler_index"
+)
+
+// indexedHandler is a handler of a chain that makes its position
+// in the chain the {handler_index} while it handles a request.
+type indexedHandler struct {
+	next  Handler
+	index string
+}
+
+// ServeHTTP sets {handler_index} and handles r with h.next. Once
+// h.next is done, {handler_index} is what it was before, so that
+// it is right for the handler that called h as well.
+func (h indexedHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) (int, error) {
+	placeholders, ok := r.Context().Value(PlaceholdersCtxKey).(map[string]string)
+	if !ok {
+		return h.next.ServeHTTP(w, r)
+	}
+	prev, hadPrev := placeholders[HandlerIndexPlaceholder]
+	placeholders[HandlerIndexPlaceholder] = h.index
+	defer func() {
+		if hadPrev {
+			placeholders[HandlerIndexPlaceholder] = prev
+		} else {
+			delete(placeholders, HandlerIndexPlaceholder)
+		}
+	}()
+	return h.next.ServeHTTP(w, r)
+}
+
+// indexHandler returns h as the handler at position i,
+// counting from 1, of a chain.
+func indexHandler(h Handler, i int) Handler {
+	return indexedHandler{next: h, index: strconv.Itoa(i)}
+}
@@ -0,0 +1,89 @@
+package httpserver
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+)
+
+// HeaderWriter wraps a ResponseWriter to call a function right
+// before the response header is written, so that middleware can
+// set headers that depend on what the handlers after it did, like
+// the Content-Type they set or how long they took.
+type HeaderWriter struct {
+	http.ResponseWriter
+
+	// OnWriteHeader, if not nil, is called once with the status of
+	// the response before its header is written. It is not called
+	// if the connection is hijacked first.
+	OnWriteHeader func(status int)
+
+	wroteHeader bool
+}
+
+// NewHeaderWriter returns a HeaderWriter that wraps w and calls
+// onWriteHeader right before the response header is written.
+func NewHeaderWriter(w http.ResponseWriter, onWriteHeader func(status int)) *HeaderWriter {
+	return &HeaderWriter{ResponseWriter: w, OnWriteHeader: onWriteHeader}
+}
+
+// WroteHeader reports whether the response was started, which
+// means its header was written or its connection hijacked. If it
+// wasn't, the response may still be written further up the chain,
+// by the errors middleware for example.
+func (w *HeaderWriter) WroteHeader() bool {
+	return w.wroteHeader
+}
+
+// WriteHeader calls OnWriteHeader, then writes the response header.
+func (w *HeaderWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		if w.OnWriteHeader != nil {
+			w.OnWriteHeader(status)
+		}
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write writes the response header if needed, then b.
+func (w *HeaderWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Hijack implements http.Hijacker. It simply wraps the underlying
+// ResponseWriter's Hijack method if there is one, or returns an error.
+func (w *HeaderWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if hj, ok := w.ResponseWriter.(http.Hijacker); ok {
+		w.wroteHeader = true
+		return hj.Hijack()
+	}
+	return nil, nil, NonHijackerError{Underlying: w.ResponseWriter}
+}
+
+// Flush implements http.Flusher. It simply wraps the underlying
+// ResponseWriter's Flush method if there is one, or panics. Since
+// flushing writes the response header, it is written here first.
+func (w *HeaderWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		if !w.wroteHeader {
+			w.WriteHeader(http.StatusOK)
+		}
+		f.Flush()
+	} else {
+		panic(NonFlusherError{Underlying: w.ResponseWriter}) // should be recovered at the beginning of middleware stack
+	}
+}
+
+// CloseNotify implements http.CloseNotifier.
+// It just inherits the underlying ResponseWriter's CloseNotify method.
+// It panics if the underlying ResponseWriter is not a CloseNotifier.
+func (w *HeaderWriter) CloseNotify() <-chan bool {
+	if cn, ok := w.ResponseWriter.(http.CloseNotifier); ok {
+		return cn.CloseNotify()
+	}
+	panic(NonCloseNotifierError{Underlying: w.ResponseWriter})
+}
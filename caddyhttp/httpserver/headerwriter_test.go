@@ -0,0 +1,53 @@
+package httpserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHeaderWriter(t *testing.T) {
+	for i, test := range []struct {
+		write          func(w http.ResponseWriter)
+		expectedStatus int
+	}{
+		{func(w http.ResponseWriter) { w.WriteHeader(http.StatusNotFound); w.WriteHeader(http.StatusOK) }, http.StatusNotFound},
+		{func(w http.ResponseWriter) { w.Write([]byte("body")); w.Write([]byte("more")) }, http.StatusOK},
+		{func(w http.ResponseWriter) { w.(http.Flusher).Flush() }, http.StatusOK},
+	} {
+		rec := httptest.NewRecorder()
+		var calls, status int
+		w := NewHeaderWriter(rec, func(s int) {
+			calls++
+			status = s
+			rec.Header().Set("X-Set", "yes")
+		})
+		if w.WroteHeader() {
+			t.Fatalf("Test %d: Expected the header not to be written yet", i)
+		}
+
+		test.write(w)
+
+		if !w.WroteHeader() {
+			t.Errorf("Test %d: Expected the header to be written", i)
+		}
+		if calls != 1 || status != test.expectedStatus {
+			t.Errorf("Test %d: Expected one call with status %d, got %d calls with status %d",
+				i, test.expectedStatus, calls, status)
+		}
+		if rec.Header().Get("X-Set") != "yes" {
+			t.Errorf("Test %d: Expected the header set by the callback to be written", i)
+		}
+	}
+}
+
+func TestHeaderWriterHijack(t *testing.T) {
+	var called bool
+	w := NewHeaderWriter(httptest.NewRecorder(), func(int) { called = true })
+	if _, _, err := w.Hijack(); err == nil {
+		t.Error("Expected an error hijacking a ResponseWriter that is not a Hijacker")
+	}
+	if w.WroteHeader() || called {
+		t.Error("Expected a failed hijack not to start the response")
+	}
+}
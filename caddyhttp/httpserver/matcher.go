@@ -0,0 +1,73 @@
+package httpserver
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Matcher is a RequestMatcher defined once, under a name, with the
+// matcher directive, for directives to refer to with match <name>.
+// A request matches if all of its conditions are true: its path is
+// under one of Paths, its method is one of Methods, its host is one
+// of Hosts, and If matches it. Conditions without values, like Hosts
+// if nil, are true for any request.
+type Matcher struct {
+	Paths   []string
+	Methods []string // upper case
+	Hosts   []string // lower case, without port; *.example.com matches subdomains
+	If      RequestMatcher
+}
+
+// Match satisfies RequestMatcher interface.
+func (m Matcher) Match(r *http.Request) bool {
+	return m.matchesPath(r.URL.Path) &&
+		m.matchesMethod(r.Method) &&
+		m.matchesHost(r.Host) &&
+		(m.If == nil || m.If.Match(r))
+}
+
+// matchesPath reports whether path is under one of m.Paths.
+func (m Matcher) matchesPath(path string) bool {
+	if len(m.Paths) == 0 {
+		return true
+	}
+	for _, p := range m.Paths {
+		if Path(path).Matches(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesMethod reports whether method is one of m.Methods.
+func (m Matcher) matchesMethod(method string) bool {
+	if len(m.Methods) == 0 {
+		return true
+	}
+	method = strings.ToUpper(method)
+	for _, mm := range m.Methods {
+		if mm == method {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesHost reports whether host, which may have a port,
+// is one of m.Hosts.
+func (m Matcher) matchesHost(host string) bool {
+	if len(m.Hosts) == 0 {
+		return true
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(host)
+	for _, h := range m.Hosts {
+		if h == host || strings.HasPrefix(h, "*.") && strings.HasSuffix(host, h[1:]) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+package httpserver
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestMatcher(t *testing.T) {
+	ifMatcher := IfMatcher{ifs: []ifCond{{a: "{>X-Debug}", op: "is", b: "1"}}}
+
+	for i, test := range []struct {
+		matcher  Matcher
+		method   string
+		url      string
+		debug    string
+		expected bool
+	}{
+		{Matcher{Paths: []string{"/api"}}, "GET", "http://example.com/api/users", "", true},
+		{Matcher{Paths: []string{"/api"}}, "GET", "http://example.com/users", "", false},
+		{Matcher{Paths: []string{"/api", "/v2"}}, "GET", "http://example.com/v2/users", "", true},
+		{Matcher{Methods: []string{"POST", "PUT"}}, "put", "http://example.com/", "", true},
+		{Matcher{Methods: []string{"POST", "PUT"}}, "GET", "http://example.com/", "", false},
+		{Matcher{Hosts: []string{"api.example.com"}}, "GET", "http://API.example.com:8080/", "", true},
+		{Matcher{Hosts: []string{"api.example.com"}}, "GET", "http://example.com/", "", false},
+		{Matcher{Hosts: []string{"*.example.com"}}, "GET", "http://a.b.example.com/", "", true},
+		{Matcher{Hosts: []string{"*.example.com"}}, "GET", "http://example.com/", "", false},
+		{Matcher{If: ifMatcher}, "GET", "http://example.com/", "1", true},
+		{Matcher{If: ifMatcher}, "GET", "http://example.com/", "", false},
+
+		// all conditions must be true
+		{Matcher{Paths: []string{"/api"}, Methods: []string{"POST"}, Hosts: []string{"example.com"}, If: ifMatcher},
+			"POST", "http://example.com/api", "1", true},
+		{Matcher{Paths: []string{"/api"}, Methods: []string{"POST"}, Hosts: []string{"example.com"}, If: ifMatcher},
+			"GET", "http://example.com/api", "1", false},
+		{Matcher{Paths: []string{"/api"}, Methods: []string{"POST"}, Hosts: []string{"example.com"}, If: ifMatcher},
+			"POST", "http://example.com/", "1", false},
+		{Matcher{Paths: []string{"/api"}, Methods: []string{"POST"}, Hosts: []string{"example.com"}, If: ifMatcher},
+			"POST", "http://example.org/api", "1", false},
+		{Matcher{Paths: []string{"/api"}, Methods: []string{"POST"}, Hosts: []string{"example.com"}, If: ifMatcher},
+			"POST", "http://example.com/api", "", false},
+	} {
+		r, err := http.NewRequest(test.method, test.url, nil)
+		if err != nil {
+			t.Fatalf("Test %d: Could not create HTTP request: %v", i, err)
+		}
+		r.Header.Set("X-Debug", test.debug)
+		if got := test.matcher.Match(r); got != test.expected {
+			t.Errorf("Test %d: Expected match %v, got %v", i, test.expected, got)
+		}
+	}
+}
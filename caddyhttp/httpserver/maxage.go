@@ -0,0 +1,28 @@
+package httpserver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseMaxAge parses s as the max_age of a directive: a number of
+// seconds or a duration that is rounded down to whole seconds.
+// Besides the units of Go durations, it may be in days, like 30d,
+// or in years of 365 days, like 1y. It must not be negative.
+func ParseMaxAge(s string) (time.Duration, error) {
+	if seconds, err := strconv.ParseInt(s, 10, 64); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, nil
+	}
+	for unit, d := range map[string]time.Duration{"d": 24 * time.Hour, "y": 365 * 24 * time.Hour} {
+		if n, err := strconv.ParseInt(strings.TrimSuffix(s, unit), 10, 64); err == nil && strings.HasSuffix(s, unit) && n >= 0 {
+			return time.Duration(n) * d, nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("max_age must be a non-negative number of seconds or a duration, got '%s'", s)
+	}
+	return d - d%time.Second, nil
+}
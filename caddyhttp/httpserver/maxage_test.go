@@ -0,0 +1,40 @@
+package httpserver
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseMaxAge(t *testing.T) {
+	for i, test := range []struct {
+		input     string
+		expected  time.Duration
+		shouldErr bool
+	}{
+		{"0", 0, false},
+		{"3600", time.Hour, false},
+		{"1h30m", 90 * time.Minute, false},
+		{"1500ms", time.Second, false},
+		{"30d", 30 * 24 * time.Hour, false},
+		{"1y", 365 * 24 * time.Hour, false},
+		{"-1", 0, true},
+		{"-1h", 0, true},
+		{"-1d", 0, true},
+		{"d", 0, true},
+		{"forever", 0, true},
+		{"", 0, true},
+	} {
+		actual, err := ParseMaxAge(test.input)
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: Expected an error for '%s', got %s", i, test.input, actual)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: Expected no error for '%s', got: %v", i, test.input, err)
+		} else if actual != test.expected {
+			t.Errorf("Test %d: Expected %s for '%s', got %s", i, test.expected, test.input, actual)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"os"
 	"path"
+	"sync"
 	"time"
 )
 
@@ -136,6 +137,43 @@ func SetLastModifiedHeader(w http.ResponseWriter, modTime time.Time) {
 	w.Header().Set("Last-Modified", modTime.UTC().Format(http.TimeFormat))
 }
 
+// NotModifiedSince reports whether r is a conditional GET or HEAD request
+// whose If-Modified-Since header is not older than modTime, meaning the
+// client's copy is still fresh and a 304 Not Modified response may be sent.
+// Times are compared at one-second resolution since that is all the HTTP
+// date format carries. As mandated by RFC 7232, If-Modified-Since is
+// ignored when the request also has an If-None-Match header.
+func NotModifiedSince(r *http.Request, modTime time.Time) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	if modTime.IsZero() || modTime.Equal(time.Unix(0, 0)) {
+		return false
+	}
+	if r.Header.Get("If-None-Match") != "" {
+		return false
+	}
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+
+	// use the same clamped time that SetLastModifiedHeader advertises
+	if now := currentTime(); modTime.After(now) {
+		modTime = now
+	}
+	return !modTime.Truncate(time.Second).After(since)
+}
+
+// WriteNotModified writes a 304 Not Modified response to w, dropping
+// the headers that describe a body which is not sent.
+func WriteNotModified(w http.ResponseWriter) {
+	h := w.Header()
+	h.Del("Content-Type")
+	h.Del("Content-Length")
+	w.WriteHeader(http.StatusNotModified)
+}
+
 // CaseSensitivePath determines if paths should be case sensitive.
 // This is configurable via CASE_SENSITIVE_PATH environment variable.
 var CaseSensitivePath = true
@@ -172,6 +210,82 @@ func (m requestMatchers) Match(r *http.Request) bool {
 	return true
 }
 
+// CtxKey is a value type for use with context.WithValue.
+type CtxKey string
+
+// ResponseRecorderCtxKey is the context key under which the
+// server stores the ResponseRecorder for the current request.
+const ResponseRecorderCtxKey CtxKey = "response_recorder"
+
+// PlaceholdersCtxKey is the context key under which the server
+// stores the custom placeholders of a request as a map[string]string;
+// see SetPlaceholder.
+const PlaceholdersCtxKey CtxKey = "placeholders"
+
+// SetPlaceholder sets the placeholder {key} to value for the remainder
+// of the handling of r, so that every Replacer created for r afterwards
+// knows it. It is a no-op if r was not passed in by the server.
+func SetPlaceholder(r *http.Request, key, value string) {
+	if placeholders, ok := r.Context().Value(PlaceholdersCtxKey).(map[string]string); ok {
+		placeholders[key] = value
+	}
+}
+
+// MatchedRulePlaceholder is the placeholder, without braces, for the
+// name of the last named rewrite or redir rule that applied to a
+// request, as in {matched_rule}.
+const MatchedRulePlaceholder = "matched_rule"
+
+// ValuesCtxKey is the context key under which the server stores
+// the values that handlers share with each other for a request;
+// see SetContextValue.
+const ValuesCtxKey CtxKey = "values"
+
+// contextValues are the values that handlers share for a request.
+// Handlers run one after the other, but may start goroutines that
+// outlive their turn, like the proxy does.
+type contextValues struct {
+	sync.Mutex
+	values map[interface{}]interface{}
+}
+
+// SetContextValue sets the value of key for the remainder of the
+// handling of r, so that handlers that come later, and the ones it
+// returns to, can get it with GetContextValue. Unlike values added
+// with r.WithContext, it does not require passing a new request on.
+// It is a no-op if r was not passed in by the server.
+//
+// Like keys of context values, keys must be comparable, and to avoid
+// collisions with the values of other plugins they should be of a
+// type that is not exported, such as:
+//
+//	type ctxKey string
+//	const userKey ctxKey = "user"
+func SetContextValue(r *http.Request, key, value interface{}) {
+	if r == nil {
+		return
+	}
+	if cv, ok := r.Context().Value(ValuesCtxKey).(*contextValues); ok {
+		cv.Lock()
+		cv.values[key] = value
+		cv.Unlock()
+	}
+}
+
+// GetContextValue returns the value of key that a handler set with
+// SetContextValue for r, or nil if none did.
+func GetContextValue(r *http.Request, key interface{}) interface{} {
+	if r == nil {
+		return nil
+	}
+	if cv, ok := r.Context().Value(ValuesCtxKey).(*contextValues); ok {
+		cv.Lock()
+		defer cv.Unlock()
+		return cv.values[key]
+	}
+	return nil
+}
+
 // currentTime, as it is defined here, returns time.Now().
 // It's defined as a variable for mocking time in tests.
 var currentTime = func() time.Time { return time.Now() }
@@ -1,8 +1,10 @@
 package httpserver
 
 import (
+	"net/http"
 	"os"
 	"testing"
+	"time"
 )
 
 func TestPathCaseSensitivity(t *testing.T) {
@@ -56,3 +58,43 @@ func TestPathCaseSensitiveEnv(t *testing.T) {
 		}
 	}
 }
+
+func TestNotModifiedSince(t *testing.T) {
+	modTime := time.Date(2016, 10, 3, 12, 30, 15, 500000000, time.UTC)
+	format := func(t time.Time) string { return t.Format(http.TimeFormat) }
+
+	tests := []struct {
+		method      string
+		since       string
+		noneMatch   string
+		modTime     time.Time
+		notModified bool
+	}{
+		{"GET", "", "", modTime, false},
+		{"GET", format(modTime), "", modTime, true}, // sub-second part is ignored
+		{"HEAD", format(modTime), "", modTime, true},
+		{"GET", format(modTime.Add(time.Hour)), "", modTime, true},
+		{"GET", format(modTime.Add(-time.Second)), "", modTime, false},
+		{"GET", "not a date", "", modTime, false},
+		{"GET", format(modTime), `"abc"`, modTime, false},
+		{"POST", format(modTime), "", modTime, false},
+		{"GET", format(modTime), "", time.Time{}, false},
+		{"GET", format(modTime), "", time.Unix(0, 0), false},
+	}
+
+	for i, test := range tests {
+		r, err := http.NewRequest(test.method, "/", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if test.since != "" {
+			r.Header.Set("If-Modified-Since", test.since)
+		}
+		if test.noneMatch != "" {
+			r.Header.Set("If-None-Match", test.noneMatch)
+		}
+		if got := NotModifiedSince(r, test.modTime); got != test.notModified {
+			t.Errorf("Test %d: Expected %v, got %v", i, test.notModified, got)
+		}
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/mholt/caddy"
@@ -25,9 +26,10 @@ func init() {
 	flag.DurationVar(&GracefulTimeout, "grace", 5*time.Second, "Maximum duration of graceful shutdown")
 	flag.BoolVar(&HTTP2, "http2", true, "Use HTTP/2")
 	flag.BoolVar(&QUIC, "quic", false, "Use experimental QUIC")
+	flag.BoolVar(&LogMiddlewareOrder, "log-middleware", false, "Log the order of the middleware of each site")
 
 	caddy.RegisterServerType(serverType, caddy.ServerType{
-		Directives: func() []string { return directives },
+		Directives: allDirectives,
 		DefaultInput: func() caddy.Input {
 			if Port == DefaultPort && Host != "" {
 				// by leaving the port blank in this case we give auto HTTPS
@@ -50,6 +52,23 @@ func init() {
 	caddytls.RegisterConfigGetter(serverType, func(c *caddy.Controller) *caddytls.Config { return GetConfig(c).TLS })
 }
 
+// noteMiddlewareOnce makes sure that the middleware of sites
+// is attributed to directives only once.
+var noteMiddlewareOnce sync.Once
+
+// noteMiddlewareDirective returns a callback that attributes the
+// middleware added to sites since the previous directive to dir.
+func noteMiddlewareDirective(dir string) caddy.ParsingCallback {
+	return func(cctx caddy.Context) error {
+		for _, cfg := range cctx.(*httpContext).siteConfigs {
+			for len(cfg.middlewareDirectives) < len(cfg.middleware) {
+				cfg.middlewareDirectives = append(cfg.middlewareDirectives, dir)
+			}
+		}
+		return nil
+	}
+}
+
 // hideCaddyfile hides the source/origin Caddyfile if it is within the
 // site root. This function should be run after parsing the root directive.
 func hideCaddyfile(cctx caddy.Context) error {
@@ -75,6 +94,12 @@ func hideCaddyfile(cctx caddy.Context) error {
 }
 
 func newContext() caddy.Context {
+	// by now, all directives are registered
+	noteMiddlewareOnce.Do(func() {
+		for _, dir := range allDirectives() {
+			caddy.RegisterParsingCallback(serverType, dir, noteMiddlewareDirective(dir))
+		}
+	})
 	return &httpContext{keysToSiteConfigs: make(map[string]*SiteConfig)}
 }
 
@@ -125,6 +150,7 @@ func (h *httpContext) InspectServerBlocks(sourceFile string, serverBlocks []cadd
 				Root:            Root,
 				TLS:             &caddytls.Config{Hostname: addr.Host},
 				originCaddyfile: sourceFile,
+				indexHandlers:   usesPlaceholder(sb, HandlerIndexPlaceholder),
 			}
 			h.saveConfig(key, cfg)
 		}
@@ -145,6 +171,19 @@ func (h *httpContext) InspectServerBlocks(sourceFile string, serverBlocks []cadd
 	return serverBlocks, nil
 }
 
+// usesPlaceholder reports whether the placeholder, without
+// braces, appears anywhere in the server block sb.
+func usesPlaceholder(sb caddyfile.ServerBlock, placeholder string) bool {
+	for _, tokens := range sb.Tokens {
+		for _, token := range tokens {
+			if strings.Contains(token.Text, "{"+placeholder+"}") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // MakeServers uses the newly-created siteConfigs to
 // create and return a list of server instances.
 func (h *httpContext) MakeServers() ([]caddy.Server, error) {
@@ -179,6 +218,16 @@ func (h *httpContext) MakeServers() ([]caddy.Server, error) {
 		return nil, err
 	}
 
+	if LogMiddlewareOrder {
+		for _, cfg := range h.siteConfigs {
+			order := "none"
+			if dirs := cfg.MiddlewareDirectives(); len(dirs) > 0 {
+				order = strings.Join(dirs, " > ")
+			}
+			log.Printf("[INFO] Middleware of %s, in the order it handles requests: %s", cfg.Addr, order)
+		}
+	}
+
 	// then we create a server for each group
 	var servers []caddy.Server
 	for addr, group := range groups {
@@ -412,6 +461,147 @@ func RegisterDevDirective(name, before string) {
 	fmt.Printf("[DEV NOTICE] %s\n", msg)
 }
 
+// RegisterDirectiveBefore registers the directive name to execute
+// immediately before the directive before, which may be a directive
+// of another plugin. Unlike with RegisterDevDirective, the order of
+// the directives does not depend on the order in which plugins are
+// initialized: it is resolved once all of them are, and directives
+// placed at the same position keep the order they were registered in.
+// Directive names must be lower-cased and unique; if they are not, or
+// the directives can't be ordered because they are placed relative to
+// each other in a cycle, that is fatal when the directives are used.
+func RegisterDirectiveBefore(name, before string) {
+	registerDirectivePlacement(directivePlacement{name: name, other: before})
+}
+
+// RegisterDirectiveAfter registers the directive name to execute
+// immediately after the directive after, like RegisterDirectiveBefore.
+func RegisterDirectiveAfter(name, after string) {
+	registerDirectivePlacement(directivePlacement{name: name, other: after, after: true})
+}
+
+// directivePlacement is the position of a directive relative
+// to another one.
+type directivePlacement struct {
+	name  string
+	other string
+	after bool // otherwise before other
+}
+
+// String describes p for error messages.
+func (p directivePlacement) String() string {
+	if p.after {
+		return fmt.Sprintf("'%s' after '%s'", p.name, p.other)
+	}
+	return fmt.Sprintf("'%s' before '%s'", p.name, p.other)
+}
+
+// registerDirectivePlacement adds p to directivePlacements.
+func registerDirectivePlacement(p directivePlacement) {
+	if p.name == "" || p.other == "" {
+		fmt.Println("[FATAL] Cannot register empty directive name")
+		os.Exit(1)
+	}
+	if strings.ToLower(p.name) != p.name {
+		fmt.Printf("[FATAL] %s: directive name must be lowercase\n", p.name)
+		os.Exit(1)
+	}
+	directivePlacements = append(directivePlacements, p)
+}
+
+var (
+	// directivePlacements are the directives registered
+	// relative to others, in the order they were registered.
+	directivePlacements []directivePlacement
+
+	// placeDirectivesOnce places them in directives.
+	placeDirectivesOnce sync.Once
+)
+
+// allDirectives returns directives, with the directives of
+// directivePlacements placed in it the first time.
+func allDirectives() []string {
+	placeDirectivesOnce.Do(func() {
+		dirs, err := placeDirectives(directives, directivePlacements)
+		if err != nil {
+			fmt.Printf("[FATAL] %v\n", err)
+			os.Exit(1)
+		}
+		directives = dirs
+	})
+	return directives
+}
+
+// placeDirectives returns a copy of dirs with the directives of
+// placements placed in it. It is an error if a directive is
+// registered twice, is placed relative to one that doesn't exist,
+// or is part of a cycle of directives placed relative to each other.
+func placeDirectives(dirs []string, placements []directivePlacement) ([]string, error) {
+	exists := make(map[string]bool)
+	for _, dir := range dirs {
+		exists[dir] = true
+	}
+	placed := make(map[string]directivePlacement)
+	for _, p := range placements {
+		if _, ok := placed[p.name]; ok || exists[p.name] {
+			return nil, fmt.Errorf("%s: directive name already exists", p.name)
+		}
+		placed[p.name] = p
+	}
+
+	// follow each placement to a directive of dirs
+	for _, p := range placements {
+		last := p
+		chain := []string{p.String()}
+		seen := map[string]bool{p.name: true}
+		for q, ok := placed[last.other]; ok; q, ok = placed[last.other] {
+			chain = append(chain, q.String())
+			if seen[q.name] {
+				return nil, fmt.Errorf("%s: directives placed in a cycle: %s", p.name, strings.Join(chain, ", "))
+			}
+			seen[q.name] = true
+			last = q
+		}
+		if !exists[last.other] {
+			return nil, fmt.Errorf("%s: cannot place %s: directive not found", last.name, last)
+		}
+	}
+
+	result := append([]string(nil), dirs...)
+	placedAfter := make(map[string]string) // of directives placed after another
+	for remaining := placements; len(remaining) > 0; {
+		var later []directivePlacement
+		for _, p := range remaining {
+			i := indexOf(result, p.other)
+			if i < 0 {
+				// placed relative to a directive yet to be placed
+				later = append(later, p)
+				continue
+			}
+			if p.after {
+				i++
+				for i < len(result) && placedAfter[result[i]] == p.other {
+					i++
+				}
+				placedAfter[p.name] = p.other
+			}
+			result = append(result[:i], append([]string{p.name}, result[i:]...)...)
+		}
+		remaining = later
+	}
+	return result, nil
+}
+
+// indexOf returns the index of s in list, or -1.
+func indexOf(list []string, s string) int {
+	for i, item := range list {
+		if item == s {
+			return i
+		}
+	}
+	return -1
+}
+
 // directives is the list of all directives known to exist for the
 // http server type, including non-standard (3rd-party) directives.
 // The ordering of this list is important.
@@ -420,8 +610,21 @@ var directives = []string{
 	"root",
 	"bind",
 	"maxrequestbody", // TODO: 'limits'
+	"max_request_header",
 	"timeouts",
+	"conn_limit",
+	"max_conns",
+	"proxy_protocol",
+	"tcp",
+	"slow_requests",
+	"request_timeout",
+	"trusted_proxies",
+	"precompressed",
+	"hidden",
+	"trailing_slash",
+	"index",
 	"tls",
+	"matcher",
 
 	// services/utilities, or other directives that don't necessarily inject handlers
 	"startup",
@@ -432,18 +635,41 @@ var directives = []string{
 	// directives that add middleware to the stack
 	"locale", // github.com/simia-tech/caddy-locale
 	"log",
+	"recover",
+	"server_timing",
+	"fault",
+	"maintenance",
+	"enforce_https",
+	"canonical_host",
+	"method_override",
+	"cors_policy",
+	"require_header",
+	"require_content_type",
+	"jwt_auth",
+	"access",
+	"throttle",
+	"lang",
 	"rewrite",
 	"ext",
 	"gzip",
+	"etag",
+	"hsts",
+	"alt_svc",
+	"csp",
 	"header",
+	"cache_control",
 	"errors",
-	"filter",    // github.com/echocat/caddy-filter
+	"concurrency",
+	"sub",
+	"filter", // github.com/echocat/caddy-filter
+	"minify_responses",
 	"minify",    // github.com/hacdias/caddy-minify
 	"ipfilter",  // github.com/pyed/ipfilter
 	"ratelimit", // github.com/xuqingfeng/caddy-rate-limit
 	"search",    // github.com/pedronasser/caddy-search
 	"expires",   // github.com/epicagency/caddy-expires
 	"basicauth",
+	"digestauth",
 	"redir",
 	"status",
 	"cors", // github.com/captncraig/cors/caddy
@@ -455,7 +681,10 @@ var directives = []string{
 	"internal",
 	"pprof",
 	"expvar",
+	"push",
 	"prometheus", // github.com/miekg/caddy-prometheus
+	"strip_prefix",
+	"mirror",
 	"proxy",
 	"fastcgi",
 	"websocket",
@@ -497,4 +726,8 @@ var (
 
 	// QUIC indicates whether QUIC is enabled or not.
 	QUIC bool
+
+	// LogMiddlewareOrder indicates whether the order of the
+	// middleware of each site is logged when servers are made.
+	LogMiddlewareOrder bool
 )
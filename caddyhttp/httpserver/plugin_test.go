@@ -1,6 +1,7 @@
 package httpserver
 
 import (
+	"reflect"
 	"strings"
 	"testing"
 
@@ -153,6 +154,25 @@ func TestInspectServerBlocksCaseInsensitiveKey(t *testing.T) {
 	}
 }
 
+func TestInspectServerBlocksHandlerIndex(t *testing.T) {
+	filename := "Testfile"
+	ctx := newContext().(*httpContext)
+	input := strings.NewReader("a.com {\n\tlog / stdout \"{handler_index} {uri}\"\n}\nb.com {\n\tlog / stdout \"{uri}\"\n}")
+	sblocks, err := caddyfile.Parse(filename, input, []string{"log"})
+	if err != nil {
+		t.Fatalf("Expected no error setting up test, got: %v", err)
+	}
+	if _, err = ctx.InspectServerBlocks(filename, sblocks); err != nil {
+		t.Fatalf("Didn't expect an error, but got: %v", err)
+	}
+	if !ctx.keysToSiteConfigs["a.com"].indexHandlers {
+		t.Error("Expected handlers to be indexed for a site that uses {handler_index}")
+	}
+	if ctx.keysToSiteConfigs["b.com"].indexHandlers {
+		t.Error("Expected handlers not to be indexed for a site that doesn't use {handler_index}")
+	}
+}
+
 func TestGetConfig(t *testing.T) {
 	// case insensitivity for key
 	con := caddy.NewTestController("http", "")
@@ -209,3 +229,118 @@ func TestContextSaveConfig(t *testing.T) {
 		t.Errorf("Expected len(siteConfigs) == %d, but was %d", want, got)
 	}
 }
+
+func TestMiddlewareDirectives(t *testing.T) {
+	ctx := newContext().(*httpContext)
+	a, b := new(SiteConfig), new(SiteConfig)
+	ctx.saveConfig("a", a)
+	ctx.saveConfig("b", b)
+
+	// the directives of the sites, in the order of the Caddyfile,
+	// and how many middleware they add
+	sites := map[*SiteConfig]map[string]int{
+		a: {"gzip": 1, "redir": 1, "tls": 0, "rewrite": 1, "header": 2},
+		b: {"errors": 1, "basicauth": 1},
+	}
+
+	// executed as caddy does: each directive for all sites,
+	// then its parsing callbacks
+	for _, dir := range directives {
+		for cfg, dirs := range sites {
+			for i := 0; i < dirs[dir]; i++ {
+				cfg.AddMiddleware(func(next Handler) Handler { return next })
+			}
+		}
+		if err := noteMiddlewareDirective(dir)(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// middleware added after the directives is unknown
+	b.AddMiddleware(func(next Handler) Handler { return next })
+
+	for i, test := range []struct {
+		cfg      *SiteConfig
+		expected []string
+	}{
+		{a, []string{"rewrite", "gzip", "header", "header", "redir"}},
+		{b, []string{"errors", "basicauth", "?"}},
+	} {
+		got := test.cfg.MiddlewareDirectives()
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("Test %d: Expected %v, got %v", i, test.expected, got)
+		}
+		// which is the order of the directives
+		for j := 1; j < len(got); j++ {
+			if got[j] != "?" && directiveIndex(got[j-1]) > directiveIndex(got[j]) {
+				t.Errorf("Test %d: Expected %s to come after %s in the directives", i, got[j-1], got[j])
+			}
+		}
+	}
+}
+
+func directiveIndex(dir string) int {
+	for i, d := range directives {
+		if d == dir {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestPlaceDirectives(t *testing.T) {
+	dirs := []string{"root", "rewrite", "gzip", "header"}
+	before := func(name, other string) directivePlacement {
+		return directivePlacement{name: name, other: other}
+	}
+	after := func(name, other string) directivePlacement {
+		return directivePlacement{name: name, other: other, after: true}
+	}
+
+	for i, test := range []struct {
+		placements []directivePlacement
+		expected   []string
+		shouldErr  bool
+	}{
+		{nil, dirs, false},
+		{[]directivePlacement{before("a", "gzip"), after("b", "rewrite")},
+			[]string{"root", "rewrite", "b", "a", "gzip", "header"}, false},
+		// first and last
+		{[]directivePlacement{before("a", "root"), after("b", "header")},
+			[]string{"a", "root", "rewrite", "gzip", "header", "b"}, false},
+		// at the same position, in the order they were registered
+		{[]directivePlacement{before("a", "gzip"), before("b", "gzip"), after("c", "gzip"), after("d", "gzip")},
+			[]string{"root", "rewrite", "a", "b", "gzip", "c", "d", "header"}, false},
+		// relative to directives registered later
+		{[]directivePlacement{after("a", "b"), before("b", "c"), after("c", "rewrite")},
+			[]string{"root", "rewrite", "b", "a", "c", "gzip", "header"}, false},
+		// duplicates
+		{[]directivePlacement{before("gzip", "header")}, nil, true},
+		{[]directivePlacement{before("a", "header"), after("a", "root")}, nil, true},
+		// not found
+		{[]directivePlacement{before("a", "proxy")}, nil, true},
+		{[]directivePlacement{before("a", "b"), after("b", "proxy")}, nil, true},
+		// cycles
+		{[]directivePlacement{before("a", "a")}, nil, true},
+		{[]directivePlacement{before("a", "b"), after("b", "a")}, nil, true},
+		{[]directivePlacement{after("a", "gzip"), before("b", "c"), before("c", "d"), after("d", "b")}, nil, true},
+	} {
+		got, err := placeDirectives(dirs, test.placements)
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: Expected an error, got %v", i, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: Expected no error, got: %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("Test %d: Expected %v, got %v", i, test.expected, got)
+		}
+	}
+
+	if dirs[2] != "gzip" || len(dirs) != 4 {
+		t.Errorf("Expected the directives not to change, got %v", dirs)
+	}
+}
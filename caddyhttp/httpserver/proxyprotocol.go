@@ -0,0 +1,214 @@
+package httpserver
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// proxyProtocolListener is a net.Listener for connections from
+// load balancers and proxies that start with a PROXY protocol
+// header (version 1 or 2), which tells the address of the client
+// that connected to them. Only the headers of trusted peers are
+// read; connections from other peers are left as they are, so a
+// header they send is not mistaken for the truth.
+type proxyProtocolListener struct {
+	net.Listener
+	trusted []*net.IPNet
+	timeout time.Duration // for the header; 0 means proxyHeaderTimeout
+}
+
+// Accept accepts a connection. If it's from a trusted peer, its
+// header is read when it's first read from or asked for its
+// remote address, in the goroutine that serves it.
+func (ln proxyProtocolListener) Accept() (net.Conn, error) {
+	c, err := ln.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	host, _, err := net.SplitHostPort(c.RemoteAddr().String())
+	if err != nil || !ipInNets(net.ParseIP(host), ln.trusted) {
+		return c, nil
+	}
+	timeout := ln.timeout
+	if timeout == 0 {
+		timeout = proxyHeaderTimeout
+	}
+	return &proxyProtocolConn{Conn: c, timeout: timeout}, nil
+}
+
+// proxyProtocolConn is a connection from a trusted peer whose
+// remote address is that of the client in its PROXY header.
+type proxyProtocolConn struct {
+	net.Conn
+	timeout time.Duration
+	once    sync.Once
+	r       *bufio.Reader
+	remote  net.Addr
+	err     error
+
+	// allow, if set, reports whether the client may connect;
+	// it is how a connection limit applies to the client
+	// rather than to the peer
+	allow func(net.Addr) bool
+}
+
+// readHeader reads the header of c, once. A peer that sends less
+// than a header does not hold on to the goroutine that serves c
+// for longer than the timeout.
+func (c *proxyProtocolConn) readHeader() {
+	c.once.Do(func() {
+		c.r = bufio.NewReader(c.Conn)
+		c.Conn.SetReadDeadline(time.Now().Add(c.timeout))
+		c.remote, c.err = readProxyHeader(c.r)
+		c.Conn.SetReadDeadline(time.Time{})
+		if c.remote == nil {
+			c.remote = c.Conn.RemoteAddr()
+		}
+		if c.err == nil && c.allow != nil && !c.allow(c.remote) {
+			c.err = errConnLimited
+			c.Conn.Close()
+		}
+	})
+}
+
+// Read reads from c after its header. If the header is not
+// valid, nothing can be read.
+func (c *proxyProtocolConn) Read(p []byte) (int, error) {
+	c.readHeader()
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.r.Read(p)
+}
+
+// RemoteAddr returns the address of the client, or that of the
+// peer if its header does not tell it.
+func (c *proxyProtocolConn) RemoteAddr() net.Addr {
+	c.readHeader()
+	return c.remote
+}
+
+// readProxyHeader reads a PROXY protocol header from r and
+// returns the source address it conveys. The address is nil if
+// the header does not convey one: for a health check of the
+// proxy itself, or for a transport that is UNKNOWN.
+func readProxyHeader(r *bufio.Reader) (net.Addr, error) {
+	sig, err := r.Peek(len(proxyV2Signature))
+	if err != nil {
+		return nil, fmt.Errorf("reading PROXY protocol header: %v", err)
+	}
+	if bytes.Equal(sig, proxyV2Signature) {
+		return readProxyHeaderV2(r)
+	}
+	if bytes.HasPrefix(sig, []byte("PROXY ")) {
+		return readProxyHeaderV1(r)
+	}
+	return nil, errNoProxyHeader
+}
+
+// readProxyHeaderV1 reads a header in the text format, like
+// "PROXY TCP4 192.0.2.1 198.51.100.1 56324 443\r\n".
+func readProxyHeaderV1(r *bufio.Reader) (net.Addr, error) {
+	var line []byte
+	for len(line) < proxyV1MaxLength {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("reading PROXY protocol header: %v", err)
+		}
+		line = append(line, b)
+		if b == '\n' {
+			break
+		}
+	}
+	if !bytes.HasSuffix(line, []byte("\r\n")) {
+		return nil, errors.New("PROXY protocol header is too long or not terminated by CRLF")
+	}
+
+	fields := strings.Split(string(line[:len(line)-2]), " ")
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return nil, nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, fmt.Errorf("malformed PROXY protocol header: %q", line)
+	}
+	ip := net.ParseIP(fields[2])
+	if ip == nil || (ip.To4() != nil) != (fields[1] == "TCP4") || net.ParseIP(fields[3]) == nil {
+		return nil, fmt.Errorf("malformed PROXY protocol header: %q", line)
+	}
+	port, err := strconv.ParseUint(fields[4], 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("malformed PROXY protocol header: %q", line)
+	}
+	if _, err := strconv.ParseUint(fields[5], 10, 16); err != nil {
+		return nil, fmt.Errorf("malformed PROXY protocol header: %q", line)
+	}
+	return &net.TCPAddr{IP: ip, Port: int(port)}, nil
+}
+
+// readProxyHeaderV2 reads a header in the binary format.
+func readProxyHeaderV2(r *bufio.Reader) (net.Addr, error) {
+	var header [16]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, fmt.Errorf("reading PROXY protocol header: %v", err)
+	}
+	if version := header[12] >> 4; version != 2 {
+		return nil, fmt.Errorf("unsupported PROXY protocol version %d", version)
+	}
+	command, family := header[12]&0x0f, header[13]>>4
+	addresses := make([]byte, binary.BigEndian.Uint16(header[14:16]))
+	if _, err := io.ReadFull(r, addresses); err != nil {
+		return nil, fmt.Errorf("reading PROXY protocol addresses: %v", err)
+	}
+
+	switch command {
+	case 0x0: // LOCAL, like a health check
+		return nil, nil
+	case 0x1: // PROXY
+	default:
+		return nil, fmt.Errorf("unsupported PROXY protocol command %d", command)
+	}
+
+	// the source address and port, after which come the destination
+	// address and port and possibly extensions, which are ignored
+	switch family {
+	case 0x1: // AF_INET
+		if len(addresses) < 12 {
+			return nil, errors.New("PROXY protocol header too short for IPv4 addresses")
+		}
+		return &net.TCPAddr{IP: net.IP(addresses[0:4]), Port: int(binary.BigEndian.Uint16(addresses[8:10]))}, nil
+	case 0x2: // AF_INET6
+		if len(addresses) < 36 {
+			return nil, errors.New("PROXY protocol header too short for IPv6 addresses")
+		}
+		return &net.TCPAddr{IP: net.IP(addresses[0:16]), Port: int(binary.BigEndian.Uint16(addresses[32:34]))}, nil
+	}
+	// AF_UNSPEC or AF_UNIX, which has no address to speak of
+	return nil, nil
+}
+
+var (
+	// proxyV2Signature starts every version 2 header.
+	proxyV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+	errNoProxyHeader = errors.New("connection from trusted peer did not start with a PROXY protocol header")
+	errConnLimited   = errors.New("client of PROXY protocol connection is over its connection limit")
+)
+
+const (
+	// proxyV1MaxLength is the length of the longest valid
+	// version 1 header, including the CRLF.
+	proxyV1MaxLength = 107
+
+	// proxyHeaderTimeout is how long a trusted peer may
+	// take to send its header.
+	proxyHeaderTimeout = 10 * time.Second
+)
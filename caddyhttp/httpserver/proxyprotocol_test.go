@@ -0,0 +1,184 @@
+package httpserver
+
+import (
+	"bufio"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// proxyTestConn dials a proxyProtocolListener on the loopback
+// interface that trusts peers in trusted, writes data to it and
+// returns the connection it accepted.
+func proxyTestConn(t *testing.T, trusted string, data string) net.Conn {
+	ipnet, err := ParseCIDR(trusted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tcpLn, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tcpLn.Close()
+	ln := proxyProtocolListener{Listener: tcpLn, trusted: []*net.IPNet{ipnet}}
+
+	client, err := net.Dial("tcp", tcpLn.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Write([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	client.Close()
+
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+func TestProxyProtocolListener(t *testing.T) {
+	v2 := func(command, family byte, addresses ...byte) string {
+		header := append([]byte(nil), proxyV2Signature...)
+		header = append(header, 0x20|command, family, 0, byte(len(addresses)))
+		return string(append(header, addresses...))
+	}
+
+	for i, test := range []struct {
+		data           string
+		expectedRemote string // empty for the peer
+		shouldErr      bool
+	}{
+		// version 1
+		{"PROXY TCP4 192.0.2.1 198.51.100.1 56324 443\r\nGET / HTTP/1.1\r\n", "192.0.2.1:56324", false},
+		{"PROXY TCP6 2001:db8::1 2001:db8::2 56324 443\r\nGET / HTTP/1.1\r\n", "[2001:db8::1]:56324", false},
+		{"PROXY UNKNOWN\r\nGET / HTTP/1.1\r\n", "", false},
+		{"PROXY UNKNOWN 192.0.2.1 198.51.100.1 56324 443\r\nGET / HTTP/1.1\r\n", "", false},
+		// version 2
+		{v2(0x1, 0x11, 192, 0, 2, 1, 198, 51, 100, 1, 0xdc, 0x04, 0x01, 0xbb) + "GET / HTTP/1.1\r\n", "192.0.2.1:56324", false},
+		{v2(0x1, 0x21,
+			0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1,
+			0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 2,
+			0xdc, 0x04, 0x01, 0xbb) + "GET / HTTP/1.1\r\n", "[2001:db8::1]:56324", false},
+		{v2(0x1, 0x11, 192, 0, 2, 1, 198, 51, 100, 1, 0xdc, 0x04, 0x01, 0xbb, 0x04, 0x00, 0x01, 0xff) + "GET / HTTP/1.1\r\n", "192.0.2.1:56324", false}, // with an extension
+		{v2(0x0, 0x00) + "GET / HTTP/1.1\r\n", "", false}, // LOCAL
+		{v2(0x0, 0x11, 192, 0, 2, 1, 198, 51, 100, 1, 0xdc, 0x04, 0x01, 0xbb) + "GET / HTTP/1.1\r\n", "", false},
+		// a trusted peer must send a header
+		{"GET / HTTP/1.1\r\n", "", true},
+		{"PROXY TCP4 192.0.2.1\r\nGET / HTTP/1.1\r\n", "", true},
+	} {
+		conn := proxyTestConn(t, "127.0.0.0/8", test.data)
+
+		remote := conn.RemoteAddr().String()
+		if test.expectedRemote == "" {
+			if !strings.HasPrefix(remote, "127.0.0.1:") {
+				t.Errorf("Test %d: Expected remote address of the peer, got %s", i, remote)
+			}
+		} else if remote != test.expectedRemote {
+			t.Errorf("Test %d: Expected remote address %s, got %s", i, test.expectedRemote, remote)
+		}
+
+		rest, err := ioutil.ReadAll(conn)
+		conn.Close()
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: Expected an error reading the connection, got %q", i, rest)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: Expected no error reading the connection, got: %v", i, err)
+		}
+		if string(rest) != "GET / HTTP/1.1\r\n" {
+			t.Errorf("Test %d: Expected the request after the header, got %q", i, rest)
+		}
+	}
+}
+
+func TestProxyProtocolListenerUntrusted(t *testing.T) {
+	const data = "PROXY TCP4 192.0.2.1 198.51.100.1 56324 443\r\nGET / HTTP/1.1\r\n"
+	conn := proxyTestConn(t, "10.0.0.0/8", data)
+	defer conn.Close()
+
+	if remote := conn.RemoteAddr().String(); !strings.HasPrefix(remote, "127.0.0.1:") {
+		t.Errorf("Expected remote address of the untrusted peer, got %s", remote)
+	}
+	rest, err := ioutil.ReadAll(conn)
+	if err != nil {
+		t.Fatalf("Expected no error reading the connection, got: %v", err)
+	}
+	if string(rest) != data {
+		t.Errorf("Expected the header of an untrusted peer to be left in the connection, got %q", rest)
+	}
+}
+
+func TestReadProxyHeaderMalformed(t *testing.T) {
+	for i, data := range []string{
+		"",
+		"PROXY",
+		"PROXY TCP4 192.0.2.1 198.51.100.1 56324 443\n",
+		"PROXY TCP4 192.0.2.1 198.51.100.1 56324 443",
+		"PROXY TCP4 2001:db8::1 198.51.100.1 56324 443\r\n",
+		"PROXY TCP6 192.0.2.1 198.51.100.1 56324 443\r\n",
+		"PROXY TCP4 192.0.2.1 198.51.100.1 65536 443\r\n",
+		"PROXY TCP4 192.0.2.1 198.51.100.1 56324\r\n",
+		"PROXY TCP4 192.0.2.1 nowhere 56324 443\r\n",
+		"PROXY UDP4 192.0.2.1 198.51.100.1 56324 443\r\n",
+		"PROXY TCP4 192.0.2.1 198.51.100.1 56324 443" + strings.Repeat(" ", 100) + "\r\n",
+		string(proxyV2Signature) + "\x11\x11\x00\x0c" + strings.Repeat("\x00", 12), // version 1 in binary
+		string(proxyV2Signature) + "\x22\x11\x00\x0c" + strings.Repeat("\x00", 12), // unknown command
+		string(proxyV2Signature) + "\x21\x11\x00\x04" + strings.Repeat("\x00", 4),  // too short for IPv4
+		string(proxyV2Signature) + "\x21\x21\x00\x0c" + strings.Repeat("\x00", 12), // too short for IPv6
+		string(proxyV2Signature) + "\x21\x11\x00\x0c" + strings.Repeat("\x00", 8),  // truncated
+	} {
+		if addr, err := readProxyHeader(bufio.NewReader(strings.NewReader(data))); err == nil {
+			t.Errorf("Test %d: Expected an error for %q, got address %v", i, data, addr)
+		}
+	}
+}
+
+func TestProxyProtocolListenerHeaderTimeout(t *testing.T) {
+	tcpLn, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tcpLn.Close()
+	ln := proxyProtocolListener{
+		Listener: tcpLn,
+		trusted:  []*net.IPNet{{IP: net.IP{127, 0, 0, 0}, Mask: net.CIDRMask(8, 32)}},
+		timeout:  50 * time.Millisecond,
+	}
+
+	// a peer that sends less than a signature and waits
+	client, err := net.Dial("tcp", tcpLn.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if _, err := client.Write([]byte("PRO")); err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	done := make(chan error)
+	go func() {
+		_, err := ioutil.ReadAll(conn)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("Expected an error reading a connection without a complete header")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected reading the header to time out")
+	}
+}
@@ -2,8 +2,10 @@ package httpserver
 
 import (
 	"bufio"
+	"bytes"
 	"net"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -19,12 +21,25 @@ import (
 // placeholder values for logging utilities to use.
 //
 // Beware when accessing the Replacer value; it may be nil!
+//
+// By default, a ResponseRecorder passes everything through to the
+// underlying ResponseWriter as it is written. Middleware that needs
+// the whole response before writing it (to transform the body, for
+// example) can use NewBufferedResponseRecorder instead.
 type ResponseRecorder struct {
 	http.ResponseWriter
 	Replacer Replacer
 	status   int
 	size     int
 	start    time.Time
+	trailers []string // trailer names declared before the header was written
+
+	wroteHeader bool
+	buf         *bytes.Buffer // non-nil while the response is buffered
+	bufLimit    int           // if > 0, the most that is buffered
+
+	compressed       bool // whether uncompressedSize was counted
+	uncompressedSize int
 }
 
 // NewResponseRecorder makes and returns a new responseRecorder,
@@ -41,16 +56,69 @@ func NewResponseRecorder(w http.ResponseWriter) *ResponseRecorder {
 	}
 }
 
-// WriteHeader records the status code and calls the
-// underlying ResponseWriter's WriteHeader method.
+// NewBufferedResponseRecorder makes and returns a new ResponseRecorder
+// that holds back the status and body written to it, so that they can
+// be inspected or changed before being written with Release. Only the
+// header map is shared with w, as it is not sent until Release writes
+// the status.
+//
+// If the response is flushed while it is buffered (for streaming
+// responses like server-sent events), whatever has been buffered is
+// released and the recorder passes everything through from then on,
+// so flushes still reach the client promptly. Middleware should check
+// Buffered after calling the next handler to know whether it still
+// holds the whole response.
+func NewBufferedResponseRecorder(w http.ResponseWriter) *ResponseRecorder {
+	r := NewResponseRecorder(w)
+	r.buf = new(bytes.Buffer)
+	return r
+}
+
+// WriteHeader records the status code and any declared trailers,
+// then calls the underlying ResponseWriter's WriteHeader method
+// unless the response is being buffered.
 func (r *ResponseRecorder) WriteHeader(status int) {
 	r.status = status
-	r.ResponseWriter.WriteHeader(status)
+	if !r.wroteHeader {
+		r.wroteHeader = true
+		r.recordTrailers()
+	}
+	if r.buf == nil {
+		r.ResponseWriter.WriteHeader(status)
+	}
+}
+
+// recordTrailers records the trailer names declared in the Trailer
+// header, which must happen before the header is written.
+func (r *ResponseRecorder) recordTrailers() {
+	for _, declared := range r.Header()["Trailer"] {
+		for _, name := range strings.Split(declared, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				r.trailers = append(r.trailers, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
 }
 
 // Write is a wrapper that records the size of the body
-// that gets written.
+// that gets written. If the response is being buffered,
+// buf is appended to the buffer instead; its size is
+// recorded when it is released.
 func (r *ResponseRecorder) Write(buf []byte) (int, error) {
+	if !r.wroteHeader {
+		// the underlying Write will write the header implicitly
+		r.wroteHeader = true
+		r.recordTrailers()
+	}
+	if r.buf != nil {
+		if r.bufLimit <= 0 || r.buf.Len()+len(buf) <= r.bufLimit {
+			return r.buf.Write(buf)
+		}
+		// too big to hold on to; send what we have
+		if err := r.Release(); err != nil {
+			return 0, err
+		}
+	}
 	n, err := r.ResponseWriter.Write(buf)
 	if err == nil {
 		r.size += n
@@ -58,16 +126,103 @@ func (r *ResponseRecorder) Write(buf []byte) (int, error) {
 	return n, err
 }
 
+// SetBufferLimit limits how much of the body r buffers to n
+// bytes. A write that would exceed the limit releases the
+// response instead, as if it were flushed. If n is 0, there
+// is no limit.
+func (r *ResponseRecorder) SetBufferLimit(n int) {
+	r.bufLimit = n
+}
+
+// Buffered returns true if r is holding back the response.
+// It is always false for recorders that are not buffered,
+// and becomes false once a buffered response is released.
+func (r *ResponseRecorder) Buffered() bool {
+	return r.buf != nil
+}
+
+// Buffer returns the body buffered so far, which may be modified
+// before it is released. It returns nil if r is not buffering.
+func (r *ResponseRecorder) Buffer() *bytes.Buffer {
+	return r.buf
+}
+
+// Release writes the buffered status and body to the underlying
+// ResponseWriter, after which r passes everything through. If
+// nothing was written to r, nothing is written to the underlying
+// ResponseWriter either, so that the status returned by the handler
+// can still be handled. Release does nothing if r is not buffering.
+func (r *ResponseRecorder) Release() error {
+	if r.buf == nil {
+		return nil
+	}
+	buf := r.buf
+	r.buf = nil
+	if !r.wroteHeader {
+		return nil
+	}
+	r.ResponseWriter.WriteHeader(r.status)
+	if buf.Len() == 0 {
+		return nil
+	}
+	_, err := r.Write(buf.Bytes())
+	return err
+}
+
 // Size is a Getter to size property
 func (r *ResponseRecorder) Size() int {
 	return r.size
 }
 
+// CountUncompressed adds n to the size of the body before it was
+// compressed. Middleware that compresses the response calls it on
+// the server's ResponseRecorder (see ResponseRecorderCtxKey) for
+// every write it compresses, so that both sizes can be logged.
+func (r *ResponseRecorder) CountUncompressed(n int) {
+	r.compressed = true
+	r.uncompressedSize += n
+}
+
+// UncompressedSize returns the size of the body before compression.
+// It is the same as Size if the response was not compressed.
+func (r *ResponseRecorder) UncompressedSize() int {
+	if r.compressed {
+		return r.uncompressedSize
+	}
+	return r.size
+}
+
 // Status is a Getter to status property
 func (r *ResponseRecorder) Status() int {
 	return r.status
 }
 
+// Trailer returns the trailers set on the response so far. Trailers
+// are the values of the names declared in the Trailer header before
+// the header was written, plus any header set with the "Trailer:"
+// prefix (Go 1.8's convention for trailers not known in advance). Since
+// the header is shared with the underlying ResponseWriter, these go
+// out to the client with it when the response is finished.
+func (r *ResponseRecorder) Trailer() http.Header {
+	trailer := make(http.Header)
+	header := r.Header()
+	for _, name := range r.trailers {
+		if v, ok := header[name]; ok {
+			trailer[name] = v
+		}
+	}
+	for name, v := range header {
+		if strings.HasPrefix(name, trailerPrefix) {
+			trailer[http.CanonicalHeaderKey(strings.TrimPrefix(name, trailerPrefix))] = v
+		}
+	}
+	return trailer
+}
+
+// trailerPrefix is the magic prefix for header keys that
+// net/http sends as trailers instead of headers.
+const trailerPrefix = "Trailer:"
+
 // Hijack implements http.Hijacker. It simply wraps the underlying
 // ResponseWriter's Hijack method if there is one, or returns an error.
 func (r *ResponseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
@@ -78,9 +233,17 @@ func (r *ResponseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 }
 
 // Flush implements http.Flusher. It simply wraps the underlying
-// ResponseWriter's Flush method if there is one, or does nothing.
+// ResponseWriter's Flush method if there is one, or panics. If the
+// response is being buffered, the buffer is released first, since
+// flushing means the client should get what was written right away.
 func (r *ResponseRecorder) Flush() {
 	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		if r.buf != nil {
+			if !r.wroteHeader {
+				r.WriteHeader(http.StatusOK)
+			}
+			r.Release()
+		}
 		f.Flush()
 	} else {
 		panic(NonFlusherError{Underlying: r.ResponseWriter}) // should be recovered at the beginning of middleware stack
@@ -0,0 +1,14 @@
+// +build go1.8
+
+package httpserver
+
+import "net/http"
+
+// Push implements http.Pusher. It simply wraps the underlying
+// ResponseWriter's Push method if there is one, or returns an error.
+func (r *ResponseRecorder) Push(target string, opts *http.PushOptions) error {
+	if p, ok := r.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return NonPusherError{Underlying: r.ResponseWriter}
+}
@@ -0,0 +1,41 @@
+// +build go1.8
+
+package httpserver
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestTrailerPrefix(t *testing.T) {
+	var rr *ResponseRecorder
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rr = NewResponseRecorder(w)
+		rr.Header().Set("Trailer", "X-Declared")
+		rr.Write([]byte("body"))
+		rr.Header().Set("X-Declared", "1")
+		rr.Header().Set(http.TrailerPrefix+"X-Late", "2")
+	}))
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	expected := http.Header{
+		"X-Declared": []string{"1"},
+		"X-Late":     []string{"2"},
+	}
+	if !reflect.DeepEqual(resp.Trailer, expected) {
+		t.Errorf("Expected client to receive trailers %v, got %v", expected, resp.Trailer)
+	}
+	if got := rr.Trailer(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected recorder to report trailers %v, got %v", expected, got)
+	}
+}
@@ -1,9 +1,13 @@
 package httpserver
 
 import (
+	"bufio"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"testing"
+	"time"
 )
 
 func TestNewResponseRecorder(t *testing.T) {
@@ -38,3 +42,173 @@ func TestWrite(t *testing.T) {
 		t.Fatalf("Expected Response Body to be %s , but found %s\n", responseTestString, w.Body.String())
 	}
 }
+
+func TestTrailer(t *testing.T) {
+	var rr *ResponseRecorder
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rr = NewResponseRecorder(w)
+		rr.Header().Set("Trailer", "Grpc-Status, grpc-message")
+		rr.WriteHeader(http.StatusOK)
+		rr.Write([]byte("body"))
+		rr.Header().Set("Grpc-Status", "0")
+		rr.Header().Set("Grpc-Message", "OK")
+		rr.Header().Set("Undeclared", "nope")
+	}))
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatalf("Reading body failed: %v", err)
+	}
+	if string(body) != "body" {
+		t.Errorf("Expected body 'body', got %q", body)
+	}
+
+	expected := http.Header{
+		"Grpc-Status":  []string{"0"},
+		"Grpc-Message": []string{"OK"},
+	}
+	if !reflect.DeepEqual(resp.Trailer, expected) {
+		t.Errorf("Expected client to receive trailers %v, got %v", expected, resp.Trailer)
+	}
+	if got := rr.Trailer(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected recorder to report trailers %v, got %v", expected, got)
+	}
+}
+
+func TestBufferedResponseRecorder(t *testing.T) {
+	w := httptest.NewRecorder()
+	rr := NewBufferedResponseRecorder(w)
+	rr.Header().Set("Content-Type", "text/plain")
+	rr.WriteHeader(http.StatusCreated)
+	rr.Write([]byte("hello"))
+
+	if !rr.Buffered() {
+		t.Fatal("Expected recorder to be buffering")
+	}
+	if w.Body.Len() != 0 || w.Code != http.StatusOK {
+		t.Fatalf("Expected nothing to be written before release, got status %d and body %q", w.Code, w.Body.String())
+	}
+	if rr.Status() != http.StatusCreated {
+		t.Errorf("Expected recorded status %d, got %d", http.StatusCreated, rr.Status())
+	}
+
+	rr.Buffer().WriteString(" world")
+	if err := rr.Release(); err != nil {
+		t.Fatalf("Expected no error releasing, got: %v", err)
+	}
+	if rr.Buffered() {
+		t.Error("Expected recorder to stop buffering after release")
+	}
+	if w.Code != http.StatusCreated || w.Body.String() != "hello world" {
+		t.Errorf("Expected status %d and body 'hello world', got %d and %q", http.StatusCreated, w.Code, w.Body.String())
+	}
+	if rr.Size() != len("hello world") {
+		t.Errorf("Expected size %d, got %d", len("hello world"), rr.Size())
+	}
+
+	// writes after release pass through
+	rr.Write([]byte("!"))
+	if w.Body.String() != "hello world!" {
+		t.Errorf("Expected write after release to pass through, got body %q", w.Body.String())
+	}
+}
+
+func TestBufferedResponseRecorderNothingWritten(t *testing.T) {
+	w := httptest.NewRecorder()
+	rr := NewBufferedResponseRecorder(w)
+	if err := rr.Release(); err != nil {
+		t.Fatalf("Expected no error releasing, got: %v", err)
+	}
+	if w.Flushed || w.Body.Len() != 0 {
+		t.Errorf("Expected nothing to be written for an empty response, got body %q", w.Body.String())
+	}
+}
+
+func TestBufferedResponseRecorderLimit(t *testing.T) {
+	w := httptest.NewRecorder()
+	rr := NewBufferedResponseRecorder(w)
+	rr.SetBufferLimit(10)
+
+	rr.WriteHeader(http.StatusAccepted)
+	rr.Write([]byte("12345"))
+	rr.Write([]byte("67890"))
+	if !rr.Buffered() || w.Body.Len() != 0 {
+		t.Fatalf("Expected response up to the limit to be buffered, got body %q", w.Body.String())
+	}
+
+	rr.Write([]byte("!"))
+	if rr.Buffered() {
+		t.Error("Expected response over the limit to be released")
+	}
+	if w.Code != http.StatusAccepted {
+		t.Errorf("Expected status %d, got %d", http.StatusAccepted, w.Code)
+	}
+	if got := w.Body.String(); got != "1234567890!" {
+		t.Errorf("Expected whole body to be written, got %q", got)
+	}
+	if rr.Size() != 11 {
+		t.Errorf("Expected size 11, got %d", rr.Size())
+	}
+}
+
+func TestResponseRecorderStreaming(t *testing.T) {
+	for _, buffered := range []bool{false, true} {
+		events := make(chan string)
+		next := make(chan struct{})
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rr := NewResponseRecorder(w)
+			if buffered {
+				rr = NewBufferedResponseRecorder(w)
+			}
+			rr.Header().Set("Content-Type", "text/event-stream")
+			for i := 0; i < 2; i++ {
+				rr.Write([]byte("data: event\n\n"))
+				rr.Flush()
+				<-next
+			}
+			if buffered && rr.Buffered() {
+				t.Error("Expected flushing to release the buffer")
+			}
+		}))
+
+		go func() {
+			resp, err := http.Get(ts.URL)
+			if err != nil {
+				t.Errorf("Request failed: %v", err)
+				close(events)
+				return
+			}
+			defer resp.Body.Close()
+			rd := bufio.NewReader(resp.Body)
+			for {
+				line, err := rd.ReadString('\n')
+				if err != nil {
+					close(events)
+					return
+				}
+				if line != "\n" {
+					events <- line
+				}
+			}
+		}()
+
+		for i := 0; i < 2; i++ {
+			select {
+			case ev := <-events:
+				if ev != "data: event\n" {
+					t.Errorf("Buffered=%t: Expected event line, got %q", buffered, ev)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("Buffered=%t: Timed out waiting for flushed event %d", buffered, i)
+			}
+			next <- struct{}{}
+		}
+		ts.Close()
+	}
+}
@@ -0,0 +1,120 @@
+package httpserver
+
+import (
+	"encoding/binary"
+	"log"
+	"net"
+	"sync"
+	"time"
+)
+
+// renegotiationListener watches the TLS records that clients
+// send on the connections it accepts, to log attempts to
+// renegotiate. crypto/tls rejects them with a no_renegotiation
+// alert, but does not tell anyone.
+type renegotiationListener struct {
+	net.Listener
+}
+
+// Accept accepts a connection and watches it.
+func (ln renegotiationListener) Accept() (net.Conn, error) {
+	c, err := ln.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &renegotiationConn{Conn: c}, nil
+}
+
+// renegotiationConn follows the record layer of the TLS stream
+// that is read from it, without decrypting anything: after the
+// client's ChangeCipherSpec, the only handshake record a client
+// sends is its Finished (with TLS 1.2; TLS 1.3 sends none), so
+// another one starts a renegotiation.
+type renegotiationConn struct {
+	net.Conn
+	header     [5]byte
+	headerLen  int // of header, while it's being read
+	body       int // left to skip of the current record
+	encrypted  bool
+	handshakes int // handshake records since encryption started
+}
+
+// Read reads from c and watches what it read.
+func (c *renegotiationConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.watch(p[:n])
+	return n, err
+}
+
+// watch follows the records in b, which continues what was
+// read before.
+func (c *renegotiationConn) watch(b []byte) {
+	for len(b) > 0 {
+		if c.body > 0 {
+			skip := c.body
+			if skip > len(b) {
+				skip = len(b)
+			}
+			c.body -= skip
+			b = b[skip:]
+			continue
+		}
+
+		n := copy(c.header[c.headerLen:], b)
+		c.headerLen += n
+		b = b[n:]
+		if c.headerLen < len(c.header) {
+			return
+		}
+		c.headerLen = 0
+		c.body = int(binary.BigEndian.Uint16(c.header[3:5]))
+
+		switch c.header[0] {
+		case recordTypeChangeCipherSpec:
+			c.encrypted = true
+		case recordTypeHandshake:
+			if c.encrypted {
+				c.handshakes++
+				if c.handshakes == 2 {
+					renegotiationLog.attempt(c.RemoteAddr())
+				}
+			}
+		}
+	}
+}
+
+// TLS record content types
+const (
+	recordTypeChangeCipherSpec = 20
+	recordTypeHandshake        = 22
+)
+
+// renegotiationLogger logs attempts to renegotiate, but no more
+// than one per interval, so that clients can't flood the log.
+type renegotiationLogger struct {
+	sync.Mutex
+	interval   time.Duration
+	last       time.Time
+	suppressed int
+}
+
+// attempt logs an attempt to renegotiate by the client at addr,
+// unless another attempt was logged within the interval.
+func (l *renegotiationLogger) attempt(addr net.Addr) {
+	l.Lock()
+	defer l.Unlock()
+	now := time.Now()
+	if now.Sub(l.last) < l.interval {
+		l.suppressed++
+		return
+	}
+	if l.suppressed > 0 {
+		log.Printf("[WARNING] %s attempted TLS renegotiation, which was rejected (and %d more since the last such warning)",
+			addr, l.suppressed)
+	} else {
+		log.Printf("[WARNING] %s attempted TLS renegotiation, which was rejected", addr)
+	}
+	l.last, l.suppressed = now, 0
+}
+
+var renegotiationLog = &renegotiationLogger{interval: 10 * time.Second}
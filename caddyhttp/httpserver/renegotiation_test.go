@@ -0,0 +1,147 @@
+package httpserver
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// record makes a TLS record of type typ with a body of n bytes.
+func record(typ byte, n int) []byte {
+	return append([]byte{typ, 3, 3, byte(n >> 8), byte(n)}, make([]byte, n)...)
+}
+
+func TestRenegotiationConnWatch(t *testing.T) {
+	log.SetOutput(ioutil.Discard)
+	defer log.SetOutput(os.Stderr)
+	conn, _ := net.Pipe()
+	defer conn.Close()
+
+	var handshake []byte
+	handshake = append(handshake, record(recordTypeHandshake, 300)...) // ClientHello
+	handshake = append(handshake, record(recordTypeHandshake, 70)...)  // ClientKeyExchange
+	handshake = append(handshake, record(recordTypeChangeCipherSpec, 1)...)
+	handshake = append(handshake, record(recordTypeHandshake, 40)...) // Finished
+	handshake = append(handshake, record(23, 500)...)                 // application data
+
+	for i, test := range []struct {
+		stream   []byte
+		expected int
+	}{
+		{handshake, 1},
+		{append(handshake, record(recordTypeHandshake, 200)...), 2},
+		{append(record(recordTypeHandshake, 300), record(23, 60)...), 0}, // TLS 1.3
+	} {
+		// whole, and in pieces that split headers and bodies
+		for _, size := range []int{len(test.stream), 1, 3, 7} {
+			c := &renegotiationConn{Conn: conn}
+			for b := test.stream; len(b) > 0; {
+				n := size
+				if n > len(b) {
+					n = len(b)
+				}
+				c.watch(b[:n])
+				b = b[n:]
+			}
+			if c.handshakes != test.expected {
+				t.Errorf("Test %d: Expected %d encrypted handshake records read %d bytes at a time, got %d",
+					i, test.expected, size, c.handshakes)
+			}
+		}
+	}
+}
+
+func TestRenegotiationRejected(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+	defer func(l *renegotiationLogger) { renegotiationLog = l }(renegotiationLog)
+	renegotiationLog = &renegotiationLogger{interval: time.Hour}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverConfig := &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+	}
+	clientConfig := &tls.Config{
+		InsecureSkipVerify: true,
+		MaxVersion:         tls.VersionTLS12,
+	}
+
+	attempt := func() error {
+		clientSide, serverSide := net.Pipe()
+		defer clientSide.Close()
+		server := tls.Server(&renegotiationConn{Conn: serverSide}, serverConfig)
+		defer server.Close()
+
+		errs := make(chan error, 1)
+		go func() {
+			client := tls.Client(clientSide, clientConfig)
+			if _, err := client.Write([]byte("hello")); err != nil {
+				errs <- err
+				return
+			}
+			// a handshake record after the handshake, as a client
+			// that renegotiates would send
+			_, err := clientSide.Write(record(recordTypeHandshake, 64))
+			errs <- err
+			io.Copy(ioutil.Discard, clientSide) // the alert
+		}()
+
+		p := make([]byte, 5)
+		if _, err := server.Read(p); err != nil {
+			t.Fatalf("Expected to read the request, got: %v", err)
+		}
+		if buf.Len() > 0 {
+			t.Errorf("Expected nothing logged for the handshake, got: %s", buf.String())
+		}
+		if _, err := server.Read(p); err == nil {
+			t.Error("Expected an error for the renegotiation, got none")
+		}
+		return <-errs
+	}
+
+	if err := attempt(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "attempted TLS renegotiation, which was rejected") {
+		t.Errorf("Expected the attempt to be logged, got: %q", buf.String())
+	}
+
+	// more attempts within the interval are not logged
+	buf.Reset()
+	if err := attempt(); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() > 0 {
+		t.Errorf("Expected the second attempt not to be logged, got: %s", buf.String())
+	}
+	if renegotiationLog.suppressed != 1 {
+		t.Errorf("Expected 1 suppressed attempt, got %d", renegotiationLog.suppressed)
+	}
+}
@@ -2,6 +2,9 @@ package httpserver
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"net"
@@ -12,7 +15,10 @@ import (
 	"path"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
+
+	"github.com/mholt/caddy/caddytls"
 )
 
 // requestReplacer is a strings.Replacer which is used to
@@ -78,14 +84,48 @@ func (lw *limitWriter) String() string {
 	return lw.w.String()
 }
 
+// requestBodySizeCtxKey is the context key under which the server
+// stores the *bodyCounter of a request whose size is not known
+// in advance because it is chunked.
+const requestBodySizeCtxKey CtxKey = "request_body_size"
+
+// bodyCounter counts the bytes read from a request body.
+type bodyCounter struct {
+	io.ReadCloser
+	n int64 // accessed atomically, as the proxy reads in the background
+}
+
+func (bc *bodyCounter) Read(p []byte) (int, error) {
+	n, err := bc.ReadCloser.Read(p)
+	atomic.AddInt64(&bc.n, int64(n))
+	return n, err
+}
+
+// requestBodySize returns the size of the body of r: its Content-Length
+// if it has one, or else the number of bytes read from it so far.
+func requestBodySize(r *http.Request) (int64, bool) {
+	if r.ContentLength >= 0 {
+		return r.ContentLength, true
+	}
+	if bc, ok := r.Context().Value(requestBodySizeCtxKey).(*bodyCounter); ok {
+		return atomic.LoadInt64(&bc.n), true
+	}
+	return 0, false
+}
+
 // NewReplacer makes a new replacer based on r and rr which
 // are used for request and response placeholders, respectively.
 // Request placeholders are created immediately, whereas
 // response placeholders are not created until Replace()
-// is invoked. rr may be nil if it is not available.
+// is invoked. If rr is nil, the ResponseRecorder stored in
+// the request context by the server is used, if there is one.
+// Placeholders set with SetPlaceholder are included.
 // emptyValue should be the string that is used in place
 // of empty string (can still be empty string).
 func NewReplacer(r *http.Request, rr *ResponseRecorder, emptyValue string) Replacer {
+	if rr == nil {
+		rr, _ = r.Context().Value(ResponseRecorderCtxKey).(*ResponseRecorder)
+	}
 	rb := newLimitWriter(MaxLogBodySize)
 	if r.Body != nil {
 		r.Body = struct {
@@ -93,13 +133,19 @@ func NewReplacer(r *http.Request, rr *ResponseRecorder, emptyValue string) Repla
 			io.Closer
 		}{io.TeeReader(r.Body, rb), io.Closer(r.Body)}
 	}
-	return &replacer{
+	rep := &replacer{
 		request:            r,
 		requestBody:        rb,
 		responseRecorder:   rr,
 		customReplacements: make(map[string]string),
 		emptyValue:         emptyValue,
 	}
+	if placeholders, ok := r.Context().Value(PlaceholdersCtxKey).(map[string]string); ok {
+		for key, value := range placeholders {
+			rep.Set(key, value)
+		}
+	}
+	return rep
 }
 
 func canLogRequest(r *http.Request) bool {
@@ -199,10 +245,43 @@ func (r *replacer) getSubstitution(key string) string {
 		}
 	}
 
+	// search host labels, {label1} being the leftmost
+	if strings.HasPrefix(key, "{label") {
+		if n, err := strconv.Atoi(key[len("{label") : len(key)-1]); err == nil && n > 0 {
+			host, _, err := net.SplitHostPort(r.request.Host)
+			if err != nil {
+				host = r.request.Host
+			}
+			labels := strings.Split(host, ".")
+			if n > len(labels) {
+				return r.emptyValue
+			}
+			return labels[n-1]
+		}
+	}
+
+	// search query parameters, {query.name} being the
+	// first value of name, decoded
+	if strings.HasPrefix(key, "{query.") {
+		if values := r.request.URL.Query()[key[len("{query."):len(key)-1]]; len(values) > 0 {
+			return values[0]
+		}
+		return r.emptyValue
+	}
+
+	// search cookies, {cookie.name} being the value of the
+	// first cookie called name, without quotes
+	if strings.HasPrefix(key, "{cookie.") {
+		if cookie, err := r.request.Cookie(key[len("{cookie.") : len(key)-1]); err == nil {
+			return cookie.Value
+		}
+		return r.emptyValue
+	}
+
 	// search default replacements in the end
 	switch key {
 	case "{method}":
-		return r.request.Method
+		return strings.ToUpper(r.request.Method)
 	case "{scheme}":
 		if r.request.TLS != nil {
 			return "https"
@@ -260,10 +339,20 @@ func (r *replacer) getSubstitution(key string) string {
 			return r.emptyValue
 		}
 		return port
+	case "{server_addr}":
+		// the address that the connection of the request was
+		// accepted on, which is a path for Unix sockets
+		addr, ok := r.request.Context().Value(http.LocalAddrContextKey).(net.Addr)
+		if !ok || addr.String() == "" {
+			return r.emptyValue
+		}
+		return addr.String()
 	case "{uri}":
 		return r.request.URL.RequestURI()
 	case "{uri_escaped}":
 		return url.QueryEscape(r.request.URL.RequestURI())
+	case "{uri_path}":
+		return decodedPath(r.request.URL)
 	case "{when}":
 		return now().Format(timeFormat)
 	case "{when_iso}":
@@ -280,6 +369,15 @@ func (r *replacer) getSubstitution(key string) string {
 			return r.emptyValue
 		}
 		return requestReplacer.Replace(string(dump))
+	case "{request_body_size}":
+		// the body is not read for this; if it is chunked, the
+		// size is only known after the handler has read it, so
+		// it is best used in the access log
+		size, ok := requestBodySize(r.request)
+		if !ok {
+			return r.emptyValue
+		}
+		return strconv.FormatInt(size, 10)
 	case "{request_body}":
 		if !canLogRequest(r.request) {
 			return r.emptyValue
@@ -301,6 +399,54 @@ func (r *replacer) getSubstitution(key string) string {
 			return r.emptyValue
 		}
 		return strconv.Itoa(r.responseRecorder.size)
+	case "{size_uncompressed}":
+		if r.responseRecorder == nil {
+			return r.emptyValue
+		}
+		// compressing middleware counts on the server's recorder,
+		// which need not be the one of this replacer
+		if rr, ok := r.request.Context().Value(ResponseRecorderCtxKey).(*ResponseRecorder); ok && rr.compressed {
+			return strconv.Itoa(rr.uncompressedSize)
+		}
+		return strconv.Itoa(r.responseRecorder.UncompressedSize())
+	case "{content_type}":
+		// as it is when evaluated: the handlers of a request may not
+		// have determined it yet, but it is final once they wrote it
+		if r.responseRecorder == nil {
+			return r.emptyValue
+		}
+		if contentType := r.responseRecorder.Header().Get(headerContentType); contentType != "" {
+			return contentType
+		}
+		return r.emptyValue
+	case "{tls_ocsp}":
+		// of the certificate for the server name, which is
+		// the one served unless a handshake replaced it since
+		if r.request.TLS == nil {
+			return r.emptyValue
+		}
+		return caddytls.OCSPStatus(r.request.TLS.ServerName)
+	case "{tls_resumed}":
+		if r.request.TLS == nil {
+			return r.emptyValue
+		}
+		return strconv.FormatBool(r.request.TLS.DidResume)
+	case "{tls_client_subject}", "{tls_client_issuer}", "{tls_client_serial}", "{tls_client_fingerprint}":
+		// of the certificate of the client, if it was verified; the
+		// subject and issuer by their common names
+		cert := verifiedClientCert(r.request)
+		if cert == nil {
+			return r.emptyValue
+		}
+		switch key {
+		case "{tls_client_subject}":
+			return cert.Subject.CommonName
+		case "{tls_client_issuer}":
+			return cert.Issuer.CommonName
+		case "{tls_client_serial}":
+			return cert.SerialNumber.String()
+		}
+		return fmt.Sprintf("%x", sha256.Sum256(cert.Raw))
 	case "{latency}":
 		if r.responseRecorder == nil {
 			return r.emptyValue
@@ -317,7 +463,36 @@ func (r *replacer) getSubstitution(key string) string {
 	return r.emptyValue
 }
 
-//convertToMilliseconds returns the number of milliseconds in the given duration
+// verifiedClientCert returns the certificate of the client of r,
+// or nil if the client sent none or it was not verified against
+// the CAs of the site.
+func verifiedClientCert(r *http.Request) *x509.Certificate {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return r.TLS.VerifiedChains[0][0]
+}
+
+// decodedPath returns the path of u, URL-decoded and cleaned but
+// without the query. Encoded slashes (%2F) stay encoded, so that a
+// decoded path has the same segments as the path that was requested.
+// A trailing slash is kept.
+func decodedPath(u *url.URL) string {
+	escaped := u.EscapedPath()
+	escaped = strings.Replace(escaped, "%2F", "%252F", -1)
+	escaped = strings.Replace(escaped, "%2f", "%252F", -1)
+	decoded, err := url.ParseRequestURI("/" + strings.TrimPrefix(escaped, "/"))
+	if err != nil {
+		return path.Clean("/" + u.Path)
+	}
+	p := path.Clean(decoded.Path)
+	if strings.HasSuffix(decoded.Path, "/") && p != "/" {
+		p += "/"
+	}
+	return p
+}
+
+// convertToMilliseconds returns the number of milliseconds in the given duration
 func convertToMilliseconds(d time.Duration) int64 {
 	return d.Nanoseconds() / 1e6
 }
@@ -1,12 +1,26 @@
 package httpserver
 
 import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/mholt/caddy/caddyhttp/staticfiles"
+	"github.com/mholt/caddy/caddytls"
 )
 
 func TestNewReplacer(t *testing.T) {
@@ -177,3 +191,398 @@ func TestMillisecondConverstion(t *testing.T) {
 		}
 	}
 }
+
+func TestResponseRecorderFromContext(t *testing.T) {
+	w := httptest.NewRecorder()
+	rr := NewResponseRecorder(w)
+	request, err := http.NewRequest("GET", "http://localhost", nil)
+	if err != nil {
+		t.Fatal("Request Formation Failed\n")
+	}
+	request = request.WithContext(context.WithValue(request.Context(), ResponseRecorderCtxKey, rr))
+
+	repl := NewReplacer(request, nil, "-")
+	if got, want := repl.Replace("{status} {size}"), "200 0"; got != want {
+		t.Errorf("Expected %q before the response was written, got %q", want, got)
+	}
+
+	rr.WriteHeader(http.StatusNotFound)
+	rr.Write([]byte("not "))
+	rr.Write([]byte("found"))
+	if got, want := repl.Replace("{status} {size}"), "404 9"; got != want {
+		t.Errorf("Expected %q after the response was written, got %q", want, got)
+	}
+
+	// without a recorder in the context, the placeholders are empty
+	request, err = http.NewRequest("GET", "http://localhost", nil)
+	if err != nil {
+		t.Fatal("Request Formation Failed\n")
+	}
+	if got, want := NewReplacer(request, nil, "-").Replace("{status} {size}"), "- -"; got != want {
+		t.Errorf("Expected %q without a recorder, got %q", want, got)
+	}
+}
+
+func TestLabelPlaceholders(t *testing.T) {
+	request, err := http.NewRequest("GET", "http://www.example.com:8080/", nil)
+	if err != nil {
+		t.Fatal("Request Formation Failed\n")
+	}
+	repl := NewReplacer(request, nil, "-")
+	if got, want := repl.Replace("{label1} {label2} {label3} {label4} {label0}"), "www example com - -"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestSizeUncompressed(t *testing.T) {
+	rr := NewResponseRecorder(httptest.NewRecorder())
+	request, err := http.NewRequest("GET", "http://localhost", nil)
+	if err != nil {
+		t.Fatal("Request Formation Failed\n")
+	}
+	request = request.WithContext(context.WithValue(request.Context(), ResponseRecorderCtxKey, rr))
+
+	// a recorder of its own, like the log middleware's
+	own := NewResponseRecorder(rr)
+	own.Write([]byte("plain"))
+	own.Write([]byte(" body"))
+	repl := NewReplacer(request, own, "-")
+	if got, want := repl.Replace("{size} {size_uncompressed}"), "10 10"; got != want {
+		t.Errorf("Expected %q for an uncompressed response, got %q", want, got)
+	}
+
+	rr.CountUncompressed(100)
+	rr.CountUncompressed(50)
+	if got, want := repl.Replace("{size} {size_uncompressed}"), "10 150"; got != want {
+		t.Errorf("Expected %q for a compressed response, got %q", want, got)
+	}
+}
+
+func TestContentTypePlaceholder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "caddy_contenttype")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	png := []byte("\x89PNG\r\n\x1a\n")
+	if err := ioutil.WriteFile(filepath.Join(dir, "logo.png"), png, 0644); err != nil {
+		t.Fatal(err)
+	}
+	files := staticfiles.FileServer{Root: http.Dir(dir)}
+
+	for i, test := range []struct {
+		path     string
+		expected string
+	}{
+		{"/logo.png", "image/png"},
+		{"/missing.png", "-"}, // nothing written, no type
+	} {
+		request, err := http.NewRequest("GET", "http://localhost"+test.path, nil)
+		if err != nil {
+			t.Fatal("Request Formation Failed\n")
+		}
+		rr := NewResponseRecorder(httptest.NewRecorder())
+		repl := NewReplacer(request, rr, "-")
+		if got := repl.Replace("{content_type}"); got != "-" {
+			t.Errorf("Test %d: Expected no type before serving, got %q", i, got)
+		}
+
+		files.ServeHTTP(rr, request)
+		if got := repl.Replace("{content_type}"); got != test.expected {
+			t.Errorf("Test %d: Expected %q for %s, got %q", i, test.expected, test.path, got)
+		}
+	}
+}
+
+func TestQueryPlaceholders(t *testing.T) {
+	request, err := http.NewRequest("GET", "http://localhost/a%20b?q=caddy+server&tag=go&tag=web&next=%2Fhome%3Fx%3D1&empty=&%C3%A9t%C3%A9=%E2%9C%93", nil)
+	if err != nil {
+		t.Fatal("Request Formation Failed\n")
+	}
+	repl := NewReplacer(request, nil, "-")
+
+	for i, test := range []struct {
+		placeholder string
+		expected    string
+	}{
+		{"{query}", "q=caddy+server&tag=go&tag=web&next=%2Fhome%3Fx%3D1&empty=&%C3%A9t%C3%A9=%E2%9C%93"},
+		{"{uri_escaped}", url.QueryEscape("/a%20b?q=caddy+server&tag=go&tag=web&next=%2Fhome%3Fx%3D1&empty=&%C3%A9t%C3%A9=%E2%9C%93")},
+		{"{query.q}", "caddy server"},
+		{"{query.tag}", "go"}, // the first one
+		{"{query.next}", "/home?x=1"},
+		{"{query.été}", "✓"},
+		{"{query.empty}", ""},
+		{"{query.missing}", "-"},
+		{"{query.Q}", "-"}, // names are case-sensitive
+	} {
+		if got := repl.Replace(test.placeholder); got != test.expected {
+			t.Errorf("Test %d: Expected %s to be %q, got %q", i, test.placeholder, test.expected, got)
+		}
+	}
+}
+
+func TestURIPathPlaceholder(t *testing.T) {
+	for i, test := range []struct {
+		url      string
+		expected string
+	}{
+		{"http://localhost", "/"},
+		{"http://localhost/", "/"},
+		{"http://localhost/?a=b", "/"},
+		{"http://localhost/a/b?a=b", "/a/b"},
+		{"http://localhost/dir/", "/dir/"},
+		{"http://localhost/caf%C3%A9/a%20b", "/café/a b"},
+		{"http://localhost/a/../../b/./c", "/b/c"},
+		{"http://localhost//a//b", "/a/b"},
+		// encoded slashes don't become separators
+		{"http://localhost/a%2Fb/c", "/a%2Fb/c"},
+		{"http://localhost/a%2fb/c", "/a%2Fb/c"},
+		{"http://localhost/a%2F..%2Fb", "/a%2F..%2Fb"},
+		{"http://localhost/%3F", "/?"},
+	} {
+		request, err := http.NewRequest("GET", test.url, nil)
+		if err != nil {
+			t.Fatalf("Test %d: Request Formation Failed: %v", i, err)
+		}
+		repl := NewReplacer(request, nil, "-")
+		if got := repl.Replace("{uri_path}"); got != test.expected {
+			t.Errorf("Test %d: Expected {uri_path} of %s to be %q, got %q", i, test.url, test.expected, got)
+		}
+	}
+}
+
+func TestMethodPlaceholder(t *testing.T) {
+	request, err := http.NewRequest("get", "http://localhost/", nil)
+	if err != nil {
+		t.Fatal("Request Formation Failed\n")
+	}
+	repl := NewReplacer(request, nil, "-")
+	if got := repl.Replace("{method}"); got != "GET" {
+		t.Errorf("Expected {method} to be GET, got %q", got)
+	}
+}
+
+func TestRequestBodySizePlaceholder(t *testing.T) {
+	var before, after string
+	site := &SiteConfig{
+		Addr: Address{Original: "localhost", Host: "localhost"},
+		TLS:  new(caddytls.Config),
+	}
+	site.AddMiddleware(func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+			before = NewReplacer(r, nil, "-").Replace("{request_body_size}")
+			if _, err := ioutil.ReadAll(r.Body); err != nil {
+				return http.StatusInternalServerError, err
+			}
+			after = NewReplacer(r, nil, "-").Replace("{request_body_size}")
+			return http.StatusOK, nil
+		})
+	})
+	s, err := NewServer("127.0.0.1:0", []*SiteConfig{site})
+	if err != nil {
+		t.Fatalf("Expected no error making server, got: %v", err)
+	}
+
+	for i, test := range []struct {
+		method         string
+		body           string
+		chunked        bool
+		expectedBefore string
+		expectedAfter  string
+	}{
+		{"POST", "hello world", false, "11", "11"},
+		{"POST", "hello world", true, "0", "11"},
+		{"POST", "", true, "0", "0"},
+		{"GET", "", false, "0", "0"},
+	} {
+		req, err := http.NewRequest(test.method, "http://localhost/", strings.NewReader(test.body))
+		if err != nil {
+			t.Fatalf("Test %d: Could not create HTTP request: %v", i, err)
+		}
+		if test.chunked {
+			req.ContentLength = -1
+			req.TransferEncoding = []string{"chunked"}
+		}
+		s.ServeHTTP(httptest.NewRecorder(), req)
+		if before != test.expectedBefore || after != test.expectedAfter {
+			t.Errorf("Test %d: Expected {request_body_size} %s before reading the body and %s after, got %s and %s",
+				i, test.expectedBefore, test.expectedAfter, before, after)
+		}
+	}
+
+	// outside of the server, the size of chunked bodies is unknown
+	req, err := http.NewRequest("POST", "http://localhost/", strings.NewReader("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.ContentLength = -1
+	if got := NewReplacer(req, nil, "-").Replace("{request_body_size}"); got != "-" {
+		t.Errorf("Expected unknown size of a chunked body to be -, got %s", got)
+	}
+}
+
+func TestCookiePlaceholders(t *testing.T) {
+	for i, test := range []struct {
+		cookies     []string // Cookie headers
+		placeholder string
+		expected    string
+	}{
+		{[]string{"session=abc123; consent=yes"}, "{cookie.session}", "abc123"},
+		{[]string{"session=abc123; consent=yes"}, "{cookie.consent}", "yes"},
+		{[]string{"session=abc123", "consent=yes"}, "{cookie.consent}", "yes"},
+		{[]string{`theme="dark mode"; lang=en`}, "{cookie.theme}", "dark mode"},
+		{[]string{"session=first; session=second"}, "{cookie.session}", "first"},
+		{[]string{"empty=; x=1"}, "{cookie.empty}", ""},
+		// absent
+		{[]string{"session=abc123"}, "{cookie.consent}", "-"},
+		{nil, "{cookie.session}", "-"},
+		{[]string{"session=abc123"}, "{cookie.Session}", "-"},
+		// malformed pairs are skipped
+		{[]string{"=novalue; ;;consent=yes; bad name=x"}, "{cookie.consent}", "yes"},
+		{[]string{"bad name=x"}, "{cookie.bad name}", "-"},
+		{[]string{`theme="unterminated; lang=en`}, "{cookie.theme}", "-"},
+	} {
+		request, err := http.NewRequest("GET", "http://localhost/", nil)
+		if err != nil {
+			t.Fatal("Request Formation Failed\n")
+		}
+		request.Header["Cookie"] = test.cookies
+		repl := NewReplacer(request, nil, "-")
+		if got := repl.Replace(test.placeholder); got != test.expected {
+			t.Errorf("Test %d: Expected %s to be %q, got %q", i, test.placeholder, test.expected, got)
+		}
+	}
+}
+
+func TestTLSOCSPPlaceholder(t *testing.T) {
+	request, err := http.NewRequest("GET", "https://localhost/", nil)
+	if err != nil {
+		t.Fatal("Request Formation Failed\n")
+	}
+	repl := NewReplacer(request, nil, "-")
+	if got := repl.Replace("{tls_ocsp}"); got != "-" {
+		t.Errorf("Expected - for a plaintext request, got %q", got)
+	}
+
+	// the statuses of stapled certificates are tested in caddytls
+	request.TLS = &tls.ConnectionState{ServerName: "nocert.localhost"}
+	repl = NewReplacer(request, nil, "-")
+	if got := repl.Replace("{tls_ocsp}"); got != "none" {
+		t.Errorf("Expected none for a server name without a certificate, got %q", got)
+	}
+}
+
+func TestTLSResumedPlaceholder(t *testing.T) {
+	for i, test := range []struct {
+		state    *tls.ConnectionState
+		expected string
+	}{
+		{nil, "-"},
+		{&tls.ConnectionState{HandshakeComplete: true}, "false"},
+		{&tls.ConnectionState{HandshakeComplete: true, DidResume: true}, "true"},
+	} {
+		request, err := http.NewRequest("GET", "https://localhost/", nil)
+		if err != nil {
+			t.Fatal("Request Formation Failed\n")
+		}
+		request.TLS = test.state
+		repl := NewReplacer(request, nil, "-")
+		if got := repl.Replace("{tls_resumed}"); got != test.expected {
+			t.Errorf("Test %d: Expected %q, got %q", i, test.expected, got)
+		}
+	}
+}
+
+func TestTLSClientPlaceholders(t *testing.T) {
+	cert := &x509.Certificate{
+		Raw:          []byte("client"),
+		Subject:      pkix.Name{CommonName: "client", Organization: []string{"Example"}},
+		Issuer:       pkix.Name{CommonName: "Example CA"},
+		SerialNumber: big.NewInt(42),
+	}
+	for i, test := range []struct {
+		state    *tls.ConnectionState
+		expected string
+	}{
+		{nil, "- - - -"},
+		{&tls.ConnectionState{}, "- - - -"},
+		// sent, but not verified
+		{&tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}, "- - - -"},
+		{&tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}, VerifiedChains: [][]*x509.Certificate{{cert}}},
+			"client Example CA 42 " + fmt.Sprintf("%x", sha256.Sum256([]byte("client")))},
+	} {
+		request, err := http.NewRequest("GET", "https://localhost/", nil)
+		if err != nil {
+			t.Fatal("Request Formation Failed\n")
+		}
+		request.TLS = test.state
+		repl := NewReplacer(request, nil, "-")
+		got := repl.Replace("{tls_client_subject} {tls_client_issuer} {tls_client_serial} {tls_client_fingerprint}")
+		if got != test.expected {
+			t.Errorf("Test %d: Expected %q, got %q", i, test.expected, got)
+		}
+	}
+}
+
+func TestSetPlaceholder(t *testing.T) {
+	request, err := http.NewRequest("GET", "http://localhost/page", nil)
+	if err != nil {
+		t.Fatal("Request Formation Failed\n")
+	}
+
+	// without the server's placeholder map, nothing is kept
+	SetPlaceholder(request, "slug", "hello")
+	if got, want := NewReplacer(request, nil, "-").Replace("{slug}"), "-"; got != want {
+		t.Errorf("Expected %q without a placeholder map, got %q", want, got)
+	}
+
+	request = request.WithContext(context.WithValue(request.Context(), PlaceholdersCtxKey, make(map[string]string)))
+	SetPlaceholder(request, "slug", "hello")
+	SetPlaceholder(request, "1", "world")
+	if got, want := NewReplacer(request, nil, "-").Replace("{slug} {1} {path}"), "hello world /page"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestServerAddrPlaceholder(t *testing.T) {
+	for i, test := range []struct {
+		addr     net.Addr
+		expected string
+	}{
+		{nil, "-"},
+		{&net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 8080}, "192.0.2.1:8080"},
+		{&net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 443}, "[2001:db8::1]:443"},
+		{&net.UnixAddr{Name: "/run/caddy.sock", Net: "unix"}, "/run/caddy.sock"},
+		// an unnamed socket
+		{&net.UnixAddr{Net: "unix"}, "-"},
+	} {
+		request, err := http.NewRequest("GET", "http://localhost/", nil)
+		if err != nil {
+			t.Fatal("Request Formation Failed\n")
+		}
+		if test.addr != nil {
+			request = request.WithContext(context.WithValue(request.Context(), http.LocalAddrContextKey, test.addr))
+		}
+		repl := NewReplacer(request, nil, "-")
+		if got := repl.Replace("{server_addr}"); got != test.expected {
+			t.Errorf("Test %d: Expected %q, got %q", i, test.expected, got)
+		}
+	}
+}
+
+func TestServerAddrPlaceholderTCP(t *testing.T) {
+	var got string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = NewReplacer(r, nil, "-").Replace("{server_addr}")
+	}))
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if expected := ts.Listener.Addr().String(); got != expected {
+		t.Errorf("Expected {server_addr} %q, got %q", expected, got)
+	}
+}
@@ -0,0 +1,42 @@
+package httpserver
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// requestTimeoutCtxKey is the context key under which the context
+// with the deadline of the request timeout of the site is.
+const requestTimeoutCtxKey CtxKey = "request_timeout"
+
+// RequestTimedOut reports whether handling r took longer than the
+// request timeout of its site, which cancelled the context of r.
+// It is false if the client went away instead, which cancels the
+// context of r as well.
+func RequestTimedOut(r *http.Request) bool {
+	ctx, ok := r.Context().Value(requestTimeoutCtxKey).(context.Context)
+	return ok && ctx.Err() == context.DeadlineExceeded
+}
+
+// serveWithTimeout handles r with h, giving it a context that is
+// cancelled after timeout, so that handlers and the I/O they do
+// with the context give up. Handlers that ignore the context finish
+// as they would without the timeout. If the request timed out before
+// its response was started, it is a 504 Gateway Timeout; once the
+// response is started, the client gets what was written.
+func serveWithTimeout(h Handler, w http.ResponseWriter, r *http.Request, timeout time.Duration) (int, error) {
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+	ctx = context.WithValue(ctx, requestTimeoutCtxKey, ctx)
+	r = r.WithContext(ctx)
+
+	status, err := h.ServeHTTP(w, r)
+	if !RequestTimedOut(r) {
+		return status, err
+	}
+	if rr, ok := w.(*ResponseRecorder); ok && rr.wroteHeader {
+		return status, err
+	}
+	return http.StatusGatewayTimeout, err
+}
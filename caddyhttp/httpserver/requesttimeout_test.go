@@ -0,0 +1,89 @@
+package httpserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestServeWithTimeout(t *testing.T) {
+	const timeout = 10 * time.Millisecond
+
+	for i, test := range []struct {
+		next         HandlerFunc
+		expectedCode int
+		expectedBody string
+	}{
+		// a handler that gives up when the context is cancelled
+		{
+			next: func(w http.ResponseWriter, r *http.Request) (int, error) {
+				<-r.Context().Done()
+				return http.StatusBadGateway, r.Context().Err()
+			},
+			expectedCode: http.StatusGatewayTimeout,
+		},
+		// one that is done in time
+		{
+			next: func(w http.ResponseWriter, r *http.Request) (int, error) {
+				w.Write([]byte("in time"))
+				return http.StatusOK, nil
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: "in time",
+		},
+		// one that started the response before it ran out of time
+		{
+			next: func(w http.ResponseWriter, r *http.Request) (int, error) {
+				w.Write([]byte("partial"))
+				<-r.Context().Done()
+				return http.StatusOK, nil
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: "partial",
+		},
+	} {
+		req, err := http.NewRequest("GET", "/", nil)
+		if err != nil {
+			t.Fatalf("Test %d: Could not create HTTP request: %v", i, err)
+		}
+		rec := httptest.NewRecorder()
+		rr := NewResponseRecorder(rec)
+
+		code, _ := serveWithTimeout(test.next, rr, req, timeout)
+		if code >= 400 {
+			DefaultErrorFunc(rr, req, code)
+		}
+		if rec.Code != test.expectedCode {
+			t.Errorf("Test %d: Expected status %d, got %d", i, test.expectedCode, rec.Code)
+		}
+		if test.expectedBody != "" && rec.Body.String() != test.expectedBody {
+			t.Errorf("Test %d: Expected body %q, got %q", i, test.expectedBody, rec.Body.String())
+		}
+	}
+}
+
+func TestServeWithTimeoutClientGone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequest("GET", "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = req.WithContext(ctx)
+
+	var timedOut bool
+	next := HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+		cancel()
+		<-r.Context().Done()
+		timedOut = RequestTimedOut(r)
+		return http.StatusBadGateway, r.Context().Err()
+	})
+	code, _ := serveWithTimeout(next, NewResponseRecorder(httptest.NewRecorder()), req, time.Minute)
+	if code != http.StatusBadGateway {
+		t.Errorf("Expected the status of the handler for a client that went away, got %d", code)
+	}
+	if timedOut {
+		t.Error("Expected a request whose client went away not to have timed out")
+	}
+}
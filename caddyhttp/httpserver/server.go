@@ -2,6 +2,7 @@
 package httpserver
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 	"io"
@@ -9,7 +10,10 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"path/filepath"
+	"reflect"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -31,6 +35,11 @@ type Server struct {
 	connWg      sync.WaitGroup // one increment per connection
 	tlsGovChan  chan struct{}  // close to stop the TLS maintenance goroutine
 	vhosts      *vhostTrie
+	connLimit   *ConnLimit     // of the listener, if any
+	maxConns    int            // open connections of the listener, if not 0
+	proxyPeers  []*net.IPNet   // that send a PROXY protocol header, if any
+	sockOpts    *SocketOptions // of the listener, if any
+	ticketEvery time.Duration  // how often to rotate session ticket keys
 }
 
 // ensure it satisfies the interface
@@ -87,16 +96,100 @@ func NewServer(addr string, group []*SiteConfig) (*Server, error) {
 		return nil, err
 	}
 
-	// As of Go 1.7, HTTP/2 is enabled only if NextProtos includes the string "h2"
-	if HTTP2 && s.Server.TLSConfig != nil && len(s.Server.TLSConfig.NextProtos) == 0 {
-		s.Server.TLSConfig.NextProtos = []string{"h2"}
+	// The connection limits apply to the listener, before
+	// it's known which site a connection is for
+	for _, site := range group {
+		if site.ConnLimit == nil {
+			continue
+		}
+		if s.connLimit != nil && !reflect.DeepEqual(s.connLimit, site.ConnLimit) {
+			return nil, fmt.Errorf("sites on %s have different connection limits", addr)
+		}
+		s.connLimit = site.ConnLimit
+	}
+	for _, site := range group {
+		if site.MaxConns > 0 && (s.maxConns == 0 || site.MaxConns < s.maxConns) {
+			s.maxConns = site.MaxConns
+		}
+	}
+	for _, site := range group {
+		if site.ProxyProtocol == nil {
+			continue
+		}
+		if s.proxyPeers != nil && !reflect.DeepEqual(s.proxyPeers, site.ProxyProtocol) {
+			return nil, fmt.Errorf("sites on %s have different PROXY protocol peers", addr)
+		}
+		s.proxyPeers = site.ProxyProtocol
+	}
+	for _, site := range group {
+		if site.SocketOptions == nil {
+			continue
+		}
+		if s.sockOpts != nil && !reflect.DeepEqual(s.sockOpts, site.SocketOptions) {
+			return nil, fmt.Errorf("sites on %s have different socket options", addr)
+		}
+		s.sockOpts = site.SocketOptions
+	}
+
+	// Session ticket keys are shared by the listener, so
+	// they're rotated as often as any site wants
+	for _, site := range group {
+		if site.TLS == nil {
+			continue
+		}
+		every := site.TLS.TicketRotation
+		if every > 0 && (s.ticketEvery == 0 || every < s.ticketEvery) {
+			s.ticketEvery = every
+		}
+	}
+	if s.ticketEvery == 0 {
+		s.ticketEvery = caddytls.TicketRotateInterval
+	}
+
+	// As of Go 1.7, HTTP/2 is enabled only if NextProtos includes the string "h2";
+	// it's there by default, but never if HTTP/2 is disabled, whatever the sites chose
+	if s.Server.TLSConfig != nil {
+		if HTTP2 && len(s.Server.TLSConfig.NextProtos) == 0 {
+			s.Server.TLSConfig.NextProtos = []string{"h2"}
+		} else if !HTTP2 {
+			s.Server.TLSConfig.NextProtos = withoutProto(s.Server.TLSConfig.NextProtos, "h2")
+		}
 	}
 
 	// Compile custom middleware for every site (enables virtual hosting)
 	for _, site := range group {
-		stack := Handler(staticfiles.FileServer{Root: http.Dir(site.Root), Hide: site.HiddenFiles})
+		files := staticfiles.FileServer{
+			Root:          site.RootFileSys(),
+			Hide:          site.HiddenFiles,
+			Precompressed: site.Precompressed,
+			IndexPages:    site.IndexPages,
+
+			HiddenPatterns:   site.HiddenPatterns,
+			HiddenExceptions: site.HiddenExceptions,
+			TrailingSlash:    site.TrailingSlash,
+			MimeTypes:        site.MimeTypes,
+		}
+		stack := Handler(files)
+		if site.FileSys == nil && IsDynamicRoot(site.Root) {
+			base := site.RootBase
+			if base == "" {
+				base = DynamicRootBase(site.Root)
+			}
+			base, err = filepath.Abs(base)
+			if err != nil {
+				return nil, err
+			}
+			stack = dynamicRootServer{root: site.Root, base: base, files: files}
+		}
+		site.handlerCount = len(site.middleware) + 1
+		if site.indexHandlers {
+			stack = indexHandler(stack, site.handlerCount)
+		}
 		for i := len(site.middleware) - 1; i >= 0; i-- {
 			stack = site.middleware[i](stack)
+			if site.indexHandlers {
+				stack = indexHandler(stack, i+1)
+			}
 		}
 		site.middlewareChain = stack
 		s.vhosts.Insert(site.Addr.VHost(), site)
@@ -119,7 +212,7 @@ func (s *Server) Listen() (net.Listener, error) {
 		return nil, fmt.Errorf("Server field is nil")
 	}
 
-	ln, err := net.Listen("tcp", s.Server.Addr)
+	ln, err := listenTCP(s.Server.Addr, s.sockOpts)
 	if err != nil {
 		var succeeded bool
 		if runtime.GOOS == "windows" {
@@ -128,7 +221,7 @@ func (s *Server) Listen() (net.Listener, error) {
 			// in succession. TODO: Better way to handle this? And why limit this to Windows?
 			for i := 0; i < 20; i++ {
 				time.Sleep(100 * time.Millisecond)
-				ln, err = net.Listen("tcp", s.Server.Addr)
+				ln, err = listenTCP(s.Server.Addr, s.sockOpts)
 				if err == nil {
 					succeeded = true
 					break
@@ -160,7 +253,19 @@ func (s *Server) ListenPacket() (net.PacketConn, error) {
 // Serve serves requests on ln. It blocks until ln is closed.
 func (s *Server) Serve(ln net.Listener) error {
 	if tcpLn, ok := ln.(*net.TCPListener); ok {
-		ln = tcpKeepAliveListener{TCPListener: tcpLn}
+		ln = tcpKeepAliveListener{TCPListener: tcpLn, period: s.keepAlive()}
+	}
+
+	// the PROXY protocol goes first, so that the connection
+	// limit applies to clients instead of their proxies
+	if s.proxyPeers != nil {
+		ln = proxyProtocolListener{Listener: ln, trusted: s.proxyPeers}
+	}
+	if s.connLimit != nil {
+		ln = newConnLimitListener(ln, *s.connLimit)
+	}
+	if s.maxConns > 0 {
+		ln = newMaxConnsListener(ln, s.maxConns)
 	}
 
 	ln = newGracefulListener(ln, &s.connWg)
@@ -175,10 +280,10 @@ func (s *Server) Serve(ln net.Listener) error {
 		// not implement the File() method we need for graceful restarts
 		// on POSIX systems.
 		// TODO: Is this ^ still relevant anymore? Maybe we can now that it's a net.Listener...
-		ln = tls.NewListener(ln, s.Server.TLSConfig)
+		ln = tls.NewListener(renegotiationListener{ln}, s.Server.TLSConfig)
 
 		// Rotate TLS session ticket keys
-		s.tlsGovChan = caddytls.RotateSessionTicketKeys(s.Server.TLSConfig)
+		s.tlsGovChan = caddytls.RotateSessionTicketKeysEvery(s.Server.TLSConfig, s.ticketEvery)
 	}
 
 	err := s.Server.Serve(ln)
@@ -203,7 +308,10 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		// We absolutely need to be sure we stay alive up here,
 		// even though, in theory, the errors middleware does this.
 		if rec := recover(); rec != nil {
-			log.Printf("[PANIC] %v", rec)
+			if _, ok := rec.(Repanic); ok {
+				panic(rec)
+			}
+			log.Printf("[PANIC] %v (error ID %s)", rec, MakeErrorID(r))
 			DefaultErrorFunc(w, r, http.StatusInternalServerError)
 		}
 	}()
@@ -212,11 +320,25 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	sanitizePath(r)
 
-	status, _ := s.serveHTTP(w, r)
+	// record the response so that placeholders which depend on
+	// it, like {status} and {size}, resolve in every handler
+	rr := NewResponseRecorder(w)
+	ctx := context.WithValue(r.Context(), ResponseRecorderCtxKey, rr)
+	ctx = context.WithValue(ctx, PlaceholdersCtxKey, make(map[string]string))
+	ctx = context.WithValue(ctx, ValuesCtxKey, &contextValues{values: make(map[interface{}]interface{})})
+	if r.ContentLength < 0 {
+		// count the size of chunked bodies for {request_body_size}
+		bc := &bodyCounter{ReadCloser: r.Body}
+		r.Body = bc
+		ctx = context.WithValue(ctx, requestBodySizeCtxKey, bc)
+	}
+	r = r.WithContext(ctx)
+
+	status, _ := s.serveHTTP(rr, r)
 
 	// Fallback error response in case error handling wasn't chained in
 	if status >= 400 {
-		DefaultErrorFunc(w, r, status)
+		DefaultErrorFunc(rr, r, status)
 	}
 }
 
@@ -249,12 +371,38 @@ func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) (int, error)
 		return 0, nil
 	}
 
+	// refuse oversized headers before anything looks at them
+	if requestHeaderTooLarge(r.Header, vhost.MaxRequestHeaderBytes, vhost.MaxRequestHeaderFields) {
+		return http.StatusRequestHeaderFieldsTooLarge, nil
+	}
+
+	// let handlers know which site definition matched, as {vhost}
+	SetPlaceholder(r, "vhost", vhost.Addr.VHost())
+	SetPlaceholder(r, HandlerCountPlaceholder, strconv.Itoa(vhost.handlerCount))
+
+	// when behind trusted proxies, let placeholders describe
+	// the client as the proxies report it
+	if len(vhost.TrustedProxies) > 0 {
+		SetContextValue(r, trustedProxiesCtxKey, vhost.TrustedProxies)
+		client := ResolveClient(r, vhost.TrustedProxies)
+		if client.IP != nil {
+			SetPlaceholder(r, "remote", client.IP.String())
+		}
+		SetPlaceholder(r, "scheme", client.Scheme)
+		SetPlaceholder(r, "host", client.Host)
+	}
+
+	// let plugins add what they know about the request
+	enrich(r)
+
 	// we still check for ACME challenge if the vhost exists,
 	// because we must apply its HTTP challenge config settings
 	if s.proxyHTTPChallenge(vhost, w, r) {
 		return 0, nil
 	}
 
+	path := r.URL.Path
+
 	// trim the path portion of the site address from the beginning of
 	// the URL path, so a request to example.com/foo/blog on the site
 	// defined as example.com/foo appears as /blog instead of /foo/blog.
@@ -272,13 +420,38 @@ func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) (int, error)
 	if r.Body != nil {
 		for _, pathlimit := range vhost.MaxRequestBodySizes {
 			if Path(r.URL.Path).Matches(pathlimit.Path) {
+				// A client waiting for 100 Continue before it sends a
+				// body that is too large is turned away before any of
+				// it is read; net/http sends the 100 only when the body
+				// is first read, and does not reuse the connection when
+				// it was never read
+				if r.ContentLength > pathlimit.Limit && expectsContinue(r) {
+					return http.StatusExpectationFailed, nil
+				}
 				r.Body = MaxBytesReader(w, r.Body, pathlimit.Limit)
 				break
 			}
 		}
 	}
 
-	return vhost.middlewareChain.ServeHTTP(w, r)
+	var status int
+	if vhost.RequestTimeout > 0 {
+		status, err = serveWithTimeout(vhost.middlewareChain, w, r, vhost.RequestTimeout)
+	} else {
+		status, err = vhost.middlewareChain.ServeHTTP(w, r)
+	}
+
+	if threshold := vhost.SlowRequestThreshold; threshold > 0 {
+		if rr, ok := w.(*ResponseRecorder); ok {
+			if elapsed := time.Since(rr.start); elapsed > threshold {
+				// quoted, so that a path can't forge log lines
+				log.Printf("[WARNING] Slow request: %s %q took %s (threshold %s)",
+					r.Method, hostname+path, roundDuration(elapsed), threshold)
+			}
+		}
+	}
+
+	return status, err
 }
 
 // proxyHTTPChallenge solves the ACME HTTP challenge if r is the HTTP
@@ -456,16 +629,22 @@ func makeHTTPServer(addr string, group []*SiteConfig) *http.Server {
 // Borrowed from the Go standard library.
 type tcpKeepAliveListener struct {
 	*net.TCPListener
+	period time.Duration // negative to disable keep-alive
 }
 
-// Accept accepts the connection with a keep-alive enabled.
+// Accept accepts the connection with keep-alive enabled,
+// unless the period of ln is negative.
 func (ln tcpKeepAliveListener) Accept() (c net.Conn, err error) {
 	tc, err := ln.AcceptTCP()
 	if err != nil {
 		return
 	}
+	if ln.period < 0 {
+		tc.SetKeepAlive(false)
+		return tc, nil
+	}
 	tc.SetKeepAlive(true)
-	tc.SetKeepAlivePeriod(3 * time.Minute)
+	tc.SetKeepAlivePeriod(ln.period)
 	return tc, nil
 }
 
@@ -474,6 +653,32 @@ func (ln tcpKeepAliveListener) File() (*os.File, error) {
 	return ln.TCPListener.File()
 }
 
+// withoutProto returns protos without proto.
+func withoutProto(protos []string, proto string) []string {
+	var result []string
+	for _, p := range protos {
+		if p != proto {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// keepAlive returns the keep-alive period of the
+// connections accepted by s.
+func (s *Server) keepAlive() time.Duration {
+	if s.sockOpts == nil || s.sockOpts.KeepAlive == 0 {
+		return defaultKeepAlive
+	}
+	return s.sockOpts.KeepAlive
+}
+
+// expectsContinue reports whether the client of r waits for
+// 100 Continue before it sends the request body.
+func expectsContinue(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Expect"), "100-continue")
+}
+
 // MaxBytesExceeded is the error type returned by MaxBytesReader
 // when the request body exceeds the limit imposed
 type MaxBytesExceeded struct{}
@@ -542,10 +747,32 @@ func (l *maxBytesReader) Close() error {
 	return l.r.Close()
 }
 
+// requestHeaderTooLarge reports whether header has more than
+// maxFields fields, or more than maxBytes bytes of names and
+// values. Limits that are 0 don't apply.
+func requestHeaderTooLarge(header http.Header, maxBytes, maxFields int) bool {
+	if maxBytes <= 0 && maxFields <= 0 {
+		return false
+	}
+	var size, fields int
+	for name, values := range header {
+		for _, value := range values {
+			size += len(name) + len(value)
+			fields++
+		}
+	}
+	return maxBytes > 0 && size > maxBytes || maxFields > 0 && fields > maxFields
+}
+
 // DefaultErrorFunc responds to an HTTP request with a simple description
-// of the specified HTTP status code.
+// of the specified HTTP status code, and the ID of the error that
+// handling it ran into if there is one (see MakeErrorID).
 func DefaultErrorFunc(w http.ResponseWriter, r *http.Request, status int) {
-	WriteTextResponse(w, status, fmt.Sprintf("%d %s\n", status, http.StatusText(status)))
+	body := fmt.Sprintf("%d %s\n", status, http.StatusText(status))
+	if id := ErrorID(r); id != "" {
+		body += fmt.Sprintf("Error ID: %s\n", id)
+	}
+	WriteTextResponse(w, status, body)
 }
 
 // WriteTextResponse writes body with code status to w. The body will
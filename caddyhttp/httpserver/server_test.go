@@ -1,9 +1,23 @@
 package httpserver
 
 import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/mholt/caddy/caddytls"
 )
 
 func TestAddress(t *testing.T) {
@@ -112,3 +126,638 @@ func TestMakeHTTPServer(t *testing.T) {
 		// }
 	}
 }
+
+func TestServeHTTPResponsePlaceholders(t *testing.T) {
+	var status, size string
+	site := &SiteConfig{
+		Addr: Address{Original: "localhost", Host: "localhost"},
+		TLS:  new(caddytls.Config),
+	}
+	site.AddMiddleware(func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+			code, err := next.ServeHTTP(w, r)
+			repl := NewReplacer(r, nil, "-")
+			status, size = repl.Replace("{status}"), repl.Replace("{size}")
+			return code, err
+		})
+	})
+	site.AddMiddleware(func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+			if r.URL.Path == "/teapot" {
+				w.WriteHeader(http.StatusTeapot)
+			}
+			w.Write([]byte("Hello"))
+			return 0, nil
+		})
+	})
+	s, err := NewServer("127.0.0.1:0", []*SiteConfig{site})
+	if err != nil {
+		t.Fatalf("Expected no error making server, got: %v", err)
+	}
+
+	for i, test := range []struct {
+		path, status, size string
+	}{
+		{"/", "200", "5"},
+		{"/teapot", "418", "5"},
+	} {
+		req, err := http.NewRequest("GET", "http://localhost"+test.path, nil)
+		if err != nil {
+			t.Fatalf("Test %d: Could not create HTTP request: %v", i, err)
+		}
+		s.ServeHTTP(httptest.NewRecorder(), req)
+		if status != test.status || size != test.size {
+			t.Errorf("Test %d: Expected {status}=%s and {size}=%s, got %s and %s",
+				i, test.status, test.size, status, size)
+		}
+	}
+}
+
+func TestServeHTTPContextValues(t *testing.T) {
+	type userKey string
+	type otherKey string
+	var user, other, result interface{}
+	site := &SiteConfig{
+		Addr: Address{Original: "localhost", Host: "localhost"},
+		TLS:  new(caddytls.Config),
+	}
+	site.AddMiddleware(func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+			SetContextValue(r, userKey("user"), "alice")
+			code, err := next.ServeHTTP(w, r)
+			result = GetContextValue(r, userKey("result"))
+			return code, err
+		})
+	})
+	site.AddMiddleware(func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+			user = GetContextValue(r, userKey("user"))
+			other = GetContextValue(r, otherKey("user")) // same name, other type
+			SetContextValue(r, userKey("result"), 42)
+			return http.StatusOK, nil
+		})
+	})
+	s, err := NewServer("127.0.0.1:0", []*SiteConfig{site})
+	if err != nil {
+		t.Fatalf("Expected no error making server, got: %v", err)
+	}
+
+	req, err := http.NewRequest("GET", "http://localhost/", nil)
+	if err != nil {
+		t.Fatalf("Could not create HTTP request: %v", err)
+	}
+	s.ServeHTTP(httptest.NewRecorder(), req)
+	if user != "alice" {
+		t.Errorf("Expected the value set by an earlier handler, got %v", user)
+	}
+	if other != nil {
+		t.Errorf("Expected no value for a key of another type, got %v", other)
+	}
+	if result != 42 {
+		t.Errorf("Expected the value set by a later handler, got %v", result)
+	}
+
+	// outside of the server, values are not kept
+	SetContextValue(req, userKey("user"), "bob")
+	if got := GetContextValue(req, userKey("user")); got != nil {
+		t.Errorf("Expected no value without the server, got %v", got)
+	}
+	if got := GetContextValue(nil, userKey("user")); got != nil {
+		t.Errorf("Expected no value for a nil request, got %v", got)
+	}
+}
+
+func TestServeHTTPPanicErrorID(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	site := &SiteConfig{
+		Addr: Address{Original: "localhost", Host: "localhost"},
+		TLS:  new(caddytls.Config),
+	}
+	site.AddMiddleware(func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+			panic("secret detail")
+		})
+	})
+	s, err := NewServer("127.0.0.1:0", []*SiteConfig{site})
+	if err != nil {
+		t.Fatalf("Expected no error making server, got: %v", err)
+	}
+
+	req, err := http.NewRequest("GET", "http://localhost/", nil)
+	if err != nil {
+		t.Fatalf("Could not create HTTP request: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status %d, got %d", http.StatusInternalServerError, rec.Code)
+	}
+	body := rec.Body.String()
+	i := strings.Index(body, "Error ID: ")
+	if i < 0 || strings.Contains(body, "secret") {
+		t.Fatalf("Expected body with only the error ID, got %q", body)
+	}
+	id := strings.TrimSpace(body[i+len("Error ID: "):])
+	if id == "" || !strings.Contains(buf.String(), "[PANIC] secret detail (error ID "+id+")") {
+		t.Errorf("Expected the panic to be logged with error ID %q, got %q", id, buf.String())
+	}
+}
+
+func TestServeHTTPRepanic(t *testing.T) {
+	site := &SiteConfig{
+		Addr: Address{Original: "localhost", Host: "localhost"},
+		TLS:  new(caddytls.Config),
+	}
+	site.AddMiddleware(func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+			panic(Repanic{Value: "handled"})
+		})
+	})
+	s, err := NewServer("127.0.0.1:0", []*SiteConfig{site})
+	if err != nil {
+		t.Fatalf("Expected no error making server, got: %v", err)
+	}
+
+	req, err := http.NewRequest("GET", "http://localhost/", nil)
+	if err != nil {
+		t.Fatalf("Could not create HTTP request: %v", err)
+	}
+	defer func() {
+		if rec := recover(); rec != (Repanic{Value: "handled"}) {
+			t.Errorf("Expected the server to pass the panic on to net/http, got %#v", rec)
+		}
+	}()
+	s.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func TestServeHTTPRequestHeaderLimits(t *testing.T) {
+	var handled bool
+	site := &SiteConfig{
+		Addr:                   Address{Original: "localhost", Host: "localhost"},
+		TLS:                    new(caddytls.Config),
+		MaxRequestHeaderBytes:  100,
+		MaxRequestHeaderFields: 4,
+	}
+	site.AddMiddleware(func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+			handled = true
+			w.Write([]byte("Hello"))
+			return 0, nil
+		})
+	})
+	s, err := NewServer("127.0.0.1:0", []*SiteConfig{site})
+	if err != nil {
+		t.Fatalf("Expected no error making server, got: %v", err)
+	}
+
+	for i, test := range []struct {
+		header   http.Header
+		expected int
+	}{
+		{http.Header{"Accept": {"text/html"}, "Cookie": {"a=1", "b=2"}}, http.StatusOK},
+		// exactly at the limits: 6+44 + 6+44 bytes, and 4 fields
+		{http.Header{"X-Long": {strings.Repeat("x", 44)}, "X-Last": {strings.Repeat("x", 44)}}, http.StatusOK},
+		{http.Header{"X-A": {"1", "2"}, "X-B": {"3", "4"}}, http.StatusOK},
+		// names count as well as values
+		{http.Header{"X-Long": {strings.Repeat("x", 44)}, "X-Longer": {strings.Repeat("x", 43)}}, http.StatusRequestHeaderFieldsTooLarge},
+		{http.Header{"X-A": {"1", "2", "3"}, "X-B": {"4", "5"}}, http.StatusRequestHeaderFieldsTooLarge},
+	} {
+		handled = false
+		req, err := http.NewRequest("GET", "http://localhost/", nil)
+		if err != nil {
+			t.Fatalf("Test %d: Could not create HTTP request: %v", i, err)
+		}
+		req.Header = test.header
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+
+		if rec.Code != test.expected {
+			t.Errorf("Test %d: Expected status %d, got %d", i, test.expected, rec.Code)
+		}
+		if handled != (test.expected == http.StatusOK) {
+			t.Errorf("Test %d: Expected the middleware to run only for allowed requests, but it ran: %v", i, handled)
+		}
+		if test.expected != http.StatusOK && len(rec.HeaderMap) > 3 {
+			t.Errorf("Test %d: Expected a minimal response header, got %v", i, rec.HeaderMap)
+		}
+	}
+}
+
+func TestServeHTTPVHostPlaceholder(t *testing.T) {
+	var vhost string
+	var sites []*SiteConfig
+	for _, addr := range []Address{
+		{Original: "localhost", Host: "localhost"},
+		{Original: "example.com/blog", Host: "example.com", Path: "/blog"},
+		{Original: "*.example.org", Host: "*.example.org"},
+		{Original: ":2015", Port: "2015"},
+	} {
+		site := &SiteConfig{Addr: addr, TLS: new(caddytls.Config)}
+		site.AddMiddleware(func(next Handler) Handler {
+			return HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+				vhost = NewReplacer(r, nil, "-").Replace("{vhost}")
+				return http.StatusOK, nil
+			})
+		})
+		sites = append(sites, site)
+	}
+	s, err := NewServer("127.0.0.1:0", sites)
+	if err != nil {
+		t.Fatalf("Expected no error making server, got: %v", err)
+	}
+
+	for i, test := range []struct {
+		url, vhost string
+	}{
+		{"http://localhost/", "localhost"},
+		{"http://localhost:2015/path", "localhost"},
+		{"http://example.com/blog/post", "example.com/blog"},
+		{"http://sub.example.org/", "*.example.org"},
+		// catch-all
+		{"http://example.com/", ":2015"},
+		{"http://unknown.net/", ":2015"},
+	} {
+		vhost = ""
+		req, err := http.NewRequest("GET", test.url, nil)
+		if err != nil {
+			t.Fatalf("Test %d: Could not create HTTP request: %v", i, err)
+		}
+		s.ServeHTTP(httptest.NewRecorder(), req)
+		if vhost != test.vhost {
+			t.Errorf("Test %d: Expected {vhost}=%s for %s, got %s", i, test.vhost, test.url, vhost)
+		}
+	}
+}
+
+func TestServeHTTPSlowRequestLog(t *testing.T) {
+	site := &SiteConfig{
+		Addr:                 Address{Original: "localhost", Host: "localhost"},
+		TLS:                  new(caddytls.Config),
+		SlowRequestThreshold: 50 * time.Millisecond,
+	}
+	site.AddMiddleware(func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+			if strings.HasPrefix(r.URL.Path, "/slow") {
+				time.Sleep(100 * time.Millisecond)
+			}
+			return http.StatusOK, nil
+		})
+	})
+	s, err := NewServer("127.0.0.1:0", []*SiteConfig{site})
+	if err != nil {
+		t.Fatalf("Expected no error making server, got: %v", err)
+	}
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	for i, test := range []struct {
+		path       string
+		logged     bool
+		loggedPath string
+	}{
+		{"/fast", false, ""},
+		{"/slow", true, `"localhost/slow"`},
+		{"/slow%0A[WARNING]", true, `"localhost/slow\n[WARNING]"`},
+	} {
+		buf.Reset()
+		req, err := http.NewRequest("POST", "http://localhost"+test.path+"?q=1", nil)
+		if err != nil {
+			t.Fatalf("Test %d: Could not create HTTP request: %v", i, err)
+		}
+		s.ServeHTTP(httptest.NewRecorder(), req)
+
+		logged := strings.Contains(buf.String(), "[WARNING] Slow request: POST "+test.loggedPath+" took ")
+		if logged != test.logged {
+			t.Errorf("Test %d: Expected slow request warning to be logged: %t, got log: %q", i, test.logged, buf.String())
+		}
+	}
+}
+
+func TestServeHTTPForwardedPlaceholders(t *testing.T) {
+	var got string
+	trusted, err := ParseCIDR("10.0.0.0/8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	site := &SiteConfig{
+		Addr:           Address{Original: "localhost", Host: "localhost"},
+		TLS:            new(caddytls.Config),
+		TrustedProxies: []*net.IPNet{trusted},
+	}
+	site.AddMiddleware(func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+			got = NewReplacer(r, nil, "-").Replace("{remote} {scheme} {host}")
+			return http.StatusOK, nil
+		})
+	})
+	s, err := NewServer("127.0.0.1:0", []*SiteConfig{site})
+	if err != nil {
+		t.Fatalf("Expected no error making server, got: %v", err)
+	}
+
+	for i, test := range []struct {
+		remoteAddr, expected string
+	}{
+		{"10.0.0.1:1234", "5.6.7.8 https www.example.com"},
+		{"1.2.3.4:1234", "1.2.3.4 http localhost"},
+	} {
+		req, err := http.NewRequest("GET", "http://localhost/", nil)
+		if err != nil {
+			t.Fatalf("Test %d: Could not create HTTP request: %v", i, err)
+		}
+		req.RemoteAddr = test.remoteAddr
+		req.Header.Set("Forwarded", `for=5.6.7.8;proto=https;host=www.example.com, for=10.0.0.2`)
+		s.ServeHTTP(httptest.NewRecorder(), req)
+		if got != test.expected {
+			t.Errorf("Test %d: Expected placeholders '%s', got '%s'", i, test.expected, got)
+		}
+	}
+}
+
+func TestServeHTTPTrustedProxiesRemote(t *testing.T) {
+	var remote string
+	var trusted []*net.IPNet
+	for _, s := range []string{"10.0.0.0/8", "fd00::/8"} {
+		ipnet, err := ParseCIDR(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		trusted = append(trusted, ipnet)
+	}
+
+	for i, test := range []struct {
+		trusted    []*net.IPNet
+		remoteAddr string
+		xff        string
+		expected   string
+	}{
+		// no trusted proxies: the peer is the client
+		{nil, "10.0.0.1:1234", "5.6.7.8", "10.0.0.1"},
+		// untrusted peer
+		{trusted, "1.2.3.4:1234", "5.6.7.8", "1.2.3.4"},
+		// trusted peer, walking past trusted hops
+		{trusted, "10.0.0.1:1234", "9.9.9.9, 5.6.7.8, 10.2.2.2", "5.6.7.8"},
+		{trusted, "[fd00::1]:1234", "2001:db8::1, fd00::2", "2001:db8::1"},
+	} {
+		site := &SiteConfig{
+			Addr:           Address{Original: "localhost", Host: "localhost"},
+			TLS:            new(caddytls.Config),
+			TrustedProxies: test.trusted,
+		}
+		site.AddMiddleware(func(next Handler) Handler {
+			return HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+				remote = NewReplacer(r, nil, "-").Replace("{remote}")
+				return http.StatusOK, nil
+			})
+		})
+		s, err := NewServer("127.0.0.1:0", []*SiteConfig{site})
+		if err != nil {
+			t.Fatalf("Test %d: Expected no error making server, got: %v", i, err)
+		}
+
+		req, err := http.NewRequest("GET", "http://localhost/", nil)
+		if err != nil {
+			t.Fatalf("Test %d: Could not create HTTP request: %v", i, err)
+		}
+		req.RemoteAddr = test.remoteAddr
+		req.Header.Set("X-Forwarded-For", test.xff)
+		s.ServeHTTP(httptest.NewRecorder(), req)
+		if remote != test.expected {
+			t.Errorf("Test %d: Expected {remote} %s, got %s", i, test.expected, remote)
+		}
+	}
+}
+
+func TestServeHTTPFileSys(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "caddy_filesys")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	if err := ioutil.WriteFile(filepath.Join(tmp, "file.txt"), []byte("from FileSys"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	site := &SiteConfig{
+		Addr:    Address{Original: "localhost", Host: "localhost"},
+		TLS:     new(caddytls.Config),
+		Root:    filepath.Join(tmp, "{label1}"),
+		FileSys: http.Dir(tmp),
+	}
+	s, err := NewServer("127.0.0.1:0", []*SiteConfig{site})
+	if err != nil {
+		t.Fatalf("Expected no error making server, got: %v", err)
+	}
+
+	req, err := http.NewRequest("GET", "http://localhost/file.txt", nil)
+	if err != nil {
+		t.Fatalf("Could not create HTTP request: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if got, want := rec.Body.String(), "from FileSys"; got != want {
+		t.Errorf("Expected body %q, got %q", want, got)
+	}
+}
+
+func TestServeHTTPExpectContinue(t *testing.T) {
+	site := &SiteConfig{
+		Addr:                Address{Original: "localhost", Host: "localhost"},
+		TLS:                 new(caddytls.Config),
+		MaxRequestBodySizes: []PathLimit{{Path: "/", Limit: 10}},
+	}
+	site.AddMiddleware(func(next Handler) Handler {
+		return HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+			if r.URL.Path == "/ignore" {
+				fmt.Fprint(w, "ignored")
+				return http.StatusOK, nil
+			}
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				return http.StatusRequestEntityTooLarge, err
+			}
+			fmt.Fprintf(w, "read %d", len(body))
+			return http.StatusOK, nil
+		})
+	})
+	s, err := NewServer("127.0.0.1:0", []*SiteConfig{site})
+	if err != nil {
+		t.Fatalf("Expected no error making server, got: %v", err)
+	}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	for i, test := range []struct {
+		path             string
+		body             string
+		expectedContinue bool
+		expectedStatus   int
+		expectedBody     string
+	}{
+		// accepted: 100 Continue, then the response to the body
+		{"/upload", "0123456789", true, http.StatusOK, "read 10"},
+		// too large: 417 before the body is sent, not from the middleware
+		{"/upload", "0123456789abc", false, http.StatusExpectationFailed, "417 Expectation Failed\n"},
+		// never read: no 100 Continue, just the response
+		{"/ignore", "0123456789", false, http.StatusOK, "ignored"},
+	} {
+		conn, err := net.Dial("tcp", ts.Listener.Addr().String())
+		if err != nil {
+			t.Fatalf("Test %d: Could not connect: %v", i, err)
+		}
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+		fmt.Fprintf(conn, "POST %s HTTP/1.1\r\nHost: localhost\r\nContent-Length: %d\r\nExpect: 100-continue\r\n\r\n",
+			test.path, len(test.body))
+
+		br := bufio.NewReader(conn)
+		resp, err := http.ReadResponse(br, nil)
+		if err != nil {
+			t.Fatalf("Test %d: Could not read response: %v", i, err)
+		}
+		continued := resp.StatusCode == http.StatusContinue
+		if continued {
+			io.WriteString(conn, test.body)
+			resp, err = http.ReadResponse(br, nil)
+			if err != nil {
+				t.Fatalf("Test %d: Could not read response after 100 Continue: %v", i, err)
+			}
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		conn.Close()
+
+		if continued != test.expectedContinue {
+			t.Errorf("Test %d: Expected 100 Continue to be %v, got %v", i, test.expectedContinue, continued)
+		}
+		if resp.StatusCode != test.expectedStatus {
+			t.Errorf("Test %d: Expected status %d, got %d", i, test.expectedStatus, resp.StatusCode)
+		}
+		if string(body) != test.expectedBody {
+			t.Errorf("Test %d: Expected body %q, got %q", i, test.expectedBody, body)
+		}
+	}
+}
+
+func TestServerKeepAlive(t *testing.T) {
+	for i, test := range []struct {
+		opts     *SocketOptions
+		expected time.Duration
+	}{
+		{nil, defaultKeepAlive},
+		{&SocketOptions{ReusePort: true}, defaultKeepAlive},
+		{&SocketOptions{KeepAlive: 30 * time.Second}, 30 * time.Second},
+		{&SocketOptions{KeepAlive: -1}, -1},
+	} {
+		s := &Server{sockOpts: test.opts}
+		if got := s.keepAlive(); got != test.expected {
+			t.Errorf("Test %d: Expected keep-alive period %v, got %v", i, test.expected, got)
+		}
+	}
+}
+
+func TestNewServerALPN(t *testing.T) {
+	defer func(http2 bool) { HTTP2 = http2 }(HTTP2)
+
+	for i, test := range []struct {
+		http2    bool
+		alpn     []string
+		expected []string
+	}{
+		{true, nil, []string{"h2"}},
+		{true, []string{"http/1.1", "acme-tls/1"}, []string{"http/1.1", "acme-tls/1"}},
+		{true, []string{"h2", "http/1.1"}, []string{"h2", "http/1.1"}},
+		// h2 is never advertised without HTTP/2
+		{false, nil, nil},
+		{false, []string{"h2", "http/1.1"}, []string{"http/1.1"}},
+	} {
+		HTTP2 = test.http2
+		site := &SiteConfig{
+			Addr: Address{Original: "localhost", Host: "localhost"},
+			TLS:  &caddytls.Config{Enabled: true, ALPN: test.alpn},
+		}
+		s, err := NewServer("127.0.0.1:0", []*SiteConfig{site})
+		if err != nil {
+			t.Fatalf("Test %d: Expected no error making server, got: %v", i, err)
+		}
+		if got := s.Server.TLSConfig.NextProtos; !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("Test %d: Expected NextProtos %v, got %v", i, test.expected, got)
+		}
+	}
+}
+
+func TestNewServerTicketRotation(t *testing.T) {
+	site := func(host string, every time.Duration) *SiteConfig {
+		return &SiteConfig{
+			Addr: Address{Original: host, Host: host},
+			TLS:  &caddytls.Config{Enabled: true, TicketRotation: every},
+		}
+	}
+	for i, test := range []struct {
+		sites    []*SiteConfig
+		expected time.Duration
+	}{
+		{[]*SiteConfig{site("a.com", 0)}, caddytls.TicketRotateInterval},
+		{[]*SiteConfig{site("a.com", 24*time.Hour)}, 24 * time.Hour},
+		{[]*SiteConfig{site("a.com", 0), site("b.com", 2*time.Hour), site("c.com", time.Hour)}, time.Hour},
+	} {
+		s, err := NewServer("127.0.0.1:0", test.sites)
+		if err != nil {
+			t.Fatalf("Test %d: Expected no error making server, got: %v", i, err)
+		}
+		if s.ticketEvery != test.expected {
+			t.Errorf("Test %d: Expected ticket keys to rotate every %v, got %v", i, test.expected, s.ticketEvery)
+		}
+	}
+}
+
+func TestServeHTTPHandlerPlaceholders(t *testing.T) {
+	var seen []string
+	record := func(r *http.Request) {
+		seen = append(seen, NewReplacer(r, nil, "-").Replace("{handler_index}/{handler_count}"))
+	}
+
+	site := &SiteConfig{Addr: Address{Original: "localhost", Host: "localhost"}, TLS: new(caddytls.Config), indexHandlers: true}
+	for i := 0; i < 3; i++ {
+		site.AddMiddleware(func(next Handler) Handler {
+			return HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+				record(r)
+				status, err := next.ServeHTTP(w, r)
+				record(r)
+				return status, err
+			})
+		})
+	}
+	// a site that only serves files
+	files := &SiteConfig{Addr: Address{Original: "example.com", Host: "example.com"}, TLS: new(caddytls.Config)}
+
+	s, err := NewServer("127.0.0.1:0", []*SiteConfig{site, files})
+	if err != nil {
+		t.Fatalf("Expected no error making server, got: %v", err)
+	}
+	if site.handlerCount != 4 {
+		t.Errorf("Expected 4 handlers for 3 middleware, got %d", site.handlerCount)
+	}
+	if files.handlerCount != 1 {
+		t.Errorf("Expected 1 handler for a site without middleware, got %d", files.handlerCount)
+	}
+	if _, ok := files.middlewareChain.(indexedHandler); ok {
+		t.Error("Expected the handlers of a site that doesn't use {handler_index} not to be wrapped")
+	}
+
+	req, err := http.NewRequest("GET", "http://localhost/missing", nil)
+	if err != nil {
+		t.Fatalf("Could not create HTTP request: %v", err)
+	}
+	s.ServeHTTP(httptest.NewRecorder(), req)
+
+	// the index advances down the chain and goes back up with it
+	expected := []string{"1/4", "2/4", "3/4", "3/4", "2/4", "1/4"}
+	if !reflect.DeepEqual(seen, expected) {
+		t.Errorf("Expected {handler_index}/{handler_count} %v, got %v", expected, seen)
+	}
+}
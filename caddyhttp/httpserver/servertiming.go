@@ -0,0 +1,63 @@
+package httpserver
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ServerTiming is a metric of the Server-Timing header, which tells
+// clients how long a stage of handling their request took.
+type ServerTiming struct {
+	Name     string // a token, like upstream
+	Duration time.Duration
+}
+
+// String returns the metric as it is in the header,
+// for example upstream;dur=12.5 (in milliseconds).
+func (t ServerTiming) String() string {
+	ms := float64(t.Duration/time.Microsecond) / 1000
+	return t.Name + ";dur=" + strconv.FormatFloat(ms, 'f', -1, 64)
+}
+
+// ServerTimings are the metrics recorded for a request.
+type ServerTimings struct {
+	mu      sync.Mutex
+	metrics []ServerTiming
+}
+
+// Add records the metric name that took d.
+func (t *ServerTimings) Add(name string, d time.Duration) {
+	t.mu.Lock()
+	t.metrics = append(t.metrics, ServerTiming{Name: name, Duration: d})
+	t.mu.Unlock()
+}
+
+// Header returns the value of the Server-Timing header that has
+// the recorded metrics, in the order they were recorded.
+func (t *ServerTimings) Header() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	metrics := make([]string, len(t.metrics))
+	for i, m := range t.metrics {
+		metrics[i] = m.String()
+	}
+	return strings.Join(metrics, ", ")
+}
+
+// ServerTimingsCtxKey is the context key under which the
+// *ServerTimings of a request are, if they are recorded.
+const ServerTimingsCtxKey CtxKey = "server_timings"
+
+// AddServerTiming records that the stage name of handling r took d,
+// to be reported in the Server-Timing header. Exposing timings is
+// opt-in, so it is a no-op unless a handler before, like the one of
+// the server_timing directive, added *ServerTimings to the context
+// of r.
+func AddServerTiming(r *http.Request, name string, d time.Duration) {
+	if t, ok := r.Context().Value(ServerTimingsCtxKey).(*ServerTimings); ok {
+		t.Add(name, d)
+	}
+}
@@ -0,0 +1,39 @@
+package httpserver
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestServerTimings(t *testing.T) {
+	for i, test := range []struct {
+		timing   ServerTiming
+		expected string
+	}{
+		{ServerTiming{"total", 12500 * time.Microsecond}, "total;dur=12.5"},
+		{ServerTiming{"upstream", 2 * time.Second}, "upstream;dur=2000"},
+		{ServerTiming{"render", 1234567 * time.Nanosecond}, "render;dur=1.234"},
+		{ServerTiming{"cache", 0}, "cache;dur=0"},
+	} {
+		if got := test.timing.String(); got != test.expected {
+			t.Errorf("Test %d: Expected %s, got %s", i, test.expected, got)
+		}
+	}
+
+	r, err := http.NewRequest("GET", "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// not recorded without ServerTimings
+	AddServerTiming(r, "upstream", time.Millisecond)
+
+	timings := new(ServerTimings)
+	r = r.WithContext(context.WithValue(r.Context(), ServerTimingsCtxKey, timings))
+	AddServerTiming(r, "upstream", time.Millisecond)
+	AddServerTiming(r, "render", 2*time.Millisecond)
+	if got, expected := timings.Header(), "upstream;dur=1, render;dur=2"; got != expected {
+		t.Errorf("Expected header %q, got %q", expected, got)
+	}
+}
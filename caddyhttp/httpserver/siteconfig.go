@@ -1,6 +1,8 @@
 package httpserver
 
 import (
+	"net"
+	"net/http"
 	"time"
 
 	"github.com/mholt/caddy/caddytls"
@@ -22,12 +24,36 @@ type SiteConfig struct {
 	// Uncompiled middleware stack
 	middleware []Middleware
 
+	// The directives that added the middleware,
+	// one for each as far as they are known
+	middlewareDirectives []string
+
 	// Compiled middleware stack
 	middlewareChain Handler
 
-	// Directory from which to serve files
+	// The number of handlers in middlewareChain,
+	// including the one that serves files
+	handlerCount int
+
+	// Whether the handlers of middlewareChain set
+	// {handler_index}, which costs a wrapper for each
+	// of them and so is only done if the site uses it
+	indexHandlers bool
+
+	// Directory from which to serve files; if it contains
+	// placeholders, it is resolved for every request
 	Root string
 
+	// The directory that a Root with placeholders must
+	// resolve to a subdirectory of; if empty, it is the
+	// directory of Root up to its first placeholder
+	RootBase string
+
+	// The files to serve instead of the ones in Root, such
+	// as the embedded files of a single-binary deployment;
+	// it must be set before the directives that use it
+	FileSys http.FileSystem
+
 	// A list of files to hide (for example, the
 	// source Caddyfile). TODO: Enforcing this
 	// should be centralized, for example, a
@@ -38,6 +64,14 @@ type SiteConfig struct {
 	// Max amount of bytes a request can send on a given path
 	MaxRequestBodySizes []PathLimit
 
+	// The largest request header a request may have, in bytes
+	// of the names and values of its fields, and the most fields
+	// it may have; larger headers are refused with 431 Request
+	// Header Fields Too Large before any middleware runs. If 0,
+	// only the limit of the server (1 MB) applies.
+	MaxRequestHeaderBytes  int
+	MaxRequestHeaderFields int
+
 	// The path to the Caddyfile used to generate this site config
 	originCaddyfile string
 
@@ -49,6 +83,67 @@ type SiteConfig struct {
 	// preserving functionality needed for proxying,
 	// websockets, etc.
 	Timeouts Timeouts
+
+	// The proxies whose Forwarded and X-Forwarded-For headers
+	// are trusted when determining the address of a client;
+	// see ClientIP. If empty, no proxy is trusted
+	TrustedProxies []*net.IPNet
+
+	// Content-codings of precompressed files for the static
+	// file server to prefer; nil means the default ones
+	Precompressed []string
+
+	// Index files for the static file server to look for
+	// in directories; nil means the default ones
+	IndexPages []string
+
+	// Patterns of path components that the static file server
+	// and directory listings treat as not found; nil means the
+	// default ones
+	HiddenPatterns []string
+
+	// Paths that are not hidden even if they match HiddenPatterns
+	HiddenExceptions []string
+
+	// How the static file server redirects directory paths
+	// with or without a trailing slash; "" means it adds one
+	TrailingSlash string
+
+	// Content types by file name extension for the static
+	// file server to prefer over the ones of the system
+	MimeTypes map[string]string
+
+	// Requests that take longer than this are logged
+	// with a warning; 0 means they are not
+	SlowRequestThreshold time.Duration
+
+	// Requests that take longer than this have their context
+	// cancelled and, unless their response was started, get a
+	// 504 Gateway Timeout; 0 means they may take as long as
+	// they take
+	RequestTimeout time.Duration
+
+	// How fast clients may open connections to the listener
+	// of the site; the sites on a listener must agree on it
+	ConnLimit *ConnLimit
+
+	// The most connections the listener of the site may have
+	// open at once; 0 means no limit. Of the sites on a
+	// listener, the one with the lowest maximum decides
+	MaxConns int
+
+	// Peers whose connections to the listener of the site start
+	// with a PROXY protocol header; the sites on a listener must
+	// agree on them
+	ProxyProtocol []*net.IPNet
+
+	// Options for the listening socket of the site and its
+	// connections; the sites on a listener must agree on them
+	SocketOptions *SocketOptions
+
+	// Matchers defined with the matcher directive, by name,
+	// for directives to refer to with match <name>
+	Matchers map[string]RequestMatcher
 }
 
 // Timeouts specify various timeouts for a server to use.
@@ -80,6 +175,15 @@ func (s *SiteConfig) AddMiddleware(m Middleware) {
 	s.middleware = append(s.middleware, m)
 }
 
+// RootFileSys returns the files of the site: s.FileSys,
+// or if that is nil, the directory s.Root.
+func (s SiteConfig) RootFileSys() http.FileSystem {
+	if s.FileSys != nil {
+		return s.FileSys
+	}
+	return http.Dir(s.Root)
+}
+
 // TLSConfig returns s.TLS.
 func (s SiteConfig) TLSConfig() *caddytls.Config {
 	return s.TLS
@@ -99,3 +203,18 @@ func (s SiteConfig) Port() string {
 func (s SiteConfig) Middleware() []Middleware {
 	return s.middleware
 }
+
+// MiddlewareDirectives returns the names of the directives that
+// added the middleware of s, in the order that the middleware
+// handles requests, which is the order of the directives. Middleware
+// that was not added by a directive is "?".
+func (s SiteConfig) MiddlewareDirectives() []string {
+	dirs := make([]string, len(s.middleware))
+	for i := range dirs {
+		dirs[i] = "?"
+		if i < len(s.middlewareDirectives) {
+			dirs[i] = s.middlewareDirectives[i]
+		}
+	}
+	return dirs
+}
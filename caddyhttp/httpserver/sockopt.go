@@ -0,0 +1,34 @@
+package httpserver
+
+import (
+	"net"
+	"time"
+)
+
+// SocketOptions tune the listening socket of a site and the
+// connections accepted on it.
+type SocketOptions struct {
+	// How often to probe idle connections to see if the
+	// client is still there; 0 means the default of 3
+	// minutes and a negative period means never
+	KeepAlive time.Duration
+
+	// Set SO_REUSEADDR and SO_REUSEPORT on the listening
+	// socket, so that other processes may bind the same
+	// address, like a new instance during an upgrade. Where
+	// an option is not supported, it is skipped with a warning
+	ReuseAddr, ReusePort bool
+}
+
+// defaultKeepAlive is the keep-alive period of connections
+// if the sites on a listener don't choose one.
+const defaultKeepAlive = 3 * time.Minute
+
+// listenTCP creates a TCP listener on addr with the socket
+// options opts, which may be nil.
+func listenTCP(addr string, opts *SocketOptions) (net.Listener, error) {
+	if opts == nil || (!opts.ReuseAddr && !opts.ReusePort) {
+		return net.Listen("tcp", addr)
+	}
+	return listenReuse(addr, *opts)
+}
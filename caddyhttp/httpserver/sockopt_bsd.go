@@ -0,0 +1,8 @@
+// +build go1.11
+// +build darwin dragonfly freebsd netbsd openbsd
+
+package httpserver
+
+import "syscall"
+
+const soReusePort = syscall.SO_REUSEPORT
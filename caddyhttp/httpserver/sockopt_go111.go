@@ -0,0 +1,26 @@
+// +build go1.11
+
+package httpserver
+
+import (
+	"context"
+	"net"
+	"syscall"
+)
+
+// listenReuse creates a TCP listener on addr with the address
+// reuse options of opts set on its socket before it's bound.
+func listenReuse(addr string, opts SocketOptions) (net.Listener, error) {
+	lc := net.ListenConfig{
+		Control: func(network, address string, c syscall.RawConn) error {
+			var err error
+			if cerr := c.Control(func(fd uintptr) {
+				err = setReuse(fd, opts)
+			}); cerr != nil {
+				return cerr
+			}
+			return err
+		},
+	}
+	return lc.Listen(context.Background(), "tcp", addr)
+}
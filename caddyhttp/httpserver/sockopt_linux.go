@@ -0,0 +1,15 @@
+// +build go1.11
+
+package httpserver
+
+import "runtime"
+
+// soReusePort is SO_REUSEPORT, which package syscall lacks
+// on Linux; its value is different on MIPS.
+var soReusePort = func() int {
+	if runtime.GOARCH == "mips" || runtime.GOARCH == "mipsle" ||
+		runtime.GOARCH == "mips64" || runtime.GOARCH == "mips64le" {
+		return 0x200
+	}
+	return 0xf
+}()
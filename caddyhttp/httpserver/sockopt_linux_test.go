@@ -0,0 +1,74 @@
+package httpserver
+
+import (
+	"net"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// acceptKeepAlive accepts a connection through a
+// tcpKeepAliveListener with period and returns its
+// SO_KEEPALIVE and TCP_KEEPIDLE options.
+func acceptKeepAlive(t *testing.T, period time.Duration) (keepAlive, idle int) {
+	tcpLn, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tcpLn.Close()
+	ln := tcpKeepAliveListener{TCPListener: tcpLn, period: period}
+
+	client, err := net.Dial("tcp", tcpLn.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	raw, err := conn.(*net.TCPConn).SyscallConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var errKeepAlive, errIdle error
+	raw.Control(func(fd uintptr) {
+		keepAlive, errKeepAlive = syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_KEEPALIVE)
+		idle, errIdle = syscall.GetsockoptInt(int(fd), syscall.IPPROTO_TCP, syscall.TCP_KEEPIDLE)
+	})
+	if errKeepAlive != nil || errIdle != nil {
+		t.Fatalf("Getting socket options: %v, %v", errKeepAlive, errIdle)
+	}
+	return keepAlive, idle
+}
+
+func TestTCPKeepAliveListenerPeriod(t *testing.T) {
+	if keepAlive, idle := acceptKeepAlive(t, 42*time.Second); keepAlive == 0 || idle != 42 {
+		t.Errorf("Expected keep-alive every 42s, got SO_KEEPALIVE=%d and TCP_KEEPIDLE=%d", keepAlive, idle)
+	}
+	if keepAlive, _ := acceptKeepAlive(t, -1); keepAlive != 0 {
+		t.Errorf("Expected keep-alive to be disabled, got SO_KEEPALIVE=%d", keepAlive)
+	}
+}
+
+func TestListenTCPReusePort(t *testing.T) {
+	opts := &SocketOptions{ReuseAddr: true, ReusePort: true}
+	ln1, err := listenTCP("127.0.0.1:0", opts)
+	if err != nil {
+		t.Fatalf("Expected no error listening, got: %v", err)
+	}
+	defer ln1.Close()
+
+	ln2, err := listenTCP(ln1.Addr().String(), opts)
+	if err != nil {
+		t.Fatalf("Expected a second listener on %s with SO_REUSEPORT, got: %v", ln1.Addr(), err)
+	}
+	ln2.Close()
+
+	if ln3, err := listenTCP(ln1.Addr().String(), nil); err == nil {
+		ln3.Close()
+		t.Errorf("Expected an error listening on %s without SO_REUSEPORT", ln1.Addr())
+	}
+}
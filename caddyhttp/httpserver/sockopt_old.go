@@ -0,0 +1,15 @@
+// +build !go1.11
+
+package httpserver
+
+import (
+	"log"
+	"net"
+)
+
+// listenReuse creates a TCP listener on addr without the address
+// reuse options of opts, which Go before 1.11 cannot set.
+func listenReuse(addr string, opts SocketOptions) (net.Listener, error) {
+	log.Printf("[WARNING] Address reuse is not supported by this build; listening on %s without it", addr)
+	return net.Listen("tcp", addr)
+}
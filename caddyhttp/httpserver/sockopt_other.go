@@ -0,0 +1,14 @@
+// +build go1.11
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package httpserver
+
+import "log"
+
+// setReuse skips the address reuse options of opts, which are
+// not supported here; on Windows, SO_REUSEADDR would even let
+// other processes take over the address.
+func setReuse(fd uintptr, opts SocketOptions) error {
+	log.Printf("[WARNING] Address reuse is not supported on this system; listening without it")
+	return nil
+}
@@ -0,0 +1,27 @@
+// +build go1.11
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package httpserver
+
+import (
+	"log"
+	"os"
+	"syscall"
+)
+
+// setReuse sets the address reuse options of opts on the socket
+// fd. If the system does not know SO_REUSEPORT, the socket is
+// used without it.
+func setReuse(fd uintptr, opts SocketOptions) error {
+	if opts.ReuseAddr {
+		if err := syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_REUSEADDR, 1); err != nil {
+			return os.NewSyscallError("setsockopt SO_REUSEADDR", err)
+		}
+	}
+	if opts.ReusePort {
+		if err := syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, soReusePort, 1); err != nil {
+			log.Printf("[WARNING] Setting SO_REUSEPORT: %v; listening without it", err)
+		}
+	}
+	return nil
+}
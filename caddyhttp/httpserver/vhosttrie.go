@@ -54,49 +54,53 @@ func (t *vhostTrie) insertPath(remainingPath, originalPath string, site *SiteCon
 // If there is no match, nil and empty string will
 // be returned.
 //
+// Hosts are tried in order of precedence: the exact
+// host, then wildcard hosts from the most to the least
+// specific, then the catch-all hosts. If a host has no
+// site for the path, the next host is tried.
+//
 // A typical key will be in the form "host" or "host/path".
 func (t *vhostTrie) Match(key string) (*SiteConfig, string) {
 	host, path := t.splitHostPath(key)
-	// try the given host, then, if no match, try wildcard hosts
-	branch := t.matchHost(host)
-	if branch == nil {
-		branch = t.matchHost("0.0.0.0")
-	}
-	if branch == nil {
-		branch = t.matchHost("")
-	}
-	if branch == nil {
-		return nil, ""
-	}
-	node := branch.matchPath(path)
-	if node == nil {
-		return nil, ""
+	for _, candidate := range hostCandidates(host) {
+		branch, ok := t.edges[candidate]
+		if !ok {
+			continue
+		}
+		if node := branch.matchPath(path); node != nil {
+			return node.site, node.path
+		}
 	}
-	return node.site, node.path
+	return nil, ""
 }
 
-// matchHost returns the vhostTrie matching host. The matching
-// algorithm is the same as used to match certificates to host
-// with SNI during TLS handshakes. In other words, it supports,
-// to some degree, the use of wildcard (*) characters.
-func (t *vhostTrie) matchHost(host string) *vhostTrie {
-	// try exact match
-	if subtree, ok := t.edges[host]; ok {
-		return subtree
-	}
+// hostCandidates returns the hosts in the trie that may match
+// host, in order of precedence. The wildcard hosts are the same
+// as used to match certificates to host with SNI during TLS
+// handshakes, where each * replaces one label, followed by
+// hosts like *.example.com that match subdomains of any depth.
+// IP addresses only match exactly.
+func hostCandidates(host string) []string {
+	candidates := []string{host}
+
+	if host != "" && net.ParseIP(host) == nil {
+		// replace labels in the host with
+		// wildcards, from left to right
+		labels := strings.Split(host, ".")
+		for i := range labels {
+			labels[i] = "*"
+			candidates = append(candidates, strings.Join(labels, "."))
+		}
 
-	// then try replacing labels in the host
-	// with wildcards until we get a match
-	labels := strings.Split(host, ".")
-	for i := range labels {
-		labels[i] = "*"
-		candidate := strings.Join(labels, ".")
-		if subtree, ok := t.edges[candidate]; ok {
-			return subtree
+		// then let a wildcard match multiple labels,
+		// from the longest to the shortest suffix
+		labels = strings.Split(host, ".")
+		for i := 2; i < len(labels); i++ {
+			candidates = append(candidates, "*."+strings.Join(labels[i:], "."))
 		}
 	}
 
-	return nil
+	return append(candidates, "0.0.0.0", "", "*")
 }
 
 // matchPath traverses t until it finds the longest key matching
@@ -134,7 +138,12 @@ func (t *vhostTrie) splitHostPath(key string) (host, path string) {
 	hostname, _, err := net.SplitHostPort(host)
 	if err == nil {
 		host = hostname
+	} else if strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]") {
+		// IPv6 address without port
+		host = host[1 : len(host)-1]
 	}
+	// a fully qualified name may end with a dot
+	host = strings.TrimSuffix(host, ".")
 	return
 }
 
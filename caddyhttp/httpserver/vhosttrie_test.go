@@ -81,6 +81,49 @@ func TestVHostTriePort(t *testing.T) {
 	}, true)
 }
 
+func TestVHostTrieWildcardPrecedence(t *testing.T) {
+	trie := newVHostTrie()
+	populateTestTrie(trie, []string{
+		"",
+		"example.com",
+		"www.example.com",
+		"*.example.com",
+		"*.sub.example.com",
+		"*.*.example.org",
+		"api.example.net/v1",
+		"*.example.net",
+		"127.0.0.1",
+		"[::1]",
+	})
+	assertTestTrie(t, trie, []vhostTrieTest{
+		// exact beats wildcard
+		{"www.example.com", true, "www.example.com", "/"},
+		{"example.com", true, "example.com", "/"},
+		// wildcard matches a subdomain
+		{"foo.example.com", true, "*.example.com", "/"},
+		{"FOO.Example.COM:8080/path", true, "*.example.com", "/"},
+		{"foo.example.com.", true, "*.example.com", "/"},
+		// the most specific wildcard wins
+		{"a.sub.example.com", true, "*.sub.example.com", "/"},
+		{"a.b.example.com", true, "*.example.com", "/"},
+		{"a.b.c.example.com", true, "*.example.com", "/"},
+		{"a.b.example.org", true, "*.*.example.org", "/"},
+		// an apex is not matched by its own wildcard
+		{"sub.example.com", true, "*.example.com", "/"},
+		{"example.org", true, "", "/"},
+		{"a.example.org", true, "", "/"},
+		{"other.com", true, "", "/"},
+		// a host without a site for the path falls back to wildcards
+		{"api.example.net/v1/users", true, "api.example.net/v1", "/v1"},
+		{"api.example.net/v2", true, "*.example.net", "/"},
+		// IP addresses aren't matched by wildcards
+		{"127.0.0.1", true, "127.0.0.1", "/"},
+		{"127.0.0.2", true, "", "/"},
+		{"[::1]:2015", true, "[::1]", "/"},
+		{"::1", true, "[::1]", "/"},
+	}, true)
+}
+
 func populateTestTrie(trie *vhostTrie, keys []string) {
 	for _, key := range keys {
 		// we wrap this in a func, passing in the key, otherwise the
@@ -0,0 +1,39 @@
+// Package index configures the files that a site serves
+// for requests to directories.
+package index
+
+import (
+	"strings"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func init() {
+	caddy.RegisterPlugin("index", caddy.Plugin{
+		ServerType: "http",
+		Action:     setupIndex,
+	})
+}
+
+// setupIndex parses the index directive:
+//
+//	index filenames...
+//
+// The first of the files that exists in a directory is served.
+func setupIndex(c *caddy.Controller) error {
+	config := httpserver.GetConfig(c)
+	for c.Next() {
+		args := c.RemainingArgs()
+		if len(args) == 0 {
+			return c.ArgErr()
+		}
+		for _, name := range args {
+			if name == "." || name == ".." || strings.Contains(name, "/") {
+				return c.Errf("invalid index file name '%s'", name)
+			}
+			config.IndexPages = append(config.IndexPages, name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package index
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func TestSetupIndex(t *testing.T) {
+	for i, test := range []struct {
+		input     string
+		shouldErr bool
+		expected  []string
+	}{
+		{"index index.html", false, []string{"index.html"}},
+		{"index index.html default.htm", false, []string{"index.html", "default.htm"}},
+		{"index home.html\nindex index.php", false, []string{"home.html", "index.php"}},
+		{"index", true, nil},
+		{"index sub/index.html", true, nil},
+		{"index ..", true, nil},
+	} {
+		c := caddy.NewTestController("http", test.input)
+		err := setupIndex(c)
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %d: Expected an error, but did not have one", i)
+		}
+		if !test.shouldErr && err != nil {
+			t.Errorf("Test %d: Did not expect error, but got: %v", i, err)
+		}
+
+		got := httpserver.GetConfig(c).IndexPages
+		if !test.shouldErr && !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("Test %d: Expected %v, got %v", i, test.expected, got)
+		}
+	}
+}
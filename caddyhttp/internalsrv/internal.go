@@ -8,6 +8,8 @@ package internalsrv
 
 import (
 	"net/http"
+	"path"
+	"strings"
 
 	"github.com/mholt/caddy/caddyhttp/httpserver"
 )
@@ -48,7 +50,8 @@ func (i Internal) ServeHTTP(w http.ResponseWriter, r *http.Request) (int, error)
 	for c := 0; c < maxRedirectCount && isInternalRedirect(iw); c++ {
 		// Redirect - adapt request URL path and send it again
 		// "down the chain"
-		r.URL.Path = iw.Header().Get(redirectHeader)
+		r.URL.Path, r.URL.RawQuery = redirectTarget(iw.Header().Get(redirectHeader), r.URL.RawQuery)
+		r.URL.RawPath = ""
 		iw.ClearHeader()
 
 		status, err = i.Next.ServeHTTP(iw, r)
@@ -63,6 +66,17 @@ func (i Internal) ServeHTTP(w http.ResponseWriter, r *http.Request) (int, error)
 	return status, err
 }
 
+// redirectTarget returns the path and query of the internal location
+// named by the redirect header value target. The path is cleaned as an
+// absolute path so that it cannot escape the site root, and the query
+// of the original request is kept unless target has one of its own.
+func redirectTarget(target, rawQuery string) (string, string) {
+	if i := strings.Index(target, "?"); i >= 0 {
+		target, rawQuery = target[:i], target[i+1:]
+	}
+	return path.Clean("/" + target), rawQuery
+}
+
 // internalResponseWriter wraps the underlying http.ResponseWriter and ignores
 // calls to Write and WriteHeader if the response should be redirected to an
 // internal location.
@@ -33,6 +33,9 @@ func TestInternal(t *testing.T) {
 		{"/public/internal", 0, "/public/internal"},
 
 		{"/redirect", 0, "/internal"},
+		{"/redirect-query?a=b", 0, "/internal?page=2"},
+		{"/redirect-keep-query?a=b", 0, "/internal?a=b"},
+		{"/escape", 0, "/internal"},
 
 		{"/cycle", http.StatusInternalServerError, ""},
 	}
@@ -55,6 +58,10 @@ func TestInternal(t *testing.T) {
 			t.Errorf("Test %d: Expected body '%s' for %s, but got '%s'",
 				i, test.expectedBody, test.url, rec.Body.String())
 		}
+		if val := rec.Header().Get("X-Accel-Redirect"); val != "" {
+			t.Errorf("Test %d: Expected no X-Accel-Redirect header for %s, but got '%s'",
+				i, test.url, val)
+		}
 	}
 
 	{
@@ -91,6 +98,10 @@ func TestInternal(t *testing.T) {
 			t.Errorf("Test %d: Expected removal of content-encoding header for %s",
 				i, "/download")
 		}
+		if val := rec.Header().Get("X-Accel-Redirect"); val != "" {
+			t.Errorf("Test %d: Expected removal of X-Accel-Redirect header for %s",
+				i, "/download")
+		}
 	}
 }
 
@@ -99,6 +110,15 @@ func internalTestHandlerFunc(w http.ResponseWriter, r *http.Request) (int, error
 	case "/redirect":
 		w.Header().Set("X-Accel-Redirect", "/internal")
 
+	case "/redirect-query":
+		w.Header().Set("X-Accel-Redirect", "/internal?page=2")
+
+	case "/redirect-keep-query":
+		w.Header().Set("X-Accel-Redirect", "/internal")
+
+	case "/escape":
+		w.Header().Set("X-Accel-Redirect", "../../internal")
+
 	case "/cycle":
 		w.Header().Set("X-Accel-Redirect", "/cycle")
 
@@ -121,3 +141,24 @@ func internalTestHandlerFunc(w http.ResponseWriter, r *http.Request) (int, error
 
 	return 0, nil
 }
+
+func TestRedirectTarget(t *testing.T) {
+	for i, test := range []struct {
+		target, rawQuery        string
+		expectPath, expectQuery string
+	}{
+		{"/files/a.txt", "", "/files/a.txt", ""},
+		{"/files/a.txt", "x=1", "/files/a.txt", "x=1"},
+		{"/files/a.txt?y=2", "x=1", "/files/a.txt", "y=2"},
+		{"files/a.txt", "", "/files/a.txt", ""},
+		{"/files/../../../etc/passwd", "", "/etc/passwd", ""},
+		{"..", "", "/", ""},
+		{"", "", "/", ""},
+	} {
+		p, q := redirectTarget(test.target, test.rawQuery)
+		if p != test.expectPath || q != test.expectQuery {
+			t.Errorf("Test %d: Expected path %q and query %q, got %q and %q",
+				i, test.expectPath, test.expectQuery, p, q)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+// Package jwt is middleware that validates JSON Web Tokens
+// sent as bearer tokens and exposes their claims as placeholders.
+package jwt
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+// JWT is middleware that requires requests for protected paths to
+// carry a valid JSON Web Token in the Authorization header. The
+// claims of a valid token are available as {jwt.<claim>} placeholders
+// to the handlers that come after it.
+type JWT struct {
+	Next  httpserver.Handler
+	Rules []Rule
+}
+
+// Rule is a set of paths whose requests need a token that is
+// signed with Key. Key is an HMAC secret ([]byte), an
+// *rsa.PublicKey or an *ecdsa.PublicKey, which also determines
+// the signing algorithms that are accepted. Leeway is the clock
+// skew that is tolerated when checking the exp and nbf claims.
+type Rule struct {
+	Paths  []string
+	Key    interface{}
+	Leeway time.Duration
+}
+
+// PlaceholderPrefix is the prefix of the placeholders of the claims.
+const PlaceholderPrefix = "jwt."
+
+// ServeHTTP implements the httpserver.Handler interface.
+func (j JWT) ServeHTTP(w http.ResponseWriter, r *http.Request) (int, error) {
+	for _, rule := range j.Rules {
+		if !rule.protects(r.URL.Path) {
+			continue
+		}
+
+		token, ok := bearerToken(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="Restricted"`)
+			return http.StatusUnauthorized, nil
+		}
+		claims, err := rule.Validate(token, time.Now())
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="Restricted", error="invalid_token"`)
+			return http.StatusUnauthorized, nil
+		}
+		for name, value := range claims {
+			httpserver.SetPlaceholder(r, PlaceholderPrefix+name, value)
+		}
+		break
+	}
+
+	return j.Next.ServeHTTP(w, r)
+}
+
+// protects reports whether the rule applies to the request path p.
+func (r Rule) protects(p string) bool {
+	for _, path := range r.Paths {
+		if httpserver.Path(p).Matches(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// bearerToken returns the token in the Authorization header of r.
+func bearerToken(r *http.Request) (string, bool) {
+	const prefix = "Bearer "
+	auth := r.Header.Get("Authorization")
+	if len(auth) <= len(prefix) || !strings.EqualFold(auth[:len(prefix)], prefix) {
+		return "", false
+	}
+	token := strings.TrimSpace(auth[len(prefix):])
+	return token, token != ""
+}
@@ -0,0 +1,68 @@
+package jwt
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func TestJWT(t *testing.T) {
+	var sub string
+	j := JWT{
+		Next: httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+			sub = httpserver.NewReplacer(r, nil, "").Replace("{jwt.sub}")
+			return http.StatusOK, nil
+		}),
+		Rules: []Rule{{Paths: []string{"/api"}, Key: testSecret}},
+	}
+	exp := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+	expired := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+
+	for i, test := range []struct {
+		path          string
+		authorization string
+		status        int
+		sub           string
+	}{
+		{"/api", "Bearer " + makeToken(`{"alg":"HS256"}`, `{"sub":"alice","exp":`+exp+`}`, hs256(testSecret)), http.StatusOK, "alice"},
+		{"/api", "bearer " + makeToken(`{"alg":"HS256"}`, `{"sub":"bob"}`, hs256(testSecret)), http.StatusOK, "bob"},
+		{"/api", "Bearer " + makeToken(`{"alg":"HS256"}`, `{"sub":"alice","exp":`+expired+`}`, hs256(testSecret)), http.StatusUnauthorized, ""},
+		{"/api", "Bearer " + makeToken(`{"alg":"HS256"}`, `{"sub":"alice"}`, hs256([]byte("wrong"))), http.StatusUnauthorized, ""},
+		{"/api", "Bearer " + makeToken(`{"alg":"none"}`, `{"sub":"alice"}`, unsigned), http.StatusUnauthorized, ""},
+		{"/api", "Bearer not.a.token", http.StatusUnauthorized, ""},
+		{"/api", "Bearer ", http.StatusUnauthorized, ""},
+		{"/api", "Basic dXNlcjpwYXNz", http.StatusUnauthorized, ""},
+		{"/api", "", http.StatusUnauthorized, ""},
+		{"/public", "", http.StatusOK, ""},
+	} {
+		sub = ""
+		req, err := http.NewRequest("GET", test.path, nil)
+		if err != nil {
+			t.Fatalf("Test %d: Could not create HTTP request: %v", i, err)
+		}
+		req = req.WithContext(context.WithValue(req.Context(), httpserver.PlaceholdersCtxKey, make(map[string]string)))
+		if test.authorization != "" {
+			req.Header.Set("Authorization", test.authorization)
+		}
+
+		rec := httptest.NewRecorder()
+		status, err := j.ServeHTTP(rec, req)
+		if err != nil {
+			t.Fatalf("Test %d: Could not ServeHTTP: %v", i, err)
+		}
+		if status != test.status {
+			t.Errorf("Test %d: Expected status %d, got %d", i, test.status, status)
+		}
+		if sub != test.sub {
+			t.Errorf("Test %d: Expected {jwt.sub} to be '%s', got '%s'", i, test.sub, sub)
+		}
+		if challenged := rec.Header().Get("WWW-Authenticate") != ""; challenged != (test.status == http.StatusUnauthorized) {
+			t.Errorf("Test %d: Expected WWW-Authenticate header only for a 401, got '%s'", i, rec.Header().Get("WWW-Authenticate"))
+		}
+	}
+}
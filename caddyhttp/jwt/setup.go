@@ -0,0 +1,133 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"time"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func init() {
+	caddy.RegisterPlugin("jwt_auth", caddy.Plugin{
+		ServerType: "http",
+		Action:     setup,
+	})
+}
+
+// setup configures a new JWT middleware instance.
+func setup(c *caddy.Controller) error {
+	rules, err := jwtParse(c)
+	if err != nil {
+		return err
+	}
+
+	httpserver.GetConfig(c).AddMiddleware(func(next httpserver.Handler) httpserver.Handler {
+		return JWT{Next: next, Rules: rules}
+	})
+
+	return nil
+}
+
+// jwtParse parses the jwt_auth directive, which is not called jwt
+// so as not to clash with the plugin of that name:
+//
+//	jwt_auth [paths...] {
+//		path       paths...
+//		secret     hmac_secret
+//		public_key pem_file
+//		leeway     duration
+//	}
+//
+// Exactly one of secret and public_key is required. The paths
+// default to all of the site.
+func jwtParse(c *caddy.Controller) ([]Rule, error) {
+	var rules []Rule
+	root := httpserver.GetConfig(c).Root
+
+	for c.Next() {
+		rule := Rule{Paths: c.RemainingArgs()}
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "path":
+				paths := c.RemainingArgs()
+				if len(paths) == 0 {
+					return rules, c.ArgErr()
+				}
+				rule.Paths = append(rule.Paths, paths...)
+				continue
+			case "secret":
+				if rule.Key != nil || !c.NextArg() {
+					return rules, c.ArgErr()
+				}
+				rule.Key = []byte(c.Val())
+			case "public_key":
+				if rule.Key != nil || !c.NextArg() {
+					return rules, c.ArgErr()
+				}
+				filename := c.Val()
+				if !filepath.IsAbs(filename) {
+					filename = filepath.Join(root, filename)
+				}
+				key, err := loadPublicKey(filename)
+				if err != nil {
+					return rules, c.Errf("Loading public key: %v", err)
+				}
+				rule.Key = key
+			case "leeway":
+				if !c.NextArg() {
+					return rules, c.ArgErr()
+				}
+				leeway, err := time.ParseDuration(c.Val())
+				if err != nil || leeway < 0 {
+					return rules, c.Errf("leeway must be a non-negative duration, got '%s'", c.Val())
+				}
+				rule.Leeway = leeway
+			default:
+				return rules, c.Errf("unknown subdirective '%s'", c.Val())
+			}
+			if c.NextArg() {
+				return rules, c.ArgErr()
+			}
+		}
+
+		if rule.Key == nil {
+			return rules, c.Err("jwt_auth requires a secret or a public_key")
+		}
+		if len(rule.Paths) == 0 {
+			rule.Paths = []string{"/"}
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+// loadPublicKey loads the PEM encoded PKIX RSA or ECDSA public
+// key in the file filename.
+func loadPublicKey(filename string) (interface{}, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data in %s", filename)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	switch key.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+		return key, nil
+	}
+	return nil, fmt.Errorf("unsupported public key type %T", key)
+}
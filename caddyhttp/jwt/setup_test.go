@@ -0,0 +1,108 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func TestSetup(t *testing.T) {
+	c := caddy.NewTestController("http", "jwt_auth /api {\n\tsecret s3cr3t\n}")
+	err := setup(c)
+	if err != nil {
+		t.Errorf("Expected no errors, got: %v", err)
+	}
+	mids := httpserver.GetConfig(c).Middleware()
+	if len(mids) == 0 {
+		t.Fatal("Expected middleware, got 0 instead")
+	}
+
+	handler := mids[0](httpserver.EmptyNext)
+	myHandler, ok := handler.(JWT)
+	if !ok {
+		t.Fatalf("Expected handler to be type JWT, got: %#v", handler)
+	}
+	if !httpserver.SameNext(myHandler.Next, httpserver.EmptyNext) {
+		t.Error("'Next' field of handler was not set properly")
+	}
+}
+
+func TestJWTParse(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jwt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(dir, "key.pem")
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0644); err != nil {
+		t.Fatal(err)
+	}
+	badFile := filepath.Join(dir, "bad.pem")
+	if err := ioutil.WriteFile(badFile, []byte("not a key"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, test := range []struct {
+		input     string
+		shouldErr bool
+		paths     []string
+		leeway    time.Duration
+	}{
+		{"jwt_auth {\n\tsecret s\n}", false, []string{"/"}, 0},
+		{"jwt_auth /a /b {\n\tsecret s\n\tpath /c\n\tleeway 30s\n}", false, []string{"/a", "/b", "/c"}, 30 * time.Second},
+		{"jwt_auth /api {\n\tpublic_key " + keyFile + "\n}", false, []string{"/api"}, 0},
+		{"jwt_auth /api {\n\tpublic_key " + badFile + "\n}", true, nil, 0},
+		{"jwt_auth /api {\n\tpublic_key " + keyFile + ".missing\n}", true, nil, 0},
+		{"jwt_auth /api {\n\tsecret s\n\tpublic_key " + keyFile + "\n}", true, nil, 0},
+		{"jwt_auth /api", true, nil, 0},
+		{"jwt_auth /api {\n\tsecret\n}", true, nil, 0},
+		{"jwt_auth /api {\n\tsecret a b\n}", true, nil, 0},
+		{"jwt_auth /api {\n\tsecret s\n\tleeway -1s\n}", true, nil, 0},
+		{"jwt_auth /api {\n\tsecret s\n\tpath\n}", true, nil, 0},
+		{"jwt_auth /api {\n\tsecret s\n\talgorithm none\n}", true, nil, 0},
+	} {
+		rules, err := jwtParse(caddy.NewTestController("http", test.input))
+		if err == nil && test.shouldErr {
+			t.Errorf("Test %d didn't error, but it should have", i)
+		} else if err != nil && !test.shouldErr {
+			t.Errorf("Test %d errored, but it shouldn't have; got '%v'", i, err)
+		}
+		if test.shouldErr {
+			continue
+		}
+		if len(rules) != 1 {
+			t.Fatalf("Test %d: Expected 1 rule, got %d", i, len(rules))
+		}
+		if got, want := len(rules[0].Paths), len(test.paths); got != want {
+			t.Errorf("Test %d: Expected paths %v, got %v", i, test.paths, rules[0].Paths)
+		} else {
+			for j := range test.paths {
+				if rules[0].Paths[j] != test.paths[j] {
+					t.Errorf("Test %d: Expected paths %v, got %v", i, test.paths, rules[0].Paths)
+				}
+			}
+		}
+		if rules[0].Leeway != test.leeway {
+			t.Errorf("Test %d: Expected leeway %s, got %s", i, test.leeway, rules[0].Leeway)
+		}
+	}
+}
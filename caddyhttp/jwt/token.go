@@ -0,0 +1,163 @@
+package jwt
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	// register the hash functions of the algorithms
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+)
+
+// hashes are the hash functions of the supported algorithms, by
+// the size in bits in the algorithm name (the 256 of HS256).
+var hashes = map[string]crypto.Hash{
+	"256": crypto.SHA256,
+	"384": crypto.SHA384,
+	"512": crypto.SHA512,
+}
+
+// Validate checks the signature of token against the rule's key and
+// checks that it is not expired or not yet valid at now. It returns
+// the claims of the token, formatted as placeholder values. Tokens
+// signed with an algorithm that doesn't belong to the type of key,
+// including the unsigned "none", are rejected.
+func (r Rule) Validate(token string, now time.Time) (map[string]string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("header: %v", err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("signature: %v", err)
+	}
+	if err := r.verify(header.Alg, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("claims: %v", err)
+	}
+	if exp, ok, err := numericDate(claims, "exp"); err != nil {
+		return nil, err
+	} else if ok && !now.Before(exp.Add(r.Leeway)) {
+		return nil, errors.New("token is expired")
+	}
+	if nbf, ok, err := numericDate(claims, "nbf"); err != nil {
+		return nil, err
+	} else if ok && now.Before(nbf.Add(-r.Leeway)) {
+		return nil, errors.New("token is not valid yet")
+	}
+
+	values := make(map[string]string, len(claims))
+	for name, claim := range claims {
+		switch v := claim.(type) {
+		case string:
+			values[name] = v
+		case json.Number:
+			values[name] = v.String()
+		default:
+			b, err := json.Marshal(v)
+			if err != nil {
+				return nil, err
+			}
+			values[name] = string(b)
+		}
+	}
+	return values, nil
+}
+
+// verify checks that signature is the signature of signed made with
+// the algorithm alg and the rule's key.
+func (r Rule) verify(alg, signed string, signature []byte) error {
+	if len(alg) != 5 || hashes[alg[2:]] == 0 {
+		return fmt.Errorf("unsupported algorithm '%s'", alg)
+	}
+	hash := hashes[alg[2:]]
+
+	switch key := r.Key.(type) {
+	case []byte:
+		if alg[:2] != "HS" {
+			return fmt.Errorf("algorithm '%s' is not allowed with an HMAC secret", alg)
+		}
+		mac := hmac.New(hash.New, key)
+		mac.Write([]byte(signed))
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	case *rsa.PublicKey:
+		if alg[:2] != "RS" {
+			return fmt.Errorf("algorithm '%s' is not allowed with an RSA key", alg)
+		}
+		h := hash.New()
+		h.Write([]byte(signed))
+		if err := rsa.VerifyPKCS1v15(key, hash, h.Sum(nil), signature); err != nil {
+			return errors.New("invalid signature")
+		}
+		return nil
+	case *ecdsa.PublicKey:
+		if alg[:2] != "ES" {
+			return fmt.Errorf("algorithm '%s' is not allowed with an ECDSA key", alg)
+		}
+		size := (key.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return errors.New("invalid signature")
+		}
+		h := hash.New()
+		h.Write([]byte(signed))
+		rs, ss := new(big.Int).SetBytes(signature[:size]), new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(key, h.Sum(nil), rs, ss) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported key type %T", r.Key)
+}
+
+// decodeSegment decodes the base64url encoded JSON segment s into v.
+func decodeSegment(s string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+// numericDate returns the time of the NumericDate claim name.
+// ok is false if there is no such claim.
+func numericDate(claims map[string]interface{}, name string) (t time.Time, ok bool, err error) {
+	claim, ok := claims[name]
+	if !ok {
+		return t, false, nil
+	}
+	n, isNumber := claim.(json.Number)
+	if !isNumber {
+		return t, false, fmt.Errorf("claim '%s' is not a number", name)
+	}
+	seconds, err := n.Float64()
+	if err != nil {
+		return t, false, fmt.Errorf("claim '%s': %v", name, err)
+	}
+	return time.Unix(int64(seconds), 0), true, nil
+}
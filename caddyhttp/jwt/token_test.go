@@ -0,0 +1,141 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"testing"
+	"time"
+)
+
+var testSecret = []byte("secret")
+
+// makeToken returns a token with the header and claims JSON,
+// signed by sign.
+func makeToken(header, claims string, sign func(signed string) []byte) string {
+	signed := base64.RawURLEncoding.EncodeToString([]byte(header)) + "." +
+		base64.RawURLEncoding.EncodeToString([]byte(claims))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sign(signed))
+}
+
+func hs256(secret []byte) func(string) []byte {
+	return func(signed string) []byte {
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(signed))
+		return mac.Sum(nil)
+	}
+}
+
+func unsigned(string) []byte { return nil }
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1500000000, 0)
+	rule := Rule{Key: testSecret, Leeway: 30 * time.Second}
+
+	for i, test := range []struct {
+		token     string
+		shouldErr bool
+	}{
+		{makeToken(`{"alg":"HS256","typ":"JWT"}`, `{"sub":"1"}`, hs256(testSecret)), false},
+		{makeToken(`{"alg":"HS256"}`, `{"exp":1500000060,"nbf":1499999940}`, hs256(testSecret)), false},
+		// expired, but within the leeway
+		{makeToken(`{"alg":"HS256"}`, `{"exp":1499999990}`, hs256(testSecret)), false},
+		{makeToken(`{"alg":"HS256"}`, `{"exp":1499999900}`, hs256(testSecret)), true},
+		// not valid yet, but within the leeway
+		{makeToken(`{"alg":"HS256"}`, `{"nbf":1500000010}`, hs256(testSecret)), false},
+		{makeToken(`{"alg":"HS256"}`, `{"nbf":1500000100}`, hs256(testSecret)), true},
+		{makeToken(`{"alg":"HS256"}`, `{"exp":"tomorrow"}`, hs256(testSecret)), true},
+		{makeToken(`{"alg":"HS256"}`, `{"sub":"1"}`, hs256([]byte("wrong"))), true},
+		{makeToken(`{"alg":"none"}`, `{"sub":"1"}`, unsigned), true},
+		{makeToken(`{"alg":"None"}`, `{"sub":"1"}`, unsigned), true},
+		{makeToken(`{"alg":"RS256"}`, `{"sub":"1"}`, hs256(testSecret)), true},
+		{makeToken(`{"alg":"HS257"}`, `{"sub":"1"}`, hs256(testSecret)), true},
+		{makeToken(`{}`, `{"sub":"1"}`, hs256(testSecret)), true},
+		{makeToken(`not json`, `{"sub":"1"}`, hs256(testSecret)), true},
+		{"a.b", true},
+		{"", true},
+	} {
+		_, err := rule.Validate(test.token, now)
+		if err == nil && test.shouldErr {
+			t.Errorf("Test %d didn't error, but it should have", i)
+		} else if err != nil && !test.shouldErr {
+			t.Errorf("Test %d errored, but it shouldn't have; got '%v'", i, err)
+		}
+	}
+}
+
+func TestValidateClaims(t *testing.T) {
+	token := makeToken(`{"alg":"HS256"}`, `{"sub":"user","admin":true,"n":1234567890,"roles":["a","b"]}`, hs256(testSecret))
+	claims, err := Rule{Key: testSecret}.Validate(token, time.Now())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for name, expected := range map[string]string{
+		"sub":   "user",
+		"admin": "true",
+		"n":     "1234567890",
+		"roles": `["a","b"]`,
+	} {
+		if claims[name] != expected {
+			t.Errorf("Expected claim %s to be %s, got %s", name, expected, claims[name])
+		}
+	}
+}
+
+func TestValidatePublicKeys(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs256 := func(signed string) []byte {
+		sum := sha256.Sum256([]byte(signed))
+		sig, err := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, sum[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sig
+	}
+	es256 := func(signed string) []byte {
+		sum := sha256.Sum256([]byte(signed))
+		r, s, err := ecdsa.Sign(rand.Reader, ecKey, sum[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig := make([]byte, 64)
+		rb, sb := r.Bytes(), s.Bytes()
+		copy(sig[32-len(rb):], rb)
+		copy(sig[64-len(sb):], sb)
+		return sig
+	}
+	rsaPublic := &rsaKey.PublicKey
+
+	for i, test := range []struct {
+		key       interface{}
+		token     string
+		shouldErr bool
+	}{
+		{rsaPublic, makeToken(`{"alg":"RS256"}`, `{}`, rs256), false},
+		{&ecKey.PublicKey, makeToken(`{"alg":"ES256"}`, `{}`, es256), false},
+		{rsaPublic, makeToken(`{"alg":"ES256"}`, `{}`, es256), true},
+		{&ecKey.PublicKey, makeToken(`{"alg":"RS256"}`, `{}`, rs256), true},
+		{rsaPublic, makeToken(`{"alg":"none"}`, `{}`, unsigned), true},
+		// the public key used as an HMAC secret
+		{rsaPublic, makeToken(`{"alg":"HS256"}`, `{}`, hs256(rsaPublic.N.Bytes())), true},
+	} {
+		_, err := Rule{Key: test.key}.Validate(test.token, time.Now())
+		if err == nil && test.shouldErr {
+			t.Errorf("Test %d didn't error, but it should have", i)
+		} else if err != nil && !test.shouldErr {
+			t.Errorf("Test %d errored, but it shouldn't have; got '%v'", i, err)
+		}
+	}
+}
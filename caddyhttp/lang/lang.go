@@ -0,0 +1,104 @@
+// Package lang is middleware that picks the language of the
+// response from the Accept-Language request header and makes it
+// available as the {lang} placeholder, for rewriting or
+// redirecting requests to localized content.
+package lang
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+	"github.com/mholt/caddy/caddyhttp/staticfiles"
+)
+
+// Lang is middleware that sets the {lang} placeholder to the
+// supported language that the client prefers, or to Default if
+// the client accepts none of them.
+type Lang struct {
+	Next      httpserver.Handler
+	Languages []string // language tags, in order of the site's preference
+	Default   string
+}
+
+// ServeHTTP implements the httpserver.Handler interface.
+func (l Lang) ServeHTTP(w http.ResponseWriter, r *http.Request) (int, error) {
+	lang := Negotiate(r.Header.Get("Accept-Language"), l.Languages)
+	if lang == "" {
+		lang = l.Default
+	}
+	httpserver.SetPlaceholder(r, "lang", lang)
+	w.Header().Add("Vary", "Accept-Language")
+	return l.Next.ServeHTTP(w, r)
+}
+
+// languageRange is a language range of an Accept-Language header.
+type languageRange struct {
+	tag string // lower-cased; "*" matches any language
+	q   float64
+}
+
+// Negotiate returns the language of supported that is most preferred
+// by the Accept-Language header value accept, or "" if none of them
+// is acceptable. Language ranges match languages as in the basic
+// filtering of RFC 4647: a range matches a language if it is the same
+// as the language or a prefix of it followed by "-", so "en" matches
+// "en-US" but not the other way around. A language gets the quality
+// of the most specific range that matches it, so "de;q=0" excludes
+// German even if "*" is acceptable. Among languages with the same
+// quality, the one matched by the earlier range wins, and after that
+// the one that comes first in supported.
+func Negotiate(accept string, supported []string) string {
+	ranges := parseAcceptLanguage(accept)
+
+	var best string
+	var bestQ float64
+	bestPos := len(ranges)
+	for _, lang := range supported {
+		q, pos, ok := quality(ranges, strings.ToLower(lang))
+		if !ok || q <= 0 {
+			continue
+		}
+		if q > bestQ || (q == bestQ && pos < bestPos) {
+			best, bestQ, bestPos = lang, q, pos
+		}
+	}
+	return best
+}
+
+// quality returns the quality of lang, which must be lower-cased,
+// according to the most specific of ranges that matches it, and
+// the position of that range. ok is false if no range matches.
+func quality(ranges []languageRange, lang string) (q float64, pos int, ok bool) {
+	specificity := -1
+	for i, lr := range ranges {
+		var s int
+		switch {
+		case lr.tag == "*":
+			s = 0
+		case lr.tag == lang || strings.HasPrefix(lang, lr.tag+"-"):
+			s = len(lr.tag)
+		default:
+			continue
+		}
+		if s > specificity {
+			specificity, q, pos, ok = s, lr.q, i, true
+		}
+	}
+	return q, pos, ok
+}
+
+// parseAcceptLanguage parses the language ranges of the
+// Accept-Language header value accept. Ranges without a valid
+// quality value are ignored.
+func parseAcceptLanguage(accept string) []languageRange {
+	var ranges []languageRange
+	for _, part := range strings.Split(accept, ",") {
+		tag, q, ok := staticfiles.ParseQValue(part)
+		if tag == "" || !ok {
+			continue
+		}
+		ranges = append(ranges, languageRange{tag: tag, q: q})
+	}
+	return ranges
+}
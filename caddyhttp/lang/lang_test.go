@@ -0,0 +1,89 @@
+package lang
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func TestNegotiate(t *testing.T) {
+	supported := []string{"en", "fr", "de-CH"}
+
+	for i, test := range []struct {
+		accept   string
+		expected string
+	}{
+		// straightforward matches
+		{"fr", "fr"},
+		{"FR", "fr"},
+		{"de-CH", "de-CH"},
+		// a range matches longer tags, but not shorter ones
+		{"de", "de-CH"},
+		{"en-US", ""},
+		{"en-US, en;q=0.8", "en"},
+		// q-values
+		{"fr;q=0.5, en;q=0.9", "en"},
+		{"fr, en", "fr"},
+		{"en, fr", "en"},
+		{"es, fr;q=0.2", "fr"},
+		// q=0 excludes, even if a wildcard is acceptable
+		{"en;q=0, *", "fr"},
+		{"*;q=0.1, fr;q=0", "en"},
+		{"fr;q=0", ""},
+		// wildcard, unknown or malformed input
+		{"*", "en"},
+		{"es, it", ""},
+		{"", ""},
+		{"fr;q=2, en;q=0.1", "en"},
+		{" , de ; q=0.7 ", "de-CH"},
+	} {
+		if got := Negotiate(test.accept, supported); got != test.expected {
+			t.Errorf("Test %d: Expected %q for %q, got %q", i, test.expected, test.accept, got)
+		}
+	}
+}
+
+func TestLang(t *testing.T) {
+	var lang string
+	l := Lang{
+		Next: httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+			lang = httpserver.NewReplacer(r, nil, "").Replace("{lang}")
+			return http.StatusOK, nil
+		}),
+		Languages: []string{"en", "fr", "nl"},
+		Default:   "nl",
+	}
+
+	for i, test := range []struct {
+		accept   string
+		expected string
+	}{
+		{"fr-CA, fr;q=0.9, en;q=0.8", "fr"},
+		{"en;q=0.3, nl;q=0.6", "nl"},
+		{"", "nl"},   // no header, default
+		{"ja", "nl"}, // nothing acceptable, default
+	} {
+		r, err := http.NewRequest("GET", "/", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = r.WithContext(context.WithValue(r.Context(), httpserver.PlaceholdersCtxKey, make(map[string]string)))
+		if test.accept != "" {
+			r.Header.Set("Accept-Language", test.accept)
+		}
+		w := httptest.NewRecorder()
+
+		if _, err := l.ServeHTTP(w, r); err != nil {
+			t.Fatalf("Test %d: %v", i, err)
+		}
+		if lang != test.expected {
+			t.Errorf("Test %d: Expected {lang} %q, got %q", i, test.expected, lang)
+		}
+		if got := w.Header().Get("Vary"); got != "Accept-Language" {
+			t.Errorf("Test %d: Expected Vary: Accept-Language, got %q", i, got)
+		}
+	}
+}
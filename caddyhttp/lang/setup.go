@@ -0,0 +1,74 @@
+package lang
+
+import (
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func init() {
+	caddy.RegisterPlugin("lang", caddy.Plugin{
+		ServerType: "http",
+		Action:     setup,
+	})
+}
+
+// setup configures a new Lang middleware instance.
+func setup(c *caddy.Controller) error {
+	l, err := langParse(c)
+	if err != nil {
+		return err
+	}
+
+	httpserver.GetConfig(c).AddMiddleware(func(next httpserver.Handler) httpserver.Handler {
+		l.Next = next
+		return l
+	})
+
+	return nil
+}
+
+// langParse parses the directive:
+//
+//	lang languages... {
+//		default language
+//	}
+//
+// The default language, used when the client accepts none of
+// the languages, defaults to the first one.
+func langParse(c *caddy.Controller) (Lang, error) {
+	var l Lang
+	var parsed bool
+
+	for c.Next() {
+		if parsed {
+			return l, c.Err("lang can only be specified once per site")
+		}
+		parsed = true
+
+		l.Languages = c.RemainingArgs()
+		if len(l.Languages) == 0 {
+			return l, c.ArgErr()
+		}
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "default":
+				if !c.NextArg() {
+					return l, c.ArgErr()
+				}
+				l.Default = c.Val()
+			default:
+				return l, c.Errf("unknown subdirective '%s'", c.Val())
+			}
+			if c.NextArg() {
+				return l, c.ArgErr()
+			}
+		}
+
+		if l.Default == "" {
+			l.Default = l.Languages[0]
+		}
+	}
+
+	return l, nil
+}
@@ -0,0 +1,66 @@
+package lang
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func TestSetup(t *testing.T) {
+	c := caddy.NewTestController("http", `lang en fr`)
+	err := setup(c)
+	if err != nil {
+		t.Errorf("Expected no errors, got: %v", err)
+	}
+	mids := httpserver.GetConfig(c).Middleware()
+	if len(mids) == 0 {
+		t.Fatal("Expected middleware, got 0 instead")
+	}
+
+	handler := mids[0](httpserver.EmptyNext)
+	myHandler, ok := handler.(Lang)
+	if !ok {
+		t.Fatalf("Expected handler to be type Lang, got: %#v", handler)
+	}
+	if !httpserver.SameNext(myHandler.Next, httpserver.EmptyNext) {
+		t.Error("'Next' field of handler was not set properly")
+	}
+}
+
+func TestLangParse(t *testing.T) {
+	for i, test := range []struct {
+		input     string
+		shouldErr bool
+		languages []string
+		def       string
+	}{
+		{`lang en`, false, []string{"en"}, "en"},
+		{`lang en fr de`, false, []string{"en", "fr", "de"}, "en"},
+		{"lang en fr {\n\tdefault fr\n}", false, []string{"en", "fr"}, "fr"},
+		{`lang`, true, nil, ""},
+		{"lang en {\n\tdefault\n}", true, nil, ""},
+		{"lang en {\n\tdefault en fr\n}", true, nil, ""},
+		{"lang en {\n\tfallback en\n}", true, nil, ""},
+		{"lang en\nlang fr", true, nil, ""},
+	} {
+		l, err := langParse(caddy.NewTestController("http", test.input))
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: Expected an error, but did not have one", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: Did not expect error, but got: %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(l.Languages, test.languages) {
+			t.Errorf("Test %d: Expected languages %v, got %v", i, test.languages, l.Languages)
+		}
+		if l.Default != test.def {
+			t.Errorf("Test %d: Expected default %q, got %q", i, test.def, l.Default)
+		}
+	}
+}
@@ -2,8 +2,11 @@
 package log
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/mholt/caddy"
 	"github.com/mholt/caddy/caddyhttp/httpserver"
@@ -26,6 +29,8 @@ type Logger struct {
 func (l Logger) ServeHTTP(w http.ResponseWriter, r *http.Request) (int, error) {
 	for _, rule := range l.Rules {
 		if httpserver.Path(r.URL.Path).Matches(rule.PathScope) {
+			start := time.Now()
+
 			// Record the response
 			responseRecorder := httpserver.NewResponseRecorder(w)
 
@@ -52,7 +57,15 @@ func (l Logger) ServeHTTP(w http.ResponseWriter, r *http.Request) (int, error) {
 
 			// Write log entries
 			for _, e := range rule.Entries {
-				e.Log.Println(rep.Replace(e.Format))
+				if !e.Sampler.Sample(responseRecorder.Status()) {
+					continue
+				}
+				entryRep := e.Redact.Replacer(rep, r, responseRecorder, CommonLogEmptyValue)
+				if e.Format == JSONLogFormat {
+					e.Log.Println(jsonEntry(entryRep, time.Since(start)))
+				} else {
+					e.Log.Println(entryRep.Replace(e.Format))
+				}
 			}
 
 			return status, err
@@ -61,10 +74,56 @@ func (l Logger) ServeHTTP(w http.ResponseWriter, r *http.Request) (int, error) {
 	return l.Next.ServeHTTP(w, r)
 }
 
+// jsonEntry returns the JSON object that is logged for a request
+// when the entry format is JSONLogFormat. Fields are filled in
+// from placeholders, so values set by other middleware apply;
+// the empty value becomes an empty string (or 0 for numbers).
+func jsonEntry(rep httpserver.Replacer, duration time.Duration) string {
+	field := func(placeholder string) string {
+		if v := rep.Replace(placeholder); v != CommonLogEmptyValue {
+			return v
+		}
+		return ""
+	}
+	status, _ := strconv.Atoi(field("{status}"))
+	size, _ := strconv.Atoi(field("{size}"))
+
+	entry, err := json.Marshal(struct {
+		Timestamp string  `json:"timestamp"`
+		Remote    string  `json:"remote"`
+		Method    string  `json:"method"`
+		Host      string  `json:"host"`
+		URI       string  `json:"uri"`
+		Proto     string  `json:"proto"`
+		Status    int     `json:"status"`
+		Size      int     `json:"size"`
+		Duration  float64 `json:"duration"`
+		UserAgent string  `json:"user_agent"`
+	}{
+		Timestamp: field("{when_iso}"),
+		Remote:    field("{remote}"),
+		Method:    field("{method}"),
+		Host:      field("{host}"),
+		URI:       field("{uri}"),
+		Proto:     field("{proto}"),
+		Status:    status,
+		Size:      size,
+		Duration:  duration.Seconds(),
+		UserAgent: field("{>User-Agent}"),
+	})
+	if err != nil {
+		// only strings and numbers; this can't happen
+		return fmt.Sprintf(`{"error": %q}`, err.Error())
+	}
+	return string(entry)
+}
+
 // Entry represents a log entry under a path scope
 type Entry struct {
-	Format string
-	Log    *httpserver.Logger
+	Format  string
+	Log     *httpserver.Logger
+	Sampler *Sampler
+	Redact  *Redaction
 }
 
 // Rule configures the logging middleware.
@@ -84,4 +143,7 @@ const (
 	CombinedLogFormat = CommonLogFormat + ` "{>Referer}" "{>User-Agent}"`
 	// DefaultLogFormat is the default log format.
 	DefaultLogFormat = CommonLogFormat
+	// JSONLogFormat is the format of entries that are logged as
+	// JSON objects (one per line) instead of templated text.
+	JSONLogFormat = "{json}"
 )
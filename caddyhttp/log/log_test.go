@@ -2,10 +2,13 @@ package log
 
 import (
 	"bytes"
+	"encoding/json"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 
 	"github.com/mholt/caddy/caddyhttp/httpserver"
@@ -63,6 +66,53 @@ func TestLoggedStatus(t *testing.T) {
 	}
 }
 
+func TestLoggedJSON(t *testing.T) {
+	var f bytes.Buffer
+	logger := Logger{
+		Rules: []*Rule{{
+			PathScope: "/",
+			Entries: []*Entry{{
+				Format: JSONLogFormat,
+				Log:    httpserver.NewTestLogger(&f),
+			}},
+		}},
+		Next: httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+			w.WriteHeader(http.StatusTeapot)
+			w.Write([]byte("short and stout"))
+			return 0, nil
+		}),
+	}
+
+	r, err := http.NewRequest("POST", "/pot?brew=1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set("User-Agent", "Teapot/1.0")
+
+	if _, err := logger.ServeHTTP(httptest.NewRecorder(), r); err != nil {
+		t.Fatalf("Expected error to be nil, instead got: %v", err)
+	}
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(f.Bytes(), &entry); err != nil {
+		t.Fatalf("Expected log entry to be a JSON object, got error %v: %s", err, f.String())
+	}
+	for field, want := range map[string]interface{}{
+		"method":     "POST",
+		"uri":        "/pot?brew=1",
+		"user_agent": "Teapot/1.0",
+		"status":     float64(http.StatusTeapot),
+		"size":       float64(len("short and stout")),
+	} {
+		if got := entry[field]; got != want {
+			t.Errorf("Expected %s to be %#v, but was %#v", field, want, got)
+		}
+	}
+	if _, ok := entry["duration"].(float64); !ok {
+		t.Errorf("Expected duration to be a number, but was %#v", entry["duration"])
+	}
+}
+
 func TestLogRequestBody(t *testing.T) {
 	var got bytes.Buffer
 	logger := Logger{
@@ -163,3 +213,82 @@ func TestMultiEntries(t *testing.T) {
 		t.Errorf("Expected %q, but got %q", expect, got)
 	}
 }
+
+func TestLogSampling(t *testing.T) {
+	for i, test := range []struct {
+		sampler  *Sampler
+		statuses []int
+		min, max int // expected number of logged lines
+	}{
+		{nil, repeatStatus(http.StatusOK, 100), 100, 100},
+		{&Sampler{Every: 1}, repeatStatus(http.StatusOK, 100), 100, 100},
+		{&Sampler{Every: 10}, repeatStatus(http.StatusOK, 100), 10, 10},
+		{&Sampler{Every: 3}, repeatStatus(http.StatusOK, 10), 4, 4},
+		{&Sampler{Percent: 100}, repeatStatus(http.StatusOK, 100), 100, 100},
+		{&Sampler{Percent: 25}, repeatStatus(http.StatusOK, 4000), 800, 1200},
+		{&Sampler{Every: 10}, repeatStatus(http.StatusNotFound, 100), 10, 10},
+		{&Sampler{Every: 10, KeepErrors: true}, repeatStatus(http.StatusNotFound, 100), 100, 100},
+		{&Sampler{Percent: 1, KeepErrors: true}, repeatStatus(http.StatusBadGateway, 100), 100, 100},
+		{&Sampler{Every: 100, KeepErrors: true},
+			append(repeatStatus(http.StatusOK, 50), repeatStatus(http.StatusInternalServerError, 5)...), 6, 6},
+	} {
+		var f bytes.Buffer
+		logger := Logger{
+			Rules: []*Rule{{
+				PathScope: "/",
+				Entries: []*Entry{{
+					Format:  "{status}",
+					Log:     httpserver.NewTestLogger(&f),
+					Sampler: test.sampler,
+				}},
+			}},
+		}
+
+		for _, status := range test.statuses {
+			status := status
+			logger.Next = httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+				w.WriteHeader(status)
+				return 0, nil
+			})
+			r, err := http.NewRequest("GET", "/", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			logger.ServeHTTP(httptest.NewRecorder(), r)
+		}
+
+		logged := strings.Count(f.String(), "\n")
+		if logged < test.min || logged > test.max {
+			t.Errorf("Test %d: Expected between %d and %d logged lines, got %d", i, test.min, test.max, logged)
+		}
+	}
+}
+
+func TestSamplerConcurrent(t *testing.T) {
+	sampler := &Sampler{Every: 8}
+	var logged uint64
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if sampler.Sample(http.StatusOK) {
+					atomic.AddUint64(&logged, 1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if logged != 200 {
+		t.Errorf("Expected 200 of 1600 requests to be sampled, got %d", logged)
+	}
+}
+
+func repeatStatus(status, n int) []int {
+	statuses := make([]int, n)
+	for i := range statuses {
+		statuses[i] = status
+	}
+	return statuses
+}
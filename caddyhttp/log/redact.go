@@ -0,0 +1,190 @@
+package log
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+// RedactedValue replaces the value of redacted query
+// parameters and headers in log entries.
+const RedactedValue = "REDACTED"
+
+// Redaction lists the query parameters and request headers
+// whose values must not appear in a log entry. Names may
+// contain * as a wildcard, so "api_*" matches "api_key".
+// Header names are matched case-insensitively.
+type Redaction struct {
+	Query   []string
+	Headers []string
+}
+
+// redactedPlaceholders are the placeholders that are
+// filled in from a redacted copy of the request.
+var redactedPlaceholders = map[string]struct{}{
+	"{uri}":           {},
+	"{uri_escaped}":   {},
+	"{query}":         {},
+	"{query_escaped}": {},
+	"{path}":          {},
+	"{path_escaped}":  {},
+	"{request}":       {},
+}
+
+// redactedPrefixes are the prefixes of the placeholders, like
+// {query.name}, that are filled in from a redacted copy of the
+// request as well. Cookies are in the Cookie header, so if it is
+// redacted, {cookie.name} is empty.
+var redactedPrefixes = []string{"{>", "{query.", "{cookie."}
+
+// redacted reports whether placeholder is filled in from a
+// redacted copy of the request.
+func redacted(placeholder string) bool {
+	if _, ok := redactedPlaceholders[placeholder]; ok {
+		return true
+	}
+	for _, prefix := range redactedPrefixes {
+		if strings.HasPrefix(placeholder, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Replacer returns a replacer for log entries that fills in
+// request placeholders from a redacted copy of r. The other
+// placeholders, including the ones set by other middleware,
+// are left to rep. If rd is nil, rep is returned as is.
+func (rd *Redaction) Replacer(rep httpserver.Replacer, r *http.Request, rr *httpserver.ResponseRecorder, emptyValue string) httpserver.Replacer {
+	if rd == nil {
+		return rep
+	}
+	return redactingReplacer{
+		Replacer: rep,
+		redacted: httpserver.NewReplacer(rd.request(r), rr, emptyValue),
+	}
+}
+
+// request returns a copy of r with the values of redacted
+// query parameters and headers replaced. r is not modified.
+func (rd *Redaction) request(r *http.Request) *http.Request {
+	r2 := new(http.Request)
+	*r2 = *r
+	r2.Body = nil // not read through the redacted copy
+
+	u := *r.URL
+	u.RawQuery = rd.query(u.RawQuery)
+	r2.URL = &u
+
+	r2.Header = make(http.Header, len(r.Header))
+	for name, values := range r.Header {
+		if rd.header(name) {
+			values = []string{RedactedValue}
+		} else if name == http.CanonicalHeaderKey("Caddy-Rewrite-Original-URI") {
+			// the URI from before a rewrite has a query string too
+			values = []string{rd.uri(r.Header.Get(name))}
+		}
+		r2.Header[name] = values
+	}
+	return r2
+}
+
+// query redacts the values of matching parameters in the raw
+// query string, keeping the order and encoding of the others.
+func (rd *Redaction) query(rawQuery string) string {
+	if len(rd.Query) == 0 || rawQuery == "" {
+		return rawQuery
+	}
+	params := strings.Split(rawQuery, "&")
+	for i, param := range params {
+		key := param
+		if eq := strings.Index(param, "="); eq >= 0 {
+			key = param[:eq]
+		}
+		name, err := url.QueryUnescape(key)
+		if err != nil {
+			name = key
+		}
+		for _, pattern := range rd.Query {
+			if matchName(pattern, name) {
+				params[i] = key + "=" + RedactedValue
+				break
+			}
+		}
+	}
+	return strings.Join(params, "&")
+}
+
+// uri redacts the query string of a request URI.
+func (rd *Redaction) uri(uri string) string {
+	if q := strings.Index(uri, "?"); q >= 0 {
+		return uri[:q+1] + rd.query(uri[q+1:])
+	}
+	return uri
+}
+
+// header reports whether the value of header name is redacted.
+func (rd *Redaction) header(name string) bool {
+	for _, pattern := range rd.Headers {
+		if matchName(strings.ToLower(pattern), strings.ToLower(name)) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchName reports whether name matches pattern, in which
+// * matches any sequence of characters.
+func matchName(pattern, name string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == name
+	}
+	if !strings.HasPrefix(name, parts[0]) {
+		return false
+	}
+	name = name[len(parts[0]):]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(name, part)
+		if i < 0 {
+			return false
+		}
+		name = name[i+len(part):]
+	}
+	return strings.HasSuffix(name, parts[len(parts)-1])
+}
+
+// redactingReplacer fills in the request placeholders that
+// could reveal redacted values with redacted, and all other
+// placeholders with the embedded Replacer.
+type redactingReplacer struct {
+	httpserver.Replacer
+	redacted httpserver.Replacer
+}
+
+// Replace replaces each placeholder in s.
+func (r redactingReplacer) Replace(s string) string {
+	var result string
+	for {
+		start := strings.Index(s, "{")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(s[start:], "}")
+		if end < 0 {
+			break
+		}
+		end += start + 1
+
+		placeholder := s[start:end]
+		if redacted(placeholder) {
+			result += s[:start] + r.redacted.Replace(placeholder)
+		} else {
+			result += s[:start] + r.Replacer.Replace(placeholder)
+		}
+		s = s[end:]
+	}
+	return result + s
+}
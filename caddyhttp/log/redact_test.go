@@ -0,0 +1,140 @@
+package log
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func TestLogRedaction(t *testing.T) {
+	var f bytes.Buffer
+	logger := Logger{
+		Rules: []*Rule{{
+			PathScope: "/",
+			Entries: []*Entry{{
+				Format: `{uri} {query} "{>Authorization}" "{>X-Api-Key}" {>Accept} {testval} {status}`,
+				Log:    httpserver.NewTestLogger(&f),
+				Redact: &Redaction{
+					Query:   []string{"token", "api_*"},
+					Headers: []string{"authorization", "X-Api-*"},
+				},
+			}},
+		}},
+		Next: httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+			if rr, ok := w.(*httpserver.ResponseRecorder); ok {
+				rr.Replacer.Set("testval", "foobar")
+			}
+			w.WriteHeader(http.StatusOK)
+			return 0, nil
+		}),
+	}
+
+	r, err := http.NewRequest("GET", "/search?q=caddy&token=s3cret&api_key=k3y&page=2", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set("Authorization", "Bearer s3cret")
+	r.Header.Set("X-Api-Key", "k3y")
+	r.Header.Set("Accept", "text/html")
+
+	if _, err := logger.ServeHTTP(httptest.NewRecorder(), r); err != nil {
+		t.Fatalf("Expected error to be nil, instead got: %v", err)
+	}
+
+	expect := `/search?q=caddy&token=REDACTED&api_key=REDACTED&page=2 q=caddy&token=REDACTED&api_key=REDACTED&page=2 "REDACTED" "REDACTED" text/html foobar 200`
+	if got := strings.TrimSpace(f.String()); got != expect {
+		t.Errorf("Expected log entry\n%s\nbut got\n%s", expect, got)
+	}
+	if strings.Contains(f.String(), "s3cret") || strings.Contains(f.String(), "k3y") {
+		t.Errorf("Expected secrets not to be logged, but got: %s", f.String())
+	}
+
+	// the request itself must be left alone
+	if got := r.URL.RawQuery; got != "q=caddy&token=s3cret&api_key=k3y&page=2" {
+		t.Errorf("Expected request query to be unchanged, but got %s", got)
+	}
+	if got := r.Header.Get("Authorization"); got != "Bearer s3cret" {
+		t.Errorf("Expected Authorization header to be unchanged, but got %s", got)
+	}
+}
+
+func TestRedactionRewrittenURI(t *testing.T) {
+	rd := &Redaction{Query: []string{"token"}}
+	r, err := http.NewRequest("GET", "/index.php?page=1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set("Caddy-Rewrite-Original-URI", "/page?token=s3cret")
+
+	rep := rd.Replacer(httpserver.NewReplacer(r, nil, "-"), r, nil, "-")
+	if got, expect := rep.Replace("{path}"), "/page?token=REDACTED"; got != expect {
+		t.Errorf("Expected %s, got %s", expect, got)
+	}
+	if got := r.Header.Get("Caddy-Rewrite-Original-URI"); got != "/page?token=s3cret" {
+		t.Errorf("Expected original URI header to be unchanged, but got %s", got)
+	}
+}
+
+func TestRedactionQueryPlaceholders(t *testing.T) {
+	rd := &Redaction{Query: []string{"token", "api_*"}}
+	r, err := http.NewRequest("GET", "/search?q=caddy&token=s3cret&api_key=k3y", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rep := rd.Replacer(httpserver.NewReplacer(r, nil, "-"), r, nil, "-")
+	got := rep.Replace("{query.q} {query.token} {query.api_key} {query.missing}")
+	if expect := "caddy REDACTED REDACTED -"; got != expect {
+		t.Errorf("Expected %s, got %s", expect, got)
+	}
+}
+
+func TestRedactionCookiePlaceholders(t *testing.T) {
+	r, err := http.NewRequest("GET", "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.AddCookie(&http.Cookie{Name: "session", Value: "s3cret"})
+	r.AddCookie(&http.Cookie{Name: "theme", Value: "dark"})
+
+	for i, test := range []struct {
+		rd     *Redaction
+		expect string
+	}{
+		{&Redaction{Headers: []string{"Cookie"}}, "- - REDACTED"},
+		{&Redaction{Headers: []string{"Authorization"}}, "s3cret dark session=s3cret; theme=dark"},
+	} {
+		rep := test.rd.Replacer(httpserver.NewReplacer(r, nil, "-"), r, nil, "-")
+		if got := rep.Replace("{cookie.session} {cookie.theme} {>Cookie}"); got != test.expect {
+			t.Errorf("Test %d: Expected %s, got %s", i, test.expect, got)
+		}
+	}
+}
+
+func TestMatchName(t *testing.T) {
+	for i, test := range []struct {
+		pattern, name string
+		expect        bool
+	}{
+		{"token", "token", true},
+		{"token", "tokens", false},
+		{"*", "anything", true},
+		{"api_*", "api_key", true},
+		{"api_*", "api_", true},
+		{"api_*", "myapi_key", false},
+		{"*_token", "access_token", true},
+		{"*_token", "access_token_id", false},
+		{"x-*-key", "x-api-key", true},
+		{"x-*-key", "x-key", false},
+		{"a*b*c", "aXbYc", true},
+		{"a*b*c", "acb", false},
+	} {
+		if got := matchName(test.pattern, test.name); got != test.expect {
+			t.Errorf("Test %d: matchName(%q, %q) = %v, expected %v", i, test.pattern, test.name, got, test.expect)
+		}
+	}
+}
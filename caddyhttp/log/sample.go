@@ -0,0 +1,42 @@
+package log
+
+import (
+	"math/rand"
+	"sync/atomic"
+)
+
+// Sampler decides which requests are written to a log, so that
+// busy sites can keep only a portion of their access log. A nil
+// Sampler logs every request.
+type Sampler struct {
+	count uint64 // first field, for 64-bit alignment of atomic ops
+
+	// Every logs one out of every Every requests; 1 logs all of
+	// them. It is ignored when Percent is set.
+	Every uint64
+
+	// Percent is the chance, from 0 to 100, that a request is logged.
+	Percent float64
+
+	// KeepErrors logs every response that does not have a 2xx
+	// status, regardless of the sample rate.
+	KeepErrors bool
+}
+
+// Sample reports whether a request that was answered with status
+// should be logged. It is safe for concurrent use.
+func (s *Sampler) Sample(status int) bool {
+	if s == nil {
+		return true
+	}
+	if s.KeepErrors && (status < 200 || status > 299) {
+		return true
+	}
+	if s.Percent > 0 {
+		return rand.Float64()*100 < s.Percent
+	}
+	if s.Every <= 1 {
+		return true
+	}
+	return (atomic.AddUint64(&s.count, 1)-1)%s.Every == 0
+}
@@ -1,6 +1,9 @@
 package log
 
 import (
+	"strconv"
+	"strings"
+
 	"github.com/mholt/caddy"
 	"github.com/mholt/caddy/caddyhttp/httpserver"
 )
@@ -34,8 +37,34 @@ func logParse(c *caddy.Controller) ([]*Rule, error) {
 		var logRoller *httpserver.LogRoller
 		logRoller = httpserver.DefaultLogRoller()
 
+		var sampler *Sampler
+		var redact *Redaction
+
 		for c.NextBlock() {
 			what := c.Val()
+			if what == "sample" {
+				var err error
+				sampler, err = parseSampler(c)
+				if err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if what == "redact_query" || what == "redact_header" {
+				names := c.RemainingArgs()
+				if len(names) == 0 {
+					return nil, c.ArgErr()
+				}
+				if redact == nil {
+					redact = new(Redaction)
+				}
+				if what == "redact_query" {
+					redact.Query = append(redact.Query, names...)
+				} else {
+					redact.Headers = append(redact.Headers, names...)
+				}
+				continue
+			}
 			if !c.NextArg() {
 				return nil, c.ArgErr()
 			}
@@ -57,7 +86,9 @@ func logParse(c *caddy.Controller) ([]*Rule, error) {
 					Output: DefaultLogFilename,
 					Roller: logRoller,
 				},
-				Format: DefaultLogFormat,
+				Format:  DefaultLogFormat,
+				Sampler: sampler,
+				Redact:  redact,
 			})
 		} else if len(args) == 1 {
 			// Only an output file specified
@@ -66,7 +97,9 @@ func logParse(c *caddy.Controller) ([]*Rule, error) {
 					Output: args[0],
 					Roller: logRoller,
 				},
-				Format: DefaultLogFormat,
+				Format:  DefaultLogFormat,
+				Sampler: sampler,
+				Redact:  redact,
 			})
 		} else {
 			// Path scope, output file, and maybe a format specified
@@ -89,7 +122,9 @@ func logParse(c *caddy.Controller) ([]*Rule, error) {
 					Output: args[1],
 					Roller: logRoller,
 				},
-				Format: format,
+				Format:  format,
+				Sampler: sampler,
+				Redact:  redact,
 			})
 		}
 	}
@@ -97,6 +132,44 @@ func logParse(c *caddy.Controller) ([]*Rule, error) {
 	return rules, nil
 }
 
+// parseSampler parses the arguments of the sample subdirective:
+//
+//	sample <rate> [keep_errors]
+//
+// where rate is either N, to log one in every N requests, or a
+// percentage of requests such as 25%.
+func parseSampler(c *caddy.Controller) (*Sampler, error) {
+	args := c.RemainingArgs()
+	if len(args) == 0 || len(args) > 2 {
+		return nil, c.ArgErr()
+	}
+
+	sampler := new(Sampler)
+	if len(args) == 2 {
+		if args[1] != "keep_errors" {
+			return nil, c.Errf("unknown sample option '%s'", args[1])
+		}
+		sampler.KeepErrors = true
+	}
+
+	rate := args[0]
+	if strings.HasSuffix(rate, "%") {
+		percent, err := strconv.ParseFloat(strings.TrimSuffix(rate, "%"), 64)
+		if err != nil || percent <= 0 || percent > 100 {
+			return nil, c.Errf("sample percentage must be greater than 0%% and at most 100%%, got '%s'", rate)
+		}
+		sampler.Percent = percent
+	} else {
+		every, err := strconv.ParseUint(rate, 10, 64)
+		if err != nil || every == 0 {
+			return nil, c.Errf("sample rate must be a positive integer or a percentage, got '%s'", rate)
+		}
+		sampler.Every = every
+	}
+
+	return sampler, nil
+}
+
 func appendEntry(rules []*Rule, pathScope string, entry *Entry) []*Rule {
 	for _, rule := range rules {
 		if rule.PathScope == pathScope {
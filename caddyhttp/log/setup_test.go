@@ -134,6 +134,16 @@ func TestLogParse(t *testing.T) {
 				Format: CombinedLogFormat,
 			}},
 		}}},
+		{`log /test accesslog.txt {json}`, false, []Rule{{
+			PathScope: "/test",
+			Entries: []*Entry{{
+				Log: &httpserver.Logger{
+					Output: "accesslog.txt",
+					Roller: httpserver.DefaultLogRoller(),
+				},
+				Format: JSONLogFormat,
+			}},
+		}}},
 		{`log /api1 log.txt
 		  log /api2 accesslog.txt {combined}`, false, []Rule{{
 			PathScope: "/api1",
@@ -188,6 +198,76 @@ func TestLogParse(t *testing.T) {
 				Format: DefaultLogFormat,
 			}},
 		}}},
+		{`log access.log {
+			sample 10
+		  }`, false, []Rule{{
+			PathScope: "/",
+			Entries: []*Entry{{
+				Log: &httpserver.Logger{
+					Output: "access.log",
+					Roller: httpserver.DefaultLogRoller(),
+				},
+				Format:  DefaultLogFormat,
+				Sampler: &Sampler{Every: 10},
+			}},
+		}}},
+		{`log /api access.log {json} {
+			sample 12.5% keep_errors
+			rotate_keep 3
+		  }`, false, []Rule{{
+			PathScope: "/api",
+			Entries: []*Entry{{
+				Log: &httpserver.Logger{
+					Output: "access.log",
+					Roller: &httpserver.LogRoller{
+						MaxSize:    100,
+						MaxAge:     14,
+						MaxBackups: 3,
+						LocalTime:  true,
+					}},
+				Format:  JSONLogFormat,
+				Sampler: &Sampler{Percent: 12.5, KeepErrors: true},
+			}},
+		}}},
+		{`log access.log {
+			redact_query token api_*
+			redact_header Authorization
+			redact_query secret
+		  }`, false, []Rule{{
+			PathScope: "/",
+			Entries: []*Entry{{
+				Log: &httpserver.Logger{
+					Output: "access.log",
+					Roller: httpserver.DefaultLogRoller(),
+				},
+				Format: DefaultLogFormat,
+				Redact: &Redaction{
+					Query:   []string{"token", "api_*", "secret"},
+					Headers: []string{"Authorization"},
+				},
+			}},
+		}}},
+		{`log access.log {
+			redact_header
+		  }`, true, nil},
+		{`log access.log {
+			sample 0
+		  }`, true, nil},
+		{`log access.log {
+			sample 0%
+		  }`, true, nil},
+		{`log access.log {
+			sample 101%
+		  }`, true, nil},
+		{`log access.log {
+			sample ten
+		  }`, true, nil},
+		{`log access.log {
+			sample
+		  }`, true, nil},
+		{`log access.log {
+			sample 10 keep_all
+		  }`, true, nil},
 		{`log / stdout {host}
 		  log / log.txt {when}`, false, []Rule{{
 			PathScope: "/",
@@ -241,6 +321,16 @@ func TestLogParse(t *testing.T) {
 					t.Errorf("Test %d expected %dth LogRule Format to be  %s  , but got %s",
 						i, j, test.expectedLogRules[j].Entries[k].Format, actualEntry.Format)
 				}
+
+				if !reflect.DeepEqual(actualEntry.Redact, test.expectedLogRules[j].Entries[k].Redact) {
+					t.Errorf("Test %d expected %dth LogRule Redact to be  %+v  , but got %+v",
+						i, j, test.expectedLogRules[j].Entries[k].Redact, actualEntry.Redact)
+				}
+
+				if !reflect.DeepEqual(actualEntry.Sampler, test.expectedLogRules[j].Entries[k].Sampler) {
+					t.Errorf("Test %d expected %dth LogRule Sampler to be  %+v  , but got %+v",
+						i, j, test.expectedLogRules[j].Entries[k].Sampler, actualEntry.Sampler)
+				}
 			}
 		}
 	}
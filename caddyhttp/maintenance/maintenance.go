@@ -0,0 +1,62 @@
+// Package maintenance implements a maintenance mode that answers
+// requests with 503 Service Unavailable, except for allowed clients.
+package maintenance
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+// Maintenance is middleware that takes a site down for maintenance.
+type Maintenance struct {
+	Next httpserver.Handler
+
+	// RetryAfter is sent in the Retry-After header, rounded up
+	// to whole seconds; it is omitted if zero.
+	RetryAfter time.Duration
+
+	// Page is the body of the response, of type ContentType.
+	// If it is nil, the server writes a plain text description
+	// of the status; maintenance comes before the errors
+	// middleware, so the error pages of the site don't apply.
+	Page        []byte
+	ContentType string
+
+	// Allow lists the clients that are let through to the site.
+	Allow []*net.IPNet
+
+	// TrustedProxies are the proxies whose X-Forwarded-For
+	// headers are used to find the client; see httpserver.ClientIP.
+	TrustedProxies []*net.IPNet
+}
+
+// ServeHTTP implements the httpserver.Handler interface.
+func (m Maintenance) ServeHTTP(w http.ResponseWriter, r *http.Request) (int, error) {
+	if ip := httpserver.ClientIP(r, m.TrustedProxies); ip != nil {
+		for _, ipnet := range m.Allow {
+			if ipnet.Contains(ip) {
+				return m.Next.ServeHTTP(w, r)
+			}
+		}
+	}
+
+	if m.RetryAfter > 0 {
+		seconds := (m.RetryAfter + time.Second - 1) / time.Second
+		w.Header().Set("Retry-After", strconv.FormatInt(int64(seconds), 10))
+	}
+	if m.Page == nil {
+		return http.StatusServiceUnavailable, nil
+	}
+
+	w.Header().Set("Content-Type", m.ContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(m.Page)))
+	w.WriteHeader(http.StatusServiceUnavailable)
+	if r.Method != http.MethodHead {
+		w.Write(m.Page)
+	}
+	return 0, nil
+}
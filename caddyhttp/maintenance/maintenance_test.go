@@ -0,0 +1,95 @@
+package maintenance
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func TestMaintenance(t *testing.T) {
+	m := Maintenance{
+		Next: httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+			w.Write([]byte("site"))
+			return 0, nil
+		}),
+		RetryAfter:     90*time.Second + time.Millisecond,
+		Page:           []byte("<h1>Back soon</h1>"),
+		ContentType:    "text/html; charset=utf-8",
+		Allow:          []*net.IPNet{{IP: net.IP{192, 168, 0, 0}, Mask: net.CIDRMask(16, 32)}, {IP: net.ParseIP("2001:db8::1"), Mask: net.CIDRMask(128, 128)}},
+		TrustedProxies: []*net.IPNet{{IP: net.IP{10, 0, 0, 1}, Mask: net.CIDRMask(32, 32)}},
+	}
+
+	for i, test := range []struct {
+		method     string
+		remoteAddr string
+		xff        string
+		status     int
+		body       string
+	}{
+		{"GET", "1.2.3.4:1234", "", http.StatusServiceUnavailable, "<h1>Back soon</h1>"},
+		{"HEAD", "1.2.3.4:1234", "", http.StatusServiceUnavailable, ""},
+		{"GET", "192.168.5.5:1234", "", http.StatusOK, "site"},
+		{"GET", "[2001:db8::1]:1234", "", http.StatusOK, "site"},
+		{"GET", "[2001:db8::2]:1234", "", http.StatusServiceUnavailable, "<h1>Back soon</h1>"},
+
+		// only a trusted proxy can vouch for an allowed client
+		{"GET", "10.0.0.1:1234", "192.168.5.5", http.StatusOK, "site"},
+		{"GET", "10.0.0.1:1234", "1.2.3.4", http.StatusServiceUnavailable, "<h1>Back soon</h1>"},
+		{"GET", "1.2.3.4:1234", "192.168.5.5", http.StatusServiceUnavailable, "<h1>Back soon</h1>"},
+	} {
+		r, err := http.NewRequest(test.method, "/", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.RemoteAddr = test.remoteAddr
+		if test.xff != "" {
+			r.Header.Set("X-Forwarded-For", test.xff)
+		}
+		rec := httptest.NewRecorder()
+
+		status, err := m.ServeHTTP(rec, r)
+		if err != nil {
+			t.Errorf("Test %d: Expected no error, got %v", i, err)
+		}
+		if status != 0 {
+			t.Errorf("Test %d: Expected status 0 (written), got %d", i, status)
+		}
+		if rec.Code != test.status {
+			t.Errorf("Test %d: Expected response status %d, got %d", i, test.status, rec.Code)
+		}
+		if got := rec.Body.String(); got != test.body {
+			t.Errorf("Test %d: Expected body %q, got %q", i, test.body, got)
+		}
+		if test.status != http.StatusServiceUnavailable {
+			continue
+		}
+		if got := rec.Header().Get("Retry-After"); got != "91" {
+			t.Errorf("Test %d: Expected Retry-After 91, got %q", i, got)
+		}
+		if got := rec.Header().Get("Content-Type"); got != "text/html; charset=utf-8" {
+			t.Errorf("Test %d: Expected Content-Type text/html, got %q", i, got)
+		}
+	}
+}
+
+func TestMaintenanceWithoutPage(t *testing.T) {
+	m := Maintenance{Next: httpserver.EmptyNext}
+
+	r, err := http.NewRequest("GET", "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.RemoteAddr = "1.2.3.4:1234"
+	rec := httptest.NewRecorder()
+
+	if status, _ := m.ServeHTTP(rec, r); status != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %d, got %d", http.StatusServiceUnavailable, status)
+	}
+	if got, ok := rec.Header()["Retry-After"]; ok {
+		t.Errorf("Expected no Retry-After header, got %q", got)
+	}
+}
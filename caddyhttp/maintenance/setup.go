@@ -0,0 +1,126 @@
+package maintenance
+
+import (
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func init() {
+	caddy.RegisterPlugin("maintenance", caddy.Plugin{
+		ServerType: "http",
+		Action:     setup,
+	})
+}
+
+// setup configures a new Maintenance middleware instance.
+func setup(c *caddy.Controller) error {
+	cfg := httpserver.GetConfig(c)
+
+	m, err := maintenanceParse(c, cfg.Root)
+	if err != nil {
+		return err
+	}
+	m.TrustedProxies = cfg.TrustedProxies
+
+	cfg.AddMiddleware(func(next httpserver.Handler) httpserver.Handler {
+		m.Next = next
+		return m
+	})
+
+	return nil
+}
+
+// maintenanceParse parses the directive:
+//
+//	maintenance [retry_after] {
+//		retry_after <duration|seconds>
+//		page        <file>
+//		allow       <ip|cidr>...
+//	}
+func maintenanceParse(c *caddy.Controller, root string) (Maintenance, error) {
+	var m Maintenance
+
+	for c.Next() {
+		args := c.RemainingArgs()
+		switch len(args) {
+		case 0:
+		case 1:
+			retryAfter, err := parseRetryAfter(c, args[0])
+			if err != nil {
+				return m, err
+			}
+			m.RetryAfter = retryAfter
+		default:
+			return m, c.ArgErr()
+		}
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "retry_after":
+				if !c.NextArg() {
+					return m, c.ArgErr()
+				}
+				retryAfter, err := parseRetryAfter(c, c.Val())
+				if err != nil {
+					return m, err
+				}
+				m.RetryAfter = retryAfter
+			case "page":
+				if !c.NextArg() {
+					return m, c.ArgErr()
+				}
+				where := c.Val()
+				if !filepath.IsAbs(where) {
+					where = filepath.Join(root, where)
+				}
+				page, err := ioutil.ReadFile(where)
+				if err != nil {
+					return m, c.Errf("unable to read maintenance page: %v", err)
+				}
+				m.Page = page
+				m.ContentType = mime.TypeByExtension(filepath.Ext(where))
+				if m.ContentType == "" {
+					m.ContentType = http.DetectContentType(page)
+				}
+			case "allow":
+				ranges := c.RemainingArgs()
+				if len(ranges) == 0 {
+					return m, c.ArgErr()
+				}
+				for _, s := range ranges {
+					ipnet, err := httpserver.ParseCIDR(s)
+					if err != nil {
+						return m, c.Errf("invalid allowed address '%s': %v", s, err)
+					}
+					m.Allow = append(m.Allow, ipnet)
+				}
+			default:
+				return m, c.Errf("unknown maintenance property '%s'", c.Val())
+			}
+			if c.NextArg() {
+				return m, c.ArgErr()
+			}
+		}
+	}
+
+	return m, nil
+}
+
+// parseRetryAfter parses a number of seconds or a duration.
+func parseRetryAfter(c *caddy.Controller, s string) (time.Duration, error) {
+	if seconds, err := strconv.Atoi(s); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, c.Errf("retry_after must be a non-negative number of seconds or a duration, got '%s'", s)
+	}
+	return d, nil
+}
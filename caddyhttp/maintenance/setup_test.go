@@ -0,0 +1,122 @@
+package maintenance
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func TestSetup(t *testing.T) {
+	c := caddy.NewTestController("http", `maintenance 60`)
+	trusted := []*net.IPNet{{IP: net.IP{10, 0, 0, 1}, Mask: net.CIDRMask(32, 32)}}
+	httpserver.GetConfig(c).TrustedProxies = trusted
+	err := setup(c)
+	if err != nil {
+		t.Errorf("Expected no errors, got: %v", err)
+	}
+	mids := httpserver.GetConfig(c).Middleware()
+	if len(mids) == 0 {
+		t.Fatal("Expected middleware, got 0 instead")
+	}
+
+	handler := mids[0](httpserver.EmptyNext)
+	myHandler, ok := handler.(Maintenance)
+	if !ok {
+		t.Fatalf("Expected handler to be type Maintenance, got: %#v", handler)
+	}
+	if myHandler.RetryAfter != time.Minute {
+		t.Errorf("Expected RetryAfter to be 1m, got %v", myHandler.RetryAfter)
+	}
+	if !reflect.DeepEqual(myHandler.TrustedProxies, trusted) {
+		t.Errorf("Expected the site's trusted proxies %v, got %v", trusted, myHandler.TrustedProxies)
+	}
+	if !httpserver.SameNext(myHandler.Next, httpserver.EmptyNext) {
+		t.Error("'Next' field of handler was not set properly")
+	}
+}
+
+func TestMaintenanceParse(t *testing.T) {
+	root, err := ioutil.TempDir("", "caddy_maintenance")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	if err := ioutil.WriteFile(filepath.Join(root, "down.html"), []byte("<h1>Back soon</h1>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(root, "down"), []byte("Back soon"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, test := range []struct {
+		input     string
+		shouldErr bool
+		expected  Maintenance
+	}{
+		{`maintenance`, false, Maintenance{}},
+		{`maintenance 120`, false, Maintenance{RetryAfter: 2 * time.Minute}},
+		{`maintenance 1h`, false, Maintenance{RetryAfter: time.Hour}},
+		{`maintenance {
+			retry_after 90s
+			page down.html
+			allow 10.0.0.0/8 ::1
+			allow 192.168.1.10
+		}`, false, Maintenance{
+			RetryAfter:  90 * time.Second,
+			Page:        []byte("<h1>Back soon</h1>"),
+			ContentType: "text/html; charset=utf-8",
+			Allow: []*net.IPNet{
+				{IP: net.IP{10, 0, 0, 0}, Mask: net.CIDRMask(8, 32)},
+				{IP: net.IPv6loopback, Mask: net.CIDRMask(128, 128)},
+				{IP: net.IP{192, 168, 1, 10}, Mask: net.CIDRMask(32, 32)},
+			},
+		}},
+		{`maintenance {
+			page ` + filepath.Join(root, "down") + `
+		}`, false, Maintenance{
+			Page:        []byte("Back soon"),
+			ContentType: "text/plain; charset=utf-8",
+		}},
+		{`maintenance 60 120`, true, Maintenance{}},
+		{`maintenance soon`, true, Maintenance{}},
+		{`maintenance -5`, true, Maintenance{}},
+		{`maintenance {
+			retry_after
+		}`, true, Maintenance{}},
+		{`maintenance {
+			page missing.html
+		}`, true, Maintenance{}},
+		{`maintenance {
+			allow
+		}`, true, Maintenance{}},
+		{`maintenance {
+			allow 10.0.0.0/64
+		}`, true, Maintenance{}},
+		{`maintenance {
+			page down.html down
+		}`, true, Maintenance{}},
+		{`maintenance {
+			message hello
+		}`, true, Maintenance{}},
+	} {
+		actual, err := maintenanceParse(caddy.NewTestController("http", test.input), root)
+		if err == nil && test.shouldErr {
+			t.Errorf("Test %d didn't error, but it should have", i)
+		} else if err != nil && !test.shouldErr {
+			t.Errorf("Test %d errored, but it shouldn't have; got '%v'", i, err)
+		}
+		if test.shouldErr {
+			continue
+		}
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("Test %d: Expected %+v, got %+v", i, test.expected, actual)
+		}
+	}
+}
@@ -133,6 +133,12 @@ func (md Markdown) ServeHTTP(w http.ResponseWriter, r *http.Request) (int, error
 	}
 	lastModTime = latest(lastModTime, fs.ModTime())
 
+	if httpserver.NotModifiedSince(r, lastModTime) {
+		httpserver.SetLastModifiedHeader(w, lastModTime)
+		httpserver.WriteNotModified(w)
+		return 0, nil
+	}
+
 	ctx := httpserver.Context{
 		Root: md.FileSys,
 		Req:  r,
@@ -228,3 +228,61 @@ func setDefaultTemplate(filename string) *template.Template {
 
 	return template.Must(GetDefaultTemplate().Parse(string(buf)))
 }
+
+func TestMarkdownIfModifiedSince(t *testing.T) {
+	rootDir, err := ioutil.TempDir("", "caddy_markdown")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(rootDir)
+
+	modTime := time.Now().Add(-time.Hour)
+	mdFile := filepath.Join(rootDir, "post.md")
+	if err := ioutil.WriteFile(mdFile, []byte("# Post\n\nBody\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(mdFile, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+
+	md := Markdown{
+		Root:    rootDir,
+		FileSys: http.Dir(rootDir),
+		Configs: []*Config{{
+			Renderer:   blackfriday.HtmlRenderer(0, "", ""),
+			PathScope:  "/",
+			Extensions: map[string]struct{}{".md": {}},
+			Template:   GetDefaultTemplate(),
+		}},
+		Next: httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+			t.Fatalf("Next shouldn't be called")
+			return 0, nil
+		}),
+	}
+
+	for i, test := range []struct {
+		since  time.Time
+		status int
+	}{
+		{time.Time{}, http.StatusOK},
+		{modTime.Add(time.Minute), http.StatusNotModified},
+		{modTime.Add(-time.Minute), http.StatusOK},
+	} {
+		req, err := http.NewRequest("GET", "/post.md", nil)
+		if err != nil {
+			t.Fatalf("Test %d: Could not create HTTP request: %v", i, err)
+		}
+		if !test.since.IsZero() {
+			req.Header.Set("If-Modified-Since", test.since.UTC().Format(http.TimeFormat))
+		}
+		rec := httptest.NewRecorder()
+
+		md.ServeHTTP(rec, req)
+		if rec.Code != test.status {
+			t.Errorf("Test %d: Wrong status, expected: %d and got %d", i, test.status, rec.Code)
+		}
+		if test.status == http.StatusNotModified && rec.Body.Len() != 0 {
+			t.Errorf("Test %d: Expected empty body, got %q", i, rec.Body.String())
+		}
+	}
+}
@@ -1,7 +1,6 @@
 package markdown
 
 import (
-	"net/http"
 	"path/filepath"
 
 	"github.com/mholt/caddy"
@@ -18,6 +17,10 @@ func init() {
 
 // setup configures a new Markdown middleware instance.
 func setup(c *caddy.Controller) error {
+	if err := httpserver.CheckStaticRoot(c, "markdown"); err != nil {
+		return err
+	}
+
 	mdconfigs, err := markdownParse(c)
 	if err != nil {
 		return err
@@ -27,7 +30,7 @@ func setup(c *caddy.Controller) error {
 
 	md := Markdown{
 		Root:       cfg.Root,
-		FileSys:    http.Dir(cfg.Root),
+		FileSys:    cfg.RootFileSys(),
 		Configs:    mdconfigs,
 		IndexFiles: []string{"index.md"},
 	}
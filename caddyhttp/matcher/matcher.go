@@ -0,0 +1,90 @@
+// Package matcher defines named request matchers that directives
+// refer to, so that the conditions under which they apply are
+// defined once.
+package matcher
+
+import (
+	"strings"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func init() {
+	caddy.RegisterPlugin("matcher", caddy.Plugin{
+		ServerType: "http",
+		Action:     setupMatcher,
+	})
+}
+
+// setupMatcher parses the matcher directive:
+//
+//	matcher <name> {
+//		path   <paths...>
+//		method <methods...>
+//		host   <hosts...>
+//		if     <a> <operator> <b>
+//		if_op  and|or
+//		match  <name>
+//	}
+//
+// Directives that take if conditions, like header, redir and rewrite,
+// then apply only to the requests that the matcher matches if their
+// block has a match <name> line. All the conditions of a matcher must
+// be true, except that if_op or makes any of its if conditions enough.
+func setupMatcher(c *caddy.Controller) error {
+	config := httpserver.GetConfig(c)
+	for c.Next() {
+		args := c.RemainingArgs()
+		if len(args) != 1 {
+			return c.ArgErr()
+		}
+		name := args[0]
+		if _, ok := config.Matchers[name]; ok {
+			return c.Errf("matcher '%s' is already defined", name)
+		}
+
+		var m httpserver.Matcher
+		ifMatcher, err := httpserver.SetupIfMatcher(c)
+		if err != nil {
+			return err
+		}
+		if httpserver.HasIfMatcher(c) {
+			m.If = ifMatcher
+		}
+
+		for c.NextBlock() {
+			if httpserver.IfMatcherKeyword(c) {
+				continue
+			}
+			property := c.Val()
+			args := c.RemainingArgs()
+			if len(args) == 0 {
+				return c.ArgErr()
+			}
+			switch property {
+			case "path":
+				m.Paths = append(m.Paths, args...)
+			case "method":
+				for _, method := range args {
+					m.Methods = append(m.Methods, strings.ToUpper(method))
+				}
+			case "host":
+				for _, host := range args {
+					m.Hosts = append(m.Hosts, strings.ToLower(host))
+				}
+			default:
+				return c.Errf("unknown matcher property '%s'", property)
+			}
+		}
+
+		if m.Paths == nil && m.Methods == nil && m.Hosts == nil && m.If == nil {
+			return c.Errf("matcher '%s' has no conditions", name)
+		}
+		if config.Matchers == nil {
+			config.Matchers = make(map[string]httpserver.RequestMatcher)
+		}
+		config.Matchers[name] = m
+	}
+	return nil
+}
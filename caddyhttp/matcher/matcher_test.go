@@ -0,0 +1,173 @@
+package matcher
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyfile"
+	_ "github.com/mholt/caddy/caddyhttp/header"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+	_ "github.com/mholt/caddy/caddyhttp/redirect"
+)
+
+func TestSetupMatcher(t *testing.T) {
+	for i, test := range []struct {
+		input     string
+		shouldErr bool
+		expected  map[string]httpserver.RequestMatcher
+	}{
+		{`matcher api {
+			path   /api /v2
+			method get Post
+			host   API.example.com
+		  }`, false, map[string]httpserver.RequestMatcher{
+			"api": httpserver.Matcher{Paths: []string{"/api", "/v2"}, Methods: []string{"GET", "POST"}, Hosts: []string{"api.example.com"}},
+		}},
+		{`matcher api {
+			path /api
+		  }
+		  matcher admin {
+			host admin.example.com
+		  }`, false, map[string]httpserver.RequestMatcher{
+			"api":   httpserver.Matcher{Paths: []string{"/api"}},
+			"admin": httpserver.Matcher{Hosts: []string{"admin.example.com"}},
+		}},
+		{`matcher`, true, nil},
+		{`matcher api`, true, nil},
+		{`matcher api web {
+			path /api
+		  }`, true, nil},
+		{`matcher api {
+			path
+		  }`, true, nil},
+		{`matcher api {
+			header X-API
+		  }`, true, nil},
+		{`matcher api {
+			path /api
+		  }
+		  matcher api {
+			path /v2
+		  }`, true, nil},
+		{`matcher api {
+			match web
+		  }`, true, nil},
+	} {
+		c := caddy.NewTestController("http", test.input)
+		err := setupMatcher(c)
+		if err == nil && test.shouldErr {
+			t.Errorf("Test %d didn't error, but it should have", i)
+		} else if err != nil && !test.shouldErr {
+			t.Errorf("Test %d errored, but it shouldn't have; got '%v'", i, err)
+		}
+		if !test.shouldErr && !reflect.DeepEqual(httpserver.GetConfig(c).Matchers, test.expected) {
+			t.Errorf("Test %d: Expected matchers %+v, got %+v", i, test.expected, httpserver.GetConfig(c).Matchers)
+		}
+	}
+}
+
+func TestMatcherConditions(t *testing.T) {
+	c := caddy.NewTestController("http", `matcher api {
+		path /api
+		method POST
+		if {>X-Debug} is 1
+	}
+	matcher internal {
+		match api
+		host localhost
+	}`)
+	if err := setupMatcher(c); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	for i, test := range []struct {
+		name     string
+		method   string
+		url      string
+		debug    string
+		expected bool
+	}{
+		{"api", "POST", "http://example.com/api/users", "1", true},
+		{"api", "GET", "http://example.com/api/users", "1", false},
+		{"api", "POST", "http://example.com/users", "1", false},
+		{"api", "POST", "http://example.com/api/users", "", false},
+		{"internal", "POST", "http://localhost/api/users", "1", true},
+		{"internal", "POST", "http://example.com/api/users", "1", false},
+		{"internal", "GET", "http://localhost/api/users", "1", false},
+	} {
+		r, err := http.NewRequest(test.method, test.url, nil)
+		if err != nil {
+			t.Fatalf("Test %d: Could not create HTTP request: %v", i, err)
+		}
+		r.Header.Set("X-Debug", test.debug)
+		if got := httpserver.GetConfig(c).Matchers[test.name].Match(r); got != test.expected {
+			t.Errorf("Test %d: Expected %s to match %v, got %v", i, test.name, test.expected, got)
+		}
+	}
+}
+
+func TestMatcherInDirectives(t *testing.T) {
+	c := caddy.NewTestController("http", `matcher old_api {
+		path /v1
+		method GET HEAD
+	}`)
+	if err := setupMatcher(c); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	for _, directive := range []string{
+		`header / {
+			match old_api
+			Deprecation true
+		}`,
+		`redir {
+			match old_api
+			/ /v2{uri} 308
+		}`,
+	} {
+		c.Dispenser = caddyfile.NewDispenser("Testfile", strings.NewReader(directive))
+		setup, err := caddy.DirectiveAction("http", strings.Fields(directive)[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := setup(c); err != nil {
+			t.Fatalf("Expected no error setting up %s, got: %v", directive, err)
+		}
+	}
+
+	var handler httpserver.Handler = httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+		return http.StatusOK, nil
+	})
+	mids := httpserver.GetConfig(c).Middleware()
+	for i := len(mids) - 1; i >= 0; i-- {
+		handler = mids[i](handler)
+	}
+
+	for i, test := range []struct {
+		method           string
+		path             string
+		expectedLocation string
+		expectedHeader   string
+	}{
+		{"GET", "/v1/users", "/v2/v1/users", "true"},
+		{"HEAD", "/v1/users", "/v2/v1/users", "true"},
+		{"POST", "/v1/users", "", ""},
+		{"GET", "/v2/users", "", ""},
+	} {
+		r, err := http.NewRequest(test.method, "http://localhost"+test.path, nil)
+		if err != nil {
+			t.Fatalf("Test %d: Could not create HTTP request: %v", i, err)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, r)
+		if got := rec.Header().Get("Location"); got != test.expectedLocation {
+			t.Errorf("Test %d: Expected Location %q, got %q", i, test.expectedLocation, got)
+		}
+		if got := rec.Header().Get("Deprecation"); got != test.expectedHeader {
+			t.Errorf("Test %d: Expected Deprecation header %q, got %q", i, test.expectedHeader, got)
+		}
+	}
+}
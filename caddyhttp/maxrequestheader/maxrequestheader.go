@@ -0,0 +1,60 @@
+// Package maxrequestheader configures how large the request
+// headers of a site may be.
+package maxrequestheader
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func init() {
+	caddy.RegisterPlugin("max_request_header", caddy.Plugin{
+		ServerType: "http",
+		Action:     setupMaxRequestHeader,
+	})
+}
+
+// setupMaxRequestHeader parses the directive:
+//
+//	max_request_header <size> [fields]
+//
+// where size is a number of bytes, optionally followed by KB,
+// like 16KB, and fields the most header fields a request may have.
+func setupMaxRequestHeader(c *caddy.Controller) error {
+	config := httpserver.GetConfig(c)
+	for c.Next() {
+		if config.MaxRequestHeaderBytes != 0 {
+			return c.Err("max_request_header can only be specified once per site")
+		}
+		args := c.RemainingArgs()
+		if len(args) < 1 || len(args) > 2 {
+			return c.ArgErr()
+		}
+
+		size, multiplier := strings.ToUpper(args[0]), 1
+		if strings.HasSuffix(size, "KB") {
+			size, multiplier = strings.TrimSuffix(size, "KB"), 1024
+		}
+		n, err := strconv.Atoi(size)
+		if err != nil || n < 1 || n > maxSize/multiplier {
+			return c.Errf("size must be a positive number of bytes up to 1MB, got '%s'", args[0])
+		}
+		config.MaxRequestHeaderBytes = n * multiplier
+
+		if len(args) == 2 {
+			fields, err := strconv.Atoi(args[1])
+			if err != nil || fields < 1 {
+				return c.Errf("fields must be a positive number, got '%s'", args[1])
+			}
+			config.MaxRequestHeaderFields = fields
+		}
+	}
+	return nil
+}
+
+// maxSize is the limit of the server, http.DefaultMaxHeaderBytes;
+// larger limits would never be reached.
+const maxSize = 1 << 20
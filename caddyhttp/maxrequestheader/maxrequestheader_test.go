@@ -0,0 +1,42 @@
+package maxrequestheader
+
+import (
+	"testing"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func TestSetupMaxRequestHeader(t *testing.T) {
+	for i, test := range []struct {
+		input         string
+		shouldErr     bool
+		bytes, fields int
+	}{
+		{"max_request_header 8192", false, 8192, 0},
+		{"max_request_header 16kb 50", false, 16384, 50},
+		{"max_request_header 1024KB", false, 1 << 20, 0},
+		{"max_request_header", true, 0, 0},
+		{"max_request_header 0", true, 0, 0},
+		{"max_request_header 2MB", true, 0, 0},
+		{"max_request_header 1025KB", true, 0, 0},
+		{"max_request_header 8KB 0", true, 0, 0},
+		{"max_request_header 8KB many", true, 0, 0},
+		{"max_request_header 8KB 50 extra", true, 0, 0},
+		{"max_request_header 8KB\nmax_request_header 16KB", true, 0, 0},
+	} {
+		c := caddy.NewTestController("http", test.input)
+		err := setupMaxRequestHeader(c)
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %d: Expected an error, but did not have one", i)
+		}
+		if !test.shouldErr && err != nil {
+			t.Errorf("Test %d: Did not expect error, but got: %v", i, err)
+		}
+		config := httpserver.GetConfig(c)
+		if !test.shouldErr && (config.MaxRequestHeaderBytes != test.bytes || config.MaxRequestHeaderFields != test.fields) {
+			t.Errorf("Test %d: Expected %d bytes and %d fields, got %d and %d",
+				i, test.bytes, test.fields, config.MaxRequestHeaderBytes, config.MaxRequestHeaderFields)
+		}
+	}
+}
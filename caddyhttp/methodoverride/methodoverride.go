@@ -0,0 +1,84 @@
+// Package methodoverride is middleware that lets POST requests
+// ask to be handled as PUT, PATCH or DELETE requests, for clients
+// that can't send those methods themselves.
+package methodoverride
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+// maxFormSize is the most of a form body that is read to look
+// for the override field.
+const maxFormSize = 1 << 20
+
+// allowedMethods are the methods a POST request may be overridden to.
+var allowedMethods = map[string]bool{
+	"PUT":    true,
+	"PATCH":  true,
+	"DELETE": true,
+}
+
+// MethodOverride is middleware that changes the method of POST
+// requests to the one in the Header request header or, if that
+// is not set, the Field query parameter or form field. Other
+// methods and override targets are left alone.
+type MethodOverride struct {
+	Next   httpserver.Handler
+	Header string
+	Field  string
+}
+
+// ServeHTTP implements the httpserver.Handler interface.
+func (m MethodOverride) ServeHTTP(w http.ResponseWriter, r *http.Request) (int, error) {
+	if r.Method == http.MethodPost {
+		method, err := m.override(r)
+		if err != nil {
+			return http.StatusBadRequest, err
+		}
+		if method = strings.ToUpper(method); allowedMethods[method] {
+			r.Method = method
+		}
+	}
+	return m.Next.ServeHTTP(w, r)
+}
+
+// override returns the method that r asks to be handled as,
+// if any.
+func (m MethodOverride) override(r *http.Request) (string, error) {
+	if method := r.Header.Get(m.Header); method != "" {
+		return method, nil
+	}
+	if m.Field == "" {
+		return "", nil
+	}
+	if method := r.URL.Query().Get(m.Field); method != "" {
+		return method, nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "application/x-www-form-urlencoded" || r.Body == nil {
+		return "", nil
+	}
+	// read the form without consuming the body,
+	// which the next handlers still need
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxFormSize))
+	if err != nil {
+		return "", err
+	}
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+	// a form cut off at maxFormSize may not parse completely,
+	// but the fields before the error are still there
+	form, _ := url.ParseQuery(string(body))
+	return form.Get(m.Field), nil
+}
@@ -0,0 +1,67 @@
+package methodoverride
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func TestMethodOverride(t *testing.T) {
+	var method, body string
+	m := MethodOverride{
+		Next: httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+			method = r.Method
+			b, err := ioutil.ReadAll(r.Body)
+			body = string(b)
+			return http.StatusOK, err
+		}),
+		Header: "X-HTTP-Method-Override",
+		Field:  "_method",
+	}
+
+	const form = "a=1&_method=delete&b=2"
+	for i, test := range []struct {
+		method, url, header, contentType, body string
+		expected                               string
+	}{
+		{"POST", "/", "PUT", "", "", "PUT"},
+		{"POST", "/", "patch", "", "", "PATCH"},
+		{"POST", "/?_method=DELETE", "", "", "", "DELETE"},
+		{"POST", "/?_method=DELETE", "PUT", "", "", "PUT"},
+		{"POST", "/", "", "application/x-www-form-urlencoded", form, "DELETE"},
+		{"POST", "/", "", "text/plain", form, "POST"},
+		{"POST", "/", "", "", "", "POST"},
+		// only overrides of POST to PUT, PATCH and DELETE are allowed
+		{"GET", "/", "PUT", "", "", "GET"},
+		{"GET", "/?_method=DELETE", "", "", "", "GET"},
+		{"POST", "/", "GET", "", "", "POST"},
+		{"POST", "/", "CONNECT", "", "", "POST"},
+		{"POST", "/?_method=TRACE", "", "", "", "POST"},
+	} {
+		method, body = "", ""
+		req, err := http.NewRequest(test.method, test.url, strings.NewReader(test.body))
+		if err != nil {
+			t.Fatalf("Test %d: Could not create HTTP request: %v", i, err)
+		}
+		if test.header != "" {
+			req.Header.Set("X-HTTP-Method-Override", test.header)
+		}
+		if test.contentType != "" {
+			req.Header.Set("Content-Type", test.contentType)
+		}
+
+		if _, err := m.ServeHTTP(httptest.NewRecorder(), req); err != nil {
+			t.Fatalf("Test %d: Could not ServeHTTP: %v", i, err)
+		}
+		if method != test.expected {
+			t.Errorf("Test %d: Expected method %s, got %s", i, test.expected, method)
+		}
+		if body != test.body {
+			t.Errorf("Test %d: Expected next handler to read body '%s', got '%s'", i, test.body, body)
+		}
+	}
+}
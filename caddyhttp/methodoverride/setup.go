@@ -0,0 +1,75 @@
+package methodoverride
+
+import (
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func init() {
+	caddy.RegisterPlugin("method_override", caddy.Plugin{
+		ServerType: "http",
+		Action:     setup,
+	})
+}
+
+// setup configures a new MethodOverride middleware instance.
+func setup(c *caddy.Controller) error {
+	m, err := methodOverrideParse(c)
+	if err != nil {
+		return err
+	}
+
+	httpserver.GetConfig(c).AddMiddleware(func(next httpserver.Handler) httpserver.Handler {
+		m.Next = next
+		return m
+	})
+
+	return nil
+}
+
+// methodOverrideParse parses the directive:
+//
+//	method_override {
+//		header name
+//		field  name
+//	}
+//
+// The header defaults to X-HTTP-Method-Override and the field,
+// a query parameter or form field, to _method.
+func methodOverrideParse(c *caddy.Controller) (MethodOverride, error) {
+	m := MethodOverride{Header: "X-HTTP-Method-Override", Field: "_method"}
+	var parsed bool
+
+	for c.Next() {
+		if parsed {
+			return m, c.Err("method_override can only be specified once per site")
+		}
+		parsed = true
+
+		if len(c.RemainingArgs()) != 0 {
+			return m, c.ArgErr()
+		}
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "header":
+				if !c.NextArg() {
+					return m, c.ArgErr()
+				}
+				m.Header = c.Val()
+			case "field":
+				if !c.NextArg() {
+					return m, c.ArgErr()
+				}
+				m.Field = c.Val()
+			default:
+				return m, c.Errf("unknown subdirective '%s'", c.Val())
+			}
+			if c.NextArg() {
+				return m, c.ArgErr()
+			}
+		}
+	}
+
+	return m, nil
+}
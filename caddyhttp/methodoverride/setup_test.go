@@ -0,0 +1,60 @@
+package methodoverride
+
+import (
+	"testing"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func TestSetup(t *testing.T) {
+	c := caddy.NewTestController("http", `method_override`)
+	err := setup(c)
+	if err != nil {
+		t.Errorf("Expected no errors, got: %v", err)
+	}
+	mids := httpserver.GetConfig(c).Middleware()
+	if len(mids) == 0 {
+		t.Fatal("Expected middleware, got 0 instead")
+	}
+
+	handler := mids[0](httpserver.EmptyNext)
+	myHandler, ok := handler.(MethodOverride)
+	if !ok {
+		t.Fatalf("Expected handler to be type MethodOverride, got: %#v", handler)
+	}
+	if !httpserver.SameNext(myHandler.Next, httpserver.EmptyNext) {
+		t.Error("'Next' field of handler was not set properly")
+	}
+}
+
+func TestMethodOverrideParse(t *testing.T) {
+	for i, test := range []struct {
+		input     string
+		shouldErr bool
+		header    string
+		field     string
+	}{
+		{`method_override`, false, "X-HTTP-Method-Override", "_method"},
+		{"method_override {\n\theader X-Method\n\tfield m\n}", false, "X-Method", "m"},
+		{`method_override PUT`, true, "", ""},
+		{"method_override {\n\theader\n}", true, "", ""},
+		{"method_override {\n\tfield a b\n}", true, "", ""},
+		{"method_override {\n\tmethods PUT\n}", true, "", ""},
+		{"method_override\nmethod_override", true, "", ""},
+	} {
+		m, err := methodOverrideParse(caddy.NewTestController("http", test.input))
+		if err == nil && test.shouldErr {
+			t.Errorf("Test %d didn't error, but it should have", i)
+		} else if err != nil && !test.shouldErr {
+			t.Errorf("Test %d errored, but it shouldn't have; got '%v'", i, err)
+		}
+		if test.shouldErr {
+			continue
+		}
+		if m.Header != test.header || m.Field != test.field {
+			t.Errorf("Test %d: Expected header '%s' and field '%s', got '%s' and '%s'",
+				i, test.header, test.field, m.Header, m.Field)
+		}
+	}
+}
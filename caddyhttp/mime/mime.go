@@ -5,9 +5,11 @@ import (
 	"path"
 
 	"github.com/mholt/caddy/caddyhttp/httpserver"
+	"github.com/mholt/caddy/caddyhttp/staticfiles"
 )
 
-// Config represent a mime config. Map from extension to mime-type.
+// Config represent a mime config. Map from extension to mime-type;
+// an extension may have several dots, like .tar.gz.
 // Note, this should be safe with concurrent read access, as this is
 // not modified concurrently.
 type Config map[string]string
@@ -20,10 +22,8 @@ type Mime struct {
 
 // ServeHTTP implements the httpserver.Handler interface.
 func (e Mime) ServeHTTP(w http.ResponseWriter, r *http.Request) (int, error) {
-	// Get a clean /-path, grab the extension
-	ext := path.Ext(path.Clean(r.URL.Path))
-
-	if contentType, ok := e.Configs[ext]; ok {
+	// Get a clean /-path, grab the longest configured extension
+	if contentType := staticfiles.TypeByExtension(e.Configs, path.Clean(r.URL.Path)); contentType != "" {
 		w.Header().Set("Content-Type", contentType)
 	}
 
@@ -53,6 +53,28 @@ func TestMimeHandler(t *testing.T) {
 	}
 }
 
+func TestMimeHandlerLongestExtension(t *testing.T) {
+	m := Mime{Configs: Config{
+		".gz":     "application/gzip",
+		".tar.gz": "application/x-gtar",
+	}}
+
+	for url, contentType := range map[string]string{
+		"/backup.tar.gz": "application/x-gtar",
+		"/file.gz":       "application/gzip",
+		"/v1.2/file.gz":  "application/gzip",
+	} {
+		r, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m.Next = nextFunc(true, contentType)
+		if _, err := m.ServeHTTP(httptest.NewRecorder(), r); err != nil {
+			t.Errorf("%s: %v", url, err)
+		}
+	}
+}
+
 func nextFunc(shouldMime bool, contentType string) httpserver.Handler {
 	return httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
 		if shouldMime {
@@ -22,10 +22,20 @@ func setup(c *caddy.Controller) error {
 		return err
 	}
 
-	httpserver.GetConfig(c).AddMiddleware(func(next httpserver.Handler) httpserver.Handler {
+	cfg := httpserver.GetConfig(c)
+	cfg.AddMiddleware(func(next httpserver.Handler) httpserver.Handler {
 		return Mime{Next: next, Configs: configs}
 	})
 
+	// the static file server goes by the name of the file it
+	// serves, which may be an index file or a rewritten path
+	if cfg.MimeTypes == nil {
+		cfg.MimeTypes = make(map[string]string)
+	}
+	for ext, contentType := range configs {
+		cfg.MimeTypes[ext] = contentType
+	}
+
 	return nil
 }
 
@@ -27,6 +27,9 @@ func TestSetup(t *testing.T) {
 	if !httpserver.SameNext(myHandler.Next, httpserver.EmptyNext) {
 		t.Error("'Next' field of handler was not set properly")
 	}
+	if got := httpserver.GetConfig(c).MimeTypes[".txt"]; got != "text/plain" {
+		t.Errorf("Expected the static file server to get the type of .txt, got %q", got)
+	}
 
 	tests := []struct {
 		input     string
@@ -51,6 +54,7 @@ func TestSetup(t *testing.T) {
 		{`mime { .html
 		} `, true},
 		{`mime .txt text/plain`, false},
+		{`mime .tar.gz application/x-gtar`, false},
 	}
 	for i, test := range tests {
 		m, err := mimeParse(caddy.NewTestController("http", test.input))
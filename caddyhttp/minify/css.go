@@ -0,0 +1,84 @@
+package minify
+
+import "bytes"
+
+// CSS returns the style sheet b minified: comments are removed,
+// except those that start with /*! like licenses do, runs of
+// whitespace become a single space, whitespace next to { } ; , and
+// after : is removed, and so is the semicolon before }. Strings are
+// kept as they are. Whitespace before : is kept, since in selectors
+// a :hover is not a:hover.
+func CSS(b []byte) []byte {
+	var out bytes.Buffer
+	out.Grow(len(b))
+	var space bool // whether whitespace or a comment came before
+
+	for i := 0; i < len(b); {
+		c := b[i]
+		switch {
+		case c == '/' && i+1 < len(b) && b[i+1] == '*':
+			end := bytes.Index(b[i+2:], []byte("*/"))
+			if end < 0 {
+				end = len(b)
+			} else {
+				end += i + 2 + len("*/")
+			}
+			if i+2 < len(b) && b[i+2] == '!' {
+				writeCSS(&out, b[i:end], space)
+				space = false
+			} else {
+				// a comment separates what is around it
+				space = true
+			}
+			i = end
+		case isSpace(c):
+			space = true
+			i++
+		case c == '"' || c == '\'':
+			end := stringEnd(b, i)
+			writeCSS(&out, b[i:end], space)
+			space = false
+			i = end
+		default:
+			if c == '}' && bytes.HasSuffix(out.Bytes(), []byte(";")) {
+				// the last declaration needs no semicolon
+				out.Truncate(out.Len() - 1)
+			}
+			writeCSS(&out, b[i:i+1], space)
+			space = false
+			i++
+		}
+	}
+
+	return out.Bytes()
+}
+
+// writeCSS writes s to out, with a space before it if space is set
+// and it is needed to separate s from what out ends with.
+func writeCSS(out *bytes.Buffer, s []byte, space bool) {
+	if space && out.Len() > 0 {
+		last := out.Bytes()[out.Len()-1]
+		if !bytes.ContainsRune([]byte("{};,:"), rune(last)) && !bytes.ContainsRune([]byte("{};,"), rune(s[0])) {
+			out.WriteByte(' ')
+		}
+	}
+	out.Write(s)
+}
+
+// stringEnd returns the index after the end of the string literal
+// that starts at b[start] with a quote, or the index of the line
+// break or the length of b if it is not terminated.
+func stringEnd(b []byte, start int) int {
+	quote := b[start]
+	for i := start + 1; i < len(b); i++ {
+		switch b[i] {
+		case '\\':
+			i++
+		case quote:
+			return i + 1
+		case '\n':
+			return i
+		}
+	}
+	return len(b)
+}
@@ -0,0 +1,128 @@
+package minify
+
+import "bytes"
+
+// rawTextElements are the elements whose content is kept as it is:
+// whitespace is significant in pre and textarea, and script and style
+// are not HTML.
+var rawTextElements = map[string]bool{
+	"pre":      true,
+	"textarea": true,
+	"script":   true,
+	"style":    true,
+}
+
+// HTML returns the HTML document b minified: comments are removed,
+// except conditional comments, and runs of whitespace between tags
+// and in text become a single space. Tags, and the content of pre,
+// textarea, script and style elements, are kept as they are.
+func HTML(b []byte) []byte {
+	var out bytes.Buffer
+	out.Grow(len(b))
+
+	for i := 0; i < len(b); {
+		c := b[i]
+		switch {
+		case bytes.HasPrefix(b[i:], []byte("<!--")):
+			end := bytes.Index(b[i+4:], []byte("-->"))
+			if end < 0 {
+				out.Write(b[i:])
+				return out.Bytes()
+			}
+			end += i + 4 + len("-->")
+			if isConditionalComment(b[i:end]) {
+				out.Write(b[i:end])
+			}
+			i = end
+		case c == '<' && i+1 < len(b) && isTagStart(b[i+1]):
+			start, end := i, tagEnd(b, i)
+			out.Write(b[start:end])
+			i = end
+			if name := tagName(b[start:end]); rawTextElements[name] {
+				i = closingTag(b, end, name)
+				out.Write(b[end:i])
+			}
+		case isSpace(c):
+			for i < len(b) && isSpace(b[i]) {
+				i++
+			}
+			if !bytes.HasSuffix(out.Bytes(), []byte(" ")) {
+				// the space before a removed comment is enough
+				out.WriteByte(' ')
+			}
+		default:
+			out.WriteByte(c)
+			i++
+		}
+	}
+
+	return out.Bytes()
+}
+
+// isConditionalComment reports whether the comment is one of the
+// conditional comments of old versions of Internet Explorer.
+func isConditionalComment(comment []byte) bool {
+	return bytes.HasPrefix(comment, []byte("<!--[if")) || bytes.HasSuffix(comment, []byte("<![endif]-->"))
+}
+
+// isTagStart reports whether c, after a <, starts a tag
+// (or a doctype or processing instruction) rather than text.
+func isTagStart(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '/' || c == '!' || c == '?'
+}
+
+// tagEnd returns the index after the end of the tag that starts at
+// b[start], skipping > in quoted attribute values, or len(b) if the
+// tag does not end.
+func tagEnd(b []byte, start int) int {
+	var last byte // the last byte that is not whitespace
+	for i := start + 1; i < len(b); i++ {
+		switch c := b[i]; {
+		case c == '>':
+			return i + 1
+		case (c == '"' || c == '\'') && last == '=':
+			end := bytes.IndexByte(b[i+1:], c)
+			if end < 0 {
+				return len(b)
+			}
+			i += end + 1
+			last = c
+		case !isSpace(c):
+			last = c
+		}
+	}
+	return len(b)
+}
+
+// tagName returns the name of the opening tag tag in lower case,
+// or "" if it is a closing tag or not an element.
+func tagName(tag []byte) string {
+	var name []byte
+	for _, c := range tag[1:] {
+		if c >= 'A' && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-') {
+			break
+		}
+		name = append(name, c)
+	}
+	return string(name)
+}
+
+// closingTag returns the index of the closing tag of the element
+// name after b[start], or len(b) if it is not closed.
+func closingTag(b []byte, start int, name string) int {
+	for i := start; ; i += 2 {
+		j := bytes.Index(b[i:], []byte("</"))
+		if j < 0 {
+			return len(b)
+		}
+		i += j
+		end := i + 2 + len(name)
+		if end <= len(b) && bytes.EqualFold(b[i+2:end], []byte(name)) &&
+			(end == len(b) || b[end] == '>' || b[end] == '/' || isSpace(b[end])) {
+			return i
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package minify
+
+import "bytes"
+
+// JS returns the script b minified conservatively: comments are
+// removed, except those that start with /*! like licenses do, runs
+// of whitespace become a single space, or a single line break if
+// they have one, and whitespace next to punctuation that can't be
+// part of a longer operator is removed. Line breaks are only removed
+// where automatic semicolon insertion does not depend on them, and
+// strings, template literals and regular expressions are kept as
+// they are.
+func JS(b []byte) []byte {
+	var out bytes.Buffer
+	out.Grow(len(b))
+	var space, newline bool // whether whitespace, or a line break, came before
+
+	for i := 0; i < len(b); {
+		c := b[i]
+		switch {
+		case c == '/' && i+1 < len(b) && b[i+1] == '/':
+			end := bytes.IndexByte(b[i:], '\n')
+			if end < 0 {
+				end = len(b)
+			} else {
+				end += i
+			}
+			space = true
+			i = end
+		case c == '/' && i+1 < len(b) && b[i+1] == '*':
+			end := bytes.Index(b[i+2:], []byte("*/"))
+			if end < 0 {
+				end = len(b)
+			} else {
+				end += i + 2 + len("*/")
+			}
+			if i+2 < len(b) && b[i+2] == '!' {
+				writeJS(&out, b[i:end], space, newline)
+				space, newline = false, false
+			} else {
+				space = true
+				newline = newline || bytes.IndexByte(b[i:end], '\n') >= 0
+			}
+			i = end
+		case isSpace(c):
+			space = true
+			newline = newline || c == '\n' || c == '\r'
+			i++
+		case c == '"' || c == '\'' || c == '`' || c == '/' && regexpAllowed(out.Bytes()):
+			var end int
+			switch c {
+			case '`':
+				end = templateEnd(b, i)
+			case '/':
+				end = regexpEnd(b, i)
+			default:
+				end = stringEnd(b, i)
+			}
+			writeJS(&out, b[i:end], space, newline)
+			space, newline = false, false
+			i = end
+		default:
+			writeJS(&out, b[i:i+1], space, newline)
+			space, newline = false, false
+			i++
+		}
+	}
+
+	return out.Bytes()
+}
+
+// jsPunctuation can't be part of a longer token with what is next to
+// it, so whitespace next to it is never needed. Operators like + and
+// . are not, as in a + +b or 1 .toString(), and neither are < and >,
+// which could start or end HTML-like comments.
+const jsPunctuation = "{}()[];,:=?!&|*%^~"
+
+// writeJS writes s to out, with the whitespace before it if space is
+// set and it is needed to separate s from what out ends with. If the
+// whitespace has a line break, it is kept where automatic semicolon
+// insertion could depend on it.
+func writeJS(out *bytes.Buffer, s []byte, space, newline bool) {
+	if space && out.Len() > 0 {
+		last := out.Bytes()[out.Len()-1]
+		switch {
+		case newline && last != '{' && last != ';' && last != ',' && s[0] != '}':
+			out.WriteByte('\n')
+		case newline:
+		case !bytes.ContainsRune([]byte(jsPunctuation), rune(last)) && !bytes.ContainsRune([]byte(jsPunctuation), rune(s[0])):
+			out.WriteByte(' ')
+		}
+	}
+	out.Write(s)
+}
+
+// regexpKeywords are the keywords after which a / starts a regular
+// expression rather than being a division.
+var regexpKeywords = []string{"return", "typeof", "instanceof", "in", "of", "new", "delete", "void", "throw", "case", "do", "else", "yield", "await"}
+
+// regexpAllowed reports whether a / after the minified script out
+// starts a regular expression, rather than being a division.
+func regexpAllowed(out []byte) bool {
+	out = bytes.TrimRight(out, " \n")
+	if len(out) == 0 {
+		return true
+	}
+	last := out[len(out)-1]
+	switch {
+	case last == ')' || last == ']' || last == '}':
+		return false
+	case bytes.HasSuffix(out, []byte("++")) || bytes.HasSuffix(out, []byte("--")):
+		return false
+	case isIdentifierByte(last):
+		i := len(out)
+		for i > 0 && isIdentifierByte(out[i-1]) {
+			i--
+		}
+		word := string(out[i:])
+		for _, keyword := range regexpKeywords {
+			if word == keyword {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// isIdentifierByte reports whether c can be part of an identifier
+// or a number. Bytes of non-ASCII identifiers count as well.
+func isIdentifierByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '$' || c >= 0x80
+}
+
+// regexpEnd returns the index after the end of the regular
+// expression literal that starts at b[start], including its flags,
+// or the index of the line break or the length of b if it is not
+// terminated.
+func regexpEnd(b []byte, start int) int {
+	var class bool // in a character class, where / does not end it
+	for i := start + 1; i < len(b); i++ {
+		switch b[i] {
+		case '\\':
+			i++
+		case '[':
+			class = true
+		case ']':
+			class = false
+		case '/':
+			if !class {
+				i++
+				for i < len(b) && isIdentifierByte(b[i]) {
+					i++
+				}
+				return i
+			}
+		case '\n':
+			return i
+		}
+	}
+	return len(b)
+}
+
+// templateEnd returns the index after the end of the template
+// literal that starts at b[start], or the length of b if it is not
+// terminated. Substitutions like ${a + `b`} are skipped as a whole.
+func templateEnd(b []byte, start int) int {
+	for i := start + 1; i < len(b); i++ {
+		switch {
+		case b[i] == '\\':
+			i++
+		case b[i] == '`':
+			return i + 1
+		case b[i] == '$' && i+1 < len(b) && b[i+1] == '{':
+			i = substitutionEnd(b, i+2) - 1
+		}
+	}
+	return len(b)
+}
+
+// substitutionEnd returns the index after the } that ends the
+// substitution in a template literal whose expression starts at
+// b[start], or the length of b if it does not end.
+func substitutionEnd(b []byte, start int) int {
+	depth := 1
+	for i := start; i < len(b); i++ {
+		switch b[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		case '"', '\'':
+			i = stringEnd(b, i) - 1
+		case '`':
+			i = templateEnd(b, i) - 1
+		}
+	}
+	return len(b)
+}
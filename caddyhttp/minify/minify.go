@@ -0,0 +1,113 @@
+// Package minify implements middleware that minifies HTML, CSS and
+// JavaScript responses, to save bandwidth without changing what the
+// application serves.
+package minify
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+// Minify is middleware that minifies the bodies of responses under
+// certain paths. Like Sub, it only changes complete (200 OK),
+// uncompressed responses of the configured media types that are no
+// larger than MaxSize; gzip comes before it in the chain, so responses
+// are minified before they are compressed. Files that are minified
+// already, like app.min.js, are sent as they are, and so is any
+// response that minifying would not make smaller.
+type Minify struct {
+	Next  httpserver.Handler
+	Paths []string
+
+	// Types are the media types of the responses to minify; each
+	// must have a minifier in Minifiers.
+	Types []string
+
+	// MaxSize is the largest body that is held back
+	// to be minified.
+	MaxSize int
+}
+
+// Minifiers are the minifiers of the media types that
+// can be minified. A minifier must not change what the
+// document means, only how it is written.
+var Minifiers = map[string]func([]byte) []byte{
+	"text/html":                HTML,
+	"text/css":                 CSS,
+	"application/javascript":   JS,
+	"application/x-javascript": JS,
+	"text/javascript":          JS,
+}
+
+// ServeHTTP implements the httpserver.Handler interface.
+func (m Minify) ServeHTTP(w http.ResponseWriter, r *http.Request) (int, error) {
+	if !m.matches(r.URL.Path) || strings.Contains(r.URL.Path, ".min.") {
+		return m.Next.ServeHTTP(w, r)
+	}
+
+	rr := httpserver.NewBufferedResponseRecorder(w)
+	rr.SetBufferLimit(m.MaxSize)
+	status, err := m.Next.ServeHTTP(rr, r)
+	if !rr.Buffered() {
+		// streamed, or too big to minify
+		return status, err
+	}
+
+	header := rr.Header()
+	if rr.Status() == http.StatusOK && status < 400 && rr.Buffer().Len() > 0 && header.Get("Content-Encoding") == "" {
+		if minify := m.minifier(header.Get("Content-Type")); minify != nil {
+			if body := minify(rr.Buffer().Bytes()); len(body) < rr.Buffer().Len() {
+				if header.Get("Content-Length") != "" {
+					header.Set("Content-Length", strconv.Itoa(len(body)))
+				}
+				if etag := header.Get("ETag"); strings.HasPrefix(etag, `"`) {
+					// the body is equivalent, but no longer the same
+					header.Set("ETag", "W/"+etag)
+				}
+				rr.Buffer().Reset()
+				rr.Buffer().Write(body)
+			}
+		}
+	}
+
+	if relErr := rr.Release(); relErr != nil && err == nil {
+		err = relErr
+	}
+	return status, err
+}
+
+// matches reports whether responses to requests for path
+// are minified.
+func (m Minify) matches(path string) bool {
+	for _, p := range m.Paths {
+		if httpserver.Path(path).Matches(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// minifier returns the minifier for responses with the Content-Type
+// header value contentType, or nil if they are not minified.
+func (m Minify) minifier(contentType string) func([]byte) []byte {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil
+	}
+	for _, t := range m.Types {
+		if t == mediaType {
+			return Minifiers[t]
+		}
+	}
+	return nil
+}
+
+// isSpace reports whether c is whitespace in HTML,
+// CSS and JavaScript alike.
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
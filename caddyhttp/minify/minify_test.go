@@ -0,0 +1,159 @@
+package minify
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func TestMinify(t *testing.T) {
+	page := func(status int, body string, header http.Header) httpserver.Handler {
+		return httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+			for name, values := range header {
+				w.Header()[name] = values
+			}
+			w.WriteHeader(status)
+			w.Write([]byte(body))
+			return 0, nil
+		})
+	}
+	const doc = `<!DOCTYPE html>
+<html>
+  <head>
+    <!-- the title -->
+    <title>  Hello  </title>
+  </head>
+  <body>
+    <p>Hello,   world!</p>
+  </body>
+</html>
+`
+	const minified = `<!DOCTYPE html> <html> <head> <title> Hello </title> </head> <body> <p>Hello, world!</p> </body> </html> `
+
+	for i, test := range []struct {
+		path         string
+		next         httpserver.Handler
+		maxSize      int
+		expectedBody string
+		expectedLen  string
+		expectedETag string
+	}{
+		{"/", page(http.StatusOK, doc, http.Header{"Content-Type": {"text/html; charset=utf-8"}}), 1024,
+			minified, "", ""},
+		// the length is recomputed and the ETag weakened
+		{"/", page(http.StatusOK, doc, http.Header{"Content-Type": {"text/html"}, "Content-Length": {"148"}, "Etag": {`"abc"`}}), 1024,
+			minified, "105", `W/"abc"`},
+		{"/", page(http.StatusOK, "a { color: red; }", http.Header{"Content-Type": {"text/css"}}), 1024,
+			"a{color:red}", "", ""},
+		{"/", page(http.StatusOK, "var a = 1 ;\n// one\n", http.Header{"Content-Type": {"application/javascript"}}), 1024,
+			"var a=1;", "", ""},
+
+		// binary and other types are untouched
+		{"/", page(http.StatusOK, "\x89PNG  \n\n  <!-- -->", http.Header{"Content-Type": {"image/png"}}), 1024,
+			"\x89PNG  \n\n  <!-- -->", "", ""},
+		{"/", page(http.StatusOK, doc, nil), 1024, doc, "", ""},
+		// and so are files minified already, compressed, partial and too big responses
+		{"/app.min.js", page(http.StatusOK, "var a = 1 ;", http.Header{"Content-Type": {"application/javascript"}}), 1024,
+			"var a = 1 ;", "", ""},
+		{"/", page(http.StatusOK, doc, http.Header{"Content-Type": {"text/html"}, "Content-Encoding": {"gzip"}}), 1024,
+			doc, "", ""},
+		{"/", page(http.StatusPartialContent, doc, http.Header{"Content-Type": {"text/html"}}), 1024, doc, "", ""},
+		{"/", page(http.StatusOK, doc, http.Header{"Content-Type": {"text/html"}}), 10, doc, "", ""},
+		// as is what can't be made smaller
+		{"/", page(http.StatusOK, "<p>a</p>", http.Header{"Content-Type": {"text/html"}, "Etag": {`"abc"`}}), 1024,
+			"<p>a</p>", "", `"abc"`},
+	} {
+		m := Minify{Next: test.next, Paths: []string{"/"}, Types: minifiableTypes(), MaxSize: test.maxSize}
+		r, err := http.NewRequest("GET", test.path, nil)
+		if err != nil {
+			t.Fatalf("Test %d: Could not create HTTP request: %v", i, err)
+		}
+		rec := httptest.NewRecorder()
+
+		if _, err := m.ServeHTTP(rec, r); err != nil {
+			t.Errorf("Test %d: Expected no error, got: %v", i, err)
+		}
+		if got := rec.Body.String(); got != test.expectedBody {
+			t.Errorf("Test %d: Expected body %q, got %q", i, test.expectedBody, got)
+		}
+		if got := rec.Header().Get("Content-Length"); test.expectedLen != "" && got != test.expectedLen {
+			t.Errorf("Test %d: Expected Content-Length %s, got %s", i, test.expectedLen, got)
+		}
+		if got := rec.Header().Get("ETag"); test.expectedETag != "" && got != test.expectedETag {
+			t.Errorf("Test %d: Expected ETag %s, got %s", i, test.expectedETag, got)
+		}
+	}
+}
+
+func TestHTML(t *testing.T) {
+	for i, test := range []struct {
+		input, expected string
+	}{
+		{"<p>\n\t a  b \n</p>", "<p> a b </p>"},
+		{"<p>a<!-- b -->c</p>", "<p>ac</p>"},
+		{"<!--[if IE]><p>old</p><![endif]-->", "<!--[if IE]><p>old</p><![endif]-->"},
+		// tags are kept as they are, quoted > included
+		{`<a  title="a  >  b"   href=x>  y</a>`, `<a  title="a  >  b"   href=x> y</a>`},
+		{"<pre>  a\n\n  b</pre>  <PRE class=x> c  </PRE>", "<pre>  a\n\n  b</pre> <PRE class=x> c  </PRE>"},
+		{"<textarea>  a  </textarea>", "<textarea>  a  </textarea>"},
+		{"<script>\n  var a = '<!-- x -->';\n</script>", "<script>\n  var a = '<!-- x -->';\n</script>"},
+		{"<style>\n  a  {  }\n</style>", "<style>\n  a  {  }\n</style>"},
+		{"<pre>unclosed  ", "<pre>unclosed  "},
+		{"a  < b  <!-- unclosed", "a < b <!-- unclosed"},
+		{"<p>a <!-- b --> c</p>", "<p>a c</p>"},
+	} {
+		if got := string(HTML([]byte(test.input))); got != test.expected {
+			t.Errorf("Test %d: Expected %q, got %q", i, test.expected, got)
+		}
+	}
+}
+
+func TestCSS(t *testing.T) {
+	for i, test := range []struct {
+		input, expected string
+	}{
+		{"a , b {\n  color: red;\n  margin: 0  auto;\n}\n", "a,b{color:red;margin:0 auto}"},
+		{"a :hover , b:hover { }", "a :hover,b:hover{}"},
+		{"/* comment */ a { } /*! license */", "a{}/*! license */"},
+		{"a/**/b { }", "a b{}"},
+		{"a:hover > b { }", "a:hover > b{}"},
+		{`a::after { content: "  a ; b  " }`, `a::after{content:"  a ; b  "}`},
+		{`a { content: '\'  /* x */' }`, `a{content:'\'  /* x */'}`},
+		{"@media (max-width: 10px) { a { b: c; } }", "@media (max-width:10px){a{b:c}}"},
+	} {
+		if got := string(CSS([]byte(test.input))); got != test.expected {
+			t.Errorf("Test %d: Expected %q, got %q", i, test.expected, got)
+		}
+	}
+}
+
+func TestJS(t *testing.T) {
+	for i, test := range []struct {
+		input, expected string
+	}{
+		{"var a = 1 ;\nvar b = [ 1 , 2 ] ;", "var a=1;var b=[1,2];"},
+		{"function f ( a ) {\n  return a ;\n}\n", "function f(a){return a;}"},
+		{"/* comment */ a ( ) ; // comment\nb ( ) ;", "a();b();"},
+		{"/*! license */\na ( ) ;", "/*! license */\na();"},
+		// newlines that may end statements are kept
+		{"a = b\n++c", "a=b\n++c"},
+		{"return\nx", "return\nx"},
+		{"a = b\n\n\n(c)", "a=b\n(c)"},
+		// as are spaces between operators that would merge
+		{"a + +b - -c", "a + +b - -c"},
+		{"1 .toString ( )", "1 .toString()"},
+		{"a-- > b", "a-- > b"},
+		// strings, regular expressions and templates are kept as they are
+		{`a = "  // not a comment  " + '  /* nor this */  '`, `a="  // not a comment  " + '  /* nor this */  '`},
+		{`a = b . replace ( /  [/"]  /g , "" )`, `a=b . replace(/  [/"]  /g,"")`},
+		{"if ( x ) return /  a  /.test ( s )", "if(x)return /  a  /.test(s)"},
+		{"a = b / 2 / c", "a=b / 2 / c"},
+		{"a = `  ${ b + `  ${ c }  ` }  `", "a=`  ${ b + `  ${ c }  ` }  `"},
+	} {
+		if got := string(JS([]byte(test.input))); got != test.expected {
+			t.Errorf("Test %d: Expected %q, got %q", i, test.expected, got)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package minify
+
+import (
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func init() {
+	caddy.RegisterPlugin("minify_responses", caddy.Plugin{
+		ServerType: "http",
+		Action:     setup,
+	})
+}
+
+// setup configures a new Minify middleware instance.
+func setup(c *caddy.Controller) error {
+	m, err := minifyParse(c)
+	if err != nil {
+		return err
+	}
+
+	httpserver.GetConfig(c).AddMiddleware(func(next httpserver.Handler) httpserver.Handler {
+		m.Next = next
+		return m
+	})
+
+	return nil
+}
+
+// minifyParse parses the minify_responses directive, which is not
+// called minify so as not to clash with the plugin of that name:
+//
+//	minify_responses [paths...] {
+//		types    <media types...>
+//		max_size <bytes>
+//	}
+//
+// By default, all the types in Minifiers are minified.
+func minifyParse(c *caddy.Controller) (Minify, error) {
+	m := Minify{MaxSize: defaultMaxSize}
+
+	for c.Next() {
+		paths := c.RemainingArgs()
+		if len(paths) == 0 {
+			paths = []string{"/"}
+		}
+		m.Paths = append(m.Paths, paths...)
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "types":
+				types := c.RemainingArgs()
+				if len(types) == 0 {
+					return m, c.ArgErr()
+				}
+				for _, t := range types {
+					mediaType, _, err := mime.ParseMediaType(t)
+					if err != nil || !strings.Contains(mediaType, "/") {
+						return m, c.Errf("invalid media type '%s'", t)
+					}
+					if Minifiers[mediaType] == nil {
+						return m, c.Errf("cannot minify %s; types that can be minified are: %s", mediaType, strings.Join(minifiableTypes(), ", "))
+					}
+					m.Types = append(m.Types, mediaType)
+				}
+			case "max_size":
+				if !c.NextArg() {
+					return m, c.ArgErr()
+				}
+				size, err := strconv.Atoi(c.Val())
+				if err != nil || size < 1 {
+					return m, c.Errf("max_size must be a positive number of bytes, got '%s'", c.Val())
+				}
+				m.MaxSize = size
+				if c.NextArg() {
+					return m, c.ArgErr()
+				}
+			default:
+				return m, c.Errf("unknown minify_responses property '%s'", c.Val())
+			}
+		}
+	}
+
+	if len(m.Types) == 0 {
+		m.Types = minifiableTypes()
+	}
+
+	return m, nil
+}
+
+// minifiableTypes returns the media types that have
+// minifiers, in order.
+func minifiableTypes() []string {
+	var types []string
+	for t := range Minifiers {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// defaultMaxSize is the largest body that is minified by default.
+const defaultMaxSize = 1 << 20
@@ -0,0 +1,72 @@
+package minify
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func TestSetup(t *testing.T) {
+	c := caddy.NewTestController("http", `minify_responses`)
+	err := setup(c)
+	if err != nil {
+		t.Errorf("Expected no errors, got: %v", err)
+	}
+	mids := httpserver.GetConfig(c).Middleware()
+	if len(mids) == 0 {
+		t.Fatal("Expected middleware, got 0 instead")
+	}
+
+	handler := mids[0](httpserver.EmptyNext)
+	myHandler, ok := handler.(Minify)
+	if !ok {
+		t.Fatalf("Expected handler to be type Minify, got: %#v", handler)
+	}
+	if !httpserver.SameNext(myHandler.Next, httpserver.EmptyNext) {
+		t.Error("'Next' field of handler was not set properly")
+	}
+}
+
+func TestMinifyParse(t *testing.T) {
+	for i, test := range []struct {
+		input     string
+		shouldErr bool
+		expected  Minify
+	}{
+		{`minify_responses`, false, Minify{Paths: []string{"/"}, Types: minifiableTypes(), MaxSize: defaultMaxSize}},
+		{`minify_responses /blog /docs {
+			types text/HTML;charset=utf-8 text/css
+			max_size 4096
+		  }`, false, Minify{Paths: []string{"/blog", "/docs"}, Types: []string{"text/html", "text/css"}, MaxSize: 4096}},
+		{`minify_responses {
+			types
+		  }`, true, Minify{}},
+		{`minify_responses {
+			types html
+		  }`, true, Minify{}},
+		{`minify_responses {
+			types image/png
+		  }`, true, Minify{}},
+		{`minify_responses {
+			max_size 0
+		  }`, true, Minify{}},
+		{`minify_responses {
+			max_size 1 2
+		  }`, true, Minify{}},
+		{`minify_responses {
+			level 9
+		  }`, true, Minify{}},
+	} {
+		actual, err := minifyParse(caddy.NewTestController("http", test.input))
+		if err == nil && test.shouldErr {
+			t.Errorf("Test %d didn't error, but it should have", i)
+		} else if err != nil && !test.shouldErr {
+			t.Errorf("Test %d errored, but it shouldn't have; got '%v'", i, err)
+		}
+		if !test.shouldErr && !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("Test %d: Expected %+v, got %+v", i, test.expected, actual)
+		}
+	}
+}
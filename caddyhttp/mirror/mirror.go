@@ -0,0 +1,156 @@
+// Package mirror implements middleware that sends a copy of
+// requests to a secondary upstream, to try it out with real
+// traffic while the primary handlers keep serving clients.
+package mirror
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+// Mirror is middleware that mirrors requests under Path to the
+// upstream To. Mirrored requests are sent in the background and
+// their responses are discarded, so that neither their latency nor
+// their failures affect clients. Requests with bodies larger than
+// MaxBody are not mirrored, and neither are requests that come in
+// while MaxInFlight mirrored requests are still pending.
+type Mirror struct {
+	Next    httpserver.Handler
+	Path    string
+	To      *url.URL // scheme, host and optionally a path prefix
+	MaxBody int64
+	Client  *http.Client
+
+	// inFlight holds a token for every pending mirrored
+	// request; if it is nil, there is no limit.
+	inFlight chan struct{}
+}
+
+// ServeHTTP implements the httpserver.Handler interface.
+func (m Mirror) ServeHTTP(w http.ResponseWriter, r *http.Request) (int, error) {
+	if !httpserver.Path(r.URL.Path).Matches(m.Path) {
+		return m.Next.ServeHTTP(w, r)
+	}
+
+	if body, ok := m.teeBody(r); ok {
+		m.mirror(r, body)
+	}
+	return m.Next.ServeHTTP(w, r)
+}
+
+// teeBody reads the body of r up to MaxBody bytes and replaces it
+// with one that reads the same, so that the next handlers get all
+// of it. It returns what it read, and whether that is all of it.
+func (m Mirror) teeBody(r *http.Request) ([]byte, bool) {
+	if r.Body == nil {
+		return nil, true
+	}
+	if r.ContentLength > m.MaxBody {
+		return nil, false
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, m.MaxBody+1))
+	r.Body = readCloser{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+	if err != nil {
+		// the next handler gets the error as well
+		return nil, false
+	}
+	return body, int64(len(body)) <= m.MaxBody
+}
+
+// readCloser reads from a Reader and closes a Closer.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// mirror sends a copy of r, with body, to the upstream in the
+// background, unless too many mirrored requests are pending.
+func (m Mirror) mirror(r *http.Request, body []byte) {
+	if m.inFlight != nil {
+		select {
+		case m.inFlight <- struct{}{}:
+		default:
+			return
+		}
+	}
+
+	req, err := m.newRequest(r, body)
+	if err != nil {
+		m.done()
+		log.Printf("[ERROR] mirror: %v", err)
+		return
+	}
+
+	go func() {
+		defer m.done()
+		resp, err := m.Client.Do(req)
+		if err != nil {
+			log.Printf("[WARNING] mirror: %v", err)
+			return
+		}
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+	}()
+}
+
+// done releases the token of a mirrored request.
+func (m Mirror) done() {
+	if m.inFlight != nil {
+		<-m.inFlight
+	}
+}
+
+// newRequest returns the copy of r, with body, that goes
+// to the upstream.
+func (m Mirror) newRequest(r *http.Request, body []byte) (*http.Request, error) {
+	u := *m.To
+	u.Path = path.Join("/", m.To.Path, r.URL.Path)
+	if strings.HasSuffix(r.URL.Path, "/") && !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+	u.RawPath = ""
+	u.RawQuery = r.URL.RawQuery
+
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(r.Method, u.String(), bodyReader)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range r.Header {
+		req.Header[name] = append([]string(nil), values...)
+	}
+	if c := req.Header.Get("Connection"); c != "" {
+		for _, name := range strings.Split(c, ",") {
+			req.Header.Del(strings.TrimSpace(name))
+		}
+	}
+	for _, name := range hopHeaders {
+		req.Header.Del(name)
+	}
+	return req, nil
+}
+
+// hopHeaders are the hop-by-hop headers of a request,
+// which are not mirrored.
+var hopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Proxy-Connection",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
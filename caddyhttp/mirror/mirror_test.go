@@ -0,0 +1,117 @@
+package mirror
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+// mirrored is a request as the upstream received it.
+type mirrored struct {
+	method, uri, body string
+	header            http.Header
+}
+
+func TestMirror(t *testing.T) {
+	received := make(chan mirrored, 10)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		received <- mirrored{r.Method, r.RequestURI, string(body), r.Header}
+		w.WriteHeader(http.StatusInternalServerError) // never seen by clients
+		w.Write([]byte("mirror"))
+	}))
+	defer upstream.Close()
+	to, _ := url.Parse(upstream.URL + "/shadow")
+
+	// the primary handler echoes the body
+	echo := httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			return http.StatusBadRequest, err
+		}
+		w.Write(body)
+		return http.StatusOK, nil
+	})
+	m := Mirror{Next: echo, Path: "/api", To: to, MaxBody: 16, Client: http.DefaultClient,
+		inFlight: make(chan struct{}, 10)}
+
+	for i, test := range []struct {
+		method, path, body string
+		expected           *mirrored
+	}{
+		{"POST", "/api/items?x=1", "hello", &mirrored{method: "POST", uri: "/shadow/api/items?x=1", body: "hello"}},
+		{"GET", "/api/", "", &mirrored{method: "GET", uri: "/shadow/api/"}},
+		// too large to mirror, or not mirrored at all
+		{"POST", "/api/items", strings.Repeat("x", 17), nil},
+		{"POST", "/other", "hello", nil},
+	} {
+		r, err := http.NewRequest(test.method, "http://localhost"+test.path, strings.NewReader(test.body))
+		if err != nil {
+			t.Fatalf("Test %d: Could not create HTTP request: %v", i, err)
+		}
+		r.ContentLength = -1 // as if chunked
+		r.Header.Set("X-Test", "yes")
+		r.Header.Set("Connection", "X-Hop")
+		r.Header.Set("X-Hop", "yes")
+		rec := httptest.NewRecorder()
+
+		status, err := m.ServeHTTP(rec, r)
+		if status != http.StatusOK || err != nil {
+			t.Errorf("Test %d: Expected status 200 and no error, got %d and %v", i, status, err)
+		}
+		if got := rec.Body.String(); got != test.body {
+			t.Errorf("Test %d: Expected the primary handler to get body %q, got %q", i, test.body, got)
+		}
+
+		select {
+		case got := <-received:
+			if test.expected == nil {
+				t.Errorf("Test %d: Expected no mirrored request, got %+v", i, got)
+				continue
+			}
+			if got.method != test.expected.method || got.uri != test.expected.uri || got.body != test.expected.body {
+				t.Errorf("Test %d: Expected %s %s with body %q mirrored, got %s %s with body %q", i,
+					test.expected.method, test.expected.uri, test.expected.body, got.method, got.uri, got.body)
+			}
+			if got.header.Get("X-Test") != "yes" || got.header.Get("X-Hop") != "" {
+				t.Errorf("Test %d: Expected the end-to-end headers only, got %v", i, got.header)
+			}
+		case <-time.After(200 * time.Millisecond):
+			if test.expected != nil {
+				t.Errorf("Test %d: Expected a mirrored request, got none", i)
+			}
+		}
+	}
+}
+
+func TestMirrorFailure(t *testing.T) {
+	upstream := httptest.NewServer(http.NotFoundHandler())
+	to, _ := url.Parse(upstream.URL)
+	upstream.Close() // nothing listens
+
+	m := Mirror{
+		Next: httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+			w.Write([]byte("primary"))
+			return http.StatusOK, nil
+		}),
+		Path: "/", To: to, MaxBody: 16, Client: http.DefaultClient, inFlight: make(chan struct{}, 1),
+	}
+
+	for i := 0; i < 3; i++ {
+		r, err := http.NewRequest("GET", "http://localhost/", nil)
+		if err != nil {
+			t.Fatalf("Could not create HTTP request: %v", err)
+		}
+		rec := httptest.NewRecorder()
+		status, err := m.ServeHTTP(rec, r)
+		if status != http.StatusOK || err != nil || rec.Body.String() != "primary" {
+			t.Errorf("Request %d: Expected the primary response, got %d, %v and %q", i, status, err, rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package mirror
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func init() {
+	caddy.RegisterPlugin("mirror", caddy.Plugin{
+		ServerType: "http",
+		Action:     setup,
+	})
+}
+
+// setup configures a new Mirror middleware instance.
+func setup(c *caddy.Controller) error {
+	mirrors, err := mirrorParse(c)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range mirrors {
+		m := m
+		httpserver.GetConfig(c).AddMiddleware(func(next httpserver.Handler) httpserver.Handler {
+			m.Next = next
+			return m
+		})
+	}
+
+	return nil
+}
+
+// mirrorParse parses the directive:
+//
+//	mirror [path] <to> {
+//		max_body      <bytes>
+//		max_in_flight <number>
+//		timeout       <duration>
+//	}
+//
+// where to is the URL of the upstream, like http://localhost:8081,
+// which may have a path that mirrored paths are appended to.
+func mirrorParse(c *caddy.Controller) ([]Mirror, error) {
+	var mirrors []Mirror
+
+	for c.Next() {
+		m := Mirror{Path: "/", MaxBody: defaultMaxBody}
+		maxInFlight, timeout := defaultMaxInFlight, defaultTimeout
+
+		args := c.RemainingArgs()
+		switch len(args) {
+		case 1:
+		case 2:
+			m.Path = args[0]
+		default:
+			return nil, c.ArgErr()
+		}
+		to, err := url.Parse(args[len(args)-1])
+		if err != nil || (to.Scheme != "http" && to.Scheme != "https") || to.Host == "" {
+			return nil, c.Errf("mirror upstream must be an http or https URL, got '%s'", args[len(args)-1])
+		}
+		if to.RawQuery != "" || to.Fragment != "" {
+			return nil, c.Errf("mirror upstream can't have a query or fragment, got '%s'", args[len(args)-1])
+		}
+		m.To = to
+
+		for c.NextBlock() {
+			prop := c.Val()
+			if !c.NextArg() {
+				return nil, c.ArgErr()
+			}
+			switch prop {
+			case "max_body":
+				size, err := strconv.ParseInt(c.Val(), 10, 64)
+				if err != nil || size < 0 {
+					return nil, c.Errf("max_body must be a number of bytes, got '%s'", c.Val())
+				}
+				m.MaxBody = size
+			case "max_in_flight":
+				n, err := strconv.Atoi(c.Val())
+				if err != nil || n < 1 {
+					return nil, c.Errf("max_in_flight must be a positive number, got '%s'", c.Val())
+				}
+				maxInFlight = n
+			case "timeout":
+				d, err := time.ParseDuration(c.Val())
+				if err != nil || d <= 0 {
+					return nil, c.Errf("timeout must be a positive duration, got '%s'", c.Val())
+				}
+				timeout = d
+			default:
+				return nil, c.Errf("unknown mirror property '%s'", prop)
+			}
+			if c.NextArg() {
+				return nil, c.ArgErr()
+			}
+		}
+
+		m.Client = &http.Client{
+			Timeout: timeout,
+			// the response is discarded anyway
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		}
+		m.inFlight = make(chan struct{}, maxInFlight)
+		mirrors = append(mirrors, m)
+	}
+
+	return mirrors, nil
+}
+
+const (
+	// defaultMaxBody is the largest body of a request that is
+	// mirrored by default.
+	defaultMaxBody = 1 << 20
+
+	// defaultMaxInFlight is how many mirrored requests may be
+	// pending at once by default.
+	defaultMaxInFlight = 100
+
+	// defaultTimeout is how long a mirrored request may take
+	// by default.
+	defaultTimeout = 30 * time.Second
+)
@@ -0,0 +1,90 @@
+package mirror
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func TestSetup(t *testing.T) {
+	c := caddy.NewTestController("http", `mirror http://localhost:8081`)
+	err := setup(c)
+	if err != nil {
+		t.Errorf("Expected no errors, got: %v", err)
+	}
+	mids := httpserver.GetConfig(c).Middleware()
+	if len(mids) == 0 {
+		t.Fatal("Expected middleware, got 0 instead")
+	}
+
+	handler := mids[0](httpserver.EmptyNext)
+	myHandler, ok := handler.(Mirror)
+	if !ok {
+		t.Fatalf("Expected handler to be type Mirror, got: %#v", handler)
+	}
+	if !httpserver.SameNext(myHandler.Next, httpserver.EmptyNext) {
+		t.Error("'Next' field of handler was not set properly")
+	}
+}
+
+func TestMirrorParse(t *testing.T) {
+	for i, test := range []struct {
+		input       string
+		shouldErr   bool
+		path, to    string
+		maxBody     int64
+		maxInFlight int
+		timeout     time.Duration
+	}{
+		{`mirror http://localhost:8081`, false, "/", "http://localhost:8081", defaultMaxBody, defaultMaxInFlight, defaultTimeout},
+		{`mirror /api https://shadow.example.com/v2 {
+			max_body 0
+			max_in_flight 5
+			timeout 2s
+		  }`, false, "/api", "https://shadow.example.com/v2", 0, 5, 2 * time.Second},
+		{`mirror`, true, "", "", 0, 0, 0},
+		{`mirror /api http://a http://b`, true, "", "", 0, 0, 0},
+		{`mirror localhost:8081`, true, "", "", 0, 0, 0},
+		{`mirror ftp://localhost`, true, "", "", 0, 0, 0},
+		{`mirror http://localhost/?x=1`, true, "", "", 0, 0, 0},
+		{`mirror http://localhost {
+			max_body -1
+		  }`, true, "", "", 0, 0, 0},
+		{`mirror http://localhost {
+			max_in_flight 0
+		  }`, true, "", "", 0, 0, 0},
+		{`mirror http://localhost {
+			timeout
+		  }`, true, "", "", 0, 0, 0},
+		{`mirror http://localhost {
+			timeout 1s 2s
+		  }`, true, "", "", 0, 0, 0},
+		{`mirror http://localhost {
+			response true
+		  }`, true, "", "", 0, 0, 0},
+	} {
+		mirrors, err := mirrorParse(caddy.NewTestController("http", test.input))
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d didn't error, but it should have", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d errored, but it shouldn't have; got '%v'", i, err)
+			continue
+		}
+		if len(mirrors) != 1 {
+			t.Fatalf("Test %d: Expected 1 mirror, got %d", i, len(mirrors))
+		}
+		m := mirrors[0]
+		if m.Path != test.path || m.To.String() != test.to || m.MaxBody != test.maxBody ||
+			cap(m.inFlight) != test.maxInFlight || m.Client.Timeout != test.timeout {
+			t.Errorf("Test %d: Expected %s to %s with max body %d, %d in flight and timeout %s; got %s to %s with %d, %d and %s",
+				i, test.path, test.to, test.maxBody, test.maxInFlight, test.timeout,
+				m.Path, m.To, m.MaxBody, cap(m.inFlight), m.Client.Timeout)
+		}
+	}
+}
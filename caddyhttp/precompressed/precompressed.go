@@ -0,0 +1,48 @@
+// Package precompressed configures which precompressed variants
+// of static files (such as foo.js.br or foo.js.gz) a site serves.
+package precompressed
+
+import (
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+	"github.com/mholt/caddy/caddyhttp/staticfiles"
+)
+
+func init() {
+	caddy.RegisterPlugin("precompressed", caddy.Plugin{
+		ServerType: "http",
+		Action:     setupPrecompressed,
+	})
+}
+
+// setupPrecompressed parses the precompressed directive:
+//
+//	precompressed off | encodings...
+//
+// The encodings are listed in order of preference.
+func setupPrecompressed(c *caddy.Controller) error {
+	config := httpserver.GetConfig(c)
+	for c.Next() {
+		args := c.RemainingArgs()
+		if len(args) == 0 {
+			return c.ArgErr()
+		}
+		if args[0] == "off" {
+			if len(args) > 1 || len(config.Precompressed) > 0 {
+				return c.Err("precompressed off cannot be combined with encodings")
+			}
+			config.Precompressed = []string{}
+			continue
+		}
+		if config.Precompressed != nil && len(config.Precompressed) == 0 {
+			return c.Err("precompressed off cannot be combined with encodings")
+		}
+		for _, encoding := range args {
+			if _, ok := staticfiles.PrecompressedExtensions[encoding]; !ok {
+				return c.Errf("unsupported precompressed encoding '%s'", encoding)
+			}
+			config.Precompressed = append(config.Precompressed, encoding)
+		}
+	}
+	return nil
+}
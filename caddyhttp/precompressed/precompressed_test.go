@@ -0,0 +1,41 @@
+package precompressed
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func TestSetupPrecompressed(t *testing.T) {
+	for i, test := range []struct {
+		input     string
+		shouldErr bool
+		expected  []string
+	}{
+		{"precompressed gzip", false, []string{"gzip"}},
+		{"precompressed gzip br", false, []string{"gzip", "br"}},
+		{"precompressed br\nprecompressed gzip", false, []string{"br", "gzip"}},
+		{"precompressed off", false, []string{}},
+		{"precompressed", true, nil},
+		{"precompressed zstd", true, nil},
+		{"precompressed off gzip", true, nil},
+		{"precompressed off\nprecompressed gzip", true, nil},
+		{"precompressed gzip\nprecompressed off", true, nil},
+	} {
+		c := caddy.NewTestController("http", test.input)
+		err := setupPrecompressed(c)
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %d: Expected an error, but did not have one", i)
+		}
+		if !test.shouldErr && err != nil {
+			t.Errorf("Test %d: Did not expect error, but got: %v", i, err)
+		}
+
+		got := httpserver.GetConfig(c).Precompressed
+		if !test.shouldErr && !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("Test %d: Expected %#v, got %#v", i, test.expected, got)
+		}
+	}
+}
@@ -189,6 +189,16 @@ func (p Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) (int, error) {
 			downHeaderUpdateFn = createRespHeaderUpdateFn(host.DownstreamHeaders, replacer)
 		}
 
+		// report how long the upstream took to respond, until
+		// its response header came in
+		start := time.Now()
+		updateFn := func(res *http.Response) {
+			httpserver.AddServerTiming(r, "upstream", time.Since(start))
+			if downHeaderUpdateFn != nil {
+				downHeaderUpdateFn(res)
+			}
+		}
+
 		// Before we retry the request we have to make sure
 		// that the body is rewound to it's beginning.
 		if bb, ok := outreq.Body.(*bufferedBody); ok {
@@ -206,7 +216,7 @@ func (p Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) (int, error) {
 		func() {
 			atomic.AddInt64(&host.Conns, 1)
 			defer atomic.AddInt64(&host.Conns, -1)
-			backendErr = proxy.ServeHTTP(w, outreq, downHeaderUpdateFn)
+			backendErr = proxy.ServeHTTP(w, outreq, updateFn)
 		}()
 
 		// if no errors, we're done here
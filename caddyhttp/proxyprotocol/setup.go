@@ -0,0 +1,46 @@
+// Package proxyprotocol lets a site sit behind load balancers
+// and proxies that tell the address of the client with a PROXY
+// protocol header at the start of each connection.
+package proxyprotocol
+
+import (
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func init() {
+	caddy.RegisterPlugin("proxy_protocol", caddy.Plugin{
+		ServerType: "http",
+		Action:     setup,
+	})
+}
+
+// setup parses the proxy_protocol directive:
+//
+//	proxy_protocol <ranges...>
+//
+// Connections from peers in the ranges must start with a
+// version 1 or 2 header, and the client it conveys becomes the
+// remote address of the connection. Connections from other
+// peers are served as they are. The sites on a listener must
+// trust the same peers, if any.
+func setup(c *caddy.Controller) error {
+	config := httpserver.GetConfig(c)
+	for c.Next() {
+		if config.ProxyProtocol != nil {
+			return c.Err("proxy_protocol can only be specified once per site")
+		}
+		ranges := c.RemainingArgs()
+		if len(ranges) == 0 {
+			return c.ArgErr()
+		}
+		for _, r := range ranges {
+			ipnet, err := httpserver.ParseCIDR(r)
+			if err != nil {
+				return c.Errf("invalid peer range '%s': %v", r, err)
+			}
+			config.ProxyProtocol = append(config.ProxyProtocol, ipnet)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package proxyprotocol
+
+import (
+	"testing"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func TestSetup(t *testing.T) {
+	for i, test := range []struct {
+		input     string
+		shouldErr bool
+		expected  []string
+	}{
+		{"proxy_protocol 10.0.0.0/8", false, []string{"10.0.0.0/8"}},
+		{"proxy_protocol 192.168.1.10 fd00::/8", false, []string{"192.168.1.10/32", "fd00::/8"}},
+		{"proxy_protocol", true, nil},
+		{"proxy_protocol 10.0.0.0/33", true, nil},
+		{"proxy_protocol example.com", true, nil},
+		{"proxy_protocol 10.0.0.0/8\nproxy_protocol 127.0.0.1", true, nil},
+	} {
+		c := caddy.NewTestController("http", test.input)
+		err := setup(c)
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: Expected an error, but did not have one", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: Did not expect error, but got: %v", i, err)
+			continue
+		}
+
+		peers := httpserver.GetConfig(c).ProxyProtocol
+		if len(peers) != len(test.expected) {
+			t.Fatalf("Test %d: Expected %d peer ranges, got %d", i, len(test.expected), len(peers))
+		}
+		for j, ipnet := range peers {
+			if ipnet.String() != test.expected[j] {
+				t.Errorf("Test %d: Expected peer range %s, got %s", i, test.expected[j], ipnet)
+			}
+		}
+	}
+}
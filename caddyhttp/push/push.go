@@ -0,0 +1,84 @@
+// Package push provides middleware that uses HTTP/2 server push
+// to send resources to the client before it asks for them.
+package push
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+// Push is middleware that pushes associated resources to
+// the client when a request matches a configured path.
+type Push struct {
+	Next  httpserver.Handler
+	Rules []Rule
+}
+
+// Rule associates a path with the resources that should
+// be pushed when a request for that path comes in.
+type Rule struct {
+	Path      string
+	Resources []Resource
+}
+
+// Resource is a single resource to push, along with the
+// method and headers of the request promised to the client.
+type Resource struct {
+	Path   string
+	Method string
+	Header http.Header
+}
+
+// ServeHTTP implements the httpserver.Handler interface. Pushes are
+// attempted before the request is handed down the chain, since they
+// must be promised before the response body is written. Push is only
+// an optimization, so failing to push never fails the response.
+func (p Push) ServeHTTP(w http.ResponseWriter, r *http.Request) (int, error) {
+outer:
+	for _, rule := range p.Rules {
+		if !httpserver.Path(r.URL.Path).Matches(rule.Path) {
+			continue
+		}
+		for _, res := range rule.Resources {
+			err := push(w, res.Path, res.Method, promisedHeader(r, res.Header))
+			if err == errPushUnsupported {
+				// HTTP/1.x, or the client disabled push; no
+				// need to keep trying for this request
+				break outer
+			}
+		}
+	}
+	return p.Next.ServeHTTP(w, r)
+}
+
+// promisedHeader returns the header of the request promised to the
+// client for a pushed resource. Headers of the original request that
+// affect which representation is served are carried over so that the
+// pushed response matches what the client would have gotten by asking
+// for it; the configured headers are applied on top of those.
+func promisedHeader(r *http.Request, configured http.Header) http.Header {
+	h := make(http.Header)
+	for _, name := range forwardedHeaders {
+		if v, ok := r.Header[name]; ok {
+			h[name] = v
+		}
+	}
+	for name, v := range configured {
+		h[name] = v
+	}
+	return h
+}
+
+// forwardedHeaders are the request headers copied from the
+// original request to the requests promised by a push.
+var forwardedHeaders = []string{
+	"Accept-Encoding",
+	"Accept-Language",
+	"User-Agent",
+}
+
+// errPushUnsupported is returned by push if the connection
+// cannot be used for server push.
+var errPushUnsupported = errors.New("server push not supported on this connection")
@@ -0,0 +1,136 @@
+// +build go1.8
+
+package push
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+type pushed struct {
+	target string
+	method string
+	header http.Header
+}
+
+// pushRecorder is a ResponseRecorder that also implements
+// http.Pusher by recording the resources it was asked to push.
+type pushRecorder struct {
+	*httptest.ResponseRecorder
+	pushes []pushed
+	err    error
+}
+
+func (p *pushRecorder) Push(target string, opts *http.PushOptions) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.pushes = append(p.pushes, pushed{target, opts.Method, opts.Header})
+	return nil
+}
+
+func newPushRecorder() *pushRecorder {
+	return &pushRecorder{ResponseRecorder: httptest.NewRecorder()}
+}
+
+func TestPush(t *testing.T) {
+	next := httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+		w.Write([]byte("Hello"))
+		return 0, nil
+	})
+	p := Push{
+		Next: next,
+		Rules: []Rule{
+			{Path: "/index.html", Resources: []Resource{
+				{Path: "/style.css", Method: "GET", Header: http.Header{}},
+				{Path: "/app.js", Method: "HEAD", Header: http.Header{"X-Foo": []string{"bar"}}},
+			}},
+		},
+	}
+
+	for i, test := range []struct {
+		path     string
+		expected []pushed
+	}{
+		{"/index.html", []pushed{
+			{"/style.css", "GET", http.Header{"Accept-Encoding": []string{"gzip"}}},
+			{"/app.js", "HEAD", http.Header{"Accept-Encoding": []string{"gzip"}, "X-Foo": []string{"bar"}}},
+		}},
+		{"/about.html", nil},
+	} {
+		req, err := http.NewRequest("GET", test.path, nil)
+		if err != nil {
+			t.Fatalf("Test %d: Could not create HTTP request: %v", i, err)
+		}
+		req.Header.Set("Accept-Encoding", "gzip")
+		req.Header.Set("Cookie", "secret=1")
+		rec := newPushRecorder()
+
+		p.ServeHTTP(rec, req)
+
+		if !reflect.DeepEqual(rec.pushes, test.expected) {
+			t.Errorf("Test %d: Expected pushes %+v, got %+v", i, test.expected, rec.pushes)
+		}
+		if got := rec.Body.String(); got != "Hello" {
+			t.Errorf("Test %d: Expected response body 'Hello', got %q", i, got)
+		}
+	}
+}
+
+func TestPushThroughResponseRecorder(t *testing.T) {
+	p := Push{
+		Next: httpserver.EmptyNext,
+		Rules: []Rule{
+			{Path: "/", Resources: []Resource{{Path: "/style.css", Method: "GET", Header: http.Header{}}}},
+		},
+	}
+	req, err := http.NewRequest("GET", "/", nil)
+	if err != nil {
+		t.Fatalf("Could not create HTTP request: %v", err)
+	}
+	rec := newPushRecorder()
+
+	p.ServeHTTP(httpserver.NewResponseRecorder(rec), req)
+
+	if len(rec.pushes) != 1 || rec.pushes[0].target != "/style.css" {
+		t.Errorf("Expected /style.css to be pushed through the ResponseRecorder, got %+v", rec.pushes)
+	}
+}
+
+func TestPushUnsupported(t *testing.T) {
+	next := httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+		w.WriteHeader(http.StatusTeapot)
+		return 0, nil
+	})
+	p := Push{
+		Next: next,
+		Rules: []Rule{
+			{Path: "/", Resources: []Resource{{Path: "/style.css", Method: "GET", Header: http.Header{}}}},
+		},
+	}
+	req, err := http.NewRequest("GET", "/", nil)
+	if err != nil {
+		t.Fatalf("Could not create HTTP request: %v", err)
+	}
+
+	// HTTP/1.x: the writer is not a pusher at all
+	rec := httptest.NewRecorder()
+	status, err := p.ServeHTTP(rec, req)
+	if status != 0 || err != nil || rec.Code != http.StatusTeapot {
+		t.Errorf("Expected response to be served normally without push support, got status %d, error %v, code %d",
+			status, err, rec.Code)
+	}
+
+	// client disabled push, or the push itself failed
+	prec := newPushRecorder()
+	prec.err = http.ErrNotSupported
+	status, err = p.ServeHTTP(prec, req)
+	if status != 0 || err != nil || prec.Code != http.StatusTeapot {
+		t.Errorf("Expected response to be served normally when push fails, got status %d, error %v, code %d",
+			status, err, prec.Code)
+	}
+}
@@ -0,0 +1,21 @@
+// +build go1.8
+
+package push
+
+import "net/http"
+
+// push promises the resource at target to the client. It returns
+// errPushUnsupported if w cannot push, either because it does not
+// implement http.Pusher (HTTP/1.x) or because the client disabled
+// push for the connection.
+func push(w http.ResponseWriter, target, method string, header http.Header) error {
+	pusher, ok := w.(http.Pusher)
+	if !ok {
+		return errPushUnsupported
+	}
+	err := pusher.Push(target, &http.PushOptions{Method: method, Header: header})
+	if err == http.ErrNotSupported {
+		return errPushUnsupported
+	}
+	return err
+}
@@ -0,0 +1,11 @@
+// +build !go1.8
+
+package push
+
+import "net/http"
+
+// push always returns errPushUnsupported since server
+// push requires http.Pusher, which was added in Go 1.8.
+func push(w http.ResponseWriter, target, method string, header http.Header) error {
+	return errPushUnsupported
+}
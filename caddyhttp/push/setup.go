@@ -0,0 +1,118 @@
+package push
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func init() {
+	caddy.RegisterPlugin("push", caddy.Plugin{
+		ServerType: "http",
+		Action:     setup,
+	})
+}
+
+// setup configures a new Push middleware instance.
+func setup(c *caddy.Controller) error {
+	rules, err := pushParse(c)
+	if err != nil {
+		return err
+	}
+
+	httpserver.GetConfig(c).AddMiddleware(func(next httpserver.Handler) httpserver.Handler {
+		return Push{Next: next, Rules: rules}
+	})
+
+	return nil
+}
+
+func pushParse(c *caddy.Controller) ([]Rule, error) {
+	var rules []Rule
+
+	for c.Next() {
+		args := c.RemainingArgs()
+		if len(args) == 0 {
+			return rules, c.ArgErr()
+		}
+		path := args[0]
+
+		var resources []Resource
+		for _, target := range args[1:] {
+			res, err := newResource(c, target)
+			if err != nil {
+				return rules, err
+			}
+			resources = append(resources, res)
+		}
+
+		// the method and headers in a block apply
+		// to all the resources listed in that block
+		var blockResources []Resource
+		method := http.MethodGet
+		header := make(http.Header)
+		for c.NextBlock() {
+			switch c.Val() {
+			case "method":
+				if !c.NextArg() {
+					return rules, c.ArgErr()
+				}
+				method = strings.ToUpper(c.Val())
+				if method != http.MethodGet && method != http.MethodHead {
+					return rules, c.Errf("push method must be GET or HEAD, got '%s'", c.Val())
+				}
+			case "header":
+				hdrArgs := c.RemainingArgs()
+				if len(hdrArgs) != 2 {
+					return rules, c.ArgErr()
+				}
+				header.Add(hdrArgs[0], hdrArgs[1])
+			default:
+				res, err := newResource(c, c.Val())
+				if err != nil {
+					return rules, err
+				}
+				if c.NextArg() {
+					return rules, c.ArgErr()
+				}
+				blockResources = append(blockResources, res)
+			}
+		}
+		for _, res := range blockResources {
+			res.Method = method
+			res.Header = header
+			resources = append(resources, res)
+		}
+
+		if len(resources) == 0 {
+			return rules, c.Errf("no resources to push for path '%s'", path)
+		}
+
+		// resources for a path that was already
+		// configured are pushed along with those
+		var merged bool
+		for i := range rules {
+			if rules[i].Path == path {
+				rules[i].Resources = append(rules[i].Resources, resources...)
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			rules = append(rules, Rule{Path: path, Resources: resources})
+		}
+	}
+
+	return rules, nil
+}
+
+// newResource makes a Resource for target that is pushed with a
+// GET request, or returns an error if target is not a path.
+func newResource(c *caddy.Controller, target string) (Resource, error) {
+	if !strings.HasPrefix(target, "/") {
+		return Resource{}, c.Errf("push resource must be a path starting with '/', got '%s'", target)
+	}
+	return Resource{Path: target, Method: http.MethodGet, Header: http.Header{}}, nil
+}
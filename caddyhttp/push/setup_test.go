@@ -0,0 +1,105 @@
+package push
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func TestSetup(t *testing.T) {
+	c := caddy.NewTestController("http", `push /index.html /style.css`)
+	err := setup(c)
+	if err != nil {
+		t.Fatalf("Expected no errors, but got: %v", err)
+	}
+
+	mids := httpserver.GetConfig(c).Middleware()
+	if len(mids) == 0 {
+		t.Fatal("Expected middleware, had 0 instead")
+	}
+
+	handler := mids[0](httpserver.EmptyNext)
+	myHandler, ok := handler.(Push)
+	if !ok {
+		t.Fatalf("Expected handler to be type Push, got: %#v", handler)
+	}
+
+	if !httpserver.SameNext(myHandler.Next, httpserver.EmptyNext) {
+		t.Error("'Next' field of handler was not set properly")
+	}
+}
+
+func TestPushParse(t *testing.T) {
+	get := func(path string) Resource {
+		return Resource{Path: path, Method: "GET", Header: http.Header{}}
+	}
+
+	tests := []struct {
+		input     string
+		shouldErr bool
+		expected  []Rule
+	}{
+		{`push /index.html /style.css /app.js`, false, []Rule{
+			{Path: "/index.html", Resources: []Resource{get("/style.css"), get("/app.js")}},
+		}},
+		{`push /index.html {
+			/style.css
+			method HEAD
+			header Accept-Encoding gzip
+			header X-Foo bar
+		}`, false, []Rule{
+			{Path: "/index.html", Resources: []Resource{
+				{Path: "/style.css", Method: "HEAD", Header: http.Header{
+					"Accept-Encoding": []string{"gzip"},
+					"X-Foo":           []string{"bar"},
+				}},
+			}},
+		}},
+		{`push /index.html /app.js {
+			/style.css
+		}`, false, []Rule{
+			{Path: "/index.html", Resources: []Resource{get("/app.js"), get("/style.css")}},
+		}},
+		{"push /index.html /style.css\npush /index.html /app.js\npush /about /about.css", false, []Rule{
+			{Path: "/index.html", Resources: []Resource{get("/style.css"), get("/app.js")}},
+			{Path: "/about", Resources: []Resource{get("/about.css")}},
+		}},
+		{`push`, true, nil},
+		{`push /index.html`, true, nil},
+		{`push /index.html style.css`, true, nil},
+		{`push /index.html {
+			method POST
+			/style.css
+		}`, true, nil},
+		{`push /index.html {
+			method
+		}`, true, nil},
+		{`push /index.html {
+			header X-Foo
+			/style.css
+		}`, true, nil},
+		{`push /index.html {
+			/style.css /app.js
+		}`, true, nil},
+	}
+
+	for i, test := range tests {
+		actual, err := pushParse(caddy.NewTestController("http", test.input))
+
+		if err == nil && test.shouldErr {
+			t.Errorf("Test %d didn't error, but it should have", i)
+		} else if err != nil && !test.shouldErr {
+			t.Errorf("Test %d errored, but it shouldn't have; got '%v'", i, err)
+		}
+		if test.shouldErr {
+			continue
+		}
+
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("Test %d: Expected rules %+v, but got %+v", i, test.expected, actual)
+		}
+	}
+}
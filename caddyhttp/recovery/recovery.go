@@ -0,0 +1,91 @@
+// Package recovery is middleware that recovers from panics of the
+// handlers after it, so that clients get a clean response.
+package recovery
+
+import (
+	"io/ioutil"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"strings"
+
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+// Recover is middleware that recovers from panics of the handlers
+// after it. It logs the panic with the request, and the stack trace
+// if Stack is set, then responds with a 500 and the page in the file
+// Page, or a plain text description if Page is empty. A panic after
+// the response was started can't be responded to anymore, so it is
+// only logged; with Repanic, it is passed on to net/http, which aborts
+// the response so that clients can tell it is incomplete. The errors
+// middleware of a site with Recover leaves panics to it.
+type Recover struct {
+	Next    httpserver.Handler
+	Page    string
+	Stack   bool
+	Repanic bool // always pass panics on instead of responding
+}
+
+// ServeHTTP implements the httpserver.Handler interface.
+func (rc Recover) ServeHTTP(w http.ResponseWriter, r *http.Request) (status int, err error) {
+	// records whether the response was started, when it
+	// can't be replaced by an error anymore
+	rw := httpserver.NewHeaderWriter(w, nil)
+	defer func() {
+		rec := recover()
+		if rec == nil {
+			return
+		}
+		if _, ok := rec.(httpserver.Repanic); ok {
+			panic(rec)
+		}
+
+		msg := "[PANIC] " + r.Method + " " + r.URL.RequestURI() + " from " + r.RemoteAddr
+		if rw.WroteHeader() {
+			msg += ", after the response was started"
+		}
+		if rc.Stack {
+			log.Printf("%s: %v (error ID %s)\n%s", msg, rec, httpserver.MakeErrorID(r), debug.Stack())
+		} else {
+			log.Printf("%s: %v (error ID %s)", msg, rec, httpserver.MakeErrorID(r))
+		}
+
+		switch {
+		case rc.Repanic:
+			panic(httpserver.Repanic{Value: rec})
+		case rw.WroteHeader():
+			// the connection was either hijacked, or the response
+			// has started and will end where the handler left it
+			status, err = 0, nil
+		default:
+			status, err = rc.errorPage(w, r), nil
+		}
+	}()
+
+	return rc.Next.ServeHTTP(rw, r)
+}
+
+// errorPage writes the 500 response to w and returns 0 to signal that
+// it did. If the page can't be read, the plain text one is written.
+func (rc Recover) errorPage(w http.ResponseWriter, r *http.Request) int {
+	// set by the handler before it panicked, these
+	// would not be true of the error page
+	w.Header().Del("Content-Length")
+	w.Header().Del("Content-Encoding")
+	if rc.Page == "" {
+		httpserver.DefaultErrorFunc(w, r, http.StatusInternalServerError)
+		return 0
+	}
+	page, err := ioutil.ReadFile(rc.Page)
+	if err != nil {
+		log.Printf("[ERROR] Could not read error page %s: %v", rc.Page, err)
+		httpserver.DefaultErrorFunc(w, r, http.StatusInternalServerError)
+		return 0
+	}
+	body := strings.Replace(string(page), "{"+httpserver.ErrorIDPlaceholder+"}", httpserver.MakeErrorID(r), -1)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusInternalServerError)
+	w.Write([]byte(body))
+	return 0
+}
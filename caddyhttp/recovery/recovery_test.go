@@ -0,0 +1,138 @@
+package recovery
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func TestRecover(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	dir, err := ioutil.TempDir("", "caddy_recover")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	page := filepath.Join(dir, "500.html")
+	if err := ioutil.WriteFile(page, []byte("<p>Error {error_id}</p>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	panicking := httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+		w.Header().Set("Content-Length", "100")
+		panic("secret detail")
+	})
+	partial := httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+		w.Write([]byte("partial"))
+		panic("secret detail")
+	})
+
+	for i, test := range []struct {
+		rc             Recover
+		expectedStatus int
+		expectedBody   string // with <id> in place of the error ID
+		expectedLog    []string
+		unexpectedLog  string
+	}{
+		// a panic before any write
+		{Recover{Next: panicking, Stack: true}, http.StatusInternalServerError,
+			"500 Internal Server Error\nError ID: <id>\n",
+			[]string{"[PANIC] GET /foo?a=b from 1.2.3.4:5678: secret detail (error ID <id>)\n", "goroutine "},
+			"after the response"},
+		{Recover{Next: panicking}, http.StatusInternalServerError,
+			"500 Internal Server Error\nError ID: <id>\n",
+			[]string{"secret detail (error ID <id>)\n"},
+			"goroutine "},
+		{Recover{Next: panicking, Page: page}, http.StatusInternalServerError,
+			"<p>Error <id></p>",
+			[]string{"secret detail (error ID <id>)"},
+			""},
+		{Recover{Next: panicking, Page: filepath.Join(dir, "missing.html")}, http.StatusInternalServerError,
+			"500 Internal Server Error\nError ID: <id>\n",
+			[]string{"secret detail (error ID <id>)", "[ERROR] Could not read error page"},
+			""},
+		// a panic after a partial write is only logged
+		{Recover{Next: partial, Page: page}, http.StatusOK,
+			"partial",
+			[]string{"from 1.2.3.4:5678, after the response was started: secret detail (error ID <id>)"},
+			""},
+	} {
+		buf.Reset()
+		r, err := http.NewRequest("GET", "/foo?a=b", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.RemoteAddr = "1.2.3.4:5678"
+		placeholders := make(map[string]string)
+		r = r.WithContext(context.WithValue(r.Context(), httpserver.PlaceholdersCtxKey, placeholders))
+		w := httptest.NewRecorder()
+
+		status, err := test.rc.ServeHTTP(w, r)
+		if status != 0 || err != nil {
+			t.Errorf("Test %d: Expected 0 and no error for a written response, got %d and %v", i, status, err)
+		}
+		id := placeholders[httpserver.ErrorIDPlaceholder]
+		if id == "" {
+			t.Fatalf("Test %d: Expected {error_id} to be set", i)
+		}
+		if w.Code != test.expectedStatus {
+			t.Errorf("Test %d: Expected status %d, got %d", i, test.expectedStatus, w.Code)
+		}
+		if expected := strings.Replace(test.expectedBody, "<id>", id, -1); w.Body.String() != expected {
+			t.Errorf("Test %d: Expected body %q, got %q", i, expected, w.Body.String())
+		}
+		if got := w.Header().Get("Content-Length"); test.expectedStatus != http.StatusOK && got != "" {
+			t.Errorf("Test %d: Expected no Content-Length of the handler, got %s", i, got)
+		}
+		for _, expected := range test.expectedLog {
+			if expected = strings.Replace(expected, "<id>", id, -1); !strings.Contains(buf.String(), expected) {
+				t.Errorf("Test %d: Expected log to contain %q, got %q", i, expected, buf.String())
+			}
+		}
+		if test.unexpectedLog != "" && strings.Contains(buf.String(), test.unexpectedLog) {
+			t.Errorf("Test %d: Expected log not to contain %q, got %q", i, test.unexpectedLog, buf.String())
+		}
+	}
+}
+
+func TestRecoverRepanic(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	rc := Recover{
+		Next: httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+			w.Write([]byte("partial"))
+			panic("secret detail")
+		}),
+		Repanic: true,
+	}
+	r, err := http.NewRequest("GET", "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		rec := recover()
+		if p, ok := rec.(httpserver.Repanic); !ok || p.Value != "secret detail" {
+			t.Errorf("Expected the panic to be passed on, got %#v", rec)
+		}
+		if !strings.Contains(buf.String(), "secret detail") {
+			t.Errorf("Expected the panic to be logged, got %q", buf.String())
+		}
+	}()
+	rc.ServeHTTP(httptest.NewRecorder(), r)
+	t.Error("Expected a panic")
+}
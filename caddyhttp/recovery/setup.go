@@ -0,0 +1,90 @@
+package recovery
+
+import (
+	"path/filepath"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func init() {
+	caddy.RegisterPlugin("recover", caddy.Plugin{
+		ServerType: "http",
+		Action:     setup,
+	})
+}
+
+// setup configures a new Recover middleware instance.
+func setup(c *caddy.Controller) error {
+	rc, err := recoverParse(c)
+	if err != nil {
+		return err
+	}
+
+	httpserver.GetConfig(c).AddMiddleware(func(next httpserver.Handler) httpserver.Handler {
+		rc.Next = next
+		return rc
+	})
+
+	return nil
+}
+
+// recoverParse parses the directive:
+//
+//	recover {
+//		page  <file>
+//		stack on|off
+//		repanic
+//	}
+//
+// The page is relative to the site root, and the stack
+// trace is logged unless it is turned off.
+func recoverParse(c *caddy.Controller) (Recover, error) {
+	rc := Recover{Stack: true}
+	var parsed bool
+
+	for c.Next() {
+		if parsed {
+			return rc, c.Err("recover can only be specified once per site")
+		}
+		parsed = true
+
+		if len(c.RemainingArgs()) > 0 {
+			return rc, c.ArgErr()
+		}
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "page":
+				if !c.NextArg() {
+					return rc, c.ArgErr()
+				}
+				rc.Page = c.Val()
+				if !filepath.IsAbs(rc.Page) {
+					rc.Page = filepath.Join(httpserver.GetConfig(c).Root, rc.Page)
+				}
+			case "stack":
+				if !c.NextArg() {
+					return rc, c.ArgErr()
+				}
+				switch c.Val() {
+				case "on":
+					rc.Stack = true
+				case "off":
+					rc.Stack = false
+				default:
+					return rc, c.Errf("stack must be on or off, got '%s'", c.Val())
+				}
+			case "repanic":
+				rc.Repanic = true
+			default:
+				return rc, c.Errf("unknown subdirective '%s'", c.Val())
+			}
+			if c.NextArg() {
+				return rc, c.ArgErr()
+			}
+		}
+	}
+
+	return rc, nil
+}
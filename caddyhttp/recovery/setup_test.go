@@ -0,0 +1,67 @@
+package recovery
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func TestSetup(t *testing.T) {
+	c := caddy.NewTestController("http", `recover`)
+	err := setup(c)
+	if err != nil {
+		t.Errorf("Expected no errors, got: %v", err)
+	}
+	mids := httpserver.GetConfig(c).Middleware()
+	if len(mids) == 0 {
+		t.Fatal("Expected middleware, got 0 instead")
+	}
+
+	handler := mids[0](httpserver.EmptyNext)
+	myHandler, ok := handler.(Recover)
+	if !ok {
+		t.Fatalf("Expected handler to be type Recover, got: %#v", handler)
+	}
+	if !httpserver.SameNext(myHandler.Next, httpserver.EmptyNext) {
+		t.Error("'Next' field of handler was not set properly")
+	}
+}
+
+func TestRecoverParse(t *testing.T) {
+	for i, test := range []struct {
+		input     string
+		shouldErr bool
+		expected  Recover
+	}{
+		{`recover`, false, Recover{Stack: true}},
+		{"recover {\n\tpage 500.html\n}", false, Recover{Page: filepath.Join("root", "500.html"), Stack: true}},
+		{"recover {\n\tpage /srv/500.html\n\tstack off\n\trepanic\n}", false,
+			Recover{Page: "/srv/500.html", Repanic: true}},
+		{"recover {\n\tstack on\n}", false, Recover{Stack: true}},
+		{`recover 500.html`, true, Recover{}},
+		{"recover {\n\tpage\n}", true, Recover{}},
+		{"recover {\n\tstack maybe\n}", true, Recover{}},
+		{"recover {\n\trepanic always\n}", true, Recover{}},
+		{"recover {\n\tstatus 503\n}", true, Recover{}},
+		{"recover\nrecover", true, Recover{}},
+	} {
+		c := caddy.NewTestController("http", test.input)
+		httpserver.GetConfig(c).Root = "root"
+		rc, err := recoverParse(c)
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: Expected an error, but did not have one", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: Did not expect error, but got: %v", i, err)
+			continue
+		}
+		if rc != test.expected {
+			t.Errorf("Test %d: Expected %+v, got %+v", i, test.expected, rc)
+		}
+	}
+}
@@ -20,6 +20,9 @@ type Redirect struct {
 func (rd Redirect) ServeHTTP(w http.ResponseWriter, r *http.Request) (int, error) {
 	for _, rule := range rd.Rules {
 		if (rule.FromPath == "/" || r.URL.Path == rule.FromPath) && schemeMatches(rule, r) && rule.Match(r) {
+			if rule.Name != "" {
+				httpserver.SetPlaceholder(r, httpserver.MatchedRulePlaceholder, rule.Name)
+			}
 			to := httpserver.NewReplacer(r, nil, "").Replace(rule.To)
 			if rule.Meta {
 				safeTo := html.EscapeString(to)
@@ -44,6 +47,10 @@ type Rule struct {
 	Code                     int
 	Meta                     bool
 	httpserver.RequestMatcher
+
+	// Name of the rule, if any, for the {matched_rule}
+	// placeholder once it redirected a request
+	Name string
 }
 
 // Script tag comes first since that will better imitate a redirect in the browser's
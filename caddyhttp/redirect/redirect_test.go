@@ -2,6 +2,7 @@ package redirect
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"io/ioutil"
 	"net/http"
@@ -9,6 +10,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/mholt/caddy"
 	"github.com/mholt/caddy/caddyhttp/httpserver"
 )
 
@@ -87,6 +89,102 @@ func TestRedirect(t *testing.T) {
 	}
 }
 
+func TestConditionalRedirect(t *testing.T) {
+	c := caddy.NewTestController("http", `redir {
+		if {>X-Forwarded-Proto} is http
+		/ https://{host}{uri} 308
+	}
+	redir {
+		if {>User-Agent} has MSIE
+		/ /legacy{uri} 302
+	}
+	redir {
+		if {>User-Agent} has Trident
+		/ /trident 302
+	}`)
+	rules, err := redirParse(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	re := Redirect{
+		Next: httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+			return http.StatusTeapot, nil
+		}),
+		Rules: rules,
+	}
+
+	for i, test := range []struct {
+		header           http.Header
+		expectedLocation string
+		expectedStatus   int
+	}{
+		{http.Header{"X-Forwarded-Proto": {"http"}}, "https://localhost/a?b=c", http.StatusPermanentRedirect},
+		{http.Header{"X-Forwarded-Proto": {"https"}}, "", http.StatusTeapot},
+		{http.Header{"User-Agent": {"Mozilla/4.0 (compatible; MSIE 6.0; Trident/4.0)"}}, "/legacy/a?b=c", http.StatusFound},
+		{http.Header{"User-Agent": {"Mozilla/5.0 (Trident/7.0)"}}, "/trident", http.StatusFound},
+		{http.Header{"User-Agent": {"Mozilla/5.0"}}, "", http.StatusTeapot},
+		{nil, "", http.StatusTeapot},
+	} {
+		req, err := http.NewRequest("GET", "http://localhost/a?b=c", nil)
+		if err != nil {
+			t.Fatalf("Test %d: Could not create HTTP request: %v", i, err)
+		}
+		for name, values := range test.header {
+			req.Header[name] = values
+		}
+		rec := httptest.NewRecorder()
+
+		status, _ := re.ServeHTTP(rec, req)
+		if test.expectedLocation == "" {
+			if status != test.expectedStatus {
+				t.Errorf("Test %d: Expected the request to pass through with %d, got %d", i, test.expectedStatus, status)
+			}
+			continue
+		}
+		if rec.Code != test.expectedStatus {
+			t.Errorf("Test %d: Expected status code to be %d but was %d", i, test.expectedStatus, rec.Code)
+		}
+		if got := rec.Header().Get("Location"); got != test.expectedLocation {
+			t.Errorf("Test %d: Expected Location header to be %q but was %q", i, test.expectedLocation, got)
+		}
+	}
+}
+
+func TestMethodPreservingRedirect(t *testing.T) {
+	re := Redirect{
+		Rules: []Rule{
+			{FromPath: "/api", To: "/v2/api", Code: http.StatusPermanentRedirect, RequestMatcher: httpserver.IfMatcher{}},
+			{FromPath: "/upload", To: "/v2/upload", Code: http.StatusTemporaryRedirect, RequestMatcher: httpserver.IfMatcher{}},
+		},
+	}
+
+	for i, test := range []struct {
+		path, location string
+		code           int
+	}{
+		{"/api", "/v2/api", http.StatusPermanentRedirect},
+		{"/upload", "/v2/upload", http.StatusTemporaryRedirect},
+	} {
+		for _, method := range []string{"POST", "PUT", "DELETE", "GET"} {
+			req, err := http.NewRequest(method, test.path, strings.NewReader(`{"a":1}`))
+			if err != nil {
+				t.Fatalf("Test %d: Could not create HTTP request: %v", i, err)
+			}
+			rec := httptest.NewRecorder()
+			re.ServeHTTP(rec, req)
+
+			// clients repeat the request with the same method
+			// and body only for these codes, so they must be kept
+			if rec.Code != test.code {
+				t.Errorf("Test %d (%s): Expected status code %d but was %d", i, method, test.code, rec.Code)
+			}
+			if got := rec.Header().Get("Location"); got != test.location {
+				t.Errorf("Test %d (%s): Expected Location %q but was %q", i, method, test.location, got)
+			}
+		}
+	}
+}
+
 func TestParametersRedirect(t *testing.T) {
 	re := Redirect{
 		Rules: []Rule{
@@ -152,3 +250,36 @@ func TestMetaRedirect(t *testing.T) {
 		}
 	}
 }
+
+func TestRedirectMatchedRule(t *testing.T) {
+	re := Redirect{
+		Next: httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+			return 0, nil
+		}),
+		Rules: []Rule{
+			{FromPath: "/old", To: "/new", Code: http.StatusMovedPermanently, RequestMatcher: httpserver.IfMatcher{}, Name: "moved"},
+			{FromPath: "/tmp", To: "/new", Code: http.StatusFound, RequestMatcher: httpserver.IfMatcher{}},
+		},
+	}
+
+	for i, test := range []struct {
+		path     string
+		expected string
+	}{
+		{"/old", "moved"},
+		{"/tmp", ""}, // not named
+		{"/new", ""}, // not redirected
+	} {
+		req, err := http.NewRequest("GET", "http://localhost"+test.path, nil)
+		if err != nil {
+			t.Fatalf("Test %d: Could not create HTTP request: %v", i, err)
+		}
+		placeholders := make(map[string]string)
+		req = req.WithContext(context.WithValue(req.Context(), httpserver.PlaceholdersCtxKey, placeholders))
+
+		re.ServeHTTP(httptest.NewRecorder(), req)
+		if got := placeholders[httpserver.MatchedRulePlaceholder]; got != test.expected {
+			t.Errorf("Test %d: Expected matched rule %q, got %q", i, test.expected, got)
+		}
+	}
+}
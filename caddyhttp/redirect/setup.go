@@ -2,6 +2,8 @@ package redirect
 
 import (
 	"net/http"
+	"sort"
+	"strings"
 
 	"github.com/mholt/caddy"
 	"github.com/mholt/caddy/caddyhttp/httpserver"
@@ -73,24 +75,33 @@ func redirParse(c *caddy.Controller) ([]Rule, error) {
 		if codeNumber, ok := httpRedirs[code]; ok {
 			rule.Code = codeNumber
 		} else {
-			return c.Errf("Invalid redirect code '%v'", code)
+			return c.Errf("Invalid redirect code '%v'; must be one of %s or meta", code, supportedRedirs())
 		}
 
 		return nil
 	}
 
+	// unconditional holds the 'from' values of rules without if conditions
+	unconditional := make(map[string]bool)
+
 	// checkAndSaveRule checks the rule for validity (except the redir code)
-	// and saves it if it's valid, or returns an error.
-	checkAndSaveRule := func(rule Rule) error {
+	// and saves it if it's valid, or returns an error. Conditional rules may
+	// share a 'from' value; the first one whose conditions pass is used.
+	checkAndSaveRule := func(rule Rule, conditional bool) error {
 		if rule.FromPath == rule.To {
 			return c.Err("'from' and 'to' values of redirect rule cannot be the same")
 		}
 
-		for _, otherRule := range redirects {
-			if otherRule.FromPath == rule.FromPath {
-				return c.Errf("rule with duplicate 'from' value: %s -> %s", otherRule.FromPath, otherRule.To)
+		if unconditional[rule.FromPath] {
+			for _, otherRule := range redirects {
+				if otherRule.FromPath == rule.FromPath {
+					return c.Errf("rule with duplicate 'from' value: %s -> %s", otherRule.FromPath, otherRule.To)
+				}
 			}
 		}
+		if !conditional {
+			unconditional[rule.FromPath] = true
+		}
 
 		redirects = append(redirects, rule)
 		return nil
@@ -104,12 +115,23 @@ func redirParse(c *caddy.Controller) ([]Rule, error) {
 		if err != nil {
 			return nil, err
 		}
+		conditional := httpserver.HasIfMatcher(c)
 
 		var hadOptionalBlock bool
+		var name string
+		first := len(redirects)
 		for c.NextBlock() {
 			if httpserver.IfMatcherKeyword(c) {
 				continue
 			}
+			if c.Val() == "name" {
+				nameArgs := c.RemainingArgs()
+				if len(nameArgs) != 1 {
+					return redirects, c.ArgErr()
+				}
+				name = nameArgs[0]
+				continue
+			}
 
 			hadOptionalBlock = true
 
@@ -131,7 +153,7 @@ func redirParse(c *caddy.Controller) ([]Rule, error) {
 				return redirects, err
 			}
 
-			err = checkAndSaveRule(rule)
+			err = checkAndSaveRule(rule, conditional)
 			if err != nil {
 				return redirects, err
 			}
@@ -146,11 +168,16 @@ func redirParse(c *caddy.Controller) ([]Rule, error) {
 				return redirects, err
 			}
 
-			err = checkAndSaveRule(rule)
+			err = checkAndSaveRule(rule, conditional)
 			if err != nil {
 				return redirects, err
 			}
 		}
+
+		// the name is for all the rules of the block
+		for i := first; i < len(redirects); i++ {
+			redirects[i].Name = name
+		}
 	}
 
 	return redirects, nil
@@ -167,3 +194,14 @@ var httpRedirs = map[string]int{
 	"307": http.StatusTemporaryRedirect,
 	"308": http.StatusPermanentRedirect, // Permanent Redirect (RFC 7238)
 }
+
+// supportedRedirs returns the supported HTTP redirect
+// codes as a sorted, comma-separated list.
+func supportedRedirs() string {
+	codes := make([]string, 0, len(httpRedirs))
+	for code := range httpRedirs {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return strings.Join(codes, ", ")
+}
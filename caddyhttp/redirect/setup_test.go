@@ -2,12 +2,46 @@ package redirect
 
 import (
 	"fmt"
+	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/mholt/caddy"
 	"github.com/mholt/caddy/caddyhttp/httpserver"
 )
 
+func TestSetupRedirectCodes(t *testing.T) {
+	for i, test := range []struct {
+		code      string
+		shouldErr bool
+		expected  int
+	}{
+		{"307", false, http.StatusTemporaryRedirect},
+		{"308", false, http.StatusPermanentRedirect},
+		{"301", false, http.StatusMovedPermanently},
+		{"200", true, 0},
+		{"309", true, 0},
+		{"3O8", true, 0},
+	} {
+		rules, err := redirParse(caddy.NewTestController("http", "redir /api /v2/api "+test.code))
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: Expected an error for code %s", i, test.code)
+			} else if !strings.Contains(err.Error(), "308") {
+				t.Errorf("Test %d: Expected the error to list the supported codes, got: %v", i, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: Expected no error, got: %v", i, err)
+			continue
+		}
+		if len(rules) != 1 || rules[0].Code != test.expected {
+			t.Errorf("Test %d: Expected one rule with code %d, got %+v", i, test.expected, rules)
+		}
+	}
+}
+
 func TestSetup(t *testing.T) {
 
 	for j, test := range []struct {
@@ -65,6 +99,32 @@ func TestSetup(t *testing.T) {
 				matcher, _ := httpserver.SetupIfMatcher(c)
 				return matcher.(httpserver.IfMatcher)
 			}()}}},
+
+		// test case #13 tests that conditional rules may share a from path
+		{"redir {\n if {>User-Agent} has MSIE\n/ /legacy\n}\nredir {\n/ /modern\n}", false,
+			[]Rule{{FromPath: "/", To: "/legacy", Code: 301,
+				RequestMatcher: func() httpserver.IfMatcher {
+					c := caddy.NewTestController("http", "{\n if {>User-Agent} has MSIE\n}")
+					matcher, _ := httpserver.SetupIfMatcher(c)
+					return matcher.(httpserver.IfMatcher)
+				}()},
+				{FromPath: "/", To: "/modern", Code: 301, RequestMatcher: httpserver.IfMatcher{}}}},
+
+		// test case #14 tests that an unconditional rule still can't be duplicated after a conditional one
+		{"redir {\n if {>User-Agent} has MSIE\n/ /legacy\n}\nredir / /modern\nredir / /other", true, []Rule{{}}},
+
+		// test case #15 tests that the name of a block is the name of all of its rules
+		{"redir {\n /a /b\n name legacy\n /c /d\n}\nredir {\n /e /f\n}", false,
+			[]Rule{{FromPath: "/a", To: "/b", Code: 301, RequestMatcher: httpserver.IfMatcher{}, Name: "legacy"},
+				{FromPath: "/c", To: "/d", Code: 301, RequestMatcher: httpserver.IfMatcher{}, Name: "legacy"},
+				{FromPath: "/e", To: "/f", Code: 301, RequestMatcher: httpserver.IfMatcher{}}}},
+
+		// test case #16 tests the name of a rule that is not in a block
+		{"redir /old /new 308 {\n name moved\n}", false,
+			[]Rule{{FromPath: "/old", To: "/new", Code: 308, RequestMatcher: httpserver.IfMatcher{}, Name: "moved"}}},
+
+		// test case #17 tests the detection of a missing name
+		{"redir {\n name\n / /foo\n}", true, []Rule{{}}},
 	} {
 		c := caddy.NewTestController("http", test.input)
 		err := setup(c)
@@ -75,6 +135,9 @@ func TestSetup(t *testing.T) {
 		}
 		mids := httpserver.GetConfig(c).Middleware()
 		receivedRules := mids[len(mids)-1](nil).(Redirect).Rules
+		if len(receivedRules) != len(test.expectedRules) {
+			t.Fatalf("Test case #%d expected %d rules, but received %d", j, len(test.expectedRules), len(receivedRules))
+		}
 
 		for i, receivedRule := range receivedRules {
 			if receivedRule.FromPath != test.expectedRules[i].FromPath {
@@ -83,6 +146,9 @@ func TestSetup(t *testing.T) {
 			if receivedRule.To != test.expectedRules[i].To {
 				t.Errorf("Test case #%d.%d expected a TO path of %s, but received a TO path of %s", j, i, test.expectedRules[i].To, receivedRule.To)
 			}
+			if receivedRule.Name != test.expectedRules[i].Name {
+				t.Errorf("Test case #%d.%d expected a name of %q, but received a name of %q", j, i, test.expectedRules[i].Name, receivedRule.Name)
+			}
 			if receivedRule.Code != test.expectedRules[i].Code {
 				t.Errorf("Test case #%d.%d expected a HTTP status code of %d, but received a code of %d", j, i, test.expectedRules[i].Code, receivedRule.Code)
 			}
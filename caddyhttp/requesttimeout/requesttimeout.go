@@ -0,0 +1,41 @@
+// Package requesttimeout configures how long requests of a site
+// may take before their context is cancelled.
+package requesttimeout
+
+import (
+	"time"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func init() {
+	caddy.RegisterPlugin("request_timeout", caddy.Plugin{
+		ServerType: "http",
+		Action:     setupRequestTimeout,
+	})
+}
+
+// setupRequestTimeout parses the directive:
+//
+//	request_timeout <timeout>
+func setupRequestTimeout(c *caddy.Controller) error {
+	config := httpserver.GetConfig(c)
+	for c.Next() {
+		if config.RequestTimeout != 0 {
+			return c.Err("request_timeout can only be specified once per site")
+		}
+		if !c.NextArg() {
+			return c.ArgErr()
+		}
+		timeout, err := time.ParseDuration(c.Val())
+		if err != nil || timeout <= 0 {
+			return c.Errf("timeout must be a positive duration, got '%s'", c.Val())
+		}
+		if c.NextArg() {
+			return c.ArgErr()
+		}
+		config.RequestTimeout = timeout
+	}
+	return nil
+}
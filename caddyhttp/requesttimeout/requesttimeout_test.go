@@ -0,0 +1,38 @@
+package requesttimeout
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func TestSetupRequestTimeout(t *testing.T) {
+	for i, test := range []struct {
+		input     string
+		shouldErr bool
+		expected  time.Duration
+	}{
+		{"request_timeout 30s", false, 30 * time.Second},
+		{"request_timeout 1m30s", false, 90 * time.Second},
+		{"request_timeout", true, 0},
+		{"request_timeout 0s", true, 0},
+		{"request_timeout -1s", true, 0},
+		{"request_timeout 5", true, 0},
+		{"request_timeout 1s 2s", true, 0},
+		{"request_timeout 1s\nrequest_timeout 2s", true, 0},
+	} {
+		c := caddy.NewTestController("http", test.input)
+		err := setupRequestTimeout(c)
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %d: Expected an error, but did not have one", i)
+		}
+		if !test.shouldErr && err != nil {
+			t.Errorf("Test %d: Did not expect error, but got: %v", i, err)
+		}
+		if got := httpserver.GetConfig(c).RequestTimeout; !test.shouldErr && got != test.expected {
+			t.Errorf("Test %d: Expected timeout %s, got %s", i, test.expected, got)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+// Package requirecontenttype implements middleware that rejects
+// requests with bodies of media types that are not allowed.
+package requirecontenttype
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+// RequireContentType is middleware that fails requests under Paths
+// with a 415 Unsupported Media Type if their method is one of Methods
+// and their Content-Type is not one of Types. Parameters of the media
+// type, like charset, are ignored.
+type RequireContentType struct {
+	Next    httpserver.Handler
+	Paths   []string
+	Methods []string // in upper case
+
+	// Types are the media types allowed, such as application/json,
+	// or all of a type, such as application/*. */* allows any type,
+	// but still requires one if AllowMissing is false.
+	Types []string
+
+	// AllowMissing allows requests with a body but
+	// no Content-Type. Requests without body, like
+	// an empty POST, are allowed to have none.
+	AllowMissing bool
+}
+
+// ServeHTTP implements the httpserver.Handler interface.
+func (rc RequireContentType) ServeHTTP(w http.ResponseWriter, r *http.Request) (int, error) {
+	if !rc.matches(r) {
+		return rc.Next.ServeHTTP(w, r)
+	}
+
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		if rc.AllowMissing || !hasBody(r) {
+			return rc.Next.ServeHTTP(w, r)
+		}
+		return http.StatusUnsupportedMediaType, nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || !rc.allowed(mediaType) {
+		return http.StatusUnsupportedMediaType, nil
+	}
+	return rc.Next.ServeHTTP(w, r)
+}
+
+// matches reports whether the content type of r is checked.
+func (rc RequireContentType) matches(r *http.Request) bool {
+	var method bool
+	for _, m := range rc.Methods {
+		if r.Method == m {
+			method = true
+			break
+		}
+	}
+	if !method {
+		return false
+	}
+	for _, p := range rc.Paths {
+		if httpserver.Path(r.URL.Path).Matches(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowed reports whether the media type mediaType,
+// which is in lower case, is one of the types allowed.
+func (rc RequireContentType) allowed(mediaType string) bool {
+	for _, t := range rc.Types {
+		if t == "*/*" || t == mediaType {
+			return true
+		}
+		if strings.HasSuffix(t, "/*") && strings.HasPrefix(mediaType, t[:len(t)-1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasBody reports whether r has a body, or might have one
+// because it is chunked.
+func hasBody(r *http.Request) bool {
+	return r.ContentLength != 0
+}
@@ -0,0 +1,80 @@
+package requirecontenttype
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func TestRequireContentType(t *testing.T) {
+	next := httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+		return http.StatusOK, nil
+	})
+	reject := RequireContentType{
+		Next:    next,
+		Paths:   []string{"/api"},
+		Methods: defaultMethods,
+		Types:   []string{"application/json", "text/*"},
+	}
+	allow := reject
+	allow.AllowMissing = true
+
+	for i, test := range []struct {
+		rc             RequireContentType
+		method         string
+		path           string
+		contentType    string
+		body           string
+		expectedStatus int
+	}{
+		{reject, "POST", "/api", "application/json", "{}", http.StatusOK},
+		{reject, "PUT", "/api", "application/json; charset=utf-8", "{}", http.StatusOK},
+		{reject, "PATCH", "/api", "Application/JSON", "{}", http.StatusOK},
+		{reject, "POST", "/api", "text/plain", "hi", http.StatusOK},
+		{reject, "POST", "/api", "application/xml", "<a/>", http.StatusUnsupportedMediaType},
+		{reject, "POST", "/api", "application/json-patch+json", "[]", http.StatusUnsupportedMediaType},
+		{reject, "POST", "/api", "textual/plain", "hi", http.StatusUnsupportedMediaType},
+		{reject, "POST", "/api", "application/json; charset", "{}", http.StatusUnsupportedMediaType},
+		// missing content type
+		{reject, "POST", "/api", "", "{}", http.StatusUnsupportedMediaType},
+		{allow, "POST", "/api", "", "{}", http.StatusOK},
+		{reject, "POST", "/api", "", "", http.StatusOK}, // no body
+		{allow, "POST", "/api", "application/xml", "<a/>", http.StatusUnsupportedMediaType},
+		// other methods and paths are not checked
+		{reject, "GET", "/api", "application/xml", "", http.StatusOK},
+		{reject, "DELETE", "/api", "", "{}", http.StatusOK},
+		{reject, "POST", "/upload", "image/png", "png", http.StatusOK},
+	} {
+		r, err := http.NewRequest(test.method, test.path, strings.NewReader(test.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if test.contentType != "" {
+			r.Header.Set("Content-Type", test.contentType)
+		}
+
+		status, err := test.rc.ServeHTTP(nil, r)
+		if err != nil {
+			t.Fatalf("Test %d: %v", i, err)
+		}
+		if status != test.expectedStatus {
+			t.Errorf("Test %d: Expected status %d for %s %s with %q, got %d",
+				i, test.expectedStatus, test.method, test.path, test.contentType, status)
+		}
+	}
+}
+
+func TestAllowedWildcards(t *testing.T) {
+	rc := RequireContentType{Types: []string{"*/*"}}
+	for _, mediaType := range []string{"application/json", "image/png"} {
+		if !rc.allowed(mediaType) {
+			t.Errorf("Expected */* to allow %s", mediaType)
+		}
+	}
+	rc.Types = []string{"image/*"}
+	if rc.allowed("imagex/png") || rc.allowed("application/json") {
+		t.Error("Expected image/* to allow only images")
+	}
+}
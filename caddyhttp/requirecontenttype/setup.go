@@ -0,0 +1,104 @@
+package requirecontenttype
+
+import (
+	"mime"
+	"strings"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func init() {
+	caddy.RegisterPlugin("require_content_type", caddy.Plugin{
+		ServerType: "http",
+		Action:     setup,
+	})
+}
+
+// defaultMethods are the methods of requests with
+// bodies, whose content type is checked by default.
+var defaultMethods = []string{"POST", "PUT", "PATCH"}
+
+// setup configures a new RequireContentType middleware instance.
+func setup(c *caddy.Controller) error {
+	rules, err := requireContentTypeParse(c)
+	if err != nil {
+		return err
+	}
+
+	for _, rc := range rules {
+		rc := rc
+		httpserver.GetConfig(c).AddMiddleware(func(next httpserver.Handler) httpserver.Handler {
+			rc.Next = next
+			return rc
+		})
+	}
+
+	return nil
+}
+
+// requireContentTypeParse parses the directive:
+//
+//	require_content_type [paths...] {
+//		types   <media types...>
+//		methods <methods...>
+//		missing allow|reject
+//	}
+//
+// Types like application/* allow all of a type. By default, the
+// methods are POST, PUT and PATCH, and bodies without a content
+// type are rejected.
+func requireContentTypeParse(c *caddy.Controller) ([]RequireContentType, error) {
+	var rules []RequireContentType
+
+	for c.Next() {
+		rc := RequireContentType{Paths: c.RemainingArgs(), Methods: defaultMethods}
+		if len(rc.Paths) == 0 {
+			rc.Paths = []string{"/"}
+		}
+
+		for c.NextBlock() {
+			prop := c.Val()
+			args := c.RemainingArgs()
+			if len(args) == 0 {
+				return nil, c.ArgErr()
+			}
+			switch prop {
+			case "types":
+				for _, t := range args {
+					mediaType, params, err := mime.ParseMediaType(t)
+					if err != nil || len(params) > 0 || !strings.Contains(mediaType, "/") || strings.HasPrefix(mediaType, "*/") && mediaType != "*/*" {
+						return nil, c.Errf("invalid media type '%s'", t)
+					}
+					rc.Types = append(rc.Types, mediaType)
+				}
+			case "methods":
+				rc.Methods = nil
+				for _, m := range args {
+					rc.Methods = append(rc.Methods, strings.ToUpper(m))
+				}
+			case "missing":
+				if len(args) != 1 {
+					return nil, c.ArgErr()
+				}
+				switch args[0] {
+				case "allow":
+					rc.AllowMissing = true
+				case "reject":
+					rc.AllowMissing = false
+				default:
+					return nil, c.Errf("missing must be allow or reject, got '%s'", args[0])
+				}
+			default:
+				return nil, c.Errf("unknown subdirective '%s'", prop)
+			}
+		}
+		if len(rc.Types) == 0 {
+			return nil, c.Err("require_content_type needs the types that are allowed")
+		}
+
+		rules = append(rules, rc)
+	}
+
+	return rules, nil
+}
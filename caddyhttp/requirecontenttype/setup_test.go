@@ -0,0 +1,72 @@
+package requirecontenttype
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func TestSetup(t *testing.T) {
+	c := caddy.NewTestController("http", "require_content_type {\n\ttypes application/json\n}")
+	err := setup(c)
+	if err != nil {
+		t.Errorf("Expected no errors, got: %v", err)
+	}
+	mids := httpserver.GetConfig(c).Middleware()
+	if len(mids) == 0 {
+		t.Fatal("Expected middleware, got 0 instead")
+	}
+
+	handler := mids[0](httpserver.EmptyNext)
+	myHandler, ok := handler.(RequireContentType)
+	if !ok {
+		t.Fatalf("Expected handler to be type RequireContentType, got: %#v", handler)
+	}
+	if !httpserver.SameNext(myHandler.Next, httpserver.EmptyNext) {
+		t.Error("'Next' field of handler was not set properly")
+	}
+}
+
+func TestRequireContentTypeParse(t *testing.T) {
+	for i, test := range []struct {
+		input     string
+		shouldErr bool
+		expected  []RequireContentType
+	}{
+		{"require_content_type {\n\ttypes application/json\n}", false, []RequireContentType{
+			{Paths: []string{"/"}, Methods: []string{"POST", "PUT", "PATCH"}, Types: []string{"application/json"}},
+		}},
+		{"require_content_type /api /v2 {\n\ttypes Application/JSON text/* */*\n\tmethods post delete\n\tmissing allow\n}", false, []RequireContentType{
+			{Paths: []string{"/api", "/v2"}, Methods: []string{"POST", "DELETE"}, Types: []string{"application/json", "text/*", "*/*"}, AllowMissing: true},
+		}},
+		{"require_content_type /a {\n\ttypes text/plain\n\tmissing reject\n}\nrequire_content_type /b {\n\ttypes image/*\n}", false, []RequireContentType{
+			{Paths: []string{"/a"}, Methods: []string{"POST", "PUT", "PATCH"}, Types: []string{"text/plain"}},
+			{Paths: []string{"/b"}, Methods: []string{"POST", "PUT", "PATCH"}, Types: []string{"image/*"}},
+		}},
+		{`require_content_type`, true, nil},
+		{"require_content_type {\n\tmethods POST\n}", true, nil},
+		{"require_content_type {\n\ttypes\n}", true, nil},
+		{"require_content_type {\n\ttypes json\n}", true, nil},
+		{"require_content_type {\n\ttypes */json\n}", true, nil},
+		{"require_content_type {\n\ttypes text/plain;charset=utf-8\n}", true, nil},
+		{"require_content_type {\n\ttypes text/plain\n\tmissing maybe\n}", true, nil},
+		{"require_content_type {\n\ttypes text/plain\n\tmax_size 1\n}", true, nil},
+	} {
+		rules, err := requireContentTypeParse(caddy.NewTestController("http", test.input))
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: Expected an error, but did not have one", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: Did not expect error, but got: %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(rules, test.expected) {
+			t.Errorf("Test %d: Expected %+v, got %+v", i, test.expected, rules)
+		}
+	}
+}
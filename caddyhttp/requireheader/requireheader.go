@@ -0,0 +1,83 @@
+// Package requireheader implements middleware that rejects
+// requests which lack required headers, or have them with
+// unexpected values.
+package requireheader
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+// RequireHeader is middleware that responds to requests under Paths
+// that don't meet all of Requirements with a 400 Bad Request, which
+// tells what is wrong with them.
+type RequireHeader struct {
+	Next         httpserver.Handler
+	Paths        []string
+	Requirements []Requirement
+}
+
+// Requirement is a header that requests must have. Without Values or
+// Pattern, it must only be present, if empty; otherwise, one of its
+// values must be one of Values, or match Pattern.
+type Requirement struct {
+	Name    string // canonical, like X-Api-Version
+	Values  []string
+	Pattern *regexp.Regexp
+}
+
+// ServeHTTP implements the httpserver.Handler interface.
+func (rh RequireHeader) ServeHTTP(w http.ResponseWriter, r *http.Request) (int, error) {
+	if rh.matches(r.URL.Path) {
+		for _, req := range rh.Requirements {
+			if msg := req.check(r); msg != "" {
+				httpserver.WriteTextResponse(w, http.StatusBadRequest, msg+"\n")
+				return 0, nil
+			}
+		}
+	}
+	return rh.Next.ServeHTTP(w, r)
+}
+
+// matches reports whether requests for path must have the headers.
+func (rh RequireHeader) matches(path string) bool {
+	for _, p := range rh.Paths {
+		if httpserver.Path(path).Matches(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// check returns why r does not meet the requirement,
+// or "" if it does.
+func (req Requirement) check(r *http.Request) string {
+	values, ok := r.Header[req.Name]
+	if req.Name == "Host" {
+		// net/http moves it out of the header
+		values, ok = []string{r.Host}, r.Host != ""
+	}
+	if !ok {
+		return "Missing required header " + req.Name
+	}
+	if len(req.Values) == 0 && req.Pattern == nil {
+		return ""
+	}
+	for _, value := range values {
+		if req.Pattern != nil && req.Pattern.MatchString(value) {
+			return ""
+		}
+		for _, v := range req.Values {
+			if value == v {
+				return ""
+			}
+		}
+	}
+	if len(req.Values) > 0 {
+		return "Header " + req.Name + " must be one of: " + strings.Join(req.Values, ", ")
+	}
+	return "Header " + req.Name + " must match " + req.Pattern.String()
+}
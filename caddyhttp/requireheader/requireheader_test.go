@@ -0,0 +1,88 @@
+package requireheader
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func TestRequireHeader(t *testing.T) {
+	rh := RequireHeader{
+		Next: httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+			return http.StatusOK, nil
+		}),
+		Paths: []string{"/api"},
+		Requirements: []Requirement{
+			{Name: "X-Api-Version", Values: []string{"1", "2"}},
+			{Name: "X-Client"},
+			{Name: "X-Request-Id", Pattern: regexp.MustCompile(`^[0-9a-f]{8}$`)},
+		},
+	}
+
+	for i, test := range []struct {
+		path           string
+		header         http.Header
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"/api/users", http.Header{"X-Api-Version": {"2"}, "X-Client": {"app"}, "X-Request-Id": {"0badcafe"}},
+			http.StatusOK, ""},
+		// present but empty is present
+		{"/api/users", http.Header{"X-Api-Version": {"1"}, "X-Client": {""}, "X-Request-Id": {"0badcafe"}},
+			http.StatusOK, ""},
+		// one of several values is enough
+		{"/api/users", http.Header{"X-Api-Version": {"3", "2"}, "X-Client": {"app"}, "X-Request-Id": {"0badcafe"}},
+			http.StatusOK, ""},
+		{"/api/users", http.Header{"X-Api-Version": {"2"}, "X-Request-Id": {"0badcafe"}},
+			http.StatusBadRequest, "Missing required header X-Client\n"},
+		{"/api/users", http.Header{"X-Api-Version": {"3"}, "X-Client": {"app"}, "X-Request-Id": {"0badcafe"}},
+			http.StatusBadRequest, "Header X-Api-Version must be one of: 1, 2\n"},
+		{"/api/users", http.Header{"X-Api-Version": {""}, "X-Client": {"app"}, "X-Request-Id": {"0badcafe"}},
+			http.StatusBadRequest, "Header X-Api-Version must be one of: 1, 2\n"},
+		{"/api/users", http.Header{"X-Api-Version": {"1"}, "X-Client": {"app"}, "X-Request-Id": {"nothex!!"}},
+			http.StatusBadRequest, "Header X-Request-Id must match ^[0-9a-f]{8}$\n"},
+		// other paths are not checked
+		{"/public", http.Header{}, http.StatusOK, ""},
+	} {
+		r, err := http.NewRequest("GET", test.path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Header = test.header
+		w := httptest.NewRecorder()
+
+		status, err := rh.ServeHTTP(w, r)
+		if err != nil {
+			t.Fatalf("Test %d: %v", i, err)
+		}
+		if status == 0 {
+			status = w.Code
+		}
+		if status != test.expectedStatus {
+			t.Errorf("Test %d: Expected status %d, got %d", i, test.expectedStatus, status)
+		}
+		if w.Body.String() != test.expectedBody {
+			t.Errorf("Test %d: Expected body %q, got %q", i, test.expectedBody, w.Body.String())
+		}
+	}
+}
+
+func TestRequireHost(t *testing.T) {
+	req := Requirement{Name: "Host", Values: []string{"api.example.com"}}
+	for i, test := range []struct {
+		host     string
+		expected bool
+	}{
+		{"api.example.com", true},
+		{"www.example.com", false},
+		{"", false},
+	} {
+		r := &http.Request{Host: test.host, Header: http.Header{}}
+		if ok := req.check(r) == ""; ok != test.expected {
+			t.Errorf("Test %d: Expected host %q to meet the requirement: %v", i, test.host, test.expected)
+		}
+	}
+}
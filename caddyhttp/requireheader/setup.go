@@ -0,0 +1,81 @@
+package requireheader
+
+import (
+	"net/http"
+	"regexp"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func init() {
+	caddy.RegisterPlugin("require_header", caddy.Plugin{
+		ServerType: "http",
+		Action:     setup,
+	})
+}
+
+// setup configures a new RequireHeader middleware instance.
+func setup(c *caddy.Controller) error {
+	rules, err := requireHeaderParse(c)
+	if err != nil {
+		return err
+	}
+
+	for _, rh := range rules {
+		rh := rh
+		httpserver.GetConfig(c).AddMiddleware(func(next httpserver.Handler) httpserver.Handler {
+			rh.Next = next
+			return rh
+		})
+	}
+
+	return nil
+}
+
+// requireHeaderParse parses the directive:
+//
+//	require_header [paths...] {
+//		<name>
+//		<name> value <values...>
+//		<name> match <regexp>
+//	}
+//
+// A header without a value or pattern must only be present.
+// Header names are case-insensitive.
+func requireHeaderParse(c *caddy.Controller) ([]RequireHeader, error) {
+	var rules []RequireHeader
+
+	for c.Next() {
+		rh := RequireHeader{Paths: c.RemainingArgs()}
+		if len(rh.Paths) == 0 {
+			rh.Paths = []string{"/"}
+		}
+
+		for c.NextBlock() {
+			req := Requirement{Name: http.CanonicalHeaderKey(c.Val())}
+			args := c.RemainingArgs()
+			switch {
+			case len(args) == 0:
+			case args[0] == "value" && len(args) > 1:
+				req.Values = args[1:]
+			case args[0] == "match" && len(args) == 2:
+				re, err := regexp.Compile(args[1])
+				if err != nil {
+					return nil, c.Errf("invalid regular expression '%s': %v", args[1], err)
+				}
+				req.Pattern = re
+			default:
+				return nil, c.ArgErr()
+			}
+			rh.Requirements = append(rh.Requirements, req)
+		}
+		if len(rh.Requirements) == 0 {
+			return nil, c.Err("require_header needs at least one header")
+		}
+
+		rules = append(rules, rh)
+	}
+
+	return rules, nil
+}
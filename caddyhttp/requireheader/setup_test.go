@@ -0,0 +1,89 @@
+package requireheader
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func TestSetup(t *testing.T) {
+	c := caddy.NewTestController("http", "require_header {\n\tX-Api-Version\n}")
+	err := setup(c)
+	if err != nil {
+		t.Errorf("Expected no errors, got: %v", err)
+	}
+	mids := httpserver.GetConfig(c).Middleware()
+	if len(mids) == 0 {
+		t.Fatal("Expected middleware, got 0 instead")
+	}
+
+	handler := mids[0](httpserver.EmptyNext)
+	myHandler, ok := handler.(RequireHeader)
+	if !ok {
+		t.Fatalf("Expected handler to be type RequireHeader, got: %#v", handler)
+	}
+	if !httpserver.SameNext(myHandler.Next, httpserver.EmptyNext) {
+		t.Error("'Next' field of handler was not set properly")
+	}
+}
+
+func TestRequireHeaderParse(t *testing.T) {
+	for i, test := range []struct {
+		input     string
+		shouldErr bool
+		paths     []string
+		names     []string
+		values    [][]string
+		patterns  []string
+	}{
+		{"require_header {\n\tx-api-version\n}", false,
+			[]string{"/"}, []string{"X-Api-Version"}, [][]string{nil}, []string{""}},
+		{"require_header /api /v2 {\n\tX-Api-Version value 1 2\n\tX-Request-Id match ^[0-9a-f]+$\n}", false,
+			[]string{"/api", "/v2"}, []string{"X-Api-Version", "X-Request-Id"},
+			[][]string{{"1", "2"}, nil}, []string{"", "^[0-9a-f]+$"}},
+		{`require_header`, true, nil, nil, nil, nil},
+		{`require_header /api`, true, nil, nil, nil, nil},
+		{"require_header {\n\tX-Api-Version value\n}", true, nil, nil, nil, nil},
+		{"require_header {\n\tX-Api-Version 2\n}", true, nil, nil, nil, nil},
+		{"require_header {\n\tX-Api-Version match a b\n}", true, nil, nil, nil, nil},
+		{"require_header {\n\tX-Api-Version match (\n}", true, nil, nil, nil, nil},
+	} {
+		rules, err := requireHeaderParse(caddy.NewTestController("http", test.input))
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: Expected an error, but did not have one", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: Did not expect error, but got: %v", i, err)
+			continue
+		}
+		if len(rules) != 1 {
+			t.Fatalf("Test %d: Expected 1 rule, got %d", i, len(rules))
+		}
+		if !reflect.DeepEqual(rules[0].Paths, test.paths) {
+			t.Errorf("Test %d: Expected paths %v, got %v", i, test.paths, rules[0].Paths)
+		}
+		if len(rules[0].Requirements) != len(test.names) {
+			t.Fatalf("Test %d: Expected %d requirements, got %d", i, len(test.names), len(rules[0].Requirements))
+		}
+		for j, req := range rules[0].Requirements {
+			if req.Name != test.names[j] {
+				t.Errorf("Test %d, requirement %d: Expected name %s, got %s", i, j, test.names[j], req.Name)
+			}
+			if !reflect.DeepEqual(req.Values, test.values[j]) {
+				t.Errorf("Test %d, requirement %d: Expected values %v, got %v", i, j, test.values[j], req.Values)
+			}
+			var pattern string
+			if req.Pattern != nil {
+				pattern = req.Pattern.String()
+			}
+			if pattern != test.patterns[j] {
+				t.Errorf("Test %d, requirement %d: Expected pattern %q, got %q", i, j, test.patterns[j], pattern)
+			}
+		}
+	}
+}
@@ -34,12 +34,63 @@ type Rewrite struct {
 // ServeHTTP implements the httpserver.Handler interface.
 func (rw Rewrite) ServeHTTP(w http.ResponseWriter, r *http.Request) (int, error) {
 	if rule := httpserver.ConfigSelector(rw.Rules).Select(r); rule != nil {
-		rule.(Rule).Rewrite(rw.FileSys, r)
+		if complexRule, ok := rule.(*ComplexRule); ok {
+			re, fellBack := complexRule.rewrite(rw.FileSys, r)
+			if re == RewriteDone && fellBack && complexRule.Status != 0 {
+				return rw.serveStatus(w, r, complexRule.Status)
+			}
+		} else {
+			rule.(Rule).Rewrite(rw.FileSys, r)
+		}
 	}
 
 	return rw.Next.ServeHTTP(w, r)
 }
 
+// serveStatus serves the rewritten request r, which is for a page like
+// /404.html, as a response with status instead of 200 OK. If there is
+// no such page, the error page of status is served instead.
+func (rw Rewrite) serveStatus(w http.ResponseWriter, r *http.Request, status int) (int, error) {
+	// the page is not what was requested, so the
+	// conditions and ranges of the request are not for it
+	for _, name := range []string{"If-Match", "If-None-Match", "If-Modified-Since", "If-Unmodified-Since", "If-Range", "Range"} {
+		r.Header.Del(name)
+	}
+
+	sw := &statusResponseWriter{ResponseWriter: w, status: status}
+	code, err := rw.Next.ServeHTTP(sw, r)
+	if !sw.wroteHeader && code == http.StatusNotFound {
+		return status, nil
+	}
+	return code, err
+}
+
+// statusResponseWriter writes the response header with its status
+// instead of 200 OK.
+type statusResponseWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+// WriteHeader writes the response header, with w.status if code is
+// 200 OK.
+func (w *statusResponseWriter) WriteHeader(code int) {
+	w.wroteHeader = true
+	if code == http.StatusOK {
+		code = w.status
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Write writes the response header if needed, then b.
+func (w *statusResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
 // Rule describes an internal location rewrite rule.
 type Rule interface {
 	httpserver.HandlerConfig
@@ -88,6 +139,16 @@ type ComplexRule struct {
 	httpserver.RequestMatcher
 
 	*regexp.Regexp
+
+	// Name of the rule, if any, for the {matched_rule}
+	// placeholder once it rewrote a request
+	Name string
+
+	// Error status to respond with when the request is rewritten
+	// to the last of the paths to rewrite to, like /404.html,
+	// because none of the others exists; the page is served as
+	// the body. 0 to handle the rewritten request as usual
+	Status int
 }
 
 // NewComplexRule creates a new RegexpRule. It returns an error if regexp
@@ -101,6 +162,12 @@ func NewComplexRule(base, pattern, to string, ext []string, matcher httpserver.R
 		if err != nil {
 			return nil, err
 		}
+		// named groups must not shadow the numbered placeholders
+		for _, name := range r.SubexpNames() {
+			if name != "" && strings.Trim(name, "0123456789") == "" {
+				return nil, fmt.Errorf("invalid capture group name %v: conflicts with {%v}", name, name)
+			}
+		}
 	}
 
 	// validate extensions if present
@@ -158,8 +225,16 @@ func (r *ComplexRule) Match(req *http.Request) bool {
 }
 
 // Rewrite rewrites the internal location of the current request.
-func (r *ComplexRule) Rewrite(fs http.FileSystem, req *http.Request) (re Result) {
+func (r *ComplexRule) Rewrite(fs http.FileSystem, req *http.Request) Result {
+	re, _ := r.rewrite(fs, req)
+	return re
+}
+
+// rewrite is Rewrite that also reports whether the request was
+// rewritten to the last of the paths to rewrite to.
+func (r *ComplexRule) rewrite(fs http.FileSystem, req *http.Request) (re Result, fellBack bool) {
 	replacer := newReplacer(req)
+	captures := make(map[string]string)
 
 	// validate regexp if present
 	if r.Regexp != nil {
@@ -170,10 +245,12 @@ func (r *ComplexRule) Rewrite(fs http.FileSystem, req *http.Request) (re Result)
 			return
 		default:
 			// set regexp match variables {1}, {2} ...
+			// and {name} for named groups
 
 			// url escaped values of ? and #.
 			q, f := url.QueryEscape("?"), url.QueryEscape("#")
 
+			names := r.SubexpNames()
 			for i := 1; i < len(matches); i++ {
 				// Special case of unescaped # and ? by stdlib regexp.
 				// Reverse the unescape.
@@ -181,13 +258,30 @@ func (r *ComplexRule) Rewrite(fs http.FileSystem, req *http.Request) (re Result)
 					matches[i] = strings.NewReplacer("?", q, "#", f).Replace(matches[i])
 				}
 
-				replacer.Set(fmt.Sprint(i), matches[i])
+				captures[fmt.Sprint(i)] = matches[i]
+				if names[i] != "" {
+					captures[names[i]] = matches[i]
+				}
+			}
+			for key, value := range captures {
+				replacer.Set(key, value)
 			}
 		}
 	}
 
 	// attempt rewrite
-	return To(fs, req, r.To, replacer)
+	re, fellBack = rewriteTo(fs, req, r.To, replacer)
+
+	// expose the captures to the handlers that follow
+	if re == RewriteDone {
+		for key, value := range captures {
+			httpserver.SetPlaceholder(req, key, value)
+		}
+		if r.Name != "" {
+			httpserver.SetPlaceholder(req, httpserver.MatchedRulePlaceholder, r.Name)
+		}
+	}
+	return re, fellBack
 }
 
 // matchExt matches rPath against registered file extensions.
@@ -1,13 +1,18 @@
 package rewrite
 
 import (
+	"context"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/mholt/caddy/caddyhttp/httpserver"
+	"github.com/mholt/caddy/caddyhttp/staticfiles"
 )
 
 func TestRewrite(t *testing.T) {
@@ -36,6 +41,8 @@ func TestRewrite(t *testing.T) {
 		{"/reg2grp", `(.*)`, "/{1}", ""},
 		{"/reg3grp", `(.*)/(.*)/(.*)`, "/{1}{2}{3}", ""},
 		{"/hashtest", "(.*)", "/{1}", ""},
+		{"/named", `/(?P<year>[0-9]{4})/(?P<slug>[a-z-]+)`, "/posts/{slug}?year={year}", ""},
+		{"/mixed", `/([a-z]+)/(?P<id>[0-9]+)`, "/{1}?id={id}&second={2}", ""},
 	}
 
 	for _, regexpRule := range regexps {
@@ -97,6 +104,10 @@ func TestRewrite(t *testing.T) {
 		{"/hashtest/a%20%23%20test", "/a%20%23%20test"},
 		{"/hashtest/a%20%3F%20test", "/a%20%3F%20test"},
 		{"/hashtest/a%20%3F%23test", "/a%20%3F%23test"},
+		{"/named/2016/hello-world", "/posts/hello-world?year=2016"},
+		{"/named/2016/hello-world?page=2", "/posts/hello-world?year=2016"},
+		{"/named/16/hello-world", "/named/16/hello-world"},
+		{"/mixed/user/42", "/user?id=42&second=42"},
 	}
 
 	for i, test := range tests {
@@ -115,6 +126,173 @@ func TestRewrite(t *testing.T) {
 	}
 }
 
+func TestRewriteCapturePlaceholders(t *testing.T) {
+	rule, err := NewComplexRule("/blog", `/(?P<year>[0-9]{4})/(.*)`, "/index.php", nil, httpserver.IfMatcher{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rw := Rewrite{
+		Next: httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+			fmt.Fprint(w, httpserver.NewReplacer(r, nil, "-").Replace("{year} {1} {2} {rewrite_path}"))
+			return 0, nil
+		}),
+		Rules: []httpserver.HandlerConfig{rule},
+	}
+
+	for i, test := range []struct {
+		from     string
+		expected string
+	}{
+		{"/blog/2016/hello", "2016 2016 hello /index.php"},
+		{"/blog/hello", "- - - /blog/hello"},
+	} {
+		req, err := http.NewRequest("GET", test.from, nil)
+		if err != nil {
+			t.Fatalf("Test %d: Could not create HTTP request: %v", i, err)
+		}
+		ctx := context.WithValue(req.Context(), httpserver.PlaceholdersCtxKey, make(map[string]string))
+		req = req.WithContext(ctx)
+
+		rec := httptest.NewRecorder()
+		rw.ServeHTTP(rec, req)
+
+		if rec.Body.String() != test.expected {
+			t.Errorf("Test %d: Expected placeholders to be '%s' but were '%s'",
+				i, test.expected, rec.Body.String())
+		}
+	}
+}
+
+func TestRewriteMatchedRule(t *testing.T) {
+	api, err := NewComplexRule("/api", "", "/api.php", nil, httpserver.IfMatcher{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	api.Name = "api"
+	blog, err := NewComplexRule("/blog", "", "/index.php", nil, httpserver.IfMatcher{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	blog.Name = "blog"
+	unnamed, err := NewComplexRule("/docs", "", "/docs.php", nil, httpserver.IfMatcher{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, test := range []struct {
+		rewrites []Rewrite
+		from     string
+		expected string
+	}{
+		{[]Rewrite{{Rules: []httpserver.HandlerConfig{api, blog}}}, "/api/users", "api"},
+		{[]Rewrite{{Rules: []httpserver.HandlerConfig{api, blog}}}, "/blog/hello", "blog"},
+		{[]Rewrite{{Rules: []httpserver.HandlerConfig{api, blog}}}, "/about", "-"},
+		{[]Rewrite{{Rules: []httpserver.HandlerConfig{unnamed}}}, "/docs/a", "-"},
+		// of the rules that fire, the last one counts...
+		{[]Rewrite{{Rules: []httpserver.HandlerConfig{blog}}, {Rules: []httpserver.HandlerConfig{NewSimpleRule("/index.php", "/api/index")}},
+			{Rules: []httpserver.HandlerConfig{api}}}, "/blog/hello", "api"},
+		// ...if it is named
+		{[]Rewrite{{Rules: []httpserver.HandlerConfig{blog}}, {Rules: []httpserver.HandlerConfig{NewSimpleRule("/index.php", "/docs/index")}},
+			{Rules: []httpserver.HandlerConfig{unnamed}}}, "/blog/hello", "blog"},
+	} {
+		var handler httpserver.Handler = httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+			fmt.Fprint(w, httpserver.NewReplacer(r, nil, "-").Replace("{matched_rule}"))
+			return 0, nil
+		})
+		for j := len(test.rewrites) - 1; j >= 0; j-- {
+			rw := test.rewrites[j]
+			rw.Next = handler
+			handler = rw
+		}
+
+		req, err := http.NewRequest("GET", test.from, nil)
+		if err != nil {
+			t.Fatalf("Test %d: Could not create HTTP request: %v", i, err)
+		}
+		ctx := context.WithValue(req.Context(), httpserver.PlaceholdersCtxKey, make(map[string]string))
+		req = req.WithContext(ctx)
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Body.String() != test.expected {
+			t.Errorf("Test %d: Expected {matched_rule} to be '%s' but was '%s'",
+				i, test.expected, rec.Body.String())
+		}
+	}
+}
+
+func TestRewriteStatus(t *testing.T) {
+	root, err := ioutil.TempDir("", "caddy_rewrite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	for name, content := range map[string]string{"page.html": "page", "404.html": "not found"} {
+		if err := ioutil.WriteFile(filepath.Join(root, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i, test := range []struct {
+		root           string
+		from           string
+		header         string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{root, "/page.html", "", http.StatusOK, "page"},
+		{root, "/missing.html", "", http.StatusNotFound, "not found"},
+		// the page is not what the conditions of the request are about
+		{root, "/missing.html", "Thu, 01 Jan 2037 00:00:00 GMT", http.StatusNotFound, "not found"},
+		// without the page, the error page of the status is served
+		{filepath.Join(root, "nonexistent"), "/missing.html", "", http.StatusNotFound, ""},
+	} {
+		rule, err := NewComplexRule("/", "", "{path} /404.html", nil, httpserver.IfMatcher{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rule.Status = http.StatusNotFound
+		rw := Rewrite{
+			Next:    staticfiles.FileServer{Root: http.Dir(test.root)},
+			Rules:   []httpserver.HandlerConfig{rule},
+			FileSys: http.Dir(test.root),
+		}
+
+		req, err := http.NewRequest("GET", test.from, nil)
+		if err != nil {
+			t.Fatalf("Test %d: Could not create HTTP request: %v", i, err)
+		}
+		if test.header != "" {
+			req.Header.Set("If-Modified-Since", test.header)
+		}
+		rec := httptest.NewRecorder()
+
+		status, err := rw.ServeHTTP(rec, req)
+		if err != nil {
+			t.Errorf("Test %d: Expected no error, got: %v", i, err)
+		}
+		if status < 400 {
+			status = rec.Code
+		}
+		if status != test.expectedStatus {
+			t.Errorf("Test %d: Expected status %d, got %d", i, test.expectedStatus, status)
+		}
+		if rec.Body.String() != test.expectedBody {
+			t.Errorf("Test %d: Expected body %q, got %q", i, test.expectedBody, rec.Body.String())
+		}
+	}
+}
+
+func TestNewComplexRuleNumericGroupName(t *testing.T) {
+	if _, err := NewComplexRule("/", `(?P<1>[a-z]+)`, "/{1}", nil, httpserver.IfMatcher{}); err == nil {
+		t.Error("Expected an error for a capture group named like a numbered placeholder")
+	}
+	if _, err := NewComplexRule("/", `(?P<p1>[a-z]+)`, "/{p1}", nil, httpserver.IfMatcher{}); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+}
+
 func urlPrinter(w http.ResponseWriter, r *http.Request) (int, error) {
 	fmt.Fprint(w, r.URL.String())
 	return 0, nil
@@ -1,7 +1,7 @@
 package rewrite
 
 import (
-	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/mholt/caddy"
@@ -27,7 +27,7 @@ func setup(c *caddy.Controller) error {
 	cfg.AddMiddleware(func(next httpserver.Handler) httpserver.Handler {
 		return Rewrite{
 			Next:    next,
-			FileSys: http.Dir(cfg.Root),
+			FileSys: cfg.RootFileSys(),
 			Rules:   rewrites,
 		}
 	})
@@ -42,7 +42,8 @@ func rewriteParse(c *caddy.Controller) ([]httpserver.HandlerConfig, error) {
 		var rule Rule
 		var err error
 		var base = "/"
-		var pattern, to string
+		var pattern, to, name string
+		var status int
 		var ext []string
 
 		args := c.RemainingArgs()
@@ -75,6 +76,12 @@ func rewriteParse(c *caddy.Controller) ([]httpserver.HandlerConfig, error) {
 					if len(args1) == 0 {
 						return nil, c.ArgErr()
 					}
+					if len(args1) > 1 {
+						// the paths before the last are tried as files
+						if err := httpserver.CheckStaticRoot(c, "rewrite to several paths"); err != nil {
+							return nil, err
+						}
+					}
 					to = strings.Join(args1, " ")
 				case "ext":
 					args1 := c.RemainingArgs()
@@ -82,6 +89,21 @@ func rewriteParse(c *caddy.Controller) ([]httpserver.HandlerConfig, error) {
 						return nil, c.ArgErr()
 					}
 					ext = args1
+				case "name":
+					args1 := c.RemainingArgs()
+					if len(args1) != 1 {
+						return nil, c.ArgErr()
+					}
+					name = args1[0]
+				case "status":
+					args1 := c.RemainingArgs()
+					if len(args1) != 1 {
+						return nil, c.ArgErr()
+					}
+					status, err = strconv.Atoi(args1[0])
+					if err != nil || status < 400 || status > 599 {
+						return nil, c.Errf("status must be an error status code between 400 and 599, got '%s'", args1[0])
+					}
 				default:
 					return nil, c.ArgErr()
 				}
@@ -90,13 +112,21 @@ func rewriteParse(c *caddy.Controller) ([]httpserver.HandlerConfig, error) {
 			if to == "" {
 				return nil, c.ArgErr()
 			}
-			if rule, err = NewComplexRule(base, pattern, to, ext, matcher); err != nil {
+			complexRule, err := NewComplexRule(base, pattern, to, ext, matcher)
+			if err != nil {
 				return nil, err
 			}
-			rules = append(rules, rule)
+			complexRule.Name = name
+			complexRule.Status = status
+			rules = append(rules, complexRule)
 
 		// the only unhandled case is 2 and above
 		default:
+			if len(args) > 2 {
+				if err := httpserver.CheckStaticRoot(c, "rewrite to several paths"); err != nil {
+					return nil, err
+				}
+			}
 			rule = NewSimpleRule(args[0], strings.Join(args[1:], " "))
 			rules = append(rules, rule)
 		}
@@ -35,6 +35,27 @@ func TestSetup(t *testing.T) {
 	}
 }
 
+func TestRewriteParseDynamicRoot(t *testing.T) {
+	for i, test := range []struct {
+		input     string
+		shouldErr bool
+	}{
+		{`rewrite /from /to`, false},
+		{`rewrite /from {path} /index.php`, true},
+		{"rewrite {\n to /to\n}", false},
+		{"rewrite {\n to {path} {path}/ /index.php\n}", true},
+	} {
+		c := caddy.NewTestController("http", test.input)
+		httpserver.GetConfig(c).Root = "/srv/{label1}"
+		_, err := rewriteParse(c)
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %d: Expected an error for a root with placeholders, got none", i)
+		} else if !test.shouldErr && err != nil {
+			t.Errorf("Test %d: Expected no error, got: %v", i, err)
+		}
+	}
+}
+
 func TestRewriteParse(t *testing.T) {
 	simpleTests := []struct {
 		input     string
@@ -137,6 +158,42 @@ func TestRewriteParse(t *testing.T) {
 		 }`, false, []Rule{
 			&ComplexRule{Base: "/", To: "/to"},
 		}},
+		{`rewrite /blog {
+			to		/index.php
+			name	wordpress
+		 }`, false, []Rule{
+			&ComplexRule{Base: "/blog", To: "/index.php", Name: "wordpress"},
+		}},
+		{`rewrite {
+			to		/to
+			name
+		 }`, true, []Rule{
+			&ComplexRule{},
+		}},
+		{`rewrite {
+			to		{path} /404.html
+			status	404
+		 }`, false, []Rule{
+			&ComplexRule{Base: "/", To: "{path} /404.html", Status: 404},
+		}},
+		{`rewrite {
+			to		/404.html
+			status	200
+		 }`, true, []Rule{
+			&ComplexRule{},
+		}},
+		{`rewrite {
+			to		/404.html
+			status	missing
+		 }`, true, []Rule{
+			&ComplexRule{},
+		}},
+		{`rewrite {
+			to		/to
+			name	a b
+		 }`, true, []Rule{
+			&ComplexRule{},
+		}},
 	}
 
 	for i, test := range regexpTests {
@@ -169,6 +226,16 @@ func TestRewriteParse(t *testing.T) {
 					i, j, expectedRule.To, actualRule.To)
 			}
 
+			if actualRule.Status != expectedRule.Status {
+				t.Errorf("Test %d, rule %d: Expected Status=%d, got %d",
+					i, j, expectedRule.Status, actualRule.Status)
+			}
+
+			if actualRule.Name != expectedRule.Name {
+				t.Errorf("Test %d, rule %d: Expected Name=%s, got %s",
+					i, j, expectedRule.Name, actualRule.Name)
+			}
+
 			if fmt.Sprint(actualRule.Exts) != fmt.Sprint(expectedRule.Exts) {
 				t.Errorf("Test %d, rule %d: Expected Ext=%v, got %v",
 					i, j, expectedRule.To, actualRule.To)
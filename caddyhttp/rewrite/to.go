@@ -11,18 +11,32 @@ import (
 )
 
 // To attempts rewrite. It attempts to rewrite to first valid path
-// or the last path if none of the paths are valid.
+// or the last path if none of the paths are valid. A path is valid
+// if it names an existing file, or directory if it ends with "/",
+// inside the site root; paths that climb above the root are never
+// valid, like try_files of other servers.
 func To(fs http.FileSystem, r *http.Request, to string, replacer httpserver.Replacer) Result {
+	re, _ := rewriteTo(fs, r, to, replacer)
+	return re
+}
+
+// rewriteTo is To that also reports whether the request was rewritten
+// to the last path, the fallback if none of the others is valid.
+func rewriteTo(fs http.FileSystem, r *http.Request, to string, replacer httpserver.Replacer) (Result, bool) {
 	tos := strings.Fields(to)
 
 	// try each rewrite paths
 	t := ""
 	query := ""
-	for _, v := range tos {
+	var fellBack bool
+	for i, v := range tos {
+		fellBack = i == len(tos)-1
 		t = replacer.Replace(v)
 		tparts := strings.SplitN(t, "?", 2)
-		t = path.Clean(tparts[0])
+		t = path.Clean("/" + tparts[0])
 
+		// only the query of the chosen path counts
+		query = ""
 		if len(tparts) > 1 {
 			query = tparts[1]
 		}
@@ -33,7 +47,7 @@ func To(fs http.FileSystem, r *http.Request, to string, replacer httpserver.Repl
 		}
 
 		// validate file
-		if validFile(fs, t) {
+		if !escapesRoot(tparts[0]) && validFile(fs, t) {
 			break
 		}
 	}
@@ -44,7 +58,7 @@ func To(fs http.FileSystem, r *http.Request, to string, replacer httpserver.Repl
 		// Let the user know we got here. Rewrite is expected but
 		// the resulting url is invalid.
 		log.Printf("[ERROR] rewrite: resulting path '%v' is invalid. error: %v", t, err)
-		return RewriteIgnored
+		return RewriteIgnored, false
 	}
 
 	// take note of this rewrite for internal use by fastcgi
@@ -62,7 +76,7 @@ func To(fs http.FileSystem, r *http.Request, to string, replacer httpserver.Repl
 		r.URL.Fragment = u.Fragment
 	}
 
-	return RewriteDone
+	return RewriteDone, fellBack
 }
 
 // validFile checks if file exists on the filesystem.
@@ -91,3 +105,22 @@ func validFile(fs http.FileSystem, file string) bool {
 	// file
 	return !stat.IsDir()
 }
+
+// escapesRoot reports whether the slash-separated path p
+// climbs above the directory it is relative to.
+func escapesRoot(p string) bool {
+	depth := 0
+	for _, segment := range strings.Split(p, "/") {
+		switch segment {
+		case "", ".":
+		case "..":
+			depth--
+			if depth < 0 {
+				return true
+			}
+		default:
+			depth++
+		}
+	}
+	return false
+}
@@ -25,6 +25,20 @@ func TestTo(t *testing.T) {
 		{"/test?url=http://", " /p/{path}?{query}", "/p/test?url=http://"},
 		{"/test?url=http://", " /p/{rewrite_path}?{query}", "/p/test?url=http://"},
 		{"/test/?url=http://", " /{uri}", "/test/?url=http://"},
+		// first existing file, with its query
+		{"/page?a=b", "/testfile?from=try /testdir/ /index.html", "/testfile?from=try"},
+		// fallback to the default keeps the original query
+		{"/page?a=b", "/missing?from=try /missingdir/ /index.html", "/index.html?a=b"},
+		{"/page?a=b", "{path}.html {path}/ /index.php?p={path}", "/index.php?p=/page"},
+		// directories only match with a trailing slash
+		{"/testdir", "{path} {path}/ /index.html", "/testdir/"},
+		// candidates are rooted
+		{"/", "testfile /index.html", "/testfile"},
+		// candidates may not climb above the root
+		{"/", "/../testfile /index.html", "/index.html"},
+		{"/", "/testdir/../../testfile /index.html", "/index.html"},
+		{"/", "/testdir/../testfile /index.html", "/testfile"},
+		{"/", "/index.html /../../etc/passwd", "/etc/passwd"},
 	}
 
 	uri := func(r *url.URL) string {
@@ -45,3 +59,23 @@ func TestTo(t *testing.T) {
 		}
 	}
 }
+
+func TestEscapesRoot(t *testing.T) {
+	for i, test := range []struct {
+		path    string
+		escapes bool
+	}{
+		{"/", false},
+		{"/a/b", false},
+		{"/a/../b", false},
+		{"/a/./../b/..", false},
+		{"a//..", false},
+		{"/..", true},
+		{"/a/../../b", true},
+		{"../a", true},
+	} {
+		if got := escapesRoot(test.path); got != test.escapes {
+			t.Errorf("Test %d: Expected escapesRoot(%q) to be %v, got %v", i, test.path, test.escapes, got)
+		}
+	}
+}
@@ -15,6 +15,19 @@ func init() {
 	})
 }
 
+// setupRoot parses the directive:
+//
+//	root path {
+//		base directory
+//	}
+//
+// The path may contain placeholders, in which case it is resolved
+// for every request and must resolve to a directory below the base,
+// which defaults to the directory of the path up to its first
+// placeholder. Only static files are served from such a root;
+// directives that read files from the root themselves, like
+// browse, templates, markdown, ext, fastcgi and rewrite to
+// several paths, refuse it.
 func setupRoot(c *caddy.Controller) error {
 	config := httpserver.GetConfig(c)
 
@@ -23,21 +36,51 @@ func setupRoot(c *caddy.Controller) error {
 			return c.ArgErr()
 		}
 		config.Root = c.Val()
-		if c.NextArg() {
+		if len(c.RemainingArgs()) != 0 {
 			// only one argument allowed
 			return c.ArgErr()
 		}
+		for c.NextBlock() {
+			switch c.Val() {
+			case "base":
+				if !c.NextArg() {
+					return c.ArgErr()
+				}
+				config.RootBase = c.Val()
+				if c.NextArg() {
+					return c.ArgErr()
+				}
+			default:
+				return c.Errf("unknown subdirective '%s'", c.Val())
+			}
+		}
+	}
+
+	if httpserver.IsDynamicRoot(config.Root) {
+		if config.RootBase == "" {
+			config.RootBase = httpserver.DynamicRootBase(config.Root)
+		}
+		// the directories of the requests may appear at any time,
+		// but the one they are all in should exist already
+		return checkRoot(c, config.RootBase)
 	}
+	if config.RootBase != "" {
+		return c.Err("base can only be used with a root that has placeholders")
+	}
+
+	return checkRoot(c, config.Root)
+}
 
-	// Check if root path exists
-	_, err := os.Stat(config.Root)
+// checkRoot checks that the root path root can be accessed.
+func checkRoot(c *caddy.Controller, root string) error {
+	_, err := os.Stat(root)
 	if err != nil {
 		if os.IsNotExist(err) {
 			// Allow this, because the folder might appear later.
 			// But make sure the user knows!
-			log.Printf("[WARNING] Root path does not exist: %s", config.Root)
+			log.Printf("[WARNING] Root path does not exist: %s", root)
 		} else {
-			return c.Errf("Unable to access root path '%s': %v", config.Root, err)
+			return c.Errf("Unable to access root path '%s': %v", root, err)
 		}
 	}
 
@@ -91,6 +91,38 @@ func TestRoot(t *testing.T) {
 	}
 }
 
+func TestDynamicRoot(t *testing.T) {
+	for i, test := range []struct {
+		input        string
+		shouldErr    bool
+		expectedBase string
+	}{
+		{`root /srv/{label1}`, false, "/srv"},
+		{`root /srv/site-{label1}/public`, false, "/srv"},
+		{"root /srv/{label1}/public {\n\tbase /srv/sites\n}", false, "/srv/sites"},
+		{"root /srv/{label1} {\n\tbase\n}", true, ""},
+		{"root /srv/{label1} {\n\tbase /srv /var\n}", true, ""},
+		{"root /srv/{label1} {\n\tjail /srv\n}", true, ""},
+		{"root /srv/site {\n\tbase /srv\n}", true, ""},
+	} {
+		c := caddy.NewTestController("http", test.input)
+		err := setupRoot(c)
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: Expected error but got nil for input '%s'", i, test.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: Expected no error but found one for input %s. Error was: %v", i, test.input, err)
+			continue
+		}
+		if got := httpserver.GetConfig(c).RootBase; got != filepath.FromSlash(test.expectedBase) {
+			t.Errorf("Test %d: Expected base %s, got %s", i, test.expectedBase, got)
+		}
+	}
+}
+
 // getTempDirPath returnes the path to the system temp directory. If it does not exists - an error is returned.
 func getTempDirPath() (string, error) {
 	tempDir := os.TempDir()
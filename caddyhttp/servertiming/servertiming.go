@@ -0,0 +1,61 @@
+// Package servertiming is middleware that adds the Server-Timing
+// header to responses, to tell clients how long handling their
+// requests took, in total and in stages such as the upstream.
+package servertiming
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+// ServerTiming is middleware that reports how long handling requests
+// for Paths took. Besides the total, it reports the stages that other
+// handlers record with httpserver.AddServerTiming, like upstream for
+// the time until a proxy backend responded, or render for executing
+// templates. Timings tell how the server is set up, so they are only
+// reported where the site enables them.
+type ServerTiming struct {
+	Next  httpserver.Handler
+	Paths []string
+}
+
+// ServeHTTP implements the httpserver.Handler interface.
+func (s ServerTiming) ServeHTTP(w http.ResponseWriter, r *http.Request) (int, error) {
+	if !s.matches(r.URL.Path) {
+		return s.Next.ServeHTTP(w, r)
+	}
+
+	timings := new(httpserver.ServerTimings)
+	r = r.WithContext(context.WithValue(r.Context(), httpserver.ServerTimingsCtxKey, timings))
+	// the header is added when the response header is written, which
+	// is as late as it can be and when the total is known best; stages
+	// that end after it, while the body is written, are not reported.
+	// Metrics that the response has already, from a proxy backend for
+	// example, are kept.
+	start := time.Now()
+	setHeader := func(int) {
+		timings.Add("total", time.Since(start))
+		w.Header().Add("Server-Timing", timings.Header())
+	}
+	tw := httpserver.NewHeaderWriter(w, setHeader)
+	status, err := s.Next.ServeHTTP(tw, r)
+	if !tw.WroteHeader() {
+		// the response is written further up the
+		// chain, if at all (error pages for example)
+		setHeader(status)
+	}
+	return status, err
+}
+
+// matches reports whether timings are reported for path.
+func (s ServerTiming) matches(path string) bool {
+	for _, p := range s.Paths {
+		if httpserver.Path(path).Matches(p) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package servertiming
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func TestServerTiming(t *testing.T) {
+	for i, test := range []struct {
+		paths    []string
+		next     httpserver.Handler
+		expected []string // patterns of the Server-Timing headers
+	}{
+		// stages that handlers record come before the total
+		{[]string{"/"}, httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+			httpserver.AddServerTiming(r, "upstream", 3*time.Millisecond)
+			w.Write([]byte("body"))
+			return http.StatusOK, nil
+		}), []string{`^upstream;dur=3, total;dur=[0-9]+(\.[0-9]+)?$`}},
+		// headers that the response has are kept
+		{[]string{"/"}, httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+			w.Header().Set("Server-Timing", "db;dur=53")
+			w.WriteHeader(http.StatusNoContent)
+			return http.StatusNoContent, nil
+		}), []string{`^db;dur=53$`, `^total;dur=[0-9]+(\.[0-9]+)?$`}},
+		// responses written further up the chain
+		{[]string{"/"}, httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+			httpserver.AddServerTiming(r, "render", time.Millisecond)
+			return http.StatusNotFound, nil
+		}), []string{`^render;dur=1, total;dur=[0-9]+(\.[0-9]+)?$`}},
+		// paths that don't match
+		{[]string{"/api"}, httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+			httpserver.AddServerTiming(r, "upstream", time.Millisecond)
+			return http.StatusOK, nil
+		}), nil},
+	} {
+		s := ServerTiming{Next: test.next, Paths: test.paths}
+
+		r, err := http.NewRequest("GET", "/public/", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		if _, err := s.ServeHTTP(w, r); err != nil {
+			t.Fatalf("Test %d: %v", i, err)
+		}
+
+		got := w.Header()["Server-Timing"]
+		if len(got) != len(test.expected) {
+			t.Fatalf("Test %d: Expected %d Server-Timing headers, got %q", i, len(test.expected), got)
+		}
+		for j, pattern := range test.expected {
+			if !regexp.MustCompile(pattern).MatchString(got[j]) {
+				t.Errorf("Test %d: Expected Server-Timing header %d to match %s, got %q", i, j, pattern, got[j])
+			}
+		}
+	}
+}
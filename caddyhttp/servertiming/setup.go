@@ -0,0 +1,52 @@
+package servertiming
+
+import (
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func init() {
+	caddy.RegisterPlugin("server_timing", caddy.Plugin{
+		ServerType: "http",
+		Action:     setup,
+	})
+}
+
+// setup configures a new ServerTiming middleware instance.
+func setup(c *caddy.Controller) error {
+	s, err := serverTimingParse(c)
+	if err != nil {
+		return err
+	}
+
+	httpserver.GetConfig(c).AddMiddleware(func(next httpserver.Handler) httpserver.Handler {
+		s.Next = next
+		return s
+	})
+
+	return nil
+}
+
+// serverTimingParse parses the directive:
+//
+//	server_timing [paths...]
+//
+// Without paths, timings are reported for the whole site.
+func serverTimingParse(c *caddy.Controller) (ServerTiming, error) {
+	var s ServerTiming
+
+	for c.Next() {
+		if s.Paths != nil {
+			return s, c.Err("server_timing can only be specified once per site")
+		}
+		s.Paths = c.RemainingArgs()
+		if len(s.Paths) == 0 {
+			s.Paths = []string{"/"}
+		}
+		if c.NextBlock() {
+			return s, c.Errf("unknown subdirective '%s'", c.Val())
+		}
+	}
+
+	return s, nil
+}
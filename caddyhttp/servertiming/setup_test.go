@@ -0,0 +1,58 @@
+package servertiming
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func TestSetup(t *testing.T) {
+	c := caddy.NewTestController("http", `server_timing`)
+	err := setup(c)
+	if err != nil {
+		t.Errorf("Expected no errors, got: %v", err)
+	}
+	mids := httpserver.GetConfig(c).Middleware()
+	if len(mids) == 0 {
+		t.Fatal("Expected middleware, got 0 instead")
+	}
+
+	handler := mids[0](httpserver.EmptyNext)
+	myHandler, ok := handler.(ServerTiming)
+	if !ok {
+		t.Fatalf("Expected handler to be type ServerTiming, got: %#v", handler)
+	}
+	if !httpserver.SameNext(myHandler.Next, httpserver.EmptyNext) {
+		t.Error("'Next' field of handler was not set properly")
+	}
+}
+
+func TestServerTimingParse(t *testing.T) {
+	for i, test := range []struct {
+		input     string
+		shouldErr bool
+		paths     []string
+	}{
+		{`server_timing`, false, []string{"/"}},
+		{`server_timing /api /debug`, false, []string{"/api", "/debug"}},
+		{"server_timing {\n\tupstream\n}", true, nil},
+		{"server_timing\nserver_timing /api", true, nil},
+	} {
+		s, err := serverTimingParse(caddy.NewTestController("http", test.input))
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: Expected an error, but did not have one", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: Did not expect error, but got: %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(s.Paths, test.paths) {
+			t.Errorf("Test %d: Expected paths %v, got %v", i, test.paths, s.Paths)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+// Package slowrequests configures the threshold above which the
+// server logs requests of a site as slow.
+package slowrequests
+
+import (
+	"time"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func init() {
+	caddy.RegisterPlugin("slow_requests", caddy.Plugin{
+		ServerType: "http",
+		Action:     setupSlowRequests,
+	})
+}
+
+// setupSlowRequests parses the directive:
+//
+//	slow_requests <threshold>
+func setupSlowRequests(c *caddy.Controller) error {
+	config := httpserver.GetConfig(c)
+	for c.Next() {
+		if config.SlowRequestThreshold != 0 {
+			return c.Err("slow_requests can only be specified once per site")
+		}
+		if !c.NextArg() {
+			return c.ArgErr()
+		}
+		threshold, err := time.ParseDuration(c.Val())
+		if err != nil || threshold <= 0 {
+			return c.Errf("threshold must be a positive duration, got '%s'", c.Val())
+		}
+		if c.NextArg() {
+			return c.ArgErr()
+		}
+		config.SlowRequestThreshold = threshold
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package slowrequests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func TestSetupSlowRequests(t *testing.T) {
+	for i, test := range []struct {
+		input     string
+		shouldErr bool
+		expected  time.Duration
+	}{
+		{"slow_requests 2s", false, 2 * time.Second},
+		{"slow_requests 1m30s", false, 90 * time.Second},
+		{"slow_requests", true, 0},
+		{"slow_requests 0s", true, 0},
+		{"slow_requests -1s", true, 0},
+		{"slow_requests 5", true, 0},
+		{"slow_requests 1s 2s", true, 0},
+		{"slow_requests 1s\nslow_requests 2s", true, 0},
+	} {
+		c := caddy.NewTestController("http", test.input)
+		err := setupSlowRequests(c)
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %d: Expected an error, but did not have one", i)
+		}
+		if !test.shouldErr && err != nil {
+			t.Errorf("Test %d: Did not expect error, but got: %v", i, err)
+		}
+		if got := httpserver.GetConfig(c).SlowRequestThreshold; !test.shouldErr && got != test.expected {
+			t.Errorf("Test %d: Expected threshold %s, got %s", i, test.expected, got)
+		}
+	}
+}
@@ -1,15 +1,21 @@
 package staticfiles
 
 import (
+	"crypto/sha256"
 	"fmt"
+	"io"
 	"math/rand"
+	"mime"
 	"net/http"
+	"net/url"
 	"os"
 	"path"
 	"path/filepath"
+	"reflect"
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 )
 
 // FileServer implements a production-ready file server
@@ -24,13 +30,58 @@ import (
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 type FileServer struct {
-	// Jailed disk access
+	// Jailed disk access, usually an http.Dir; with Go 1.16
+	// or newer, it may also be an fs.FS, see FS
 	Root http.FileSystem
 
 	// List of files to treat as "Not Found"
 	Hide []string
+
+	// Index files to look for in directories, in order
+	// of preference; nil means the IndexPages default
+	IndexPages []string
+
+	// Content-codings of precompressed sibling files to
+	// serve in place of the requested file, in order of
+	// preference; nil means DefaultPrecompressed
+	Precompressed []string
+
+	// Patterns (as for path.Match) of path components that
+	// make a path "Not Found" wherever they appear in it,
+	// compared case-insensitively; nil means DefaultHiddenPatterns
+	HiddenPatterns []string
+
+	// Paths that are served, along with the paths below
+	// them, even if they match HiddenPatterns
+	HiddenExceptions []string
+
+	// How to treat the trailing slash of directory paths,
+	// one of the TrailingSlash values; "" means TrailingSlashAdd
+	TrailingSlash string
+
+	// Content types by file name extension, which take
+	// precedence over the ones of the system; see TypeByExtension
+	MimeTypes map[string]string
 }
 
+// The ways in which the file server can treat the trailing
+// slash of a request path.
+const (
+	// TrailingSlashAdd redirects directory paths without a
+	// trailing slash to the path with one, and file paths
+	// with a trailing slash to the path without one
+	TrailingSlashAdd = "add"
+
+	// TrailingSlashRemove redirects all paths with a trailing
+	// slash to the path without one, and serves directories
+	// at paths without one
+	TrailingSlashRemove = "remove"
+
+	// TrailingSlashOff serves paths as requested, with or
+	// without a trailing slash
+	TrailingSlashOff = "off"
+)
+
 // ServeHTTP serves static files for r according to fs's configuration.
 func (fs FileServer) ServeHTTP(w http.ResponseWriter, r *http.Request) (int, error) {
 	// r.URL.Path has already been cleaned by Caddy.
@@ -46,6 +97,10 @@ func (fs FileServer) serveFile(w http.ResponseWriter, r *http.Request, name stri
 
 	location := name
 
+	if fs.IsHiddenPath(name) {
+		return http.StatusNotFound, nil
+	}
+
 	// Prevent absolute path access on Windows.
 	// TODO remove when stdlib http.Dir fixes this.
 	if runtime.GOOS == "windows" {
@@ -80,24 +135,14 @@ func (fs FileServer) serveFile(w http.ResponseWriter, r *http.Request, name stri
 	}
 
 	// redirect to canonical path
-	url := r.URL.Path
-	if d.IsDir() {
-		// Ensure / at end of directory url
-		if !strings.HasSuffix(url, "/") {
-			Redirect(w, r, path.Base(url)+"/", http.StatusMovedPermanently)
-			return http.StatusMovedPermanently, nil
-		}
-	} else {
-		// Ensure no / at end of file url
-		if strings.HasSuffix(url, "/") {
-			Redirect(w, r, "../"+path.Base(url), http.StatusMovedPermanently)
-			return http.StatusMovedPermanently, nil
-		}
+	if target := fs.canonicalURL(r, d.IsDir()); target != "" {
+		http.Redirect(w, r, target, http.StatusMovedPermanently)
+		return http.StatusMovedPermanently, nil
 	}
 
 	// use contents of an index file, if present, for directory
 	if d.IsDir() {
-		for _, indexPage := range IndexPages {
+		for _, indexPage := range fs.Indexes() {
 			index := strings.TrimSuffix(name, "/") + "/" + indexPage
 			ff, err := fs.Root.Open(index)
 			if err != nil {
@@ -109,7 +154,7 @@ func (fs FileServer) serveFile(w http.ResponseWriter, r *http.Request, name stri
 			defer ff.Close()
 
 			dd, err := ff.Stat()
-			if err != nil {
+			if err != nil || dd.IsDir() {
 				ff.Close()
 				continue
 			}
@@ -130,28 +175,43 @@ func (fs FileServer) serveFile(w http.ResponseWriter, r *http.Request, name stri
 		return http.StatusNotFound, nil
 	}
 
-	if fs.IsHidden(d) {
+	if fs.IsHidden(d) || fs.IsHiddenPath(location) {
 		return http.StatusNotFound, nil
 	}
 
 	filename := d.Name()
 
-	for _, encoding := range staticEncodingPriority {
-		if !strings.Contains(r.Header.Get("Accept-Encoding"), encoding) {
+	if ctype := TypeByExtension(fs.MimeTypes, filename); ctype != "" && w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", ctype)
+	}
+
+	served := location
+	precompressed := fs.Precompressed
+	if precompressed == nil {
+		precompressed = DefaultPrecompressed
+	}
+	for _, encoding := range precompressed {
+		if !acceptsEncoding(r.Header.Get("Accept-Encoding"), encoding) {
 			continue
 		}
 
-		encodedFile, err := fs.Root.Open(location + staticEncoding[encoding])
+		encodedFile, err := fs.Root.Open(location + PrecompressedExtensions[encoding])
 		if err != nil {
 			continue
 		}
 
 		encodedFileInfo, err := encodedFile.Stat()
-		if err != nil {
+		if err != nil || encodedFileInfo.IsDir() {
 			encodedFile.Close()
 			continue
 		}
 
+		// The variant must be described as the original file,
+		// not as the compressed data it contains
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", contentType(filename, f))
+		}
+
 		// Close previous file - release fd
 		f.Close()
 
@@ -160,6 +220,7 @@ func (fs FileServer) serveFile(w http.ResponseWriter, r *http.Request, name stri
 
 		// Encoded file will be served
 		f = encodedFile
+		served = location + PrecompressedExtensions[encoding]
 
 		w.Header().Add("Vary", "Accept-Encoding")
 		w.Header().Set("Content-Encoding", encoding)
@@ -169,17 +230,89 @@ func (fs FileServer) serveFile(w http.ResponseWriter, r *http.Request, name stri
 
 	}
 
-	// Experimental ETag header
+	// Experimental ETag header; files without a modification time,
+	// like the ones of embedded file systems, are identified by
+	// their content instead
 	e := fmt.Sprintf(`W/"%x-%x"`, d.ModTime().Unix(), d.Size())
+	if d.ModTime().IsZero() {
+		e, err = fs.contentETag(served, d.Size(), f)
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+	}
 	w.Header().Set("ETag", e)
 
 	// Note: Errors generated by ServeContent are written immediately
 	// to the response. This usually only happens if seeking fails (rare).
+	// ServeContent also answers range requests: a single range with
+	// just that part of the file, several ranges with a
+	// multipart/byteranges body in the order they were requested, and
+	// ranges that start beyond the end of the file with a 416 status.
 	http.ServeContent(w, r, filename, d.ModTime(), f)
 
 	return http.StatusOK, nil
 }
 
+// contentETagKey identifies a file whose ETag is computed
+// from its content.
+type contentETagKey struct {
+	root http.FileSystem
+	name string
+	size int64
+}
+
+// contentETags are the ETags computed from the content of files.
+// Those are files without a modification time, typically embedded
+// in the binary, so they don't change and the ETags can be kept.
+var (
+	contentETags   = make(map[contentETagKey]string)
+	contentETagsMu sync.RWMutex
+)
+
+// contentETag returns a strong ETag computed from the content of
+// f, the file name in fs.Root with the given size, and rewinds f.
+// It is only computed the first time for a file.
+func (fs FileServer) contentETag(name string, size int64, f http.File) (string, error) {
+	// file systems are told apart by their address, so ones that
+	// are not pointers, whose values may not even be comparable,
+	// can't be cached for; FS returns a pointer
+	cache := reflect.ValueOf(fs.Root).Kind() == reflect.Ptr
+	key := contentETagKey{root: fs.Root, name: name, size: size}
+	if cache {
+		contentETagsMu.RLock()
+		e, ok := contentETags[key]
+		contentETagsMu.RUnlock()
+		if ok {
+			return e, nil
+		}
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	e := fmt.Sprintf(`"%x"`, h.Sum(nil)[:16])
+
+	if cache {
+		contentETagsMu.Lock()
+		contentETags[key] = e
+		contentETagsMu.Unlock()
+	}
+	return e, nil
+}
+
+// Indexes returns the names of the index files that fs
+// serves for directories, in order of preference.
+func (fs FileServer) Indexes() []string {
+	if fs.IndexPages == nil {
+		return IndexPages
+	}
+	return fs.IndexPages
+}
+
 // IsHidden checks if file with FileInfo d is on hide list.
 func (fs FileServer) IsHidden(d os.FileInfo) bool {
 	// If the file is supposed to be hidden, return a 404
@@ -196,6 +329,73 @@ func (fs FileServer) IsHidden(d os.FileInfo) bool {
 	return false
 }
 
+// IsHiddenPath reports whether a component of the path p
+// matches one of fs's hidden patterns, unless p is one of the
+// exceptions or below one.
+func (fs FileServer) IsHiddenPath(p string) bool {
+	patterns := fs.HiddenPatterns
+	if patterns == nil {
+		patterns = DefaultHiddenPatterns
+	}
+	if len(patterns) == 0 {
+		return false
+	}
+	for _, exception := range fs.HiddenExceptions {
+		exception = strings.TrimSuffix(exception, "/")
+		if p == exception || strings.HasPrefix(p, exception+"/") {
+			return false
+		}
+	}
+	for _, component := range strings.Split(strings.ToLower(p), "/") {
+		if component == "" {
+			continue
+		}
+		for _, pattern := range patterns {
+			if matched, _ := path.Match(strings.ToLower(pattern), component); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// canonicalURL returns the URL to redirect r to so that its path
+// ends in a slash as fs.TrailingSlash wants it for a directory (if
+// isDir) or a file, or "" if r should be served as it is. The root
+// path is never redirected.
+//
+// The redirect is for the path and query string that the client
+// requested, which a rewrite may have changed. If that path has the
+// wanted form already, there is no redirect: it would only lead the
+// client back to the same rewrite, in a loop.
+func (fs FileServer) canonicalURL(r *http.Request, isDir bool) string {
+	if fs.TrailingSlash == TrailingSlashOff {
+		return ""
+	}
+	wantSlash := isDir && fs.TrailingSlash != TrailingSlashRemove
+	if r.URL.Path == "/" || strings.HasSuffix(r.URL.Path, "/") == wantSlash {
+		return ""
+	}
+
+	requested := &url.URL{Path: r.URL.Path, RawQuery: r.URL.RawQuery}
+	if original := r.Header.Get("Caddy-Rewrite-Original-URI"); original != "" {
+		if u, err := url.ParseRequestURI(original); err == nil && u.Path != "" {
+			requested = &url.URL{Path: u.Path, RawQuery: u.RawQuery}
+		}
+	}
+	if requested.Path == "/" || strings.HasSuffix(requested.Path, "/") == wantSlash {
+		return ""
+	}
+
+	// cleaning keeps a path like //example.com from becoming a
+	// redirect to another host
+	requested.Path = path.Clean(requested.Path)
+	if wantSlash {
+		requested.Path += "/"
+	}
+	return requested.RequestURI()
+}
+
 // Redirect sends an HTTP redirect to the client but will preserve
 // the query string for the new path. Based on http.localRedirect
 // from the Go standard library.
@@ -217,16 +417,112 @@ var IndexPages = []string{
 	"default.txt",
 }
 
-// staticEncoding is a map of content-encoding to a file extension.
-// If client accepts given encoding (via Accept-Encoding header) and compressed file with given extensions exists
-// it will be served to the client instead of original one.
-var staticEncoding = map[string]string{
+// DefaultHiddenPatterns are the path components that are hidden
+// if no others are configured: the metadata of version control
+// systems and files with configuration or secrets that are not
+// meant to be published.
+var DefaultHiddenPatterns = []string{
+	".git",
+	".hg",
+	".svn",
+	".bzr",
+	".env",
+	".env.*",
+	".htaccess",
+	".htpasswd",
+	".DS_Store",
+}
+
+// PrecompressedExtensions maps content-codings to the file extension
+// of precompressed files. If the client accepts a given encoding (via the
+// Accept-Encoding header) and a file with that extension appended to the
+// requested name exists, it is served to the client instead of the original.
+var PrecompressedExtensions = map[string]string{
 	"gzip": ".gz",
 	"br":   ".br",
 }
 
-// staticEncodingPriority is a list of preferred static encodings (most efficient compression to least one).
-var staticEncodingPriority = []string{
+// DefaultPrecompressed is the list of precompressed encodings served by
+// default, from the most efficient compression to the least.
+var DefaultPrecompressed = []string{
 	"br",
 	"gzip",
 }
+
+// acceptsEncoding reports whether the Accept-Encoding header value
+// acceptEncoding allows the given content-coding, honoring q=0 and
+// the "*" wildcard as described in RFC 7231 section 5.3.4.
+func acceptsEncoding(acceptEncoding, encoding string) bool {
+	wildcard := false
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, q, _ := ParseQValue(part)
+		if name != encoding && name != "*" {
+			continue
+		}
+		if name == encoding {
+			// an explicit mention overrides the wildcard
+			return q > 0
+		}
+		wildcard = q > 0
+	}
+	return wildcard
+}
+
+// ParseQValue parses an element of a header with quality values,
+// like "gzip;q=0.8" of Accept-Encoding or "de;q=0.5" of
+// Accept-Language, into its lowercased value and its quality, which
+// is 1 if it is not given. ok is false, and q is 0, if the quality
+// is not a number from 0 to 1 (RFC 7231 section 5.3.1).
+func ParseQValue(element string) (value string, q float64, ok bool) {
+	params := strings.Split(element, ";")
+	value = strings.ToLower(strings.TrimSpace(params[0]))
+	q = 1
+	for _, param := range params[1:] {
+		param = strings.TrimSpace(param)
+		if len(param) < 2 || (param[0] != 'q' && param[0] != 'Q') || param[1] != '=' {
+			continue
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(param[2:]), 64)
+		if err != nil || v < 0 || v > 1 {
+			return value, 0, false
+		}
+		q = v
+	}
+	return value, q, true
+}
+
+// TypeByExtension returns the content type that types has for
+// the extension of the file named name, or "" if it has none. An
+// extension may have several dots, like .tar.gz, and the longest
+// one that name has wins; they are matched case-insensitively.
+func TypeByExtension(types map[string]string, name string) string {
+	if len(types) == 0 {
+		return ""
+	}
+	name = path.Base(name)
+	for i := 0; i < len(name); i++ {
+		if name[i] != '.' {
+			continue
+		}
+		if ctype, ok := types[name[i:]]; ok {
+			return ctype
+		}
+		if ctype, ok := types[strings.ToLower(name[i:])]; ok {
+			return ctype
+		}
+	}
+	return ""
+}
+
+// contentType returns the content type of the file named name with
+// contents f, going by its extension first and sniffing it otherwise.
+// It is used when serving a precompressed variant of f, from which
+// the type cannot be determined; f is left at an undefined offset.
+func contentType(name string, f io.Reader) string {
+	if ctype := mime.TypeByExtension(filepath.Ext(name)); ctype != "" {
+		return ctype
+	}
+	var buf [512]byte
+	n, _ := io.ReadFull(f, buf[:])
+	return http.DetectContentType(buf[:n])
+}
@@ -0,0 +1,31 @@
+// +build go1.16
+
+package staticfiles
+
+import (
+	"io/fs"
+	"net/http"
+	"path"
+)
+
+// FS returns the files of fsys, such as an embed.FS, as the
+// root of a FileServer, so that a site can be served without
+// any files on disk. Unlike an http.Dir on some systems, the
+// names of the files are always case-sensitive. Files without
+// a modification time, like the ones in an embed.FS, get an
+// ETag that is computed from their content once.
+func FS(fsys fs.FS) http.FileSystem {
+	return &fsFileSystem{http.FS(fsys)}
+}
+
+// fsFileSystem is an http.FileSystem of an fs.FS that, like
+// http.Dir, also opens directories by names ending in a slash,
+// which are not valid in an fs.FS.
+type fsFileSystem struct {
+	http.FileSystem
+}
+
+// Open implements http.FileSystem.
+func (f *fsFileSystem) Open(name string) (http.File, error) {
+	return f.FileSystem.Open(path.Clean("/" + name))
+}
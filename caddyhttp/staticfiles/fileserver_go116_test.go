@@ -0,0 +1,127 @@
+// +build go1.16
+
+package staticfiles
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestServeFS(t *testing.T) {
+	fileServer := FileServer{Root: FS(fstest.MapFS{
+		"index.html":         {Data: []byte("<h1>index</h1>")},
+		"file.txt":           {Data: []byte("0123456789")},
+		"dated.txt":          {Data: []byte("dated"), ModTime: time.Unix(1500000000, 0)},
+		"dir/index.html":     {Data: []byte("<h1>dir</h1>")},
+		"nested/nested.html": {Data: []byte("nested")},
+	})}
+
+	for i, test := range []struct {
+		path            string
+		rangeHeader     string
+		expectedStatus  int
+		expectedBody    string
+		expectedETag    string
+		expectedHeaders map[string]string
+	}{
+		{path: "/file.txt", expectedStatus: http.StatusOK, expectedBody: "0123456789",
+			expectedETag: `"84d89877f0d4041efb6bf91a16f0248f"`},
+		{path: "/file.txt", rangeHeader: "bytes=2-4", expectedStatus: http.StatusPartialContent, expectedBody: "234",
+			expectedHeaders: map[string]string{"Content-Range": "bytes 2-4/10"}},
+		{path: "/dated.txt", expectedStatus: http.StatusOK, expectedBody: "dated",
+			expectedETag: `W/"59682f00-5"`},
+		{path: "/", expectedStatus: http.StatusOK, expectedBody: "<h1>index</h1>"},
+		{path: "/dir/", expectedStatus: http.StatusOK, expectedBody: "<h1>dir</h1>"},
+		{path: "/nested/", expectedStatus: http.StatusNotFound},
+		{path: "/missing.txt", expectedStatus: http.StatusNotFound},
+		// names in an fs.FS are case-sensitive
+		{path: "/FILE.txt", expectedStatus: http.StatusNotFound},
+	} {
+		r, err := http.NewRequest("GET", test.path, nil)
+		if err != nil {
+			t.Fatalf("Test %d: Could not create HTTP request: %v", i, err)
+		}
+		if test.rangeHeader != "" {
+			r.Header.Set("Range", test.rangeHeader)
+		}
+		w := httptest.NewRecorder()
+
+		status, err := fileServer.ServeHTTP(w, r)
+		if err != nil {
+			t.Errorf("Test %d: Expected no error, got: %v", i, err)
+		}
+		if status == http.StatusOK {
+			status = w.Code
+		}
+		if status != test.expectedStatus {
+			t.Errorf("Test %d: Expected status %d, got %d", i, test.expectedStatus, status)
+		}
+		if test.expectedBody != "" && w.Body.String() != test.expectedBody {
+			t.Errorf("Test %d: Expected body %q, got %q", i, test.expectedBody, w.Body.String())
+		}
+		if test.expectedETag != "" && w.Header().Get("ETag") != test.expectedETag {
+			t.Errorf("Test %d: Expected ETag %s, got %s", i, test.expectedETag, w.Header().Get("ETag"))
+		}
+		for name, value := range test.expectedHeaders {
+			if got := w.Header().Get(name); got != value {
+				t.Errorf("Test %d: Expected header %s: %s, got %s", i, name, value, got)
+			}
+		}
+	}
+}
+
+func TestServeFSConditional(t *testing.T) {
+	fileServer := FileServer{Root: FS(fstest.MapFS{
+		"file.txt": {Data: []byte("0123456789")},
+	})}
+
+	r, err := http.NewRequest("GET", "/file.txt", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	fileServer.ServeHTTP(w, r)
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("Expected an ETag for a file without modification time")
+	}
+
+	r.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	fileServer.ServeHTTP(w, r)
+	if w.Code != http.StatusNotModified {
+		t.Errorf("Expected status %d for a matching ETag, got %d", http.StatusNotModified, w.Code)
+	}
+}
+
+func TestServeFSContentETagCached(t *testing.T) {
+	fsys := fstest.MapFS{"file.txt": {Data: []byte("0123456789")}}
+	fileServer := FileServer{Root: FS(fsys)}
+
+	serve := func() string {
+		r, err := http.NewRequest("GET", "/file.txt", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		fileServer.ServeHTTP(w, r)
+		return w.Header().Get("ETag")
+	}
+	etag := serve()
+
+	// the content is not hashed again, so a change of the same
+	// size goes unnoticed; embedded files don't change
+	fsys["file.txt"].Data = []byte("9876543210")
+	if got := serve(); got != etag {
+		t.Errorf("Expected the ETag %s to be kept, got %s", etag, got)
+	}
+
+	// another file system has ETags of its own
+	fileServer.Root = FS(fstest.MapFS{"file.txt": {Data: []byte("9876543210")}})
+	if got := serve(); got == etag {
+		t.Errorf("Expected another ETag for other content, got %s", got)
+	}
+}
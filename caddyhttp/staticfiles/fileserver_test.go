@@ -2,6 +2,10 @@ package staticfiles
 
 import (
 	"errors"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -30,6 +34,12 @@ var (
 // '---- dir/
 // '------ file2.html
 // '------ hidden.html
+// '---- multiindex/
+// '------ index.htm
+// '------ default.html
+// '---- idxdir/
+// '------ index.html/
+// '------ home.html
 var testFiles = map[string]string{
 	"unreachable.html":                                     "<h1>must not leak</h1>",
 	filepath.Join("webroot", "file1.html"):                 "<h1>file1.html</h1>",
@@ -42,6 +52,10 @@ var testFiles = map[string]string{
 	filepath.Join("webroot", "dirwithindex", "index.html"): "<h1>dirwithindex/index.html</h1>",
 	filepath.Join("webroot", "dir", "file2.html"):          "<h1>dir/file2.html</h1>",
 	filepath.Join("webroot", "dir", "hidden.html"):         "<h1>dir/hidden.html</h1>",
+	filepath.Join("webroot", "multiindex", "index.htm"):    "<h1>multiindex/index.htm</h1>",
+	filepath.Join("webroot", "multiindex", "default.html"): "<h1>multiindex/default.html</h1>",
+	filepath.Join("webroot", "idxdir", "index.html", "a"):  "not an index file",
+	filepath.Join("webroot", "idxdir", "home.html"):        "<h1>idxdir/home.html</h1>",
 }
 
 // TestServeHTTP covers positive scenarios when serving files.
@@ -219,7 +233,445 @@ func TestServeHTTP(t *testing.T) {
 
 }
 
+// TestServeHTTPPrecompressed covers the negotiation of precompressed files.
+func TestServeHTTPPrecompressed(t *testing.T) {
+	beforeServeHTTPTest(t)
+	defer afterServeHTTPTest(t)
+
+	tests := []struct {
+		url              string
+		acceptEncoding   string
+		precompressed    []string
+		expectedBody     string
+		expectedEncoding string
+	}{
+		// br is preferred when both are accepted
+		{"/sub/brotli.html", "gzip, br", nil, "brotli.html.br", "br"},
+		// gzip is the fallback for clients without br
+		{"/sub/brotli.html", "gzip, deflate", nil, "brotli.html.gz", "gzip"},
+		{"/sub/brotli.html", "br;q=0, gzip", nil, "brotli.html.gz", "gzip"},
+		{"/sub/brotli.html", "*", nil, "brotli.html.br", "br"},
+		{"/sub/brotli.html", "*, br;q=0", nil, "brotli.html.gz", "gzip"},
+		// the uncompressed file when there is no acceptable variant
+		{"/sub/gzipped.html", "br", nil, "<h1>gzipped.html</h1>", ""},
+		{"/sub/gzipped.html", "gzip;q=0", nil, "<h1>gzipped.html</h1>", ""},
+		{"/sub/brotli.html", "", nil, "brotli.html", ""},
+		{"/file1.html", "br, gzip", nil, "<h1>file1.html</h1>", ""},
+		// configured encodings only
+		{"/sub/brotli.html", "br, gzip", []string{"gzip"}, "brotli.html.gz", "gzip"},
+		{"/sub/brotli.html", "br, gzip", []string{}, "brotli.html", ""},
+	}
+
+	for i, test := range tests {
+		fileserver := FileServer{Root: http.Dir(testWebRoot), Precompressed: test.precompressed}
+		request, err := http.NewRequest("GET", "https://foo"+test.url, nil)
+		if err != nil {
+			t.Fatalf("Test %d: Error making request: %v", i, err)
+		}
+		if test.acceptEncoding != "" {
+			request.Header.Set("Accept-Encoding", test.acceptEncoding)
+		}
+		responseRecorder := httptest.NewRecorder()
+
+		if _, err := fileserver.ServeHTTP(responseRecorder, request); err != nil {
+			t.Errorf("Test %d: Serving file at %s failed. Error was: %v", i, test.url, err)
+		}
+
+		if body := responseRecorder.Body.String(); body != test.expectedBody {
+			t.Errorf("Test %d: Expected body %q, found %q", i, test.expectedBody, body)
+		}
+		if encoding := responseRecorder.Header().Get("Content-Encoding"); encoding != test.expectedEncoding {
+			t.Errorf("Test %d: Expected Content-Encoding %q, found %q", i, test.expectedEncoding, encoding)
+		}
+		if ctype := responseRecorder.Header().Get("Content-Type"); !strings.HasPrefix(ctype, "text/html") {
+			t.Errorf("Test %d: Expected Content-Type of the original file, found %q", i, ctype)
+		}
+		if vary := responseRecorder.Header().Get("Vary"); test.expectedEncoding != "" && vary != "Accept-Encoding" {
+			t.Errorf("Test %d: Expected Vary: Accept-Encoding, found %q", i, vary)
+		}
+	}
+}
+
+func TestServeHTTPMimeTypes(t *testing.T) {
+	beforeServeHTTPTest(t)
+	defer afterServeHTTPTest(t)
+
+	for name, content := range map[string]string{
+		"app.wasm":         "\x00asm",
+		"app.wasm.gz":      "app.wasm.gz",
+		"data.custom":      "custom",
+		"backup.tar.gz":    "backup",
+		"archive.gz":       "archive",
+		"site.webmanifest": "{}",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(testWebRoot, name), []byte(content), 0640); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mimeTypes := map[string]string{
+		".wasm":        "application/wasm",
+		".custom":      "application/x-custom",
+		".tar.gz":      "application/x-gtar",
+		".gz":          "application/gzip",
+		".webmanifest": "application/manifest+json",
+	}
+
+	for i, test := range []struct {
+		url              string
+		acceptEncoding   string
+		expectedType     string
+		expectedEncoding string
+	}{
+		{"/app.wasm", "", "application/wasm", ""},
+		{"/data.custom", "", "application/x-custom", ""},
+		{"/site.webmanifest", "", "application/manifest+json", ""},
+		// the longest extension wins
+		{"/backup.tar.gz", "", "application/x-gtar", ""},
+		{"/archive.gz", "", "application/gzip", ""},
+		// precompressed variants are described as the original file
+		{"/app.wasm", "gzip", "application/wasm", "gzip"},
+		// files without an override are unaffected
+		{"/file1.html", "", "text/html; charset=utf-8", ""},
+	} {
+		fileserver := FileServer{Root: http.Dir(testWebRoot), MimeTypes: mimeTypes}
+		request, err := http.NewRequest("GET", "https://foo"+test.url, nil)
+		if err != nil {
+			t.Fatalf("Test %d: Could not create HTTP request: %v", i, err)
+		}
+		if test.acceptEncoding != "" {
+			request.Header.Set("Accept-Encoding", test.acceptEncoding)
+		}
+		responseRecorder := httptest.NewRecorder()
+
+		if _, err := fileserver.ServeHTTP(responseRecorder, request); err != nil {
+			t.Errorf("Test %d: Serving file at %s failed. Error was: %v", i, test.url, err)
+		}
+		if ctype := responseRecorder.Header().Get("Content-Type"); ctype != test.expectedType {
+			t.Errorf("Test %d: Expected Content-Type %q for %s, found %q", i, test.expectedType, test.url, ctype)
+		}
+		if encoding := responseRecorder.Header().Get("Content-Encoding"); encoding != test.expectedEncoding {
+			t.Errorf("Test %d: Expected Content-Encoding %q, found %q", i, test.expectedEncoding, encoding)
+		}
+	}
+}
+
+func TestTypeByExtension(t *testing.T) {
+	types := map[string]string{
+		".gz":     "application/gzip",
+		".tar.gz": "application/x-gtar",
+		".wasm":   "application/wasm",
+	}
+	for i, test := range []struct {
+		name     string
+		expected string
+	}{
+		{"a.wasm", "application/wasm"},
+		{"/dir.d/a.WASM", "application/wasm"},
+		{"a.tar.gz", "application/x-gtar"},
+		{"a.b.tar.gz", "application/x-gtar"},
+		{"a.gz", "application/gzip"},
+		{"a.tar", ""},
+		{"wasm", ""},
+		{"/dir.wasm/a", ""},
+	} {
+		if got := TypeByExtension(types, test.name); got != test.expected {
+			t.Errorf("Test %d: Expected %q for %s, got %q", i, test.expected, test.name, got)
+		}
+	}
+}
+
+func TestParseQValue(t *testing.T) {
+	for i, test := range []struct {
+		element       string
+		expectedValue string
+		expectedQ     float64
+		expectedOK    bool
+	}{
+		{"gzip", "gzip", 1, true},
+		{" GZIP ; q=0.5", "gzip", 0.5, true},
+		{"de;Q= 0", "de", 0, true},
+		{"en-US;level=1;q=0.8", "en-us", 0.8, true},
+		{"br;q=2", "br", 0, false},
+		{"br;q=-1", "br", 0, false},
+		{"br;q=high", "br", 0, false},
+		{"", "", 1, true},
+	} {
+		value, q, ok := ParseQValue(test.element)
+		if value != test.expectedValue || q != test.expectedQ || ok != test.expectedOK {
+			t.Errorf("Test %d: Expected %q, %v, %v for %q, got %q, %v, %v",
+				i, test.expectedValue, test.expectedQ, test.expectedOK, test.element, value, q, ok)
+		}
+	}
+}
+
+// TestServeHTTPIndexPages covers the negotiation of directory index files.
+func TestServeHTTPIndexPages(t *testing.T) {
+	beforeServeHTTPTest(t)
+	defer afterServeHTTPTest(t)
+
+	tests := []struct {
+		url            string
+		indexPages     []string
+		expectedStatus int
+		expectedFile   string
+	}{
+		// first match
+		{"/multiindex/", []string{"default.html", "index.htm"}, http.StatusOK, filepath.Join("webroot", "multiindex", "default.html")},
+		// fallback to the second
+		{"/multiindex/", []string{"index.html", "index.htm", "default.html"}, http.StatusOK, filepath.Join("webroot", "multiindex", "index.htm")},
+		// the defaults
+		{"/multiindex/", nil, http.StatusOK, filepath.Join("webroot", "multiindex", "index.htm")},
+		// directories are not index files
+		{"/idxdir/", []string{"index.html", "home.html"}, http.StatusOK, filepath.Join("webroot", "idxdir", "home.html")},
+		// none present
+		{"/dir/", []string{"index.html", "index.htm"}, http.StatusNotFound, ""},
+		{"/dirwithindex/", []string{"default.html"}, http.StatusNotFound, ""},
+		{"/multiindex/", []string{}, http.StatusNotFound, ""},
+	}
+
+	for i, test := range tests {
+		fileserver := FileServer{Root: http.Dir(testWebRoot), IndexPages: test.indexPages}
+		request, err := http.NewRequest("GET", "https://foo"+test.url, nil)
+		if err != nil {
+			t.Fatalf("Test %d: Error making request: %v", i, err)
+		}
+		responseRecorder := httptest.NewRecorder()
+
+		status, err := fileserver.ServeHTTP(responseRecorder, request)
+		if err != nil {
+			t.Errorf("Test %d: Serving file at %s failed. Error was: %v", i, test.url, err)
+		}
+		if status != test.expectedStatus {
+			t.Errorf("Test %d: Expected status %d, found %d", i, test.expectedStatus, status)
+		}
+		if test.expectedFile == "" {
+			continue
+		}
+		if body := responseRecorder.Body.String(); body != testFiles[test.expectedFile] {
+			t.Errorf("Test %d: Expected body %q, found %q", i, testFiles[test.expectedFile], body)
+		}
+
+		// the index file must be served like a direct request for it
+		fileURL := "https://foo/" + filepath.ToSlash(strings.TrimPrefix(test.expectedFile, "webroot"+string(filepath.Separator)))
+		direct, err := http.NewRequest("GET", fileURL, nil)
+		if err != nil {
+			t.Fatalf("Test %d: Error making request: %v", i, err)
+		}
+		directRecorder := httptest.NewRecorder()
+		fileserver.ServeHTTP(directRecorder, direct)
+		for _, header := range []string{"Etag", "Last-Modified", "Content-Type"} {
+			if got, want := responseRecorder.Header().Get(header), directRecorder.Header().Get(header); got != want || got == "" {
+				t.Errorf("Test %d: Expected %s %q like the direct request, found %q", i, header, want, got)
+			}
+		}
+	}
+}
+
 // beforeServeHTTPTest creates a test directory with the structure, defined in the variable testFiles
+func TestServeHTTPHiddenPaths(t *testing.T) {
+	beforeServeHTTPTest(t)
+	defer afterServeHTTPTest(t)
+
+	for name, content := range map[string]string{
+		".env":                             "SECRET=1",
+		filepath.Join(".git", "config"):    "[core]",
+		filepath.Join("dir", ".htaccess"):  "Deny from all",
+		filepath.Join("docs", ".htaccess"): "Options -Indexes",
+		filepath.Join("dir", "notes.bak"):  "old notes",
+	} {
+		filename := filepath.Join(testWebRoot, name)
+		if err := os.MkdirAll(filepath.Dir(filename), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filename, []byte(content), 0640); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i, test := range []struct {
+		patterns       []string
+		exceptions     []string
+		url            string
+		expectedStatus int
+	}{
+		// default patterns
+		{nil, nil, "/.env", http.StatusNotFound},
+		{nil, nil, "/.git/config", http.StatusNotFound},
+		{nil, nil, "/.git/", http.StatusNotFound},
+		{nil, nil, "/dir/.htaccess", http.StatusNotFound},
+		{nil, nil, "/.GIT/config", http.StatusNotFound},
+		{nil, nil, "/dir/notes.bak", http.StatusOK},
+		{nil, nil, "/file1.html", http.StatusOK},
+		// exceptions
+		{nil, []string{"/docs"}, "/docs/.htaccess", http.StatusOK},
+		{nil, []string{"/docs"}, "/dir/.htaccess", http.StatusNotFound},
+		{nil, []string{"/.env"}, "/.env", http.StatusOK},
+		// configured patterns
+		{[]string{"*.bak"}, nil, "/dir/notes.bak", http.StatusNotFound},
+		{[]string{"*.bak"}, nil, "/.env", http.StatusOK},
+		{[]string{}, nil, "/.git/config", http.StatusOK},
+	} {
+		fileserver := FileServer{
+			Root:             http.Dir(testWebRoot),
+			HiddenPatterns:   test.patterns,
+			HiddenExceptions: test.exceptions,
+		}
+		request, err := http.NewRequest("GET", "https://foo"+test.url, nil)
+		if err != nil {
+			t.Fatalf("Test %d: Could not create HTTP request: %v", i, err)
+		}
+		status, _ := fileserver.ServeHTTP(httptest.NewRecorder(), request)
+		if status != test.expectedStatus {
+			t.Errorf("Test %d: Expected status %d for %s, found %d", i, test.expectedStatus, test.url, status)
+		}
+	}
+}
+
+func TestServeHTTPTrailingSlash(t *testing.T) {
+	beforeServeHTTPTest(t)
+	defer afterServeHTTPTest(t)
+
+	for i, test := range []struct {
+		mode             string
+		url              string
+		originalURI      string // as set by rewrite
+		expectedStatus   int
+		expectedLocation string
+	}{
+		// add, also the default
+		{"", "/dirwithindex", "", http.StatusMovedPermanently, "/dirwithindex/"},
+		{"add", "/dirwithindex", "", http.StatusMovedPermanently, "/dirwithindex/"},
+		{"add", "/dirwithindex?a=1&b=2", "", http.StatusMovedPermanently, "/dirwithindex/?a=1&b=2"},
+		{"add", "/dirwithindex/", "", http.StatusOK, ""},
+		{"add", "/file1.html/", "", http.StatusMovedPermanently, "/file1.html"},
+		{"add", "/file1.html", "", http.StatusOK, ""},
+		{"add", "/", "", http.StatusNotFound, ""}, // no index file, but no redirect
+		// remove
+		{"remove", "/dirwithindex/", "", http.StatusMovedPermanently, "/dirwithindex"},
+		{"remove", "/dirwithindex/?a=1", "", http.StatusMovedPermanently, "/dirwithindex?a=1"},
+		{"remove", "/dirwithindex", "", http.StatusOK, ""},
+		{"remove", "/file1.html/", "", http.StatusMovedPermanently, "/file1.html"},
+		{"remove", "/", "", http.StatusNotFound, ""},
+		// off
+		{"off", "/dirwithindex", "", http.StatusOK, ""},
+		{"off", "/dirwithindex/", "", http.StatusOK, ""},
+		{"off", "/", "", http.StatusNotFound, ""},
+		// rewritten requests redirect the path the client requested...
+		{"add", "/dirwithindex", "/docs?v=2", http.StatusMovedPermanently, "/docs/?v=2"},
+		{"remove", "/dirwithindex/", "/docs/", http.StatusMovedPermanently, "/docs"},
+		{"add", "/dirwithindex", "//example.com", http.StatusMovedPermanently, "/example.com/"},
+		// ...unless it has the wanted form, which would loop
+		{"add", "/dirwithindex", "/docs/", http.StatusOK, ""},
+		{"remove", "/dirwithindex/", "/docs", http.StatusOK, ""},
+		{"remove", "/", "/docs/", http.StatusNotFound, ""},
+	} {
+		fileserver := FileServer{Root: http.Dir(testWebRoot), TrailingSlash: test.mode}
+		request, err := http.NewRequest("GET", "https://foo"+test.url, nil)
+		if err != nil {
+			t.Fatalf("Test %d: Could not create HTTP request: %v", i, err)
+		}
+		if test.originalURI != "" {
+			request.Header.Set("Caddy-Rewrite-Original-URI", test.originalURI)
+		}
+		responseRecorder := httptest.NewRecorder()
+
+		status, err := fileserver.ServeHTTP(responseRecorder, request)
+		if err != nil {
+			t.Errorf("Test %d: Serving %s failed. Error was: %v", i, test.url, err)
+		}
+		if status != test.expectedStatus {
+			t.Errorf("Test %d: Expected status %d for %s in mode %q, found %d", i, test.expectedStatus, test.url, test.mode, status)
+		}
+		if location := responseRecorder.Header().Get("Location"); location != test.expectedLocation {
+			t.Errorf("Test %d: Expected Location %q, found %q", i, test.expectedLocation, location)
+		}
+	}
+}
+
+func TestServeHTTPRanges(t *testing.T) {
+	beforeServeHTTPTest(t)
+	defer afterServeHTTPTest(t)
+
+	fileserver := FileServer{Root: http.Dir(testWebRoot)}
+
+	type part struct {
+		contentRange string
+		body         string
+	}
+
+	for i, test := range []struct {
+		rangeHeader          string
+		expectedStatus       int
+		expectedContentRange string // of a single range
+		expectedBody         string // of a single range
+		expectedParts        []part // of multiple ranges
+	}{
+		{"bytes=0-3", http.StatusPartialContent, "bytes 0-3/19", "<h1>", nil},
+		{"bytes=4-100", http.StatusPartialContent, "bytes 4-18/19", "file1.html</h1>", nil},
+		{"bytes=-5", http.StatusPartialContent, "bytes 14-18/19", "</h1>", nil},
+		{"bytes=0-3,14-18", http.StatusPartialContent, "", "", []part{
+			{"bytes 0-3/19", "<h1>"},
+			{"bytes 14-18/19", "</h1>"},
+		}},
+		// served in the order requested, overlapping or not
+		{"bytes=14-18,0-3,2-5", http.StatusPartialContent, "", "", []part{
+			{"bytes 14-18/19", "</h1>"},
+			{"bytes 0-3/19", "<h1>"},
+			{"bytes 2-5/19", "1>fi"},
+		}},
+		{"bytes=50-60", http.StatusRequestedRangeNotSatisfiable, "bytes */19", "", nil},
+	} {
+		request, err := http.NewRequest("GET", "https://foo/file1.html", nil)
+		if err != nil {
+			t.Fatalf("Test %d: Could not create HTTP request: %v", i, err)
+		}
+		request.Header.Set("Range", test.rangeHeader)
+		responseRecorder := httptest.NewRecorder()
+		fileserver.ServeHTTP(responseRecorder, request)
+
+		if responseRecorder.Code != test.expectedStatus {
+			t.Errorf("Test %d: Expected status %d, found %d", i, test.expectedStatus, responseRecorder.Code)
+		}
+		if got := responseRecorder.Header().Get("Content-Range"); got != test.expectedContentRange {
+			t.Errorf("Test %d: Expected Content-Range %q, found %q", i, test.expectedContentRange, got)
+		}
+		if test.expectedParts == nil {
+			if test.expectedBody != "" && responseRecorder.Body.String() != test.expectedBody {
+				t.Errorf("Test %d: Expected body %q, found %q", i, test.expectedBody, responseRecorder.Body.String())
+			}
+			continue
+		}
+
+		mediaType, params, err := mime.ParseMediaType(responseRecorder.Header().Get("Content-Type"))
+		if err != nil || mediaType != "multipart/byteranges" || params["boundary"] == "" {
+			t.Fatalf("Test %d: Expected multipart/byteranges with a boundary, found %q", i, responseRecorder.Header().Get("Content-Type"))
+		}
+		reader := multipart.NewReader(responseRecorder.Body, params["boundary"])
+		for j, expected := range test.expectedParts {
+			p, err := reader.NextPart()
+			if err != nil {
+				t.Fatalf("Test %d: Expected part %d, got error: %v", i, j, err)
+			}
+			if got := p.Header.Get("Content-Range"); got != expected.contentRange {
+				t.Errorf("Test %d: Expected Content-Range %q of part %d, found %q", i, expected.contentRange, j, got)
+			}
+			if got := p.Header.Get("Content-Type"); !strings.HasPrefix(got, "text/html") {
+				t.Errorf("Test %d: Expected Content-Type of the file in part %d, found %q", i, j, got)
+			}
+			body, err := ioutil.ReadAll(p)
+			if err != nil {
+				t.Fatalf("Test %d: Reading part %d: %v", i, j, err)
+			}
+			if string(body) != expected.body {
+				t.Errorf("Test %d: Expected body %q of part %d, found %q", i, expected.body, j, body)
+			}
+		}
+		if _, err := reader.NextPart(); err != io.EOF {
+			t.Errorf("Test %d: Expected %d parts, got more (err: %v)", i, len(test.expectedParts), err)
+		}
+	}
+}
+
 func beforeServeHTTPTest(t *testing.T) {
 	// make the root test dir
 	err := os.MkdirAll(testWebRoot, os.ModePerm)
@@ -0,0 +1,61 @@
+package stripprefix
+
+import (
+	"strings"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func init() {
+	caddy.RegisterPlugin("strip_prefix", caddy.Plugin{
+		ServerType: "http",
+		Action:     setup,
+	})
+}
+
+// setup configures a new StripPrefix middleware instance.
+func setup(c *caddy.Controller) error {
+	s, err := stripPrefixParse(c)
+	if err != nil {
+		return err
+	}
+
+	httpserver.GetConfig(c).AddMiddleware(func(next httpserver.Handler) httpserver.Handler {
+		s.Next = next
+		return s
+	})
+
+	return nil
+}
+
+// stripPrefixParse parses the directive:
+//
+//	strip_prefix <prefix> [strict]
+func stripPrefixParse(c *caddy.Controller) (StripPrefix, error) {
+	var s StripPrefix
+
+	for c.Next() {
+		if s.Prefix != "" {
+			return s, c.Err("strip_prefix can only be specified once per site")
+		}
+		args := c.RemainingArgs()
+		switch len(args) {
+		case 2:
+			if args[1] != "strict" {
+				return s, c.Errf("unknown strip_prefix option '%s'", args[1])
+			}
+			s.Strict = true
+			fallthrough
+		case 1:
+			s.Prefix = args[0]
+		default:
+			return s, c.ArgErr()
+		}
+		if !strings.HasPrefix(s.Prefix, "/") || s.Prefix == "/" {
+			return s, c.Errf("prefix must be a path below /, got '%s'", s.Prefix)
+		}
+	}
+
+	return s, nil
+}
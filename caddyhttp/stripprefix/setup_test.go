@@ -0,0 +1,56 @@
+package stripprefix
+
+import (
+	"testing"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func TestSetup(t *testing.T) {
+	c := caddy.NewTestController("http", `strip_prefix /app`)
+	err := setup(c)
+	if err != nil {
+		t.Errorf("Expected no errors, got: %v", err)
+	}
+	mids := httpserver.GetConfig(c).Middleware()
+	if len(mids) == 0 {
+		t.Fatal("Expected middleware, got 0 instead")
+	}
+
+	handler := mids[0](httpserver.EmptyNext)
+	myHandler, ok := handler.(StripPrefix)
+	if !ok {
+		t.Fatalf("Expected handler to be type StripPrefix, got: %#v", handler)
+	}
+	if !httpserver.SameNext(myHandler.Next, httpserver.EmptyNext) {
+		t.Error("'Next' field of handler was not set properly")
+	}
+}
+
+func TestStripPrefixParse(t *testing.T) {
+	for i, test := range []struct {
+		input     string
+		shouldErr bool
+		expected  StripPrefix
+	}{
+		{`strip_prefix /app`, false, StripPrefix{Prefix: "/app"}},
+		{`strip_prefix /app/ strict`, false, StripPrefix{Prefix: "/app/", Strict: true}},
+		{`strip_prefix`, true, StripPrefix{}},
+		{`strip_prefix /`, true, StripPrefix{}},
+		{`strip_prefix app`, true, StripPrefix{}},
+		{`strip_prefix /app loose`, true, StripPrefix{}},
+		{`strip_prefix /app strict extra`, true, StripPrefix{}},
+		{"strip_prefix /a\nstrip_prefix /b", true, StripPrefix{}},
+	} {
+		actual, err := stripPrefixParse(caddy.NewTestController("http", test.input))
+		if err == nil && test.shouldErr {
+			t.Errorf("Test %d didn't error, but it should have", i)
+		} else if err != nil && !test.shouldErr {
+			t.Errorf("Test %d errored, but it shouldn't have; got '%v'", i, err)
+		}
+		if !test.shouldErr && actual != test.expected {
+			t.Errorf("Test %d: Expected %+v, got %+v", i, test.expected, actual)
+		}
+	}
+}
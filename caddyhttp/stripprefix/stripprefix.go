@@ -0,0 +1,83 @@
+// Package stripprefix is middleware that removes a leading path
+// prefix from requests, for sites whose backend or files are
+// mounted under a sub-path.
+package stripprefix
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+// StripPrefix is middleware that removes Prefix from the path of
+// requests. The prefix only matches whole path segments, so /app
+// is removed from /app and /app/x, but not from /application.
+// Requests that don't match are passed on unchanged, unless Strict
+// is set; then they are answered with 404 Not Found.
+type StripPrefix struct {
+	Next   httpserver.Handler
+	Prefix string
+	Strict bool
+}
+
+// ServeHTTP implements the httpserver.Handler interface.
+func (s StripPrefix) ServeHTTP(w http.ResponseWriter, r *http.Request) (int, error) {
+	prefix := strings.TrimSuffix(s.Prefix, "/")
+	rest, ok := stripPath(r.URL.Path, prefix)
+	if !ok {
+		if s.Strict {
+			return http.StatusNotFound, nil
+		}
+		return s.Next.ServeHTTP(w, r)
+	}
+
+	// take note of the original URI for internal use by
+	// fastcgi, unless a rewrite did so already
+	if r.Header.Get(originalURIHeader) == "" {
+		r.Header.Set(originalURIHeader, r.URL.RequestURI())
+	}
+
+	if r.URL.RawPath != "" {
+		r.URL.RawPath = stripRawPath(r.URL.RawPath, len(prefix))
+	}
+	r.URL.Path = rest
+	return s.Next.ServeHTTP(w, r)
+}
+
+// stripPath removes prefix, which has no trailing slash, from p.
+// It reports false if p doesn't start with the segments of prefix.
+func stripPath(p, prefix string) (string, bool) {
+	if !httpserver.Path(p).Matches(prefix) {
+		return p, false
+	}
+	rest := p[len(prefix):]
+	if rest == "" {
+		return "/", true
+	}
+	if rest[0] != '/' {
+		return p, false
+	}
+	return rest, true
+}
+
+// stripRawPath removes the escaped form of the first n bytes of
+// the unescaped path from rawPath, the escaped form of the path.
+func stripRawPath(rawPath string, n int) string {
+	i := 0
+	for ; n > 0 && i < len(rawPath); n-- {
+		if rawPath[i] == '%' && i+2 < len(rawPath) {
+			i += 3
+		} else {
+			i++
+		}
+	}
+	if i >= len(rawPath) {
+		return "/"
+	}
+	return rawPath[i:]
+}
+
+// originalURIHeader is the header in which rewrite takes note
+// of the original request URI.
+const originalURIHeader = "Caddy-Rewrite-Original-URI"
@@ -0,0 +1,58 @@
+package stripprefix
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func TestStripPrefix(t *testing.T) {
+	var uri string
+	next := httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+		uri = r.URL.RequestURI()
+		return http.StatusOK, nil
+	})
+
+	for i, test := range []struct {
+		prefix   string
+		strict   bool
+		url      string
+		status   int
+		expected string
+	}{
+		{"/app", false, "/app/index.html", http.StatusOK, "/index.html"},
+		{"/app/", false, "/app/a/b?x=1&y=2", http.StatusOK, "/a/b?x=1&y=2"},
+		{"/app", false, "/app", http.StatusOK, "/"},
+		{"/app", false, "/app?x=1", http.StatusOK, "/?x=1"},
+		{"/app", false, "/app/", http.StatusOK, "/"},
+		{"/app", false, "/application", http.StatusOK, "/application"},
+		{"/app", false, "/other/app", http.StatusOK, "/other/app"},
+		{"/app", true, "/app/x", http.StatusOK, "/x"},
+		{"/app", true, "/application", http.StatusNotFound, ""},
+		{"/app", true, "/other", http.StatusNotFound, ""},
+		// escaped path segments are kept escaped
+		{"/app", false, "/app/a%2Fb/c", http.StatusOK, "/a%2Fb/c"},
+		{"/my app", false, "/my%20app/a%2Fb", http.StatusOK, "/a%2Fb"},
+		{"/app", false, "/app/%2F", http.StatusOK, "/%2F"},
+	} {
+		uri = ""
+		s := StripPrefix{Next: next, Prefix: test.prefix, Strict: test.strict}
+		req, err := http.NewRequest("GET", test.url, nil)
+		if err != nil {
+			t.Fatalf("Test %d: Could not create HTTP request: %v", i, err)
+		}
+
+		status, err := s.ServeHTTP(httptest.NewRecorder(), req)
+		if err != nil {
+			t.Fatalf("Test %d: Could not ServeHTTP: %v", i, err)
+		}
+		if status != test.status {
+			t.Errorf("Test %d: Expected status %d, got %d", i, test.status, status)
+		}
+		if uri != test.expected {
+			t.Errorf("Test %d: Expected next handler to get %s, got %s", i, test.expected, uri)
+		}
+	}
+}
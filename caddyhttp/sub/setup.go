@@ -0,0 +1,120 @@
+package sub
+
+import (
+	"mime"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func init() {
+	caddy.RegisterPlugin("sub", caddy.Plugin{
+		ServerType: "http",
+		Action:     setup,
+	})
+}
+
+// setup configures a new Sub middleware instance.
+func setup(c *caddy.Controller) error {
+	s, err := subParse(c)
+	if err != nil {
+		return err
+	}
+
+	httpserver.GetConfig(c).AddMiddleware(func(next httpserver.Handler) httpserver.Handler {
+		s.Next = next
+		return s
+	})
+
+	return nil
+}
+
+// subParse parses the directive:
+//
+//	sub [paths...] {
+//		replace  <search> <replacement>
+//		regex    <pattern> <replacement>
+//		types    <media types...>
+//		max_size <bytes>
+//	}
+//
+// Rules are applied in the order they are given. Only text/html
+// responses are changed by default.
+func subParse(c *caddy.Controller) (Sub, error) {
+	s := Sub{MaxSize: defaultMaxSize}
+
+	for c.Next() {
+		paths := c.RemainingArgs()
+		if len(paths) == 0 {
+			paths = []string{"/"}
+		}
+		s.Paths = append(s.Paths, paths...)
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "replace", "regex":
+				kind := c.Val()
+				args := c.RemainingArgs()
+				if len(args) != 2 {
+					return s, c.ArgErr()
+				}
+				rule := Rule{Search: args[0], Replacement: args[1]}
+				if rule.Search == "" {
+					return s, c.Errf("%s needs something to search for", kind)
+				}
+				if kind == "regex" {
+					re, err := regexp.Compile(rule.Search)
+					if err != nil {
+						return s, c.Errf("invalid regular expression '%s': %v", rule.Search, err)
+					}
+					rule.Search, rule.Regexp = "", re
+				}
+				s.Rules = append(s.Rules, rule)
+			case "types":
+				types := c.RemainingArgs()
+				if len(types) == 0 {
+					return s, c.ArgErr()
+				}
+				for _, t := range types {
+					if !strings.HasSuffix(t, "/*") {
+						mediaType, _, err := mime.ParseMediaType(t)
+						if err != nil || !strings.Contains(mediaType, "/") {
+							return s, c.Errf("invalid media type '%s'", t)
+						}
+						t = mediaType
+					}
+					s.Types = append(s.Types, strings.ToLower(t))
+				}
+			case "max_size":
+				if !c.NextArg() {
+					return s, c.ArgErr()
+				}
+				size, err := strconv.Atoi(c.Val())
+				if err != nil || size < 1 {
+					return s, c.Errf("max_size must be a positive number of bytes, got '%s'", c.Val())
+				}
+				s.MaxSize = size
+				if c.NextArg() {
+					return s, c.ArgErr()
+				}
+			default:
+				return s, c.Errf("unknown sub property '%s'", c.Val())
+			}
+		}
+	}
+
+	if len(s.Rules) == 0 {
+		return s, c.Err("sub needs at least one replace or regex rule")
+	}
+	if len(s.Types) == 0 {
+		s.Types = []string{"text/html"}
+	}
+
+	return s, nil
+}
+
+// defaultMaxSize is the largest body that is changed by default.
+const defaultMaxSize = 1 << 20
@@ -0,0 +1,93 @@
+package sub
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func TestSetup(t *testing.T) {
+	c := caddy.NewTestController("http", `sub {
+		replace http:// https://
+	}`)
+	err := setup(c)
+	if err != nil {
+		t.Errorf("Expected no errors, got: %v", err)
+	}
+	mids := httpserver.GetConfig(c).Middleware()
+	if len(mids) == 0 {
+		t.Fatal("Expected middleware, got 0 instead")
+	}
+
+	handler := mids[0](httpserver.EmptyNext)
+	myHandler, ok := handler.(Sub)
+	if !ok {
+		t.Fatalf("Expected handler to be type Sub, got: %#v", handler)
+	}
+	if !httpserver.SameNext(myHandler.Next, httpserver.EmptyNext) {
+		t.Error("'Next' field of handler was not set properly")
+	}
+}
+
+func TestSubParse(t *testing.T) {
+	for i, test := range []struct {
+		input     string
+		shouldErr bool
+		expected  Sub
+	}{
+		{`sub {
+			replace http://example.com/ /
+		  }`, false, Sub{Paths: []string{"/"}, Rules: []Rule{{Search: "http://example.com/", Replacement: "/"}},
+			Types: []string{"text/html"}, MaxSize: defaultMaxSize}},
+		{`sub /blog /docs {
+			regex "</(body)>" "<p>footer</p></$1>"
+			replace a ""
+			types text/* application/JSON;charset=utf-8
+			max_size 4096
+		  }`, false, Sub{Paths: []string{"/blog", "/docs"},
+			Rules: []Rule{
+				{Regexp: regexp.MustCompile("</(body)>"), Replacement: "<p>footer</p></$1>"},
+				{Search: "a", Replacement: ""},
+			},
+			Types: []string{"text/*", "application/json"}, MaxSize: 4096}},
+		{`sub`, true, Sub{}},
+		{`sub {
+			replace a
+		  }`, true, Sub{}},
+		{`sub {
+			replace "" a
+		  }`, true, Sub{}},
+		{`sub {
+			regex ( a
+		  }`, true, Sub{}},
+		{`sub {
+			replace a b
+			types
+		  }`, true, Sub{}},
+		{`sub {
+			replace a b
+			types html
+		  }`, true, Sub{}},
+		{`sub {
+			replace a b
+			max_size 0
+		  }`, true, Sub{}},
+		{`sub {
+			replace a b
+			once
+		  }`, true, Sub{}},
+	} {
+		actual, err := subParse(caddy.NewTestController("http", test.input))
+		if err == nil && test.shouldErr {
+			t.Errorf("Test %d didn't error, but it should have", i)
+		} else if err != nil && !test.shouldErr {
+			t.Errorf("Test %d errored, but it shouldn't have; got '%v'", i, err)
+		}
+		if !test.shouldErr && !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("Test %d: Expected %+v, got %+v", i, test.expected, actual)
+		}
+	}
+}
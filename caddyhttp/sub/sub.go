@@ -0,0 +1,120 @@
+// Package sub implements middleware that substitutes strings in the
+// bodies of responses, to rewrite absolute URLs or inject a snippet
+// into pages without changing the application that serves them.
+package sub
+
+import (
+	"bytes"
+	"mime"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+// Sub is middleware that applies substitution rules to the
+// bodies of responses under certain paths. Only complete (200 OK),
+// uncompressed responses of the configured media types are changed;
+// responses larger than MaxSize are sent unchanged. Range requests
+// under the paths are answered with the whole response, since the
+// ranges of the response before it is changed don't make sense.
+type Sub struct {
+	Next  httpserver.Handler
+	Paths []string
+	Rules []Rule
+
+	// Types are the media types of the responses to change, such
+	// as text/html; a type like text/* matches all text types.
+	Types []string
+
+	// MaxSize is the largest body that is held back
+	// to be changed.
+	MaxSize int
+}
+
+// Rule replaces every occurrence of Search, or every match of
+// Regexp if it is set, with Replacement. In the replacement of a
+// regular expression, $1 stands for the first submatch, and so on.
+type Rule struct {
+	Search      string
+	Regexp      *regexp.Regexp
+	Replacement string
+}
+
+// Apply returns body with the rule applied to it.
+func (rule Rule) Apply(body []byte) []byte {
+	if rule.Regexp != nil {
+		return rule.Regexp.ReplaceAll(body, []byte(rule.Replacement))
+	}
+	return bytes.Replace(body, []byte(rule.Search), []byte(rule.Replacement), -1)
+}
+
+// ServeHTTP implements the httpserver.Handler interface.
+func (s Sub) ServeHTTP(w http.ResponseWriter, r *http.Request) (int, error) {
+	if !s.matches(r.URL.Path) {
+		return s.Next.ServeHTTP(w, r)
+	}
+
+	r.Header.Del("Range")
+	r.Header.Del("If-Range")
+
+	rr := httpserver.NewBufferedResponseRecorder(w)
+	rr.SetBufferLimit(s.MaxSize)
+	status, err := s.Next.ServeHTTP(rr, r)
+	if !rr.Buffered() {
+		// streamed, or too big to change
+		return status, err
+	}
+
+	header := rr.Header()
+	if rr.Status() == http.StatusOK && status < 400 && rr.Buffer().Len() > 0 &&
+		header.Get("Content-Encoding") == "" && s.changesType(header.Get("Content-Type")) {
+		body := rr.Buffer().Bytes()
+		for _, rule := range s.Rules {
+			body = rule.Apply(body)
+		}
+		if header.Get("Content-Length") != "" {
+			header.Set("Content-Length", strconv.Itoa(len(body)))
+		}
+		if etag := header.Get("ETag"); strings.HasPrefix(etag, `"`) {
+			// the body is equivalent, but no longer the same
+			header.Set("ETag", "W/"+etag)
+		}
+		header.Del("Accept-Ranges")
+		rr.Buffer().Reset()
+		rr.Buffer().Write(body)
+	}
+
+	if relErr := rr.Release(); relErr != nil && err == nil {
+		err = relErr
+	}
+	return status, err
+}
+
+// matches reports whether responses to requests for path
+// are changed.
+func (s Sub) matches(path string) bool {
+	for _, p := range s.Paths {
+		if httpserver.Path(path).Matches(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// changesType reports whether responses with the Content-Type
+// header value contentType are changed.
+func (s Sub) changesType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, t := range s.Types {
+		if t == mediaType || strings.HasSuffix(t, "/*") && strings.HasPrefix(mediaType, t[:len(t)-1]) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,136 @@
+package sub
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func TestSub(t *testing.T) {
+	page := func(status int, body string, header http.Header) httpserver.Handler {
+		return httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+			for name, values := range header {
+				w.Header()[name] = values
+			}
+			w.WriteHeader(status)
+			w.Write([]byte(body))
+			return 0, nil
+		})
+	}
+	html := http.Header{"Content-Type": {"text/html; charset=utf-8"}}
+	rules := []Rule{
+		{Search: "http://example.com/", Replacement: "https://example.com/"},
+		{Regexp: regexp.MustCompile(`</(body)>`), Replacement: "<script src=\"/x.js\"></script></$1>"},
+	}
+
+	for i, test := range []struct {
+		next         httpserver.Handler
+		types        []string
+		maxSize      int
+		expectedBody string
+		expectedLen  string
+	}{
+		{page(http.StatusOK, `<a href="http://example.com/a">a</a></body>`, html), []string{"text/html"}, 1024,
+			`<a href="https://example.com/a">a</a><script src="/x.js"></script></body>`, ""},
+		// the length is recomputed
+		{page(http.StatusOK, `<a href="http://example.com/a">a</a>`, http.Header{
+			"Content-Type": {"text/html"}, "Content-Length": {"36"}}), []string{"text/*"}, 1024,
+			`<a href="https://example.com/a">a</a>`, "37"},
+
+		// other types, binary ones included, are untouched
+		{page(http.StatusOK, "\x89PNG http://example.com/ </body>", http.Header{"Content-Type": {"image/png"}}),
+			[]string{"text/html"}, 1024, "\x89PNG http://example.com/ </body>", ""},
+		{page(http.StatusOK, "http://example.com/", http.Header{"Content-Type": {"application/json"}}),
+			[]string{"text/*"}, 1024, "http://example.com/", ""},
+		{page(http.StatusOK, "http://example.com/", nil), []string{"text/html"}, 1024, "http://example.com/", ""},
+		// and so are compressed, partial, too big responses and other paths
+		{page(http.StatusOK, "http://example.com/", http.Header{
+			"Content-Type": {"text/html"}, "Content-Encoding": {"gzip"}}), []string{"text/html"}, 1024, "http://example.com/", ""},
+		{page(http.StatusPartialContent, "http://example.com/", html), []string{"text/html"}, 1024, "http://example.com/", ""},
+		{page(http.StatusOK, "http://example.com/", html), []string{"text/html"}, 10, "http://example.com/", ""},
+		{page(http.StatusNotFound, "http://example.com/", html), []string{"text/html"}, 1024, "http://example.com/", ""},
+	} {
+		s := Sub{Next: test.next, Paths: []string{"/"}, Rules: rules, Types: test.types, MaxSize: test.maxSize}
+		r, err := http.NewRequest("GET", "/", nil)
+		if err != nil {
+			t.Fatalf("Test %d: Could not create HTTP request: %v", i, err)
+		}
+		rec := httptest.NewRecorder()
+
+		if _, err := s.ServeHTTP(rec, r); err != nil {
+			t.Errorf("Test %d: Expected no error, got: %v", i, err)
+		}
+		if got := rec.Body.String(); got != test.expectedBody {
+			t.Errorf("Test %d: Expected body %q, got %q", i, test.expectedBody, got)
+		}
+		if got := rec.Header().Get("Content-Length"); test.expectedLen != "" && got != test.expectedLen {
+			t.Errorf("Test %d: Expected Content-Length %s, got %s", i, test.expectedLen, got)
+		}
+	}
+}
+
+func TestSubOtherPaths(t *testing.T) {
+	s := Sub{
+		Next: httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("old"))
+			return 0, nil
+		}),
+		Paths:   []string{"/blog"},
+		Rules:   []Rule{{Search: "old", Replacement: "new"}},
+		Types:   []string{"text/html"},
+		MaxSize: 1024,
+	}
+
+	for path, expected := range map[string]string{"/blog/post": "new", "/about": "old"} {
+		r, err := http.NewRequest("GET", path, nil)
+		if err != nil {
+			t.Fatalf("Could not create HTTP request: %v", err)
+		}
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, r)
+		if got := rec.Body.String(); got != expected {
+			t.Errorf("Expected %q for %s, got %q", expected, path, got)
+		}
+	}
+}
+
+func TestSubValidators(t *testing.T) {
+	s := Sub{
+		Next: httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+			if r.Header.Get("Range") != "" || r.Header.Get("If-Range") != "" {
+				t.Error("Expected the range of the request to be dropped")
+			}
+			w.Header().Set("Content-Type", "text/html")
+			w.Header().Set("ETag", `"abc"`)
+			w.Header().Set("Accept-Ranges", "bytes")
+			w.Write([]byte("old"))
+			return 0, nil
+		}),
+		Paths:   []string{"/"},
+		Rules:   []Rule{{Search: "old", Replacement: "new"}},
+		Types:   []string{"text/html"},
+		MaxSize: 1024,
+	}
+	r, err := http.NewRequest("GET", "/", nil)
+	if err != nil {
+		t.Fatalf("Could not create HTTP request: %v", err)
+	}
+	r.Header.Set("Range", "bytes=0-1")
+	r.Header.Set("If-Range", `"abc"`)
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, r)
+
+	if got := rec.Body.String(); got != "new" {
+		t.Errorf("Expected the whole changed body, got %q", got)
+	}
+	if got := rec.Header().Get("ETag"); got != `W/"abc"` {
+		t.Errorf("Expected a weak ETag, got %s", got)
+	}
+	if got := rec.Header().Get("Accept-Ranges"); got != "" {
+		t.Errorf("Expected no Accept-Ranges, got %s", got)
+	}
+}
@@ -0,0 +1,70 @@
+// Package tcp tunes the listening socket of a site and the
+// TCP connections accepted on it.
+package tcp
+
+import (
+	"time"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func init() {
+	caddy.RegisterPlugin("tcp", caddy.Plugin{
+		ServerType: "http",
+		Action:     setup,
+	})
+}
+
+// setup parses the tcp directive:
+//
+//	tcp {
+//		keepalive <duration|none>
+//		reuse_addr
+//		reuse_port
+//	}
+//
+// The options apply to the listener of the site, so the sites
+// on a listener must have the same ones, if any.
+func setup(c *caddy.Controller) error {
+	config := httpserver.GetConfig(c)
+	for c.Next() {
+		if config.SocketOptions != nil {
+			return c.Err("tcp can only be specified once per site")
+		}
+		if len(c.RemainingArgs()) != 0 {
+			return c.ArgErr()
+		}
+		opts := new(httpserver.SocketOptions)
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "keepalive":
+				if !c.NextArg() {
+					return c.ArgErr()
+				}
+				if c.Val() == "none" {
+					opts.KeepAlive = -1
+				} else {
+					dur, err := time.ParseDuration(c.Val())
+					if err != nil || dur < time.Second {
+						return c.Errf("keepalive must be a duration of at least 1s or none, got '%s'", c.Val())
+					}
+					opts.KeepAlive = dur
+				}
+			case "reuse_addr":
+				opts.ReuseAddr = true
+			case "reuse_port":
+				opts.ReusePort = true
+			default:
+				return c.Errf("unknown tcp option '%s'", c.Val())
+			}
+			if c.NextArg() {
+				return c.ArgErr()
+			}
+		}
+
+		config.SocketOptions = opts
+	}
+	return nil
+}
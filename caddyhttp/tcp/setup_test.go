@@ -0,0 +1,50 @@
+package tcp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func TestSetup(t *testing.T) {
+	for i, test := range []struct {
+		input     string
+		shouldErr bool
+		expected  httpserver.SocketOptions
+	}{
+		{"tcp", false, httpserver.SocketOptions{}},
+		{"tcp {\n\tkeepalive 30s\n}", false, httpserver.SocketOptions{KeepAlive: 30 * time.Second}},
+		{"tcp {\n\tkeepalive none\n}", false, httpserver.SocketOptions{KeepAlive: -1}},
+		{"tcp {\n\treuse_addr\n\treuse_port\n}", false, httpserver.SocketOptions{ReuseAddr: true, ReusePort: true}},
+		{"tcp on", true, httpserver.SocketOptions{}},
+		{"tcp {\n\tkeepalive\n}", true, httpserver.SocketOptions{}},
+		{"tcp {\n\tkeepalive 10ms\n}", true, httpserver.SocketOptions{}},
+		{"tcp {\n\tkeepalive forever\n}", true, httpserver.SocketOptions{}},
+		{"tcp {\n\treuse_port yes\n}", true, httpserver.SocketOptions{}},
+		{"tcp {\n\tnodelay\n}", true, httpserver.SocketOptions{}},
+		{"tcp\ntcp", true, httpserver.SocketOptions{}},
+	} {
+		c := caddy.NewTestController("http", test.input)
+		err := setup(c)
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: Expected an error, but did not have one", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: Did not expect error, but got: %v", i, err)
+			continue
+		}
+
+		opts := httpserver.GetConfig(c).SocketOptions
+		if opts == nil {
+			t.Fatalf("Test %d: Expected socket options, got none", i)
+		}
+		if *opts != test.expected {
+			t.Errorf("Test %d: Expected socket options %+v, got %+v", i, test.expected, *opts)
+		}
+	}
+}
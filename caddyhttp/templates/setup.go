@@ -1,8 +1,6 @@
 package templates
 
 import (
-	"net/http"
-
 	"github.com/mholt/caddy"
 	"github.com/mholt/caddy/caddyhttp/httpserver"
 )
@@ -16,6 +14,10 @@ func init() {
 
 // setup configures a new Templates middleware instance.
 func setup(c *caddy.Controller) error {
+	if err := httpserver.CheckStaticRoot(c, "templates"); err != nil {
+		return err
+	}
+
 	rules, err := templatesParse(c)
 	if err != nil {
 		return err
@@ -26,7 +28,7 @@ func setup(c *caddy.Controller) error {
 	tmpls := Templates{
 		Rules:   rules,
 		Root:    cfg.Root,
-		FileSys: http.Dir(cfg.Root),
+		FileSys: cfg.RootFileSys(),
 	}
 
 	cfg.AddMiddleware(func(next httpserver.Handler) httpserver.Handler {
@@ -9,6 +9,7 @@ import (
 	"path"
 	"path/filepath"
 	"text/template"
+	"time"
 
 	"github.com/mholt/caddy/caddyhttp/httpserver"
 )
@@ -55,18 +56,28 @@ func (t Templates) ServeHTTP(w http.ResponseWriter, r *http.Request) (int, error
 					return http.StatusInternalServerError, err
 				}
 
+				// Skip rendering if the client's copy is still fresh
+				var modTime time.Time
+				if templateInfo, err := os.Stat(templatePath); err == nil {
+					modTime = templateInfo.ModTime()
+				}
+				if httpserver.NotModifiedSince(r, modTime) {
+					httpserver.SetLastModifiedHeader(w, modTime)
+					httpserver.WriteNotModified(w)
+					return 0, nil
+				}
+
 				// Execute it
 				var buf bytes.Buffer
+				start := time.Now()
 				err = tpl.Execute(&buf, ctx)
 				if err != nil {
 					return http.StatusInternalServerError, err
 				}
+				httpserver.AddServerTiming(r, "render", time.Since(start))
 
-				templateInfo, err := os.Stat(templatePath)
-				if err == nil {
-					// add the Last-Modified header if we were able to read the stamp
-					httpserver.SetLastModifiedHeader(w, templateInfo.ModTime())
-				}
+				// add the Last-Modified header if we were able to read the stamp
+				httpserver.SetLastModifiedHeader(w, modTime)
 				buf.WriteTo(w)
 
 				return http.StatusOK, nil
@@ -3,7 +3,9 @@ package templates
 import (
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"testing"
+	"time"
 
 	"github.com/mholt/caddy/caddyhttp/httpserver"
 )
@@ -136,3 +138,55 @@ func TestTemplates(t *testing.T) {
 		t.Fatalf("Test: the expected body %v is different from the response one: %v", expectedBody, respBody)
 	}
 }
+
+func TestTemplatesIfModifiedSince(t *testing.T) {
+	tmpl := Templates{
+		Next: httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+			t.Fatal("Next shouldn't be called")
+			return 0, nil
+		}),
+		Rules:   []Rule{{Extensions: []string{".html"}, Path: "/"}},
+		Root:    "./testdata",
+		FileSys: http.Dir("./testdata"),
+	}
+
+	info, err := os.Stat("./testdata/root.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	modTime := info.ModTime()
+	lastModified := modTime.UTC().Format(http.TimeFormat)
+
+	for i, test := range []struct {
+		since  string
+		status int
+	}{
+		{"", http.StatusOK},
+		{modTime.Add(time.Hour).UTC().Format(http.TimeFormat), http.StatusNotModified},
+		{lastModified, http.StatusNotModified},
+		{modTime.Add(-time.Hour).UTC().Format(http.TimeFormat), http.StatusOK},
+	} {
+		req, err := http.NewRequest("GET", "/root.html", nil)
+		if err != nil {
+			t.Fatalf("Test %d: Could not create HTTP request: %v", i, err)
+		}
+		if test.since != "" {
+			req.Header.Set("If-Modified-Since", test.since)
+		}
+		rec := httptest.NewRecorder()
+
+		tmpl.ServeHTTP(rec, req)
+
+		if rec.Code != test.status {
+			t.Errorf("Test %d: Wrong response code: %d, should be %d", i, rec.Code, test.status)
+		}
+		if got := rec.Header().Get("Last-Modified"); got != lastModified {
+			t.Errorf("Test %d: Expected Last-Modified %q, got %q", i, lastModified, got)
+		}
+		if test.status == http.StatusNotModified && rec.Body.Len() != 0 {
+			t.Errorf("Test %d: Expected empty body, got %q", i, rec.Body.String())
+		} else if test.status == http.StatusOK && rec.Body.Len() == 0 {
+			t.Errorf("Test %d: Expected a rendered body", i)
+		}
+	}
+}
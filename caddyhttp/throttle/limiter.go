@@ -0,0 +1,105 @@
+package throttle
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// limiter keeps a token bucket for each client key. It holds
+// at most max buckets; when it is full, the least recently
+// used bucket that isn't throttling its client is dropped.
+type limiter struct {
+	rate  float64 // tokens added per second
+	burst float64 // capacity of a bucket
+	max   int
+
+	mu      sync.Mutex
+	buckets map[string]*list.Element
+	lru     *list.List // of *bucket, most recently used first
+	now     func() time.Time
+}
+
+type bucket struct {
+	key    string
+	tokens float64
+	last   time.Time
+}
+
+func newLimiter(rate float64, burst, max int) *limiter {
+	return &limiter{
+		rate:    rate,
+		burst:   float64(burst),
+		max:     max,
+		buckets: make(map[string]*list.Element),
+		lru:     list.New(),
+		now:     time.Now,
+	}
+}
+
+// allow takes a token from the bucket for key. If the bucket
+// is empty, it returns false and how long until a token is
+// available. It is safe for concurrent use.
+func (l *limiter) allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+
+	var b *bucket
+	if el, ok := l.buckets[key]; ok {
+		l.lru.MoveToFront(el)
+		b = el.Value.(*bucket)
+		l.refill(b, now)
+	} else {
+		if l.lru.Len() >= l.max && !l.evict(now) {
+			// every client we know of is being throttled;
+			// rather than turn away new ones, let them in
+			return true, 0
+		}
+		b = &bucket{key: key, tokens: l.burst, last: now}
+		l.buckets[key] = l.lru.PushFront(b)
+	}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	return false, wait
+}
+
+// refill adds the tokens earned since b was last used.
+func (l *limiter) refill(b *bucket, now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens += elapsed.Seconds() * l.rate
+		if b.tokens > l.burst {
+			b.tokens = l.burst
+		}
+	}
+	b.last = now
+}
+
+// evict drops the least recently used bucket that has a token
+// to spare; dropping one that is empty would let its client
+// start over with a full burst. It reports whether a bucket
+// was dropped.
+func (l *limiter) evict(now time.Time) bool {
+	for el := l.lru.Back(); el != nil; el = el.Prev() {
+		b := el.Value.(*bucket)
+		l.refill(b, now)
+		if b.tokens >= 1 {
+			l.lru.Remove(el)
+			delete(l.buckets, b.key)
+			return true
+		}
+	}
+	return false
+}
+
+// len returns the number of buckets.
+func (l *limiter) len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.lru.Len()
+}
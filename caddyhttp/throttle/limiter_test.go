@@ -0,0 +1,169 @@
+package throttle
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.mu.Lock()
+	c.t = c.t.Add(d)
+	c.mu.Unlock()
+}
+
+func TestLimiterBurst(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1000, 0)}
+	l := newLimiter(2, 5, 100)
+	l.now = clock.now
+
+	for i := 0; i < 5; i++ {
+		if ok, _ := l.allow("a"); !ok {
+			t.Fatalf("Expected request %d within burst to be allowed", i)
+		}
+	}
+	ok, wait := l.allow("a")
+	if ok {
+		t.Fatal("Expected request beyond burst to be blocked")
+	}
+	if wait != 500*time.Millisecond {
+		t.Errorf("Expected to wait 500ms for a token, got %v", wait)
+	}
+
+	// other clients have their own bucket
+	if ok, _ := l.allow("b"); !ok {
+		t.Error("Expected another client to be allowed")
+	}
+
+	// tokens come back at the rate
+	clock.advance(500 * time.Millisecond)
+	if ok, _ := l.allow("a"); !ok {
+		t.Error("Expected a request to be allowed after a token was added")
+	}
+	if ok, _ := l.allow("a"); ok {
+		t.Error("Expected the next request to be blocked")
+	}
+
+	// but never more than the burst
+	clock.advance(time.Hour)
+	for i := 0; i < 5; i++ {
+		if ok, _ := l.allow("a"); !ok {
+			t.Fatalf("Expected request %d within refilled burst to be allowed", i)
+		}
+	}
+	if ok, _ := l.allow("a"); ok {
+		t.Error("Expected request beyond refilled burst to be blocked")
+	}
+}
+
+func TestLimiterEviction(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1000, 0)}
+	l := newLimiter(1, 1, 3)
+	l.now = clock.now
+
+	// a and b are throttled, c is idle
+	l.allow("a")
+	l.allow("b")
+	clock.advance(2 * time.Second)
+	l.allow("c")
+	clock.advance(2 * time.Second)
+	l.allow("a")
+	l.allow("b")
+	if ok, _ := l.allow("a"); ok {
+		t.Fatal("Expected a to be throttled")
+	}
+	if ok, _ := l.allow("b"); ok {
+		t.Fatal("Expected b to be throttled")
+	}
+
+	// d makes room by dropping idle c, not throttled a or b
+	if ok, _ := l.allow("d"); !ok {
+		t.Error("Expected new client d to be allowed")
+	}
+	if n := l.len(); n != 3 {
+		t.Errorf("Expected 3 buckets, got %d", n)
+	}
+	if _, ok := l.buckets["c"]; ok {
+		t.Error("Expected idle bucket c to be evicted")
+	}
+	if ok, _ := l.allow("a"); ok {
+		t.Error("Expected a to still be throttled")
+	}
+	if ok, _ := l.allow("b"); ok {
+		t.Error("Expected b to still be throttled")
+	}
+
+	// with everyone throttled, new clients get in untracked
+	l.allow("d")
+	if ok, _ := l.allow("e"); !ok {
+		t.Error("Expected new client e to be allowed")
+	}
+	if n := l.len(); n != 3 {
+		t.Errorf("Expected still 3 buckets, got %d", n)
+	}
+}
+
+func TestLimiterConcurrent(t *testing.T) {
+	l := newLimiter(1, 50, 1000) // slow enough to make refills negligible
+
+	var allowed, blocked uint64
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if ok, _ := l.allow("client"); ok {
+					atomic.AddUint64(&allowed, 1)
+				} else {
+					atomic.AddUint64(&blocked, 1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// 50 for the burst, maybe a couple refilled while running
+	if allowed < 50 || allowed > 55 {
+		t.Errorf("Expected about 50 allowed requests, got %d", allowed)
+	}
+	if allowed+blocked != 1000 {
+		t.Errorf("Expected 1000 requests, got %d", allowed+blocked)
+	}
+}
+
+func TestLimiterConcurrentBounded(t *testing.T) {
+	l := newLimiter(100, 10, 50)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				l.allow(strconv.Itoa(i*100 + j))
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if n := l.len(); n > 50 {
+		t.Errorf("Expected at most 50 buckets, got %d", n)
+	}
+	if len(l.buckets) != l.lru.Len() {
+		t.Errorf("Expected map and list to agree, got %d and %d", len(l.buckets), l.lru.Len())
+	}
+}
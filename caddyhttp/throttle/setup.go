@@ -0,0 +1,135 @@
+package throttle
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func init() {
+	caddy.RegisterPlugin("throttle", caddy.Plugin{
+		ServerType: "http",
+		Action:     setup,
+	})
+}
+
+// setup configures a new Throttle middleware instance.
+func setup(c *caddy.Controller) error {
+	rules, err := throttleParse(c)
+	if err != nil {
+		return err
+	}
+
+	cfg := httpserver.GetConfig(c)
+	cfg.AddMiddleware(func(next httpserver.Handler) httpserver.Handler {
+		return Throttle{Next: next, Rules: rules, TrustedProxies: cfg.TrustedProxies}
+	})
+
+	return nil
+}
+
+// throttleParse parses the directive:
+//
+//	throttle [path] {
+//		rate     <n>/<s|m|h|duration>
+//		burst    <n>
+//		key      <placeholders>
+//		max_keys <n>
+//	}
+func throttleParse(c *caddy.Controller) ([]*Rule, error) {
+	var rules []*Rule
+
+	for c.Next() {
+		rule := &Rule{Path: "/", MaxKeys: defaultMaxKeys}
+
+		args := c.RemainingArgs()
+		switch len(args) {
+		case 0:
+		case 1:
+			rule.Path = args[0]
+		default:
+			return rules, c.ArgErr()
+		}
+
+		for c.NextBlock() {
+			what := c.Val()
+			if !c.NextArg() {
+				return rules, c.ArgErr()
+			}
+			value := c.Val()
+			if c.NextArg() {
+				return rules, c.ArgErr()
+			}
+
+			switch what {
+			case "rate":
+				rate, err := ParseRate(value)
+				if err != nil {
+					return rules, c.Errf("invalid rate '%s': %v", value, err)
+				}
+				rule.Rate = rate
+			case "burst", "max_keys":
+				n, err := strconv.Atoi(value)
+				if err != nil || n < 1 {
+					return rules, c.Errf("%s must be a positive integer, got '%s'", what, value)
+				}
+				if what == "burst" {
+					rule.Burst = n
+				} else {
+					rule.MaxKeys = n
+				}
+			case "key":
+				rule.Key = value
+			default:
+				return rules, c.Errf("unknown throttle property '%s'", what)
+			}
+		}
+
+		if rule.Rate == 0 {
+			return rules, c.Err("throttle: rate is required")
+		}
+		if rule.Burst == 0 {
+			// allow at least one request, and a second's worth at once
+			rule.Burst = int(rule.Rate)
+			if rule.Burst < 1 {
+				rule.Burst = 1
+			}
+		}
+		rule.limiter = newLimiter(rule.Rate, rule.Burst, rule.MaxKeys)
+
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+// ParseRate parses a rate like 10/s, 600/m, 5/h or 3/10s
+// into a number (of requests, say) per second.
+func ParseRate(s string) (float64, error) {
+	parts := strings.SplitN(s, "/", 2)
+	if len(parts) != 2 {
+		return 0, errRateSyntax
+	}
+	n, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil || n <= 0 {
+		return 0, errRateSyntax
+	}
+	unit := parts[1]
+	if unit != "" && (unit[0] < '0' || unit[0] > '9') {
+		unit = "1" + unit
+	}
+	per, err := time.ParseDuration(unit)
+	if err != nil || per <= 0 {
+		return 0, errRateSyntax
+	}
+	return n / per.Seconds(), nil
+}
+
+var errRateSyntax = errors.New("must be a positive number per time unit, like 10/s or 600/m")
+
+// defaultMaxKeys is the number of clients tracked by default.
+const defaultMaxKeys = 10000
@@ -0,0 +1,115 @@
+package throttle
+
+import (
+	"testing"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func TestSetup(t *testing.T) {
+	c := caddy.NewTestController("http", `throttle /api {
+		rate 10/s
+	}`)
+	err := setup(c)
+	if err != nil {
+		t.Errorf("Expected no errors, got: %v", err)
+	}
+	mids := httpserver.GetConfig(c).Middleware()
+	if len(mids) == 0 {
+		t.Fatal("Expected middleware, got 0 instead")
+	}
+
+	handler := mids[0](httpserver.EmptyNext)
+	myHandler, ok := handler.(Throttle)
+	if !ok {
+		t.Fatalf("Expected handler to be type Throttle, got: %#v", handler)
+	}
+	if len(myHandler.Rules) != 1 || myHandler.Rules[0].limiter == nil {
+		t.Errorf("Expected one rule with a limiter, got %+v", myHandler.Rules)
+	}
+	if !httpserver.SameNext(myHandler.Next, httpserver.EmptyNext) {
+		t.Error("'Next' field of handler was not set properly")
+	}
+}
+
+func TestThrottleParse(t *testing.T) {
+	for i, test := range []struct {
+		input     string
+		shouldErr bool
+		expected  []Rule
+	}{
+		{`throttle {
+			rate 10/s
+		}`, false, []Rule{{Path: "/", Rate: 10, Burst: 10, MaxKeys: defaultMaxKeys}}},
+		{`throttle /api {
+			rate 30/m
+			burst 5
+			key {>X-Api-Key}
+			max_keys 100
+		}
+		throttle /login {
+			rate 3/10s
+		}`, false, []Rule{
+			{Path: "/api", Rate: 0.5, Burst: 5, Key: "{>X-Api-Key}", MaxKeys: 100},
+			{Path: "/login", Rate: 0.3, Burst: 1, MaxKeys: defaultMaxKeys},
+		}},
+		{`throttle {
+			rate 3600/h
+		}`, false, []Rule{{Path: "/", Rate: 1, Burst: 1, MaxKeys: defaultMaxKeys}}},
+		{`throttle`, true, nil},
+		{`throttle /a /b {
+			rate 1/s
+		}`, true, nil},
+		{`throttle {
+			burst 5
+		}`, true, nil},
+		{`throttle {
+			rate 10
+		}`, true, nil},
+		{`throttle {
+			rate 0/s
+		}`, true, nil},
+		{`throttle {
+			rate 10/fortnight
+		}`, true, nil},
+		{`throttle {
+			rate 10/s
+			burst 0
+		}`, true, nil},
+		{`throttle {
+			rate 10/s
+			burst
+		}`, true, nil},
+		{`throttle {
+			rate 10/s 20/m
+		}`, true, nil},
+		{`throttle {
+			rate 10/s
+			window 1m
+		}`, true, nil},
+	} {
+		actual, err := throttleParse(caddy.NewTestController("http", test.input))
+		if err == nil && test.shouldErr {
+			t.Errorf("Test %d didn't error, but it should have", i)
+		} else if err != nil && !test.shouldErr {
+			t.Errorf("Test %d errored, but it shouldn't have; got '%v'", i, err)
+		}
+		if test.shouldErr {
+			continue
+		}
+		if len(actual) != len(test.expected) {
+			t.Fatalf("Test %d: Expected %d rules, got %d", i, len(test.expected), len(actual))
+		}
+		for j, rule := range actual {
+			expected := test.expected[j]
+			if rule.Path != expected.Path || rule.Rate != expected.Rate || rule.Burst != expected.Burst ||
+				rule.Key != expected.Key || rule.MaxKeys != expected.MaxKeys {
+				t.Errorf("Test %d, rule %d: Expected %+v, got %+v", i, j, expected, *rule)
+			}
+			if rule.limiter == nil {
+				t.Errorf("Test %d, rule %d: Expected a limiter", i, j)
+			}
+		}
+	}
+}
@@ -0,0 +1,73 @@
+// Package throttle implements middleware that limits the rate
+// of requests from each client with token buckets.
+package throttle
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+// Throttle is middleware that answers clients that make too
+// many requests with 429 Too Many Requests.
+type Throttle struct {
+	Next  httpserver.Handler
+	Rules []*Rule
+
+	// TrustedProxies are the proxies whose X-Forwarded-For
+	// headers are used to find the client; see httpserver.ClientIP.
+	TrustedProxies []*net.IPNet
+}
+
+// Rule limits the requests under Path. Only the first rule
+// with a matching path applies to a request.
+type Rule struct {
+	Path string
+
+	// Rate is the number of requests allowed per second, on
+	// average; Burst is how many can be made at once.
+	Rate  float64
+	Burst int
+
+	// Key tells clients apart; it may contain placeholders.
+	// If empty, clients are told apart by IP address.
+	Key string
+
+	// MaxKeys bounds the number of clients that are tracked.
+	MaxKeys int
+
+	limiter *limiter
+}
+
+// ServeHTTP implements the httpserver.Handler interface.
+func (t Throttle) ServeHTTP(w http.ResponseWriter, r *http.Request) (int, error) {
+	for _, rule := range t.Rules {
+		if !httpserver.Path(r.URL.Path).Matches(rule.Path) {
+			continue
+		}
+
+		var key string
+		if rule.Key == "" {
+			// without an IP address, fall back to the remote
+			// address rather than lumping such clients together
+			if ip := httpserver.ClientIP(r, t.TrustedProxies); ip != nil {
+				key = ip.String()
+			} else {
+				key = r.RemoteAddr
+			}
+		} else {
+			key = httpserver.NewReplacer(r, nil, "").Replace(rule.Key)
+		}
+
+		if ok, wait := rule.limiter.allow(key); !ok {
+			seconds := (wait + time.Second - 1) / time.Second
+			w.Header().Set("Retry-After", strconv.FormatInt(int64(seconds), 10))
+			return http.StatusTooManyRequests, nil
+		}
+		break
+	}
+	return t.Next.ServeHTTP(w, r)
+}
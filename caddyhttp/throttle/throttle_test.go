@@ -0,0 +1,76 @@
+package throttle
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func TestThrottle(t *testing.T) {
+	_, proxy, _ := net.ParseCIDR("10.0.0.1/32")
+	th := Throttle{
+		Next: httpserver.HandlerFunc(func(w http.ResponseWriter, r *http.Request) (int, error) {
+			return http.StatusOK, nil
+		}),
+		Rules: []*Rule{
+			{Path: "/api", Key: "{>X-Api-Key}", limiter: newLimiter(1, 2, 10)},
+			{Path: "/", limiter: newLimiter(1, 1, 10)},
+		},
+		TrustedProxies: []*net.IPNet{proxy},
+	}
+
+	for i, test := range []struct {
+		path, remoteAddr, xff, apiKey string
+		expected                      int
+	}{
+		{"/", "1.2.3.4:1000", "", "", http.StatusOK},
+		{"/", "1.2.3.4:1001", "", "", http.StatusTooManyRequests},
+		{"/", "5.6.7.8:1000", "", "", http.StatusOK},
+
+		// clients behind a trusted proxy are told apart
+		{"/", "10.0.0.1:1000", "9.9.9.9", "", http.StatusOK},
+		{"/", "10.0.0.1:1001", "9.9.9.9", "", http.StatusTooManyRequests},
+		{"/", "10.0.0.1:1002", "8.8.8.8", "", http.StatusOK},
+
+		// clients without an IP address are not lumped together
+		{"/", "@conn1", "", "", http.StatusOK},
+		{"/", "@conn1", "", "", http.StatusTooManyRequests},
+		{"/", "@conn2", "", "", http.StatusOK},
+
+		// keyed by placeholder, with a burst of two
+		{"/api/x", "1.2.3.4:1000", "", "k1", http.StatusOK},
+		{"/api/x", "5.6.7.8:1000", "", "k1", http.StatusOK},
+		{"/api/x", "1.2.3.4:1000", "", "k1", http.StatusTooManyRequests},
+		{"/api/x", "1.2.3.4:1000", "", "k2", http.StatusOK},
+	} {
+		r, err := http.NewRequest("GET", test.path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.RemoteAddr = test.remoteAddr
+		if test.xff != "" {
+			r.Header.Set("X-Forwarded-For", test.xff)
+		}
+		if test.apiKey != "" {
+			r.Header.Set("X-Api-Key", test.apiKey)
+		}
+		rec := httptest.NewRecorder()
+
+		status, err := th.ServeHTTP(rec, r)
+		if err != nil {
+			t.Errorf("Test %d: Expected no error, got %v", i, err)
+		}
+		if status != test.expected {
+			t.Errorf("Test %d: Expected status %d, got %d", i, test.expected, status)
+		}
+		retryAfter := rec.Header().Get("Retry-After")
+		if status == http.StatusTooManyRequests && retryAfter != "1" {
+			t.Errorf("Test %d: Expected Retry-After 1, got %q", i, retryAfter)
+		} else if status != http.StatusTooManyRequests && retryAfter != "" {
+			t.Errorf("Test %d: Expected no Retry-After, got %q", i, retryAfter)
+		}
+	}
+}
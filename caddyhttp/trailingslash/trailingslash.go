@@ -0,0 +1,45 @@
+// Package trailingslash configures how the static file server of
+// a site redirects paths with and without a trailing slash.
+package trailingslash
+
+import (
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+	"github.com/mholt/caddy/caddyhttp/staticfiles"
+)
+
+func init() {
+	caddy.RegisterPlugin("trailing_slash", caddy.Plugin{
+		ServerType: "http",
+		Action:     setupTrailingSlash,
+	})
+}
+
+// setupTrailingSlash parses the trailing_slash directive:
+//
+//	trailing_slash add | remove | off
+//
+// add, the default, redirects directories to the path with a
+// trailing slash; remove redirects them to the path without one;
+// off serves them at either path without redirecting.
+func setupTrailingSlash(c *caddy.Controller) error {
+	config := httpserver.GetConfig(c)
+	for c.Next() {
+		if config.TrailingSlash != "" {
+			return c.Err("trailing_slash can only be specified once per site")
+		}
+		if !c.NextArg() {
+			return c.ArgErr()
+		}
+		switch mode := c.Val(); mode {
+		case staticfiles.TrailingSlashAdd, staticfiles.TrailingSlashRemove, staticfiles.TrailingSlashOff:
+			config.TrailingSlash = mode
+		default:
+			return c.Errf("unknown mode '%s': must be add, remove or off", mode)
+		}
+		if c.NextArg() {
+			return c.ArgErr()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package trailingslash
+
+import (
+	"testing"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func TestSetupTrailingSlash(t *testing.T) {
+	for i, test := range []struct {
+		input        string
+		shouldErr    bool
+		expectedMode string
+	}{
+		{"trailing_slash add", false, "add"},
+		{"trailing_slash remove", false, "remove"},
+		{"trailing_slash off", false, "off"},
+		{"trailing_slash", true, ""},
+		{"trailing_slash strip", true, ""},
+		{"trailing_slash add remove", true, ""},
+		{"trailing_slash add\ntrailing_slash remove", true, ""},
+	} {
+		c := caddy.NewTestController("http", test.input)
+		err := setupTrailingSlash(c)
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: Expected an error, but did not have one", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: Did not expect error, but got: %v", i, err)
+			continue
+		}
+		if mode := httpserver.GetConfig(c).TrailingSlash; mode != test.expectedMode {
+			t.Errorf("Test %d: Expected mode %q, got %q", i, test.expectedMode, mode)
+		}
+	}
+}
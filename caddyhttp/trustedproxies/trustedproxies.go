@@ -0,0 +1,35 @@
+// Package trustedproxies configures the proxies that a site trusts
+// to report the address of the client in Forwarded or X-Forwarded-For.
+// Without any, the headers are ignored and the client is always the
+// peer of the connection.
+package trustedproxies
+
+import (
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func init() {
+	caddy.RegisterPlugin("trusted_proxies", caddy.Plugin{
+		ServerType: "http",
+		Action:     setupTrustedProxies,
+	})
+}
+
+func setupTrustedProxies(c *caddy.Controller) error {
+	config := httpserver.GetConfig(c)
+	for c.Next() {
+		args := c.RemainingArgs()
+		if len(args) == 0 {
+			return c.ArgErr()
+		}
+		for _, arg := range args {
+			ipnet, err := httpserver.ParseCIDR(arg)
+			if err != nil {
+				return c.Errf("invalid trusted proxy '%s': %v", arg, err)
+			}
+			config.TrustedProxies = append(config.TrustedProxies, ipnet)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package trustedproxies
+
+import (
+	"testing"
+
+	"github.com/mholt/caddy"
+	"github.com/mholt/caddy/caddyhttp/httpserver"
+)
+
+func TestSetupTrustedProxies(t *testing.T) {
+	for i, test := range []struct {
+		input     string
+		shouldErr bool
+		expected  []string
+	}{
+		{"trusted_proxies 10.0.0.1", false, []string{"10.0.0.1/32"}},
+		{"trusted_proxies 10.0.0.0/8 192.168.1.0/24", false, []string{"10.0.0.0/8", "192.168.1.0/24"}},
+		{"trusted_proxies ::1 fd00::/8\ntrusted_proxies 127.0.0.1", false, []string{"::1/128", "fd00::/8", "127.0.0.1/32"}},
+		{"trusted_proxies", true, nil},
+		{"trusted_proxies 10.0.0.0/33", true, nil},
+		{"trusted_proxies localhost", true, nil},
+	} {
+		c := caddy.NewTestController("http", test.input)
+		err := setupTrustedProxies(c)
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %d: Expected an error, but did not have one", i)
+		}
+		if !test.shouldErr && err != nil {
+			t.Errorf("Test %d: Did not expect error, but got: %v", i, err)
+		}
+
+		got := httpserver.GetConfig(c).TrustedProxies
+		if !test.shouldErr && len(got) != len(test.expected) {
+			t.Fatalf("Test %d: Expected %d trusted proxies, got %d", i, len(test.expected), len(got))
+		}
+		for j, ipnet := range got {
+			if j < len(test.expected) && ipnet.String() != test.expected[j] {
+				t.Errorf("Test %d: Expected trusted proxy %d to be %s, got %s", i, j, test.expected[j], ipnet)
+			}
+		}
+	}
+}
@@ -85,6 +85,27 @@ func getCertificate(name string) (cert Certificate, matched, defaulted bool) {
 	return
 }
 
+// OCSPStatus returns the status in the OCSP response of the
+// certificate that is served for the server name name: "good",
+// "revoked" or "unknown", or "none" if there is no such
+// certificate or it has no OCSP response. Only a good response
+// is stapled in the handshake.
+//
+// This function is safe for concurrent use.
+func OCSPStatus(name string) string {
+	cert, matched, defaulted := getCertificate(name)
+	if (!matched && !defaulted) || cert.OCSP == nil {
+		return "none"
+	}
+	switch cert.OCSP.Status {
+	case ocsp.Good:
+		return "good"
+	case ocsp.Revoked:
+		return "revoked"
+	}
+	return "unknown"
+}
+
 // CacheManagedCertificate loads the certificate for domain into the
 // cache, flagging it as Managed and, if onDemand is true, as "OnDemand"
 // (meaning that it was obtained or loaded during a TLS handshake).
@@ -1,6 +1,10 @@
 package caddytls
 
-import "testing"
+import (
+	"testing"
+
+	"golang.org/x/crypto/ocsp"
+)
 
 func TestUnexportedGetCertificate(t *testing.T) {
 	defer func() { certCache = make(map[string]Certificate) }()
@@ -57,3 +61,35 @@ func TestCacheCertificate(t *testing.T) {
 		t.Error("Expected second cert to NOT be cached as default, but it was")
 	}
 }
+
+func TestOCSPStatus(t *testing.T) {
+	defer func() { certCache = make(map[string]Certificate) }()
+
+	if got := OCSPStatus("example.com"); got != "none" {
+		t.Errorf("Expected status none when cache was empty, got %s", got)
+	}
+
+	certCache[""] = Certificate{Names: []string{"example.com", ""}}
+	certCache["example.com"] = certCache[""]
+	certCache["good.example.com"] = Certificate{OCSP: &ocsp.Response{Status: ocsp.Good}}
+	certCache["revoked.example.com"] = Certificate{OCSP: &ocsp.Response{Status: ocsp.Revoked}}
+	certCache["unknown.example.com"] = Certificate{OCSP: &ocsp.Response{Status: ocsp.Unknown}}
+	certCache["*.failed.example.com"] = Certificate{OCSP: &ocsp.Response{Status: ocsp.ServerFailed}}
+
+	for i, test := range []struct {
+		name     string
+		expected string
+	}{
+		{"good.example.com", "good"},
+		{"GOOD.example.com", "good"},
+		{"revoked.example.com", "revoked"},
+		{"unknown.example.com", "unknown"},
+		{"sub.failed.example.com", "unknown"},
+		{"example.com", "none"}, // no staple
+		{"nomatch", "none"},     // default certificate, no staple
+	} {
+		if got := OCSPStatus(test.name); got != test.expected {
+			t.Errorf("Test %d: Expected OCSP status %s for %s, got %s", i, test.expected, test.name, got)
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/mholt/caddy"
 	"github.com/xenolf/lego/acme"
@@ -32,12 +33,24 @@ type Config struct {
 	// TLS_FALLBACK_SCSV to prevent degrade attacks
 	Ciphers []uint16
 
+	// Whether to leave the cipher suites to Go, which
+	// chooses them for the hardware; Ciphers is empty then
+	GoCiphers bool
+
 	// Whether to prefer server cipher suites
 	PreferServerCipherSuites bool
 
 	// The list of preferred curves
 	CurvePreferences []tls.CurveID
 
+	// The application protocols to offer with ALPN, in
+	// order of preference; if empty, the server decides
+	ALPN []string
+
+	// How often to rotate the session ticket keys; 0
+	// means every TicketRotateInterval
+	TicketRotation time.Duration
+
 	// Client authentication policy
 	ClientAuth tls.ClientAuthType
 
@@ -108,6 +121,12 @@ type Config struct {
 
 	// Add the must staple TLS extension to the CSR generated by lego/acme
 	MustStaple bool
+
+	// Whether to reject handshakes that don't indicate the
+	// server name (SNI); as handshakes without one can't be
+	// told apart by site, if any config on a listener requires
+	// SNI, all handshakes on it must have it
+	RequireSNI bool
 }
 
 // OnDemandState contains some state relevant for providing
@@ -227,7 +246,10 @@ func MakeTLSConfig(configs []*Config) (*tls.Config, error) {
 	config := new(tls.Config)
 	ciphersAdded := make(map[uint16]struct{})
 	curvesAdded := make(map[tls.CurveID]struct{})
+	alpnAdded := make(map[string]struct{})
+	var alpnSet bool
 	configMap := make(configGroup)
+	var goCiphers bool
 
 	for i, cfg := range configs {
 		if cfg == nil {
@@ -257,7 +279,12 @@ func MakeTLSConfig(configs []*Config) (*tls.Config, error) {
 			continue
 		}
 
-		// Union cipher suites
+		// Union cipher suites; those of Go can't be combined
+		// with others, since Go does not tell which they are
+		if i > 0 && cfg.GoCiphers != configs[i-1].GoCiphers {
+			return nil, fmt.Errorf("cannot both use the default cipher suites of Go and configure them")
+		}
+		goCiphers = cfg.GoCiphers
 		for _, ciph := range cfg.Ciphers {
 			if _, ok := ciphersAdded[ciph]; !ok {
 				ciphersAdded[ciph] = struct{}{}
@@ -279,8 +306,26 @@ func MakeTLSConfig(configs []*Config) (*tls.Config, error) {
 			}
 		}
 
-		// Go with the widest range of protocol versions
-		if config.MinVersion == 0 || cfg.ProtocolMinVersion < config.MinVersion {
+		// Union application protocols; a site that doesn't
+		// choose them gets the defaults, if another one does
+		alpn := cfg.ALPN
+		if len(alpn) == 0 {
+			alpn = defaultALPN
+		} else {
+			alpnSet = true
+		}
+		for _, proto := range alpn {
+			if _, ok := alpnAdded[proto]; !ok {
+				alpnAdded[proto] = struct{}{}
+				config.NextProtos = append(config.NextProtos, proto)
+			}
+		}
+
+		// Go with the strictest minimum protocol version, so that
+		// no site gets an older one than it asked for, and with
+		// the widest maximum; the range is never empty, since the
+		// minimum of each site is no higher than its maximum
+		if cfg.ProtocolMinVersion > config.MinVersion {
 			config.MinVersion = cfg.ProtocolMinVersion
 		}
 		if cfg.ProtocolMaxVersion > config.MaxVersion {
@@ -301,13 +346,19 @@ func MakeTLSConfig(configs []*Config) (*tls.Config, error) {
 		return nil, nil
 	}
 
-	// Default cipher suites
-	if len(config.CipherSuites) == 0 {
+	// Leave the application protocols to the server
+	// if no site chose them
+	if !alpnSet {
+		config.NextProtos = nil
+	}
+
+	// Default cipher suites, unless they're left to Go
+	if len(config.CipherSuites) == 0 && !goCiphers {
 		config.CipherSuites = defaultCiphers
 	}
 
 	// For security, ensure TLS_FALLBACK_SCSV is always included first
-	if len(config.CipherSuites) == 0 || config.CipherSuites[0] != tls.TLS_FALLBACK_SCSV {
+	if len(config.CipherSuites) > 0 && config.CipherSuites[0] != tls.TLS_FALLBACK_SCSV {
 		config.CipherSuites = append([]uint16{tls.TLS_FALLBACK_SCSV}, config.CipherSuites...)
 	}
 
@@ -359,13 +410,15 @@ func RegisterConfigGetter(serverType string, fn ConfigGetter) {
 // (it does not overwrite; only fills in missing values).
 func SetDefaultTLSParams(config *Config) {
 	// If no ciphers provided, use default list
-	if len(config.Ciphers) == 0 {
+	if len(config.Ciphers) == 0 && !config.GoCiphers {
 		config.Ciphers = defaultCiphers
 	}
 
 	// Not a cipher suite, but still important for mitigating protocol downgrade attacks
 	// (prepend since having it at end breaks http2 due to non-h2-approved suites before it)
-	config.Ciphers = append([]uint16{tls.TLS_FALLBACK_SCSV}, config.Ciphers...)
+	if len(config.Ciphers) > 0 {
+		config.Ciphers = append([]uint16{tls.TLS_FALLBACK_SCSV}, config.Ciphers...)
+	}
 
 	// Set default protocol min and max versions - must balance compatibility and security
 	if config.ProtocolMinVersion == 0 {
@@ -421,6 +474,18 @@ var supportedCiphersMap = map[string]uint16{
 	"RSA-3DES-EDE-CBC-SHA":          tls.TLS_RSA_WITH_3DES_EDE_CBC_SHA,
 }
 
+// The application protocols offered by a site on a
+// listener where another site chose them.
+var defaultALPN = []string{"h2", "http/1.1"}
+
+// The cipher suites of TLS 1.3, which Go does not let
+// be configured; they are ignored when parsing config.
+var tls13Ciphers = map[string]struct{}{
+	"TLS_AES_128_GCM_SHA256":       {},
+	"TLS_AES_256_GCM_SHA384":       {},
+	"TLS_CHACHA20_POLY1305_SHA256": {},
+}
+
 // List of all the ciphers we want to use by default
 var defaultCiphers = []uint16{
 	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
@@ -1,11 +1,22 @@
 package caddytls
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
 	"errors"
+	"io/ioutil"
+	"math/big"
+	"net"
 	"net/url"
+	"os"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestMakeTLSConfigProtocolVersions(t *testing.T) {
@@ -29,6 +40,151 @@ func TestMakeTLSConfigProtocolVersions(t *testing.T) {
 	}
 }
 
+func TestMakeTLSConfigStrictestMinVersion(t *testing.T) {
+	defer func() { certCache = make(map[string]Certificate) }()
+
+	// sites with different minimums on one listener
+	configs := []*Config{
+		{Enabled: true, Hostname: "old.localhost", ProtocolMinVersion: tls.VersionTLS10, ProtocolMaxVersion: tls.VersionTLS11},
+		{Enabled: true, Hostname: "localhost", ProtocolMinVersion: tls.VersionTLS12, ProtocolMaxVersion: tls.VersionTLS12},
+		{Enabled: true, Hostname: "mid.localhost", ProtocolMinVersion: tls.VersionTLS11, ProtocolMaxVersion: tls.VersionTLS12},
+	}
+	result, err := MakeTLSConfig(configs)
+	if err != nil {
+		t.Fatalf("Did not expect an error, but got %v", err)
+	}
+	if got, want := result.MinVersion, uint16(tls.VersionTLS12); got != want {
+		t.Errorf("Expected the strictest min version %x, got %x", want, got)
+	}
+	if got, want := result.MaxVersion, uint16(tls.VersionTLS12); got != want {
+		t.Errorf("Expected the widest max version %x, got %x", want, got)
+	}
+
+	// a client that only speaks older versions is rejected
+	if err := makeSelfSignedCert(configs[1]); err != nil {
+		t.Fatal(err)
+	}
+	for i, test := range []struct {
+		clientMax uint16
+		shouldErr bool
+	}{
+		{tls.VersionTLS11, true},
+		{tls.VersionTLS12, false},
+	} {
+		err, clientErr := testHandshake(result, &tls.Config{
+			ServerName:         "localhost",
+			InsecureSkipVerify: true,
+			MinVersion:         tls.VersionTLS10,
+			MaxVersion:         test.clientMax,
+		})
+		if test.shouldErr && (err == nil || clientErr == nil) {
+			t.Errorf("Test %d: Expected handshake up to version %x to fail", i, test.clientMax)
+		}
+		if !test.shouldErr && (err != nil || clientErr != nil) {
+			t.Errorf("Test %d: Expected handshake up to version %x to succeed, got: %v, %v", i, test.clientMax, err, clientErr)
+		}
+	}
+}
+
+// testHandshake performs a handshake between a server with
+// config server and a client with config client, and returns
+// the errors of each.
+func testHandshake(server, client *tls.Config) (serverErr, clientErr error) {
+	serverConn, clientConn := net.Pipe()
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- tls.Server(serverConn, server).Handshake()
+		serverConn.Close()
+	}()
+	clientErr = tls.Client(clientConn, client).Handshake()
+	clientConn.Close()
+	return <-errChan, clientErr
+}
+
+// makeTestCert makes a certificate for a client signed by parent
+// and its key, or a self-signed CA if parent is nil.
+func makeTestCert(t *testing.T, parent *tls.Certificate) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerKey := template, interface{}(key)
+	if parent == nil {
+		template.Subject.CommonName = "Test CA"
+		template.IsCA, template.BasicConstraintsValid = true, true
+		template.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.Leaf, parent.PrivateKey
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+func TestMakeTLSConfigClientAuth(t *testing.T) {
+	defer func() { certCache = make(map[string]Certificate) }()
+
+	ca := makeTestCert(t, nil)
+	caFile, err := ioutil.TempFile("", "caddy_client_ca")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(caFile.Name())
+	pem.Encode(caFile, &pem.Block{Type: "CERTIFICATE", Bytes: ca.Certificate[0]})
+	caFile.Close()
+
+	// the strictest mode of the sites applies, with the CAs of all
+	configs := []*Config{
+		{Enabled: true, Hostname: "localhost", ClientAuth: tls.RequestClientCert},
+		{Enabled: true, Hostname: "secure.localhost", ClientAuth: tls.RequireAndVerifyClientCert, ClientCerts: []string{caFile.Name()}},
+	}
+	result, err := MakeTLSConfig(configs)
+	if err != nil {
+		t.Fatalf("Did not expect an error, but got %v", err)
+	}
+	if result.ClientAuth != tls.RequireAndVerifyClientCert {
+		t.Errorf("Expected client auth %v, got %v", tls.RequireAndVerifyClientCert, result.ClientAuth)
+	}
+	if result.ClientCAs == nil || len(result.ClientCAs.Subjects()) != 1 {
+		t.Fatalf("Expected a pool with the client CA, got %v", result.ClientCAs)
+	}
+
+	if err := makeSelfSignedCert(configs[0]); err != nil {
+		t.Fatal(err)
+	}
+	for i, test := range []struct {
+		certs     []tls.Certificate
+		shouldErr bool
+	}{
+		{nil, true},
+		{[]tls.Certificate{makeTestCert(t, nil)}, true}, // unknown CA
+		{[]tls.Certificate{makeTestCert(t, &ca)}, false},
+	} {
+		client := &tls.Config{ServerName: "localhost", InsecureSkipVerify: true, Certificates: test.certs}
+		serverErr, clientErr := testHandshake(result, client)
+		if test.shouldErr && serverErr == nil {
+			t.Errorf("Test %d: Expected the client certificate to be rejected", i)
+		}
+		if !test.shouldErr && (serverErr != nil || clientErr != nil) {
+			t.Errorf("Test %d: Expected the client certificate to be accepted, got: %v, %v", i, serverErr, clientErr)
+		}
+	}
+}
+
 func TestMakeTLSConfigPreferServerCipherSuites(t *testing.T) {
 	// prefer server cipher suites
 	configs := []*Config{{Enabled: true, PreferServerCipherSuites: true}}
@@ -105,6 +261,55 @@ func TestMakeTLSConfigCipherSuites(t *testing.T) {
 	}
 }
 
+func TestMakeTLSConfigALPN(t *testing.T) {
+	// left to the server if no site chooses them
+	configs := []*Config{{Enabled: true}, {Enabled: true}}
+	result, err := MakeTLSConfig(configs)
+	if err != nil {
+		t.Fatalf("Did not expect an error, but got %v", err)
+	}
+	if result.NextProtos != nil {
+		t.Errorf("Expected no ALPN protocols, got %v", result.NextProtos)
+	}
+
+	// unioned in order, with the defaults for sites that don't choose
+	configs = []*Config{
+		{Enabled: true, ALPN: []string{"http/1.1", "acme-tls/1"}},
+		{Enabled: true},
+	}
+	result, err = MakeTLSConfig(configs)
+	if err != nil {
+		t.Fatalf("Did not expect an error, but got %v", err)
+	}
+	expected := []string{"http/1.1", "acme-tls/1", "h2"}
+	if !reflect.DeepEqual(result.NextProtos, expected) {
+		t.Errorf("Expected ALPN protocols %v, got %v", expected, result.NextProtos)
+	}
+}
+
+func TestMakeTLSConfigGoCipherSuites(t *testing.T) {
+	configs := []*Config{
+		{Enabled: true, GoCiphers: true},
+		{Enabled: true, GoCiphers: true},
+	}
+	result, err := MakeTLSConfig(configs)
+	if err != nil {
+		t.Fatalf("Did not expect an error, but got %v", err)
+	}
+	if result.CipherSuites != nil {
+		t.Errorf("Expected the cipher suites to be left to Go, got %v", result.CipherSuites)
+	}
+
+	// they can't be combined with configured ones
+	configs = []*Config{
+		{Enabled: true, GoCiphers: true},
+		{Enabled: true, Ciphers: []uint16{tls.TLS_FALLBACK_SCSV, 0xc02c}},
+	}
+	if _, err := MakeTLSConfig(configs); err == nil {
+		t.Error("Expected an error combining the cipher suites of Go with configured ones")
+	}
+}
+
 func TestStorageForNoURL(t *testing.T) {
 	c := &Config{}
 	if _, err := c.StorageFor(""); err == nil {
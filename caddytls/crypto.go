@@ -235,9 +235,20 @@ func makeSelfSignedCert(config *Config) error {
 // close when you are ready to stop the key rotation, like when the
 // server using cfg is no longer running.
 func RotateSessionTicketKeys(cfg *tls.Config) chan struct{} {
+	return RotateSessionTicketKeysEvery(cfg, TicketRotateInterval)
+}
+
+// RotateSessionTicketKeysEvery is like RotateSessionTicketKeys,
+// but rotates the keys every interval. The first key is set
+// before it returns, so that it's there for the first handshake.
+func RotateSessionTicketKeysEvery(cfg *tls.Config, interval time.Duration) chan struct{} {
 	ch := make(chan struct{})
-	ticker := time.NewTicker(TicketRotateInterval)
-	go runTLSTicketKeyRotation(cfg, ticker, ch)
+	keys := firstTLSTicketKeys(cfg)
+	if keys == nil {
+		return ch
+	}
+	ticker := time.NewTicker(interval)
+	go runTLSTicketKeyRotation(cfg, keys, ticker, ch)
 	return ch
 }
 
@@ -247,18 +258,11 @@ var (
 	setSessionTicketKeysTestHook = func(keys [][32]byte) [][32]byte { return keys }
 )
 
-// standaloneTLSTicketKeyRotation governs over the array of TLS ticket keys used to de/crypt TLS tickets.
-// It periodically sets a new ticket key as the first one, used to encrypt (and decrypt),
-// pushing any old ticket keys to the back, where they are considered for decryption only.
-//
-// Lack of entropy for the very first ticket key results in the feature being disabled (as does Go),
-// later lack of entropy temporarily disables ticket key rotation.
-// Old ticket keys are still phased out, though.
-//
-// Stops the ticker when returning.
-func standaloneTLSTicketKeyRotation(c *tls.Config, ticker *time.Ticker, exitChan chan struct{}) {
-	defer ticker.Stop()
-
+// firstTLSTicketKeys sets the first ticket key on c and returns
+// the array of keys holding it, which standaloneTLSTicketKeyRotation
+// takes over. Lack of entropy for it results in the feature being
+// disabled (as does Go), and a nil array.
+func firstTLSTicketKeys(c *tls.Config) [][32]byte {
 	// The entire page should be marked as sticky, but Go cannot do that
 	// without resorting to syscall#Mlock. And, we don't have madvise (for NODUMP), too. ☹
 	keys := make([][32]byte, 1, NumTickets)
@@ -269,10 +273,25 @@ func standaloneTLSTicketKeyRotation(c *tls.Config, ticker *time.Ticker, exitChan
 	}
 	if _, err := io.ReadFull(rng, keys[0][:]); err != nil {
 		c.SessionTicketsDisabled = true // bail if we don't have the entropy for the first one
-		return
+		return nil
 	}
 	c.SessionTicketKey = keys[0] // SetSessionTicketKeys doesn't set a 'tls.keysAlreadySet'
 	c.SetSessionTicketKeys(setSessionTicketKeysTestHook(keys))
+	return keys
+}
+
+// standaloneTLSTicketKeyRotation governs over the array of TLS ticket keys used to de/crypt TLS tickets.
+// It periodically sets a new ticket key as the first one, used to encrypt (and decrypt),
+// pushing any old ticket keys to the back, where they are considered for decryption only.
+// SetSessionTicketKeys replaces the keys at once, so a handshake uses either the old or
+// the new ones.
+//
+// Later lack of entropy temporarily disables ticket key rotation.
+// Old ticket keys are still phased out, though.
+//
+// Stops the ticker when returning.
+func standaloneTLSTicketKeyRotation(c *tls.Config, keys [][32]byte, ticker *time.Ticker, exitChan chan struct{}) {
+	defer ticker.Stop()
 
 	for {
 		select {
@@ -281,7 +300,7 @@ func standaloneTLSTicketKeyRotation(c *tls.Config, ticker *time.Ticker, exitChan
 				return
 			}
 		case <-ticker.C:
-			rng = c.Rand // could've changed since the start
+			rng := c.Rand // could've changed since the start
 			if rng == nil {
 				rng = rand.Reader
 			}
@@ -101,7 +101,7 @@ func TestStandaloneTLSTicketKeyRotation(t *testing.T) {
 	c := new(tls.Config)
 	timer := time.NewTicker(time.Millisecond * 1)
 
-	go standaloneTLSTicketKeyRotation(c, timer, tlsGovChan)
+	go standaloneTLSTicketKeyRotation(c, firstTLSTicketKeys(c), timer, tlsGovChan)
 
 	rounds := 0
 	var lastTicketKey [32]byte
@@ -133,3 +133,53 @@ func TestStandaloneTLSTicketKeyRotation(t *testing.T) {
 		}
 	}
 }
+
+func TestRotateSessionTicketKeysEvery(t *testing.T) {
+	rotations := make(chan [][32]byte, NumTickets+2)
+
+	oldHook := setSessionTicketKeysTestHook
+	defer func() {
+		setSessionTicketKeysTestHook = oldHook
+	}()
+	setSessionTicketKeysTestHook = func(keys [][32]byte) [][32]byte {
+		rotations <- append([][32]byte(nil), keys...)
+		return keys
+	}
+
+	c := new(tls.Config)
+	tlsGovChan := RotateSessionTicketKeysEvery(c, time.Millisecond)
+
+	// the first key is there before any handshake
+	var zero [32]byte
+	if c.SessionTicketKey == zero {
+		t.Fatal("Expected the first ticket key to be set when rotation starts")
+	}
+
+	var previous [][32]byte
+	for rounds := 0; rounds <= NumTickets+1; rounds++ {
+		var keys [][32]byte
+		select {
+		case keys = <-rotations:
+		case <-time.After(time.Second):
+			close(tlsGovChan)
+			t.Fatalf("Timeout after %d rounds", rounds)
+		}
+		if rounds == 0 && keys[0] != c.SessionTicketKey {
+			t.Errorf("Expected the first ticket key to be in use, got %x", keys[0])
+		}
+		if previous != nil {
+			if keys[0] == previous[0] {
+				t.Errorf("Round %d: Expected a new ticket key, got the same one", rounds)
+			}
+			// the keys of the previous cycles are kept for decryption
+			if keys[1] != previous[0] {
+				t.Errorf("Round %d: Expected the previous ticket key to be kept", rounds)
+			}
+		}
+		if len(keys) > NumTickets {
+			t.Errorf("Round %d: Expected at most %d ticket keys, got %d", rounds, NumTickets, len(keys))
+		}
+		previous = keys
+	}
+	close(tlsGovChan)
+}
@@ -52,17 +52,48 @@ func (cg configGroup) getConfig(name string) *Config {
 
 // GetCertificate gets a certificate to satisfy clientHello. In getting
 // the certificate, it abides the rules and settings defined in the
-// Config that matches clientHello.ServerName. It first checks the in-
-// memory cache, then, if the config enables "OnDemand", it accesses
-// disk, then accesses the network if it must obtain a new certificate
-// via ACME.
+// Config that matches clientHello.ServerName. It first asks the
+// certificate hooks, then checks the in-memory cache, then, if the
+// config enables "OnDemand", it accesses disk, then accesses the
+// network if it must obtain a new certificate via ACME.
 //
 // This method is safe for use as a tls.Config.GetCertificate callback.
 func (cg configGroup) GetCertificate(clientHello *tls.ClientHelloInfo) (*tls.Certificate, error) {
-	cert, err := cg.getCertDuringHandshake(strings.ToLower(clientHello.ServerName), true, true)
+	if clientHello.ServerName == "" && cg.requireSNI() {
+		return nil, errNoSNI
+	}
+	name := strings.ToLower(clientHello.ServerName)
+
+	// Plugins get to choose first
+	for _, hook := range certificateHooks {
+		cert, err := hook(name, clientHello)
+		if err != nil {
+			return nil, err
+		}
+		if cert != nil {
+			return cert, nil
+		}
+	}
+
+	cert, err := cg.getCertDuringHandshake(name, true, true)
 	return &cert.Certificate, err
 }
 
+// requireSNI returns true if any config in cg requires
+// handshakes to indicate the server name.
+func (cg configGroup) requireSNI() bool {
+	for _, cfg := range cg {
+		if cfg.RequireSNI {
+			return true
+		}
+	}
+	return false
+}
+
+// errNoSNI is returned for handshakes without a server
+// name when SNI is required, which aborts them.
+var errNoSNI = errors.New("no server name (SNI) indicated, but it is required")
+
 // getCertDuringHandshake will get a certificate for name. It first tries
 // the in-memory cache. If no certificate for name is in the cache, the
 // config most closely corresponding to name will be loaded. If that config
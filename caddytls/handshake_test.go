@@ -3,6 +3,7 @@ package caddytls
 import (
 	"crypto/tls"
 	"crypto/x509"
+	"errors"
 	"testing"
 )
 
@@ -54,3 +55,86 @@ func TestGetCertificate(t *testing.T) {
 		t.Errorf("Expected default cert with no matches, got: %v", cert)
 	}
 }
+
+func TestGetCertificateRequireSNI(t *testing.T) {
+	defer func() { certCache = make(map[string]Certificate) }()
+
+	defaultCert := Certificate{Names: []string{"example.com", ""}, Certificate: tls.Certificate{Leaf: &x509.Certificate{DNSNames: []string{"example.com"}}}}
+	certCache[""] = defaultCert
+	certCache["example.com"] = defaultCert
+
+	hello := &tls.ClientHelloInfo{ServerName: "example.com"}
+	helloNoSNI := &tls.ClientHelloInfo{}
+
+	// SNI is optional by default
+	cg := configGroup{"example.com": {Hostname: "example.com"}}
+	if _, err := cg.GetCertificate(helloNoSNI); err != nil {
+		t.Errorf("Expected the default certificate without SNI when it is not required, got error: %v", err)
+	}
+
+	// one config requiring SNI is enough to require it on the listener
+	cg["other.com"] = &Config{Hostname: "other.com", RequireSNI: true}
+	if cert, err := cg.GetCertificate(helloNoSNI); err != errNoSNI {
+		t.Errorf("Expected handshake without SNI to be rejected, got cert=%v, err=%v", cert, err)
+	}
+	if cert, err := cg.GetCertificate(hello); err != nil {
+		t.Errorf("Expected handshake with SNI to succeed, got error: %v", err)
+	} else if cert.Leaf.DNSNames[0] != "example.com" {
+		t.Errorf("Expected certificate for example.com, got: %v", cert)
+	}
+}
+
+func TestGetCertificateHooks(t *testing.T) {
+	defer func() { certCache = make(map[string]Certificate) }()
+	defer func(hooks []CertificateHook) { certificateHooks = hooks }(certificateHooks)
+
+	cert := func(name string) *tls.Certificate {
+		return &tls.Certificate{Leaf: &x509.Certificate{DNSNames: []string{name}}}
+	}
+	certificateHooks = []CertificateHook{
+		func(name string, clientHello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			switch name {
+			case "a.example.com":
+				return cert("a.example.com"), nil
+			case "fail.example.com":
+				return nil, errors.New("no certificate for you")
+			}
+			return nil, nil
+		},
+		func(name string, clientHello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			if name == "b.example.com" {
+				return cert("b.example.com"), nil
+			}
+			return nil, nil
+		},
+	}
+	certCache[""] = Certificate{Names: []string{"example.com", ""}, Certificate: *cert("example.com")}
+
+	cg := make(configGroup)
+	for i, test := range []struct {
+		serverName string
+		expected   string // DNS name of the certificate
+		shouldErr  bool
+	}{
+		{"a.example.com", "a.example.com", false},
+		{"B.example.com", "b.example.com", false},
+		{"c.example.com", "example.com", false}, // falls through to the cache
+		{"", "example.com", false},
+		{"fail.example.com", "", true},
+	} {
+		got, err := cg.GetCertificate(&tls.ClientHelloInfo{ServerName: test.serverName})
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: Expected an error, got certificate %v", i, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: Expected no error, got: %v", i, err)
+			continue
+		}
+		if got.Leaf.DNSNames[0] != test.expected {
+			t.Errorf("Test %d: Expected the certificate of %s, got the one of %s", i, test.expected, got.Leaf.DNSNames[0])
+		}
+	}
+}
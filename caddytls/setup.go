@@ -11,6 +11,7 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/mholt/caddy"
 )
@@ -97,14 +98,55 @@ func setupTLS(c *caddy.Controller) error {
 						return c.Errf("Minimum protocol version cannot be higher than maximum (reverse the order)")
 					}
 				}
+			case "min_version":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return c.ArgErr()
+				}
+				value, ok := supportedProtocols[strings.ToLower(args[0])]
+				if !ok {
+					return c.Errf("Unknown TLS version '%s'; must be one of tls1.0, tls1.1 or tls1.2", args[0])
+				}
+				if config.ProtocolMaxVersion != 0 && value > config.ProtocolMaxVersion {
+					return c.Errf("Minimum TLS version '%s' is higher than the maximum protocol version", args[0])
+				}
+				config.ProtocolMinVersion = value
 			case "ciphers":
 				for c.NextArg() {
+					if _, ok := tls13Ciphers[strings.ToUpper(c.Val())]; ok {
+						log.Printf("[WARNING] Cipher suite %s of TLS 1.3 cannot be configured; ignoring it", c.Val())
+						continue
+					}
 					value, ok := supportedCiphersMap[strings.ToUpper(c.Val())]
 					if !ok {
 						return c.Errf("Wrong cipher name or cipher not supported: '%s'", c.Val())
 					}
 					config.Ciphers = append(config.Ciphers, value)
 				}
+				// without any that can be configured,
+				// the cipher suites are left to Go
+				config.GoCiphers = len(config.Ciphers) == 0
+			case "alpn":
+				args := c.RemainingArgs()
+				if len(args) == 0 {
+					return c.ArgErr()
+				}
+				for _, proto := range args {
+					if !validALPN(proto) {
+						return c.Errf("Invalid ALPN protocol '%s'", proto)
+					}
+				}
+				config.ALPN = args
+			case "ticket_rotation":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return c.ArgErr()
+				}
+				interval, err := time.ParseDuration(args[0])
+				if err != nil || interval < time.Minute {
+					return c.Errf("ticket_rotation must be a duration of at least 1m, got '%s'", args[0])
+				}
+				config.TicketRotation = interval
 			case "curves":
 				for c.NextArg() {
 					value, ok := supportedCurvesMap[strings.ToUpper(c.Val())]
@@ -121,6 +163,12 @@ func setupTLS(c *caddy.Controller) error {
 
 				listStart, mustProvideCA := 1, true
 				switch clientCertList[0] {
+				case "none":
+					if len(clientCertList) > 1 {
+						return c.ArgErr()
+					}
+					config.ClientAuth = tls.NoClientCert
+					mustProvideCA = false
 				case "request":
 					config.ClientAuth = tls.RequestClientCert
 					mustProvideCA = false
@@ -129,12 +177,14 @@ func setupTLS(c *caddy.Controller) error {
 					mustProvideCA = false
 				case "verify_if_given":
 					config.ClientAuth = tls.VerifyClientCertIfGiven
+				case "require_and_verify":
+					config.ClientAuth = tls.RequireAndVerifyClientCert
 				default:
 					config.ClientAuth = tls.RequireAndVerifyClientCert
 					listStart = 0
 				}
 				if mustProvideCA && len(clientCertList) <= listStart {
-					return c.ArgErr()
+					return c.Errf("Verifying client certificates requires the files of the CAs to trust")
 				}
 
 				config.ClientCerts = clientCertList[listStart:]
@@ -166,6 +216,11 @@ func setupTLS(c *caddy.Controller) error {
 				config.StorageProvider = args[0]
 			case "muststaple":
 				config.MustStaple = true
+			case "require_sni":
+				if c.NextArg() {
+					return c.ArgErr()
+				}
+				config.RequireSNI = true
 			default:
 				return c.Errf("Unknown keyword '%s'", c.Val())
 			}
@@ -221,6 +276,22 @@ func setupTLS(c *caddy.Controller) error {
 	return nil
 }
 
+// validALPN reports whether proto can be an ALPN protocol
+// name: 1 to 255 visible ASCII characters, like h2 or
+// acme-tls/1. The protocol allows any bytes, but names
+// that are registered are all like that.
+func validALPN(proto string) bool {
+	if len(proto) == 0 || len(proto) > 255 {
+		return false
+	}
+	for i := 0; i < len(proto); i++ {
+		if proto[i] <= ' ' || proto[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
 // loadCertsInDir loads all the certificates/keys in dir, as long as
 // the file ends with .pem. This method of loading certificates is
 // modeled after haproxy, which expects the certificate and key to
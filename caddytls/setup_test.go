@@ -5,7 +5,10 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/mholt/caddy"
 	"github.com/xenolf/lego/acme"
@@ -104,6 +107,7 @@ func TestSetupParseWithOptionalParams(t *testing.T) {
             protocols tls1.0 tls1.2
             ciphers RSA-AES256-CBC-SHA ECDHE-RSA-AES128-GCM-SHA256 ECDHE-ECDSA-AES256-GCM-SHA384
             muststaple
+            require_sni
         }`
 	cfg := new(Config)
 	RegisterConfigGetter("", func(c *caddy.Controller) *Config { return cfg })
@@ -129,6 +133,10 @@ func TestSetupParseWithOptionalParams(t *testing.T) {
 	if !cfg.MustStaple {
 		t.Errorf("Expected must staple to be true")
 	}
+
+	if !cfg.RequireSNI {
+		t.Errorf("Expected SNI to be required")
+	}
 }
 
 func TestSetupDefaultWithOptionalParams(t *testing.T) {
@@ -235,6 +243,18 @@ func TestSetupParseWithClientAuth(t *testing.T) {
 		{`tls ` + certFile + ` ` + keyFile + ` {
 			clients verify_if_given
 		}`, tls.VerifyClientCertIfGiven, true, noCAs},
+		{`tls ` + certFile + ` ` + keyFile + ` {
+			clients require_and_verify client_ca.crt client2_ca.crt
+		}`, tls.RequireAndVerifyClientCert, false, twoCAs},
+		{`tls ` + certFile + ` ` + keyFile + ` {
+			clients require_and_verify
+		}`, tls.RequireAndVerifyClientCert, true, noCAs},
+		{`tls ` + certFile + ` ` + keyFile + ` {
+			clients none
+		}`, tls.NoClientCert, false, noCAs},
+		{`tls ` + certFile + ` ` + keyFile + ` {
+			clients none client_ca.crt
+		}`, tls.NoClientCert, true, noCAs},
 	} {
 		cfg := new(Config)
 		RegisterConfigGetter("", func(c *caddy.Controller) *Config { return cfg })
@@ -335,6 +355,149 @@ func TestSetupParseWithOneTLSProtocol(t *testing.T) {
 	}
 }
 
+func TestSetupParseCipherSuites(t *testing.T) {
+	for i, test := range []struct {
+		params          string
+		shouldErr       bool
+		expectedCiphers []uint16
+		expectedGo      bool
+	}{
+		{"tls {\n\tciphers ECDHE-ECDSA-AES128-GCM-SHA256 ecdhe-rsa-aes256-gcm-sha384\n}", false,
+			[]uint16{tls.TLS_FALLBACK_SCSV, tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384}, false},
+		// suites of TLS 1.3 are ignored
+		{"tls {\n\tciphers TLS_AES_128_GCM_SHA256 ECDHE-RSA-AES128-GCM-SHA256\n}", false,
+			[]uint16{tls.TLS_FALLBACK_SCSV, tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}, false},
+		// without any, the suites are left to Go
+		{"tls {\n\tciphers\n}", false, nil, true},
+		{"tls {\n\tciphers TLS_CHACHA20_POLY1305_SHA256\n}", false, nil, true},
+		{"tls {\n\tciphers ECDHE-RSA-AES128-GCM-SHA256 RC4-SHA\n}", true, nil, false},
+	} {
+		cfg := new(Config)
+		RegisterConfigGetter("", func(c *caddy.Controller) *Config { return cfg })
+		c := caddy.NewTestController("", test.params)
+
+		err := setupTLS(c)
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: Expected an error, but did not have one", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: Expected no errors, got: %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(cfg.Ciphers, test.expectedCiphers) {
+			t.Errorf("Test %d: Expected ciphers %#x, got %#x", i, test.expectedCiphers, cfg.Ciphers)
+		}
+		if cfg.GoCiphers != test.expectedGo {
+			t.Errorf("Test %d: Expected GoCiphers %v, got %v", i, test.expectedGo, cfg.GoCiphers)
+		}
+	}
+}
+
+func TestSetupParseALPN(t *testing.T) {
+	for i, test := range []struct {
+		params    string
+		shouldErr bool
+		expected  []string
+	}{
+		{"tls {\n\talpn h2 http/1.1\n}", false, []string{"h2", "http/1.1"}},
+		{"tls {\n\talpn http/1.1 acme-tls/1\n}", false, []string{"http/1.1", "acme-tls/1"}},
+		{"tls {\n\talpn\n}", true, nil},
+		{"tls {\n\talpn \"h2 c\"\n}", true, nil},
+		{"tls {\n\talpn " + strings.Repeat("x", 256) + "\n}", true, nil},
+	} {
+		cfg := new(Config)
+		RegisterConfigGetter("", func(c *caddy.Controller) *Config { return cfg })
+		c := caddy.NewTestController("", test.params)
+
+		err := setupTLS(c)
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: Expected an error, but did not have one", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: Expected no errors, got: %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(cfg.ALPN, test.expected) {
+			t.Errorf("Test %d: Expected ALPN %v, got %v", i, test.expected, cfg.ALPN)
+		}
+	}
+}
+
+func TestSetupParseTicketRotation(t *testing.T) {
+	for i, test := range []struct {
+		params    string
+		shouldErr bool
+		expected  time.Duration
+	}{
+		{"tls {\n\tticket_rotation 1h\n}", false, time.Hour},
+		{"tls {\n\tticket_rotation 30s\n}", true, 0},
+		{"tls {\n\tticket_rotation often\n}", true, 0},
+		{"tls {\n\tticket_rotation\n}", true, 0},
+	} {
+		cfg := new(Config)
+		RegisterConfigGetter("", func(c *caddy.Controller) *Config { return cfg })
+		c := caddy.NewTestController("", test.params)
+
+		err := setupTLS(c)
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: Expected an error, but did not have one", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: Expected no errors, got: %v", i, err)
+			continue
+		}
+		if cfg.TicketRotation != test.expected {
+			t.Errorf("Test %d: Expected ticket rotation every %v, got %v", i, test.expected, cfg.TicketRotation)
+		}
+	}
+}
+
+func TestSetupParseMinVersion(t *testing.T) {
+	for i, test := range []struct {
+		params      string
+		shouldErr   bool
+		expectedMin uint16
+	}{
+		{"tls {\n\tmin_version tls1.2\n}", false, tls.VersionTLS12},
+		{"tls {\n\tmin_version TLS1.0\n}", false, tls.VersionTLS10},
+		{"tls {\n\tmin_version ssl3\n}", true, 0},
+		{"tls {\n\tmin_version\n}", true, 0},
+		{"tls {\n\tmin_version tls1.1 tls1.2\n}", true, 0},
+		{"tls {\n\tprotocols tls1.0 tls1.1\n\tmin_version tls1.2\n}", true, 0},
+	} {
+		cfg := new(Config)
+		RegisterConfigGetter("", func(c *caddy.Controller) *Config { return cfg })
+		c := caddy.NewTestController("", test.params)
+
+		err := setupTLS(c)
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: Expected an error, but did not have one", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: Expected no errors, got: %v", i, err)
+			continue
+		}
+		if cfg.ProtocolMinVersion != test.expectedMin {
+			t.Errorf("Test %d: Expected ProtocolMinVersion %#x, got %#x", i, test.expectedMin, cfg.ProtocolMinVersion)
+		}
+		if cfg.ProtocolMaxVersion != tls.VersionTLS12 {
+			t.Errorf("Test %d: Expected default ProtocolMaxVersion tls1.2, got %#x", i, cfg.ProtocolMaxVersion)
+		}
+	}
+}
+
 const (
 	certFile = "test_cert.pem"
 	keyFile  = "test_key.pem"
@@ -15,6 +15,7 @@
 package caddytls
 
 import (
+	"crypto/tls"
 	"encoding/json"
 	"net"
 	"strings"
@@ -176,3 +177,23 @@ func RegisterStorageProvider(name string, provider StorageConstructor) {
 	storageProviders[name] = provider
 	caddy.RegisterPlugin("tls.storage."+name, caddy.Plugin{})
 }
+
+// CertificateHook chooses the certificate for a TLS handshake
+// with the server name name, which is in lower case and may be
+// empty. If it returns neither a certificate nor an error, the
+// certificate is chosen as if there were no hook; an error
+// fails the handshake.
+type CertificateHook func(name string, clientHello *tls.ClientHelloInfo) (*tls.Certificate, error)
+
+// certificateHooks are the hooks that have been plugged in,
+// in the order in which they were registered.
+var certificateHooks []CertificateHook
+
+// RegisterCertificateHook registers hook by name for choosing
+// certificates at handshake time, before the certificates that
+// are cached or managed. This allows certificates to come from
+// elsewhere, like a database.
+func RegisterCertificateHook(name string, hook CertificateHook) {
+	certificateHooks = append(certificateHooks, hook)
+	caddy.RegisterPlugin("tls.certificate."+name, caddy.Plugin{})
+}